@@ -13,6 +13,13 @@ type MetricMaker interface {
 	Log() cua.Logger
 }
 
+// MetricSelector is implemented by the makers filtering the metrics, it is
+// evaluated before a metric is created to not allocate the metrics which
+// would be dropped by the filters.
+type MetricSelector interface {
+	SelectMetric(measurement string, tags map[string]string, fields map[string]interface{}) bool
+}
+
 type accumulator struct {
 	maker     MetricMaker
 	metrics   chan<- cua.Metric
@@ -99,6 +106,9 @@ func (ac *accumulator) addFields(
 	tp cua.ValueType,
 	t ...time.Time,
 ) {
+	if sel, ok := ac.maker.(MetricSelector); ok && !sel.SelectMetric(measurement, tags, fields) {
+		return
+	}
 	m, err := metric.New(measurement, tags, fields, ac.getTime(t), tp)
 	if err != nil {
 		return
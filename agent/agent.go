@@ -20,12 +20,37 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	ballast []byte
+
+	// serverless shortens the startup, see RunServerless.
+	serverless bool
+
+	// flushMu protects the flush requests of the outputs and the channel
+	// the inputs write to, set once the outputs run.
+	flushMu       sync.Mutex
+	flushRequests []chan chan error
+	pipelineSrc   chan<- cua.Metric
+	outputsReady  chan struct{}
+
+	// schedule is the timezone and business days of the agent.
+	schedule *schedule
+
+	// tap streams the metrics written to the outputs to the clients of the
+	// control socket, nil when the control socket is disabled.
+	tap *tap
 }
 
 // NewAgent returns an Agent for the given Config.
 func NewAgent(config *config.Config) (*Agent, error) {
+	schedule, err := newSchedule(config.Agent)
+	if err != nil {
+		return nil, err
+	}
 	a := &Agent{
-		Config: config,
+		Config:       config,
+		outputsReady: make(chan struct{}),
+		schedule:     schedule,
 	}
 	return a, nil
 }
@@ -101,12 +126,29 @@ func (a *Agent) Run(ctx context.Context) error {
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Quiet,
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
+	a.tuneCPU()
+	if err := a.tuneMemory(); err != nil {
+		return err
+	}
+
 	log.Printf("D! [agent] Initializing plugins")
 	err := a.initPlugins()
 	if err != nil {
 		return err
 	}
 
+	stopHealth, err := a.startHealthServer(ctx)
+	if err != nil {
+		return err
+	}
+	defer stopHealth()
+
+	if n := a.Config.Agent.GoroutineLeakIntervals; n > 0 {
+		detectorCtx, cancelDetector := context.WithCancel(ctx)
+		defer cancelDetector()
+		go newLeakDetector(n).run(detectorCtx, a.Config.Agent.Interval.Duration)
+	}
+
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
@@ -147,7 +189,22 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
+	a.flushMu.Lock()
+	a.pipelineSrc = next
+	a.flushMu.Unlock()
+
 	var wg sync.WaitGroup
+	if a.Config.Agent.ControlSocket != "" {
+		a.tap = newTap()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.serveControl(ctx, a.Config.Agent.ControlSocket); err != nil {
+				log.Printf("E! [agent] Error serving control socket: %v", err)
+			}
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -212,6 +269,12 @@ func (a *Agent) Run(ctx context.Context) error {
 	return err
 }
 
+// CheckConfig initializes the plugins, validating their configuration
+// without starting them.
+func (a *Agent) CheckConfig() error {
+	return a.initPlugins()
+}
+
 // initPlugins runs the Init function on plugins.
 func (a *Agent) initPlugins() error {
 	for _, input := range a.Config.Inputs {
@@ -269,7 +332,10 @@ func (a *Agent) startInputs(
 			acc := NewAccumulator(input, dst)
 			acc.SetPrecision(getPrecision(precision, interval))
 
-			err := si.Start(acc)
+			var err error
+			runLabeled(input.LogName(), func() {
+				err = si.Start(acc)
+			})
 			if err != nil {
 				stopServiceInputs(unit.inputs)
 				return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
@@ -310,11 +376,17 @@ func (a *Agent) runInputs(
 			jitter = input.Config.CollectionJitter
 		}
 
+		// Overwrite agent collection_offset if this plugin has its own.
+		offset := a.Config.Agent.CollectionOffset.Duration
+		if input.Config.CollectionOffset != 0 {
+			offset = input.Config.CollectionOffset
+		}
+
 		var ticker Ticker
 		if a.Config.Agent.RoundInterval {
-			ticker = NewAlignedTicker(startTime, interval, jitter)
+			ticker = NewAlignedTicker(startTime, interval, jitter, offset, a.location())
 		} else {
-			ticker = NewUnalignedTicker(interval, jitter)
+			ticker = NewUnalignedTicker(interval, jitter, offset)
 		}
 		defer ticker.Stop()
 
@@ -324,7 +396,9 @@ func (a *Agent) runInputs(
 		wg.Add(1)
 		go func(input *models.RunningInput) {
 			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
+			runLabeled(input.LogName(), func() {
+				a.gatherLoop(ctx, acc, input, ticker, interval)
+			})
 		}(input)
 	}
 
@@ -333,7 +407,11 @@ func (a *Agent) runInputs(
 	log.Printf("D! [agent] Stopping service inputs")
 	stopServiceInputs(unit.inputs)
 
+	// no barrier is sent once the channel is closed
+	a.flushMu.Lock()
+	a.pipelineSrc = nil
 	close(unit.dst)
+	a.flushMu.Unlock()
 	log.Printf("D! [agent] Input channel closed")
 }
 
@@ -519,7 +597,10 @@ func (a *Agent) startProcessors(
 		src = make(chan cua.Metric, 100)
 		acc := NewAccumulator(processor, dst)
 
-		err := processor.Start(acc)
+		var err error
+		runLabeled(processor.LogName(), func() {
+			err = processor.Start(acc)
+		})
 		if err != nil {
 			for _, u := range units {
 				u.processor.Stop()
@@ -552,12 +633,18 @@ func (a *Agent) runProcessors(
 			defer wg.Done()
 
 			acc := NewAccumulator(unit.processor, unit.dst)
-			for m := range unit.src {
-				if err := unit.processor.Add(m, acc); err != nil {
-					acc.AddError(err)
-					m.Drop()
+			runLabeled(unit.processor.LogName(), func() {
+				for m := range unit.src {
+					if b, ok := m.(*pipelineBarrier); ok {
+						unit.dst <- b
+						continue
+					}
+					if err := unit.processor.Add(m, acc); err != nil {
+						acc.AddError(err)
+						m.Drop()
+					}
 				}
-			}
+			})
 			unit.processor.Stop()
 			close(unit.dst)
 			log.Printf("D! [agent] Processor channel closed")
@@ -602,6 +689,10 @@ func (a *Agent) runAggregators(
 	go func() {
 		defer wg.Done()
 		for metric := range unit.src {
+			if b, ok := metric.(*pipelineBarrier); ok {
+				unit.outputC <- b
+				continue
+			}
 			var dropOriginal bool
 			for _, agg := range a.Config.Aggregators {
 				if ok := agg.Add(metric); ok {
@@ -628,7 +719,9 @@ func (a *Agent) runAggregators(
 
 			acc := NewAccumulator(agg, unit.aggC)
 			acc.SetPrecision(getPrecision(precision, interval))
-			a.push(ctx, agg, acc)
+			runLabeled(agg.LogName(), func() {
+				a.push(ctx, agg, acc)
+			})
 		}(agg)
 	}
 
@@ -707,21 +800,32 @@ func (a *Agent) startOutputs(
 // connectOutputs connects to all outputs.
 func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput) error {
 	log.Printf("D! [agent] Attempting connection to [%s]", output.LogName())
-	err := output.Output.Connect()
+	var err error
+	runLabeled(output.LogName(), func() {
+		err = output.Output.Connect()
+	})
 	if err != nil {
-		log.Printf("E! [agent] Failed to connect to [%s], retrying in 15s, "+
-			"error was '%s'", output.LogName(), err)
+		retry := 15 * time.Second
+		if a.serverless {
+			// the startup of a serverless environment is time limited
+			retry = time.Second
+		}
+		log.Printf("E! [agent] Failed to connect to [%s], retrying in %s, "+
+			"error was '%s'", output.LogName(), retry, err)
 
-		err := internal.SleepContext(ctx, 15*time.Second)
+		err := internal.SleepContext(ctx, retry)
 		if err != nil {
 			return fmt.Errorf("sleepcontext: %w", err)
 		}
 
-		err = output.Output.Connect()
+		runLabeled(output.LogName(), func() {
+			err = output.Output.Connect()
+		})
 		if err != nil {
 			return fmt.Errorf("Error connecting to output %q: %w", output.LogName(), err)
 		}
 	}
+	output.SetConnected(true)
 	log.Printf("D! [agent] Successfully connected to %s", output.LogName())
 	return nil
 }
@@ -740,6 +844,7 @@ func (a *Agent) runOutputs(
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	requests := make([]chan chan error, 0, len(unit.outputs))
 	for _, output := range unit.outputs {
 		interval := interval
 		// Overwrite agent flush_interval if this plugin has its own.
@@ -753,20 +858,53 @@ func (a *Agent) runOutputs(
 			jitter = output.Config.FlushJitter
 		}
 
+		flushRequest := make(chan chan error)
+		requests = append(requests, flushRequest)
+
 		wg.Add(1)
 		go func(output *models.RunningOutput) {
 			defer wg.Done()
 
-			ticker := NewRollingTicker(interval, jitter)
+			var ticker Ticker
+			if a.Config.Agent.RoundFlushInterval {
+				ticker = NewAlignedTicker(time.Now(), interval, jitter, 0, a.location())
+			} else {
+				ticker = NewRollingTicker(interval, jitter)
+			}
 			defer ticker.Stop()
 
-			a.flushLoop(ctx, output, ticker)
+			pacer := newFlushPacer(output, interval)
+			runLabeled(output.LogName(), func() {
+				a.flushLoop(ctx, output, ticker, flushRequest, pacer)
+			})
 		}(output)
 	}
 
+	a.flushMu.Lock()
+	a.flushRequests = requests
+	if a.outputsReady != nil {
+		select {
+		case <-a.outputsReady:
+		default:
+			close(a.outputsReady)
+		}
+	}
+	a.flushMu.Unlock()
+
+	var targets []*models.RunningOutput
 	for metric := range unit.src {
-		for i, output := range unit.outputs {
-			if i == len(a.Config.Outputs)-1 {
+		if b, ok := metric.(*pipelineBarrier); ok {
+			close(b.done)
+			continue
+		}
+		a.tagBusinessDay(metric)
+		a.tap.publish(metric)
+		targets = routeMetric(unit.outputs, metric, targets[:0])
+		if len(targets) == 0 {
+			metric.Drop()
+		}
+		for i, output := range targets {
+			if i == len(targets)-1 {
 				output.AddMetric(metric)
 			} else {
 				output.AddMetric(metric.Copy())
@@ -775,22 +913,60 @@ func (a *Agent) runOutputs(
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
+	a.flushMu.Lock()
+	a.flushRequests = nil
+	a.flushMu.Unlock()
 	cancel()
 	wg.Wait()
 }
 
+// routeMetric appends to targets the outputs the metric is added to: all the
+// outputs, except the fallback outputs when the metric is selected by one of
+// the other outputs.
+func routeMetric(outputs []*models.RunningOutput, metric cua.Metric, targets []*models.RunningOutput) []*models.RunningOutput {
+	checked, routed := false, false
+	for _, output := range outputs {
+		if output.Config.Fallback {
+			if !checked {
+				checked = true
+				for _, other := range outputs {
+					if !other.Config.Fallback && other.Select(metric) {
+						routed = true
+						break
+					}
+				}
+			}
+			if routed {
+				continue
+			}
+		}
+		targets = append(targets, output)
+	}
+	return targets
+}
+
 // flushLoop runs an output's flush function periodically until the context is
-// done.
+// done.  A flush is also run for each request received, the result of the
+// write is sent on the request channel.
 func (a *Agent) flushLoop(
 	ctx context.Context,
 	output *models.RunningOutput,
 	ticker Ticker,
+	requests <-chan chan error,
+	pacer *flushPacer,
 ) {
 	logError := func(err error) {
 		if err != nil {
-			log.Printf("E! [agent] Error writing to %s: %v", output.LogName(), err)
+			// logged by the output, for the error to be counted and
+			// reported by its status
+			output.Log().Errorf("Error writing: %v", err)
 		}
 	}
+	flush := func(writeFunc func() error) error {
+		return pacer.flush(output, func() error {
+			return a.flushOnce(output, ticker, writeFunc)
+		})
+	}
 
 	// watch for flush requests
 	flushRequested := make(chan os.Signal, 1)
@@ -801,31 +977,145 @@ func (a *Agent) flushLoop(
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.finalFlush(output, ticker))
 			return
 		default:
 		}
 
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.finalFlush(output, ticker))
 			return
 		case <-ticker.Elapsed():
-			logError(a.flushOnce(output, ticker, output.Write))
+			if pacer.skip(time.Now()) && !output.UnderPressure() {
+				continue
+			}
+			logError(flush(output.Write))
 		case <-flushRequested:
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(flush(output.Write))
+		case done := <-requests:
+			err := flush(output.Write)
+			logError(err)
+			done <- err
 		case <-output.BatchReady:
+			if pacer.skip(time.Now()) && !output.UnderPressure() {
+				continue
+			}
 			// Favor the ticker over batch ready
 			select {
 			case <-ticker.Elapsed():
-				logError(a.flushOnce(output, ticker, output.Write))
+				logError(flush(output.Write))
 			default:
-				logError(a.flushOnce(output, ticker, output.WriteBatch))
+				logError(flush(output.WriteBatch))
 			}
 		}
 	}
 }
 
+// flushPacer stretches the flush interval of an output with adaptive
+// flushing while its writes are slow, up to a maximum interval.  The ticks
+// within the stretched interval are skipped.
+type flushPacer struct {
+	interval time.Duration
+	max      time.Duration
+	stretch  time.Duration
+	next     time.Time
+}
+
+// newFlushPacer returns the pacer of an output, nil without adaptive
+// flushing.
+func newFlushPacer(output *models.RunningOutput, interval time.Duration) *flushPacer {
+	af := output.Config.AdaptiveFlush
+	if !af.Enabled || interval <= 0 {
+		return nil
+	}
+	max := af.MaxInterval
+	if max < interval {
+		max = interval * 4
+	}
+	return &flushPacer{interval: interval, max: max, stretch: interval}
+}
+
+// skip returns whether a tick comes before the end of the stretched
+// interval.
+func (p *flushPacer) skip(now time.Time) bool {
+	return p != nil && now.Before(p.next)
+}
+
+// flush runs a flush, the interval is doubled when the flush takes more
+// than half of the interval and halved back otherwise.
+func (p *flushPacer) flush(output *models.RunningOutput, flush func() error) error {
+	if p == nil {
+		return flush()
+	}
+
+	start := time.Now()
+	err := flush()
+	elapsed := time.Since(start)
+
+	stretch := p.stretch
+	if elapsed > p.interval/2 {
+		stretch *= 2
+		if stretch > p.max {
+			stretch = p.max
+		}
+	} else if stretch > p.interval {
+		stretch /= 2
+		if stretch < p.interval {
+			stretch = p.interval
+		}
+	}
+	if stretch != p.stretch {
+		output.Log().Debugf("Flush took %s, flush interval set to %s", elapsed, stretch)
+		p.stretch = stretch
+	}
+	// with a margin for the ticks coming before the end of the interval
+	p.next = start.Add(p.stretch - p.interval/2)
+	return err
+}
+
+// shutdownRetryInterval is the wait between the retries of the final flush.
+var shutdownRetryInterval = time.Second
+
+// finalFlush writes the buffered metrics of the output on shutdown.  With a
+// shutdown timeout the failed writes are retried until the deadline, when
+// the write still running is abandoned.
+func (a *Agent) finalFlush(output *models.RunningOutput, ticker Ticker) error {
+	timeout := a.Config.Agent.ShutdownTimeout.Duration
+	if timeout <= 0 {
+		return a.flushOnce(output, ticker, output.Write)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		done := make(chan error, 1)
+		go func() {
+			done <- output.Write()
+		}()
+
+		var err error
+		select {
+		case err = <-done:
+			output.LogBufferStatus()
+			if err == nil {
+				return nil
+			}
+		case <-deadline.C:
+			return fmt.Errorf("flush not completed within the shutdown timeout of %s, %d metrics not written",
+				timeout, output.BufferLength())
+		}
+
+		output.Log().Warnf("Error writing on shutdown, retrying: %v", err)
+		select {
+		case <-time.After(shutdownRetryInterval):
+		case <-deadline.C:
+			return fmt.Errorf("shutdown timeout of %s reached, %d metrics not written: %w",
+				timeout, output.BufferLength(), err)
+		}
+	}
+}
+
 // flushOnce runs the output's Write function once, logging a warning each
 // interval it fails to complete before.
 func (a *Agent) flushOnce(
@@ -861,6 +1151,7 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 	go func() {
 		defer wg.Done()
 		s := influx.NewSerializer()
+		s.Log = models.NewLogger("serializers", "influx", "")
 		s.SetFieldSortOrder(influx.SortFields)
 
 		for metric := range src {
@@ -885,6 +1176,30 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 	return nil
 }
 
+// Sample runs the inputs, processors and aggregators for a single gather and
+// returns the metrics.
+func (a *Agent) Sample(ctx context.Context, wait time.Duration) ([]cua.Metric, error) {
+	src := make(chan cua.Metric, 100)
+
+	var metrics []cua.Metric
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for metric := range src {
+			metrics = append(metrics, metric.Copy())
+			metric.Reject()
+		}
+	}()
+
+	err := a.test(ctx, wait, src)
+	if err != nil {
+		return nil, err
+	}
+
+	<-done
+	return metrics, nil
+}
+
 // Test runs the agent and performs a single gather sending output to the
 // outputF.  After gathering pauses for the wait duration to allow service
 // inputs to run.
@@ -1021,6 +1336,12 @@ func (a *Agent) once(ctx context.Context, wait time.Duration) error {
 		return err
 	}
 
+	stopHealth, err := a.startHealthServer(ctx)
+	if err != nil {
+		return err
+	}
+	defer stopHealth()
+
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
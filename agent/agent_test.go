@@ -5,8 +5,11 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/models"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/all"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/all"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs/discard"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -167,3 +170,62 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestRouteMetric(t *testing.T) {
+	prod := models.NewRunningOutput("circonus", &discard.Discard{}, &models.OutputConfig{
+		Name: "circonus",
+		Filter: models.Filter{
+			TagPass: []models.TagFilter{{Name: "env", Filter: []string{"prod"}}},
+		},
+	}, 0, 0)
+	require.NoError(t, prod.Config.Filter.Compile())
+	debug := models.NewRunningOutput("file", &discard.Discard{}, &models.OutputConfig{
+		Name:     "file",
+		Fallback: true,
+	}, 0, 0)
+	outputs := []*models.RunningOutput{prod, debug}
+
+	m := testutil.MustMetric("cpu", map[string]string{"env": "prod"}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	require.Equal(t, []*models.RunningOutput{prod}, routeMetric(outputs, m, nil))
+
+	// the metrics not selected by the other outputs are sent to the fallback
+	// outputs, and dropped by the filters of the other outputs
+	m = testutil.MustMetric("cpu", map[string]string{"env": "dev"}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	require.Equal(t, []*models.RunningOutput{prod, debug}, routeMetric(outputs, m, nil))
+}
+
+func TestFlushPacer(t *testing.T) {
+	output := models.NewRunningOutput("circonus", &discard.Discard{}, &models.OutputConfig{Name: "circonus"}, 0, 0)
+	require.Nil(t, newFlushPacer(output, time.Second))
+	var pacer *flushPacer
+	require.False(t, pacer.skip(time.Now()))
+
+	output = models.NewRunningOutput("circonus", &discard.Discard{}, &models.OutputConfig{
+		Name:          "circonus",
+		AdaptiveFlush: models.AdaptiveFlushConfig{Enabled: true},
+	}, 0, 0)
+	pacer = newFlushPacer(output, 100*time.Millisecond)
+	require.Equal(t, 400*time.Millisecond, pacer.max)
+
+	slow := func() error {
+		time.Sleep(60 * time.Millisecond)
+		return nil
+	}
+	fast := func() error { return nil }
+
+	// slow flushes stretch the interval up to the max
+	for _, stretch := range []time.Duration{200, 400, 400} {
+		start := time.Now()
+		require.NoError(t, pacer.flush(output, slow))
+		require.Equal(t, stretch*time.Millisecond, pacer.stretch)
+		require.True(t, pacer.skip(start.Add(100*time.Millisecond)))
+		require.False(t, pacer.skip(start.Add(pacer.stretch)))
+	}
+
+	// fast flushes shrink it back
+	for _, stretch := range []time.Duration{200, 100, 100} {
+		require.NoError(t, pacer.flush(output, fast))
+		require.Equal(t, stretch*time.Millisecond, pacer.stretch)
+	}
+	require.False(t, pacer.skip(time.Now().Add(50*time.Millisecond)))
+}
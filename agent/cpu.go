@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal/cgroup"
+)
+
+// tuneCPU sets GOMAXPROCS to max_procs or to the CPU limit of the cgroup,
+// so a container with a small CPU quota is not scheduled as if it owned all
+// the CPUs of the host.  The GOMAXPROCS environment variable takes
+// precedence.  Plugins defaulting their concurrency to the number of CPUs
+// use cgroup.AvailableCPUs as well.
+func (a *Agent) tuneCPU() {
+	if os.Getenv("GOMAXPROCS") != "" {
+		log.Printf("D! [agent] GOMAXPROCS environment variable set, ignoring max_procs")
+		return
+	}
+
+	procs := a.Config.Agent.MaxProcs
+	if procs <= 0 {
+		procs = cgroup.AvailableCPUs()
+	}
+	if procs == runtime.GOMAXPROCS(0) {
+		return
+	}
+	runtime.GOMAXPROCS(procs)
+	log.Printf("I! [agent] GOMAXPROCS set to %d", procs)
+}
@@ -0,0 +1,25 @@
+package agent
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_TuneCPU(t *testing.T) {
+	if os.Getenv("GOMAXPROCS") != "" {
+		t.Skip("GOMAXPROCS environment variable set")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	c := config.NewConfig()
+	c.Agent.MaxProcs = 1
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	a.tuneCPU()
+	require.Equal(t, 1, runtime.GOMAXPROCS(0))
+}
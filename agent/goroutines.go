@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"runtime/pprof"
+	"strconv"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+// pluginLabel is the profiler label of the goroutines run for a plugin.  The
+// goroutines started by a labeled goroutine inherit its labels, the
+// goroutines of a plugin are counted from the goroutine profile.
+const pluginLabel = "plugin"
+
+var (
+	profileCountRe = regexp.MustCompile(`^(\d+) @`)
+	profileLabelRe = regexp.MustCompile(`"` + pluginLabel + `":("(?:[^"\\]|\\.)*")`)
+)
+
+// runLabeled runs f with the goroutine labeled as the plugin.
+func runLabeled(plugin string, f func()) {
+	pprof.Do(context.Background(), pprof.Labels(pluginLabel, plugin), func(context.Context) {
+		f()
+	})
+}
+
+// countGoroutines returns the number of goroutines of each plugin.
+func countGoroutines() (map[string]int, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil, fmt.Errorf("goroutine profile: %w", err)
+	}
+	return parseGoroutineProfile(&buf), nil
+}
+
+// PluginGoroutines returns the number of goroutines of each plugin, for the
+// diagnostics.
+func PluginGoroutines() (map[string]int, error) {
+	return countGoroutines()
+}
+
+// parseGoroutineProfile parses the goroutine profile in the debug=1 format:
+// the stacks are preceded by their number of goroutines, followed by their
+// labels.
+func parseGoroutineProfile(buf *bytes.Buffer) map[string]int {
+	counts := make(map[string]int)
+	count := 0
+	scanner := bufio.NewScanner(buf)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := profileCountRe.FindStringSubmatch(line); m != nil {
+			count, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if m := profileLabelRe.FindStringSubmatch(line); m != nil && count > 0 {
+			if plugin, err := strconv.Unquote(m[1]); err == nil {
+				counts[plugin] += count
+			}
+			count = 0
+		}
+	}
+	return counts
+}
+
+// goroutineStats are the internal_goroutines stats of a plugin.
+type goroutineStats struct {
+	count   selfstat.Stat
+	growing selfstat.Stat
+	leak    selfstat.Stat
+}
+
+// leakDetector flags the plugins whose number of goroutines grew for a
+// number of consecutive checks, without ever decreasing.
+type leakDetector struct {
+	intervals int
+
+	last    map[string]int
+	growing map[string]int
+	stats   map[string]*goroutineStats
+}
+
+func newLeakDetector(intervals int) *leakDetector {
+	return &leakDetector{
+		intervals: intervals,
+		last:      make(map[string]int),
+		growing:   make(map[string]int),
+		stats:     make(map[string]*goroutineStats),
+	}
+}
+
+// run checks the goroutines each interval until the context is done.
+func (d *leakDetector) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			counts, err := countGoroutines()
+			if err != nil {
+				log.Printf("E! [agent] Counting goroutines: %v", err)
+				continue
+			}
+			d.check(counts)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check updates the stats with the goroutines counted, the plugins not
+// counted have no goroutine left.
+func (d *leakDetector) check(counts map[string]int) {
+	for plugin := range d.last {
+		if _, ok := counts[plugin]; !ok {
+			counts[plugin] = 0
+		}
+	}
+
+	for plugin, count := range counts {
+		last, seen := d.last[plugin]
+		switch {
+		case !seen:
+		case count > last:
+			d.growing[plugin]++
+		case count < last:
+			d.growing[plugin] = 0
+		}
+		d.last[plugin] = count
+
+		stats, ok := d.stats[plugin]
+		if !ok {
+			tags := map[string]string{pluginLabel: plugin}
+			stats = &goroutineStats{
+				count:   selfstat.Register("goroutines", "count", tags),
+				growing: selfstat.Register("goroutines", "growing_intervals", tags),
+				leak:    selfstat.Register("goroutines", "leak_suspected", tags),
+			}
+			d.stats[plugin] = stats
+		}
+		stats.count.Set(int64(count))
+		stats.growing.Set(int64(d.growing[plugin]))
+
+		leak := int64(0)
+		if d.growing[plugin] >= d.intervals {
+			leak = 1
+			if d.growing[plugin] == d.intervals {
+				log.Printf("W! [agent] Goroutines of %s grew for %d intervals, now %d: possible leak",
+					plugin, d.intervals, count)
+			}
+		}
+		stats.leak.Set(leak)
+	}
+}
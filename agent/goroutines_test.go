@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountGoroutines(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	runLabeled("inputs.test", func() {
+		for i := 0; i < 3; i++ {
+			go func() {
+				<-stop
+			}()
+		}
+	})
+
+	require.Eventually(t, func() bool {
+		counts, err := countGoroutines()
+		require.NoError(t, err)
+		return counts["inputs.test"] == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLeakDetector(t *testing.T) {
+	d := newLeakDetector(3)
+	for _, count := range []int{1, 2, 2, 3, 4} {
+		d.check(map[string]int{"inputs.leaky": count, "inputs.steady": 2})
+	}
+	require.Equal(t, int64(4), d.stats["inputs.leaky"].count.Get())
+	require.Equal(t, int64(3), d.stats["inputs.leaky"].growing.Get())
+	require.Equal(t, int64(1), d.stats["inputs.leaky"].leak.Get())
+	require.Equal(t, int64(0), d.stats["inputs.steady"].leak.Get())
+
+	// decreasing resets the suspicion, the plugins not counted have none
+	d.check(map[string]int{"inputs.leaky": 1})
+	require.Equal(t, int64(0), d.stats["inputs.leaky"].growing.Get())
+	require.Equal(t, int64(0), d.stats["inputs.leaky"].leak.Get())
+	require.Equal(t, int64(0), d.stats["inputs.steady"].count.Get())
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/models"
+)
+
+// pluginsStatus is the body of the /plugins endpoint.
+type pluginsStatus struct {
+	Inputs      []models.PluginStatus `json:"inputs"`
+	Processors  []models.PluginStatus `json:"processors"`
+	Aggregators []models.PluginStatus `json:"aggregators"`
+	Outputs     []models.OutputStatus `json:"outputs"`
+}
+
+// startHealthServer listens on health_listen, when set, and serves the health
+// endpoint in the background.  The endpoint is shut down when the context is
+// done or the returned function is called.
+func (a *Agent) startHealthServer(ctx context.Context) (context.CancelFunc, error) {
+	healthCtx, cancel := context.WithCancel(ctx)
+	if a.Config.Agent.HealthListen == "" {
+		return cancel, nil
+	}
+
+	listener, err := net.Listen("tcp", a.Config.Agent.HealthListen)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("health endpoint: %w", err)
+	}
+	go func() {
+		if err := a.serveHealth(healthCtx, listener); err != nil {
+			log.Printf("E! [agent] Error serving health endpoint: %v", err)
+		}
+	}()
+	return cancel, nil
+}
+
+// serveHealth serves the health endpoint until the context is done:
+//
+//   GET /healthz  the agent is running
+//   GET /readyz   the outputs are connected and their buffers below the threshold
+//   GET /plugins  the status of the plugins in JSON
+func (a *Agent) serveHealth(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{
+		Handler:     a.healthHandler(),
+		ReadTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	log.Printf("I! [agent] Health endpoint listening on %s", listener.Addr())
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("health endpoint: %w", err)
+	}
+	return nil
+}
+
+func (a *Agent) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", a.handleReady)
+	mux.HandleFunc("/plugins", a.handlePlugins)
+	return mux
+}
+
+// handleReady answers 503 with the reasons when an output is not connected
+// or its buffer is filled above the threshold.
+func (a *Agent) handleReady(res http.ResponseWriter, req *http.Request) {
+	threshold := a.Config.Agent.HealthBufferThreshold
+	if threshold <= 0 {
+		threshold = 0.9
+	}
+
+	var reasons []string
+	for _, output := range a.Config.Outputs {
+		status := output.Status()
+		if !status.Connected {
+			reasons = append(reasons, fmt.Sprintf("%s: not connected", output.LogName()))
+			continue
+		}
+		if status.BufferLimit > 0 && float64(status.BufferSize)/float64(status.BufferLimit) > threshold {
+			reasons = append(reasons, fmt.Sprintf("%s: buffer at %d of %d metrics",
+				output.LogName(), status.BufferSize, status.BufferLimit))
+		}
+	}
+
+	if len(reasons) > 0 {
+		res.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = res.Write([]byte(strings.Join(reasons, "\n") + "\n"))
+		return
+	}
+	_, _ = res.Write([]byte("ok\n"))
+}
+
+func (a *Agent) handlePlugins(res http.ResponseWriter, req *http.Request) {
+	status := pluginsStatus{
+		Inputs:      []models.PluginStatus{},
+		Processors:  []models.PluginStatus{},
+		Aggregators: []models.PluginStatus{},
+		Outputs:     []models.OutputStatus{},
+	}
+	for _, input := range a.Config.Inputs {
+		status.Inputs = append(status.Inputs, input.Status())
+	}
+	for _, processor := range a.Config.Processors {
+		status.Processors = append(status.Processors, processor.Status())
+	}
+	for _, aggregator := range a.Config.Aggregators {
+		status.Aggregators = append(status.Aggregators, aggregator.Status())
+	}
+	for _, output := range a.Config.Outputs {
+		status.Outputs = append(status.Outputs, output.Status())
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(res)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(status)
+}
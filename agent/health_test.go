@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/models"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type healthInput struct {
+	err error
+}
+
+func (i *healthInput) SampleConfig() string { return "" }
+func (i *healthInput) Description() string  { return "" }
+func (i *healthInput) Gather(acc cua.Accumulator) error {
+	return i.err
+}
+
+type healthOutput struct{}
+
+func (o *healthOutput) SampleConfig() string                    { return "" }
+func (o *healthOutput) Description() string                     { return "" }
+func (o *healthOutput) Connect() error                          { return nil }
+func (o *healthOutput) Close() error                            { return nil }
+func (o *healthOutput) Write(metrics []cua.Metric) (int, error) { return len(metrics), nil }
+
+func TestHealthEndpoint(t *testing.T) {
+	c := config.NewConfig()
+	input := models.NewRunningInput(&healthInput{}, &models.InputConfig{Name: "good"})
+	failing := models.NewRunningInput(&healthInput{err: errors.New("unreachable")}, &models.InputConfig{Name: "bad"})
+	output := models.NewRunningOutput("out", &healthOutput{}, &models.OutputConfig{Name: "out"}, 1, 10)
+	c.Inputs = append(c.Inputs, input, failing)
+	c.Outputs = append(c.Outputs, output)
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(a.healthHandler())
+	defer server.Close()
+	get := func(path string) *http.Response {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := get("/healthz")
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the output is not connected yet
+	resp = get("/readyz")
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	output.SetConnected(true)
+	resp = get("/readyz")
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// a buffer filled above the threshold
+	for i := 0; i < 10; i++ {
+		output.AddMetric(testutil.TestMetric(i))
+	}
+	resp = get("/readyz")
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.NoError(t, output.Write())
+
+	require.NoError(t, input.Gather(&testutil.Accumulator{}))
+	err = failing.Gather(&testutil.Accumulator{})
+	require.Error(t, err)
+	failing.Log().Errorf("Error in plugin: %v", err)
+
+	resp = get("/plugins")
+	defer resp.Body.Close()
+	var status pluginsStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.Len(t, status.Inputs, 2)
+	require.NotNil(t, status.Inputs[0].LastSuccess)
+	require.Empty(t, status.Inputs[0].LastError)
+	require.Nil(t, status.Inputs[1].LastSuccess)
+	require.Equal(t, "Error in plugin: gather (input bad): unreachable", status.Inputs[1].LastError)
+	require.NotNil(t, status.Inputs[1].LastErrorTime)
+	require.Len(t, status.Outputs, 1)
+	require.True(t, status.Outputs[0].Connected)
+	require.Equal(t, 0, status.Outputs[0].BufferSize)
+	require.Equal(t, 10, status.Outputs[0].BufferLimit)
+	require.NotNil(t, status.Outputs[0].LastSuccess)
+}
+
+func TestStartHealthServer(t *testing.T) {
+	c := config.NewConfig()
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	// without health_listen there is nothing to serve
+	stop, err := a.startHealthServer(context.Background())
+	require.NoError(t, err)
+	stop()
+
+	// the address in use fails the start of the agent
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	c.Agent.HealthListen = listener.Addr().String()
+	_, err = a.startHealthServer(context.Background())
+	require.Error(t, err)
+
+	c.Agent.HealthListen = "127.0.0.1:0"
+	stop, err = a.startHealthServer(context.Background())
+	require.NoError(t, err)
+	stop()
+}
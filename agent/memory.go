@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal/cgroup"
+)
+
+// tuneMemory applies the garbage collector settings and allocates the heap
+// ballast.  Settings of the GOGC and GOMEMLIMIT environment variables take
+// precedence.
+func (a *Agent) tuneMemory() error {
+	cfg := a.Config.Agent
+
+	if cfg.MemoryLimitCgroupRatio < 0 || cfg.MemoryLimitCgroupRatio > 1 {
+		return fmt.Errorf("memory_limit_cgroup_ratio must be between 0 and 1, found %v", cfg.MemoryLimitCgroupRatio)
+	}
+
+	if cfg.GOGC != 0 {
+		if os.Getenv("GOGC") != "" {
+			log.Printf("I! [agent] GOGC environment variable set, ignoring gogc")
+		} else {
+			debug.SetGCPercent(cfg.GOGC)
+			log.Printf("I! [agent] GC target percentage set to %d", cfg.GOGC)
+		}
+	}
+
+	limit := cfg.MemoryLimit.Size
+	if limit == 0 && cfg.MemoryLimitCgroupRatio > 0 {
+		cgroupLimit, err := cgroup.MemoryLimit()
+		if err != nil {
+			log.Printf("W! [agent] Unable to read cgroup memory limit: %s", err)
+		} else if cgroupLimit > 0 {
+			limit = int64(float64(cgroupLimit) * cfg.MemoryLimitCgroupRatio)
+			log.Printf("D! [agent] Cgroup memory limit is %d bytes", cgroupLimit)
+		}
+	}
+	if limit > 0 {
+		switch {
+		case os.Getenv("GOMEMLIMIT") != "":
+			log.Printf("I! [agent] GOMEMLIMIT environment variable set, ignoring memory limit")
+		case !setMemoryLimit(limit):
+			log.Printf("W! [agent] Memory limit is not supported by this build, ignoring memory limit")
+		default:
+			log.Printf("I! [agent] Memory limit set to %d bytes", limit)
+		}
+	}
+
+	if cfg.MemoryBallast.Size > 0 {
+		// never written to, the pages are not backed by memory until used
+		a.ballast = make([]byte, cfg.MemoryBallast.Size)
+		log.Printf("I! [agent] Allocated a heap ballast of %d bytes", cfg.MemoryBallast.Size)
+	}
+
+	return nil
+}
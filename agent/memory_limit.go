@@ -0,0 +1,10 @@
+// +build go1.19
+
+package agent
+
+import "runtime/debug"
+
+func setMemoryLimit(limit int64) bool {
+	debug.SetMemoryLimit(limit)
+	return true
+}
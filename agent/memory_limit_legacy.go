@@ -0,0 +1,9 @@
+// +build !go1.19
+
+package agent
+
+// setMemoryLimit is a no-op, the runtime supports a memory limit starting
+// with go1.19.
+func setMemoryLimit(limit int64) bool {
+	return false
+}
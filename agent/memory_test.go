@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"os"
+	"runtime/debug"
+	"testing"
+
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_TuneMemory(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.GOGC = 50
+	c.Agent.MemoryBallast.Size = 1024 * 1024
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	require.NoError(t, a.tuneMemory())
+	require.Len(t, a.ballast, 1024*1024)
+	if os.Getenv("GOGC") == "" {
+		require.Equal(t, 50, debug.SetGCPercent(100))
+	}
+}
+
+func TestAgent_TuneMemoryInvalidRatio(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.MemoryLimitCgroupRatio = 1.5
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.Error(t, a.tuneMemory())
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// schedule is the timezone the collections and flushes are aligned in, and
+// the business days of the metrics.
+type schedule struct {
+	location     *time.Location
+	businessDays map[time.Weekday]bool
+}
+
+func newSchedule(cfg *config.AgentConfig) (*schedule, error) {
+	s := &schedule{location: time.UTC}
+	if cfg.Timezone != "" {
+		location, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+		s.location = location
+	}
+
+	if cfg.BusinessDayTag == "" {
+		return s, nil
+	}
+	s.businessDays = make(map[time.Weekday]bool)
+	days := cfg.BusinessDays
+	if len(days) == 0 {
+		days = []string{"mon", "tue", "wed", "thu", "fri"}
+	}
+	for _, day := range days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("business_days: unknown day %q, expected one of mon, tue, wed, thu, fri, sat or sun", day)
+		}
+		s.businessDays[weekday] = true
+	}
+	return s, nil
+}
+
+// isBusinessDay returns whether the time is on a business day in the
+// timezone.
+func (s *schedule) isBusinessDay(t time.Time) bool {
+	return s.businessDays[t.In(s.location).Weekday()]
+}
+
+// location returns the timezone the collections and flushes are aligned in.
+func (a *Agent) location() *time.Location {
+	if a.schedule == nil {
+		return time.UTC
+	}
+	return a.schedule.location
+}
+
+// tagBusinessDay sets the business day tag of the metric, when enabled.
+func (a *Agent) tagBusinessDay(m cua.Metric) {
+	if a.schedule == nil || a.schedule.businessDays == nil {
+		return
+	}
+	if a.schedule.isBusinessDay(m.Time()) {
+		m.AddTag(a.Config.Agent.BusinessDayTag, "true")
+	} else {
+		m.AddTag(a.Config.Agent.BusinessDayTag, "false")
+	}
+}
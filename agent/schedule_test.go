@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleBusinessDayTag(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Timezone = "America/New_York"
+	c.Agent.BusinessDayTag = "business_day"
+	a, err := NewAgent(c)
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+
+	// friday evening in New York, saturday in UTC
+	friday := testutil.MustMetric("cpu", nil, map[string]interface{}{"usage": 1.0},
+		time.Date(2021, 6, 4, 22, 0, 0, 0, a.location()))
+	a.tagBusinessDay(friday)
+	tag, _ := friday.GetTag("business_day")
+	require.Equal(t, "true", tag)
+
+	saturday := testutil.MustMetric("cpu", nil, map[string]interface{}{"usage": 1.0},
+		time.Date(2021, 6, 5, 9, 0, 0, 0, a.location()))
+	a.tagBusinessDay(saturday)
+	tag, _ = saturday.GetTag("business_day")
+	require.Equal(t, "false", tag)
+}
+
+func TestScheduleBusinessDays(t *testing.T) {
+	s, err := newSchedule(&config.AgentConfig{BusinessDayTag: "business_day", BusinessDays: []string{"Sun", "thu"}})
+	require.NoError(t, err)
+	require.Equal(t, time.UTC, s.location)
+	require.True(t, s.isBusinessDay(time.Date(2021, 6, 6, 12, 0, 0, 0, time.UTC)))
+	require.True(t, s.isBusinessDay(time.Date(2021, 6, 3, 12, 0, 0, 0, time.UTC)))
+	require.False(t, s.isBusinessDay(time.Date(2021, 6, 4, 12, 0, 0, 0, time.UTC)))
+
+	// no tag without business_day_tag
+	s, err = newSchedule(&config.AgentConfig{})
+	require.NoError(t, err)
+	require.Nil(t, s.businessDays)
+}
+
+func TestScheduleErrors(t *testing.T) {
+	_, err := newSchedule(&config.AgentConfig{Timezone: "Mars/Olympus_Mons"})
+	require.Error(t, err)
+
+	_, err = newSchedule(&config.AgentConfig{BusinessDayTag: "business_day", BusinessDays: []string{"monday"}})
+	require.Error(t, err)
+}
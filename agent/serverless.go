@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal/serverless"
+	"github.com/circonus-labs/circonus-unified-agent/models"
+)
+
+const (
+	// telemetryAddr is the address of the Telemetry API listener, the API
+	// reaches the extensions through the sandbox.localdomain host.
+	telemetryAddr = "0.0.0.0:8187"
+	telemetryHost = "sandbox.localdomain"
+
+	// deadlineMargin is kept before the deadline of an invocation to flush
+	// the metrics.
+	deadlineMargin = 200 * time.Millisecond
+)
+
+// RunServerless runs the agent until the context is done as a serverless
+// extension: the metrics of the function are received on the ingestion
+// endpoint and the outputs are flushed before the execution environment is
+// frozen.
+//
+// In the AWS Lambda execution environment the agent registers as a Lambda
+// extension and flushes at the end of each invocation.  Elsewhere, such as a
+// Cloud Run sidecar, the function requests the flushes from the ingestion
+// endpoint and the agent flushes on shutdown.
+func (a *Agent) RunServerless(ctx context.Context) error {
+	a.serverless = true
+
+	ingest := newIngestInput(a.Config.Agent.ServerlessListen, a.Flush)
+	a.Config.Inputs = append(a.Config.Inputs, models.NewRunningInput(ingest, &models.InputConfig{
+		Name: "serverless",
+	}))
+
+	api := os.Getenv(serverless.RuntimeAPIEnv)
+	if api == "" {
+		return a.Run(ctx)
+	}
+
+	// the extension registers before the plugins start, the registration
+	// must happen during the initialization of the execution environment
+	ext := serverless.NewExtension(api, filepath.Base(os.Args[0]))
+	if err := ext.Register(ctx); err != nil {
+		return fmt.Errorf("lambda extension: %w", err)
+	}
+
+	telemetry, err := serverless.NewTelemetryListener(telemetryAddr, telemetryHost)
+	if err == nil {
+		defer telemetry.Close()
+		err = ext.SubscribeTelemetry(ctx, telemetry)
+	}
+	if err != nil {
+		// without the telemetry the end of the invocations is not known,
+		// the metrics are flushed when the next invocation starts
+		log.Printf("W! [agent] Telemetry API unavailable, flushing at the start of the invocations: %v", err)
+		telemetry = nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- a.Run(ctx)
+	}()
+
+	err = a.lambdaLoop(ctx, ext, telemetry)
+	// stopping the agent flushes the outputs one last time
+	cancel()
+	if rerr := <-runErr; rerr != nil && !errors.Is(rerr, context.Canceled) {
+		return rerr
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// lambdaLoop processes the events of the extension until the shutdown.
+func (a *Agent) lambdaLoop(ctx context.Context, ext *serverless.Extension, telemetry *serverless.TelemetryListener) error {
+	for {
+		event, err := ext.Next(ctx)
+		if err != nil {
+			return fmt.Errorf("lambda extension: %w", err)
+		}
+
+		switch event.EventType {
+		case serverless.EventInvoke:
+			if telemetry != nil {
+				waitRuntimeDone(ctx, telemetry, event)
+			}
+			flushCtx, cancel := context.WithDeadline(ctx, event.Deadline())
+			if err := a.Flush(flushCtx); err != nil {
+				log.Printf("E! [agent] Flushing invocation %s: %v", event.RequestID, err)
+			}
+			cancel()
+		case serverless.EventShutdown:
+			log.Printf("I! [agent] Lambda shutdown: %s", event.ShutdownReason)
+			return nil
+		}
+	}
+}
+
+// waitRuntimeDone waits for the function to complete the invocation of the
+// event, or until shortly before its deadline.
+func waitRuntimeDone(ctx context.Context, telemetry *serverless.TelemetryListener, event *serverless.Event) {
+	timer := time.NewTimer(time.Until(event.Deadline().Add(-deadlineMargin)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case id := <-telemetry.RuntimeDone():
+			if id == event.RequestID {
+				return
+			}
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Flush writes the metrics buffered by the outputs and returns once the
+// writes complete.  The metrics still in the processors are waited for, the
+// metrics held by the aggregators until the end of their period are not
+// written.
+func (a *Agent) Flush(ctx context.Context) error {
+	if a.outputsReady == nil {
+		return fmt.Errorf("agent not running")
+	}
+	select {
+	case <-a.outputsReady:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for outputs: %w", ctx.Err())
+	}
+
+	if err := a.waitPipeline(ctx); err != nil {
+		return err
+	}
+
+	a.flushMu.Lock()
+	requests := a.flushRequests
+	a.flushMu.Unlock()
+
+	results := make([]chan error, 0, len(requests))
+	for _, request := range requests {
+		done := make(chan error, 1)
+		select {
+		case request <- done:
+			results = append(results, done)
+		case <-ctx.Done():
+			return fmt.Errorf("requesting flush: %w", ctx.Err())
+		}
+	}
+
+	var errs []error
+	for _, done := range results {
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for flush: %w", ctx.Err())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d outputs failed, first error: %w", len(errs), errs[0])
+	}
+	return nil
+}
+
+// pipelineBarrier is sent through the pipeline behind the metrics to wait
+// for.  The processors and aggregators pass it on without handing it to the
+// plugins, the outputs close done once the metrics sent before it are in
+// their buffers.
+type pipelineBarrier struct {
+	cua.Metric
+	done chan struct{}
+}
+
+// waitPipeline waits for the metrics sent by the inputs to reach the buffers
+// of the outputs, including the metrics being processed.  The metrics emitted
+// later by the processors running in the background are not waited for.
+func (a *Agent) waitPipeline(ctx context.Context) error {
+	barrier := &pipelineBarrier{done: make(chan struct{})}
+
+	// the lock keeps the channel open while the barrier is sent
+	a.flushMu.Lock()
+	if a.pipelineSrc == nil {
+		// stopping, the metrics are flushed on shutdown
+		a.flushMu.Unlock()
+		return nil
+	}
+	select {
+	case a.pipelineSrc <- barrier:
+	case <-ctx.Done():
+		a.flushMu.Unlock()
+		return fmt.Errorf("waiting for the pipeline: %w", ctx.Err())
+	}
+	a.flushMu.Unlock()
+
+	select {
+	case <-barrier.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for the pipeline: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/influx"
+)
+
+// maxIngestBodySize limits the size of a write to the ingestion endpoint.
+const maxIngestBodySize = 32 * 1024 * 1024
+
+// ingestInput is the ingestion endpoint of the serverless mode, it receives
+// the metrics of the function in the line protocol:
+//
+//   POST /write        writes the metrics of the body
+//   POST /write?flush  writes the metrics and flushes the outputs
+//   POST /flush        flushes the outputs
+type ingestInput struct {
+	addr  string
+	flush func(ctx context.Context) error
+
+	mu       sync.Mutex
+	parser   *influx.Parser
+	listener net.Listener
+	server   *http.Server
+	acc      cua.Accumulator
+}
+
+func newIngestInput(addr string, flush func(ctx context.Context) error) *ingestInput {
+	return &ingestInput{
+		addr:   addr,
+		flush:  flush,
+		parser: influx.NewParser(influx.NewMetricHandler()),
+	}
+}
+
+func (i *ingestInput) Description() string {
+	return "Serverless mode ingestion endpoint"
+}
+
+func (i *ingestInput) SampleConfig() string {
+	return ""
+}
+
+func (i *ingestInput) Gather(_ cua.Accumulator) error {
+	return nil
+}
+
+func (i *ingestInput) Start(acc cua.Accumulator) error {
+	listener, err := net.Listen("tcp", i.addr)
+	if err != nil {
+		return fmt.Errorf("serverless ingestion endpoint: %w", err)
+	}
+	i.listener = listener
+	i.acc = acc
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/write", i.handleWrite)
+	mux.HandleFunc("/flush", i.handleFlush)
+	i.server = &http.Server{
+		Handler:     mux,
+		ReadTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := i.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! [agent] Serverless ingestion endpoint: %v", err)
+		}
+	}()
+
+	log.Printf("I! [agent] Serverless ingestion endpoint listening on %s", listener.Addr())
+	return nil
+}
+
+func (i *ingestInput) Stop() {
+	if i.server != nil {
+		_ = i.server.Close()
+	}
+}
+
+func (i *ingestInput) handleWrite(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxIngestBodySize+1))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxIngestBodySize {
+		res.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	i.mu.Lock()
+	metrics, err := i.parser.Parse(body)
+	i.mu.Unlock()
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, m := range metrics {
+		i.acc.AddMetric(m)
+	}
+
+	if _, ok := req.URL.Query()["flush"]; ok {
+		i.handleFlush(res, req)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (i *ingestInput) handleFlush(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := i.flush(req.Context()); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/models"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs/discard"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestInputWrite(t *testing.T) {
+	flushes := 0
+	i := newIngestInput("127.0.0.1:0", func(ctx context.Context) error {
+		flushes++
+		return nil
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Start(&acc))
+	defer i.Stop()
+	url := "http://" + i.listener.Addr().String()
+
+	resp, err := http.Post(url+"/write", "text/plain", strings.NewReader("cpu,host=a value=42 1600000000000000000\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, 0, flushes)
+
+	resp, err = http.Post(url+"/write?flush", "text/plain", strings.NewReader("cpu,host=b value=43 1600000000000000000\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, 1, flushes)
+
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{"value": 42.0}, map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "cpu", map[string]interface{}{"value": 43.0}, map[string]string{"host": "b"})
+
+	resp, err = http.Post(url+"/write", "text/plain", strings.NewReader("not line protocol"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(url + "/write")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestIngestInputFlushError(t *testing.T) {
+	i := newIngestInput("127.0.0.1:0", func(ctx context.Context) error {
+		return errors.New("output down")
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Start(&acc))
+	defer i.Stop()
+
+	resp, err := http.Post("http://"+i.listener.Addr().String()+"/flush", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+// slowProcessor holds each metric for a while, as a processor calling an
+// external service.
+type slowProcessor struct{}
+
+func (p *slowProcessor) SampleConfig() string { return "" }
+func (p *slowProcessor) Description() string  { return "" }
+func (p *slowProcessor) Apply(in ...cua.Metric) []cua.Metric {
+	time.Sleep(50 * time.Millisecond)
+	return in
+}
+
+func TestWaitPipeline(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+	// stopped, nothing to wait for
+	require.NoError(t, a.waitPipeline(context.Background()))
+
+	src := make(chan cua.Metric, 10)
+	processed := make(chan cua.Metric, 10)
+	units := []*processorUnit{{
+		src: src,
+		dst: processed,
+		processor: models.NewRunningProcessor(processors.NewStreamingProcessorFromProcessor(&slowProcessor{}),
+			&models.ProcessorConfig{Name: "slow"}),
+	}}
+	output := models.NewRunningOutput("discard", &discard.Discard{}, &models.OutputConfig{Name: "discard"}, 10, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.runProcessors(units)
+	}()
+	go a.runOutputs(&outputUnit{src: processed, outputs: []*models.RunningOutput{output}})
+	a.pipelineSrc = src
+
+	// the metrics held by the processor are waited for, the channels are
+	// empty while it holds them
+	src <- testutil.TestMetric(1)
+	src <- testutil.TestMetric(2)
+	require.NoError(t, a.waitPipeline(context.Background()))
+	require.Equal(t, 2, output.BufferLength())
+
+	src <- testutil.TestMetric(3)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Error(t, a.waitPipeline(ctx))
+
+	close(src)
+	<-done
+}
+
+func TestFlushRequests(t *testing.T) {
+	a := &Agent{outputsReady: make(chan struct{})}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	require.Error(t, a.Flush(ctx))
+	cancel()
+
+	request := make(chan chan error)
+	a.flushRequests = []chan chan error{request}
+	close(a.outputsReady)
+	go func() {
+		done := <-request
+		done <- errors.New("write failed")
+	}()
+	err := a.Flush(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "write failed")
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/models"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyOutput fails the writes until failures is 0, and blocks them while
+// block is set.
+type flakyOutput struct {
+	failures int32
+	block    chan struct{}
+	written  int32
+}
+
+func (o *flakyOutput) SampleConfig() string { return "" }
+func (o *flakyOutput) Description() string  { return "" }
+func (o *flakyOutput) Connect() error       { return nil }
+func (o *flakyOutput) Close() error         { return nil }
+func (o *flakyOutput) Write(metrics []cua.Metric) (int, error) {
+	if o.block != nil {
+		<-o.block
+	}
+	if atomic.AddInt32(&o.failures, -1) >= 0 {
+		return 0, errors.New("unavailable")
+	}
+	atomic.AddInt32(&o.written, int32(len(metrics)))
+	return len(metrics), nil
+}
+
+func TestFinalFlush(t *testing.T) {
+	defer func(d time.Duration) { shutdownRetryInterval = d }(shutdownRetryInterval)
+	shutdownRetryInterval = time.Millisecond
+
+	newOutput := func(o *flakyOutput) *models.RunningOutput {
+		output := models.NewRunningOutput("flaky", o, &models.OutputConfig{Name: "flaky"}, 2, 100)
+		for i := 0; i < 5; i++ {
+			output.AddMetric(testutil.TestMetric(i))
+		}
+		return output
+	}
+	c := config.NewConfig()
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	ticker := NewRollingTicker(time.Hour, 0)
+	defer ticker.Stop()
+
+	// without a timeout the buffer is flushed once
+	o := &flakyOutput{failures: 1}
+	output := newOutput(o)
+	require.Error(t, a.finalFlush(output, ticker))
+	require.Equal(t, 5, output.BufferLength())
+
+	// the failed writes are retried until the deadline
+	c.Agent.ShutdownTimeout.Duration = time.Minute
+	o = &flakyOutput{failures: 3}
+	output = newOutput(o)
+	require.NoError(t, a.finalFlush(output, ticker))
+	require.Equal(t, int32(5), atomic.LoadInt32(&o.written))
+	require.Zero(t, output.BufferLength())
+
+	// a hanging write is abandoned at the deadline
+	c.Agent.ShutdownTimeout.Duration = 10 * time.Millisecond
+	o = &flakyOutput{block: make(chan struct{})}
+	defer close(o.block)
+	output = newOutput(o)
+	start := time.Now()
+	require.Error(t, a.finalFlush(output, ticker))
+	require.True(t, time.Since(start) < time.Minute)
+}
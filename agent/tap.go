@@ -0,0 +1,335 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/models"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/influx"
+)
+
+const (
+	// metrics buffered for a tap client, the following metrics are dropped
+	// until the client catches up
+	tapBufferSize = 1000
+	// time allowed to a client to send its request
+	tapRequestTimeout = 5 * time.Second
+)
+
+// tapRequest is the request sent by a client of the control socket.
+type tapRequest struct {
+	Command string   `json:"command"`
+	Filters []string `json:"filters"`
+}
+
+// tapResponse is the response of the control socket to a request, followed
+// by the metrics in the line protocol when there is no error.
+type tapResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// tapCondition is a condition of a tap filter, such as "measurement=~cpu.*".
+type tapCondition struct {
+	key    string
+	negate bool
+	value  string
+	regex  *regexp.Regexp
+}
+
+// parseTapFilter parses the conditions of a tap filter, a metric matches
+// the filter when it matches all the conditions.  The conditions compare a
+// value with "=", "!=", "=~" or "!~" for a regular expression, the value is
+// the measurement with the "measurement" key, the input plugin with "input",
+// the field keys with "field", and the value of the tag otherwise.
+func parseTapFilter(exprs []string) ([]tapCondition, error) {
+	conditions := make([]tapCondition, 0, len(exprs))
+	for _, expr := range exprs {
+		i := strings.IndexAny(expr, "=!")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid filter %q, expected <key><operator><value>", expr)
+		}
+
+		c := tapCondition{key: strings.TrimSpace(expr[:i])}
+		op := expr[i:]
+		switch {
+		case strings.HasPrefix(op, "=~"), strings.HasPrefix(op, "!~"):
+			c.negate = op[0] == '!'
+			regex, err := regexp.Compile(op[2:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+			}
+			c.regex = regex
+		case strings.HasPrefix(op, "!="):
+			c.negate = true
+			c.value = op[2:]
+		case strings.HasPrefix(op, "="):
+			c.value = op[1:]
+		default:
+			return nil, fmt.Errorf("invalid filter %q, unknown operator", expr)
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+func (c tapCondition) matchValue(value string) bool {
+	if c.regex != nil {
+		return c.regex.MatchString(value)
+	}
+	return c.value == value
+}
+
+func (c tapCondition) match(m cua.Metric) bool {
+	var matched bool
+	switch c.key {
+	case "measurement", "name":
+		matched = c.matchValue(m.Name())
+	case "input":
+		matched = c.matchValue(m.Origin())
+	case "field":
+		for _, field := range m.FieldList() {
+			if c.matchValue(field.Key) {
+				matched = true
+				break
+			}
+		}
+	default:
+		value, _ := m.GetTag(c.key)
+		matched = c.matchValue(value)
+	}
+	return matched != c.negate
+}
+
+// tapSubscriber is a client of the control socket receiving the metrics
+// matching its filter.
+type tapSubscriber struct {
+	conditions []tapCondition
+	metrics    chan cua.Metric
+	dropped    uint64
+}
+
+func (s *tapSubscriber) match(m cua.Metric) bool {
+	for _, c := range s.conditions {
+		if !c.match(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// tap publishes copies of the metrics written to the outputs to the
+// subscribers, without ever blocking the pipeline.
+type tap struct {
+	// number of subscribers, checked without locking for each metric
+	active      int32
+	mu          sync.Mutex
+	subscribers map[*tapSubscriber]struct{}
+}
+
+func newTap() *tap {
+	return &tap{subscribers: map[*tapSubscriber]struct{}{}}
+}
+
+func (t *tap) subscribe(conditions []tapCondition) *tapSubscriber {
+	s := &tapSubscriber{
+		conditions: conditions,
+		metrics:    make(chan cua.Metric, tapBufferSize),
+	}
+	t.mu.Lock()
+	t.subscribers[s] = struct{}{}
+	atomic.StoreInt32(&t.active, int32(len(t.subscribers)))
+	t.mu.Unlock()
+	return s
+}
+
+func (t *tap) unsubscribe(s *tapSubscriber) {
+	t.mu.Lock()
+	delete(t.subscribers, s)
+	atomic.StoreInt32(&t.active, int32(len(t.subscribers)))
+	t.mu.Unlock()
+}
+
+func (t *tap) publish(m cua.Metric) {
+	if t == nil || atomic.LoadInt32(&t.active) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for s := range t.subscribers {
+		if !s.match(m) {
+			continue
+		}
+		select {
+		case s.metrics <- m.Copy():
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// serveControl accepts the clients of the control socket until the context
+// is done.
+func (a *Agent) serveControl(ctx context.Context, path string) error {
+	// remove the socket left by a previous run
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listen (%s): %w", path, err)
+	}
+	if err := os.Chmod(path, 0660); err != nil {
+		listener.Close()
+		return fmt.Errorf("chmod (%s): %w", path, err)
+	}
+	log.Printf("I! [agent] Control socket listening on %s", path)
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.handleControl(ctx, conn); err != nil {
+				log.Printf("D! [agent] Control socket client: %v", err)
+			}
+		}()
+	}
+}
+
+func (a *Agent) handleControl(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+
+	encoder := json.NewEncoder(conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(tapRequestTimeout))
+	var req tapRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		return fmt.Errorf("read request: %w", err)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	if req.Command != "tap" {
+		_ = encoder.Encode(tapResponse{Error: fmt.Sprintf("unknown command %q", req.Command)})
+		return nil
+	}
+	conditions, err := parseTapFilter(req.Filters)
+	if err != nil {
+		_ = encoder.Encode(tapResponse{Error: err.Error()})
+		return nil
+	}
+	if err := encoder.Encode(tapResponse{}); err != nil {
+		return fmt.Errorf("write response: %w", err)
+	}
+
+	s := a.tap.subscribe(conditions)
+	defer a.tap.unsubscribe(s)
+
+	// the client does not send anything else, a read returns when it
+	// disconnects
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	serializer := influx.NewSerializer()
+	serializer.Log = models.NewLogger("serializers", "influx", "")
+	serializer.SetFieldSortOrder(influx.SortFields)
+	var dropped uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-closed:
+			return nil
+		case m := <-s.metrics:
+			octets, err := serializer.Serialize(m)
+			if err != nil {
+				continue
+			}
+			if n := atomic.LoadUint64(&s.dropped); n != dropped {
+				octets = append([]byte(fmt.Sprintf("# %d metrics dropped, the client is not reading fast enough\n", n-dropped)), octets...)
+				dropped = n
+			}
+			if _, err := conn.Write(octets); err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+		}
+	}
+}
+
+// Tap connects to the control socket of a running agent and copies the
+// metrics matching the filter to w until the context is done or the agent
+// stops.
+func Tap(ctx context.Context, path string, filters []string, w io.Writer) error {
+	if _, err := parseTapFilter(filters); err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return fmt.Errorf("connect (%s): %w", path, err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if err := json.NewEncoder(conn).Encode(tapRequest{Command: "tap", Filters: filters}); err != nil {
+		return fmt.Errorf("write request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	var resp tapResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s", resp.Error)
+	}
+
+	if _, err := io.Copy(w, reader); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("read metrics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTapFilter(t *testing.T) {
+	m := testutil.MustMetric("nstat",
+		map[string]string{"name": "netstat", "host": "a"},
+		map[string]interface{}{"TcpInSegs": 42},
+		time.Unix(0, 0))
+
+	tests := []struct {
+		filters []string
+		match   bool
+	}{
+		{nil, true},
+		{[]string{"measurement=nstat"}, true},
+		{[]string{"measurement=~nst.*"}, true},
+		{[]string{"measurement!~nst.*"}, false},
+		{[]string{"measurement!=cpu"}, true},
+		{[]string{"host=a", "field=~^Tcp"}, true},
+		{[]string{"host=a", "field=UdpInDatagrams"}, false},
+		{[]string{"host!=a"}, false},
+		{[]string{"missing="}, true},
+		{[]string{"missing=x"}, false},
+	}
+	for _, tt := range tests {
+		conditions, err := parseTapFilter(tt.filters)
+		require.NoError(t, err)
+		s := &tapSubscriber{conditions: conditions}
+		require.Equal(t, tt.match, s.match(m), "%v", tt.filters)
+	}
+
+	for _, filter := range []string{"measurement", "=cpu", "measurement=~(", "host!cpu"} {
+		_, err := parseTapFilter([]string{filter})
+		require.Error(t, err, filter)
+	}
+}
+
+func TestTapPublishDrops(t *testing.T) {
+	tp := newTap()
+	// no subscribers
+	tp.publish(testutil.TestMetric(1))
+
+	conditions, err := parseTapFilter([]string{"measurement=test1"})
+	require.NoError(t, err)
+	s := tp.subscribe(conditions)
+	for i := 0; i < tapBufferSize+10; i++ {
+		tp.publish(testutil.TestMetric(i))
+		tp.publish(testutil.TestMetric(i, "other"))
+	}
+	require.Len(t, s.metrics, tapBufferSize)
+	require.Equal(t, uint64(10), s.dropped)
+
+	tp.unsubscribe(s)
+	require.Equal(t, int32(0), tp.active)
+}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestTapControlSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+	a.tap = newTap()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- a.serveControl(ctx, path)
+	}()
+
+	var out syncBuffer
+	tapCtx, stopTap := context.WithCancel(ctx)
+	tapped := make(chan error, 1)
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	// invalid filters are rejected by the client
+	require.Error(t, Tap(ctx, path, []string{"measurement"}, &syncBuffer{}))
+
+	go func() {
+		tapped <- Tap(tapCtx, path, []string{"measurement=cpu"}, &out)
+	}()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&a.tap.active) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	a.tap.publish(testutil.MustMetric("mem", nil, map[string]interface{}{"used": 1}, time.Unix(0, 0)))
+	a.tap.publish(testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 99.5}, time.Unix(0, 0)))
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "\n")
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "cpu,cpu=cpu0 usage_idle=99.5 0\n", out.String())
+
+	stopTap()
+	require.NoError(t, <-tapped)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&a.tap.active) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-served)
+}
+
+func TestTapUnknownCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+	a.tap = newTap()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = a.serveControl(ctx, path)
+	}()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(`{"command":"reload"}` + "\n"))
+	require.NoError(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, `{"error":"unknown command \"reload\""}`+"\n", line)
+}
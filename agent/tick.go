@@ -23,8 +23,14 @@ type Ticker interface {
 // the interval.  However the overall pace of is that of the interval, so on
 // average you will have one collection each interval.
 //
+// The ticks may be shifted by a fixed offset from the alignment, so that a 1m
+// interval with a 10s offset ticks at 10s past each minute.
+//
 // The first tick is emitted at the next alignment.
 //
+// The ticks are aligned on the wall clock of the location, so a 24h interval
+// ticks at midnight in the location even across daylight saving time changes.
+//
 // Ticks are dropped for slow consumers.
 //
 // The implementation currently does not recalculate until the next tick with
@@ -33,22 +39,26 @@ type Ticker interface {
 type AlignedTicker struct {
 	interval    time.Duration
 	jitter      time.Duration
+	offset      time.Duration
 	minInterval time.Duration
+	location    *time.Location
 	ch          chan time.Time
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 }
 
-func NewAlignedTicker(now time.Time, interval, jitter time.Duration) *AlignedTicker {
-	return newAlignedTicker(now, interval, jitter, clock.New())
+func NewAlignedTicker(now time.Time, interval, jitter, offset time.Duration, location *time.Location) *AlignedTicker {
+	return newAlignedTicker(now, interval, jitter, offset, location, clock.New())
 }
 
-func newAlignedTicker(now time.Time, interval, jitter time.Duration, clock clock.Clock) *AlignedTicker {
+func newAlignedTicker(now time.Time, interval, jitter, offset time.Duration, location *time.Location, clock clock.Clock) *AlignedTicker {
 	ctx, cancel := context.WithCancel(context.Background())
 	t := &AlignedTicker{
 		interval:    interval,
 		jitter:      jitter,
+		offset:      offset,
 		minInterval: interval / 100,
+		location:    location,
 		ch:          make(chan time.Time, 1),
 		cancel:      cancel,
 	}
@@ -69,9 +79,9 @@ func (t *AlignedTicker) next(now time.Time) time.Duration {
 	// Add minimum interval size to avoid scheduling an interval that is
 	// exceptionally short.  This avoids an issue that can occur where the
 	// previous interval ends slightly early due to very minor clock changes.
-	next := now.Add(t.minInterval)
+	next := now.Add(t.minInterval - t.offset)
 
-	next = internal.AlignTime(next, t.interval)
+	next = internal.AlignTimeIn(next, t.interval, t.location).Add(t.offset)
 	d := next.Sub(now)
 	if d == 0 {
 		d = t.interval
@@ -114,37 +124,60 @@ func (t *AlignedTicker) Stop() {
 // the interval.  However the overall pace of is that of the interval, so on
 // average you will have one collection each interval.
 //
-// The first tick is emitted immediately.
+// The ticks may be delayed by a fixed offset.
+//
+// The first tick is emitted immediately, or after the offset.
 //
 // Ticks are dropped for slow consumers.
 type UnalignedTicker struct {
 	interval time.Duration
 	jitter   time.Duration
+	offset   time.Duration
 	ch       chan time.Time
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 }
 
-func NewUnalignedTicker(interval, jitter time.Duration) *UnalignedTicker {
-	return newUnalignedTicker(interval, jitter, clock.New())
+func NewUnalignedTicker(interval, jitter, offset time.Duration) *UnalignedTicker {
+	return newUnalignedTicker(interval, jitter, offset, clock.New())
 }
 
-func newUnalignedTicker(interval, jitter time.Duration, clock clock.Clock) *UnalignedTicker {
+func newUnalignedTicker(interval, jitter, offset time.Duration, clock clock.Clock) *UnalignedTicker {
 	ctx, cancel := context.WithCancel(context.Background())
 	t := &UnalignedTicker{
 		interval: interval,
 		jitter:   jitter,
+		offset:   offset,
 		ch:       make(chan time.Time, 1),
 		cancel:   cancel,
 	}
 
-	ticker := clock.Ticker(t.interval)
-	t.ch <- clock.Now()
+	if t.offset == 0 {
+		ticker := clock.Ticker(t.interval)
+		t.ch <- clock.Now()
 
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.run(ctx, ticker, clock)
+		}()
+		return t
+	}
+
+	// the ticks start after the offset
+	timer := clock.Timer(t.offset)
 	t.wg.Add(1)
 	go func() {
 		defer t.wg.Done()
-		t.run(ctx, ticker, clock)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			ticker := clock.Ticker(t.interval)
+			t.ch <- now
+			t.run(ctx, ticker, clock)
+		}
 	}()
 
 	return t
@@ -20,7 +20,7 @@ func TestAlignedTicker(t *testing.T) {
 	since := clock.Now()
 	until := since.Add(60 * time.Second)
 
-	ticker := newAlignedTicker(since, interval, jitter, clock)
+	ticker := newAlignedTicker(since, interval, jitter, 0, nil, clock)
 	defer ticker.Stop()
 
 	expected := []time.Time{
@@ -52,7 +52,7 @@ func TestAlignedTickerJitter(t *testing.T) {
 	since := clock.Now()
 	until := since.Add(61 * time.Second)
 
-	ticker := newAlignedTicker(since, interval, jitter, clock)
+	ticker := newAlignedTicker(since, interval, jitter, 0, nil, clock)
 	defer ticker.Stop()
 
 	last := since
@@ -70,6 +70,61 @@ func TestAlignedTickerJitter(t *testing.T) {
 	}
 }
 
+func TestAlignedTickerOffset(t *testing.T) {
+	interval := 10 * time.Second
+	jitter := 0 * time.Second
+	offset := 3 * time.Second
+
+	clock := clock.NewMock()
+	since := clock.Now()
+	until := since.Add(60 * time.Second)
+
+	ticker := newAlignedTicker(since, interval, jitter, offset, nil, clock)
+	defer ticker.Stop()
+
+	expected := []time.Time{
+		time.Unix(3, 0).UTC(),
+		time.Unix(13, 0).UTC(),
+		time.Unix(23, 0).UTC(),
+		time.Unix(33, 0).UTC(),
+		time.Unix(43, 0).UTC(),
+		time.Unix(53, 0).UTC(),
+	}
+
+	actual := []time.Time{}
+	for !clock.Now().After(until) {
+		select {
+		case tm := <-ticker.Elapsed():
+			actual = append(actual, tm.UTC())
+		default:
+		}
+		clock.Add(1 * time.Second)
+	}
+
+	require.Equal(t, expected, actual)
+}
+
+func TestUnalignedTickerOffset(t *testing.T) {
+	interval := 10 * time.Second
+	jitter := 0 * time.Second
+	offset := 3 * time.Second
+
+	clock := clock.NewMock()
+	ticker := newUnalignedTicker(interval, jitter, offset, clock)
+	defer ticker.Stop()
+
+	// the first tick is delayed by the offset
+	select {
+	case tm := <-ticker.Elapsed():
+		t.Fatalf("unexpected tick at %s", tm)
+	default:
+	}
+
+	clock.Add(offset)
+	tm := <-ticker.Elapsed()
+	require.Equal(t, time.Unix(3, 0).UTC(), tm.UTC())
+}
+
 func TestAlignedTickerMissedTick(t *testing.T) {
 	interval := 10 * time.Second
 	jitter := 0 * time.Second
@@ -77,7 +132,7 @@ func TestAlignedTickerMissedTick(t *testing.T) {
 	clock := clock.NewMock()
 	since := clock.Now()
 
-	ticker := newAlignedTicker(since, interval, jitter, clock)
+	ticker := newAlignedTicker(since, interval, jitter, 0, nil, clock)
 	defer ticker.Stop()
 
 	clock.Add(25 * time.Second)
@@ -97,7 +152,7 @@ func TestUnalignedTicker(t *testing.T) {
 	since := clock.Now()
 	until := since.Add(60 * time.Second)
 
-	ticker := newUnalignedTicker(interval, jitter, clock)
+	ticker := newUnalignedTicker(interval, jitter, 0, clock)
 	defer ticker.Stop()
 
 	expected := []time.Time{
@@ -132,7 +187,7 @@ func TestRollingTicker(t *testing.T) {
 	since := clock.Now()
 	until := since.Add(60 * time.Second)
 
-	ticker := newUnalignedTicker(interval, jitter, clock)
+	ticker := newUnalignedTicker(interval, jitter, 0, clock)
 	defer ticker.Stop()
 
 	expected := []time.Time{
@@ -171,7 +226,7 @@ func TestAlignedTickerDistribution(t *testing.T) {
 	clock := clock.NewMock()
 	since := clock.Now()
 
-	ticker := newAlignedTicker(since, interval, jitter, clock)
+	ticker := newAlignedTicker(since, interval, jitter, 0, nil, clock)
 	defer ticker.Stop()
 	dist := simulatedDist(ticker, clock)
 	printDist(dist)
@@ -191,7 +246,7 @@ func TestUnalignedTickerDistribution(t *testing.T) {
 
 	clock := clock.NewMock()
 
-	ticker := newUnalignedTicker(interval, jitter, clock)
+	ticker := newUnalignedTicker(interval, jitter, 0, clock)
 	defer ticker.Stop()
 	dist := simulatedDist(ticker, clock)
 	printDist(dist)
@@ -258,3 +313,27 @@ func simulatedDist(ticker Ticker, clock *clock.Mock) Distribution {
 
 	return dist
 }
+
+func TestAlignedTickerLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+	interval := time.Hour
+	jitter := 0 * time.Second
+
+	clock := clock.NewMock()
+	since := clock.Now()
+
+	ticker := newAlignedTicker(since, interval, jitter, 0, loc, clock)
+	defer ticker.Stop()
+
+	// the offset of India is 5:30, the ticks are on the local hours
+	clock.Add(30 * time.Minute)
+	tm := <-ticker.Elapsed()
+	require.Equal(t, time.Unix(30*60, 0).UTC(), tm.UTC())
+
+	clock.Add(time.Hour)
+	tm = <-ticker.Elapsed()
+	require.Equal(t, time.Unix(90*60, 0).UTC(), tm.UTC())
+}
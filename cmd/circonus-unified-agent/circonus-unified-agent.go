@@ -9,11 +9,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	_ "net/http/pprof" //nolint:gosec // G108 -- Comment this line to disable pprof endpoint.
 	"os"
 	"os/signal"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -22,6 +22,7 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/internal"
 	"github.com/circonus-labs/circonus-unified-agent/internal/goplugin"
 	"github.com/circonus-labs/circonus-unified-agent/logger"
+	"github.com/circonus-labs/circonus-unified-agent/models"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/aggregators/all"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/all"
@@ -34,7 +35,7 @@ import (
 var fDebug = flag.Bool("debug", false,
 	"turn on debug logging")
 var pprofAddr = flag.String("pprof-addr", "",
-	"pprof address to listen on, not activate pprof if empty")
+	"pprof and expvar address to listen on, not activate pprof if empty")
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false,
@@ -71,6 +72,23 @@ var fPlugins = flag.String("plugin-directory", "",
 	"path to directory containing external plugins")
 var fRunOnce = flag.Bool("once", false,
 	"run one gather and exit")
+var fServerless = flag.Bool("serverless", false,
+	"run as a serverless extension (AWS Lambda extension or sidecar)")
+var fLintGather = flag.Bool("lint-gather", false,
+	"run a single gather with the lint command to analyze the tags of the metrics")
+var fTestConfig = flag.Bool("test-config", false,
+	"load the configuration and initialize the plugins, without starting the agent")
+var fConfigURLs, fConfigHeaders stringList
+var fConfigCacheDir = flag.String("config-cache-dir", "",
+	"directory caching the configurations loaded from URLs, used when they cannot be fetched")
+var fConfigCheckInterval = flag.Duration("config-check-interval", 0,
+	"interval checking the configurations loaded from URLs for changes, reloading the agent when changed")
+
+func init() {
+	flag.Var(&fConfigURLs, "config-url", "URL of a configuration to load, can be repeated")
+	flag.Var(&fConfigHeaders, "config-header",
+		"header of the requests fetching the configurations, as 'Name: value', can be repeated")
+}
 
 var (
 	version   string
@@ -82,6 +100,10 @@ var (
 
 var stop chan struct{}
 
+// remoteConfig fetches the configurations loaded from URLs, kept across the
+// reloads for their cache.
+var remoteConfig = &config.Remote{Timeout: 30 * time.Second}
+
 func reloadLoop(
 	inputFilters []string,
 	outputFilters []string,
@@ -95,6 +117,19 @@ func reloadLoop(
 
 		ctx, cancel := context.WithCancel(context.Background())
 
+		// the first of the signals, the stop of the service and the changes
+		// of the configurations decides whether the agent exits or reloads
+		var stopOnce sync.Once
+		stopAgent := func(reloading bool) {
+			stopOnce.Do(func() {
+				if reloading {
+					<-reload
+					reload <- true
+				}
+				cancel()
+			})
+		}
+
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
 		go func() {
@@ -102,15 +137,21 @@ func reloadLoop(
 			case sig := <-signals:
 				if sig == syscall.SIGHUP {
 					log.Printf("I! Reloading config")
-					<-reload
-					reload <- true
 				}
-				cancel()
+				stopAgent(sig == syscall.SIGHUP)
 			case <-stop:
-				cancel()
+				stopAgent(false)
+			case <-ctx.Done():
 			}
+			signal.Stop(signals)
 		}()
 
+		if urls := configURLs(); *fConfigCheckInterval > 0 && len(urls) != 0 {
+			go watchConfigURLs(ctx, urls, func() {
+				stopAgent(true)
+			})
+		}
+
 		err := runAgent(ctx, inputFilters, outputFilters)
 		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatalf("E! [circonus-unified-agent] Error running agent: %v", err)
@@ -125,34 +166,14 @@ func runAgent(ctx context.Context,
 	log.Printf("I! Starting Circonus Unified Agent %s", version)
 
 	// If no other options are specified, load the config file and run.
-	c := config.NewConfig()
-	c.OutputFilters = outputFilters
-	c.InputFilters = inputFilters
-	err := c.LoadConfig(*fConfig)
+	c, err := loadConfig(inputFilters, outputFilters)
 	if err != nil {
-		return fmt.Errorf("loadconfig (%s): %w", *fConfig, err)
-	}
-
-	if *fConfigDirectory != "" {
-		err = c.LoadDirectory(*fConfigDirectory)
-		if err != nil {
-			return fmt.Errorf("loaddir (%s): %w", *fConfigDirectory, err)
-		}
-	}
-	if !*fTest && len(c.Outputs) == 0 {
-		return fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+		return err
 	}
-	if *fPlugins == "" && len(c.Inputs) == 0 {
-		return fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
-	}
-
-	if int64(c.Agent.Interval.Duration) <= 0 {
-		return fmt.Errorf("Agent interval must be positive, found %s", c.Agent.Interval.Duration)
-	}
-
-	if int64(c.Agent.FlushInterval.Duration) <= 0 {
-		return fmt.Errorf("Agent flush_interval must be positive; found %s", c.Agent.Interval.Duration)
+	if err := checkConfig(c); err != nil {
+		return err
 	}
+	setShutdownTimeout(c.Agent.ShutdownTimeout.Duration)
 
 	ag, err := agent.NewAgent(c)
 	if err != nil {
@@ -168,9 +189,24 @@ func runAgent(ctx context.Context,
 		RotationInterval:    ag.Config.Agent.LogfileRotationInterval,
 		RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize,
 		RotationMaxArchives: ag.Config.Agent.LogfileRotationMaxArchives,
+		Level:               ag.Config.Agent.LogLevel,
+		Format:              ag.Config.Agent.LogFormat,
+		PluginLevels:        c.PluginLogLevels(),
+	}
+
+	if *fDebug || *fQuiet {
+		// the command line flags win over the configured level
+		logConfig.Level = ""
 	}
 
 	logger.SetupLogging(logConfig)
+	models.SuppressRepeatedErrors(ctx, ag.Config.Agent.ErrorSuppressionInterval.Duration)
+
+	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
+	log.Printf("I! Loaded aggregators: %s", strings.Join(c.AggregatorNames(), " "))
+	log.Printf("I! Loaded processors: %s", strings.Join(c.ProcessorNames(), " "))
+	log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
+	log.Printf("I! Tags enabled: %s", c.ListTags())
 
 	if *fRunOnce {
 		wait := time.Duration(*fTestWait) * time.Second
@@ -182,12 +218,6 @@ func runAgent(ctx context.Context,
 		return ag.Test(ctx, wait)
 	}
 
-	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
-	log.Printf("I! Loaded aggregators: %s", strings.Join(c.AggregatorNames(), " "))
-	log.Printf("I! Loaded processors: %s", strings.Join(c.ProcessorNames(), " "))
-	log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
-	log.Printf("I! Tags enabled: %s", c.ListTags())
-
 	if *fPidfile != "" {
 		f, err := os.OpenFile(*fPidfile, os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
@@ -206,9 +236,268 @@ func runAgent(ctx context.Context,
 		}
 	}
 
+	if *fServerless {
+		return ag.RunServerless(ctx)
+	}
+
 	return ag.Run(ctx)
 }
 
+// configURLs returns the URLs the configuration is loaded from.
+func configURLs() []string {
+	var urls []string
+	if config.IsURL(*fConfig) {
+		urls = append(urls, *fConfig)
+	}
+	return append(urls, fConfigURLs...)
+}
+
+// watchConfigURLs checks the configurations of the URLs on the interval, and
+// calls onChange when one of them changed.
+func watchConfigURLs(ctx context.Context, urls []string, onChange func()) {
+	ticker := time.NewTicker(*fConfigCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, u := range urls {
+				changed := remoteConfig.Changed(u)
+				// the agent may have been stopped while the configuration
+				// was fetched
+				if ctx.Err() != nil {
+					return
+				}
+				if changed {
+					log.Printf("I! Config %s changed, reloading config", u)
+					onChange()
+					return
+				}
+			}
+		}
+	}
+}
+
+// setupRemoteConfig configures the fetching of the configurations from the
+// URLs.
+func setupRemoteConfig() error {
+	for _, u := range fConfigURLs {
+		if !config.IsURL(u) {
+			return fmt.Errorf("invalid --config-url %q, not a HTTP(S) URL", u)
+		}
+	}
+	remoteConfig.Headers = make(map[string]string, len(fConfigHeaders))
+	for _, header := range fConfigHeaders {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return fmt.Errorf("invalid --config-header %q, expected 'Name: value'", header)
+		}
+		// the values can be read from the environment to keep the secrets out
+		// of the command line
+		remoteConfig.Headers[strings.TrimSpace(parts[0])] = os.ExpandEnv(strings.TrimSpace(parts[1]))
+	}
+	remoteConfig.CacheDir = *fConfigCacheDir
+	return nil
+}
+
+// loadConfig loads the configuration file, the files of the configuration
+// directory and the configurations of the URLs.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	c.Remote = remoteConfig
+	// the default configuration file is only searched without URL
+	if *fConfig != "" || len(fConfigURLs) == 0 {
+		err := c.LoadConfig(*fConfig)
+		if err != nil {
+			return nil, fmt.Errorf("loadconfig (%s): %w", *fConfig, err)
+		}
+	}
+
+	if *fConfigDirectory != "" {
+		err := c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("loaddir (%s): %w", *fConfigDirectory, err)
+		}
+	}
+
+	for _, u := range fConfigURLs {
+		if err := c.LoadConfig(u); err != nil {
+			return nil, fmt.Errorf("loadconfig (%s): %w", u, err)
+		}
+	}
+
+	// a filter matching no plugin of the configuration is likely a mistake,
+	// the plugins of the other configuration files may still match it
+	for _, name := range inputFilters {
+		if name != "" && !hasInput(c, name) {
+			log.Printf("W! Input filter %q matches no input of the configuration", name)
+		}
+	}
+	for _, name := range outputFilters {
+		if name != "" && !hasOutput(c, name) {
+			log.Printf("W! Output filter %q matches no output of the configuration", name)
+		}
+	}
+	return c, nil
+}
+
+func hasInput(c *config.Config, name string) bool {
+	for _, input := range c.Inputs {
+		if input.Config.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOutput(c *config.Config, name string) bool {
+	for _, output := range c.Outputs {
+		if output.Config.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFilters checks the plugins of the filters exist, so a misspelled
+// plugin does not silently disable all the plugins of its type.
+func checkFilters(inputFilters, outputFilters []string) error {
+	for _, name := range inputFilters {
+		if _, ok := inputs.Inputs[name]; name != "" && !ok {
+			return fmt.Errorf("unknown input %q in --input-filter", name)
+		}
+	}
+	for _, name := range outputFilters {
+		if _, ok := outputs.Outputs[name]; name != "" && !ok {
+			return fmt.Errorf("unknown output %q in --output-filter", name)
+		}
+	}
+	return nil
+}
+
+// checkConfig checks the configuration loaded can run the agent.
+func checkConfig(c *config.Config) error {
+	if !*fTest && len(c.Outputs) == 0 {
+		return fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+	}
+	// in serverless mode the metrics may only come from the function
+	if *fPlugins == "" && !*fServerless && len(c.Inputs) == 0 {
+		return fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
+	}
+
+	if int64(c.Agent.Interval.Duration) <= 0 {
+		return fmt.Errorf("Agent interval must be positive, found %s", c.Agent.Interval.Duration)
+	}
+
+	if int64(c.Agent.FlushInterval.Duration) <= 0 {
+		return fmt.Errorf("Agent flush_interval must be positive; found %s", c.Agent.Interval.Duration)
+	}
+	return nil
+}
+
+// testConfig loads and checks the configuration, and initializes the plugins
+// without starting the agent.
+func testConfig(inputFilters, outputFilters []string) error {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
+	}
+	if err := checkConfig(c); err != nil {
+		return err
+	}
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		return fmt.Errorf("new agent: %w", err)
+	}
+	if err := ag.CheckConfig(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Inputs: %s\n", strings.Join(c.InputNames(), " "))
+	fmt.Printf("Aggregators: %s\n", strings.Join(c.AggregatorNames(), " "))
+	fmt.Printf("Processors: %s\n", strings.Join(c.ProcessorNames(), " "))
+	fmt.Printf("Outputs: %s\n", strings.Join(c.OutputNames(), " "))
+	fmt.Println("Configuration OK")
+	return nil
+}
+
+// showConfig prints the effective configuration, with the secrets redacted.
+func showConfig(inputFilters, outputFilters []string) error {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
+	}
+	return c.WriteEffective(os.Stdout)
+}
+
+// lintConfig loads the configuration and prints the likely problems found in
+// it, and in the metrics of a single gather when requested.
+func lintConfig(ctx context.Context, inputFilters, outputFilters []string) error {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
+	}
+
+	issues := c.Lint()
+
+	if *fLintGather {
+		ag, err := agent.NewAgent(c)
+		if err != nil {
+			return fmt.Errorf("new agent: %w", err)
+		}
+		metrics, err := ag.Sample(ctx, time.Duration(*fTestWait)*time.Second)
+		if err != nil {
+			return fmt.Errorf("gather: %w", err)
+		}
+		issues = append(issues, config.LintMetrics(metrics)...)
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) != 0 {
+		return fmt.Errorf("%d problems found", len(issues))
+	}
+	return nil
+}
+
+// stringList is a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(s string) error { *l = append(*l, s); return nil }
+
+// tapMetrics streams the metrics written to the outputs by the running agent,
+// through its control socket, until interrupted.
+func tapMetrics(args []string) error {
+	var filters stringList
+	fs := flag.NewFlagSet("tap", flag.ContinueOnError)
+	fs.Var(&filters, "filter", "only stream the metrics matching the filter, can be repeated")
+	socket := fs.String("socket", "", "path of the control socket, defaults to control_socket of the configuration")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("tap: %w", err)
+	}
+
+	if *socket == "" {
+		c := config.NewConfig()
+		c.Remote = remoteConfig
+		if err := c.LoadConfig(*fConfig); err != nil {
+			return fmt.Errorf("loadconfig (%s): %w", *fConfig, err)
+		}
+		*socket = c.Agent.ControlSocket
+	}
+	if *socket == "" {
+		return errors.New("no control socket, set control_socket in the agent configuration or use --socket")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return agent.Tap(ctx, *socket, filters, os.Stdout)
+}
+
 func usageExit(rc int) {
 	fmt.Println(internal.Usage)
 	os.Exit(rc)
@@ -278,6 +567,13 @@ func main() {
 		}
 	}
 
+	if err := checkFilters(inputFilters, outputFilters); err != nil {
+		log.Fatal("E! " + err.Error())
+	}
+	if err := setupRemoteConfig(); err != nil {
+		log.Fatal("E! " + err.Error())
+	}
+
 	if *pprofAddr != "" {
 		go func() {
 			pprofHostPort := *pprofAddr
@@ -285,11 +581,11 @@ func main() {
 			if len(parts) == 2 && parts[0] == "" {
 				pprofHostPort = fmt.Sprintf("localhost:%s", parts[1])
 			}
-			pprofHostPort = "http://" + pprofHostPort + "/debug/pprof"
+			pprofHostPort = "http://" + pprofHostPort + "/debug"
 
-			log.Printf("I! Starting pprof HTTP server at: %s", pprofHostPort)
+			log.Printf("I! Starting pprof HTTP server at: %s/pprof, expvar at %s/vars", pprofHostPort, pprofHostPort)
 
-			if err := http.ListenAndServe(*pprofAddr, nil); err != nil {
+			if err := http.ListenAndServe(*pprofAddr, diagnosticsHandler()); err != nil {
 				log.Fatal("E! " + err.Error())
 			}
 		}()
@@ -300,7 +596,23 @@ func main() {
 		case "version":
 			fmt.Println(formatFullVersion())
 			return
+		case "lint":
+			if err := lintConfig(context.Background(), inputFilters, outputFilters); err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			return
+		case "tap":
+			if err := tapMetrics(args[1:]); err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			return
 		case "config":
+			if len(args) > 1 && args[1] == "show" {
+				if err := showConfig(inputFilters, outputFilters); err != nil {
+					log.Fatal("E! " + err.Error())
+				}
+				return
+			}
 			config.PrintSampleConfig(
 				sectionFilters,
 				inputFilters,
@@ -339,6 +651,11 @@ func main() {
 	case *fVersion:
 		fmt.Println(formatFullVersion())
 		return
+	case *fTestConfig:
+		if err := testConfig(inputFilters, outputFilters); err != nil {
+			log.Fatal("E! " + err.Error())
+		}
+		return
 	case *fSampleConfig:
 		config.PrintSampleConfig(
 			sectionFilters,
@@ -2,6 +2,8 @@
 
 package main
 
+import "time"
+
 func run(inputFilters, outputFilters, aggregatorFilters, processorFilters []string) {
 	stop = make(chan struct{})
 	reloadLoop(
@@ -11,3 +13,6 @@ func run(inputFilters, outputFilters, aggregatorFilters, processorFilters []stri
 		processorFilters,
 	)
 }
+
+// setShutdownTimeout is only needed by the windows service.
+func setShutdownTimeout(time.Duration) {}
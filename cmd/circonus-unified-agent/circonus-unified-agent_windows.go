@@ -4,14 +4,48 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/logger"
 	"github.com/kardianos/service"
+	"golang.org/x/sys/windows/svc/mgr"
 )
 
+// serviceStopTimeout is the time the service control manager is kept
+// waiting for the agent to flush its outputs when stopping the service,
+// without shutdown_timeout.  With shutdown_timeout, it is kept waiting for
+// the final flush plus serviceStopMargin.
+const (
+	serviceStopTimeout = 30 * time.Second
+	serviceStopMargin  = 10 * time.Second
+)
+
+// shutdownTimeout is the shutdown_timeout of the running agent, in
+// nanoseconds.
+var shutdownTimeout int64
+
+func setShutdownTimeout(d time.Duration) {
+	atomic.StoreInt64(&shutdownTimeout, int64(d))
+}
+
+// stopTimeout returns the time to wait for the agent to stop.
+func stopTimeout() time.Duration {
+	if d := time.Duration(atomic.LoadInt64(&shutdownTimeout)); d > 0 {
+		return d + serviceStopMargin
+	}
+	return serviceStopTimeout
+}
+
+// serviceStatus is the --service action reporting the status of the
+// service, the other actions are the service.ControlAction.
+const serviceStatus = "status"
+
 var fService = flag.String("service", "", "operate on the service (windows only)")
 var fServiceName = flag.String("service-name", "circonus-unified-agent", "service name (windows only)")
 var fServiceDisplayName = flag.String("service-display-name", "Circonus Unified Agent Data Collector Service", "service display name (windows only)")
@@ -41,14 +75,19 @@ type program struct {
 	outputFilters     []string
 	aggregatorFilters []string
 	processorFilters  []string
+
+	// done is closed when the agent stopped
+	done chan struct{}
 }
 
 func (p *program) Start(s service.Service) error {
+	stop = make(chan struct{})
+	p.done = make(chan struct{})
 	go p.run()
 	return nil
 }
 func (p *program) run() {
-	stop = make(chan struct{})
+	defer close(p.done)
 	reloadLoop(
 		p.inputFilters,
 		p.outputFilters,
@@ -56,9 +95,18 @@ func (p *program) run() {
 		p.processorFilters,
 	)
 }
+
+// Stop stops the agent and waits for it to flush its outputs, the service
+// is reported stopped when Stop returns.
 func (p *program) Stop(s service.Service) error {
 	close(stop)
-	return nil
+	timeout := stopTimeout()
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("agent not stopped after %s, metrics may be lost", timeout)
+	}
 }
 
 func runAsWindowsService(inputFilters, outputFilters, aggregatorFilters, processorFilters []string) {
@@ -93,11 +141,22 @@ func runAsWindowsService(inputFilters, outputFilters, aggregatorFilters, process
 		if *fConfigDirectory != "" {
 			svcConfig.Arguments = append(svcConfig.Arguments, "--config-directory", *fConfigDirectory)
 		}
+		for _, u := range fConfigURLs {
+			svcConfig.Arguments = append(svcConfig.Arguments, "--config-url", u)
+		}
+		for _, header := range fConfigHeaders {
+			svcConfig.Arguments = append(svcConfig.Arguments, "--config-header", header)
+		}
+		if *fConfigCacheDir != "" {
+			svcConfig.Arguments = append(svcConfig.Arguments, "--config-cache-dir", *fConfigCacheDir)
+		}
+		if *fConfigCheckInterval != 0 {
+			svcConfig.Arguments = append(svcConfig.Arguments, "--config-check-interval", fConfigCheckInterval.String())
+		}
 		// set servicename to service cmd line, to have a custom name after relaunch as a service
 		svcConfig.Arguments = append(svcConfig.Arguments, "--service-name", *fServiceName)
 
-		err := service.Control(s, *fService)
-		if err != nil {
+		if err := controlService(s, *fService); err != nil {
 			log.Fatal("E! " + err.Error())
 		}
 		os.Exit(0)
@@ -116,6 +175,70 @@ func runAsWindowsService(inputFilters, outputFilters, aggregatorFilters, process
 	}
 }
 
+// controlService runs the --service action.
+func controlService(s service.Service, action string) error {
+	switch action {
+	case serviceStatus:
+		status, err := s.Status()
+		if err != nil {
+			return fmt.Errorf("service %s: %w", *fServiceName, err)
+		}
+		switch status {
+		case service.StatusRunning:
+			fmt.Printf("%s: running\n", *fServiceName)
+		case service.StatusStopped:
+			fmt.Printf("%s: stopped\n", *fServiceName)
+		default:
+			fmt.Printf("%s: unknown\n", *fServiceName)
+		}
+		return nil
+	case "install":
+		if err := service.Control(s, action); err != nil {
+			return fmt.Errorf("service %s install: %w", *fServiceName, err)
+		}
+		if err := setRecoveryActions(*fServiceName); err != nil {
+			log.Printf("W! Setting the recovery actions of the service %s: %v", *fServiceName, err)
+		}
+		return nil
+	}
+
+	for _, a := range service.ControlAction {
+		if action == a {
+			if err := service.Control(s, action); err != nil {
+				return fmt.Errorf("service %s %s: %w", *fServiceName, action, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid service action %q, must be one of %s or %s",
+		action, strings.Join(service.ControlAction[:], ", "), serviceStatus)
+}
+
+// setRecoveryActions configures the service control manager to restart the
+// service when the agent fails, the failures are counted over a day.
+func setRecoveryActions(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer m.Disconnect() //nolint:errcheck
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer s.Close()
+
+	actions := []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Minute},
+	}
+	if err := s.SetRecoveryActions(actions, uint32((24 * time.Hour).Seconds())); err != nil {
+		return fmt.Errorf("set recovery actions: %w", err)
+	}
+	return nil
+}
+
 // Return true if agent should create a Windows service.
 func windowsRunAsService() bool {
 	if *fService != "" {
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+
+	"github.com/circonus-labs/circonus-unified-agent/agent"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+var publishOnce sync.Once
+
+// publishDiagnostics publishes the runtime and pipeline counters to expvar:
+// the internal stats of the agent and of the plugins, and the goroutines.
+func publishDiagnostics() {
+	publishOnce.Do(func() {
+		expvar.Publish("internal", expvar.Func(func() interface{} {
+			return selfstat.Snapshot()
+		}))
+		expvar.Publish("goroutines", expvar.Func(func() interface{} {
+			return runtime.NumGoroutine()
+		}))
+		expvar.Publish("plugin_goroutines", expvar.Func(func() interface{} {
+			counts, err := agent.PluginGoroutines()
+			if err != nil {
+				log.Printf("E! Counting goroutines: %v", err)
+			}
+			return counts
+		}))
+	})
+}
+
+// diagnosticsHandler serves the pprof profiles under /debug/pprof/ and the
+// expvar variables under /debug/vars.
+func diagnosticsHandler() http.Handler {
+	publishDiagnostics()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
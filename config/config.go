@@ -19,11 +19,14 @@ import (
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/logger"
 	"github.com/circonus-labs/circonus-unified-agent/models"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/aggregators"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
+	jsonv2 "github.com/circonus-labs/circonus-unified-agent/plugins/parsers/json_v2"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/xpath"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers"
 	"github.com/influxdata/toml"
@@ -48,8 +51,19 @@ var (
 	envVarEscaper = strings.NewReplacer(
 		`"`, `\"`,
 		`\`, `\\`,
+		"\n", `\n`,
+		"\r", `\r`,
 	)
 
+	// fileRefRe is a regex to find the references to the contents of a file
+	// in the config file, as in "{file:/run/secrets/api_token}", the path
+	// may hold environment variables
+	fileRefRe = regexp.MustCompile(`\{file:((?:\$\{\w+\}|[^}\s])+)\}`)
+
+	// refRe finds the file references and the environment variables in a
+	// single pass
+	refRe = regexp.MustCompile(fileRefRe.String() + "|" + envVarRe.String())
+
 	defaultPluginsEnabled = true
 )
 
@@ -69,6 +83,10 @@ type Config struct {
 	InputFilters  []string
 	OutputFilters []string
 
+	// Remote fetches the configurations loaded from HTTP(S) URLs when set,
+	// with its headers and cache.
+	Remote *Remote
+
 	Agent       *AgentConfig
 	Inputs      []*models.RunningInput
 	Outputs     []*models.RunningOutput
@@ -76,6 +94,12 @@ type Config struct {
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors    models.RunningProcessors
 	AggProcessors models.RunningProcessors
+
+	// configuration tables of the inputs, kept for the linter
+	inputTables map[*models.RunningInput]*ast.Table
+	// configuration of the plugins in the order they are loaded, kept for
+	// the effective configuration
+	plugins []loadedPlugin
 }
 
 // NewConfig creates a new struct to hold the agent config.
@@ -84,6 +108,7 @@ type Config struct {
 func NewConfig() *Config {
 	c := &Config{
 		UnusedFields: map[string]bool{},
+		inputTables:  map[*models.RunningInput]*ast.Table{},
 		// Agent defaults:
 		Agent: &AgentConfig{
 			Interval:                   internal.Duration{Duration: 10 * time.Second},
@@ -91,6 +116,8 @@ func NewConfig() *Config {
 			FlushInterval:              internal.Duration{Duration: 10 * time.Second},
 			LogTarget:                  "file",
 			LogfileRotationMaxArchives: 5,
+			ServerlessListen:           "127.0.0.1:8186",
+			HealthBufferThreshold:      0.9,
 		},
 
 		Tags:          make(map[string]string),
@@ -121,6 +148,19 @@ type AgentConfig struct {
 	//     ie, if Interval=10s then always collect on :00, :10, :20, etc.
 	RoundInterval bool
 
+	// Timezone is the location of the wall clock the collections and flushes
+	// are rounded in, such as "Local" or "America/New_York", UTC when empty.
+	Timezone string `toml:"timezone"`
+
+	// BusinessDayTag is the tag set to "true" on the metrics timestamped on a
+	// business day in the timezone, and to "false" otherwise.  Disabled when
+	// empty.
+	BusinessDayTag string `toml:"business_day_tag"`
+
+	// BusinessDays are the days of the week, "mon" to "sun", that are business
+	// days, monday to friday when empty.
+	BusinessDays []string `toml:"business_days"`
+
 	// By default or when set to "0s", precision will be set to the same
 	// timestamp order as the collection interval, with the maximum being 1s.
 	//   ie, when interval = "10s", precision will be "1s"
@@ -135,6 +175,10 @@ type AgentConfig struct {
 	// same time, which can have a measurable effect on the system.
 	CollectionJitter internal.Duration
 
+	// CollectionOffset shifts the collections by a fixed amount, such as
+	// collecting at 10s past each minute with a 1m interval.
+	CollectionOffset internal.Duration
+
 	// FlushInterval is the Interval at which to flush data
 	FlushInterval internal.Duration
 
@@ -144,6 +188,15 @@ type AgentConfig struct {
 	// ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
 	FlushJitter internal.Duration
 
+	// ShutdownTimeout is the time the outputs have to flush their buffered
+	// metrics on shutdown, the failed writes are retried until it is
+	// reached.  When 0 the outputs are flushed once, without a deadline.
+	ShutdownTimeout internal.Duration `toml:"shutdown_timeout"`
+
+	// RoundFlushInterval rounds flush interval to 'flush_interval' in the
+	// timezone, the jitter is added to the rounded time.
+	RoundFlushInterval bool `toml:"round_flush_interval"`
+
 	// MetricBatchSize is the maximum number of metrics that is wrote to an
 	// output plugin in one call.
 	MetricBatchSize int
@@ -192,8 +245,65 @@ type AgentConfig struct {
 	// If set to -1, no archives are removed.
 	LogfileRotationMaxArchives int `toml:"logfile_rotation_max_archives"`
 
+	// LogLevel is the lowest level of the messages logged: debug, info, warn
+	// or error.  It overrides Debug and Quiet, the plugins can override it
+	// with their own log_level.
+	LogLevel string `toml:"log_level"`
+
+	// LogFormat is the format of the messages, text or json.
+	LogFormat string `toml:"log_format"`
+
+	// ErrorSuppressionInterval is the interval within which the identical
+	// errors of a plugin are logged once, the repetitions are summarized at
+	// the end of the interval.  When 0 all the errors are logged.
+	ErrorSuppressionInterval internal.Duration `toml:"error_suppression_interval"`
+
 	Hostname     string
 	OmitHostname bool
+
+	// GOGC sets the garbage collection target percentage like the GOGC
+	// environment variable.  When 0 the runtime default is kept, a negative
+	// value disables the collector until the memory limit is reached.
+	GOGC int `toml:"gogc"`
+
+	// MemoryLimit is a soft memory limit for the runtime like the GOMEMLIMIT
+	// environment variable.  When 0 no limit is set.
+	MemoryLimit internal.Size `toml:"memory_limit"`
+
+	// MemoryLimitCgroupRatio derives the memory limit from the cgroup memory
+	// limit when memory_limit is not set, ie 0.9 sets the limit to 90% of the
+	// cgroup limit.
+	MemoryLimitCgroupRatio float64 `toml:"memory_limit_cgroup_ratio"`
+
+	// MemoryBallast is the size of a heap allocation kept for the lifetime
+	// of the agent, raising the heap size the collector targets.
+	MemoryBallast internal.Size `toml:"memory_ballast"`
+
+	// MaxProcs sets GOMAXPROCS, the number of CPUs executing the agent
+	// simultaneously.  When 0 the CPU limit of the cgroup is used, if any.
+	// Only GOMAXPROCS is tuned, the concurrency of the plugins is unchanged.
+	MaxProcs int `toml:"max_procs"`
+
+	// ServerlessListen is the address of the metric ingestion endpoint in
+	// serverless mode.
+	ServerlessListen string `toml:"serverless_listen"`
+
+	// ControlSocket is the path of the unix socket used by the tap command
+	// to stream the metrics of the running agent, disabled when empty.
+	ControlSocket string `toml:"control_socket"`
+
+	// HealthListen is the address of the health endpoint serving the
+	// liveness, readiness and status of the plugins, disabled when empty.
+	HealthListen string `toml:"health_listen"`
+
+	// HealthBufferThreshold is the ratio of the buffer of an output in use
+	// above which the agent is not ready.
+	HealthBufferThreshold float64 `toml:"health_buffer_threshold"`
+
+	// GoroutineLeakIntervals is the number of consecutive intervals the
+	// goroutines of a plugin have to grow for the plugin to be suspected of
+	// leaking them.  When 0 the goroutines are not counted.
+	GoroutineLeakIntervals int `toml:"goroutine_leak_intervals"`
 }
 
 // InputNames returns a list of strings of the configured inputs.
@@ -232,6 +342,35 @@ func (c *Config) OutputNames() []string {
 	return PluginNameCounts(name)
 }
 
+// PluginLogLevels returns the log levels of the plugins overriding the level
+// of the agent, by log name.
+func (c *Config) PluginLogLevels() map[string]string {
+	levels := make(map[string]string)
+	for _, input := range c.Inputs {
+		if input.Config.LogLevel != "" {
+			levels[input.LogName()] = input.Config.LogLevel
+		}
+	}
+	for _, processors := range []models.RunningProcessors{c.Processors, c.AggProcessors} {
+		for _, processor := range processors {
+			if processor.Config.LogLevel != "" {
+				levels[processor.LogName()] = processor.Config.LogLevel
+			}
+		}
+	}
+	for _, aggregator := range c.Aggregators {
+		if aggregator.Config.LogLevel != "" {
+			levels[aggregator.LogName()] = aggregator.Config.LogLevel
+		}
+	}
+	for _, output := range c.Outputs {
+		if output.Config.LogLevel != "" {
+			levels[output.LogName()] = output.Config.LogLevel
+		}
+	}
+	return levels
+}
+
 // PluginNameCounts returns a list of sorted plugin names and their count
 func PluginNameCounts(plugins []string) []string {
 	names := make(map[string]int)
@@ -299,6 +438,17 @@ var agentConfig = `
   ## Rounds collection interval to 'interval'
   ## ie, if interval="10s" then always collect on :00, :10, :20, etc.
   round_interval = true
+  ## Timezone of the wall clock the collections and flushes are rounded in, ie
+  ## "Local" or "America/New_York", defaults to UTC.  The rounding follows the
+  ## daylight saving time changes, with interval="24h" the collections happen
+  ## at midnight in the timezone.
+  # timezone = ""
+
+  ## Tag set to "true" on the metrics timestamped on a business day in the
+  ## timezone, and to "false" otherwise, for business hours reporting.
+  # business_day_tag = ""
+  ## Days of the week that are business days.
+  # business_days = ["mon", "tue", "wed", "thu", "fri"]
 
   ## circonus-unified-agent will send metrics to outputs in batches of at most
   ## metric_batch_size metrics.
@@ -316,6 +466,11 @@ var agentConfig = `
   ## same time, which can have a measurable effect on the system.
   collection_jitter = "0s"
 
+  ## Collection offset shifts the collection by a fixed amount, such as 10s
+  ## past each minute with a 1m interval.  Spreads the collections of
+  ## plugins sharing the same interval.
+  # collection_offset = "0s"
+
   ## Default flushing interval for all outputs. Maximum flush_interval will be
   ## flush_interval + flush_jitter
   flush_interval = "10s"
@@ -323,6 +478,13 @@ var agentConfig = `
   ## large write spikes for users running a large number of circonus-unified-agent instances.
   ## ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
   flush_jitter = "0s"
+  ## Rounds flush interval to 'flush_interval' in the timezone, the jitter is
+  ## added to the rounded time.
+  # round_flush_interval = false
+  ## Time the outputs have to write the buffered metrics on shutdown, the
+  ## failed writes are retried until it is reached.  When 0 the outputs are
+  ## flushed once, without a deadline.
+  # shutdown_timeout = "0s"
 
   ## By default or when set to "0s", precision will be set to the same
   ## timestamp order as the collection interval, with the maximum being 1s.
@@ -360,11 +522,78 @@ var agentConfig = `
   ## If set to -1, no archives are removed.
   # logfile_rotation_max_archives = 5
 
+  ## Lowest level of the messages logged: "debug", "info", "warn" or "error".
+  ## Overrides the debug and quiet settings when set, the plugins can set
+  ## their own log_level.
+  # log_level = "info"
+
+  ## Format of the messages, "text" or "json".  The JSON messages are lines
+  ## with the time, level, plugin and msg keys.
+  # log_format = "text"
+
+  ## Log the identical errors of a plugin once per interval, the number of
+  ## repetitions is logged at the end of the interval.  When 0 all the errors
+  ## are logged.
+  # error_suppression_interval = "0s"
+
   ## Override default hostname, if empty use os.Hostname()
   hostname = ""
   ## If set to true, do no set the "host" tag in the circonus-unified-agent.
   omit_hostname = false
 
+  ## Garbage collection target percentage, like the GOGC environment variable.
+  ## Lower values trade CPU for a smaller heap.  When 0 the runtime default is
+  ## used.
+  # gogc = 0
+
+  ## Soft memory limit for the runtime, like the GOMEMLIMIT environment
+  ## variable.  The collector runs more often when approaching the limit.
+  # memory_limit = "0MB"
+
+  ## When running in a container or a cgroup with a memory limit, and
+  ## memory_limit is not set, set the memory limit to this ratio of the cgroup
+  ## limit.
+  # memory_limit_cgroup_ratio = 0.0
+
+  ## Size of a heap ballast allocated at startup.  The ballast raises the heap
+  ## size the collector targets, reducing the collection frequency of small
+  ## heaps.  The ballast is not touched and does not use resident memory.
+  # memory_ballast = "0MB"
+
+  ## Maximum number of CPUs executing the agent simultaneously, like the
+  ## GOMAXPROCS environment variable.  When 0 the CPU quota of the cgroup, such
+  ## as the CPU limit of a container, is used when lower than the number of
+  ## CPUs of the host.  Only GOMAXPROCS is set, the concurrency settings of
+  ## the plugins are unchanged.
+  # max_procs = 0
+
+  ## Address of the metric ingestion endpoint when running in serverless mode,
+  ## as a Lambda extension or a sidecar.  The function posts metrics in the
+  ## line protocol to /write and requests a flush of the outputs with /flush.
+  # serverless_listen = "127.0.0.1:8186"
+
+  ## Path of the control socket, used by the "tap" command to stream the
+  ## metrics written to the outputs for troubleshooting.  Anyone able to
+  ## connect to the socket can read all the metrics.
+  # control_socket = "/var/run/circonus-unified-agent/control.sock"
+
+  ## Address of the health endpoint, for the liveness and readiness probes of
+  ## Kubernetes and the fleet tooling:
+  ##   /healthz  the agent is running
+  ##   /readyz   the outputs are connected and their buffers are below the
+  ##             health_buffer_threshold ratio
+  ##   /plugins  the status of the plugins in JSON, with their last error and
+  ##             last successful gather or write
+  # health_listen = "127.0.0.1:8686"
+  # health_buffer_threshold = 0.9
+
+  ## Count the goroutines of each plugin every interval, reported by the
+  ## internal input in the internal_goroutines measurement.  A plugin whose
+  ## goroutines grew for this many consecutive intervals, without ever
+  ## decreasing, is suspected of leaking them: leak_suspected is set and a
+  ## warning is logged.  When 0 the goroutines are not counted.
+  # goroutine_leak_intervals = 0
+
 `
 
 var outputHeader = `
@@ -726,7 +955,12 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
-	data, err := loadConfig(path)
+	var data []byte
+	if c.Remote != nil && IsURL(path) {
+		data, err = c.Remote.Fetch(path)
+	} else {
+		data, err = loadConfig(path)
+	}
 	if err != nil {
 		return fmt.Errorf("Error loading config file %s: %w", path, err)
 	}
@@ -766,6 +1000,14 @@ func (c *Config) LoadConfigData(data []byte) error {
 		if err = c.toml.UnmarshalTable(subTable, c.Agent); err != nil {
 			return fmt.Errorf("error parsing agent table: %w", err)
 		}
+		if c.Agent.LogLevel != "" {
+			if _, err = logger.ParseLevel(c.Agent.LogLevel); err != nil {
+				return fmt.Errorf("agent: %w", err)
+			}
+		}
+		if err = logger.CheckFormat(c.Agent.LogFormat); err != nil {
+			return fmt.Errorf("agent: %w", err)
+		}
 	}
 
 	if !c.Agent.OmitHostname {
@@ -909,6 +1151,12 @@ func escapeEnv(value string) string {
 	return envVarEscaper.Replace(value)
 }
 
+// IsURL reports whether the configuration is loaded from a HTTP(S) URL.
+func IsURL(config string) bool {
+	u, err := url.Parse(config)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http")
+}
+
 func loadConfig(config string) ([]byte, error) {
 	u, err := url.Parse(config)
 	if err != nil {
@@ -951,34 +1199,64 @@ func fetchConfig(u fmt.Stringer) ([]byte, error) {
 
 // parseConfig loads a TOML configuration from a provided path and
 // returns the AST produced from the TOML parser. When loading the file, it
-// will find environment variables and replace them.
+// will find environment variables and file references and replace them.
 func parseConfig(contents []byte) (*ast.Table, error) {
 	contents = trimBOM(contents)
 
-	parameters := envVarRe.FindAllSubmatch(contents, -1)
-	for _, parameter := range parameters {
-		if len(parameter) != 3 {
-			continue
-		}
+	contents, err := replaceRefs(contents)
+	if err != nil {
+		return nil, err
+	}
 
-		var envVar []byte
-		switch {
-		case parameter[1] != nil:
-			envVar = parameter[1]
-		case parameter[2] != nil:
-			envVar = parameter[2]
-		default:
-			continue
-		}
+	return toml.Parse(contents)
+}
 
-		envVal, ok := os.LookupEnv(strings.TrimPrefix(string(envVar), "$"))
-		if ok {
-			envVal = escapeEnv(envVal)
-			contents = bytes.Replace(contents, parameter[0], []byte(envVal), 1)
+// replaceRefs replaces the environment variables with their values and the
+// file references with the contents of the files, without their trailing
+// newlines.  Both are replaced in a single pass over the original text, so a
+// value holding a reference is not expanded.  The file references of the
+// comment lines are left as is, a file that cannot be read is an error.
+func replaceRefs(contents []byte) ([]byte, error) {
+	lines := bytes.SplitAfter(contents, []byte("\n"))
+	for i, line := range lines {
+		comment := bytes.HasPrefix(bytes.TrimSpace(line), []byte("#"))
+		var err error
+		lines[i] = refRe.ReplaceAllFunc(line, func(ref []byte) []byte {
+			if !bytes.HasPrefix(ref, []byte("{file:")) {
+				if value, ok := lookupEnv(ref); ok {
+					return []byte(escapeEnv(value))
+				}
+				return ref
+			}
+			if comment {
+				return ref
+			}
+			path := envVarRe.ReplaceAllFunc(fileRefRe.FindSubmatch(ref)[1], func(parameter []byte) []byte {
+				if value, ok := lookupEnv(parameter); ok {
+					return []byte(value)
+				}
+				return parameter
+			})
+			value, rerr := os.ReadFile(string(path))
+			if rerr != nil {
+				if err == nil {
+					err = fmt.Errorf("line %d: file reference: %w", i+1, rerr)
+				}
+				return ref
+			}
+			return []byte(escapeEnv(strings.TrimRight(string(value), "\r\n")))
+		})
+		if err != nil {
+			return nil, err
 		}
 	}
+	return bytes.Join(lines, nil), nil
+}
 
-	return toml.Parse(contents)
+// lookupEnv returns the value of an environment variable reference, as in
+// "${NAME}" or "$NAME".
+func lookupEnv(parameter []byte) (string, bool) {
+	return os.LookupEnv(strings.Trim(string(parameter), "${}"))
 }
 
 func (c *Config) addAggregator(name string, table *ast.Table) error {
@@ -998,6 +1276,7 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	}
 
 	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	c.plugins = append(c.plugins, loadedPlugin{kind: "aggregators", name: name, plugin: aggregator, table: table})
 	return nil
 }
 
@@ -1017,6 +1296,11 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return err
 	}
 	c.Processors = append(c.Processors, rf)
+	var processor interface{} = rf.Processor
+	if p, ok := processor.(unwrappable); ok {
+		processor = p.Unwrap()
+	}
+	c.plugins = append(c.plugins, loadedPlugin{kind: "processors", name: name, plugin: processor, table: table})
 
 	// save a copy for the aggregator
 	rf, err = c.newRunningProcessor(creator, processorConfig, name, table)
@@ -1084,6 +1368,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
 	c.Outputs = append(c.Outputs, ro)
+	c.plugins = append(c.plugins, loadedPlugin{kind: "outputs", name: name, plugin: output, table: table})
 	return nil
 }
 
@@ -1139,6 +1424,8 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	rp := models.NewRunningInput(input, pluginConfig)
 	rp.SetDefaultTags(c.Tags)
 	c.Inputs = append(c.Inputs, rp)
+	c.inputTables[rp] = table
+	c.plugins = append(c.plugins, loadedPlugin{kind: "inputs", name: name, plugin: input, table: table})
 	return nil
 }
 
@@ -1161,6 +1448,7 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 	c.getFieldString(tbl, "name_suffix", &conf.MeasurementSuffix)
 	c.getFieldString(tbl, "name_override", &conf.NameOverride)
 	c.getFieldString(tbl, "alias", &conf.Alias)
+	c.getFieldLogLevel(tbl, &conf.LogLevel)
 
 	conf.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
@@ -1191,6 +1479,7 @@ func (c *Config) buildProcessor(name string, tbl *ast.Table) (*models.ProcessorC
 
 	c.getFieldInt64(tbl, "order", &conf.Order)
 	c.getFieldString(tbl, "alias", &conf.Alias)
+	c.getFieldLogLevel(tbl, &conf.LogLevel)
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
@@ -1242,6 +1531,10 @@ func (c *Config) buildInput(name string, tbl *ast.Table) (*models.InputConfig, e
 	c.getFieldDuration(tbl, "interval", &cp.Interval)
 	c.getFieldDuration(tbl, "precision", &cp.Precision)
 	c.getFieldDuration(tbl, "collection_jitter", &cp.CollectionJitter)
+	c.getFieldDuration(tbl, "collection_offset", &cp.CollectionOffset)
+	c.getFieldInt(tbl, "max_metrics_per_gather", &cp.MaxMetricsPerGather)
+	c.getFieldInt(tbl, "max_fields_per_metric", &cp.MaxFieldsPerMetric)
+	c.getFieldInt(tbl, "max_string_field_length", &cp.MaxStringFieldLength)
 	c.getFieldString(tbl, "name_prefix", &cp.MeasurementPrefix)
 	c.getFieldString(tbl, "name_suffix", &cp.MeasurementSuffix)
 	c.getFieldString(tbl, "name_override", &cp.NameOverride)
@@ -1251,6 +1544,7 @@ func (c *Config) buildInput(name string, tbl *ast.Table) (*models.InputConfig, e
 	if cp.Alias == "" {
 		cp.Alias = cp.InstanceID
 	}
+	c.getFieldLogLevel(tbl, &cp.LogLevel)
 
 	cp.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
@@ -1300,6 +1594,7 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 	c.getFieldString(tbl, "separator", &pc.Separator)
 
 	c.getFieldStringSlice(tbl, "templates", &pc.Templates)
+	c.getFieldStringSlice(tbl, "graphite_regex_templates", &pc.GraphiteRegexTemplates)
 	c.getFieldStringSlice(tbl, "tag_keys", &pc.TagKeys)
 	c.getFieldStringSlice(tbl, "json_string_fields", &pc.JSONStringFields)
 	c.getFieldString(tbl, "json_name_key", &pc.JSONNameKey)
@@ -1348,6 +1643,41 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 
 	c.getFieldStringSlice(tbl, "form_urlencoded_tag_keys", &pc.FormUrlencodedTagKeys)
 
+	c.getFieldInt(tbl, "prometheus_metric_version", &pc.PrometheusMetricVersion)
+	c.getFieldBool(tbl, "prometheus_ignore_timestamp", &pc.PrometheusIgnoreTimestamp)
+
+	if pc.DataFormat == "json_v2" {
+		if node, ok := tbl.Fields["json_v2"]; ok {
+			subtables, ok := node.([]*ast.Table)
+			if !ok {
+				return nil, fmt.Errorf("json_v2 must be an array of tables")
+			}
+			for _, subtable := range subtables {
+				var jc jsonv2.Config
+				if err := c.toml.UnmarshalTable(subtable, &jc); err != nil {
+					return nil, fmt.Errorf("json_v2: %w", err)
+				}
+				pc.JSONV2Config = append(pc.JSONV2Config, jc)
+			}
+		}
+	}
+
+	if pc.DataFormat == "xml" || pc.DataFormat == "html" {
+		if node, ok := tbl.Fields["xpath"]; ok {
+			subtables, ok := node.([]*ast.Table)
+			if !ok {
+				return nil, fmt.Errorf("xpath must be an array of tables")
+			}
+			for _, subtable := range subtables {
+				var xc xpath.Config
+				if err := c.toml.UnmarshalTable(subtable, &xc); err != nil {
+					return nil, fmt.Errorf("xpath: %w", err)
+				}
+				pc.XPathConfig = append(pc.XPathConfig, xc)
+			}
+		}
+	}
+
 	pc.MetricName = name
 
 	if c.hasErrs() {
@@ -1373,6 +1703,7 @@ func (c *Config) buildSerializer(name string, tbl *ast.Table) (serializers.Seria
 	c.getFieldString(tbl, "template", &sc.Template)
 	c.getFieldStringSlice(tbl, "templates", &sc.Templates)
 	c.getFieldString(tbl, "carbon2_format", &sc.Carbon2Format)
+	c.getFieldString(tbl, "carbon2_sanitize_replace_char", &sc.Carbon2SanitizeReplaceChar)
 	c.getFieldInt(tbl, "influx_max_line_bytes", &sc.InfluxMaxLineBytes)
 
 	c.getFieldBool(tbl, "influx_sort_fields", &sc.InfluxSortFields)
@@ -1413,18 +1744,35 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 		Filter: filter,
 	}
 
-	// TODO: support FieldPass/FieldDrop on outputs
-
 	c.getFieldDuration(tbl, "flush_interval", &oc.FlushInterval)
-	c.getFieldDuration(tbl, "flush_jitter", oc.FlushJitter)
+	c.getFieldDuration(tbl, "flush_jitter", &oc.FlushJitter)
+	c.getFieldBool(tbl, "fallback", &oc.Fallback)
 
 	c.getFieldInt(tbl, "metric_buffer_limit", &oc.MetricBufferLimit)
 	c.getFieldInt(tbl, "metric_batch_size", &oc.MetricBatchSize)
+	c.getFieldString(tbl, "disk_buffer_directory", &oc.DiskBuffer.Directory)
+	c.getFieldSize(tbl, "disk_buffer_max_size", &oc.DiskBuffer.MaxSize)
+	c.getFieldSize(tbl, "disk_buffer_segment_size", &oc.DiskBuffer.SegmentSize)
+	c.getFieldString(tbl, "disk_buffer_fsync", &oc.DiskBuffer.Fsync)
+	c.getFieldBool(tbl, "adaptive_flush", &oc.AdaptiveFlush.Enabled)
+	c.getFieldInt(tbl, "adaptive_flush_high_water_mark", &oc.AdaptiveFlush.HighWaterMark)
+	c.getFieldInt(tbl, "adaptive_flush_max_batch_size", &oc.AdaptiveFlush.MaxBatchSize)
+	c.getFieldDuration(tbl, "adaptive_flush_max_interval", &oc.AdaptiveFlush.MaxInterval)
 	c.getFieldString(tbl, "alias", &oc.Alias)
+	c.getFieldLogLevel(tbl, &oc.LogLevel)
 	c.getFieldString(tbl, "name_override", &oc.NameOverride)
 	c.getFieldString(tbl, "name_suffix", &oc.NameSuffix)
 	c.getFieldString(tbl, "name_prefix", &oc.NamePrefix)
 
+	oc.Tags = make(map[string]string)
+	if node, ok := tbl.Fields["tags"]; ok {
+		if subtbl, ok := node.(*ast.Table); ok {
+			if err := c.toml.UnmarshalTable(subtbl, oc.Tags); err != nil {
+				return nil, fmt.Errorf("could not parse tags for output %s", name)
+			}
+		}
+	}
+
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
@@ -1434,25 +1782,29 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 
 func (c *Config) missingTomlField(typ reflect.Type, key string) error {
 	switch key {
-	case "alias", "instance_id", "carbon2_format", "collectd_auth_file", "collectd_parse_multivalue",
-		"collectd_security_level", "collectd_typesdb", "collection_jitter", "csv_column_names",
+	case "adaptive_flush", "adaptive_flush_high_water_mark", "adaptive_flush_max_batch_size", "adaptive_flush_max_interval",
+		"alias", "instance_id", "carbon2_format", "carbon2_sanitize_replace_char", "collectd_auth_file", "collectd_parse_multivalue",
+		"collectd_security_level", "collectd_typesdb", "collection_jitter", "collection_offset", "csv_column_names",
 		"csv_column_types", "csv_comment", "csv_delimiter", "csv_header_row_count",
 		"csv_measurement_column", "csv_skip_columns", "csv_skip_rows", "csv_tag_columns",
 		"csv_timestamp_column", "csv_timestamp_format", "csv_timezone", "csv_trim_space",
-		"data_format", "data_type", "delay", "drop", "drop_original", "dropwizard_metric_registry_path",
+		"data_format", "data_type", "delay", "disk_buffer_directory", "disk_buffer_fsync",
+		"disk_buffer_max_size", "disk_buffer_segment_size", "drop", "drop_original", "dropwizard_metric_registry_path",
 		"dropwizard_tag_paths", "dropwizard_tags_path", "dropwizard_time_format", "dropwizard_time_path",
-		"fielddrop", "fieldpass", "flush_interval", "flush_jitter", "form_urlencoded_tag_keys",
-		"grace", "graphite_separator", "graphite_tag_support", "grok_custom_pattern_files",
+		"fallback", "fielddrop", "fieldpass", "flush_interval", "flush_jitter", "form_urlencoded_tag_keys",
+		"grace", "graphite_regex_templates", "graphite_separator", "graphite_tag_support", "grok_custom_pattern_files",
 		"grok_custom_patterns", "grok_named_patterns", "grok_patterns", "grok_timezone",
 		"grok_unique_timestamp", "influx_max_line_bytes", "influx_sort_fields", "influx_uint_support",
 		"interval", "json_name_key", "json_query", "json_strict", "json_string_fields",
-		"json_time_format", "json_time_key", "json_timestamp_units", "json_timezone",
-		"metric_batch_size", "metric_buffer_limit", "name_override", "name_prefix",
+		"json_time_format", "json_time_key", "json_timestamp_units", "json_timezone", "json_v2", "log_level",
+		"max_fields_per_metric", "max_metrics_per_gather", "max_string_field_length", "metric_batch_size",
+		"metric_buffer_limit", "name_override", "name_prefix",
 		"name_suffix", "namedrop", "namepass", "order", "pass", "period", "precision",
-		"prefix", "prometheus_export_timestamp", "prometheus_sort_metrics", "prometheus_string_as_label",
+		"prefix", "prometheus_export_timestamp", "prometheus_ignore_timestamp", "prometheus_metric_version",
+		"prometheus_sort_metrics", "prometheus_string_as_label",
 		"separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
 		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "template", "templates",
-		"wavefront_source_override", "wavefront_use_strict":
+		"wavefront_source_override", "wavefront_use_strict", "xpath":
 
 		// ignore fields that are common to all plugins.
 	default:
@@ -1461,6 +1813,17 @@ func (c *Config) missingTomlField(typ reflect.Type, key string) error {
 	return nil
 }
 
+// getFieldLogLevel reads the log_level of a plugin.
+func (c *Config) getFieldLogLevel(tbl *ast.Table, target *string) {
+	c.getFieldString(tbl, "log_level", target)
+	if *target == "" {
+		return
+	}
+	if _, err := logger.ParseLevel(*target); err != nil {
+		c.addError(tbl, err)
+	}
+}
+
 func (c *Config) getFieldString(tbl *ast.Table, fieldName string, target *string) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
@@ -1542,6 +1905,30 @@ func (c *Config) getFieldInt64(tbl *ast.Table, fieldName string, target *int64)
 	}
 }
 
+// getFieldSize reads a size in bytes, either an integer or a string with a
+// unit such as "512MB".
+func (c *Config) getFieldSize(tbl *ast.Table, fieldName string, target *int64) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size internal.Size
+			var err error
+			switch v := kv.Value.(type) {
+			case *ast.Integer:
+				err = size.UnmarshalTOML([]byte(v.Value))
+			case *ast.String:
+				err = size.UnmarshalTOML([]byte(strconv.Quote(v.Value)))
+			default:
+				return
+			}
+			if err != nil {
+				c.addError(tbl, fmt.Errorf("error parsing size: %w", err))
+				return
+			}
+			*target = size.Size
+		}
+	}
+}
+
 func (c *Config) getFieldStringSlice(tbl *ast.Table, fieldName string, target *[]string) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
@@ -1621,11 +2008,10 @@ type unwrappable interface {
 	Unwrap() cua.Processor
 }
 
-//
 // Circonus plugins
-//   agent   - which are always enabled
-//   default - which are enabled for "hosts" (disabled in docker containers)
 //
+//	agent   - which are always enabled
+//	default - which are enabled for "hosts" (disabled in docker containers)
 type circonusPlugin struct {
 	Enabled bool
 	Data    []byte
@@ -1782,6 +2168,10 @@ func (c *Config) addDefaultPlugins() error {
 		if !pluginConfig.Enabled {
 			continue // user override in configuration
 		}
+		if _, ok := inputs.Inputs[pluginName]; !ok {
+			log.Printf("D! [agent] %s plugin not included in this build, skipping", pluginName)
+			continue // custom build without the plugin
+		}
 		tbl, err := parseConfig(pluginConfig.Data)
 		if err != nil {
 			return fmt.Errorf("error parsing data: %w", err)
@@ -1850,6 +2240,10 @@ func (c *Config) addAgentPlugins() error {
 		if !pluginConfig.Enabled {
 			continue // user override in configuration
 		}
+		if _, ok := inputs.Inputs[pluginName]; !ok {
+			log.Printf("D! [agent] %s plugin not included in this build, skipping", pluginName)
+			continue // custom build without the plugin
+		}
 		tbl, err := parseConfig(pluginConfig.Data)
 		if err != nil {
 			return fmt.Errorf("error parsing data: %w", err)
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
@@ -10,10 +11,15 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/models"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs/exec"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/file"
 	httplistenerv2 "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/http_listener_v2"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs/memcached"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs/procstat"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
+	jsonv2 "github.com/circonus-labs/circonus-unified-agent/plugins/parsers/json_v2"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/xpath"
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -210,6 +216,68 @@ func TestConfig_FieldNotDefined(t *testing.T) {
 	assert.Equal(t, "Error loading config file ./testdata/invalid_field.toml: plugin inputs.http_listener_v2: line 1: configuration specified the fields [\"not_a_field\"], but they weren't used", err.Error())
 }
 
+func TestConfig_JSONV2Parser(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/json_v2.toml")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Inputs))
+
+	data, err := ioutil.ReadFile("./testdata/json_v2.toml")
+	require.NoError(t, err)
+	tbl, err := parseConfig(data)
+	require.NoError(t, err)
+	inputs, ok := tbl.Fields["inputs"].(*ast.Table)
+	require.True(t, ok)
+	files, ok := inputs.Fields["file"].([]*ast.Table)
+	require.True(t, ok)
+
+	pc, err := c.getParserConfig("file", files[0])
+	require.NoError(t, err)
+	require.Equal(t, []jsonv2.Config{
+		{
+			MeasurementName: "api",
+			TimestampPath:   "updated",
+			TimestampFormat: "unix",
+			Tags:            []jsonv2.DataSet{{Path: "region"}},
+			Fields:          []jsonv2.DataSet{{Path: "stats.requests", Type: "int"}},
+			Objects: []jsonv2.Object{
+				{
+					Path:    "disks",
+					Tags:    []string{"name"},
+					Renames: map[string]string{"name": "device"},
+				},
+			},
+		},
+	}, pc.JSONV2Config)
+}
+
+func TestConfig_XPathParser(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/xpath.toml")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Inputs))
+
+	data, err := ioutil.ReadFile("./testdata/xpath.toml")
+	require.NoError(t, err)
+	tbl, err := parseConfig(data)
+	require.NoError(t, err)
+	inputs, ok := tbl.Fields["inputs"].(*ast.Table)
+	require.True(t, ok)
+	files, ok := inputs.Fields["file"].([]*ast.Table)
+	require.True(t, ok)
+
+	pc, err := c.getParserConfig("file", files[0])
+	require.NoError(t, err)
+	require.Equal(t, []xpath.Config{
+		{
+			Selection: "/Gateway/Bus/Sensor",
+			Timestamp: "/Gateway/Timestamp",
+			Tags:      map[string]string{"name": "@name"},
+			Fields:    map[string]string{"temperature": "number(Variable/@temperature)"},
+		},
+	}, pc.XPathConfig)
+}
+
 func TestConfig_WrongFieldType(t *testing.T) {
 	c := NewConfig()
 	err := c.LoadConfig("./testdata/wrong_field_type.toml")
@@ -276,3 +344,85 @@ func TestConfig_BadOrdering(t *testing.T) {
 // 	assert.Equal(t, "", azureMonitor.NamespacePrefix)
 // 	assert.Equal(t, true, ok)
 // }
+
+func TestConfig_EnvVarsAndFileReferences(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/server"
+	require.NoError(t, ioutil.WriteFile(path, []byte("10.0.0.1:11211\n"), 0600))
+	os.Setenv("TEST_CUA_SERVER", `192.168.1.1:11211`)
+	os.Setenv("TEST_CUA_DIR", dir)
+	defer os.Unsetenv("TEST_CUA_SERVER")
+	defer os.Unsetenv("TEST_CUA_DIR")
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  instance_id = "test"
+  # servers = ["{file:/missing/in/comment}"]
+  servers = ["${TEST_CUA_SERVER}", "{file:${TEST_CUA_DIR}/server}", "$TEST_CUA_UNDEFINED"]
+`)))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, []string{"192.168.1.1:11211", "10.0.0.1:11211", "$TEST_CUA_UNDEFINED"},
+		c.Inputs[0].Input.(*memcached.Memcached).Servers)
+
+	// the references in the values of the environment variables are not
+	// expanded
+	os.Setenv("TEST_CUA_REF", "{file:"+path+"}")
+	defer os.Unsetenv("TEST_CUA_REF")
+	c = NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  instance_id = "test"
+  servers = ["${TEST_CUA_REF}"]
+`)))
+	require.Equal(t, []string{"{file:" + path + "}"}, c.Inputs[0].Input.(*memcached.Memcached).Servers)
+
+	c = NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  instance_id = "test"
+  servers = ["{file:/missing/server}"]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 4: file reference")
+}
+
+func TestParseConfig_EscapesValues(t *testing.T) {
+	os.Setenv("TEST_CUA_MULTILINE", "a \"quoted\"\nvalue\\")
+	defer os.Unsetenv("TEST_CUA_MULTILINE")
+
+	tbl, err := parseConfig([]byte(`value = "${TEST_CUA_MULTILINE}"`))
+	require.NoError(t, err)
+	var v struct {
+		Value string `toml:"value"`
+	}
+	require.NoError(t, toml.UnmarshalTable(tbl, &v))
+	require.Equal(t, "a \"quoted\"\nvalue\\", v.Value)
+}
+
+func TestConfig_LogLevels(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[agent]
+  log_level = "warn"
+  log_format = "json"
+
+[[inputs.memcached]]
+  instance_id = "test"
+  log_level = "debug"
+
+[[inputs.memcached]]
+  instance_id = "other"
+`)))
+	require.Equal(t, "warn", c.Agent.LogLevel)
+	require.Equal(t, "json", c.Agent.LogFormat)
+	require.Equal(t, map[string]string{"inputs.memcached::test": "debug"}, c.PluginLogLevels())
+
+	for _, data := range []string{
+		"[agent]\n  log_level = \"trace\"\n",
+		"[agent]\n  log_format = \"xml\"\n",
+		"[[inputs.memcached]]\n  instance_id = \"test\"\n  log_level = \"verbose\"\n",
+	} {
+		require.Error(t, NewConfig().LoadConfigData([]byte(data)), data)
+	}
+}
@@ -0,0 +1,443 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+const (
+	// redacted replaces the values of the secrets
+	redacted = "REDACTED"
+	// depth of the nested tables of a plugin above which the fields are not
+	// written, the plugins may have exported fields which are not options
+	effectiveMaxDepth = 4
+)
+
+// effectiveSecretKey matches the keys of the options holding secrets, the
+// fields of the plugins with other keys are marked with the secret:"true"
+// struct tag.
+var effectiveSecretKey = regexp.MustCompile(`(?i)(password|passwd|passphrase|secret|token|api_?key|private_key)`)
+
+// loadedPlugin is the configuration of a plugin, kept to write the effective
+// configuration.
+type loadedPlugin struct {
+	kind   string
+	name   string
+	plugin interface{}
+	table  *ast.Table
+}
+
+var (
+	durationType         = reflect.TypeOf(time.Duration(0))
+	internalDurationType = reflect.TypeOf(internal.Duration{})
+	internalSizeType     = reflect.TypeOf(internal.Size{})
+	textMarshalerType    = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// tomlTable is a table of the effective configuration, with its key/value
+// pairs and nested tables.
+type tomlTable struct {
+	name    string
+	array   bool
+	entries []tomlEntry
+	tables  []*tomlTable
+}
+
+type tomlEntry struct {
+	key   string
+	value string
+}
+
+func (t *tomlTable) has(key string) bool {
+	for _, e := range t.entries {
+		if e.key == key {
+			return true
+		}
+	}
+	for _, child := range t.tables {
+		if child.name == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tomlTable) addEntry(key, value string) {
+	t.addSecret(key, value, effectiveSecretKey.MatchString(key))
+}
+
+// addSecret adds a key/value pair, redacting the value when secret and set.
+func (t *tomlTable) addSecret(key, value string, secret bool) {
+	if secret && value != `""` && value != "[]" {
+		value = strconv.Quote(redacted)
+	}
+	t.entries = append(t.entries, tomlEntry{key: key, value: value})
+}
+
+// addStruct adds the exported fields of a plugin, with the keys of the
+// configuration: the toml tags or the snake case names of the fields.
+func (t *tomlTable) addStruct(rv reflect.Value, depth int) {
+	rt := rv.Type()
+	for i := 0; i < rv.NumField(); i++ {
+		ft := rt.Field(i)
+		if ft.PkgPath != "" && !ft.Anonymous {
+			continue
+		}
+		key := strings.Split(ft.Tag.Get("toml"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if ft.Anonymous && key == "" {
+			// the embedded structs, such as the TLS options, are flattened
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if _, ok := scalarValue(fv); !ok {
+					t.addStruct(fv, depth)
+				}
+			}
+			continue
+		}
+		if key == "" {
+			key = toml.DefaultConfig.FieldToKey(rt, ft.Name)
+		}
+		if ft.Type.Kind() == reflect.Interface {
+			// the interfaces, such as the loggers, are options only when
+			// holding a value
+			if fv.IsNil() {
+				continue
+			}
+			if s, ok := scalarValue(fv.Elem()); ok {
+				t.addEntry(key, s)
+			}
+			continue
+		}
+		if ft.Tag.Get("secret") == "true" {
+			t.addSecretValue(key, fv)
+			continue
+		}
+		t.addValue(key, fv, depth)
+	}
+}
+
+// addSecretValue adds the redacted value of a field marked as a secret.
+func (t *tomlTable) addSecretValue(key string, v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if s, ok := scalarValue(v); ok {
+		t.addSecret(key, s, true)
+	}
+}
+
+func (t *tomlTable) addValue(key string, v reflect.Value, depth int) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if s, ok := scalarValue(v); ok {
+		t.addEntry(key, s)
+		return
+	}
+	if depth >= effectiveMaxDepth {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		child := &tomlTable{name: key}
+		child.addStruct(v, depth+1)
+		if len(child.entries) > 0 || len(child.tables) > 0 {
+			t.tables = append(t.tables, child)
+		}
+	case reflect.Map:
+		child := &tomlTable{name: key}
+		child.addMap(v, depth+1)
+		if len(child.entries) > 0 || len(child.tables) > 0 {
+			t.tables = append(t.tables, child)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			for e.Kind() == reflect.Ptr || e.Kind() == reflect.Interface {
+				if e.IsNil() {
+					break
+				}
+				e = e.Elem()
+			}
+			child := &tomlTable{name: key, array: true}
+			switch e.Kind() {
+			case reflect.Struct:
+				child.addStruct(e, depth+1)
+			case reflect.Map:
+				child.addMap(e, depth+1)
+			default:
+				continue
+			}
+			t.tables = append(t.tables, child)
+		}
+	}
+}
+
+func (t *tomlTable) addMap(v reflect.Value, depth int) {
+	keys := v.MapKeys()
+	names := make(map[string]reflect.Value, len(keys))
+	for _, k := range keys {
+		switch {
+		case k.Kind() == reflect.String:
+			names[k.String()] = k
+		case k.CanInterface():
+			names[fmt.Sprint(k.Interface())] = k
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		t.addValue(name, v.MapIndex(names[name]), depth)
+	}
+}
+
+// addTable adds the key/value pairs and tables of the configuration not
+// already added, such as the options common to the plugins or of the data
+// formats.
+func (t *tomlTable) addTable(tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		if !t.has(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch field := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			t.addEntry(key, astValue(field.Value))
+		case *ast.Table:
+			child := &tomlTable{name: key}
+			child.addTable(field)
+			t.tables = append(t.tables, child)
+		case []*ast.Table:
+			for _, sub := range field {
+				child := &tomlTable{name: key, array: true}
+				child.addTable(sub)
+				t.tables = append(t.tables, child)
+			}
+		}
+	}
+}
+
+func (t *tomlTable) write(w io.Writer, prefix, indent string) error {
+	name := t.name
+	if prefix != "" {
+		name = prefix + "." + name
+	}
+	header := "[" + name + "]"
+	if t.array {
+		header = "[" + header + "]"
+	}
+	if _, err := fmt.Fprintf(w, "%s%s\n", indent, header); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	for _, e := range t.entries {
+		if _, err := fmt.Fprintf(w, "%s  %s = %s\n", indent, quoteKey(e.key), e.value); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+	for _, child := range t.tables {
+		if err := child.write(w, name, indent+"  "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the TOML value of v, false when v is a table or is not
+// an option.
+func scalarValue(v reflect.Value) (string, bool) {
+	switch v.Type() {
+	case durationType:
+		return strconv.Quote(time.Duration(v.Int()).String()), true
+	case internalDurationType:
+		return strconv.Quote(time.Duration(v.Field(0).Int()).String()), true
+	case internalSizeType:
+		return strconv.FormatInt(v.Field(0).Int(), 10), true
+	}
+	if v.Type().Implements(textMarshalerType) && v.CanInterface() {
+		if text, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return strconv.Quote(string(text)), true
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(redactURL(v.String())), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		s := strconv.FormatFloat(v.Float(), 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eIN") {
+			s += ".0"
+		}
+		return s, true
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return strconv.Quote(string(v.Bytes())), true
+		}
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			for e.Kind() == reflect.Ptr || e.Kind() == reflect.Interface {
+				if e.IsNil() {
+					return "", false
+				}
+				e = e.Elem()
+			}
+			s, ok := scalarValue(e)
+			if !ok {
+				return "", false
+			}
+			values = append(values, s)
+		}
+		if len(values) == 0 && isTableType(v.Type().Elem()) {
+			return "", false
+		}
+		return "[" + strings.Join(values, ", ") + "]", true
+	}
+	return "", false
+}
+
+// isTableType returns whether the values of the type are written as tables.
+func isTableType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case internalDurationType, internalSizeType:
+		return false
+	}
+	if t.Implements(textMarshalerType) {
+		return false
+	}
+	return t.Kind() == reflect.Struct || t.Kind() == reflect.Map
+}
+
+func astValue(v ast.Value) string {
+	switch v := v.(type) {
+	case *ast.String:
+		return strconv.Quote(redactURL(v.Value))
+	case *ast.Array:
+		values := make([]string, 0, len(v.Value))
+		for _, e := range v.Value {
+			values = append(values, astValue(e))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	}
+	return v.Source()
+}
+
+// redactURL redacts the password of the URLs with credentials, such as the
+// data source names of the databases.
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") || !strings.Contains(s, "@") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	u.User = url.UserPassword(u.User.Username(), redacted)
+	return u.String()
+}
+
+func quoteKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return strconv.Quote(key)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+// WriteEffective writes the effective configuration: the agent options and
+// the plugins loaded from all the configuration files, with the environment
+// variables substituted, and the default values of the options not set.  The
+// values of the options holding secrets, and the passwords of the URLs, are
+// redacted.
+func (c *Config) WriteEffective(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "# Effective configuration, the secrets are redacted"); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	tags := &tomlTable{name: "global_tags"}
+	tags.addMap(reflect.ValueOf(c.Tags), 0)
+	agent := &tomlTable{name: "agent"}
+	agent.addStruct(reflect.ValueOf(c.Agent).Elem(), 0)
+	tables := []*tomlTable{tags, agent}
+
+	plugins := make([]loadedPlugin, len(c.plugins))
+	copy(plugins, c.plugins)
+	order := map[string]int{"outputs": 0, "processors": 1, "aggregators": 2, "inputs": 3}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		if plugins[i].kind != plugins[j].kind {
+			return order[plugins[i].kind] < order[plugins[j].kind]
+		}
+		return plugins[i].name < plugins[j].name
+	})
+	for _, p := range plugins {
+		t := &tomlTable{name: p.kind + "." + p.name, array: true}
+		v := reflect.ValueOf(p.plugin)
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Struct {
+			t.addStruct(v, 0)
+		}
+		t.addTable(p.table)
+		tables = append(tables, t)
+	}
+
+	for _, t := range tables {
+		if _, err := fmt.Fprintln(w); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+		if err := t.write(w, "", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/models"
+	"github.com/influxdata/toml/ast"
+)
+
+const (
+	// distinct values of a tag in a single gather above which the tag is
+	// considered unbounded
+	lintMaxTagValues = 100
+	// shortest interval of an input not reported
+	lintMinInterval = time.Second
+)
+
+var (
+	// tag keys usually holding a value per event rather than per series
+	lintUnboundedTagKey = regexp.MustCompile(`(?i)^((.*[_.-])?(request|trace|span|session|transaction|txn|correlation|message|event)[_.-]?id|` +
+		`uuid|guid|timestamp|time|url|uri|query|message|msg|user_agent|useragent|email|client_ip|remote_addr|src_port|source_port)$`)
+	// tag values looking like unique identifiers
+	lintUniqueValue = regexp.MustCompile(`(?i)^([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[0-9a-f]{24,}|[0-9]{10,})$`)
+
+	// options of the parsers turning values into tags
+	lintTagKeyOptions = []string{"tag_keys", "csv_tag_columns", "form_urlencoded_tag_keys"}
+)
+
+// LintIssue is a likely problem found in the configuration, or in the
+// metrics it produces.
+type LintIssue struct {
+	// Plugin is the plugin the issue is about, empty for the agent.
+	Plugin  string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	if i.Plugin == "" {
+		return "[agent] " + i.Message
+	}
+	return "[" + i.Plugin + "] " + i.Message
+}
+
+// Lint statically analyzes the loaded configuration and returns the likely
+// problems found: missing intervals, duplicate plugin instances, conflicting
+// name_override values and parser options creating unbounded tags.
+func (c *Config) Lint() []LintIssue {
+	var issues []LintIssue
+	add := func(plugin, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Plugin: plugin, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Agent.Interval.Duration <= 0 {
+		add("", "interval is not set, it must be positive")
+	}
+	if c.Agent.FlushInterval.Duration <= 0 {
+		add("", "flush_interval is not set, it must be positive")
+	}
+	if len(c.Inputs) == 0 {
+		add("", "no inputs configured")
+	}
+	if len(c.Outputs) == 0 {
+		add("", "no outputs configured")
+	}
+
+	// the order of the plugins of different types is not preserved when
+	// loading the configuration
+	inputs := make([]*models.RunningInput, len(c.Inputs))
+	copy(inputs, c.Inputs)
+	sort.SliceStable(inputs, func(i, j int) bool {
+		return inputs[i].LogName() < inputs[j].LogName()
+	})
+
+	instances := map[string][]*models.RunningInput{}
+	configs := map[string][]*models.RunningInput{}
+	overrides := map[string][]*models.RunningInput{}
+	for _, input := range inputs {
+		name := input.LogName()
+
+		interval := c.Agent.Interval.Duration
+		if input.Config.Interval != 0 {
+			interval = input.Config.Interval
+		}
+		switch {
+		case interval > 0 && interval < lintMinInterval:
+			add(name, "interval of %s is shorter than %s", interval, lintMinInterval)
+		case input.Config.CollectionJitter != 0 && input.Config.CollectionJitter >= interval:
+			add(name, "collection_jitter of %s is not shorter than the interval of %s", input.Config.CollectionJitter, interval)
+		}
+		if input.Config.Precision > interval {
+			add(name, "precision of %s is longer than the interval of %s, the metrics of successive gathers share their timestamp",
+				input.Config.Precision, interval)
+		}
+
+		key := input.Config.Name + "\x00" + input.Config.InstanceID
+		instances[key] = append(instances[key], input)
+
+		if table, ok := c.inputTables[input]; ok {
+			key := input.Config.Name + "\x00" + lintCanonical(table)
+			configs[key] = append(configs[key], input)
+
+			for _, option := range lintTagKeyOptions {
+				var keys []string
+				c.getFieldStringSlice(table, option, &keys)
+				for _, k := range keys {
+					if lintUnboundedTagKey.MatchString(k) {
+						add(name, "%s makes %q a tag, its values are likely unbounded", option, k)
+					}
+				}
+			}
+		}
+
+		if input.Config.NameOverride != "" {
+			measurement := input.Config.MeasurementPrefix + input.Config.NameOverride + input.Config.MeasurementSuffix
+			overrides[measurement] = append(overrides[measurement], input)
+		}
+	}
+
+	for _, key := range lintSortedKeys(instances) {
+		if dups := instances[key]; len(dups) > 1 {
+			add(dups[0].LogName(), "instance_id %q is used by %d inputs.%s plugins", dups[0].Config.InstanceID, len(dups), dups[0].Config.Name)
+		}
+	}
+	for _, key := range lintSortedKeys(configs) {
+		if dups := configs[key]; len(dups) > 1 {
+			add(dups[0].LogName(), "same configuration as %s, the metrics are gathered twice", lintNames(dups[1:]))
+		}
+	}
+	for _, measurement := range lintSortedKeys(overrides) {
+		inputs := overrides[measurement]
+		plugins := map[string]bool{}
+		for _, input := range inputs {
+			plugins[input.Config.Name] = true
+		}
+		if len(plugins) > 1 {
+			add(inputs[0].LogName(), "name_override produces the measurement %q also produced by %s", measurement, lintNames(inputs[1:]))
+		}
+	}
+
+	return issues
+}
+
+// LintMetrics analyzes the metrics of a single gather and returns the tags
+// likely to be unbounded: tags with many distinct values, with values looking
+// like unique identifiers, or with keys usually holding a value per event.
+func LintMetrics(metrics []cua.Metric) []LintIssue {
+	type tagStats struct {
+		origin string
+		values map[string]bool
+		unique int
+	}
+	stats := map[string]*tagStats{}
+	for _, m := range metrics {
+		origin := "inputs." + m.Origin()
+		if m.OriginInstance() != "" {
+			origin += "::" + m.OriginInstance()
+		}
+		for _, tag := range m.TagList() {
+			key := origin + "\x00" + m.Name() + "\x00" + tag.Key
+			s, ok := stats[key]
+			if !ok {
+				s = &tagStats{origin: origin, values: map[string]bool{}}
+				stats[key] = s
+			}
+			if !s.values[tag.Value] {
+				s.values[tag.Value] = true
+				if lintUniqueValue.MatchString(tag.Value) {
+					s.unique++
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var issues []LintIssue
+	for _, key := range keys {
+		s := stats[key]
+		parts := strings.SplitN(key, "\x00", 3)
+		measurement, tag := parts[1], parts[2]
+		switch {
+		case len(s.values) > lintMaxTagValues:
+			issues = append(issues, LintIssue{Plugin: s.origin,
+				Message: fmt.Sprintf("tag %q of %q has %d distinct values in a single gather", tag, measurement, len(s.values))})
+		case s.unique > 0 && s.unique*2 >= len(s.values):
+			issues = append(issues, LintIssue{Plugin: s.origin,
+				Message: fmt.Sprintf("tag %q of %q has values looking like unique identifiers", tag, measurement)})
+		case lintUnboundedTagKey.MatchString(tag):
+			issues = append(issues, LintIssue{Plugin: s.origin,
+				Message: fmt.Sprintf("tag %q of %q is likely unbounded", tag, measurement)})
+		}
+	}
+	return issues
+}
+
+// lintCanonical returns a representation of a plugin table independent of
+// its formatting, without the options identifying the instance.
+func lintCanonical(table *ast.Table) string {
+	var b strings.Builder
+	keys := make([]string, 0, len(table.Fields))
+	for key := range table.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "instance_id" || key == "alias" {
+			continue
+		}
+		b.WriteString(key)
+		switch node := table.Fields[key].(type) {
+		case *ast.KeyValue:
+			b.WriteString("=")
+			b.WriteString(lintValue(node.Value))
+		case *ast.Table:
+			b.WriteString("{" + lintCanonical(node) + "}")
+		case []*ast.Table:
+			for _, t := range node {
+				b.WriteString("[" + lintCanonical(t) + "]")
+			}
+		}
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+func lintValue(value ast.Value) string {
+	if array, ok := value.(*ast.Array); ok {
+		values := make([]string, 0, len(array.Value))
+		for _, v := range array.Value {
+			values = append(values, lintValue(v))
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	}
+	return value.Source()
+}
+
+func lintNames(inputs []*models.RunningInput) string {
+	names := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		names = append(names, input.LogName())
+	}
+	return strings.Join(names, ", ")
+}
+
+func lintSortedKeys(m map[string][]*models.RunningInput) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/discard"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+)
+
+func lintMessages(issues []LintIssue) []string {
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		messages = append(messages, issue.String())
+	}
+	return messages
+}
+
+func TestLint(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  instance_id = "cache"
+  servers = ["localhost"]
+  interval = "500ms"
+
+[[inputs.memcached]]
+  instance_id = "cache"
+  servers = ["cache01"]
+
+[[inputs.memcached]]
+  instance_id = "cache-copy"
+  servers = [ "localhost" ]
+  interval = "500ms"
+
+[[inputs.exec]]
+  instance_id = "requests"
+  commands = ["/usr/local/bin/requests.sh"]
+  data_format = "json"
+  tag_keys = ["endpoint", "request_id"]
+  name_override = "app"
+  collection_jitter = "10s"
+
+[[inputs.procstat]]
+  instance_id = "app"
+  pattern = "app"
+  name_override = "app"
+  precision = "1m"
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"[agent] no outputs configured",
+		"[inputs.exec::requests] collection_jitter of 10s is not shorter than the interval of 10s",
+		`[inputs.exec::requests] tag_keys makes "request_id" a tag, its values are likely unbounded`,
+		"[inputs.memcached::cache] interval of 500ms is shorter than 1s",
+		"[inputs.memcached::cache-copy] interval of 500ms is shorter than 1s",
+		"[inputs.procstat::app] precision of 1m0s is longer than the interval of 10s, the metrics of successive gathers share their timestamp",
+		`[inputs.memcached::cache] instance_id "cache" is used by 2 inputs.memcached plugins`,
+		"[inputs.memcached::cache] same configuration as inputs.memcached::cache-copy, the metrics are gathered twice",
+		`[inputs.exec::requests] name_override produces the measurement "app" also produced by inputs.procstat::app`,
+	}, lintMessages(c.Lint()))
+}
+
+func TestLintClean(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  instance_id = "cache"
+  servers = ["localhost"]
+
+[[inputs.memcached]]
+  instance_id = "cache2"
+  servers = ["cache01"]
+
+[[outputs.discard]]
+`))
+	require.NoError(t, err)
+	require.Empty(t, c.Lint())
+}
+
+func TestLintMetrics(t *testing.T) {
+	newMetric := func(tags map[string]string) cua.Metric {
+		m := testutil.MustMetric("http", tags, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+		m.SetOrigin("http_listener_v2")
+		m.SetOriginInstance("api")
+		return m
+	}
+
+	var metrics []cua.Metric
+	for i := 0; i < 150; i++ {
+		metrics = append(metrics, newMetric(map[string]string{
+			"method": "GET",
+			"path":   fmt.Sprintf("/users/%d", i),
+		}))
+	}
+	metrics = append(metrics,
+		newMetric(map[string]string{"method": "GET", "span": "5f0c9a6e-4a1b-4c2d-9e8f-0a1b2c3d4e5f"}),
+		newMetric(map[string]string{"method": "POST", "span": "7d2e0b3f-1a2b-4c3d-8e9f-1b2c3d4e5f60"}),
+		newMetric(map[string]string{"method": "POST", "trace_id": "abc"}),
+	)
+
+	require.Equal(t, []string{
+		`[inputs.http_listener_v2::api] tag "path" of "http" has 150 distinct values in a single gather`,
+		`[inputs.http_listener_v2::api] tag "span" of "http" has values looking like unique identifiers`,
+		`[inputs.http_listener_v2::api] tag "trace_id" of "http" is likely unbounded`,
+	}, lintMessages(LintMetrics(metrics)))
+}
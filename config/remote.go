@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+)
+
+// Remote fetches the configurations served over HTTP(S).  The configurations
+// are cached with their ETag in the cache directory: they are only
+// downloaded again when changed, and the cached copy is used when the server
+// cannot be reached.
+type Remote struct {
+	// Headers are added to the requests, such as Authorization.
+	Headers map[string]string
+	// CacheDir is the directory of the cached copies, none when empty.
+	CacheDir string
+	// Timeout of the requests.
+	Timeout time.Duration
+
+	mu sync.Mutex
+	// hashes of the configurations loaded, to detect their changes
+	loaded map[string][32]byte
+	// ETag and body of the configurations fetched, when not cached on disk
+	etags  map[string]string
+	bodies map[string][]byte
+}
+
+// Fetch returns the configuration of the URL, loaded by the agent.
+func (r *Remote) Fetch(u string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, err := r.fetch(u)
+	if err != nil {
+		return nil, err
+	}
+	if r.loaded == nil {
+		r.loaded = make(map[string][32]byte)
+	}
+	r.loaded[u] = sha256.Sum256(body)
+	return body, nil
+}
+
+// Changed reports whether the configuration of the URL changed since it was
+// loaded.  The configuration is considered unchanged when it cannot be
+// fetched.
+func (r *Remote) Changed(u string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, err := r.fetch(u)
+	if err != nil {
+		log.Printf("W! Checking the config %s: %v", u, err)
+		return false
+	}
+	loaded, ok := r.loaded[u]
+	return ok && loaded != sha256.Sum256(body)
+}
+
+func (r *Remote) fetch(u string) ([]byte, error) {
+	etag, cached := r.cached(u)
+
+	body, err := r.get(u, etag, cached != nil)
+	if err != nil {
+		if cached == nil {
+			return nil, err
+		}
+		log.Printf("W! Using the cached copy of the config %s: %v", u, err)
+		return cached, nil
+	}
+	if body == nil {
+		// not modified
+		return cached, nil
+	}
+	return body, nil
+}
+
+// get requests the configuration of the URL, nil is returned when the
+// configuration did not change since its ETag.
+func (r *Remote) get(u, etag string, conditional bool) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http new req (%s): %w", u, err)
+	}
+	if v, exists := os.LookupEnv("INFLUX_TOKEN"); exists {
+		req.Header.Add("Authorization", "Token "+v)
+	}
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Add("Accept", "application/toml")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	if conditional && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := &http.Client{Timeout: r.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if conditional {
+			return nil, nil
+		}
+		fallthrough
+	default:
+		return nil, fmt.Errorf("failed to retrieve remote config: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if err := r.store(u, resp.Header.Get("ETag"), body); err != nil {
+		log.Printf("W! Caching the config %s: %v", u, err)
+	}
+	return body, nil
+}
+
+// cachePath returns the path of the cached copy of the URL, without
+// extension.
+func (r *Remote) cachePath(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(r.CacheDir, hex.EncodeToString(sum[:8]))
+}
+
+// cached returns the ETag and the body of the cached copy of the URL, a nil
+// body when not cached.
+func (r *Remote) cached(u string) (string, []byte) {
+	if r.CacheDir == "" {
+		return r.etags[u], r.bodies[u]
+	}
+	body, err := os.ReadFile(r.cachePath(u) + ".conf")
+	if err != nil {
+		return "", nil
+	}
+	etag, _ := os.ReadFile(r.cachePath(u) + ".etag")
+	return string(bytes.TrimSpace(etag)), body
+}
+
+func (r *Remote) store(u, etag string, body []byte) error {
+	if r.CacheDir == "" {
+		if r.etags == nil {
+			r.etags = make(map[string]string)
+			r.bodies = make(map[string][]byte)
+		}
+		r.etags[u], r.bodies[u] = etag, body
+		return nil
+	}
+
+	// the configurations may hold secrets
+	if err := os.MkdirAll(r.CacheDir, 0700); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+	path := r.cachePath(u)
+	if err := writeFileAtomic(path+".conf", body); err != nil {
+		return err
+	}
+	return writeFileAtomic(path+".etag", []byte(etag))
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
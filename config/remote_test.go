@@ -0,0 +1,108 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type configServer struct {
+	sync.Mutex
+	body     string
+	etag     string
+	down     bool
+	requests []*http.Request
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.requests = append(s.requests, r)
+	switch {
+	case s.down:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case s.etag != "" && r.Header.Get("If-None-Match") == s.etag:
+		w.WriteHeader(http.StatusNotModified)
+	default:
+		if s.etag != "" {
+			w.Header().Set("ETag", s.etag)
+		}
+		_, _ = w.Write([]byte(s.body))
+	}
+}
+
+func (s *configServer) set(body, etag string, down bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.body, s.etag, s.down = body, etag, down
+}
+
+func TestRemote(t *testing.T) {
+	s := &configServer{body: "[[inputs.cpu]]\n", etag: `"1"`}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	u := ts.URL + "/agent.conf"
+
+	r := &Remote{
+		Headers:  map[string]string{"Authorization": "Bearer secret"},
+		CacheDir: t.TempDir(),
+		Timeout:  time.Second,
+	}
+	body, err := r.Fetch(u)
+	require.NoError(t, err)
+	require.Equal(t, "[[inputs.cpu]]\n", string(body))
+	require.Equal(t, "Bearer secret", s.requests[0].Header.Get("Authorization"))
+	require.Empty(t, s.requests[0].Header.Get("If-None-Match"))
+
+	// not modified
+	require.False(t, r.Changed(u))
+	require.Equal(t, `"1"`, s.requests[1].Header.Get("If-None-Match"))
+
+	// the cached copy is used when the server is down, by another agent too
+	s.set("", "", true)
+	require.False(t, r.Changed(u))
+	body, err = (&Remote{CacheDir: r.CacheDir, Timeout: time.Second}).Fetch(u)
+	require.NoError(t, err)
+	require.Equal(t, "[[inputs.cpu]]\n", string(body))
+
+	s.set("[[inputs.mem]]\n", `"2"`, false)
+	require.True(t, r.Changed(u))
+	body, err = r.Fetch(u)
+	require.NoError(t, err)
+	require.Equal(t, "[[inputs.mem]]\n", string(body))
+	require.False(t, r.Changed(u))
+}
+
+func TestRemoteWithoutCache(t *testing.T) {
+	s := &configServer{body: "[[inputs.cpu]]\n"}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	r := &Remote{Timeout: time.Second}
+	_, err := r.Fetch(ts.URL)
+	require.NoError(t, err)
+	require.False(t, r.Changed(ts.URL))
+
+	s.set("[[inputs.mem]]\n", "", false)
+	require.True(t, r.Changed(ts.URL))
+
+	s.set("", "", true)
+	_, err = (&Remote{Timeout: time.Second}).Fetch(ts.URL)
+	require.Error(t, err)
+}
+
+func TestConfig_LoadRemote(t *testing.T) {
+	s := &configServer{body: "[[inputs.memcached]]\n  instance_id = \"test\"\n", etag: `"1"`}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote = &Remote{Headers: map[string]string{"X-Fleet": "edge"}, Timeout: time.Second}
+	require.NoError(t, c.LoadConfig(ts.URL+"/agent.conf"))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, "edge", s.requests[0].Header.Get("X-Fleet"))
+}
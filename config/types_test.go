@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/config"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/discard"
 	reversedns "github.com/circonus-labs/circonus-unified-agent/plugins/processors/reverse_dns"
 	"github.com/stretchr/testify/require"
 )
@@ -29,3 +30,39 @@ func TestConfigDuration(t *testing.T) {
 	require.Equal(t, p.MaxParallelLookups, 13)
 	require.Equal(t, p.Ordered, true)
 }
+
+func TestConfigInputQuotas(t *testing.T) {
+	c := config.NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.exec]]
+  instance_id = "scripts"
+  commands = ["/usr/local/bin/report.sh"]
+  data_format = "json"
+  max_metrics_per_gather = 1000
+  max_fields_per_metric = 50
+  max_string_field_length = 1024
+`))
+	require.NoError(t, err)
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, 1000, c.Inputs[0].Config.MaxMetricsPerGather)
+	require.Equal(t, 50, c.Inputs[0].Config.MaxFieldsPerMetric)
+	require.Equal(t, 1024, c.Inputs[0].Config.MaxStringFieldLength)
+	require.Empty(t, c.UnusedFields)
+}
+
+func TestConfigOutputTransformations(t *testing.T) {
+	c := config.NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.discard]]
+  name_prefix = "host_"
+  fieldpass = ["usage_*"]
+  [outputs.discard.tags]
+    destination = "archive"
+`))
+	require.NoError(t, err)
+	require.Len(t, c.Outputs, 1)
+	require.Equal(t, "host_", c.Outputs[0].Config.NamePrefix)
+	require.Equal(t, []string{"usage_*"}, c.Outputs[0].Config.Filter.FieldPass)
+	require.Equal(t, map[string]string{"destination": "archive"}, c.Outputs[0].Config.Tags)
+	require.Empty(t, c.UnusedFields)
+}
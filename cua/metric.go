@@ -142,4 +142,9 @@ type Metric interface {
 	OriginInstance() string
 	// SetOriginInstance sets the origin instance id
 	SetOriginInstance(string)
+	// Arrival gets the time the metric entered the pipeline, the zero time
+	// when unknown
+	Arrival() time.Time
+	// SetArrival sets the time the metric entered the pipeline
+	SetArrival(time.Time)
 }
@@ -0,0 +1,237 @@
+// Package cgroup provides functions for reading the resource limits of the
+// control group of the process, both cgroup v1 and v2 are supported.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var (
+	procSelfCgroup = "/proc/self/cgroup"
+	mountPoint     = "/sys/fs/cgroup"
+)
+
+// cgroup v1 reports "no limit" as a very large page aligned value.
+const unlimitedV1 = 1 << 62
+
+// MemoryLimit returns the memory limit in bytes of the process, the lowest
+// limit of its cgroup and the parent cgroups.  Zero is returned when the
+// memory is not limited or cgroups are not available.
+func MemoryLimit() (uint64, error) {
+	dirs, v2, err := controllerDirs("memory")
+	if err != nil || len(dirs) == 0 {
+		return 0, err
+	}
+
+	var limit uint64
+	for _, dir := range dirs {
+		var l uint64
+		var ok bool
+		if v2 {
+			l, ok, err = readLimit(filepath.Join(dir, "memory.max"))
+		} else {
+			l, ok, err = readLimit(filepath.Join(dir, "memory.limit_in_bytes"))
+			if l >= unlimitedV1 {
+				ok = false
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		if ok && (limit == 0 || l < limit) {
+			limit = l
+		}
+	}
+	return limit, nil
+}
+
+// CPULimit returns the number of CPUs the process may use according to the
+// CFS quota of its cgroup and the parent cgroups, ie 1.5 for a quota of 150ms
+// per 100ms period.  Zero is returned when the CPU is not limited or cgroups
+// are not available.
+func CPULimit() (float64, error) {
+	dirs, v2, err := controllerDirs("cpu")
+	if err != nil || len(dirs) == 0 {
+		return 0, err
+	}
+
+	var limit float64
+	for _, dir := range dirs {
+		var l float64
+		var ok bool
+		if v2 {
+			l, ok, err = readCPUMax(filepath.Join(dir, "cpu.max"))
+		} else {
+			l, ok, err = readCFSQuota(dir)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if ok && (limit == 0 || l < limit) {
+			limit = l
+		}
+	}
+	return limit, nil
+}
+
+// AvailableCPUs returns the number of CPUs of the host, or the CPU limit of
+// the cgroup rounded up if lower.
+func AvailableCPUs() int {
+	cpus := runtime.NumCPU()
+	limit, err := CPULimit()
+	if err != nil || limit == 0 {
+		return cpus
+	}
+	if n := int(math.Ceil(limit)); n < cpus {
+		return n
+	}
+	return cpus
+}
+
+// readCPUMax reads the cgroup v2 "$MAX $PERIOD" quota.
+func readCPUMax(path string) (float64, bool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("cgroup: %w", err)
+	}
+	parts := strings.Fields(string(b))
+	if len(parts) == 0 || len(parts) > 2 {
+		return 0, false, fmt.Errorf("cgroup: invalid %s: %q", path, b)
+	}
+	if parts[0] == "max" {
+		return 0, false, nil
+	}
+	quota, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("cgroup: parsing %s: %w", path, err)
+	}
+	// default period
+	period := uint64(100000)
+	if len(parts) == 2 {
+		period, err = strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("cgroup: parsing %s: %w", path, err)
+		}
+	}
+	if period == 0 {
+		return 0, false, nil
+	}
+	return float64(quota) / float64(period), true, nil
+}
+
+// readCFSQuota reads the cgroup v1 quota, -1 means no limit.
+func readCFSQuota(dir string) (float64, bool, error) {
+	quotaPath := filepath.Join(dir, "cpu.cfs_quota_us")
+	b, err := ioutil.ReadFile(quotaPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("cgroup: %w", err)
+	}
+	quota, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("cgroup: parsing %s: %w", quotaPath, err)
+	}
+	if quota <= 0 {
+		return 0, false, nil
+	}
+
+	period, ok, err := readLimit(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil || !ok || period == 0 {
+		return 0, false, err
+	}
+	return float64(quota) / float64(period), true, nil
+}
+
+// controllerDirs returns the directories of the cgroup of the process for
+// the controller and of its parents up to the mount point, and whether the
+// unified (v2) hierarchy is used.
+func controllerDirs(controller string) ([]string, bool, error) {
+	f, err := os.Open(procSelfCgroup)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("cgroup: %w", err)
+	}
+	defer f.Close()
+
+	var v2Path string
+	var v2 bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2Path, v2 = parts[2], true
+			continue
+		}
+		for _, c := range strings.Split(parts[1], ",") {
+			if c == controller {
+				return walkUp(filepath.Join(mountPoint, controller), parts[2]), false, nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, fmt.Errorf("cgroup: %w", err)
+	}
+
+	if v2 {
+		return walkUp(mountPoint, v2Path), true, nil
+	}
+	return nil, false, nil
+}
+
+// walkUp returns the directories from root/path up to root.  Within a
+// container the cgroup path is often not visible below the mount point, the
+// root is then the cgroup of the container.
+func walkUp(root, path string) []string {
+	dirs := []string{}
+	for p := filepath.Clean("/" + path); ; p = filepath.Dir(p) {
+		dir := filepath.Join(root, p)
+		if _, err := os.Stat(dir); err == nil {
+			dirs = append(dirs, dir)
+		}
+		if p == "/" {
+			break
+		}
+	}
+	return dirs
+}
+
+// readLimit reads a limit file, ok is false when the file does not exist
+// or contains "max".
+func readLimit(path string) (uint64, bool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("cgroup: %w", err)
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "max" {
+		return 0, false, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("cgroup: parsing %s: %w", path, err)
+	}
+	return v, true, nil
+}
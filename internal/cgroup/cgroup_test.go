@@ -0,0 +1,129 @@
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T, selfCgroup string, files map[string]string) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	procSelf := filepath.Join(dir, "cgroup")
+	require.NoError(t, ioutil.WriteFile(procSelf, []byte(selfCgroup), 0600))
+
+	mount := filepath.Join(dir, "sys")
+	require.NoError(t, os.MkdirAll(mount, 0755))
+	for name, content := range files {
+		path := filepath.Join(mount, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+
+	oldProc, oldMount := procSelfCgroup, mountPoint
+	procSelfCgroup, mountPoint = procSelf, mount
+	t.Cleanup(func() { procSelfCgroup, mountPoint = oldProc, oldMount })
+}
+
+func TestMemoryLimitV2(t *testing.T) {
+	setup(t, "0::/system.slice/cua.service\n", map[string]string{
+		"system.slice/memory.max":             "max\n",
+		"system.slice/cua.service/memory.max": "536870912\n",
+	})
+	limit, err := MemoryLimit()
+	require.NoError(t, err)
+	require.Equal(t, uint64(536870912), limit)
+}
+
+func TestMemoryLimitV2Parent(t *testing.T) {
+	setup(t, "0::/kubepods/pod1/container1\n", map[string]string{
+		"kubepods/pod1/memory.max":            "268435456\n",
+		"kubepods/pod1/container1/memory.max": "max\n",
+	})
+	limit, err := MemoryLimit()
+	require.NoError(t, err)
+	require.Equal(t, uint64(268435456), limit)
+}
+
+func TestMemoryLimitV2Namespace(t *testing.T) {
+	// within a container the cgroup is mounted at the mount point
+	setup(t, "0::/docker/abc\n", map[string]string{
+		"memory.max": "1073741824\n",
+	})
+	limit, err := MemoryLimit()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1073741824), limit)
+}
+
+func TestMemoryLimitV1(t *testing.T) {
+	setup(t, "12:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n0::/\n", map[string]string{
+		"memory/memory.limit_in_bytes":            "9223372036854771712\n",
+		"memory/docker/abc/memory.limit_in_bytes": "104857600\n",
+	})
+	limit, err := MemoryLimit()
+	require.NoError(t, err)
+	require.Equal(t, uint64(104857600), limit)
+}
+
+func TestMemoryUnlimited(t *testing.T) {
+	setup(t, "4:memory:/\n", map[string]string{
+		"memory/memory.limit_in_bytes": "9223372036854771712\n",
+	})
+	limit, err := MemoryLimit()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), limit)
+}
+
+func TestMemoryLimitInvalid(t *testing.T) {
+	setup(t, "0::/\n", map[string]string{
+		"memory.max": "lots\n",
+	})
+	_, err := MemoryLimit()
+	require.Error(t, err)
+}
+
+func TestCPULimitV2(t *testing.T) {
+	setup(t, "0::/system.slice/cua.service\n", map[string]string{
+		"system.slice/cpu.max":             "max 100000\n",
+		"system.slice/cua.service/cpu.max": "150000 100000\n",
+	})
+	limit, err := CPULimit()
+	require.NoError(t, err)
+	require.Equal(t, 1.5, limit)
+	require.LessOrEqual(t, AvailableCPUs(), 2)
+}
+
+func TestCPULimitV2Unlimited(t *testing.T) {
+	setup(t, "0::/\n", map[string]string{
+		"cpu.max": "max 100000\n",
+	})
+	limit, err := CPULimit()
+	require.NoError(t, err)
+	require.Equal(t, 0.0, limit)
+}
+
+func TestCPULimitV1(t *testing.T) {
+	setup(t, "12:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n", map[string]string{
+		"cpu/cpu.cfs_quota_us":             "-1\n",
+		"cpu/cpu.cfs_period_us":            "100000\n",
+		"cpu/docker/abc/cpu.cfs_quota_us":  "50000\n",
+		"cpu/docker/abc/cpu.cfs_period_us": "100000\n",
+	})
+	limit, err := CPULimit()
+	require.NoError(t, err)
+	require.Equal(t, 0.5, limit)
+	require.Equal(t, 1, AvailableCPUs())
+}
+
+func TestCPULimitInvalid(t *testing.T) {
+	setup(t, "0::/\n", map[string]string{
+		"cpu.max": "lots 100000\n",
+	})
+	_, err := CPULimit()
+	require.Error(t, err)
+}
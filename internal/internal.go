@@ -266,6 +266,46 @@ func AlignTime(tm time.Time, interval time.Duration) time.Time {
 	return truncated.Add(interval)
 }
 
+// AlignTimeIn returns the time of the next interval aligned on the wall
+// clock of the location, ie the next local midnight with a 24h interval, even
+// across daylight saving time changes.  A nil location is UTC.
+// If the current time is aligned the current time is returned.
+func AlignTimeIn(tm time.Time, interval time.Duration, loc *time.Location) time.Time {
+	if loc == nil || loc == time.UTC {
+		return AlignTime(tm, interval)
+	}
+
+	offset := func(t time.Time) time.Duration {
+		_, offset := t.In(loc).Zone()
+		return time.Duration(offset) * time.Second
+	}
+
+	// align the wall clock time, then go back with the offset of the current
+	// time or, when the offset changes before the aligned time, the new offset
+	aligned := AlignTime(tm.Add(offset(tm)), interval)
+	before := aligned.Add(-offset(tm))
+	after := aligned.Add(-offset(before))
+
+	// keep the earliest time aligned on the wall clock
+	var next time.Time
+	for _, candidate := range []time.Time{before, after} {
+		if candidate.Before(tm) {
+			continue
+		}
+		wall := candidate.Add(offset(candidate))
+		if !AlignTime(wall, interval).Equal(wall) {
+			continue
+		}
+		if next.IsZero() || candidate.Before(next) {
+			next = candidate
+		}
+	}
+	if next.IsZero() {
+		return after
+	}
+	return next
+}
+
 // Exit status takes the error from exec.Command
 // and returns the exit status and true
 // if error is not exit status, will return 0 and false
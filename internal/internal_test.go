@@ -366,6 +366,75 @@ func TestAlignTime(t *testing.T) {
 	}
 }
 
+func TestAlignTimeIn(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+	rfc3339 := func(value string) time.Time {
+		t, _ := time.Parse(time.RFC3339, value)
+		return t
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		interval time.Duration
+		expected time.Time
+	}{
+		{
+			name:     "aligned",
+			now:      rfc3339("2021-06-01T00:00:00-04:00"),
+			interval: 24 * time.Hour,
+			expected: rfc3339("2021-06-01T00:00:00-04:00"),
+		},
+		{
+			name:     "local midnight",
+			now:      rfc3339("2021-06-01T12:00:00-04:00"),
+			interval: 24 * time.Hour,
+			expected: rfc3339("2021-06-02T00:00:00-04:00"),
+		},
+		{
+			name:     "short day",
+			now:      rfc3339("2021-03-14T00:00:01-05:00"),
+			interval: 24 * time.Hour,
+			expected: rfc3339("2021-03-15T00:00:00-04:00"),
+		},
+		{
+			name:     "long day",
+			now:      rfc3339("2021-11-07T00:00:01-04:00"),
+			interval: 24 * time.Hour,
+			expected: rfc3339("2021-11-08T00:00:00-05:00"),
+		},
+		{
+			name:     "skipped hour",
+			now:      rfc3339("2021-03-14T01:30:00-05:00"),
+			interval: time.Hour,
+			expected: rfc3339("2021-03-14T03:00:00-04:00"),
+		},
+		{
+			name:     "repeated hour",
+			now:      rfc3339("2021-11-07T01:30:00-04:00"),
+			interval: time.Hour,
+			expected: rfc3339("2021-11-07T01:00:00-05:00"),
+		},
+		{
+			name:     "repeated hour seconds",
+			now:      rfc3339("2021-11-07T01:30:05-04:00"),
+			interval: 10 * time.Second,
+			expected: rfc3339("2021-11-07T01:30:10-04:00"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			actual := AlignTimeIn(tt.now, tt.interval, loc)
+			require.True(t, tt.expected.Equal(actual), "expected %s, got %s", tt.expected, actual.In(loc))
+		})
+	}
+}
+
 func TestParseTimestamp(t *testing.T) {
 	rfc3339 := func(value string) time.Time {
 		tm, err := time.Parse(time.RFC3339Nano, value)
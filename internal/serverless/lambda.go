@@ -0,0 +1,240 @@
+// Package serverless implements the clients of the AWS Lambda Extensions and
+// Telemetry APIs used to run the agent as a Lambda extension.
+package serverless
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// RuntimeAPIEnv is the environment variable holding the address of the
+	// Lambda runtime APIs, it is only set in the Lambda execution environment.
+	RuntimeAPIEnv = "AWS_LAMBDA_RUNTIME_API"
+
+	extensionNameHeader = "Lambda-Extension-Name"
+	extensionIDHeader   = "Lambda-Extension-Identifier"
+
+	// EventInvoke and EventShutdown are the event types of the Extensions API.
+	EventInvoke   = "INVOKE"
+	EventShutdown = "SHUTDOWN"
+)
+
+// Event is an event of the Extensions API.
+type Event struct {
+	EventType      string `json:"eventType"`
+	DeadlineMs     int64  `json:"deadlineMs"`
+	RequestID      string `json:"requestId"`
+	ShutdownReason string `json:"shutdownReason"`
+}
+
+// Deadline returns the time the invocation or the shutdown times out.
+func (e *Event) Deadline() time.Time {
+	return time.Unix(0, e.DeadlineMs*int64(time.Millisecond))
+}
+
+// Extension is a client of the Lambda Extensions API.
+type Extension struct {
+	api    string
+	name   string
+	id     string
+	client *http.Client
+}
+
+// NewExtension creates a client of the Extensions API at the api address.
+// The name must be the file name of the extension executable.
+func NewExtension(api, name string) *Extension {
+	return &Extension{
+		api:  api,
+		name: name,
+		// the next event request blocks while the environment is frozen
+		client: &http.Client{},
+	}
+}
+
+// Register registers the extension for the invoke and shutdown events, it
+// must be called during the initialization of the execution environment.
+func (e *Extension) Register(ctx context.Context) error {
+	body, err := json.Marshal(map[string][]string{
+		"events": {EventInvoke, EventShutdown},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal register request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"http://"+e.api+"/2020-01-01/extension/register", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("register request: %w", err)
+	}
+	req.Header.Set(extensionNameHeader, e.name)
+
+	resp, err := e.do(req)
+	if err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	defer resp.Body.Close()
+
+	e.id = resp.Header.Get(extensionIDHeader)
+	if e.id == "" {
+		return fmt.Errorf("register: no extension identifier in response")
+	}
+	return nil
+}
+
+// Next blocks until the next event, the execution environment is frozen
+// once the function and all the extensions wait for their next event.
+func (e *Extension) Next(ctx context.Context) (*Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://"+e.api+"/2020-01-01/extension/event/next", nil)
+	if err != nil {
+		return nil, fmt.Errorf("next event request: %w", err)
+	}
+	req.Header.Set(extensionIDHeader, e.id)
+
+	resp, err := e.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("next event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var event Event
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		return nil, fmt.Errorf("decoding event: %w", err)
+	}
+	return &event, nil
+}
+
+// SubscribeTelemetry subscribes the listener to the platform events of the
+// Telemetry API.
+func (e *Extension) SubscribeTelemetry(ctx context.Context, l *TelemetryListener) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": "2022-12-13",
+		"types":         []string{"platform"},
+		"buffering": map[string]int{
+			"maxItems":  1000,
+			"maxBytes":  256 * 1024,
+			"timeoutMs": 25,
+		},
+		"destination": map[string]string{
+			"protocol": "HTTP",
+			"URI":      l.URI(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal subscribe request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut,
+		"http://"+e.api+"/2022-07-01/telemetry", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("subscribe request: %w", err)
+	}
+	req.Header.Set(extensionIDHeader, e.id)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.do(req)
+	if err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (e *Extension) do(req *http.Request) (*http.Response, error) {
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http do: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return nil, fmt.Errorf("%s returned HTTP status %s: %q", req.URL, resp.Status, body)
+	}
+	return resp, nil
+}
+
+// TelemetryListener receives the platform events of the Telemetry API and
+// reports the invocations completed by the function runtime.
+type TelemetryListener struct {
+	host        string
+	listener    net.Listener
+	server      *http.Server
+	runtimeDone chan string
+}
+
+// NewTelemetryListener listens on the address for the events, host is the
+// name the Telemetry API reaches the listener with.
+func NewTelemetryListener(addr, host string) (*TelemetryListener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("telemetry listener: %w", err)
+	}
+
+	l := &TelemetryListener{
+		host:        host,
+		listener:    listener,
+		runtimeDone: make(chan string, 100),
+	}
+	l.server = &http.Server{Handler: l}
+	go func() {
+		_ = l.server.Serve(listener)
+	}()
+	return l, nil
+}
+
+// URI returns the destination of the telemetry subscription.
+func (l *TelemetryListener) URI() string {
+	_, port, _ := net.SplitHostPort(l.listener.Addr().String())
+	return "http://" + net.JoinHostPort(l.host, port)
+}
+
+// RuntimeDone returns the request ids of the invocations completed by the
+// function runtime.
+func (l *TelemetryListener) RuntimeDone() <-chan string {
+	return l.runtimeDone
+}
+
+// Close stops the listener.
+func (l *TelemetryListener) Close() error {
+	if err := l.server.Close(); err != nil {
+		return fmt.Errorf("closing telemetry listener: %w", err)
+	}
+	return nil
+}
+
+func (l *TelemetryListener) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	var events []struct {
+		Type   string `json:"type"`
+		Record struct {
+			RequestID string `json:"requestId"`
+		} `json:"record"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&events); err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, event := range events {
+		if event.Type != "platform.runtimeDone" {
+			continue
+		}
+		select {
+		case l.runtimeDone <- event.Record.RequestID:
+		default:
+			// nobody is waiting for the invocations, drop the oldest
+			select {
+			case <-l.runtimeDone:
+			default:
+			}
+			l.runtimeDone <- event.Record.RequestID
+		}
+	}
+	res.WriteHeader(http.StatusOK)
+}
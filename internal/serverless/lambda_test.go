@@ -0,0 +1,98 @@
+package serverless
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtension(t *testing.T) {
+	var subscription map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2020-01-01/extension/register":
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "cua", r.Header.Get(extensionNameHeader))
+			w.Header().Set(extensionIDHeader, "ext-id")
+			_, _ = w.Write([]byte(`{}`))
+		case "/2020-01-01/extension/event/next":
+			require.Equal(t, "ext-id", r.Header.Get(extensionIDHeader))
+			_, _ = w.Write([]byte(`{"eventType":"INVOKE","deadlineMs":1600000000000,"requestId":"req-1"}`))
+		case "/2022-07-01/telemetry":
+			require.Equal(t, http.MethodPut, r.Method)
+			require.Equal(t, "ext-id", r.Header.Get(extensionIDHeader))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&subscription))
+			_, _ = w.Write([]byte(`OK`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	ext := NewExtension(strings.TrimPrefix(ts.URL, "http://"), "cua")
+	require.NoError(t, ext.Register(ctx))
+
+	event, err := ext.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, EventInvoke, event.EventType)
+	require.Equal(t, "req-1", event.RequestID)
+	require.Equal(t, time.Unix(1600000000, 0), event.Deadline())
+
+	l, err := NewTelemetryListener("127.0.0.1:0", "sandbox.localdomain")
+	require.NoError(t, err)
+	defer l.Close()
+
+	require.NoError(t, ext.SubscribeTelemetry(ctx, l))
+	require.Equal(t, []interface{}{"platform"}, subscription["types"])
+	destination := subscription["destination"].(map[string]interface{})
+	require.Equal(t, l.URI(), destination["URI"])
+	require.True(t, strings.HasPrefix(l.URI(), "http://sandbox.localdomain:"))
+}
+
+func TestExtensionRegisterError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errorType":"Extension.Forbidden"}`))
+	}))
+	defer ts.Close()
+
+	ext := NewExtension(strings.TrimPrefix(ts.URL, "http://"), "cua")
+	err := ext.Register(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "403")
+}
+
+func TestTelemetryListenerRuntimeDone(t *testing.T) {
+	l, err := NewTelemetryListener("127.0.0.1:0", "127.0.0.1")
+	require.NoError(t, err)
+	defer l.Close()
+
+	body := `[
+		{"time":"2022-10-12T00:00:00.000Z","type":"platform.start","record":{"requestId":"req-1"}},
+		{"time":"2022-10-12T00:00:01.000Z","type":"platform.runtimeDone","record":{"requestId":"req-1","status":"success"}}
+	]`
+	resp, err := http.Post(l.URI(), "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case id := <-l.RuntimeDone():
+		require.Equal(t, "req-1", id)
+	case <-time.After(time.Second):
+		t.Fatal("runtime done not reported")
+	}
+	require.Len(t, l.RuntimeDone(), 0)
+
+	resp, err = http.Post(l.URI(), "application/json", strings.NewReader(`not json`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
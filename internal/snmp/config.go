@@ -12,7 +12,7 @@ type ClientConfig struct {
 	Version uint8 `toml:"version"`
 
 	// Parameters for Version 1 & 2
-	Community string `toml:"community"`
+	Community string `toml:"community" secret:"true"`
 
 	// Parameters for Version 2 & 3
 	MaxRepetitions uint8 `toml:"max_repetitions"`
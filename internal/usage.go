@@ -11,29 +11,57 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config show         print the effective configuration loaded from --config
+                      and --config-directory, with the defaults and the
+                      environment variables substituted, secrets redacted
+  lint                print the likely problems of the configuration, such as
+                      duplicate plugins or unbounded tags
+  tap                 stream the metrics written to the outputs by the running
+                      agent, through its control socket; options:
+                        --filter <key><op><value>  only stream the matching
+                                                   metrics, op is =, !=, =~ or !~,
+                                                   can be repeated
+                        --socket <path>            control socket, defaults to
+                                                   control_socket of the config
   version             print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
   --config <file>                configuration file to load
   --config-directory <directory> directory containing additional *.conf files
+  --config-url <url>             URL of a configuration to load after the files,
+                                 can be repeated
+  --config-header <header>       header of the requests fetching the configurations,
+                                 as 'Name: value' with environment variables expanded,
+                                 can be repeated
+  --config-cache-dir <directory> directory caching the configurations of the URLs,
+                                 used when they cannot be fetched
+  --config-check-interval <dur>  interval checking the configurations of the URLs
+                                 for changes, reloading the agent when changed
   --plugin-directory             directory containing *.so files, this directory will be
                                  searched recursively. Any Plugin found will be loaded
                                  and namespaced.
   --debug                        turn on debug logging
   --input-filter <filter>        filter the inputs to enable, separator is :
   --input-list                   print available input plugins.
+  --lint-gather                  run a single gather with the lint command to
+                                 analyze the tags of the metrics
   --output-filter <filter>       filter the outputs to enable, separator is :
   --output-list                  print available output plugins.
   --pidfile <file>               file to write our pid to
-  --pprof-addr <address>         pprof address to listen on, don't activate pprof if empty
+  --pprof-addr <address>         pprof and expvar address to listen on, don't activate pprof if empty
   --processor-filter <filter>    filter the processors to enable, separator is :
   --quiet                        run in quiet mode
   --section-filter               filter config sections to output, separator is :
                                  Valid values are 'agent', 'global_tags', 'outputs',
                                  'processors', 'aggregators' and 'inputs'
+  --serverless                   run as a serverless extension (AWS Lambda extension or sidecar)
   --sample-config                print out full sample configuration
-  --once                         enable once mode: gather metrics once, write them, and exit
-  --test                         enable test mode: gather metrics once and print them
+  --once                         enable once mode: gather metrics once, write them, and exit,
+                                 with a non-zero status on gather or write errors
+  --test                         enable test mode: gather metrics once and print them,
+                                 with a non-zero status on gather errors
+  --test-config                  load the configuration and initialize the plugins,
+                                 without starting the agent
   --test-wait                    wait up to this many seconds for service
                                  inputs to complete in test or once mode
   --usage <plugin>               print usage for a plugin, ie, 'circonus-unified-agent --usage mysql'
@@ -47,15 +75,36 @@ Examples:
   # generate config with only cpu input & circonus output plugins defined
   circonus-unified-agent --input-filter cpu --output-filter circonus config
 
+  # print the configuration merged from a configuration directory
+  circonus-unified-agent --config circonus-unified-agent.conf --config-directory conf.d config show
+
+  # validate the configuration without starting the agent
+  circonus-unified-agent --config circonus-unified-agent.conf --config-directory conf.d --test-config
+
+  # check the configuration, including the tags of a single collection
+  circonus-unified-agent --config circonus-unified-agent.conf --lint-gather lint
+
+  # stream the nstat metrics of the running agent
+  circonus-unified-agent --config circonus-unified-agent.conf tap --filter 'measurement=~nstat.*'
+
   # run a single collection, outputting metrics to stdout
   circonus-unified-agent --config circonus-unified-agent.conf --test
 
+  # run a single collection of the cpu input, outputting metrics to stdout
+  circonus-unified-agent --config circonus-unified-agent.conf --test --input-filter cpu
+
+  # run a single collection, writing metrics to the circonus output, and exit
+  circonus-unified-agent --config circonus-unified-agent.conf --once --output-filter circonus
+
   # run with all plugins defined in config file
   circonus-unified-agent --config circonus-unified-agent.conf
 
   # run, enabling the cpu & memory input, and circonus output plugins
   circonus-unified-agent --config circonus-unified-agent.conf --input-filter cpu:mem --output-filter circonus
 
+  # run with a configuration fetched from a server, checked every 5 minutes
+  circonus-unified-agent --config-url https://config.example.com/agent.conf --config-header 'Authorization: Bearer $CONFIG_TOKEN' --config-cache-dir /var/cache/cua --config-check-interval 5m
+
   # run with pprof
   circonus-unified-agent --config circonus-unified-agent.conf --pprof-addr localhost:6060
 `
@@ -11,33 +11,62 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config show         print the effective configuration loaded from --config
+                      and --config-directory, with the defaults and the
+                      environment variables substituted, secrets redacted
+  lint                print the likely problems of the configuration, such as
+                      duplicate plugins or unbounded tags
+  tap                 stream the metrics written to the outputs by the running
+                      agent, through its control socket; options:
+                        --filter <key><op><value>  only stream the matching
+                                                   metrics, op is =, !=, =~ or !~,
+                                                   can be repeated
+                        --socket <path>            control socket, defaults to
+                                                   control_socket of the config
   version             print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
   --config <file>                configuration file to load
   --config-directory <directory> directory containing additional *.conf files
+  --config-url <url>             URL of a configuration to load after the files,
+                                 can be repeated
+  --config-header <header>       header of the requests fetching the configurations,
+                                 as 'Name: value' with environment variables expanded,
+                                 can be repeated
+  --config-cache-dir <directory> directory caching the configurations of the URLs,
+                                 used when they cannot be fetched
+  --config-check-interval <dur>  interval checking the configurations of the URLs
+                                 for changes, reloading the agent when changed
   --debug                        turn on debug logging
   --input-filter <filter>        filter the inputs to enable, separator is :
   --input-list                   print available input plugins.
+  --lint-gather                  run a single gather with the lint command to
+                                 analyze the tags of the metrics
   --output-filter <filter>       filter the outputs to enable, separator is :
   --output-list                  print available output plugins.
   --pidfile <file>               file to write our pid to
-  --pprof-addr <address>         pprof address to listen on, don't activate pprof if empty
+  --pprof-addr <address>         pprof and expvar address to listen on, don't activate pprof if empty
   --processor-filter <filter>    filter the processors to enable, separator is :
   --quiet                        run in quiet mode
   --sample-config                print out full sample configuration
   --section-filter               filter config sections to output, separator is :
                                  Valid values are 'agent', 'global_tags', 'outputs',
                                  'processors', 'aggregators' and 'inputs'
-  --once                         enable once mode: gather metrics once, write them, and exit
-  --test                         enable test mode: gather metrics once and print them
+  --serverless                   run as a serverless extension (AWS Lambda extension or sidecar)
+  --once                         enable once mode: gather metrics once, write them, and exit,
+                                 with a non-zero status on gather or write errors
+  --test                         enable test mode: gather metrics once and print them,
+                                 with a non-zero status on gather errors
+  --test-config                  load the configuration and initialize the plugins,
+                                 without starting the agent
   --test-wait                    wait up to this many seconds for service
                                  inputs to complete in test or once mode
   --usage <plugin>               print usage for a plugin, ie, 'circonus-unified-agentd --usage mysql'
   --version                      display the version and exit
 
   --console                      run as console application (windows only)
-  --service <service>            operate on the service (windows only)
+  --service <action>             operate on the service: install, uninstall,
+                                 start, stop, restart or status (windows only)
   --service-name                 service name (windows only)
   --service-display-name         service display name (windows only)
 
@@ -49,15 +78,36 @@ Examples:
   # generate config with only cpu input & circonus output plugins defined
   circonus-unified-agentd.exe --input-filter cpu --output-filter circonus config
 
+  # print the configuration merged from a configuration directory
+  circonus-unified-agentd.exe --config circonus-unified-agent.conf --config-directory conf.d config show
+
+  # validate the configuration without starting the agent
+  circonus-unified-agentd.exe --config circonus-unified-agent.conf --config-directory conf.d --test-config
+
+  # check the configuration, including the tags of a single collection
+  circonus-unified-agentd.exe --config circonus-unified-agent.conf --lint-gather lint
+
+  # stream the nstat metrics of the running agent
+  circonus-unified-agentd.exe --config circonus-unified-agent.conf tap --filter 'measurement=~nstat.*'
+
   # run a single collection, outputting metrics to stdout
   circonus-unified-agentd.exe --config circonus-unfied-agent.conf --test
 
+  # run a single collection of the cpu input, outputting metrics to stdout
+  circonus-unified-agentd.exe --config circonus-unfied-agent.conf --test --input-filter cpu
+
+  # run a single collection, writing metrics to the circonus output, and exit
+  circonus-unified-agentd.exe --config circonus-unfied-agent.conf --once --output-filter circonus
+
   # run with all plugins defined in config file
   circonus-unified-agentd.exe --config circonus-unified-agent.conf
 
   # run, enabling the cpu & memory input, and circonus output plugins
   circonus-unified-agentd.exe --config circonus-unified-agent.conf --input-filter cpu:mem --output-filter circonus
 
+  # run with a configuration fetched from a server, checked every 5 minutes
+  circonus-unified-agentd.exe --config-url https://config.example.com/agent.conf --config-header "Authorization: Bearer ${CONFIG_TOKEN}" --config-cache-dir C:\ProgramData\Circonus\cache --config-check-interval 5m
+
   # run with pprof
   circonus-unified-agentd.exe --config circonus-unified-agent.conf --pprof-addr localhost:6060
 
@@ -4,7 +4,6 @@ import (
 	"io"
 	"strings"
 
-	"github.com/influxdata/wlog"
 	"github.com/kardianos/service"
 )
 
@@ -14,9 +13,13 @@ const (
 
 type eventLogger struct {
 	logger service.Logger
+	filter *levelFilter
 }
 
 func (t *eventLogger) Write(b []byte) (n int, err error) {
+	if !t.filter.allow(b) {
+		return len(b), nil
+	}
 	loc := prefixRegex.FindIndex(b)
 	n = len(b)
 	if loc == nil {
@@ -41,7 +44,7 @@ type eventLoggerCreator struct {
 }
 
 func (e *eventLoggerCreator) CreateLogger(config LogConfig) (io.Writer, error) {
-	return wlog.NewWriter(&eventLogger{logger: e.serviceLogger}), nil
+	return &eventLogger{logger: e.serviceLogger, filter: newLevelFilter(config)}, nil
 }
 
 func RegisterEventLogger(serviceLogger service.Logger) {
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/wlog"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// levelNames are the names of the levels in the configuration and in the
+// JSON output.
+var levelNames = map[wlog.Level]string{
+	wlog.DEBUG: "debug",
+	wlog.INFO:  "info",
+	wlog.WARN:  "warn",
+	wlog.ERROR: "error",
+}
+
+// ParseLevel returns the level of the name: debug, info, warn or error.
+func ParseLevel(name string) (wlog.Level, error) {
+	for level, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid log level %q, must be one of debug, info, warn or error", name)
+}
+
+// CheckFormat returns an error when the log format is not supported.
+func CheckFormat(format string) error {
+	switch format {
+	case "", FormatText, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid log format %q, must be text or json", format)
+	}
+}
+
+// configLevel returns the level of the agent.
+func configLevel(config LogConfig) wlog.Level {
+	if config.Level != "" {
+		if level, err := ParseLevel(config.Level); err == nil {
+			return level
+		}
+	}
+	switch {
+	case config.Debug:
+		return wlog.DEBUG
+	case config.Quiet:
+		return wlog.ERROR
+	default:
+		return wlog.INFO
+	}
+}
+
+// minLevel returns the lowest level of the agent and of the plugins.
+func minLevel(config LogConfig) wlog.Level {
+	lowest := configLevel(config)
+	for _, name := range config.PluginLevels {
+		if level, err := ParseLevel(name); err == nil && level < lowest {
+			lowest = level
+		}
+	}
+	return lowest
+}
+
+// levelFilter drops the messages below the level of the agent, or of the
+// plugin logging them when overridden.
+type levelFilter struct {
+	level   wlog.Level
+	plugins map[string]wlog.Level
+}
+
+func newLevelFilter(config LogConfig) *levelFilter {
+	f := &levelFilter{level: configLevel(config)}
+	for plugin, name := range config.PluginLevels {
+		level, err := ParseLevel(name)
+		if err != nil {
+			continue
+		}
+		if f.plugins == nil {
+			f.plugins = make(map[string]wlog.Level)
+		}
+		f.plugins[plugin] = level
+	}
+	return f
+}
+
+func (f *levelFilter) allow(b []byte) bool {
+	level, plugin, _ := parseMessage(b)
+	if l, ok := f.plugins[plugin]; ok {
+		return level >= l
+	}
+	return level >= f.level
+}
+
+// parseMessage splits a message in the "L! [plugin] message" format, the
+// level defaults to info and the plugin is optional.
+func parseMessage(b []byte) (wlog.Level, string, []byte) {
+	level := wlog.INFO
+	if prefixRegex.Match(b) {
+		level = wlog.Levels[b[0]]
+		b = bytes.TrimLeft(b[2:], " ")
+	}
+	plugin := ""
+	if len(b) > 0 && b[0] == '[' {
+		if end := bytes.IndexByte(b, ']'); end > 0 {
+			plugin = string(b[1:end])
+			b = bytes.TrimLeft(b[end+1:], " ")
+		}
+	}
+	return level, plugin, bytes.TrimRight(b, "\r\n")
+}
+
+type jsonLine struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Plugin  string `json:"plugin,omitempty"`
+	Message string `json:"msg"`
+}
+
+// formatJSON returns the message as a JSON line.
+func formatJSON(t time.Time, b []byte) []byte {
+	level, plugin, msg := parseMessage(b)
+	line, err := json.Marshal(jsonLine{
+		Time:    t.Format(time.RFC3339Nano),
+		Level:   levelNames[level],
+		Plugin:  plugin,
+		Message: string(msg),
+	})
+	if err != nil {
+		// the strings are always marshaled
+		return append(b, '\n')
+	}
+	return append(line, '\n')
+}
@@ -38,6 +38,13 @@ type LogConfig struct {
 	RotationMaxSize internal.Size
 	// maximum rotated files to keep (older ones will be deleted)
 	RotationMaxArchives int
+	// debug, info, warn or error, overrides Debug and Quiet when set
+	Level string
+	// text or json
+	Format string
+	// levels of the plugins by log name, such as inputs.cpu or
+	// inputs.cpu::alias, overriding Level
+	PluginLevels map[string]string
 }
 
 type Creator interface {
@@ -56,14 +63,26 @@ func registerLogger(name string, loggerCreator Creator) {
 type cuaLog struct {
 	writer         io.Writer
 	internalWriter io.Writer
+	filter         *levelFilter
+	json           bool
 }
 
 func (t *cuaLog) Write(b []byte) (n int, err error) {
+	if !t.filter.allow(b) {
+		return len(b), nil
+	}
+	now := time.Now().UTC()
+	if t.json {
+		if _, err := t.writer.Write(formatJSON(now, b)); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
 	var line []byte
 	if !prefixRegex.Match(b) {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
+		line = append([]byte(now.Format(time.RFC3339)+" I! "), b...)
 	} else {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...)
+		line = append([]byte(now.Format(time.RFC3339)+" "), b...)
 	}
 	return t.writer.Write(line)
 }
@@ -82,10 +101,12 @@ func (t *cuaLog) Close() error {
 }
 
 // newCUAWriter returns a logging-wrapped writer.
-func newCUAWriter(w io.Writer) io.Writer {
+func newCUAWriter(w io.Writer, config LogConfig) io.Writer {
 	return &cuaLog{
-		writer:         wlog.NewWriter(w),
+		writer:         w,
 		internalWriter: w,
+		filter:         newLevelFilter(config),
+		json:           config.Format == FormatJSON,
 	}
 }
 
@@ -119,7 +140,7 @@ func (t *cuaLogCreator) CreateLogger(config LogConfig) (io.Writer, error) {
 		writer = defaultWriter
 	}
 
-	return newCUAWriter(writer), nil
+	return newCUAWriter(writer, config), nil
 }
 
 // Keep track what is actually set as a log output, because log package doesn't provide a getter.
@@ -128,15 +149,9 @@ var actualLogger io.Writer
 
 func newLogWriter(config LogConfig) io.Writer {
 	log.SetFlags(0)
-	if config.Debug {
-		wlog.SetLevel(wlog.DEBUG)
-	}
-	if config.Quiet {
-		wlog.SetLevel(wlog.ERROR)
-	}
-	if !config.Debug && !config.Quiet {
-		wlog.SetLevel(wlog.INFO)
-	}
+	// the plugins checking the level of wlog log at the lowest level
+	// configured, the messages are filtered by the writers
+	wlog.SetLevel(wlog.Level(minLevel(config)))
 	var logWriter io.Writer
 	if logCreator, ok := loggerRegistry[config.LogTarget]; ok {
 		logWriter, _ = logCreator.CreateLogger(config)
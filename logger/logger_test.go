@@ -2,13 +2,16 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -133,10 +136,71 @@ func TestLogTargetSettings(t *testing.T) {
 	assert.Equal(t, logger.internalWriter, os.Stderr)
 }
 
+func TestLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	w := newCUAWriter(&buf, LogConfig{Debug: true, Level: "warn"})
+	_, _ = w.Write([]byte("I! TEST\n"))
+	_, _ = w.Write([]byte("W! TEST\n"))
+	require.Equal(t, "Z W! TEST\n", buf.String()[19:])
+}
+
+func TestPluginLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	w := newCUAWriter(&buf, LogConfig{
+		Level: "info",
+		PluginLevels: map[string]string{
+			"inputs.cpu":        "debug",
+			"inputs.mem::quiet": "error",
+		},
+	})
+	for _, msg := range []string{
+		"D! [inputs.cpu] cpu",
+		"D! [inputs.mem] mem",
+		"W! [inputs.mem::quiet] quiet",
+		"E! [inputs.mem::quiet] quiet",
+	} {
+		_, _ = w.Write([]byte(msg + "\n"))
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, "Z D! [inputs.cpu] cpu", lines[0][19:])
+	require.Equal(t, "Z E! [inputs.mem::quiet] quiet", lines[1][19:])
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	w := newCUAWriter(&buf, LogConfig{Format: FormatJSON})
+	_, _ = w.Write([]byte("E! [outputs.circonus] failed: \"quoted\"\n"))
+	_, _ = w.Write([]byte("started\n"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var line map[string]string
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+	require.NotEmpty(t, line["time"])
+	require.Equal(t, "error", line["level"])
+	require.Equal(t, "outputs.circonus", line["plugin"])
+	require.Equal(t, `failed: "quoted"`, line["msg"])
+
+	line = nil
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &line))
+	require.Equal(t, "info", line["level"])
+	require.NotContains(t, line, "plugin")
+	require.Equal(t, "started", line["msg"])
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	require.NoError(t, err)
+	require.Equal(t, wlog.WARN, level)
+	_, err = ParseLevel("trace")
+	require.Error(t, err)
+}
+
 func BenchmarkCUALogWrite(b *testing.B) {
 	var msg = []byte("test")
 	var buf bytes.Buffer
-	w := newCUAWriter(&buf)
+	w := newCUAWriter(&buf, LogConfig{})
 	for i := 0; i < b.N; i++ {
 		buf.Reset()
 		_, _ = w.Write(msg)
@@ -18,6 +18,7 @@ type metric struct {
 	aggregate      bool
 	origin         string
 	originInstance string
+	arrival        time.Time
 }
 
 func New(
@@ -77,6 +78,7 @@ func FromMetric(other cua.Metric) cua.Metric {
 		aggregate:      other.IsAggregate(),
 		origin:         other.Origin(),
 		originInstance: other.OriginInstance(),
+		arrival:        other.Arrival(),
 	}
 
 	for i, tag := range other.TagList() {
@@ -244,6 +246,7 @@ func (m *metric) Copy() cua.Metric {
 		aggregate:      m.aggregate,
 		origin:         m.origin,
 		originInstance: m.originInstance,
+		arrival:        m.arrival,
 	}
 
 	for i, tag := range m.tags {
@@ -398,3 +401,10 @@ func (m *metric) OriginInstance() string {
 func (m *metric) SetOriginInstance(instanceID string) {
 	m.originInstance = instanceID
 }
+
+func (m *metric) Arrival() time.Time {
+	return m.arrival
+}
+func (m *metric) SetArrival(t time.Time) {
+	m.arrival = t
+}
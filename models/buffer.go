@@ -267,6 +267,12 @@ func (b *Buffer) prevby(index, count int) int {
 	return index
 }
 
+// Close is a no-op, the metrics of a memory buffer are lost when the agent
+// stops.
+func (b *Buffer) Close() error {
+	return nil
+}
+
 func (b *Buffer) resetBatch() {
 	b.batchFirst = 0
 	b.batchSize = 0
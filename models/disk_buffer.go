@@ -0,0 +1,639 @@
+package models
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/influx"
+	serializer "github.com/circonus-labs/circonus-unified-agent/plugins/serializers/influx"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+// Fsync policies of the disk buffer.
+const (
+	// FsyncAlways syncs the log each time metrics are added.
+	FsyncAlways = "always"
+	// FsyncFlush syncs the log each time the output writes a batch.
+	FsyncFlush = "flush"
+	// FsyncNever leaves the sync to the operating system.
+	FsyncNever = "never"
+)
+
+const (
+	DefaultDiskBufferMaxSize     = 1024 * 1024 * 1024
+	DefaultDiskBufferSegmentSize = 16 * 1024 * 1024
+)
+
+const (
+	segmentExt = ".wal"
+	ackName    = "ack"
+
+	// a record is the length and the crc32 of its payload, followed by the
+	// payload: the value type of the metric, its arrival time in nanoseconds
+	// when the type has the recordArrival bit, and its line protocol.
+	recordHeaderSize = 8
+	maxRecordSize    = 64 * 1024 * 1024
+	recordArrival    = 0x80
+)
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// DiskBufferConfig configures the disk buffer of an output.
+type DiskBufferConfig struct {
+	// Directory of the log, each output needs its own directory.
+	Directory string
+	// MaxSize is the size of the log in bytes above which the oldest
+	// metrics are dropped.
+	MaxSize int64
+	// SegmentSize is the size of the segment files of the log, a segment
+	// is removed once all its metrics are written.
+	SegmentSize int64
+	// Fsync is the fsync policy: always, flush or never.
+	Fsync string
+}
+
+// segment is a file of the log, named by the index of its first metric.
+type segment struct {
+	path  string
+	first uint64
+	count uint64
+	size  int64
+}
+
+func (s *segment) end() uint64 {
+	return s.first + s.count
+}
+
+// position is the position of a metric in the log, offset is the offset of
+// the metric in its segment.
+type position struct {
+	index  uint64
+	offset int64
+}
+
+// DiskBuffer stores metrics in a write-ahead log on disk, so that the
+// metrics not yet written survive restarts of the agent and outages of
+// the output longer than a memory buffer could hold.
+//
+// The metrics are appended to segment files.  The index of the oldest
+// metric not written is saved in an ack file each time a batch is written,
+// and the segments holding only written metrics are removed.
+type DiskBuffer struct {
+	sync.Mutex
+	cfg DiskBufferConfig
+	log cua.Logger
+
+	segments []*segment // oldest first, metrics are appended to the last
+	file     *os.File   // the last segment
+	size     int64      // size of all the segments
+
+	first uint64   // index of the oldest metric not written
+	next  uint64   // index of the next metric added
+	read  position // position of the next metric returned by Batch
+
+	batchFirst position // position of the first metric in the batch
+	batchSize  int      // number of metrics currently in the batch
+
+	serializer *serializer.Serializer
+	parser     *influx.Parser
+
+	MetricsAdded   selfstat.Stat
+	MetricsWritten selfstat.Stat
+	MetricsDropped selfstat.Stat
+	BufferSize     selfstat.Stat
+	BufferLimit    selfstat.Stat
+	DiskSize       selfstat.Stat
+	DiskLimit      selfstat.Stat
+}
+
+// NewDiskBuffer opens the disk buffer in the directory of the config, with
+// the metrics left by a previous run of the agent.
+func NewDiskBuffer(name, alias string, cfg DiskBufferConfig, log cua.Logger) (*DiskBuffer, error) {
+	if cfg.Directory == "" {
+		return nil, errors.New("no disk buffer directory")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultDiskBufferMaxSize
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = DefaultDiskBufferSegmentSize
+	}
+	// keep the oldest segment from holding most of the log
+	if cfg.SegmentSize > cfg.MaxSize/4 {
+		cfg.SegmentSize = cfg.MaxSize / 4
+	}
+	switch cfg.Fsync {
+	case "":
+		cfg.Fsync = FsyncFlush
+	case FsyncAlways, FsyncFlush, FsyncNever:
+	default:
+		return nil, fmt.Errorf("invalid disk buffer fsync policy %q, expected %s, %s or %s", cfg.Fsync, FsyncAlways, FsyncFlush, FsyncNever)
+	}
+
+	tags := map[string]string{"output": name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+	s := serializer.NewSerializer()
+	s.Log = log
+	s.SetFieldTypeSupport(serializer.UintSupport)
+	b := &DiskBuffer{
+		cfg:        cfg,
+		log:        log,
+		serializer: s,
+		parser:     influx.NewParser(influx.NewMetricHandler()),
+
+		MetricsAdded:   selfstat.Register("write", "metrics_added", tags),
+		MetricsWritten: selfstat.Register("write", "metrics_written", tags),
+		MetricsDropped: selfstat.Register("write", "metrics_dropped", tags),
+		BufferSize:     selfstat.Register("write", "buffer_size", tags),
+		BufferLimit:    selfstat.Register("write", "buffer_limit", tags),
+		DiskSize:       selfstat.Register("write", "buffer_disk_size", tags),
+		DiskLimit:      selfstat.Register("write", "buffer_disk_limit", tags),
+	}
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+
+	// the limit of the buffer is in bytes, not in metrics
+	b.BufferLimit.Set(0)
+	b.DiskLimit.Set(cfg.MaxSize)
+	b.updateStats()
+	return b, nil
+}
+
+func (b *DiskBuffer) open() error {
+	if err := os.MkdirAll(b.cfg.Directory, 0750); err != nil {
+		return fmt.Errorf("mkdir (%s): %w", b.cfg.Directory, err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(b.cfg.Directory, "*"+segmentExt))
+	if err != nil {
+		return fmt.Errorf("glob: %w", err)
+	}
+	for _, path := range paths {
+		first, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		count, size, err := readRecords(path, 0, math.MaxUint64, nil)
+		if err != nil {
+			// a record partially written when the agent stopped
+			b.log.Warnf("Truncating disk buffer segment %s after %d metrics: %v", path, count, err)
+			if err := os.Truncate(path, size); err != nil {
+				return fmt.Errorf("truncate (%s): %w", path, err)
+			}
+		}
+		b.segments = append(b.segments, &segment{path: path, first: first, count: count, size: size})
+		b.size += size
+	}
+	sort.Slice(b.segments, func(i, j int) bool {
+		return b.segments[i].first < b.segments[j].first
+	})
+
+	if data, err := os.ReadFile(filepath.Join(b.cfg.Directory, ackName)); err == nil {
+		if first, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			b.first = first
+		}
+	}
+	if len(b.segments) == 0 {
+		b.next = b.first
+		if err := b.createSegment(); err != nil {
+			return err
+		}
+	} else {
+		last := b.segments[len(b.segments)-1]
+		b.next = last.end()
+		if b.first < b.segments[0].first {
+			b.first = b.segments[0].first
+		}
+		if b.first > b.next {
+			b.first = b.next
+		}
+		f, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return fmt.Errorf("open (%s): %w", last.path, err)
+		}
+		b.file = f
+	}
+	b.compact()
+	b.read = b.seek(b.first)
+
+	if n := b.next - b.first; n > 0 {
+		b.log.Infof("Disk buffer holds %d metrics not yet written", n)
+	}
+	return nil
+}
+
+// createSegment creates the segment of the next metric and makes it the
+// last segment.
+func (b *DiskBuffer) createSegment() error {
+	path := filepath.Join(b.cfg.Directory, fmt.Sprintf("%020d%s", b.next, segmentExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("create (%s): %w", path, err)
+	}
+	b.file = f
+	b.segments = append(b.segments, &segment{path: path, first: b.next})
+	if b.read.index == b.next {
+		b.read = position{index: b.next}
+	}
+	return nil
+}
+
+func (b *DiskBuffer) rotate() error {
+	if b.cfg.Fsync != FsyncNever {
+		if err := b.file.Sync(); err != nil {
+			return fmt.Errorf("sync: %w", err)
+		}
+	}
+	if err := b.file.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	if err := b.createSegment(); err != nil {
+		return err
+	}
+	b.compact()
+	return nil
+}
+
+// compact removes the segments holding only written metrics.
+func (b *DiskBuffer) compact() {
+	for len(b.segments) > 1 && b.segments[0].end() <= b.first {
+		b.removeOldest()
+	}
+}
+
+func (b *DiskBuffer) removeOldest() {
+	s := b.segments[0]
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		b.log.Errorf("Removing disk buffer segment: %v", err)
+	}
+	b.size -= s.size
+	b.segments = b.segments[1:]
+}
+
+// segmentOf returns the index of the segment holding the metric.
+func (b *DiskBuffer) segmentOf(index uint64) int {
+	for i := len(b.segments) - 1; i > 0; i-- {
+		if b.segments[i].first <= index {
+			return i
+		}
+	}
+	return 0
+}
+
+// seek returns the position of the metric.
+func (b *DiskBuffer) seek(index uint64) position {
+	s := b.segments[b.segmentOf(index)]
+	if index < s.first {
+		index = s.first
+	}
+	if index >= s.end() {
+		return position{index: s.end(), offset: s.size}
+	}
+	n, offset, err := readRecords(s.path, 0, index-s.first, nil)
+	if err != nil {
+		b.log.Errorf("Reading disk buffer segment %s: %v", s.path, err)
+	}
+	return position{index: s.first + n, offset: offset}
+}
+
+// Len returns the number of metrics currently in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return int(b.next - b.first)
+}
+
+// Add adds metrics to the buffer and returns number of dropped metrics.
+func (b *DiskBuffer) Add(metrics ...cua.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := 0
+	for _, m := range metrics {
+		if err := b.append(m); err != nil {
+			b.log.Errorf("Adding metric to disk buffer: %v", err)
+			b.metricDropped(m)
+			dropped++
+			continue
+		}
+		// the metric is delivered once it is in the log
+		b.MetricsAdded.Incr(1)
+		m.Accept()
+	}
+	dropped += b.enforceMaxSize()
+
+	if b.cfg.Fsync == FsyncAlways {
+		b.sync()
+	}
+	b.updateStats()
+	return dropped
+}
+
+func (b *DiskBuffer) append(m cua.Metric) error {
+	line, err := b.serializer.Serialize(m)
+	if err != nil {
+		return fmt.Errorf("serialize: %w", err)
+	}
+	prefix := 1
+	if !m.Arrival().IsZero() {
+		prefix += 8
+	}
+	record := make([]byte, recordHeaderSize+prefix+len(line))
+	binary.LittleEndian.PutUint32(record[0:], uint32(prefix+len(line)))
+	record[recordHeaderSize] = byte(m.Type())
+	if prefix > 1 {
+		record[recordHeaderSize] |= recordArrival
+		binary.LittleEndian.PutUint64(record[recordHeaderSize+1:], uint64(m.Arrival().UnixNano()))
+	}
+	copy(record[recordHeaderSize+prefix:], line)
+	binary.LittleEndian.PutUint32(record[4:], crc32.ChecksumIEEE(record[recordHeaderSize:]))
+
+	last := b.segments[len(b.segments)-1]
+	if last.size > 0 && last.size+int64(len(record)) > b.cfg.SegmentSize {
+		if err := b.rotate(); err != nil {
+			return err
+		}
+		last = b.segments[len(b.segments)-1]
+	}
+	if _, err := b.file.Write(record); err != nil {
+		// drop what was written of the record
+		_ = b.file.Truncate(last.size)
+		return fmt.Errorf("write (%s): %w", last.path, err)
+	}
+	last.size += int64(len(record))
+	last.count++
+	b.size += int64(len(record))
+	b.next++
+	return nil
+}
+
+// enforceMaxSize drops the oldest segments while the log is larger than
+// its max size, and returns the number of metrics dropped.
+func (b *DiskBuffer) enforceMaxSize() int {
+	dropped := 0
+	for b.size > b.cfg.MaxSize && len(b.segments) > 1 {
+		s := b.segments[0]
+		// the metrics of the batch are counted when the batch is done
+		from := b.first
+		if b.batchSize > 0 {
+			from = b.batchFirst.index + uint64(b.batchSize)
+		}
+		if s.end() > from {
+			n := int(s.end() - from)
+			AgentMetricsDropped.Incr(int64(n))
+			b.MetricsDropped.Incr(int64(n))
+			dropped += n
+		}
+		if b.first < s.end() {
+			b.first = s.end()
+		}
+		if b.read.index < s.end() {
+			b.read = position{index: s.end()}
+		}
+		b.removeOldest()
+	}
+	return dropped
+}
+
+// Batch returns a slice containing up to batchSize of the oldest metrics not
+// yet written.  Metrics are ordered from oldest to newest in the batch.
+func (b *DiskBuffer) Batch(batchSize int) []cua.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	b.batchFirst = b.read
+	out := make([]cua.Metric, 0, min(batchSize, int(b.next-b.read.index)))
+	for len(out) < batchSize && b.read.index < b.next {
+		i := b.segmentOf(b.read.index)
+		s := b.segments[i]
+		want := min(batchSize-len(out), int(s.end()-b.read.index))
+		n, offset, err := readRecords(s.path, b.read.offset, uint64(want), func(payload []byte) {
+			m, err := b.decode(payload)
+			if err != nil {
+				b.log.Errorf("Reading metric from disk buffer: %v", err)
+				AgentMetricsDropped.Incr(1)
+				b.MetricsDropped.Incr(1)
+				return
+			}
+			out = append(out, m)
+		})
+		b.read = position{index: b.read.index + n, offset: offset}
+		if err != nil {
+			// skip the rest of the segment
+			b.log.Errorf("Reading disk buffer segment %s: %v", s.path, err)
+			lost := s.end() - b.read.index
+			AgentMetricsDropped.Incr(int64(lost))
+			b.MetricsDropped.Incr(int64(lost))
+			b.read = position{index: s.end(), offset: s.size}
+		}
+		if b.read.index == s.end() && i+1 < len(b.segments) {
+			b.read = position{index: b.segments[i+1].first}
+		}
+	}
+	b.batchSize = int(b.read.index - b.batchFirst.index)
+	return out
+}
+
+func (b *DiskBuffer) decode(payload []byte) (cua.Metric, error) {
+	tp := payload[0]
+	var arrival time.Time
+	line := payload[1:]
+	if tp&recordArrival != 0 {
+		if len(line) < 8 {
+			return nil, errCorruptRecord
+		}
+		arrival = time.Unix(0, int64(binary.LittleEndian.Uint64(line)))
+		line = line[8:]
+		tp &^= recordArrival
+	}
+	m, err := b.parser.ParseLine(string(line))
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	m, err = metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), cua.ValueType(tp))
+	if err != nil {
+		return nil, fmt.Errorf("new metric: %w", err)
+	}
+	m.SetArrival(arrival)
+	return m, nil
+}
+
+// Accept marks the batch, acquired from Batch(), as successfully written.
+func (b *DiskBuffer) Accept(batch []cua.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		AgentMetricsWritten.Incr(1)
+		b.MetricsWritten.Incr(1)
+		m.Accept()
+	}
+
+	if end := b.batchFirst.index + uint64(b.batchSize); end > b.first {
+		b.first = end
+	}
+	b.resetBatch()
+	b.compact()
+	if b.cfg.Fsync != FsyncNever {
+		b.sync()
+	}
+	b.writeAck()
+	b.updateStats()
+}
+
+// Reject returns the batch, acquired from Batch(), to the buffer and marks it
+// as unsent.
+func (b *DiskBuffer) Reject(batch []cua.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.batchSize == 0 {
+		return
+	}
+
+	// the metrics dropped while the batch was written are lost
+	for i, m := range batch {
+		if b.batchFirst.index+uint64(i) < b.first {
+			b.metricDropped(m)
+		}
+	}
+	if b.batchFirst.index >= b.first {
+		b.read = b.batchFirst
+	} else {
+		b.read = b.seek(b.first)
+	}
+
+	b.resetBatch()
+	if b.cfg.Fsync != FsyncNever {
+		b.sync()
+	}
+	b.updateStats()
+}
+
+// Close syncs the log and saves the index of the oldest metric not written.
+func (b *DiskBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.file == nil {
+		return nil
+	}
+	b.writeAck()
+	if err := b.file.Sync(); err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+	err := b.file.Close()
+	b.file = nil
+	if err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	return nil
+}
+
+func (b *DiskBuffer) metricDropped(m cua.Metric) {
+	AgentMetricsDropped.Incr(1)
+	b.MetricsDropped.Incr(1)
+	m.Reject()
+}
+
+func (b *DiskBuffer) resetBatch() {
+	b.batchFirst = position{}
+	b.batchSize = 0
+}
+
+func (b *DiskBuffer) sync() {
+	if err := b.file.Sync(); err != nil {
+		b.log.Errorf("Syncing disk buffer: %v", err)
+	}
+}
+
+// writeAck saves the index of the oldest metric not written, the file is
+// replaced so that it is never partially written.
+func (b *DiskBuffer) writeAck() {
+	path := filepath.Join(b.cfg.Directory, ackName)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		b.log.Errorf("Saving disk buffer position: %v", err)
+		return
+	}
+	_, err = f.WriteString(strconv.FormatUint(b.first, 10) + "\n")
+	if err == nil && b.cfg.Fsync != FsyncNever {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		b.log.Errorf("Saving disk buffer position: %v", err)
+	}
+}
+
+func (b *DiskBuffer) updateStats() {
+	b.BufferSize.Set(int64(b.next - b.first))
+	b.DiskSize.Set(b.size)
+}
+
+// readRecords reads up to n records of a segment from offset, calling fn
+// with the payload of each record when not nil.  It returns the number of
+// records read and the offset following the last one.
+func readRecords(path string, offset int64, n uint64, fn func([]byte)) (uint64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, offset, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, offset, fmt.Errorf("seek: %w", err)
+	}
+
+	r := bufio.NewReader(f)
+	var header [recordHeaderSize]byte
+	var count uint64
+	for count < n {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return count, offset, errCorruptRecord
+		}
+		length := binary.LittleEndian.Uint32(header[0:])
+		if length < 2 || length > maxRecordSize {
+			return count, offset, errCorruptRecord
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return count, offset, errCorruptRecord
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:]) {
+			return count, offset, errCorruptRecord
+		}
+		if fn != nil {
+			fn(payload)
+		}
+		count++
+		offset += recordHeaderSize + int64(length)
+	}
+	return count, offset, nil
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskBuffer(t *testing.T, cfg DiskBufferConfig) *DiskBuffer {
+	t.Helper()
+	b, err := NewDiskBuffer("test", "", cfg, testutil.Logger{})
+	require.NoError(t, err)
+	b.MetricsAdded.Set(0)
+	b.MetricsWritten.Set(0)
+	b.MetricsDropped.Set(0)
+	return b
+}
+
+func TestDiskBuffer_AcceptReopen(t *testing.T) {
+	dir := t.TempDir()
+	b := newTestDiskBuffer(t, DiskBufferConfig{Directory: dir})
+
+	for i := int64(0); i < 5; i++ {
+		b.Add(MetricTime(i))
+	}
+	require.Equal(t, 5, b.Len())
+
+	batch := b.Batch(2)
+	testutil.RequireMetricsEqual(t, []cua.Metric{MetricTime(0), MetricTime(1)}, batch)
+	b.Accept(batch)
+	require.Equal(t, 3, b.Len())
+	require.Equal(t, int64(2), b.MetricsWritten.Get())
+	require.NoError(t, b.Close())
+
+	// the metrics not written are kept across restarts
+	b = newTestDiskBuffer(t, DiskBufferConfig{Directory: dir})
+	require.Equal(t, 3, b.Len())
+	batch = b.Batch(10)
+	testutil.RequireMetricsEqual(t, []cua.Metric{MetricTime(2), MetricTime(3), MetricTime(4)}, batch)
+	b.Accept(batch)
+	require.Equal(t, 0, b.Len())
+	require.NoError(t, b.Close())
+}
+
+func TestDiskBuffer_Reject(t *testing.T) {
+	b := newTestDiskBuffer(t, DiskBufferConfig{Directory: t.TempDir()})
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2))
+	batch := b.Batch(2)
+	b.Add(MetricTime(3))
+	b.Reject(batch)
+	require.Equal(t, 3, b.Len())
+
+	batch = b.Batch(10)
+	testutil.RequireMetricsEqual(t, []cua.Metric{MetricTime(1), MetricTime(2), MetricTime(3)}, batch)
+}
+
+func TestDiskBuffer_ValueTypes(t *testing.T) {
+	b := newTestDiskBuffer(t, DiskBufferConfig{Directory: t.TempDir()})
+	defer b.Close()
+
+	m, err := metric.New("requests",
+		map[string]string{"host": "a b"},
+		map[string]interface{}{"count": uint64(42), "rate": 1.5, "ok": true, "status": "up"},
+		time.Unix(0, 1234567890),
+		cua.Counter,
+	)
+	require.NoError(t, err)
+	b.Add(m)
+
+	batch := b.Batch(1)
+	require.Len(t, batch, 1)
+	testutil.RequireMetricsEqual(t, []cua.Metric{m}, batch)
+	require.Equal(t, cua.Counter, batch[0].Type())
+}
+
+func TestDiskBuffer_Arrival(t *testing.T) {
+	dir := t.TempDir()
+	b := newTestDiskBuffer(t, DiskBufferConfig{Directory: dir})
+
+	arrival := time.Unix(1600000000, 123456789)
+	m := MetricTime(1)
+	m.SetArrival(arrival)
+	b.Add(m, MetricTime(2))
+	require.NoError(t, b.Close())
+
+	// the arrival times are kept across restarts, unknown when not set
+	b = newTestDiskBuffer(t, DiskBufferConfig{Directory: dir})
+	defer b.Close()
+	batch := b.Batch(2)
+	testutil.RequireMetricsEqual(t, []cua.Metric{MetricTime(1), MetricTime(2)}, batch)
+	require.True(t, arrival.Equal(batch[0].Arrival()))
+	require.True(t, batch[1].Arrival().IsZero())
+}
+
+func TestDiskBuffer_MaxSize(t *testing.T) {
+	b := newTestDiskBuffer(t, DiskBufferConfig{Directory: t.TempDir(), MaxSize: 4000, SegmentSize: 1000})
+	defer b.Close()
+
+	for i := int64(0); i < 200; i++ {
+		b.Add(MetricTime(i))
+	}
+	require.LessOrEqual(t, b.size, int64(4000))
+	dropped := b.MetricsDropped.Get()
+	require.Greater(t, dropped, int64(0))
+	require.Equal(t, 200-int(dropped), b.Len())
+
+	// the oldest metrics are dropped
+	batch := b.Batch(1)
+	testutil.RequireMetricsEqual(t, []cua.Metric{MetricTime(dropped)}, batch)
+
+	segments, err := filepath.Glob(filepath.Join(b.cfg.Directory, "*"+segmentExt))
+	require.NoError(t, err)
+	require.Len(t, segments, len(b.segments))
+}
+
+func TestDiskBuffer_Compact(t *testing.T) {
+	dir := t.TempDir()
+	b := newTestDiskBuffer(t, DiskBufferConfig{Directory: dir, SegmentSize: 200})
+	defer b.Close()
+
+	for i := int64(0); i < 20; i++ {
+		b.Add(MetricTime(i))
+	}
+	require.Greater(t, len(b.segments), 2)
+
+	b.Accept(b.Batch(20))
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	require.Equal(t, 0, b.Len())
+}
+
+func TestDiskBuffer_TruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+	b := newTestDiskBuffer(t, DiskBufferConfig{Directory: dir})
+	b.Add(MetricTime(1), MetricTime(2))
+	path := b.segments[0].path
+	require.NoError(t, b.Close())
+
+	// a record partially written when the agent stopped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0640)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{42, 0, 0, 0, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b = newTestDiskBuffer(t, DiskBufferConfig{Directory: dir})
+	defer b.Close()
+	require.Equal(t, 2, b.Len())
+	b.Add(MetricTime(3))
+	testutil.RequireMetricsEqual(t, []cua.Metric{MetricTime(1), MetricTime(2), MetricTime(3)}, b.Batch(10))
+}
+
+func TestDiskBuffer_InvalidFsync(t *testing.T) {
+	_, err := NewDiskBuffer("test", "", DiskBufferConfig{Directory: t.TempDir(), Fsync: "sometimes"}, testutil.Logger{})
+	require.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+// ErrorsSuppressed counts the repeated errors not written to the log.
+var ErrorsSuppressed = selfstat.Register("agent", "errors_suppressed", map[string]string{})
+
+// errorSuppressor writes a repeated error of a plugin once per interval, the
+// repetitions are summarized in a single line at the end of the interval.
+type errorSuppressor struct {
+	sync.Mutex
+	interval time.Duration
+	errors   map[errorKey]*repeatedError
+}
+
+type errorKey struct {
+	plugin  string
+	message string
+}
+
+type repeatedError struct {
+	since time.Time
+	count int64
+}
+
+var suppressor = &errorSuppressor{errors: map[errorKey]*repeatedError{}}
+
+// SuppressRepeatedErrors enables the suppression of the identical errors
+// logged by a plugin within interval, until ctx is done.  The number of
+// suppressed errors is logged at the end of each interval.
+func SuppressRepeatedErrors(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	suppressor.Lock()
+	suppressor.interval = interval
+	suppressor.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				suppressor.summarize(time.Now(), true)
+				suppressor.Lock()
+				suppressor.interval = 0
+				suppressor.Unlock()
+				return
+			case now := <-ticker.C:
+				suppressor.summarize(now, false)
+			}
+		}
+	}()
+}
+
+// suppress returns true when the error was already logged within the
+// interval.
+func (s *errorSuppressor) suppress(plugin, message string, now time.Time) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.interval <= 0 {
+		return false
+	}
+	key := errorKey{plugin: plugin, message: message}
+	if e, ok := s.errors[key]; ok {
+		if now.Sub(e.since) < s.interval {
+			e.count++
+			ErrorsSuppressed.Incr(1)
+			return true
+		}
+		// the interval ended before the summaries were written
+		e.log(key, now)
+	}
+	s.errors[key] = &repeatedError{since: now}
+	return false
+}
+
+// summarize logs the count of the errors suppressed over an interval ended
+// before now, or of all the errors, and forgets them.
+func (s *errorSuppressor) summarize(now time.Time, all bool) {
+	s.Lock()
+	defer s.Unlock()
+	for key, e := range s.errors {
+		if !all && now.Sub(e.since) < s.interval {
+			continue
+		}
+		e.log(key, now)
+		delete(s.errors, key)
+	}
+}
+
+func (e *repeatedError) log(key errorKey, now time.Time) {
+	if e.count == 0 {
+		return
+	}
+	log.Printf("E! [%s] Error repeated %d more times in the last %s: %s",
+		key.plugin, e.count, now.Sub(e.since).Round(time.Second), key.message)
+}
@@ -105,13 +105,45 @@ func (f *Filter) Select(metric cua.Metric) bool {
 		return false
 	}
 
-	if !f.shouldTagsPass(metric.TagList()) {
+	if !f.tagsPass(metric.GetTag) {
 		return false
 	}
 
 	return true
 }
 
+// SelectRaw returns true if a metric with the name, tags and fields would be
+// selected and keep at least a field after the fieldpass/fielddrop filters.
+// It is evaluated before the metric is created, to not allocate the metrics
+// which would be dropped.
+func (f *Filter) SelectRaw(name string, tags map[string]string, fields map[string]interface{}) bool {
+	if !f.isActive {
+		return true
+	}
+
+	if !f.shouldNamePass(name) {
+		return false
+	}
+
+	getTag := func(key string) (string, bool) {
+		value, ok := tags[key]
+		return value, ok
+	}
+	if !f.tagsPass(getTag) {
+		return false
+	}
+
+	if f.fieldPass == nil && f.fieldDrop == nil {
+		return true
+	}
+	for key := range fields {
+		if f.shouldFieldPass(key) {
+			return true
+		}
+	}
+	return false
+}
+
 // Modify removes any tags and fields from the metric according to the
 // fieldpass/fielddrop and taginclude/tagexclude filters.
 func (f *Filter) Modify(metric cua.Metric) {
@@ -136,7 +168,7 @@ func (f *Filter) shouldNamePass(key string) bool {
 	}
 
 	drop := func(f *Filter) bool {
-		return f.nameDrop.Match(key)
+		return !f.nameDrop.Match(key)
 	}
 
 	switch {
@@ -168,17 +200,25 @@ func (f *Filter) shouldFieldPass(key string) bool {
 // shouldTagsPass returns true if the metric should pass, false if should drop
 // based on the tagdrop/tagpass filter parameters
 func (f *Filter) shouldTagsPass(tags []*cua.Tag) bool {
+	return f.tagsPass(func(key string) (string, bool) {
+		for _, tag := range tags {
+			if tag.Key == key {
+				return tag.Value, true
+			}
+		}
+		return "", false
+	})
+}
+
+// tagsPass is shouldTagsPass for the tags returned by getTag.
+func (f *Filter) tagsPass(getTag func(key string) (string, bool)) bool {
 	pass := func(f *Filter) bool {
 		for _, pat := range f.TagPass {
 			if pat.filter == nil {
 				continue
 			}
-			for _, tag := range tags {
-				if tag.Key == pat.Name {
-					if pat.filter.Match(tag.Value) {
-						return true
-					}
-				}
+			if value, ok := getTag(pat.Name); ok && pat.filter.Match(value) {
+				return true
 			}
 		}
 		return false
@@ -189,12 +229,8 @@ func (f *Filter) shouldTagsPass(tags []*cua.Tag) bool {
 			if pat.filter == nil {
 				continue
 			}
-			for _, tag := range tags {
-				if tag.Key == pat.Name {
-					if pat.filter.Match(tag.Value) {
-						return false
-					}
-				}
+			if value, ok := getTag(pat.Name); ok && pat.filter.Match(value) {
+				return false
 			}
 		}
 		return true
@@ -482,6 +482,41 @@ func TestFilter_FilterTagsPassAndDrop(t *testing.T) {
 
 }
 
+func TestFilter_SelectRaw(t *testing.T) {
+	f := Filter{
+		NamePass:  []string{"cpu*"},
+		TagDrop:   []TagFilter{{Name: "host", Filter: []string{"test*"}}},
+		FieldDrop: []string{"usage_*"},
+	}
+	require.NoError(t, f.Compile())
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		fields   map[string]interface{}
+		expected bool
+	}{
+		{"cpu", map[string]string{"host": "prod1"}, map[string]interface{}{"time_user": 1}, true},
+		{"mem", map[string]string{"host": "prod1"}, map[string]interface{}{"time_user": 1}, false},
+		{"cpu", map[string]string{"host": "test1"}, map[string]interface{}{"time_user": 1}, false},
+		{"cpu", map[string]string{}, map[string]interface{}{"usage_user": 1, "time_user": 1}, true},
+		{"cpu", map[string]string{}, map[string]interface{}{"usage_user": 1}, false},
+	}
+	for _, tt := range tests {
+		m := testutil.MustMetric(tt.name, tt.tags, tt.fields, time.Unix(0, 0))
+		require.Equal(t, tt.expected, f.SelectRaw(tt.name, tt.tags, tt.fields))
+
+		// the metrics selected before they are created are the metrics
+		// selected and keeping fields once they are created
+		selected := f.Select(m)
+		if selected {
+			f.Modify(m)
+			selected = len(m.FieldList()) > 0
+		}
+		require.Equal(t, selected, f.SelectRaw(tt.name, tt.tags, tt.fields))
+	}
+}
+
 func BenchmarkFilter(b *testing.B) {
 	tests := []struct {
 		name   string
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"fmt"
 	"log"
 	"reflect"
+	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 )
@@ -11,6 +13,8 @@ import (
 type Logger struct {
 	OnErrs []func()
 	Name   string // Name is the plugin name, will be printed in the `[]`.
+
+	lastErr lastError
 }
 
 // NewLogger creates a new logger instance
@@ -25,12 +29,19 @@ func (l *Logger) OnErr(f func()) {
 	l.OnErrs = append(l.OnErrs, f)
 }
 
-// Errorf logs an error message, patterned after log.Printf.
+// Errorf logs an error message, patterned after log.Printf.  The errors
+// repeated within the suppression interval are counted but not logged.
 func (l *Logger) Errorf(format string, args ...interface{}) {
 	for _, f := range l.OnErrs {
 		f()
 	}
-	log.Printf("E! ["+l.Name+"] "+format, args...)
+	message := fmt.Sprintf(format, args...)
+	now := time.Now()
+	l.lastErr.set(message, now)
+	if suppressor.suppress(l.Name, message, now) {
+		return
+	}
+	log.Print("E! [" + l.Name + "] " + message)
 }
 
 // Error logs an error message, patterned after log.Print.
@@ -38,7 +49,13 @@ func (l *Logger) Error(args ...interface{}) {
 	for _, f := range l.OnErrs {
 		f()
 	}
-	log.Print(append([]interface{}{"E! [" + l.Name + "] "}, args...)...)
+	message := fmt.Sprint(args...)
+	now := time.Now()
+	l.lastErr.set(message, now)
+	if suppressor.suppress(l.Name, message, now) {
+		return
+	}
+	log.Print("E! [" + l.Name + "] " + message)
 }
 
 // Debugf logs a debug message, patterned after log.Printf.
@@ -1,7 +1,12 @@
 package models
 
 import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/selfstat"
 	"github.com/stretchr/testify/require"
@@ -22,3 +27,43 @@ func TestErrorCounting(t *testing.T) {
 
 	require.Equal(t, int64(2), reg.Get())
 }
+
+func TestErrorSuppression(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	suppressor.Lock()
+	suppressor.interval = time.Minute
+	suppressor.Unlock()
+	defer func() {
+		suppressor.Lock()
+		suppressor.interval = 0
+		suppressor.errors = map[errorKey]*repeatedError{}
+		suppressor.Unlock()
+	}()
+
+	reg := selfstat.Register("gather", "errors", map[string]string{"input": "suppressed"})
+	iLog := Logger{Name: "inputs.suppressed"}
+	iLog.OnErr(func() {
+		reg.Incr(1)
+	})
+	suppressed := ErrorsSuppressed.Get()
+	for i := 0; i < 3; i++ {
+		iLog.Errorf("url %s is down", "http://localhost")
+	}
+	iLog.Error("another error")
+
+	// the repetitions are counted but only logged once
+	require.Equal(t, int64(4), reg.Get())
+	require.Equal(t, suppressed+2, ErrorsSuppressed.Get())
+	require.Equal(t, 1, strings.Count(buf.String(), "url http://localhost is down"))
+	require.Contains(t, buf.String(), "another error")
+
+	buf.Reset()
+	suppressor.summarize(time.Now(), false)
+	require.Empty(t, buf.String())
+	suppressor.summarize(time.Now().Add(time.Minute), false)
+	require.Contains(t, buf.String(), "E! [inputs.suppressed] Error repeated 2 more times in the last 1m0s: url http://localhost is down")
+	require.Empty(t, suppressor.errors)
+}
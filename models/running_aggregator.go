@@ -69,6 +69,7 @@ func NewRunningAggregator(aggregator cua.Aggregator, config *AggregatorConfig) *
 type AggregatorConfig struct {
 	Name         string
 	Alias        string
+	LogLevel     string
 	DropOriginal bool
 	Period       time.Duration
 	Delay        time.Duration
@@ -81,6 +82,11 @@ type AggregatorConfig struct {
 	Filter            Filter
 }
 
+// Status returns the last error of the aggregator.
+func (r *RunningAggregator) Status() PluginStatus {
+	return newPluginStatus(r.Config.Name, r.Config.Alias, r.log, 0)
+}
+
 func (r *RunningAggregator) LogName() string {
 	return logName("aggregators", r.Config.Name, r.Config.Alias)
 }
@@ -120,6 +126,7 @@ func (r *RunningAggregator) MakeMetric(metric cua.Metric) cua.Metric {
 
 	if m != nil {
 		m.SetAggregate(true)
+		m.SetArrival(time.Now())
 	}
 
 	r.MetricsPushed.Incr(1)
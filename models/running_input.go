@@ -2,7 +2,10 @@ package models
 
 import (
 	"fmt"
+	"sort"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/selfstat"
@@ -22,6 +25,19 @@ type RunningInput struct {
 
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
+
+	// registered when the corresponding quota is set
+	MetricsDroppedQuota   selfstat.Stat
+	FieldsDroppedQuota    selfstat.Stat
+	StringsTruncatedQuota selfstat.Stat
+
+	// metrics made since the start of the last gather, and whether the
+	// quota warning was logged, accessed atomically
+	gathered    int64
+	quotaWarned int32
+
+	// end of the last successful gather in nanoseconds, accessed atomically
+	lastGather int64
 }
 
 func NewRunningInput(input cua.Input, config *InputConfig) *RunningInput {
@@ -38,7 +54,7 @@ func NewRunningInput(input cua.Input, config *InputConfig) *RunningInput {
 	})
 	SetLoggerOnPlugin(input, logger)
 
-	return &RunningInput{
+	r := &RunningInput{
 		Input:  input,
 		Config: config,
 		MetricsGathered: selfstat.Register(
@@ -53,6 +69,16 @@ func NewRunningInput(input cua.Input, config *InputConfig) *RunningInput {
 		),
 		log: logger,
 	}
+	if config.MaxMetricsPerGather > 0 {
+		r.MetricsDroppedQuota = selfstat.Register("gather", "quota_metrics_dropped", tags)
+	}
+	if config.MaxFieldsPerMetric > 0 {
+		r.FieldsDroppedQuota = selfstat.Register("gather", "quota_fields_dropped", tags)
+	}
+	if config.MaxStringFieldLength > 0 {
+		r.StringsTruncatedQuota = selfstat.Register("gather", "quota_strings_truncated", tags)
+	}
+	return r
 }
 
 // InputConfig is the common config for all inputs.
@@ -60,8 +86,10 @@ type InputConfig struct {
 	Name             string
 	InstanceID       string
 	Alias            string
+	LogLevel         string
 	Interval         time.Duration
 	CollectionJitter time.Duration
+	CollectionOffset time.Duration
 	Precision        time.Duration
 
 	NameOverride      string
@@ -69,6 +97,12 @@ type InputConfig struct {
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
+
+	// Quotas protecting the pipeline from misbehaving inputs, disabled
+	// when zero.
+	MaxMetricsPerGather  int
+	MaxFieldsPerMetric   int
+	MaxStringFieldLength int
 }
 
 func (r *RunningInput) metricFiltered(metric cua.Metric) {
@@ -89,6 +123,12 @@ func (r *RunningInput) Init() error {
 	return nil
 }
 
+// SelectMetric returns false when the metric would be dropped by the filters
+// of the input, before the metric is created.
+func (r *RunningInput) SelectMetric(measurement string, tags map[string]string, fields map[string]interface{}) bool {
+	return r.Config.Filter.SelectRaw(measurement, tags, fields)
+}
+
 func (r *RunningInput) MakeMetric(metric cua.Metric) cua.Metric {
 	if ok := r.Config.Filter.Select(metric); !ok {
 		r.metricFiltered(metric)
@@ -105,6 +145,7 @@ func (r *RunningInput) MakeMetric(metric cua.Metric) cua.Metric {
 
 	m.SetOrigin(r.Config.Name)
 	m.SetOriginInstance(r.Config.InstanceID)
+	m.SetArrival(time.Now())
 
 	r.Config.Filter.Modify(metric)
 	if len(metric.FieldList()) == 0 {
@@ -112,12 +153,71 @@ func (r *RunningInput) MakeMetric(metric cua.Metric) cua.Metric {
 		return nil
 	}
 
+	if !r.applyQuotas(m) {
+		r.metricFiltered(m)
+		return nil
+	}
+
 	r.MetricsGathered.Incr(1)
 	GlobalMetricsGathered.Incr(1)
 	return m
 }
 
+// applyQuotas enforces the quotas of the input on the metric, it returns
+// false when the metric must be dropped.
+func (r *RunningInput) applyQuotas(metric cua.Metric) bool {
+	if max := r.Config.MaxMetricsPerGather; max > 0 {
+		if atomic.AddInt64(&r.gathered, 1) > int64(max) {
+			r.MetricsDroppedQuota.Incr(1)
+			if atomic.CompareAndSwapInt32(&r.quotaWarned, 0, 1) {
+				r.log.Warnf("Quota of %d metrics per gather reached, dropping metrics", max)
+			}
+			return false
+		}
+	}
+
+	if max := r.Config.MaxFieldsPerMetric; max > 0 {
+		fields := metric.FieldList()
+		if len(fields) > max {
+			// keep the first fields in the order of their keys, for the
+			// same fields to be kept at each gather
+			keys := make([]string, 0, len(fields))
+			for _, field := range fields {
+				keys = append(keys, field.Key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys[max:] {
+				metric.RemoveField(key)
+			}
+			r.FieldsDroppedQuota.Incr(int64(len(keys) - max))
+		}
+	}
+
+	if max := r.Config.MaxStringFieldLength; max > 0 {
+		for _, field := range metric.FieldList() {
+			if value, ok := field.Value.(string); ok && len(value) > max {
+				metric.AddField(field.Key, truncateString(value, max))
+				r.StringsTruncatedQuota.Incr(1)
+			}
+		}
+	}
+
+	return true
+}
+
+// truncateString truncates s to at most n bytes without splitting a UTF-8
+// encoded character.
+func truncateString(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
 func (r *RunningInput) Gather(acc cua.Accumulator) error {
+	atomic.StoreInt64(&r.gathered, 0)
+	atomic.StoreInt32(&r.quotaWarned, 0)
+
 	start := time.Now()
 	err := r.Input.Gather(acc)
 	elapsed := time.Since(start)
@@ -125,9 +225,16 @@ func (r *RunningInput) Gather(acc cua.Accumulator) error {
 	if err != nil {
 		return fmt.Errorf("gather (input %s): %w", r.Config.Name, err)
 	}
+	atomic.StoreInt64(&r.lastGather, start.Add(elapsed).UnixNano())
 	return nil
 }
 
+// Status returns the last error and the time of the last successful gather
+// of the input.
+func (r *RunningInput) Status() PluginStatus {
+	return newPluginStatus(r.Config.Name, r.Config.Alias, r.log, atomic.LoadInt64(&r.lastGather))
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
@@ -61,6 +61,23 @@ func TestMakeMetricNoFields(t *testing.T) {
 	assert.Nil(t, m)
 }
 
+func TestSelectMetric(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name: "TestRunningInput",
+		Filter: Filter{
+			NameDrop: []string{"mem"},
+			TagPass:  []TagFilter{{Name: "env", Filter: []string{"prod"}}},
+		},
+	})
+	require.NoError(t, ri.Config.Filter.Compile())
+
+	fields := map[string]interface{}{"value": int64(42)}
+	require.True(t, ri.SelectMetric("cpu", map[string]string{"env": "prod"}, fields))
+	require.False(t, ri.SelectMetric("mem", map[string]string{"env": "prod"}, fields))
+	require.False(t, ri.SelectMetric("cpu", map[string]string{"env": "dev"}, fields))
+	require.False(t, ri.SelectMetric("cpu", map[string]string{}, fields))
+}
+
 // nil fields should get dropped
 func TestMakeMetricNilFields(t *testing.T) {
 	now := time.Now()
@@ -286,6 +303,88 @@ func TestMetricErrorCounters(t *testing.T) {
 	require.GreaterOrEqual(t, int64(1), GlobalGatherErrors.Get())
 }
 
+func TestMakeMetricMaxMetricsPerGather(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:                "TestMaxMetricsPerGather",
+		MaxMetricsPerGather: 2,
+	})
+	ri.log = testutil.Logger{}
+
+	for gather := 0; gather < 2; gather++ {
+		require.NoError(t, ri.Gather(&testutil.Accumulator{}))
+		for i := 0; i < 3; i++ {
+			m := ri.MakeMetric(testutil.TestMetric(i))
+			if i < 2 {
+				require.NotNil(t, m)
+			} else {
+				require.Nil(t, m)
+			}
+		}
+	}
+	require.Equal(t, int64(2), ri.MetricsDroppedQuota.Get())
+	require.Equal(t, int64(4), ri.MetricsGathered.Get())
+}
+
+func TestMakeMetricMaxFieldsPerMetric(t *testing.T) {
+	now := time.Now()
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:               "TestMaxFieldsPerMetric",
+		MaxFieldsPerMetric: 2,
+	})
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"d": 4,
+			"b": 2,
+			"c": 3,
+			"a": 1,
+		},
+		now)
+	actual := ri.MakeMetric(m)
+
+	expected := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"a": 1,
+			"b": 2,
+		},
+		now)
+	testutil.RequireMetricEqual(t, expected, actual)
+	require.Equal(t, int64(2), ri.FieldsDroppedQuota.Get())
+}
+
+func TestMakeMetricMaxStringFieldLength(t *testing.T) {
+	now := time.Now()
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:                 "TestMaxStringFieldLength",
+		MaxStringFieldLength: 5,
+	})
+
+	m := testutil.MustMetric("exec",
+		map[string]string{},
+		map[string]interface{}{
+			"short":   "abc",
+			"long":    "abcdefgh",
+			"unicode": "abcd\u00e9f",
+			"value":   42,
+		},
+		now)
+	actual := ri.MakeMetric(m)
+
+	expected := testutil.MustMetric("exec",
+		map[string]string{},
+		map[string]interface{}{
+			"short":   "abc",
+			"long":    "abcde",
+			"unicode": "abcd",
+			"value":   42,
+		},
+		now)
+	testutil.RequireMetricEqual(t, expected, actual)
+	require.Equal(t, int64(2), ri.StringsTruncatedQuota.Get())
+}
+
 type testInput struct{}
 
 func (t *testInput) Description() string              { return "" }
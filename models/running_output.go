@@ -20,25 +20,63 @@ const (
 
 // OutputConfig containing name and filter
 type OutputConfig struct {
-	Name   string
-	Alias  string
-	Filter Filter
+	Name     string
+	Alias    string
+	LogLevel string
+	Filter   Filter
 
 	FlushInterval     time.Duration
 	FlushJitter       time.Duration
 	MetricBufferLimit int
 	MetricBatchSize   int
+	// DiskBuffer keeps the metrics in a log on disk instead of memory, when
+	// its directory is set.
+	DiskBuffer DiskBufferConfig
+	// AdaptiveFlush flushes early and in larger batches when the buffer
+	// fills up, and less often while the writes are slow.
+	AdaptiveFlush AdaptiveFlushConfig
 
 	NameOverride string
 	NamePrefix   string
 	NameSuffix   string
+	// Tags are added to the metrics written by the output, when not already
+	// set.
+	Tags map[string]string
+	// Fallback outputs only receive the metrics which are not selected by
+	// the filters of the other outputs.
+	Fallback bool
 }
 
+// AdaptiveFlushConfig is the configuration of the adaptive flushing of an
+// output.
+type AdaptiveFlushConfig struct {
+	Enabled bool
+	// HighWaterMark is the percentage of the buffer limit above which the
+	// buffer is flushed early, in batches of up to MaxBatchSize metrics.
+	HighWaterMark int
+	MaxBatchSize  int
+	// MaxInterval bounds the flush interval stretched by slow writes.
+	MaxInterval time.Duration
+}
+
+const (
+	// DefaultHighWaterMark is the default high-water mark of the adaptive
+	// flushing, in percent of the buffer limit.
+	DefaultHighWaterMark = 50
+
+	// adaptiveFlushFactor is the default factor of the batch size and of
+	// the flush interval bounding them with the adaptive flushing.
+	adaptiveFlushFactor = 4
+)
+
 // RunningOutput contains the output configuration
 type RunningOutput struct {
 	// Must be 64-bit aligned
 	newMetricsCount int64
 	droppedMetrics  int64
+	// end of the last successful write in nanoseconds
+	lastWrite int64
+	connected int32
 
 	Output            cua.Output
 	Config            *OutputConfig
@@ -47,15 +85,33 @@ type RunningOutput struct {
 
 	MetricsFiltered selfstat.Stat
 	WriteTime       selfstat.Stat
+	// WriteLatency is the distribution of the end-to-end latencies, from
+	// the gather of the metrics to their successful write, in seconds.
+	WriteLatency *selfstat.Histogram
 
 	BatchReady chan time.Time
 
-	buffer *Buffer
+	// highWater is the number of buffered metrics above which the buffer is
+	// under pressure, zero without adaptive flushing.
+	highWater    int
+	maxBatchSize int
+
+	buffer metricBuffer
 	log    cua.Logger
 
 	aggMutex sync.Mutex
 }
 
+// metricBuffer holds the metrics of an output until they are written.
+type metricBuffer interface {
+	Len() int
+	Add(metrics ...cua.Metric) int
+	Batch(batchSize int) []cua.Metric
+	Accept(batch []cua.Metric)
+	Reject(batch []cua.Metric)
+	Close() error
+}
+
 func NewRunningOutput(
 	name string,
 	output cua.Output,
@@ -105,12 +161,50 @@ func NewRunningOutput(
 			"write_time_ns",
 			tags,
 		),
+		WriteLatency: selfstat.RegisterHistogram("write_latency", tags),
 		log: logger,
 	}
 
+	if af := config.AdaptiveFlush; af.Enabled {
+		mark := af.HighWaterMark
+		if mark <= 0 || mark > 100 {
+			mark = DefaultHighWaterMark
+		}
+		ro.highWater = (bufferLimit*mark + 99) / 100
+		ro.maxBatchSize = af.MaxBatchSize
+		if ro.maxBatchSize < batchSize {
+			ro.maxBatchSize = batchSize * adaptiveFlushFactor
+		}
+		if ro.maxBatchSize > bufferLimit {
+			ro.maxBatchSize = bufferLimit
+		}
+	}
+
 	return ro
 }
 
+// UnderPressure returns whether the buffer of an output with adaptive
+// flushing is above its high-water mark.
+func (ro *RunningOutput) UnderPressure() bool {
+	return ro.highWater > 0 && ro.buffer.Len() >= ro.highWater
+}
+
+// batchSize returns the size of the next batch, which grows with the
+// buffer above its high-water mark.
+func (ro *RunningOutput) batchSize() int {
+	if !ro.UnderPressure() {
+		return ro.MetricBatchSize
+	}
+	size := ro.MetricBatchSize * ro.buffer.Len() / ro.highWater
+	if size > ro.maxBatchSize {
+		size = ro.maxBatchSize
+	}
+	if size < ro.MetricBatchSize {
+		size = ro.MetricBatchSize
+	}
+	return size
+}
+
 func (ro *RunningOutput) LogName() string {
 	return logName("outputs", ro.Config.Name, ro.Config.Alias)
 }
@@ -128,9 +222,22 @@ func (ro *RunningOutput) Init() error {
 		}
 
 	}
+	if ro.Config.DiskBuffer.Directory != "" {
+		buffer, err := NewDiskBuffer(ro.Config.Name, ro.Config.Alias, ro.Config.DiskBuffer, ro.log)
+		if err != nil {
+			return fmt.Errorf("disk buffer (output %s): %w", ro.Config.Name, err)
+		}
+		ro.buffer = buffer
+	}
 	return nil
 }
 
+// Select returns true if the metric is selected by the namepass/namedrop and
+// tagpass/tagdrop filters of the output.  The metric is not modified.
+func (ro *RunningOutput) Select(metric cua.Metric) bool {
+	return ro.Config.Filter.Select(metric)
+}
+
 // AddMetric adds a metric to the output.
 //
 // Takes ownership of metric
@@ -153,23 +260,21 @@ func (ro *RunningOutput) AddMetric(metric cua.Metric) {
 		return
 	}
 
-	if len(ro.Config.NameOverride) > 0 {
-		metric.SetName(ro.Config.NameOverride)
-	}
-
-	if len(ro.Config.NamePrefix) > 0 {
-		metric.AddPrefix(ro.Config.NamePrefix)
-	}
-
-	if len(ro.Config.NameSuffix) > 0 {
-		metric.AddSuffix(ro.Config.NameSuffix)
-	}
+	metric = makemetric(
+		metric,
+		ro.Config.NameOverride,
+		ro.Config.NamePrefix,
+		ro.Config.NameSuffix,
+		ro.Config.Tags,
+		nil)
 
 	dropped := ro.buffer.Add(metric)
 	atomic.AddInt64(&ro.droppedMetrics, int64(dropped))
 
+	// above the high-water mark the batch is ready when the grown batch is
+	// full, not on every metric
 	count := atomic.AddInt64(&ro.newMetricsCount, 1)
-	if count == int64(ro.MetricBatchSize) {
+	if count >= int64(ro.batchSize()) {
 		atomic.StoreInt64(&ro.newMetricsCount, 0)
 		select {
 		case ro.BatchReady <- time.Now():
@@ -194,12 +299,12 @@ func (ro *RunningOutput) Write() error {
 	// Only process the metrics in the buffer now.  Metrics added while we are
 	// writing will be sent on the next call.
 	nBuffer := ro.buffer.Len()
-	nBatches := nBuffer/ro.MetricBatchSize + 1
-	for i := 0; i < nBatches; i++ {
-		batch := ro.buffer.Batch(ro.MetricBatchSize)
+	for nBuffer > 0 {
+		batch := ro.buffer.Batch(ro.batchSize())
 		if len(batch) == 0 {
 			break
 		}
+		nBuffer -= len(batch)
 
 		err := ro.write(batch)
 		if err != nil {
@@ -213,7 +318,7 @@ func (ro *RunningOutput) Write() error {
 
 // WriteBatch writes a single batch of metrics to the output.
 func (ro *RunningOutput) WriteBatch() error {
-	batch := ro.buffer.Batch(ro.MetricBatchSize)
+	batch := ro.buffer.Batch(ro.batchSize())
 	if len(batch) == 0 {
 		return nil
 	}
@@ -230,10 +335,14 @@ func (ro *RunningOutput) WriteBatch() error {
 
 // Close closes the output
 func (ro *RunningOutput) Close() {
+	ro.SetConnected(false)
 	err := ro.Output.Close()
 	if err != nil {
 		ro.log.Errorf("Error closing output: %v", err)
 	}
+	if err := ro.buffer.Close(); err != nil {
+		ro.log.Errorf("Error closing buffer: %v", err)
+	}
 }
 
 func (ro *RunningOutput) write(metrics []cua.Metric) error {
@@ -254,11 +363,54 @@ func (ro *RunningOutput) write(metrics []cua.Metric) error {
 	if err != nil {
 		return fmt.Errorf("write (output %s): %w", ro.Config.Name, err)
 	}
+	end := start.Add(elapsed)
+	for _, m := range metrics {
+		// the timestamps of the metrics may come from their source, the
+		// latency is measured from their arrival in the pipeline, unknown
+		// for the metrics created by the processors
+		if arrival := m.Arrival(); !arrival.IsZero() {
+			ro.WriteLatency.Observe(end.Sub(arrival).Seconds())
+		}
+	}
+	atomic.StoreInt64(&ro.lastWrite, end.UnixNano())
 	return nil
 }
 
+// SetConnected records whether the output is connected.
+func (ro *RunningOutput) SetConnected(connected bool) {
+	var v int32
+	if connected {
+		v = 1
+	}
+	atomic.StoreInt32(&ro.connected, v)
+}
+
+// Status returns the connection, the buffer, the last error and the time
+// of the last successful write of the output.
+func (ro *RunningOutput) Status() OutputStatus {
+	return OutputStatus{
+		PluginStatus: newPluginStatus(ro.Config.Name, ro.Config.Alias, ro.log, atomic.LoadInt64(&ro.lastWrite)),
+		Connected:    atomic.LoadInt32(&ro.connected) == 1,
+		BufferSize:   ro.buffer.Len(),
+		BufferLimit:  ro.bufferLimit(),
+	}
+}
+
+// bufferLimit returns the max number of metrics of the buffer, zero for a
+// disk buffer which is limited in bytes.
+func (ro *RunningOutput) bufferLimit() int {
+	if _, ok := ro.buffer.(*DiskBuffer); ok {
+		return 0
+	}
+	return ro.MetricBufferLimit
+}
+
 func (ro *RunningOutput) LogBufferStatus() {
 	nBuffer := ro.buffer.Len()
+	if b, ok := ro.buffer.(*DiskBuffer); ok {
+		ro.log.Debugf("Buffer fullness: %d metrics, %d / %d bytes", nBuffer, b.DiskSize.Get(), b.cfg.MaxSize)
+		return
+	}
 	ro.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, ro.MetricBufferLimit)
 }
 
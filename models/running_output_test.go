@@ -272,6 +272,36 @@ func TestRunningOutput_NameSuffix(t *testing.T) {
 	assert.Equal(t, "metric1_suffix", m.Metrics()[0].Name())
 }
 
+// Test that the output tags are added without overwriting the metric tags
+func TestRunningOutput_Tags(t *testing.T) {
+	conf := &OutputConfig{
+		NamePrefix: "prefix_",
+		Tags:       map[string]string{"destination": "archive", "tag1": "output"},
+		Filter: Filter{
+			FieldDrop: []string{"value"},
+		},
+	}
+	assert.NoError(t, conf.Filter.Compile())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	ro.AddMetric(testutil.MustMetric("metric1",
+		map[string]string{"tag1": "value1"},
+		map[string]interface{}{"value": 101, "usage": 42},
+		time.Unix(0, 0)))
+
+	err := ro.Write()
+	assert.NoError(t, err)
+	assert.Len(t, m.Metrics(), 1)
+	testutil.RequireMetricEqual(t,
+		testutil.MustMetric("prefix_metric1",
+			map[string]string{"tag1": "value1", "destination": "archive"},
+			map[string]interface{}{"usage": 42},
+			time.Unix(0, 0)),
+		m.Metrics()[0])
+}
+
 // Test that we can write metrics with simple default setup.
 func TestRunningOutputDefault(t *testing.T) {
 	conf := &OutputConfig{
@@ -326,6 +356,36 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+// Verify that the metrics of a disk buffer are written after a restart.
+func TestRunningOutputDiskBufferRestart(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:     Filter{},
+		DiskBuffer: DiskBufferConfig{Directory: t.TempDir()},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.Init())
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	ro.Close()
+
+	m.failWrite = false
+	ro = NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.Init())
+	require.Equal(t, 5, ro.BufferLength())
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	ro.Close()
+
+	testutil.RequireMetricsEqual(t, append(first5, next5...), m.Metrics())
+}
+
 // Verify that the order of points is preserved during a write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
@@ -512,6 +572,101 @@ func TestInternalMetrics(t *testing.T) {
 	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
 }
 
+func TestRunningOutputWriteLatency(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Name:   "test_latency",
+	}
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	// the latency is measured from the arrival of the metrics, their
+	// timestamps may come from their source
+	now := time.Now()
+	arrived := func(tm, arrival time.Time) cua.Metric {
+		m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1}, tm)
+		m.SetArrival(arrival)
+		return m
+	}
+	ro.AddMetric(arrived(now.Add(-time.Hour), now.Add(-2*time.Minute)))
+	ro.AddMetric(arrived(now.Add(time.Hour), now.Add(-2*time.Minute)))
+	m.failWrite = true
+	require.Error(t, ro.Write())
+	m.failWrite = false
+	ro.AddMetric(arrived(now.Add(-2*time.Minute), now.Add(time.Minute)))
+	// without arrival time, as the metrics created by the processors
+	ro.AddMetric(testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1}, now.Add(-time.Hour)))
+	require.NoError(t, ro.Write())
+
+	var latency cua.Metric
+	for _, h := range selfstat.HistogramMetrics() {
+		output, _ := h.GetTag("output")
+		if h.Name() == "internal_write_latency" && output == "test_latency" {
+			latency = h
+		}
+	}
+	require.NotNil(t, latency)
+	require.Equal(t, cua.Histogram, latency.Type())
+	// the failed write is not counted, a metric arriving in the future has no
+	// latency
+	require.Equal(t, map[string]interface{}{"120": int64(2), "0": int64(1)}, latency.Fields())
+}
+
+func TestRunningOutputAdaptiveFlush(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		AdaptiveFlush: AdaptiveFlushConfig{
+			Enabled:       true,
+			HighWaterMark: 50,
+			MaxBatchSize:  3,
+		},
+	}
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 2, 10)
+
+	for _, metric := range first5[:3] {
+		ro.AddMetric(metric)
+	}
+	<-ro.BatchReady
+	require.False(t, ro.UnderPressure())
+	require.Equal(t, 2, ro.batchSize())
+
+	ro.AddMetric(first5[3])
+	<-ro.BatchReady
+	// above the high-water mark the batch is ready once per batch, not on
+	// every metric
+	ro.AddMetric(first5[4])
+	require.True(t, ro.UnderPressure())
+	require.Len(t, ro.BatchReady, 0)
+	ro.AddMetric(next5[0])
+	require.Len(t, ro.BatchReady, 1)
+	<-ro.BatchReady
+	require.Equal(t, 2, ro.batchSize())
+
+	// the batches grow with the buffer, up to the max batch size, and the
+	// batch is ready when the grown batch is full
+	ro.AddMetric(next5[1])
+	ro.AddMetric(next5[2])
+	require.Equal(t, 3, ro.batchSize())
+	require.Len(t, ro.BatchReady, 0)
+	ro.AddMetric(next5[3])
+	require.Len(t, ro.BatchReady, 1)
+	require.NoError(t, ro.WriteBatch())
+	require.Len(t, m.Metrics(), 3)
+
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 9)
+	require.False(t, ro.UnderPressure())
+
+	// without adaptive flushing the batch size is fixed
+	ro = NewRunningOutput("test", &mockOutput{}, &OutputConfig{Filter: Filter{}}, 2, 10)
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.False(t, ro.UnderPressure())
+	require.Equal(t, 2, ro.batchSize())
+}
+
 type mockOutput struct {
 	sync.Mutex
 
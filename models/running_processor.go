@@ -23,10 +23,11 @@ func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp
 
 // FilterConfig containing a name and filter
 type ProcessorConfig struct {
-	Name   string
-	Alias  string
-	Order  int64
-	Filter Filter
+	Name     string
+	Alias    string
+	LogLevel string
+	Order    int64
+	Filter   Filter
 }
 
 func NewRunningProcessor(processor cua.StreamingProcessor, config *ProcessorConfig) *RunningProcessor {
@@ -67,6 +68,11 @@ func (rp *RunningProcessor) Log() cua.Logger {
 	return rp.log
 }
 
+// Status returns the last error of the processor.
+func (rp *RunningProcessor) Status() PluginStatus {
+	return newPluginStatus(rp.Config.Name, rp.Config.Alias, rp.log, 0)
+}
+
 func (rp *RunningProcessor) LogName() string {
 	return logName("processors", rp.Config.Name, rp.Config.Alias)
 }
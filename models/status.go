@@ -0,0 +1,72 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// PluginStatus is the state of a running plugin, reported by the health
+// endpoint of the agent.
+type PluginStatus struct {
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+
+	// LastError is the last error logged by the plugin.
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+
+	// LastSuccess is the end of the last successful gather of an input or
+	// write of an output.
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+}
+
+// OutputStatus is the state of a running output.
+type OutputStatus struct {
+	PluginStatus
+	Connected   bool `json:"connected"`
+	BufferSize  int  `json:"buffer_size"`
+	BufferLimit int  `json:"buffer_limit"`
+}
+
+// lastError holds the last error logged by a plugin.
+type lastError struct {
+	mu      sync.Mutex
+	message string
+	time    time.Time
+}
+
+func (e *lastError) set(message string, t time.Time) {
+	e.mu.Lock()
+	e.message = message
+	e.time = t
+	e.mu.Unlock()
+}
+
+func (e *lastError) get() (string, time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.message, e.time
+}
+
+// LastError returns the last error logged and its time, the time is zero
+// when no error was logged.
+func (l *Logger) LastError() (string, time.Time) {
+	return l.lastErr.get()
+}
+
+// newPluginStatus returns the status of a plugin, with the last error of
+// its logger.
+func newPluginStatus(name, alias string, logger interface{}, lastSuccess int64) PluginStatus {
+	status := PluginStatus{Name: name, Alias: alias}
+	if l, ok := logger.(interface{ LastError() (string, time.Time) }); ok {
+		if message, t := l.LastError(); !t.IsZero() {
+			status.LastError = message
+			status.LastErrorTime = &t
+		}
+	}
+	if lastSuccess != 0 {
+		t := time.Unix(0, lastSuccess)
+		status.LastSuccess = &t
+	}
+	return status
+}
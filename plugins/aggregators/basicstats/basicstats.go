@@ -236,7 +236,7 @@ func (b *BasicStats) parseStats() *configuredStats {
 			parsed.mean = true
 		case "s2":
 			parsed.variance = true
-		case "stdev":
+		case "stdev", "stddev":
 			parsed.stdev = true
 		case "sum":
 			parsed.sum = true
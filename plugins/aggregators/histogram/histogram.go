@@ -1,6 +1,9 @@
 package histogram
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"sort"
 	"strconv"
 
@@ -32,9 +35,18 @@ type Histogram struct {
 
 // config is the config, which contains name, field of metric and histogram buckets.
 type config struct {
-	Metric  string   `toml:"measurement_name"`
-	Fields  []string `toml:"fields"`
-	Buckets buckets  `toml:"buckets"`
+	Metric      string              `toml:"measurement_name"`
+	Fields      []string            `toml:"fields"`
+	Buckets     buckets             `toml:"buckets"`
+	Exponential *exponentialBuckets `toml:"exponential_buckets"`
+}
+
+// exponentialBuckets generates count buckets, the first right border being
+// start and each next one the previous multiplied by factor.
+type exponentialBuckets struct {
+	Start  float64 `toml:"start"`
+	Factor float64 `toml:"factor"`
+	Count  int     `toml:"count"`
 }
 
 // bucketsByMetrics contains the buckets grouped by metric and field name
@@ -105,6 +117,13 @@ var sampleConfig = `
   #   measurement_name = "diskio"
   #   ## The concrete fields of metric
   #   fields = ["io_time", "read_time", "write_time"]
+
+  ## Example config with log scale buckets instead of listing them, the
+  ## right borders are 0.005, 0.01, 0.02, ... 10.24 (with +Inf implicitly added).
+  # [[aggregators.histogram.config]]
+  #   exponential_buckets = { start = 0.005, factor = 2.0, count = 12 }
+  #   measurement_name = "http_response"
+  #   fields = ["response_time"]
 `
 
 // SampleConfig returns sample of config
@@ -117,6 +136,41 @@ func (h *Histogram) Description() string {
 	return "Create aggregate histograms."
 }
 
+// Init validates the configs and generates the exponential buckets
+func (h *Histogram) Init() error {
+	for i := range h.Configs {
+		cfg := &h.Configs[i]
+		if cfg.Exponential == nil {
+			continue
+		}
+		if len(cfg.Buckets) > 0 {
+			return fmt.Errorf("measurement %q: buckets and exponential_buckets are mutually exclusive", cfg.Metric)
+		}
+		bkts, err := cfg.Exponential.generate()
+		if err != nil {
+			return fmt.Errorf("measurement %q: exponential_buckets: %w", cfg.Metric, err)
+		}
+		cfg.Buckets = bkts
+	}
+	return nil
+}
+
+func (e *exponentialBuckets) generate() (buckets, error) {
+	switch {
+	case e.Start <= 0:
+		return nil, errors.New("start must be positive")
+	case e.Factor <= 1:
+		return nil, errors.New("factor must be greater than 1")
+	case e.Count < 1:
+		return nil, errors.New("count must be at least 1")
+	}
+	bkts := make(buckets, e.Count)
+	for i := range bkts {
+		bkts[i] = e.Start * math.Pow(e.Factor, float64(i))
+	}
+	return bkts, nil
+}
+
 // Add adds new hit to the buckets
 func (h *Histogram) Add(in cua.Metric) {
 	bucketsByField := make(map[string][]float64)
@@ -300,6 +354,8 @@ func convert(in interface{}) (float64, bool) {
 		return v, true
 	case int64:
 		return float64(v), true
+	case uint64:
+		return float64(v), true
 	default:
 		return 0, false
 	}
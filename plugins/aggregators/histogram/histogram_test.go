@@ -238,6 +238,43 @@ func TestHistogramWithTwoPeriodsAndAllFields(t *testing.T) {
 	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(2), "b_bucket": int64(1), "c_bucket": int64(1)}, tags{bucketRightTag: bucketPosInf})
 }
 
+// TestHistogramExponentialBuckets tests the generated log scale buckets
+func TestHistogramExponentialBuckets(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Exponential: &exponentialBuckets{Start: 5, Factor: 2, Count: 3}})
+	histogram := NewHistogramAggregator()
+	histogram.Configs = cfg
+	assert.NoError(t, histogram.Init())
+	assert.Equal(t, buckets{5, 10, 20}, histogram.Configs[0].Buckets)
+
+	acc := &testutil.Accumulator{}
+
+	histogram.Add(firstMetric1)
+	histogram.Push(acc)
+
+	if len(acc.Metrics) != 4 {
+		assert.Fail(t, "Incorrect number of metrics")
+	}
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(0)}, tags{bucketRightTag: "5"})
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(0)}, tags{bucketRightTag: "10"})
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(1)}, tags{bucketRightTag: "20"})
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(1)}, tags{bucketRightTag: bucketPosInf})
+}
+
+// TestHistogramInvalidConfig tests the validation of the exponential buckets
+func TestHistogramInvalidConfig(t *testing.T) {
+	for _, cfg := range []config{
+		{Metric: "m", Exponential: &exponentialBuckets{Start: 0, Factor: 2, Count: 3}},
+		{Metric: "m", Exponential: &exponentialBuckets{Start: 1, Factor: 1, Count: 3}},
+		{Metric: "m", Exponential: &exponentialBuckets{Start: 1, Factor: 2, Count: 0}},
+		{Metric: "m", Buckets: []float64{1}, Exponential: &exponentialBuckets{Start: 1, Factor: 2, Count: 3}},
+	} {
+		histogram := NewHistogramAggregator()
+		histogram.Configs = []config{cfg}
+		assert.Error(t, histogram.Init())
+	}
+}
+
 // TestWrongBucketsOrder tests the calling panic with incorrect order of buckets
 func TestWrongBucketsOrder(t *testing.T) {
 	defer func() {
@@ -0,0 +1,303 @@
+package logmetrics
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/aggregators"
+)
+
+var sampleConfig = `
+  ## The period on which to flush the aggregator.
+  period = "30s"
+
+  ## If true, the log lines are dropped, only the extracted metrics are
+  ## sent to the outputs.
+  drop_original = true
+
+  ## Only the log lines are to be passed through the aggregator.
+  namepass = ["syslog", "tail"]
+
+  ## Field holding the log line, "message" for syslog, "value" for tail
+  ## with data_format = "value".
+  # field = "message"
+
+  ## If true, the counts are reset on flush instead of accumulating.
+  # reset = false
+
+  ## Rules extracting the metrics from the matching lines.
+  # [[aggregators.log_metrics.rule]]
+  #   ## Name of the extracted metric.
+  #   name = "nginx_requests"
+  #
+  #   ## Regular expression the lines are to match, its named groups can be
+  #   ## used as tags or value.  Alternatively a dissect pattern, made of
+  #   ## %{name} placeholders separated by literal text, %{} skips a part.
+  #   regex = '" (?P<status>\d{3}) \d+ (?P<request_time>[\d.]+)$'
+  #   # dissect = '%{} "%{method} %{} %{}" %{status} %{} %{request_time}'
+  #
+  #   ## Groups, or tags of the log line, set as tags of the metric.
+  #   tags = ["status"]
+  #
+  #   ## Type of the metric:
+  #   ##   counter:   count the matching lines.
+  #   ##   histogram: also sum the value group and count its values in
+  #   ##              buckets.
+  #   type = "histogram"
+  #   value = "request_time"
+  #
+  #   ## Right borders of the histogram buckets, +Inf implicitly added.
+  #   # buckets = [0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0]
+`
+
+// defaultBuckets suit request durations in seconds
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type LogMetrics struct {
+	Field       string  `toml:"field"`
+	ResetCounts bool    `toml:"reset"`
+	Rules       []*rule `toml:"rule"`
+	Log         cua.Logger
+
+	series map[string]*series
+}
+
+type rule struct {
+	Name    string    `toml:"name"`
+	Regex   string    `toml:"regex"`
+	Dissect string    `toml:"dissect"`
+	Tags    []string  `toml:"tags"`
+	Type    string    `toml:"type"`
+	Value   string    `toml:"value"`
+	Buckets []float64 `toml:"buckets"`
+
+	re     *regexp.Regexp
+	groups map[string]int
+}
+
+// series accumulates the matches of a rule with the same tags
+type series struct {
+	rule   *rule
+	tags   map[string]string
+	count  int64
+	sum    float64
+	counts []int64 // per bucket, the last one is +Inf
+}
+
+func (*LogMetrics) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*LogMetrics) Description() string {
+	return "Extract counters and histograms from the log lines matching rules."
+}
+
+func (l *LogMetrics) Init() error {
+	if l.Field == "" {
+		l.Field = "message"
+	}
+	if len(l.Rules) == 0 {
+		return errors.New("no rule")
+	}
+	for _, r := range l.Rules {
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *rule) compile() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+
+	var err error
+	switch {
+	case r.Regex != "" && r.Dissect != "":
+		return errors.New("regex and dissect are mutually exclusive")
+	case r.Regex != "":
+		r.re, err = regexp.Compile(r.Regex)
+	case r.Dissect != "":
+		r.re, err = dissectToRegexp(r.Dissect)
+	default:
+		return errors.New("regex or dissect is required")
+	}
+	if err != nil {
+		return err
+	}
+	r.groups = map[string]int{}
+	for i, name := range r.re.SubexpNames() {
+		if name != "" {
+			r.groups[name] = i
+		}
+	}
+
+	switch r.Type {
+	case "", "counter":
+		r.Type = "counter"
+	case "histogram":
+		if _, ok := r.groups[r.Value]; !ok {
+			return fmt.Errorf("value %q is not a group of the pattern", r.Value)
+		}
+		if len(r.Buckets) == 0 {
+			r.Buckets = defaultBuckets
+		}
+		sort.Float64s(r.Buckets)
+	default:
+		return fmt.Errorf("unknown type %q, expected counter or histogram", r.Type)
+	}
+	return nil
+}
+
+// dissectToRegexp converts a dissect pattern to an anchored regular
+// expression, each placeholder matching up to the next literal text.
+func dissectToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	rest := pattern
+	for {
+		start := strings.Index(rest, "%{")
+		if start < 0 {
+			b.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in %q", pattern)
+		}
+		b.WriteString(regexp.QuoteMeta(rest[:start]))
+		name := rest[start+2 : start+end]
+		rest = rest[start+end+1:]
+		// the last placeholder takes the rest of the line
+		expr := ".*?"
+		if rest == "" {
+			expr = ".*"
+		}
+		if name == "" || strings.HasPrefix(name, "?") {
+			b.WriteString(expr)
+			continue
+		}
+		b.WriteString("(?P<" + name + ">" + expr + ")")
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func (l *LogMetrics) Add(in cua.Metric) {
+	v, ok := in.GetField(l.Field)
+	if !ok {
+		return
+	}
+	line, ok := v.(string)
+	if !ok {
+		return
+	}
+	for _, r := range l.Rules {
+		match := r.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		l.observe(r, in, match)
+	}
+}
+
+func (l *LogMetrics) observe(r *rule, in cua.Metric, match []string) {
+	tags := make(map[string]string, len(r.Tags))
+	for _, name := range r.Tags {
+		if i, ok := r.groups[name]; ok {
+			if match[i] != "" {
+				tags[name] = match[i]
+			}
+			continue
+		}
+		if value, ok := in.GetTag(name); ok {
+			tags[name] = value
+		}
+	}
+
+	key := seriesKey(r.Name, tags)
+	s, ok := l.series[key]
+	if !ok {
+		s = &series{rule: r, tags: tags}
+		if r.Type == "histogram" {
+			s.counts = make([]int64, len(r.Buckets)+1)
+		}
+		l.series[key] = s
+	}
+	s.count++
+
+	if r.Type != "histogram" {
+		return
+	}
+	value, err := strconv.ParseFloat(match[r.groups[r.Value]], 64)
+	if err != nil {
+		l.Log.Debugf("Rule %q: ignoring value: %v", r.Name, err)
+		return
+	}
+	s.sum += value
+	s.counts[sort.SearchFloat64s(r.Buckets, value)]++
+}
+
+func seriesKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteString("\x00" + k + "\x00" + tags[k])
+	}
+	return b.String()
+}
+
+func (l *LogMetrics) Push(acc cua.Accumulator) {
+	for _, s := range l.series {
+		r := s.rule
+		if r.Type == "counter" {
+			acc.AddCounter(r.Name, map[string]interface{}{"count": s.count}, s.tags)
+			continue
+		}
+
+		// cumulative buckets as the histogram aggregator
+		var observed int64
+		for i, n := range s.counts {
+			observed += n
+			tags := make(map[string]string, len(s.tags)+1)
+			for k, v := range s.tags {
+				tags[k] = v
+			}
+			tags["le"] = "+Inf"
+			if i < len(r.Buckets) {
+				tags["le"] = strconv.FormatFloat(r.Buckets[i], 'f', -1, 64)
+			}
+			acc.AddHistogram(r.Name, map[string]interface{}{r.Value + "_bucket": observed}, tags)
+		}
+		acc.AddHistogram(r.Name, map[string]interface{}{
+			"count":            s.count,
+			r.Value + "_sum":   s.sum,
+			r.Value + "_count": observed,
+		}, s.tags)
+	}
+}
+
+func (l *LogMetrics) Reset() {
+	if l.ResetCounts {
+		l.series = make(map[string]*series)
+	}
+}
+
+func init() {
+	aggregators.Add("log_metrics", func() cua.Aggregator {
+		return &LogMetrics{
+			series: make(map[string]*series),
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package logmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogMetrics(rules ...*rule) *LogMetrics {
+	return &LogMetrics{
+		Rules:  rules,
+		Log:    testutil.Logger{},
+		series: make(map[string]*series),
+	}
+}
+
+func addLines(t *testing.T, l *LogMetrics, lines ...string) {
+	for _, line := range lines {
+		m, err := metric.New("syslog",
+			map[string]string{"appname": "nginx", "hostname": "web01"},
+			map[string]interface{}{"message": line},
+			time.Now(),
+		)
+		require.NoError(t, err)
+		l.Add(m)
+	}
+}
+
+var accessLines = []string{
+	`10.0.0.1 - - [18/Oct/2026:10:00:00 +0000] "GET /index.html HTTP/1.1" 200 512 0.004`,
+	`10.0.0.2 - - [18/Oct/2026:10:00:01 +0000] "GET /api HTTP/1.1" 200 128 0.120`,
+	`10.0.0.2 - - [18/Oct/2026:10:00:02 +0000] "POST /api HTTP/1.1" 500 64 2.000`,
+	`upstream timed out while reading response header`,
+}
+
+func TestCounter(t *testing.T) {
+	l := newLogMetrics(&rule{
+		Name:  "nginx_errors",
+		Regex: `upstream timed out`,
+		Tags:  []string{"hostname"},
+	})
+	require.NoError(t, l.Init())
+	addLines(t, l, accessLines...)
+	addLines(t, l, accessLines[3])
+
+	acc := testutil.Accumulator{}
+	l.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "nginx_errors",
+		map[string]interface{}{"count": int64(2)},
+		map[string]string{"hostname": "web01"})
+
+	// counts accumulate across periods unless reset
+	l.Reset()
+	addLines(t, l, accessLines[3])
+	acc.ClearMetrics()
+	l.Push(&acc)
+	acc.AssertContainsFields(t, "nginx_errors", map[string]interface{}{"count": int64(3)})
+
+	l.ResetCounts = true
+	l.Reset()
+	acc.ClearMetrics()
+	l.Push(&acc)
+	require.Empty(t, acc.Metrics)
+}
+
+func TestHistogramDissect(t *testing.T) {
+	l := newLogMetrics(&rule{
+		Name:    "nginx_requests",
+		Dissect: `%{} "%{method} %{} %{}" %{status} %{} %{request_time}`,
+		Tags:    []string{"method", "appname"},
+		Type:    "histogram",
+		Value:   "request_time",
+		Buckets: []float64{0.1, 0.01, 1},
+	})
+	require.NoError(t, l.Init())
+	addLines(t, l, accessLines...)
+
+	acc := testutil.Accumulator{}
+	l.Push(&acc)
+	get := map[string]string{"method": "GET", "appname": "nginx"}
+	acc.AssertContainsTaggedFields(t, "nginx_requests", map[string]interface{}{
+		"count":              int64(2),
+		"request_time_sum":   0.124,
+		"request_time_count": int64(2),
+	}, get)
+	for le, n := range map[string]int64{"0.01": 1, "0.1": 1, "1": 2, "+Inf": 2} {
+		acc.AssertContainsTaggedFields(t, "nginx_requests",
+			map[string]interface{}{"request_time_bucket": n},
+			map[string]string{"method": "GET", "appname": "nginx", "le": le})
+	}
+	acc.AssertContainsTaggedFields(t, "nginx_requests",
+		map[string]interface{}{"request_time_bucket": int64(0)},
+		map[string]string{"method": "POST", "appname": "nginx", "le": "1"})
+	acc.AssertContainsTaggedFields(t, "nginx_requests",
+		map[string]interface{}{"request_time_bucket": int64(1)},
+		map[string]string{"method": "POST", "appname": "nginx", "le": "+Inf"})
+}
+
+func TestDissectToRegexp(t *testing.T) {
+	re, err := dissectToRegexp(`%{client} - [%{?ts}] %{msg}`)
+	require.NoError(t, err)
+	require.Equal(t, `^(?P<client>.*?) - \[.*?\] (?P<msg>.*)$`, re.String())
+
+	_, err = dissectToRegexp(`%{client`)
+	require.Error(t, err)
+}
+
+func TestInvalidRules(t *testing.T) {
+	for _, r := range []*rule{
+		{Regex: "x"},
+		{Name: "a"},
+		{Name: "a", Regex: "x", Dissect: "%{x}"},
+		{Name: "a", Regex: "("},
+		{Name: "a", Regex: "(?P<v>x)", Type: "gauge"},
+		{Name: "a", Regex: "(?P<v>x)", Type: "histogram", Value: "w"},
+	} {
+		require.Error(t, newLogMetrics(r).Init())
+	}
+	require.Error(t, newLogMetrics().Init())
+}
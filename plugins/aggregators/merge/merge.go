@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
 	"github.com/circonus-labs/circonus-unified-agent/metric"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/aggregators"
 )
@@ -14,12 +15,18 @@ const (
   ## If true, the original metric will be dropped by the
   ## aggregator and will not get sent to the output plugins.
   drop_original = true
+
+  ## Truncate the timestamps to this duration before merging, to merge the
+  ## fields of a series gathered a few nanoseconds apart.
+  # round_timestamp_to = "1ns"
 `
 )
 
 type Merge struct {
+	RoundTimestamp internal.Duration `toml:"round_timestamp_to"`
+	Log            cua.Logger        `toml:"-"`
+
 	grouper *metric.SeriesGrouper
-	log     cua.Logger
 }
 
 func (a *Merge) Init() error {
@@ -37,10 +44,14 @@ func (a *Merge) SampleConfig() string {
 
 func (a *Merge) Add(m cua.Metric) {
 	tags := m.Tags()
+	tm := m.Time()
+	if a.RoundTimestamp.Duration > 0 {
+		tm = tm.Truncate(a.RoundTimestamp.Duration)
+	}
 	for _, field := range m.FieldList() {
-		err := a.grouper.Add(m.Name(), tags, m.Time(), field.Key, field.Value)
+		err := a.grouper.Add(m.Name(), tags, tm, field.Key, field.Value)
 		if err != nil {
-			a.log.Errorf("Error adding metric: %v", err)
+			a.Log.Errorf("Error adding metric: %v", err)
 		}
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -118,6 +119,57 @@ func TestNanosecondPrecision(t *testing.T) {
 	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
 }
 
+func TestRoundTimestamp(t *testing.T) {
+	plugin := &Merge{RoundTimestamp: internal.Duration{Duration: time.Millisecond}}
+
+	err := plugin.Init()
+	require.NoError(t, err)
+
+	plugin.Add(
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"cpu": "cpu0",
+			},
+			map[string]interface{}{
+				"time_idle": 42,
+			},
+			time.Unix(0, 1000100),
+		),
+	)
+	plugin.Add(
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"cpu": "cpu0",
+			},
+			map[string]interface{}{
+				"time_guest": 42,
+			},
+			time.Unix(0, 1000900),
+		),
+	)
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []cua.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"cpu": "cpu0",
+			},
+			map[string]interface{}{
+				"time_idle":  42,
+				"time_guest": 42,
+			},
+			time.Unix(0, 1000000),
+		),
+	}
+
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
+}
+
 func TestReset(t *testing.T) {
 	plugin := &Merge{}
 
@@ -0,0 +1,124 @@
+package quantile
+
+import (
+	"math"
+	"sort"
+)
+
+// estimator accumulates the values of a field to estimate their quantiles.
+type estimator interface {
+	add(v float64)
+	quantiles(qs []float64) []float64
+}
+
+// minIndexable is the smallest magnitude tracked by the sketch, the values
+// closer to zero are counted as zero.
+const minIndexable = 1e-9
+
+// ddsketch is a DDSketch (https://arxiv.org/abs/1908.10693) with unbounded
+// buckets: each quantile is estimated within the relative accuracy of its
+// exact value, the memory growing with the log of the range of the values.
+type ddsketch struct {
+	gamma    float64
+	logGamma float64
+	positive map[int]uint64
+	negative map[int]uint64
+	zero     uint64
+	count    uint64
+}
+
+func newDDSketch(relativeAccuracy float64) *ddsketch {
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &ddsketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		positive: map[int]uint64{},
+		negative: map[int]uint64{},
+	}
+}
+
+func (s *ddsketch) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / s.logGamma))
+}
+
+// value is the estimate of the values of a bucket, the relative error is
+// bounded as the bucket covers (gamma^(i-1), gamma^i].
+func (s *ddsketch) value(i int) float64 {
+	return 2 * math.Pow(s.gamma, float64(i)) / (s.gamma + 1)
+}
+
+func (s *ddsketch) add(v float64) {
+	switch {
+	case v > minIndexable:
+		s.positive[s.index(v)]++
+	case v < -minIndexable:
+		s.negative[s.index(-v)]++
+	default:
+		s.zero++
+	}
+	s.count++
+}
+
+// bucket is a bucket of the sketch in the order of the values.
+type bucket struct {
+	value float64
+	count uint64
+}
+
+func (s *ddsketch) buckets() []bucket {
+	buckets := make([]bucket, 0, len(s.negative)+len(s.positive)+1)
+	for i, n := range s.negative {
+		buckets = append(buckets, bucket{value: -s.value(i), count: n})
+	}
+	if s.zero > 0 {
+		buckets = append(buckets, bucket{value: 0, count: s.zero})
+	}
+	for i, n := range s.positive {
+		buckets = append(buckets, bucket{value: s.value(i), count: n})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].value < buckets[j].value })
+	return buckets
+}
+
+func (s *ddsketch) quantiles(qs []float64) []float64 {
+	buckets := s.buckets()
+	values := make([]float64, len(qs))
+	for k, q := range qs {
+		rank := q * float64(s.count-1)
+		var seen uint64
+		for _, b := range buckets {
+			seen += b.count
+			if float64(seen) > rank {
+				values[k] = b.value
+				break
+			}
+		}
+	}
+	return values
+}
+
+// exact keeps all the values, the quantiles are interpolated as the R7
+// method of Hyndman and Fan, the default of R and numpy.
+type exact struct {
+	values []float64
+}
+
+func (e *exact) add(v float64) {
+	e.values = append(e.values, v)
+}
+
+func (e *exact) quantiles(qs []float64) []float64 {
+	sort.Float64s(e.values)
+	n := len(e.values)
+	values := make([]float64, len(qs))
+	for k, q := range qs {
+		h := q * float64(n-1)
+		lo := int(math.Floor(h))
+		if lo >= n-1 {
+			values[k] = e.values[n-1]
+			continue
+		}
+		values[k] = e.values[lo] + (h-float64(lo))*(e.values[lo+1]-e.values[lo])
+	}
+	return values
+}
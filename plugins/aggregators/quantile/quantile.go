@@ -0,0 +1,166 @@
+package quantile
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/aggregators"
+)
+
+type Quantile struct {
+	Quantiles        []float64 `toml:"quantiles"`
+	Algorithm        string    `toml:"algorithm"`
+	RelativeAccuracy float64   `toml:"relative_accuracy"`
+
+	cache        map[uint64]aggregate
+	suffixes     []string
+	newEstimator func() estimator
+}
+
+type aggregate struct {
+	fields map[string]estimator
+	name   string
+	tags   map[string]string
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to output in the range [0,1], the fields are suffixed with
+  ## the percentile, like "_p99".
+  # quantiles = [0.5, 0.95, 0.99]
+
+  ## Algorithm of the estimation:
+  ##   ddsketch: estimate each quantile within the relative accuracy of its
+  ##             value, with a memory bounded by the range of the values.
+  ##   exact:    keep all the values of the period, suited to low rates.
+  # algorithm = "ddsketch"
+
+  ## Relative accuracy of ddsketch, 0.01 for quantiles within 1%.
+  # relative_accuracy = 0.01
+`
+
+func NewQuantile() *Quantile {
+	q := &Quantile{
+		Quantiles:        []float64{0.5, 0.95, 0.99},
+		Algorithm:        "ddsketch",
+		RelativeAccuracy: 0.01,
+	}
+	q.Reset()
+	return q
+}
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+func (q *Quantile) Init() error {
+	switch q.Algorithm {
+	case "ddsketch":
+		if q.RelativeAccuracy <= 0 || q.RelativeAccuracy >= 1 {
+			return fmt.Errorf("relative_accuracy %v out of the range (0,1)", q.RelativeAccuracy)
+		}
+		accuracy := q.RelativeAccuracy
+		q.newEstimator = func() estimator { return newDDSketch(accuracy) }
+	case "exact":
+		q.newEstimator = func() estimator { return &exact{} }
+	default:
+		return fmt.Errorf("unknown algorithm %q, expected ddsketch or exact", q.Algorithm)
+	}
+
+	if len(q.Quantiles) == 0 {
+		return errors.New("no quantiles")
+	}
+	q.suffixes = make([]string, len(q.Quantiles))
+	seen := map[string]bool{}
+	for i, quantile := range q.Quantiles {
+		if quantile < 0 || quantile > 1 {
+			return fmt.Errorf("quantile %v out of the range [0,1]", quantile)
+		}
+		// the percentile, rounded to not show the float errors like 95.00000000000001
+		suffix := "_p" + strconv.FormatFloat(math.Round(quantile*1e6)/1e4, 'f', -1, 64)
+		if seen[suffix] {
+			return fmt.Errorf("duplicate quantile %v", quantile)
+		}
+		seen[suffix] = true
+		q.suffixes[i] = suffix
+	}
+	return nil
+}
+
+func (q *Quantile) Add(in cua.Metric) {
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]estimator),
+		}
+		q.cache[id] = a
+	}
+	for _, field := range in.FieldList() {
+		fv, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+		e, ok := a.fields[field.Key]
+		if !ok {
+			e = q.newEstimator()
+			a.fields[field.Key] = e
+		}
+		e.add(fv)
+	}
+}
+
+func (q *Quantile) Push(acc cua.Accumulator) {
+	for _, aggregate := range q.cache {
+		fields := map[string]interface{}{}
+		for k, e := range aggregate.fields {
+			for i, v := range e.quantiles(q.Quantiles) {
+				fields[k+q.suffixes[i]] = v
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(aggregate.name, fields, aggregate.tags)
+		}
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, false
+		}
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() cua.Aggregator {
+		return NewQuantile()
+	})
+}
@@ -0,0 +1,98 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuantileExact(t *testing.T) {
+	q := NewQuantile()
+	q.Algorithm = "exact"
+	q.Quantiles = []float64{0, 0.25, 0.5, 0.999, 1}
+	require.NoError(t, q.Init())
+
+	for i := 1; i <= 5; i++ {
+		m, err := metric.New("m1",
+			map[string]string{"foo": "bar"},
+			map[string]interface{}{"a": int64(i), "b": float64(i * 10), "c": "ignored"},
+			time.Now(),
+		)
+		require.NoError(t, err)
+		q.Add(m)
+	}
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "m1", map[string]interface{}{
+		"a_p0":    float64(1),
+		"a_p25":   float64(2),
+		"a_p50":   float64(3),
+		"a_p99.9": 4.996,
+		"a_p100":  float64(5),
+		"b_p0":    float64(10),
+		"b_p25":   float64(20),
+		"b_p50":   float64(30),
+		"b_p99.9": 49.96,
+		"b_p100":  float64(50),
+	}, map[string]string{"foo": "bar"})
+
+	q.Reset()
+	acc.ClearMetrics()
+	q.Push(&acc)
+	require.Empty(t, acc.Metrics)
+}
+
+func TestQuantileDDSketchAccuracy(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	s := newDDSketch(0.01)
+	values := make([]float64, 10000)
+	for i := range values {
+		// latencies spanning several orders of magnitude, some negative
+		values[i] = math.Exp(r.NormFloat64()*2) - 0.5
+		s.add(values[i])
+	}
+	s.add(0)
+	values = append(values, 0)
+	sort.Float64s(values)
+
+	qs := []float64{0, 0.5, 0.9, 0.95, 0.99, 1}
+	for i, v := range s.quantiles(qs) {
+		expected := values[int(qs[i]*float64(len(values)-1))]
+		require.InDelta(t, expected, v, math.Abs(expected)*0.01+1e-12, "quantile %v", qs[i])
+	}
+}
+
+func TestQuantileDefaults(t *testing.T) {
+	q := NewQuantile()
+	require.NoError(t, q.Init())
+	require.Equal(t, []string{"_p50", "_p95", "_p99"}, q.suffixes)
+
+	m, err := metric.New("m1", nil, map[string]interface{}{"a": uint64(7)}, time.Now())
+	require.NoError(t, err)
+	q.Add(m)
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+	for _, v := range acc.Metrics[0].Fields {
+		require.InDelta(t, 7.0, v, 0.07)
+	}
+}
+
+func TestQuantileInvalidConfig(t *testing.T) {
+	for _, q := range []*Quantile{
+		{Algorithm: "tdigest", Quantiles: []float64{0.5}},
+		{Algorithm: "ddsketch", RelativeAccuracy: 1, Quantiles: []float64{0.5}},
+		{Algorithm: "exact", Quantiles: []float64{1.5}},
+		{Algorithm: "exact", Quantiles: []float64{0.5, 0.5}},
+		{Algorithm: "exact"},
+	} {
+		require.Error(t, q.Init())
+	}
+}
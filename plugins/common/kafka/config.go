@@ -2,9 +2,9 @@ package kafka
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/Shopify/sarama"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
 )
 
@@ -14,10 +14,10 @@ type ReadConfig struct {
 }
 
 // SetConfig on the sarama.Config object from the ReadConfig struct.
-func (k *ReadConfig) SetConfig(config *sarama.Config) error {
+func (k *ReadConfig) SetConfig(config *sarama.Config, log cua.Logger) error {
 	config.Consumer.Return.Errors = true
 
-	return k.Config.SetConfig(config)
+	return k.Config.SetConfig(config, log)
 }
 
 // WriteConfig for kafka clients meaning to write to kafka
@@ -31,7 +31,7 @@ type WriteConfig struct {
 }
 
 // SetConfig on the sarama.Config object from the WriteConfig struct.
-func (k *WriteConfig) SetConfig(config *sarama.Config) error {
+func (k *WriteConfig) SetConfig(config *sarama.Config, log cua.Logger) error {
 	config.Producer.Return.Successes = true
 	config.Producer.Idempotent = k.IdempotentWrites
 	config.Producer.Retry.Max = k.MaxRetry
@@ -39,7 +39,7 @@ func (k *WriteConfig) SetConfig(config *sarama.Config) error {
 		config.Producer.MaxMessageBytes = k.MaxMessageBytes
 	}
 	config.Producer.RequiredAcks = sarama.RequiredAcks(k.RequiredAcks)
-	return k.Config.SetConfig(config)
+	return k.Config.SetConfig(config, log)
 }
 
 // Config common to all Kafka clients.
@@ -56,9 +56,9 @@ type Config struct {
 }
 
 // SetConfig on the sarama.Config object from the Config struct.
-func (k *Config) SetConfig(config *sarama.Config) error {
+func (k *Config) SetConfig(config *sarama.Config, log cua.Logger) error {
 	if k.EnableTLS != nil {
-		log.Printf("W! [kafka] enable_tls is deprecated, and the setting does nothing, you can safely remove it from the config")
+		log.Warnf("Option enable_tls is deprecated, and the setting does nothing, you can safely remove it from the config")
 	}
 	if k.Version != "" {
 		version, err := sarama.ParseKafkaVersion(k.Version)
@@ -103,6 +103,7 @@ func hasQuit(ctx context.Context) bool {
 
 func (s *Shim) writeProcessedMetrics() error {
 	serializer := influx.NewSerializer()
+	serializer.Log = s.log
 	for m := range s.metricCh {
 		b, err := serializer.Serialize(m)
 		if err != nil {
@@ -0,0 +1,161 @@
+// Package zabbix implements the framing and messages of the Zabbix sender
+// protocol shared by the zabbix input and output plugins.
+package zabbix
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+const (
+	flagProtocol    = 0x01
+	flagCompression = 0x02
+	flagLargePacket = 0x04
+
+	// DefaultMaxPacketSize matches the 1GiB limit of the Zabbix server.
+	DefaultMaxPacketSize = 1 << 30
+)
+
+var header = []byte("ZBXD")
+
+// ErrPacketTooLarge is returned when a packet exceeds the size limit.
+var ErrPacketTooLarge = errors.New("packet too large")
+
+// Request is a "sender data" or "agent data" request.
+type Request struct {
+	Request string  `json:"request"`
+	Data    []Item  `json:"data"`
+	Clock   *int64  `json:"clock,omitempty"`
+	NS      *int64  `json:"ns,omitempty"`
+	Session *string `json:"session,omitempty"`
+}
+
+// Item is a single value for a trapper item.
+type Item struct {
+	Host  string      `json:"host"`
+	Key   string      `json:"key"`
+	Value ItemValue   `json:"value"`
+	Clock *int64      `json:"clock,omitempty"`
+	NS    *int64      `json:"ns,omitempty"`
+	ID    json.Number `json:"id,omitempty"`
+}
+
+// ItemValue holds the value of an item. Zabbix senders always send strings
+// but numbers are accepted as well.
+type ItemValue string
+
+func (v *ItemValue) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return fmt.Errorf("item value: %w", err)
+		}
+		*v = ItemValue(s)
+		return nil
+	}
+	*v = ItemValue(b)
+	return nil
+}
+
+// Response is the reply of the server to a request.
+type Response struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+}
+
+var infoRe = regexp.MustCompile(`processed: (\d+); failed: (\d+); total: (\d+)`)
+
+// Counts returns the processed and failed counts reported in the info
+// string.
+func (r *Response) Counts() (processed, failed int, err error) {
+	m := infoRe.FindStringSubmatch(r.Info)
+	if m == nil {
+		return 0, 0, fmt.Errorf("unexpected response info %q", r.Info)
+	}
+	processed, _ = strconv.Atoi(m[1])
+	failed, _ = strconv.Atoi(m[2])
+	return processed, failed, nil
+}
+
+// WritePacket writes data framed with the Zabbix protocol header.
+func WritePacket(w io.Writer, data []byte) error {
+	buf := make([]byte, len(header)+9, len(header)+9+len(data))
+	copy(buf, header)
+	buf[4] = flagProtocol
+	binary.LittleEndian.PutUint32(buf[5:], uint32(len(data)))
+	buf = append(buf, data...)
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+// ReadPacket reads a single packet and returns its payload, decompressed if
+// needed.
+func ReadPacket(r io.Reader, maxSize uint64) ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if !bytes.Equal(hdr[:4], header) {
+		return nil, fmt.Errorf("invalid header %q", hdr[:4])
+	}
+	flags := hdr[4]
+	if flags&flagProtocol == 0 {
+		return nil, fmt.Errorf("unsupported protocol flags 0x%02x", flags)
+	}
+
+	var dataLen, reserved uint64
+	if flags&flagLargePacket != 0 {
+		var b [16]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, fmt.Errorf("read length: %w", err)
+		}
+		dataLen = binary.LittleEndian.Uint64(b[:8])
+		reserved = binary.LittleEndian.Uint64(b[8:])
+	} else {
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, fmt.Errorf("read length: %w", err)
+		}
+		dataLen = uint64(binary.LittleEndian.Uint32(b[:4]))
+		reserved = uint64(binary.LittleEndian.Uint32(b[4:]))
+	}
+
+	if dataLen > maxSize {
+		return nil, ErrPacketTooLarge
+	}
+	data := make([]byte, dataLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("read data: %w", err)
+	}
+
+	if flags&flagCompression == 0 {
+		return data, nil
+	}
+
+	// For compressed packets the reserved field is the uncompressed size.
+	if reserved > maxSize {
+		return nil, ErrPacketTooLarge
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, int64(reserved)+1))
+	if err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	if uint64(len(out)) != reserved {
+		return nil, fmt.Errorf("decompressed size %d does not match %d", len(out), reserved)
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package zabbix
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WritePacket(&buf, []byte(`{"request":"sender data"}`)))
+	require.Equal(t, []byte("ZBXD\x01"), buf.Bytes()[:5])
+
+	data, err := ReadPacket(&buf, DefaultMaxPacketSize)
+	require.NoError(t, err)
+	require.Equal(t, `{"request":"sender data"}`, string(data))
+}
+
+func TestReadPacketCompressed(t *testing.T) {
+	payload := []byte(`{"request":"agent data","data":[]}`)
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	_, err := zw.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	var buf bytes.Buffer
+	buf.WriteString("ZBXD")
+	buf.WriteByte(flagProtocol | flagCompression)
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint32(z.Len())))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint32(len(payload))))
+	buf.Write(z.Bytes())
+
+	data, err := ReadPacket(&buf, DefaultMaxPacketSize)
+	require.NoError(t, err)
+	require.Equal(t, payload, data)
+}
+
+func TestReadPacketErrors(t *testing.T) {
+	_, err := ReadPacket(bytes.NewBufferString("HTTP/1.1 200 OK\r\n"), DefaultMaxPacketSize)
+	require.Error(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePacket(&buf, make([]byte, 100)))
+	_, err = ReadPacket(&buf, 10)
+	require.True(t, errors.Is(err, ErrPacketTooLarge))
+}
+
+func TestItemValue(t *testing.T) {
+	var items []Item
+	require.NoError(t, json.Unmarshal([]byte(`[{"host":"h","key":"k","value":"1.5"},{"host":"h","key":"k","value":2}]`), &items))
+	require.Equal(t, ItemValue("1.5"), items[0].Value)
+	require.Equal(t, ItemValue("2"), items[1].Value)
+}
+
+func TestResponseCounts(t *testing.T) {
+	r := Response{Response: "success", Info: "processed: 3; failed: 1; total: 4; seconds spent: 0.000055"}
+	processed, failed, err := r.Counts()
+	require.NoError(t, err)
+	require.Equal(t, 3, processed)
+	require.Equal(t, 1, failed)
+}
@@ -1,3 +1,5 @@
+// +build !custom
+
 package all
 
 //nolint:golint
@@ -9,12 +11,15 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/apcupsd"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/aurora"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/azure_storage_queue"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/bacnet"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/bcache"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/beanstalkd"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/bind"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/bond"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/burrow"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/canbus"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/ceph"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/certwatch"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/cgroup"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/chrony"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/cisco_telemetry_mdt"
@@ -22,16 +27,19 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/cloud_pubsub"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/cloud_pubsub_push"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/cloudwatch"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/collectd"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/conntrack"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/consul"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/couchbase"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/couchdb"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/cpu"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/dcos"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/directory_monitor"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/disk"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/diskio"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/disque"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/dmcache"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/dnp3"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/dns_query"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/docker"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/docker_log"
@@ -48,9 +56,11 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/filecount"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/filestat"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/fireboard"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/fluent_forward"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/fluentd"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/github"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/gnmi"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/gpsd"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/graylog"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/haproxy"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/hddtemp"
@@ -58,6 +68,7 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/http_listener_v2"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/http_response"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/icinga2"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/iec104"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/infiniband"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/influxdb"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/influxdb_listener"
@@ -86,6 +97,7 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/linux_sysctl_fs"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/logstash"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/lustre2"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/macos"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/mailchimp"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/marklogic"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/mcrouter"
@@ -94,6 +106,7 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/mesos"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/minecraft"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/modbus"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/modem"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/mongodb"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/monit"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/mqtt_consumer"
@@ -104,6 +117,7 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/neptune_apex"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/net"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/net_response"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/netflow"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nginx"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nginx_plus"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nginx_plus_api"
@@ -114,13 +128,19 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nsq"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nsq_consumer"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nstat"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nsx_t"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/ntp_peer"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/ntpq"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nvidia_dcgm"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/nvidia_smi"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/opcua"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/openldap"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/openntpd"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/opensmtpd"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/openstack"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/opentsdb_listener"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/openweathermap"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/ovirt"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/passenger"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/pf"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/pgbouncer"
@@ -131,15 +151,18 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/postgresql_extensible"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/powerdns"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/powerdns_recursor"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/process_network"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/processes"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/procstat"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/prometheus"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/proxmox"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/puppetagent"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/pushgateway"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/rabbitmq"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/raindrops"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/redfish"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/redis"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/resctrl"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/rethinkdb"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/riak"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/salesforce"
@@ -159,6 +182,7 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/syslog"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/sysstat"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/system"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/systemd_timers"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/systemd_units"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/tail"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/teamspeak"
@@ -168,16 +192,20 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/trig"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/twemproxy"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/unbound"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/upsd"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/uwsgi"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/varnish"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/vmware_vsan"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/vsphere"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/webhooks"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/win_eventlog"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/win_perf_counters"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/win_registry"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/win_services"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/wireguard"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/wireless"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/x509_cert"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/zabbix_listener"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/zfs"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/zipkin"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/inputs/zookeeper"
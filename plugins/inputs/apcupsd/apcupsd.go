@@ -88,6 +88,12 @@ func (h *ApcUpsd) Gather(acc cua.Accumulator) error {
 			"nominal_power":           status.NominalPower,
 			"firmware":                status.Firmware,
 			"battery_date":            status.BatteryDate,
+			"number_transfers":        status.NumberTransfers,
+
+			"cumulative_time_on_battery_ns": status.CumulativeTimeOnBattery.Nanoseconds(),
+		}
+		if status.LastTransfer != "" {
+			fields["last_transfer"] = status.LastTransfer
 		}
 
 		acc.AddFields("apcupsd", fields, tags)
@@ -143,6 +143,10 @@ func TestApcupsdGather(t *testing.T) {
 					"nominal_power":           int(865),
 					"firmware":                string("857.L3 .I USB FW:L3"),
 					"battery_date":            string("2016-09-06"),
+					"number_transfers":        int(2),
+					"last_transfer":           string("Low line voltage"),
+
+					"cumulative_time_on_battery_ns": int64(125000000000),
 				},
 				out: genOutput,
 			},
@@ -203,7 +207,9 @@ func genOutput() [][]byte {
 		"BATTDATE : 2016-09-06",
 		"TIMELEFT :  46.5 Minutes",
 		"TONBATT  : 0 seconds",
-		"NUMXFERS : 0",
+		"NUMXFERS : 2",
+		"LASTXFER : Low line voltage",
+		"CUMONBATT: 125 seconds",
 		"SELFTEST : NO",
 		"NOMINV   : 230 Volts",
 		"NOMBATTV : 12.0 Volts",
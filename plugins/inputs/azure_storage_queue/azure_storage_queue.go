@@ -15,7 +15,7 @@ import (
 
 type AzureStorageQueue struct {
 	StorageAccountName   string `toml:"account_name"`
-	StorageAccountKey    string `toml:"account_key"`
+	StorageAccountKey    string `toml:"account_key" secret:"true"`
 	PeekOldestMessageAge bool   `toml:"peek_oldest_message_age"`
 	Log                  cua.Logger
 
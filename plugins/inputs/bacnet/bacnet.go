@@ -0,0 +1,362 @@
+package bacnet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+var sampleConfig = `
+  ## Local address the requests are sent from, the devices usually
+  ## broadcast their I-Am to the port 47808.
+  # local_address = ":47808"
+
+  ## Address the Who-Is requests discovering the devices are broadcast to.
+  # broadcast_address = "255.255.255.255:47808"
+
+  ## Timeout of the discovery and of each request, and number of retries of
+  ## the requests timing out.
+  # timeout = "3s"
+  # retries = 2
+
+  [[inputs.bacnet.devices]]
+    ## Device instance number.
+    instance = 1234
+    ## Optional device name, added as the device_name tag.
+    # name = "ahu1"
+    ## Address of the device, discovered with Who-Is when empty.  The devices
+    ## behind a router are addressed with the network number and the MAC
+    ## address in hexadecimal, as in "192.168.1.10:47808/5:0a".
+    # address = "192.168.1.20:47808"
+
+    [[inputs.bacnet.devices.objects]]
+      ## Object type, one of analog_input, analog_output, analog_value,
+      ## binary_input, binary_output, binary_value, multi_state_input,
+      ## multi_state_output, multi_state_value, accumulator or device.
+      type = "analog_input"
+      instance = 1
+      ## Optional object name, added as the object tag.
+      # name = "zone_temp"
+      ## Properties read, among present_value, status_flags, out_of_service,
+      ## reliability, event_state, units, object_name and description.
+      # properties = ["present_value", "status_flags"]
+`
+
+type object struct {
+	Type       string   `toml:"type"`
+	Instance   int      `toml:"instance"`
+	Name       string   `toml:"name"`
+	Properties []string `toml:"properties"`
+
+	id uint32
+}
+
+type device struct {
+	Instance int      `toml:"instance"`
+	Name     string   `toml:"name"`
+	Address  string   `toml:"address"`
+	Objects  []object `toml:"objects"`
+
+	configured *address
+}
+
+type BACnet struct {
+	LocalAddress     string            `toml:"local_address"`
+	BroadcastAddress string            `toml:"broadcast_address"`
+	Timeout          internal.Duration `toml:"timeout"`
+	Retries          int               `toml:"retries"`
+	Devices          []device          `toml:"devices"`
+
+	Log cua.Logger `toml:"-"`
+
+	broadcast *net.UDPAddr
+
+	sync.Mutex
+	// addresses of the devices discovered, kept until a request times out
+	discovered map[uint32]*address
+	invokeID   byte
+}
+
+func (b *BACnet) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BACnet) Description() string {
+	return "Read the properties of the objects of BACnet/IP devices"
+}
+
+func (b *BACnet) Init() error {
+	if len(b.Devices) == 0 {
+		return errors.New("no devices configured")
+	}
+	var err error
+	b.broadcast, err = net.ResolveUDPAddr("udp", b.BroadcastAddress)
+	if err != nil {
+		return fmt.Errorf("broadcast_address: %w", err)
+	}
+	if b.Retries < 0 {
+		return fmt.Errorf("invalid retries %d", b.Retries)
+	}
+
+	for i := range b.Devices {
+		d := &b.Devices[i]
+		if d.Instance < 0 || d.Instance > 0x3FFFFE {
+			return fmt.Errorf("invalid device instance %d", d.Instance)
+		}
+		if d.Address != "" {
+			if d.configured, err = parseAddress(d.Address); err != nil {
+				return fmt.Errorf("device %d: %w", d.Instance, err)
+			}
+		}
+		for j := range d.Objects {
+			o := &d.Objects[j]
+			objectType, ok := objectTypes[o.Type]
+			if !ok {
+				return fmt.Errorf("device %d: invalid object type %q", d.Instance, o.Type)
+			}
+			if o.Instance < 0 || o.Instance > 0x3FFFFE {
+				return fmt.Errorf("device %d: invalid instance %d of object %s", d.Instance, o.Instance, o.Type)
+			}
+			o.id = objectID(objectType, uint32(o.Instance))
+			if len(o.Properties) == 0 {
+				o.Properties = []string{"present_value", "status_flags"}
+			}
+			for _, p := range o.Properties {
+				if _, ok := properties[p]; !ok {
+					return fmt.Errorf("device %d: invalid property %q", d.Instance, p)
+				}
+			}
+		}
+	}
+	b.discovered = make(map[uint32]*address)
+	return nil
+}
+
+// parseAddress parses the address of a device, <ip>:<port> or
+// <ip>:<port>/<network>:<mac> for the devices behind a router.
+func parseAddress(s string) (*address, error) {
+	host, routed := s, ""
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == '/' {
+			host, routed = s[:i], s[i+1:]
+			break
+		}
+	}
+	udp, err := net.ResolveUDPAddr("udp", host)
+	if err != nil {
+		return nil, fmt.Errorf("address %q: %w", s, err)
+	}
+	a := &address{udp: udp}
+	if routed == "" {
+		return a, nil
+	}
+	network, mac, err := net.SplitHostPort(routed)
+	if err != nil {
+		return nil, fmt.Errorf("address %q: %w", s, err)
+	}
+	n, err := strconv.ParseUint(network, 10, 16)
+	if err != nil || n == 0 || n == 0xFFFF {
+		return nil, fmt.Errorf("address %q: invalid network %q", s, network)
+	}
+	a.network = uint16(n)
+	if _, err := fmt.Sscanf(mac, "%x", &a.mac); err != nil || len(a.mac) == 0 {
+		return nil, fmt.Errorf("address %q: invalid MAC address %q", s, mac)
+	}
+	return a, nil
+}
+
+func (b *BACnet) Gather(acc cua.Accumulator) error {
+	b.Lock()
+	defer b.Unlock()
+
+	local, err := net.ResolveUDPAddr("udp", b.LocalAddress)
+	if err != nil {
+		return fmt.Errorf("local_address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", local)
+	if err != nil {
+		return fmt.Errorf("listen (%s): %w", b.LocalAddress, err)
+	}
+	defer conn.Close()
+
+	if err := b.discover(conn); err != nil {
+		return err
+	}
+
+	for _, d := range b.Devices {
+		addr := d.configured
+		if addr == nil {
+			addr = b.discovered[uint32(d.Instance)]
+		}
+		if addr == nil {
+			acc.AddError(fmt.Errorf("device %d not found", d.Instance))
+			continue
+		}
+		if err := b.gatherDevice(acc, conn, &d, addr); err != nil {
+			acc.AddError(fmt.Errorf("device %d (%s): %w", d.Instance, addr, err))
+			delete(b.discovered, uint32(d.Instance))
+		}
+	}
+	return nil
+}
+
+// discover broadcasts a Who-Is for the devices without address not yet
+// discovered, and waits for their I-Am until the timeout.
+func (b *BACnet) discover(conn *net.UDPConn) error {
+	missing := make(map[uint32]bool)
+	var low, high uint32 = 0x3FFFFF, 0
+	for _, d := range b.Devices {
+		instance := uint32(d.Instance)
+		if d.configured != nil || b.discovered[instance] != nil {
+			continue
+		}
+		missing[instance] = true
+		if instance < low {
+			low = instance
+		}
+		if instance > high {
+			high = instance
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if _, err := conn.WriteToUDP(whoIs(low, high), b.broadcast); err != nil {
+		return fmt.Errorf("who-is: %w", err)
+	}
+	deadline := time.Now().Add(b.Timeout.Duration)
+	buf := make([]byte, 1500)
+	for len(missing) > 0 {
+		msg, err := b.receive(conn, buf, deadline)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil
+			}
+			return err
+		}
+		if msg == nil {
+			continue
+		}
+		if device, ok := parseIAm(msg.apdu); ok && missing[device.instance] {
+			b.Log.Debugf("Discovered device %d at %s", device.instance, msg.source)
+			b.discovered[device.instance] = msg.source
+			delete(missing, device.instance)
+		}
+	}
+	return nil
+}
+
+// receive returns the next BACnet message received before the deadline, nil
+// for the messages ignored.
+func (b *BACnet) receive(conn *net.UDPConn, buf []byte, deadline time.Time) (*message, error) {
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+	n, from, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	msg, err := parseMessage(buf[:n], from)
+	if err != nil {
+		b.Log.Debugf("Invalid message from %s: %v", from, err)
+		return nil, nil
+	}
+	return msg, nil
+}
+
+func (b *BACnet) gatherDevice(acc cua.Accumulator, conn *net.UDPConn, d *device, addr *address) error {
+	for _, o := range d.Objects {
+		fields := make(map[string]interface{}, len(o.Properties))
+		for _, p := range o.Properties {
+			v, err := b.readProperty(conn, addr, o.id, properties[p])
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					return err
+				}
+				acc.AddError(fmt.Errorf("device %d: %s %d: %s: %w", d.Instance, o.Type, o.Instance, p, err))
+				continue
+			}
+			switch {
+			case p == "status_flags":
+				for i, name := range statusFlags {
+					fields[name] = i < len(v.bits) && v.bits[i]
+				}
+			case v.value != nil:
+				fields[p] = v.value
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		tags := map[string]string{
+			"device":          strconv.Itoa(d.Instance),
+			"object_type":     o.Type,
+			"object_instance": strconv.Itoa(o.Instance),
+		}
+		if d.Name != "" {
+			tags["device_name"] = d.Name
+		}
+		if o.Name != "" {
+			tags["object"] = o.Name
+		}
+		acc.AddFields("bacnet", fields, tags)
+	}
+	return nil
+}
+
+// readProperty reads a property of an object, the request is sent again
+// when it times out.
+func (b *BACnet) readProperty(conn *net.UDPConn, addr *address, object uint32, property uint32) (value, error) {
+	buf := make([]byte, 1500)
+	var err error
+	for attempt := 0; attempt <= b.Retries; attempt++ {
+		invokeID := b.invokeID
+		b.invokeID++
+		if _, err = conn.WriteToUDP(readProperty(addr, invokeID, object, property), addr.udp); err != nil {
+			return value{}, fmt.Errorf("write: %w", err)
+		}
+
+		deadline := time.Now().Add(b.Timeout.Duration)
+		for {
+			var msg *message
+			msg, err = b.receive(conn, buf, deadline)
+			if err != nil {
+				break
+			}
+			if msg == nil || msg.source.String() != addr.String() {
+				continue
+			}
+			v, ok, err := parseReadPropertyAck(msg.apdu, invokeID)
+			if ok {
+				return v, err
+			}
+		}
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			return value{}, err
+		}
+	}
+	return value{}, err
+}
+
+func init() {
+	inputs.Add("bacnet", func() cua.Input {
+		return &BACnet{
+			LocalAddress:     ":47808",
+			BroadcastAddress: "255.255.255.255:47808",
+			Timeout:          internal.Duration{Duration: 3 * time.Second},
+			Retries:          2,
+		}
+	})
+}
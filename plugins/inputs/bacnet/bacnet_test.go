@@ -0,0 +1,165 @@
+package bacnet
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func frame(apdu ...byte) []byte {
+	return finishBVLC(append([]byte{bvlcType, bvlcUnicastNPDU, 0, 0, npduVersion, 0}, apdu...))
+}
+
+func realValue(f float32) []byte {
+	b := []byte{0x44, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], math.Float32bits(f))
+	return b
+}
+
+// serve runs the device 1234 answering Who-Is with I-Am, and the reads of
+// the present value and status flags of the analog input 1 and of the
+// binary input 2, the other properties are unknown.
+func serve(t *testing.T) string {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	values := map[[2]uint32][]byte{
+		{objectID(0, 1), 85}:  realValue(21.5),
+		{objectID(0, 1), 111}: {0x82, 0x04, 0x40},
+		{objectID(3, 2), 85}:  {0x91, 0x01},
+		{objectID(3, 2), 111}: {0x82, 0x04, 0x00},
+	}
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			msg, err := parseMessage(buf[:n], from)
+			if err != nil || msg == nil || len(msg.apdu) < 2 {
+				continue
+			}
+			apdu := msg.apdu
+			var response []byte
+			switch {
+			case apdu[0] == pduUnconfirmedRequest && apdu[1] == serviceWhoIs:
+				response = frame(pduUnconfirmedRequest, serviceIAm, 0xC4, 0x02, 0x00, 0x04, 0xD2,
+					0x22, 0x05, 0xC4, 0x91, 0x03, 0x21, 0x00)
+			case apdu[0] == pduConfirmedRequest && len(apdu) >= 11 && apdu[3] == serviceReadProperty:
+				object := binary.BigEndian.Uint32(apdu[5:])
+				property := uint32(decodeUnsigned(apdu[10 : 10+int(apdu[9]&0x07)]))
+				invokeID := apdu[2]
+				v, ok := values[[2]uint32{object, property}]
+				if !ok {
+					response = frame(pduError, invokeID, serviceReadProperty, 0x91, 0x02, 0x91, 32)
+					break
+				}
+				response = append([]byte{pduComplexAck, invokeID, serviceReadProperty}, apdu[4:]...)
+				response = append(response, 0x3E)
+				response = append(response, v...)
+				response = frame(append(response, 0x3F)...)
+			default:
+				continue
+			}
+			_, _ = conn.WriteToUDP(response, from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestGather(t *testing.T) {
+	addr := serve(t)
+
+	b := &BACnet{
+		LocalAddress:     "127.0.0.1:0",
+		BroadcastAddress: addr,
+		Timeout:          internal.Duration{Duration: time.Second},
+		Retries:          1,
+		Devices: []device{{
+			Instance: 1234,
+			Name:     "ahu1",
+			Objects: []object{
+				{Type: "analog_input", Instance: 1, Name: "zone_temp"},
+				{Type: "binary_input", Instance: 2, Properties: []string{"present_value", "status_flags", "units"}},
+			},
+		}},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, b.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, b.Gather(acc))
+	require.Equal(t, addr, b.discovered[1234].String())
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "units: error class property, code unknown_property")
+
+	expected := []cua.Metric{
+		testutil.MustMetric("bacnet",
+			map[string]string{"device": "1234", "device_name": "ahu1", "object_type": "analog_input", "object_instance": "1", "object": "zone_temp"},
+			map[string]interface{}{"present_value": 21.5, "in_alarm": false, "fault": true, "overridden": false, "out_of_service": false},
+			time.Unix(0, 0)),
+		testutil.MustMetric("bacnet",
+			map[string]string{"device": "1234", "device_name": "ahu1", "object_type": "binary_input", "object_instance": "2"},
+			map[string]interface{}{"present_value": int64(1), "in_alarm": false, "fault": false, "overridden": false, "out_of_service": false},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherTimeout(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	b := &BACnet{
+		LocalAddress:     "127.0.0.1:0",
+		BroadcastAddress: conn.LocalAddr().String(),
+		Timeout:          internal.Duration{Duration: 50 * time.Millisecond},
+		Devices: []device{
+			{Instance: 1, Objects: []object{{Type: "analog_value", Instance: 1}}},
+			{Instance: 2, Address: conn.LocalAddr().String(), Objects: []object{{Type: "analog_value", Instance: 1}}},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, b.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, b.Gather(acc))
+	require.Len(t, acc.Errors, 2)
+	require.Contains(t, acc.Errors[0].Error(), "device 1 not found")
+	require.Contains(t, acc.Errors[1].Error(), "timeout")
+	require.Empty(t, acc.GetCUAMetrics())
+}
+
+func TestParseAddress(t *testing.T) {
+	a, err := parseAddress("192.168.1.10:47808/5:0a0b")
+	require.NoError(t, err)
+	require.Equal(t, uint16(5), a.network)
+	require.Equal(t, []byte{0x0A, 0x0B}, a.mac)
+	require.Equal(t, "192.168.1.10:47808/5:0a0b", a.String())
+
+	_, err = parseAddress("192.168.1.10:47808/0:0a")
+	require.Error(t, err)
+	_, err = parseAddress("192.168.1.10:47808/5:zz")
+	require.Error(t, err)
+}
+
+func TestInitInvalid(t *testing.T) {
+	newBACnet := func(d device) *BACnet {
+		return &BACnet{BroadcastAddress: "255.255.255.255:47808", Devices: []device{d}}
+	}
+	require.Error(t, (&BACnet{BroadcastAddress: "255.255.255.255:47808"}).Init())
+	require.Error(t, newBACnet(device{Instance: 0x3FFFFF}).Init())
+	require.Error(t, newBACnet(device{Objects: []object{{Type: "analog"}}}).Init())
+	require.Error(t, newBACnet(device{Objects: []object{{Type: "analog_input", Properties: []string{"value"}}}}).Init())
+}
@@ -0,0 +1,482 @@
+package bacnet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"unicode/utf8"
+)
+
+// BACnet virtual link control
+const (
+	bvlcType = 0x81
+
+	bvlcForwardedNPDU   = 0x04
+	bvlcUnicastNPDU     = 0x0A
+	bvlcBroadcastNPDU   = 0x0B
+	bvlcHeaderSize      = 4
+	bvlcForwardedHeader = 10
+)
+
+// network layer
+const (
+	npduVersion = 0x01
+
+	npduNetworkMessage = 0x80
+	npduDestination    = 0x20
+	npduSource         = 0x08
+	npduExpectingReply = 0x04
+)
+
+// application layer
+const (
+	pduConfirmedRequest   = 0x00
+	pduUnconfirmedRequest = 0x10
+	pduComplexAck         = 0x30
+	pduError              = 0x50
+	pduReject             = 0x60
+	pduAbort              = 0x70
+
+	serviceIAm          = 0x00
+	serviceWhoIs        = 0x08
+	serviceReadProperty = 0x0C
+
+	// maximum APDU accepted of 1476 bytes, without segmentation
+	maxAPDUAccepted = 0x05
+)
+
+// application tags
+const (
+	tagNull            = 0
+	tagBoolean         = 1
+	tagUnsigned        = 2
+	tagSigned          = 3
+	tagReal            = 4
+	tagDouble          = 5
+	tagOctetString     = 6
+	tagCharacterString = 7
+	tagBitString       = 8
+	tagEnumerated      = 9
+	tagObjectID        = 12
+)
+
+var objectTypes = map[string]uint16{
+	"analog_input":       0,
+	"analog_output":      1,
+	"analog_value":       2,
+	"binary_input":       3,
+	"binary_output":      4,
+	"binary_value":       5,
+	"device":             8,
+	"multi_state_input":  13,
+	"multi_state_output": 14,
+	"multi_state_value":  19,
+	"accumulator":        23,
+}
+
+var properties = map[string]uint32{
+	"description":    28,
+	"event_state":    36,
+	"object_name":    77,
+	"out_of_service": 81,
+	"present_value":  85,
+	"reliability":    103,
+	"status_flags":   111,
+	"units":          117,
+}
+
+// statusFlags are the names of the bits of the status flags
+var statusFlags = []string{"in_alarm", "fault", "overridden", "out_of_service"}
+
+// address is the address of a device, with the network and the MAC address
+// of the devices behind a router.
+type address struct {
+	udp     *net.UDPAddr
+	network uint16
+	mac     []byte
+}
+
+func (a *address) String() string {
+	if a.network == 0 {
+		return a.udp.String()
+	}
+	return fmt.Sprintf("%s/%d:%s", a.udp, a.network, hex.EncodeToString(a.mac))
+}
+
+func objectID(objectType uint16, instance uint32) uint32 {
+	return uint32(objectType)<<22 | instance&0x3FFFFF
+}
+
+// appendNPDU appends the virtual link and network headers of a message to
+// the address, the length of the virtual link header is set by finishBVLC.
+func appendNPDU(b []byte, function byte, to *address, control byte) []byte {
+	b = append(b, bvlcType, function, 0, 0, npduVersion)
+	if to == nil || to.network == 0 {
+		return append(b, control)
+	}
+	b = append(b, control|npduDestination, byte(to.network>>8), byte(to.network), byte(len(to.mac)))
+	b = append(b, to.mac...)
+	// hop count
+	return append(b, 0xFF)
+}
+
+func finishBVLC(b []byte) []byte {
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
+
+// appendContextUnsigned appends an unsigned integer with a context tag.
+func appendContextUnsigned(b []byte, tag byte, v uint32) []byte {
+	switch {
+	case v <= 0xFF:
+		return append(b, tag<<4|0x08|1, byte(v))
+	case v <= 0xFFFF:
+		return append(b, tag<<4|0x08|2, byte(v>>8), byte(v))
+	case v <= 0xFFFFFF:
+		return append(b, tag<<4|0x08|3, byte(v>>16), byte(v>>8), byte(v))
+	}
+	return append(b, tag<<4|0x08|4, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// whoIs returns a Who-Is request broadcast to the devices of the instances
+// from low to high.
+func whoIs(low, high uint32) []byte {
+	b := appendNPDU(nil, bvlcBroadcastNPDU, nil, 0)
+	b = append(b, pduUnconfirmedRequest, serviceWhoIs)
+	b = appendContextUnsigned(b, 0, low)
+	b = appendContextUnsigned(b, 1, high)
+	return finishBVLC(b)
+}
+
+// readProperty returns a ReadProperty request of the property of an object.
+func readProperty(to *address, invokeID byte, object uint32, property uint32) []byte {
+	b := appendNPDU(nil, bvlcUnicastNPDU, to, npduExpectingReply)
+	b = append(b, pduConfirmedRequest, maxAPDUAccepted, invokeID, serviceReadProperty)
+	b = append(b, 0x0C, byte(object>>24), byte(object>>16), byte(object>>8), byte(object))
+	b = appendContextUnsigned(b, 1, property)
+	return finishBVLC(b)
+}
+
+// message is an application message received, with its source address.
+type message struct {
+	source *address
+	apdu   []byte
+}
+
+// parseMessage decodes the virtual link and network headers of a message
+// received from the UDP address, nil is returned for the network layer
+// messages.
+func parseMessage(b []byte, from *net.UDPAddr) (*message, error) {
+	if len(b) < bvlcHeaderSize || b[0] != bvlcType {
+		return nil, errors.New("not a BACnet/IP message")
+	}
+	if int(binary.BigEndian.Uint16(b[2:])) != len(b) {
+		return nil, fmt.Errorf("invalid BVLC length %d", binary.BigEndian.Uint16(b[2:]))
+	}
+	source := &address{udp: from}
+	switch b[1] {
+	case bvlcUnicastNPDU, bvlcBroadcastNPDU:
+		b = b[bvlcHeaderSize:]
+	case bvlcForwardedNPDU:
+		// the original source of the messages forwarded by a broadcast
+		// management device
+		if len(b) < bvlcForwardedHeader {
+			return nil, errors.New("truncated forwarded NPDU")
+		}
+		source.udp = &net.UDPAddr{IP: net.IP(append([]byte(nil), b[4:8]...)), Port: int(binary.BigEndian.Uint16(b[8:]))}
+		b = b[bvlcForwardedHeader:]
+	default:
+		return nil, nil
+	}
+
+	if len(b) < 2 || b[0] != npduVersion {
+		return nil, errors.New("invalid NPDU")
+	}
+	control := b[1]
+	b = b[2:]
+	if control&npduDestination != 0 {
+		if len(b) < 3 || len(b) < 3+int(b[2]) {
+			return nil, errors.New("truncated NPDU destination")
+		}
+		b = b[3+int(b[2]):]
+	}
+	if control&npduSource != 0 {
+		if len(b) < 3 || len(b) < 3+int(b[2]) {
+			return nil, errors.New("truncated NPDU source")
+		}
+		source.network = binary.BigEndian.Uint16(b)
+		source.mac = append([]byte(nil), b[3:3+int(b[2])]...)
+		b = b[3+int(b[2]):]
+	}
+	if control&npduDestination != 0 {
+		// hop count
+		if len(b) < 1 {
+			return nil, errors.New("truncated NPDU hop count")
+		}
+		b = b[1:]
+	}
+	if control&npduNetworkMessage != 0 {
+		return nil, nil
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty APDU")
+	}
+	return &message{source: source, apdu: b}, nil
+}
+
+// tag is the header of an encoded value.
+type tag struct {
+	number  byte
+	context bool
+	opening bool
+	closing bool
+	// length of the content, or value of the application booleans
+	length uint32
+	size   int
+}
+
+func decodeTag(b []byte) (tag, error) {
+	if len(b) == 0 {
+		return tag{}, errors.New("truncated tag")
+	}
+	t := tag{number: b[0] >> 4, context: b[0]&0x08 != 0, size: 1}
+	if t.number == 0x0F {
+		if len(b) < 2 {
+			return t, errors.New("truncated tag")
+		}
+		t.number = b[1]
+		t.size++
+	}
+	lvt := b[0] & 0x07
+	switch {
+	case t.context && lvt == 6:
+		t.opening = true
+	case t.context && lvt == 7:
+		t.closing = true
+	case lvt == 5:
+		if len(b) < t.size+1 {
+			return t, errors.New("truncated tag length")
+		}
+		switch n := b[t.size]; n {
+		case 254:
+			if len(b) < t.size+3 {
+				return t, errors.New("truncated tag length")
+			}
+			t.length = uint32(binary.BigEndian.Uint16(b[t.size+1:]))
+			t.size += 3
+		case 255:
+			if len(b) < t.size+5 {
+				return t, errors.New("truncated tag length")
+			}
+			t.length = binary.BigEndian.Uint32(b[t.size+1:])
+			t.size += 5
+		default:
+			t.length = uint32(n)
+			t.size++
+		}
+	default:
+		t.length = uint32(lvt)
+	}
+	return t, nil
+}
+
+func decodeUnsigned(b []byte) uint64 {
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v
+}
+
+func decodeSigned(b []byte) int64 {
+	if len(b) == 0 {
+		return 0
+	}
+	v := int64(int8(b[0]))
+	for _, x := range b[1:] {
+		v = v<<8 | int64(x)
+	}
+	return v
+}
+
+// value is a value decoded, with the bits of the bit strings.
+type value struct {
+	value interface{}
+	bits  []bool
+}
+
+// decodeValue decodes an application tagged value and returns its size, the
+// value is nil for the types not supported.
+func decodeValue(b []byte) (value, int, error) {
+	t, err := decodeTag(b)
+	if err != nil {
+		return value{}, 0, err
+	}
+	if t.context {
+		return value{}, 0, fmt.Errorf("unexpected context tag %d", t.number)
+	}
+	if t.number == tagBoolean {
+		return value{value: t.length != 0}, t.size, nil
+	}
+	end := t.size + int(t.length)
+	if end > len(b) {
+		return value{}, 0, fmt.Errorf("truncated value of tag %d", t.number)
+	}
+	content := b[t.size:end]
+
+	var v value
+	switch t.number {
+	case tagNull:
+	case tagUnsigned, tagEnumerated:
+		v.value = int64(decodeUnsigned(content))
+	case tagSigned:
+		v.value = decodeSigned(content)
+	case tagReal:
+		if len(content) != 4 {
+			return v, 0, errors.New("invalid real")
+		}
+		v.value = float64(math.Float32frombits(binary.BigEndian.Uint32(content)))
+	case tagDouble:
+		if len(content) != 8 {
+			return v, 0, errors.New("invalid double")
+		}
+		v.value = math.Float64frombits(binary.BigEndian.Uint64(content))
+	case tagOctetString:
+		v.value = hex.EncodeToString(content)
+	case tagCharacterString:
+		// only the UTF-8 (and ANSI X3.4) character set is supported
+		if len(content) > 0 && content[0] == 0 && utf8.Valid(content[1:]) {
+			v.value = string(content[1:])
+		}
+	case tagBitString:
+		if len(content) > 0 {
+			unused := int(content[0])
+			count := 8*(len(content)-1) - unused
+			for i := 0; i < count; i++ {
+				v.bits = append(v.bits, content[1+i/8]&(0x80>>(i%8)) != 0)
+			}
+		}
+	}
+	return v, end, nil
+}
+
+// iAm is a device announced with I-Am.
+type iAm struct {
+	instance uint32
+}
+
+// parseIAm decodes an I-Am request, false is returned for the other
+// messages.
+func parseIAm(apdu []byte) (iAm, bool) {
+	if len(apdu) < 2 || apdu[0]&0xF0 != pduUnconfirmedRequest || apdu[1] != serviceIAm {
+		return iAm{}, false
+	}
+	t, err := decodeTag(apdu[2:])
+	if err != nil || t.context || t.number != tagObjectID || t.length != 4 || len(apdu) < 2+t.size+4 {
+		return iAm{}, false
+	}
+	id := binary.BigEndian.Uint32(apdu[2+t.size:])
+	if id>>22 != uint32(objectTypes["device"]) {
+		return iAm{}, false
+	}
+	return iAm{instance: id & 0x3FFFFF}, true
+}
+
+// errorClasses and errorCodes are the names of the most common errors
+var errorClasses = map[int64]string{
+	0: "device",
+	1: "object",
+	2: "property",
+	3: "resources",
+	4: "security",
+	5: "services",
+	7: "communication",
+}
+
+var errorCodes = map[int64]string{
+	0:  "other",
+	2:  "configuration_in_progress",
+	3:  "device_busy",
+	9:  "invalid_data_type",
+	25: "operational_problem",
+	27: "read_access_denied",
+	31: "unknown_object",
+	32: "unknown_property",
+	42: "invalid_array_index",
+	50: "property_is_not_an_array",
+}
+
+func errorName(names map[int64]string, v int64) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", v)
+}
+
+// parseReadPropertyAck decodes the response to a ReadProperty request, with
+// the invoke ID of the request, the first value of the property is returned.
+func parseReadPropertyAck(apdu []byte, invokeID byte) (value, bool, error) {
+	if len(apdu) < 3 {
+		return value{}, false, nil
+	}
+	switch apdu[0] & 0xF0 {
+	case pduComplexAck:
+		if apdu[1] != invokeID {
+			return value{}, false, nil
+		}
+		if apdu[0]&0x08 != 0 {
+			return value{}, true, errors.New("segmented response not supported")
+		}
+		if len(apdu) < 3 || apdu[2] != serviceReadProperty {
+			return value{}, true, errors.New("unexpected service")
+		}
+		b := apdu[3:]
+		// object identifier, property identifier and array index
+		for {
+			t, err := decodeTag(b)
+			if err != nil {
+				return value{}, true, err
+			}
+			if t.opening && t.number == 3 {
+				b = b[t.size:]
+				break
+			}
+			if !t.context || t.opening || t.closing || t.size+int(t.length) > len(b) {
+				return value{}, true, errors.New("invalid ReadProperty response")
+			}
+			b = b[t.size+int(t.length):]
+		}
+		v, _, err := decodeValue(b)
+		return v, true, err
+	case pduError:
+		if apdu[1] != invokeID {
+			return value{}, false, nil
+		}
+		b := apdu[3:]
+		class, n, err := decodeValue(b)
+		if err != nil {
+			return value{}, true, fmt.Errorf("error: %w", err)
+		}
+		code, _, err := decodeValue(b[n:])
+		if err != nil {
+			return value{}, true, fmt.Errorf("error: %w", err)
+		}
+		c, _ := class.value.(int64)
+		e, _ := code.value.(int64)
+		return value{}, true, fmt.Errorf("error class %s, code %s", errorName(errorClasses, c), errorName(errorCodes, e))
+	case pduReject:
+		if apdu[1] != invokeID {
+			return value{}, false, nil
+		}
+		return value{}, true, fmt.Errorf("request rejected with reason %d", apdu[2])
+	case pduAbort:
+		if apdu[1] != invokeID {
+			return value{}, false, nil
+		}
+		return value{}, true, fmt.Errorf("request aborted with reason %d", apdu[2])
+	}
+	return value{}, false, nil
+}
@@ -20,6 +20,7 @@ type Bind struct {
 	Urls                 []string
 	GatherMemoryContexts bool
 	GatherViews          bool
+	GatherZones          bool
 }
 
 var sampleConfig = `
@@ -28,6 +29,10 @@ var sampleConfig = `
   # urls = ["http://localhost:8053/xml/v3"]
   # gather_memory_contexts = false
   # gather_views = false
+  ## Report the serial, the refresh and the expiry of the zones, and their
+  ## counters with zone-statistics, from the zones of the JSON v1 and XML v3
+  ## statistics.
+  # gather_zones = false
 `
 
 var client = &http.Client{
@@ -74,13 +79,25 @@ func (b *Bind) gatherURL(addr *url.URL, acc cua.Accumulator) error {
 		return b.readStatsXMLv2(addr, acc)
 	case "/json/v1":
 		// BIND 9.10+
-		return b.readStatsJSON(addr, acc)
+		if err := b.readStatsJSON(addr, acc); err != nil {
+			return err
+		}
+		if b.GatherZones {
+			return b.readZonesJSON(addr, acc)
+		}
+		return nil
 	case "/xml/v2":
 		// BIND 9.9
 		return b.readStatsXMLv2(addr, acc)
 	case "/xml/v3":
 		// BIND 9.9+
-		return b.readStatsXMLv3(addr, acc)
+		if err := b.readStatsXMLv3(addr, acc); err != nil {
+			return err
+		}
+		if b.GatherZones {
+			return b.readZonesXMLv3(addr, acc)
+		}
+		return nil
 	default:
 		return fmt.Errorf("'%s' ambiguous, check plugin documentation for supported URL formats", addr)
 	}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBindJsonStats(t *testing.T) {
@@ -618,3 +619,85 @@ func TestBindUnparseableURL(t *testing.T) {
 	err := acc.GatherError(b.Gather)
 	assert.Contains(t, err.Error(), "Unable to parse address")
 }
+
+func TestBindZones(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	url := ts.Listener.Addr().String()
+	host, port, _ := net.SplitHostPort(url)
+	defer ts.Close()
+
+	for _, path := range []string{"/json/v1", "/xml/v3"} {
+		path := path
+		t.Run(path, func(t *testing.T) {
+			b := Bind{
+				Urls:        []string{ts.URL + path},
+				GatherViews: true,
+				GatherZones: true,
+			}
+
+			var acc testutil.Accumulator
+			require.NoError(t, acc.GatherError(b.Gather))
+
+			tags := func(view, zone, class, typ string) map[string]string {
+				return map[string]string{
+					"url":    url,
+					"source": host,
+					"port":   port,
+					"view":   view,
+					"zone":   zone,
+					"class":  class,
+					"type":   typ,
+				}
+			}
+
+			acc.AssertContainsTaggedFields(t, "bind_zone",
+				map[string]interface{}{"serial": int64(0)},
+				tags("_bind", "authors.bind", "CH", "builtin"))
+			acc.AssertContainsTaggedFields(t, "bind_counter",
+				map[string]interface{}{"QrySuccess": int64(120), "QryNXDOMAIN": int64(4)},
+				tags("_default", "example.com", "IN", "rcode"))
+			acc.AssertContainsTaggedFields(t, "bind_counter",
+				map[string]interface{}{"A": int64(100), "AAAA": int64(24)},
+				tags("_default", "example.com", "IN", "qtype"))
+
+			var secondary *testutil.Metric
+			for _, m := range acc.Metrics {
+				if m.Measurement == "bind_zone" && m.Tags["zone"] == "example.net" {
+					secondary = m
+				}
+			}
+			require.NotNil(t, secondary)
+			require.Equal(t, "secondary", secondary.Tags["type"])
+			require.Equal(t, int64(2021022801), secondary.Fields["serial"])
+			require.Greater(t, secondary.Fields["seconds_since_loaded"], int64(0))
+			require.Greater(t, secondary.Fields["seconds_until_refresh"], int64(0))
+			require.Greater(t, secondary.Fields["seconds_until_expiry"], secondary.Fields["seconds_until_refresh"])
+		})
+	}
+}
+
+func TestBindXmlStatsV3Cache(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer ts.Close()
+
+	b := Bind{
+		Urls:        []string{ts.URL + "/xml/v3"},
+		GatherViews: true,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(b.Gather))
+
+	var cache *testutil.Metric
+	for _, m := range acc.Metrics {
+		if m.Measurement == "bind_counter" && m.Tags["type"] == "cache" && m.Tags["view"] == "_default" {
+			cache = m
+		}
+	}
+	require.NotNil(t, cache)
+	require.Equal(t, int64(195), cache.Fields["A"])
+	require.Equal(t, int64(42), cache.Fields["NS"])
+
+	// the zones are only requested with gather_zones
+	require.False(t, acc.HasMeasurement("bind_zone"))
+}
@@ -121,6 +121,21 @@ func (b *Bind) addStatsXMLv3(stats v3Stats, acc cua.Accumulator, hostPort string
 					_ = grouper.Add("bind_counter", tags, ts, c.Name, c.Value)
 				}
 			}
+
+			// Cached RRsets, by type
+			for _, cache := range v.Caches {
+				for _, rr := range cache.RRSets {
+					tags := map[string]string{
+						"url":    hostPort,
+						"source": host,
+						"port":   port,
+						"view":   v.Name,
+						"type":   "cache",
+					}
+
+					_ = grouper.Add("bind_counter", tags, ts, rr.Name, rr.Value)
+				}
+			}
 		}
 	}
 
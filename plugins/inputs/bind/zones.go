@@ -0,0 +1,188 @@
+package bind
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+)
+
+// JSON path: /views/<view>/zones, requested from the zones endpoint
+type jsonZones struct {
+	Views map[string]struct {
+		Zones []jsonZone
+	}
+}
+
+type jsonZone struct {
+	Name      string
+	Class     string
+	Serial    int64
+	Type      string
+	Loaded    string
+	Expires   string
+	Refresh   string
+	RCodes    map[string]int
+	QTypes    map[string]int
+	GlueCache map[string]int
+}
+
+// XML path: //statistics/views/view/zones/zone, requested from the zones
+// endpoint
+type v3Zones struct {
+	Views []struct {
+		Name  string `xml:"name,attr"`
+		Zones []struct {
+			Name          string           `xml:"name,attr"`
+			Class         string           `xml:"rdataclass,attr"`
+			Serial        int64            `xml:"serial"`
+			Type          string           `xml:"type"`
+			Loaded        string           `xml:"loaded"`
+			Expires       string           `xml:"expires"`
+			Refresh       string           `xml:"refresh"`
+			CounterGroups []v3CounterGroup `xml:"counters"`
+		} `xml:"zones>zone"`
+	} `xml:"views>view"`
+}
+
+// zoneTags returns the tags of the metrics of a zone.
+func zoneTags(hostPort, view, zone, class, typ string) map[string]string {
+	host, port, _ := net.SplitHostPort(hostPort)
+	tags := map[string]string{
+		"url":    hostPort,
+		"source": host,
+		"port":   port,
+		"view":   view,
+		"zone":   zone,
+		"class":  class,
+	}
+	if typ != "" {
+		tags["type"] = typ
+	}
+	return tags
+}
+
+// zoneFields returns the serial of a zone, and the times since it was
+// loaded and until it is refreshed and expires when they are known, the
+// refresh and the expiry being those of the secondary zones.
+func zoneFields(serial int64, loaded, refresh, expires string, now time.Time) map[string]interface{} {
+	fields := map[string]interface{}{"serial": serial}
+	if t, err := time.Parse(time.RFC3339, loaded); err == nil {
+		fields["seconds_since_loaded"] = int64(now.Sub(t).Seconds())
+	}
+	if t, err := time.Parse(time.RFC3339, refresh); err == nil {
+		fields["seconds_until_refresh"] = int64(t.Sub(now).Seconds())
+	}
+	if t, err := time.Parse(time.RFC3339, expires); err == nil {
+		fields["seconds_until_expiry"] = int64(t.Sub(now).Seconds())
+	}
+	return fields
+}
+
+// addZonesJSON adds a bind_zone metric per zone, and the counters of the
+// zones with zone-statistics enabled.
+func addZonesJSON(zones jsonZones, acc cua.Accumulator, hostPort string) {
+	grouper := metric.NewSeriesGrouper()
+	now := time.Now()
+	for vName, view := range zones.Views {
+		for _, z := range view.Zones {
+			acc.AddGauge("bind_zone",
+				zoneFields(z.Serial, z.Loaded, z.Refresh, z.Expires, now),
+				zoneTags(hostPort, vName, z.Name, z.Class, z.Type))
+
+			for cntrType, counters := range map[string]map[string]int{
+				"rcode":     z.RCodes,
+				qtype:       z.QTypes,
+				"gluecache": z.GlueCache,
+			} {
+				for cntrName, value := range counters {
+					tags := zoneTags(hostPort, vName, z.Name, z.Class, cntrType)
+					_ = grouper.Add("bind_counter", tags, now, cntrName, value)
+				}
+			}
+		}
+	}
+
+	for _, metric := range grouper.Metrics() {
+		acc.AddMetric(metric)
+	}
+}
+
+// addZonesXMLv3 adds a bind_zone metric per zone, and the counters of the
+// zones with zone-statistics enabled.
+func addZonesXMLv3(zones v3Zones, acc cua.Accumulator, hostPort string) {
+	grouper := metric.NewSeriesGrouper()
+	now := time.Now()
+	for _, v := range zones.Views {
+		for _, z := range v.Zones {
+			acc.AddGauge("bind_zone",
+				zoneFields(z.Serial, z.Loaded, z.Refresh, z.Expires, now),
+				zoneTags(hostPort, v.Name, z.Name, z.Class, z.Type))
+
+			for _, cg := range z.CounterGroups {
+				for _, c := range cg.Counters {
+					tags := zoneTags(hostPort, v.Name, z.Name, z.Class, cg.Type)
+					_ = grouper.Add("bind_counter", tags, now, c.Name, c.Value)
+				}
+			}
+		}
+	}
+
+	for _, metric := range grouper.Metrics() {
+		acc.AddMetric(metric)
+	}
+}
+
+// readZones requests the zones endpoint of the statistics channel, which is
+// decoded separately from the other documents as its views only hold the
+// zones.
+func readZones(addr *url.URL, decode func(resp *http.Response) error) error {
+	scrapeURL := addr.String() + "/zones"
+
+	resp, err := client.Get(scrapeURL)
+	if err != nil {
+		return fmt.Errorf("http get (%s): %w", scrapeURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status: %s", scrapeURL, resp.Status)
+	}
+	return decode(resp)
+}
+
+func (b *Bind) readZonesJSON(addr *url.URL, acc cua.Accumulator) error {
+	var zones jsonZones
+	err := readZones(addr, func(resp *http.Response) error {
+		if err := json.NewDecoder(resp.Body).Decode(&zones); err != nil {
+			return fmt.Errorf("Unable to decode JSON blob: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	addZonesJSON(zones, acc, addr.Host)
+	return nil
+}
+
+func (b *Bind) readZonesXMLv3(addr *url.URL, acc cua.Accumulator) error {
+	var zones v3Zones
+	err := readZones(addr, func(resp *http.Response) error {
+		if err := xml.NewDecoder(resp.Body).Decode(&zones); err != nil {
+			return fmt.Errorf("Unable to decode XML document: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	addZonesXMLv3(zones, acc, addr.Host)
+	return nil
+}
@@ -0,0 +1,261 @@
+package canbus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## SocketCAN network interface to read the frames from.
+  interface = "can0"
+
+  ## DBC file describing the messages and signals decoded.
+  # dbc_file = "/etc/circonus-unified-agent/vehicle.dbc"
+
+  ## Decode the common J1939 parameter groups of the engines, vehicles and
+  ## generators from the extended frames.
+  # j1939 = false
+
+  ## Messages decoded in addition to the ones of the DBC file, identified by
+  ## their CAN ID, or by their J1939 parameter group number whatever the
+  ## source address.  The signals follow the conventions of the DBC files:
+  ## the start bit is the least significant bit of the little endian
+  ## signals, the most significant bit of the big endian signals.  The IDs
+  ## are decimal numbers, 419385573 for 0x18FF50E5.
+  # [[inputs.canbus.messages]]
+  #   name = "BMS_STATUS"
+  #   id = 419385573
+  #   extended = true
+  #   # pgn = 65360
+  #
+  #   [[inputs.canbus.messages.signals]]
+  #     name = "pack_voltage"
+  #     start_bit = 0
+  #     length = 16
+  #     # byte_order = "little_endian"
+  #     # signed = false
+  #     factor = 0.1
+  #     # offset = 0.0
+`
+
+// frame is a data frame received from the bus.
+type frame struct {
+	id       uint32
+	extended bool
+	data     []byte
+}
+
+// frameReader reads the frames of the bus.
+type frameReader interface {
+	read() (frame, error)
+	Close() error
+}
+
+// pending are the values of the signals of a message received since the
+// last gather.
+type pending struct {
+	tags   map[string]string
+	fields map[string]interface{}
+	t      time.Time
+}
+
+type CANBus struct {
+	Interface string    `toml:"interface"`
+	DBCFile   string    `toml:"dbc_file"`
+	J1939     bool      `toml:"j1939"`
+	Messages  []message `toml:"messages"`
+
+	Log cua.Logger `toml:"-"`
+
+	byID  map[uint32]*message
+	byPGN map[uint32]*message
+
+	mu      sync.Mutex
+	pending map[string]*pending
+	// order of the messages received, for the metrics to be added in order
+	order []string
+
+	conn frameReader
+	wg   sync.WaitGroup
+}
+
+func (c *CANBus) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CANBus) Description() string {
+	return "Decode the signals of the CAN bus messages with SocketCAN"
+}
+
+// idKey is the key of the messages identified by an extended CAN ID.
+const idKey = 0x80000000
+
+func (c *CANBus) Init() error {
+	if c.Interface == "" {
+		return errors.New("no interface configured")
+	}
+
+	var messages []message
+	if c.J1939 {
+		for _, m := range j1939Messages {
+			m.Signals = append([]signal(nil), m.Signals...)
+			messages = append(messages, m)
+		}
+	}
+	if c.DBCFile != "" {
+		f, err := os.Open(c.DBCFile)
+		if err != nil {
+			return fmt.Errorf("dbc_file: %w", err)
+		}
+		dbc, err := parseDBC(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("dbc_file %s: %w", c.DBCFile, err)
+		}
+		messages = append(messages, dbc...)
+	}
+	messages = append(messages, c.Messages...)
+	if len(messages) == 0 {
+		return errors.New("no messages to decode, set dbc_file, j1939 or messages")
+	}
+
+	// the messages configured last override the previous ones
+	c.byID = make(map[uint32]*message)
+	c.byPGN = make(map[uint32]*message)
+	for i := range messages {
+		m := &messages[i]
+		if m.Name == "" {
+			return errors.New("message without name")
+		}
+		for j := range m.Signals {
+			if err := m.Signals[j].init(); err != nil {
+				return fmt.Errorf("message %s: %w", m.Name, err)
+			}
+		}
+		switch {
+		case m.PGN != 0:
+			if m.PGN > 0x3FFFF {
+				return fmt.Errorf("message %s: invalid pgn %d", m.Name, m.PGN)
+			}
+			m.j1939 = true
+			c.byPGN[m.PGN] = m
+		case m.Extended:
+			if m.ID > 0x1FFFFFFF {
+				return fmt.Errorf("message %s: invalid extended id 0x%X", m.Name, m.ID)
+			}
+			c.byID[m.ID|idKey] = m
+		default:
+			if m.ID > 0x7FF {
+				return fmt.Errorf("message %s: invalid id 0x%X, set extended for the 29 bits IDs", m.Name, m.ID)
+			}
+			c.byID[m.ID] = m
+		}
+	}
+	c.pending = make(map[string]*pending)
+	return nil
+}
+
+func (c *CANBus) Start(_ cua.Accumulator) error {
+	conn, err := c.open()
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return nil
+	}
+	c.conn = conn
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			f, err := conn.read()
+			if err != nil {
+				if !errors.Is(err, os.ErrClosed) {
+					c.Log.Errorf("Reading frames from %s: %v", c.Interface, err)
+				}
+				return
+			}
+			c.handle(f, time.Now())
+		}
+	}()
+	return nil
+}
+
+func (c *CANBus) Stop() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.wg.Wait()
+}
+
+// handle decodes a frame of the messages configured.
+func (c *CANBus) handle(f frame, t time.Time) {
+	key := f.id
+	if f.extended {
+		key |= idKey
+	}
+	m, ok := c.byID[key]
+	tags := map[string]string{"interface": c.Interface}
+	if !ok && f.extended && len(c.byPGN) != 0 {
+		pgn, source := j1939PGN(f.id)
+		if m, ok = c.byPGN[pgn]; ok {
+			tags["pgn"] = strconv.FormatUint(uint64(pgn), 10)
+			tags["source_address"] = strconv.Itoa(int(source))
+		}
+	}
+	if !ok {
+		return
+	}
+	tags["message"] = m.Name
+
+	fields := make(map[string]interface{}, len(m.Signals))
+	m.decode(f.data, fields)
+	if len(fields) == 0 {
+		return
+	}
+
+	id := m.Name + "/" + tags["source_address"]
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[id]
+	if !ok {
+		p = &pending{tags: tags, fields: make(map[string]interface{}, len(fields))}
+		c.pending[id] = p
+		c.order = append(c.order, id)
+	}
+	// the multiplexed signals of the frames received are merged
+	for k, v := range fields {
+		p.fields[k] = v
+	}
+	p.t = t
+}
+
+// Gather adds the last values of the messages received since the previous
+// gather.
+func (c *CANBus) Gather(acc cua.Accumulator) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range c.order {
+		p := c.pending[id]
+		acc.AddFields("canbus", p.fields, p.tags, p.t)
+	}
+	c.pending = make(map[string]*pending)
+	c.order = c.order[:0]
+	return nil
+}
+
+func init() {
+	inputs.Add("canbus", func() cua.Input {
+		return &CANBus{
+			Interface: "can0",
+		}
+	})
+}
@@ -0,0 +1,76 @@
+// +build linux
+
+package canbus
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// canFrameSize is the size of the classic CAN frames read from the raw
+// sockets, struct can_frame.
+const canFrameSize = 16
+
+type socketReader struct {
+	f   *os.File
+	buf [canFrameSize]byte
+}
+
+// open opens a raw CAN socket bound to the interface.
+func (c *CANBus) open() (frameReader, error) {
+	iface, err := net.InterfaceByName(c.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", c.Interface, err)
+	}
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW, unix.CAN_RAW)
+	if err != nil {
+		return nil, fmt.Errorf("socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrCAN{Ifindex: iface.Index}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("bind %s: %w", c.Interface, err)
+	}
+	// non blocking for the reads to be interrupted by Close
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("set non blocking: %w", err)
+	}
+	return &socketReader{f: os.NewFile(uintptr(fd), c.Interface)}, nil
+}
+
+// read returns the next data frame, the remote and error frames are skipped.
+func (r *socketReader) read() (frame, error) {
+	for {
+		n, err := r.f.Read(r.buf[:])
+		if err != nil {
+			return frame{}, err
+		}
+		if n != canFrameSize {
+			continue
+		}
+		// the CAN ID is in the host byte order
+		id := *(*uint32)(unsafe.Pointer(&r.buf[0]))
+		if id&(unix.CAN_RTR_FLAG|unix.CAN_ERR_FLAG) != 0 {
+			continue
+		}
+		length := int(r.buf[4])
+		if length > 8 {
+			length = 8
+		}
+		f := frame{extended: id&unix.CAN_EFF_FLAG != 0, data: append([]byte(nil), r.buf[8:8+length]...)}
+		if f.extended {
+			f.id = id & unix.CAN_EFF_MASK
+		} else {
+			f.id = id & unix.CAN_SFF_MASK
+		}
+		return f, nil
+	}
+}
+
+func (r *socketReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,8 @@
+// +build !linux
+
+package canbus
+
+func (c *CANBus) open() (frameReader, error) {
+	c.Log.Warn("Current platform is not supported")
+	return nil, nil
+}
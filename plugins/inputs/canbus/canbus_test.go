@@ -0,0 +1,128 @@
+package canbus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	c := &CANBus{
+		Interface: "can0",
+		DBCFile:   "testdata/test.dbc",
+		J1939:     true,
+		Messages: []message{{
+			Name: "PROPA",
+			PGN:  61184,
+			Signals: []signal{
+				{Name: "state", StartBit: 0, Length: 8},
+			},
+		}},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, c.Init())
+
+	now := time.Now()
+	frames := []frame{
+		{id: 256, data: []byte{0xE8, 0x03, 0xF6, 0, 0, 0, 0, 0}},
+		{id: 0x18FF50E5, extended: true, data: []byte{0, 0x10, 0x0E}},
+		{id: 0x18FF50E5, extended: true, data: []byte{1, 0x74, 0x0E}},
+		// EEC1 of the engine 0
+		{id: 0x0CF00400, extended: true, data: []byte{0xFF, 0xFF, 0x96, 0xE0, 0x2E, 0xFF, 0xFF, 0xFF}},
+		// ET1 of the source 0x17, the fuel and oil temperatures not available
+		{id: 0x18FEEE17, extended: true, data: []byte{0x7D, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		// proprietary A from 0x17 to 0x2A
+		{id: 0x18EF2A17, extended: true, data: []byte{3}},
+		// unknown standard and extended frames
+		{id: 257, data: []byte{1, 2}},
+		{id: 0x18FF5100, extended: true, data: []byte{1, 2}},
+		// short frame without any of the signals of the message
+		{id: 256, data: []byte{}},
+	}
+	for _, f := range frames {
+		c.handle(f, now)
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, c.Gather(acc))
+
+	expected := []cua.Metric{
+		testutil.MustMetric("canbus",
+			map[string]string{"interface": "can0", "message": "ENGINE"},
+			map[string]interface{}{"Speed": 100.0, "Temp": -10.0},
+			now),
+		testutil.MustMetric("canbus",
+			map[string]string{"interface": "can0", "message": "BMS"},
+			map[string]interface{}{"Mux": 1.0, "CellV1": 3.6, "CellV2": 3.7},
+			now),
+		testutil.MustMetric("canbus",
+			map[string]string{"interface": "can0", "message": "EEC1", "pgn": "61444", "source_address": "0"},
+			map[string]interface{}{"actual_engine_torque": 25.0, "engine_speed": 1500.0},
+			now),
+		testutil.MustMetric("canbus",
+			map[string]string{"interface": "can0", "message": "ET1", "pgn": "65262", "source_address": "23"},
+			map[string]interface{}{"engine_coolant_temperature": 85.0},
+			now),
+		testutil.MustMetric("canbus",
+			map[string]string{"interface": "can0", "message": "PROPA", "pgn": "61184", "source_address": "23"},
+			map[string]interface{}{"state": 3.0},
+			now),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+
+	// only the messages received since the last gather are added
+	acc.ClearMetrics()
+	require.NoError(t, c.Gather(acc))
+	require.Empty(t, acc.GetCUAMetrics())
+}
+
+func TestSignalRaw(t *testing.T) {
+	data := []byte{0x12, 0x34, 0x56, 0x78}
+
+	s := signal{Name: "big", StartBit: 7, Length: 16, ByteOrder: "big_endian"}
+	require.NoError(t, s.init())
+	raw, ok := s.raw(data)
+	require.True(t, ok)
+	require.Equal(t, uint64(0x1234), raw)
+
+	s = signal{Name: "little", StartBit: 8, Length: 16}
+	require.NoError(t, s.init())
+	raw, ok = s.raw(data)
+	require.True(t, ok)
+	require.Equal(t, uint64(0x5634), raw)
+
+	// 12 bits from the bit 4 of the first byte
+	s = signal{Name: "nibbles", StartBit: 4, Length: 12, Signed: true, Factor: 0.5}
+	require.NoError(t, s.init())
+	raw, ok = s.raw(data)
+	require.True(t, ok)
+	require.Equal(t, uint64(0x341), raw)
+	require.Equal(t, 416.5, s.value(raw))
+	require.Equal(t, -0.5, s.value(0xFFF))
+
+	s = signal{Name: "short", StartBit: 24, Length: 16}
+	require.NoError(t, s.init())
+	_, ok = s.raw(data)
+	require.False(t, ok)
+}
+
+func TestParseDBCInvalid(t *testing.T) {
+	_, err := parseDBC(strings.NewReader(" SG_ Speed : 0|16@1+ (0.1,0) [0|0] \"\" X\n"))
+	require.Error(t, err)
+	_, err = parseDBC(strings.NewReader("BO_ 256 ENGINE: 8 ECU\n SG_ Speed : 0|16@2+ (0.1,0) [0|0] \"\" X\n"))
+	require.Error(t, err)
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&CANBus{Interface: "can0"}).Init())
+	require.Error(t, (&CANBus{Interface: "can0", DBCFile: "testdata/missing.dbc"}).Init())
+	require.Error(t, (&CANBus{Interface: "can0", Messages: []message{{Name: "m", ID: 0x800}}}).Init())
+	require.Error(t, (&CANBus{Interface: "can0", Messages: []message{{Name: "m", ID: 1,
+		Signals: []signal{{Name: "s", StartBit: 60, Length: 8}}}}}).Init())
+	require.Error(t, (&CANBus{Interface: "can0", Messages: []message{{Name: "m", ID: 1,
+		Signals: []signal{{Name: "s", Length: 8, ByteOrder: "middle"}}}}}).Init())
+}
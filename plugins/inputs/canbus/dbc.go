@@ -0,0 +1,91 @@
+package canbus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	dbcMessage = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:`)
+	dbcSignal  = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+M?)?\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(\s*([^,\s]+)\s*,\s*([^)\s]+)\s*\)`)
+)
+
+// dbcExtended is set in the identifiers of the DBC files for the extended
+// (29 bits) CAN IDs.
+const dbcExtended = 0x80000000
+
+// parseDBC parses the messages and signals of a DBC file, the other
+// definitions are ignored.
+func parseDBC(r io.Reader) ([]message, error) {
+	var messages []message
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if m := dbcMessage.FindStringSubmatch(line); m != nil {
+			id, err := strconv.ParseUint(m[1], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid message ID %q", n, m[1])
+			}
+			msg := message{Name: m[2], ID: uint32(id)}
+			if msg.ID&dbcExtended != 0 {
+				msg.ID &^= dbcExtended
+				msg.Extended = true
+			}
+			messages = append(messages, msg)
+			continue
+		}
+		if !strings.HasPrefix(line, "SG_ ") {
+			continue
+		}
+		m := dbcSignal.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: invalid signal", n)
+		}
+		if len(messages) == 0 {
+			return nil, fmt.Errorf("line %d: signal %s outside of a message", n, m[1])
+		}
+
+		s := signal{Name: m[1], ByteOrder: "little_endian", Signed: m[6] == "-"}
+		if m[5] == "0" {
+			s.ByteOrder = "big_endian"
+		}
+		switch {
+		case m[2] == "M":
+			s.multiplexor = true
+		case m[2] != "":
+			// the nested multiplexors of the extended multiplexing are
+			// decoded as multiplexed signals
+			v, err := strconv.ParseUint(strings.TrimSuffix(m[2][1:], "M"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid multiplexer value %q", n, m[2])
+			}
+			s.multiplexed, s.muxValue = true, v
+		}
+		var err error
+		if s.StartBit, err = strconv.Atoi(m[3]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid start bit %q", n, m[3])
+		}
+		if s.Length, err = strconv.Atoi(m[4]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid length %q", n, m[4])
+		}
+		if s.Factor, err = strconv.ParseFloat(m[7], 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid factor %q", n, m[7])
+		}
+		if s.Offset, err = strconv.ParseFloat(m[8], 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid offset %q", n, m[8])
+		}
+		msg := &messages[len(messages)-1]
+		msg.Signals = append(msg.Signals, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,75 @@
+package canbus
+
+// j1939PGN returns the parameter group number and the source address of a
+// J1939 (extended) CAN ID.  The PDU specific byte of the PDU1 format groups
+// is the destination address, not part of the group number.
+func j1939PGN(id uint32) (pgn uint32, source uint8) {
+	pgn = id >> 8 & 0x3FFFF
+	if pf := pgn >> 8 & 0xFF; pf < 240 {
+		pgn &^= 0xFF
+	}
+	return pgn, uint8(id)
+}
+
+// spn is a suspect parameter of a parameter group, at a position in bytes
+// and bits numbered from 1 as in the J1939 documents.
+func spn(name string, byteStart, bitStart, length int, factor, offset float64) signal {
+	return signal{
+		Name:     name,
+		StartBit: (byteStart-1)*8 + bitStart - 1,
+		Length:   length,
+		Factor:   factor,
+		Offset:   offset,
+	}
+}
+
+// j1939Messages are the parameter groups decoded with j1939 enabled, the
+// common engine, vehicle and generator parameters.
+var j1939Messages = []message{
+	{Name: "EEC2", PGN: 61443, Signals: []signal{
+		spn("accelerator_pedal_position", 2, 1, 8, 0.4, 0),
+		spn("engine_load", 3, 1, 8, 1, 0),
+	}},
+	{Name: "EEC1", PGN: 61444, Signals: []signal{
+		spn("actual_engine_torque", 3, 1, 8, 1, -125),
+		spn("engine_speed", 4, 1, 16, 0.125, 0),
+	}},
+	{Name: "GTACP", PGN: 65029, Signals: []signal{
+		spn("generator_total_real_power", 1, 1, 32, 1, -2000000000),
+	}},
+	{Name: "GAAC", PGN: 65030, Signals: []signal{
+		spn("generator_voltage_line_line", 1, 1, 16, 1, 0),
+		spn("generator_voltage_line_neutral", 3, 1, 16, 1, 0),
+		spn("generator_frequency", 5, 1, 16, 1.0/128, 0),
+		spn("generator_current", 7, 1, 16, 1, 0),
+	}},
+	{Name: "HOURS", PGN: 65253, Signals: []signal{
+		spn("engine_total_hours", 1, 1, 32, 0.05, 0),
+	}},
+	{Name: "ET1", PGN: 65262, Signals: []signal{
+		spn("engine_coolant_temperature", 1, 1, 8, 1, -40),
+		spn("engine_fuel_temperature", 2, 1, 8, 1, -40),
+		spn("engine_oil_temperature", 3, 1, 16, 0.03125, -273),
+	}},
+	{Name: "EFL_P1", PGN: 65263, Signals: []signal{
+		spn("engine_oil_pressure", 4, 1, 8, 4, 0),
+		spn("engine_coolant_level", 8, 1, 8, 0.4, 0),
+	}},
+	{Name: "CCVS", PGN: 65265, Signals: []signal{
+		spn("wheel_based_vehicle_speed", 2, 1, 16, 1.0/256, 0),
+	}},
+	{Name: "LFE", PGN: 65266, Signals: []signal{
+		spn("engine_fuel_rate", 1, 1, 16, 0.05, 0),
+	}},
+	{Name: "AMB", PGN: 65269, Signals: []signal{
+		spn("barometric_pressure", 1, 1, 8, 0.5, 0),
+		spn("ambient_air_temperature", 4, 1, 16, 0.03125, -273),
+	}},
+	{Name: "VEP1", PGN: 65271, Signals: []signal{
+		spn("charging_system_potential", 3, 1, 16, 0.05, 0),
+		spn("battery_potential", 5, 1, 16, 0.05, 0),
+	}},
+	{Name: "DD", PGN: 65276, Signals: []signal{
+		spn("fuel_level", 2, 1, 8, 0.4, 0),
+	}},
+}
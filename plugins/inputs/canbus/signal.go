@@ -0,0 +1,153 @@
+package canbus
+
+import (
+	"fmt"
+)
+
+// signal is a value encoded in the bits of the data of a message, with the
+// conventions of the DBC files.
+type signal struct {
+	Name      string  `toml:"name"`
+	StartBit  int     `toml:"start_bit"`
+	Length    int     `toml:"length"`
+	ByteOrder string  `toml:"byte_order"`
+	Signed    bool    `toml:"signed"`
+	Factor    float64 `toml:"factor"`
+	Offset    float64 `toml:"offset"`
+
+	bigEndian bool
+	// multiplexor is set for the signal selecting the multiplexed signals
+	// decoded, multiplexed for the signals decoded when the multiplexor has
+	// the value muxValue.
+	multiplexor bool
+	multiplexed bool
+	muxValue    uint64
+}
+
+// message is a message of the bus, identified by its CAN ID or by its J1939
+// parameter group number.
+type message struct {
+	Name     string   `toml:"name"`
+	ID       uint32   `toml:"id"`
+	Extended bool     `toml:"extended"`
+	PGN      uint32   `toml:"pgn"`
+	Signals  []signal `toml:"signals"`
+
+	j1939 bool
+}
+
+func (s *signal) init() error {
+	if s.Name == "" {
+		return fmt.Errorf("signal without name")
+	}
+	switch s.ByteOrder {
+	case "", "little_endian":
+	case "big_endian":
+		s.bigEndian = true
+	default:
+		return fmt.Errorf("signal %s: invalid byte_order %q", s.Name, s.ByteOrder)
+	}
+	if s.Length < 1 || s.Length > 64 {
+		return fmt.Errorf("signal %s: invalid length %d", s.Name, s.Length)
+	}
+	if s.StartBit < 0 || s.StartBit > 63 {
+		return fmt.Errorf("signal %s: invalid start_bit %d", s.Name, s.StartBit)
+	}
+	if !s.bigEndian && s.StartBit+s.Length > 64 {
+		return fmt.Errorf("signal %s: bits beyond the 8 bytes of data", s.Name)
+	}
+	if s.Factor == 0 {
+		s.Factor = 1
+	}
+	return nil
+}
+
+// raw extracts the bits of the signal, false is returned when the data is
+// too short.  The start bit of the little endian (Intel) signals is their
+// least significant bit, the one of the big endian (Motorola) signals their
+// most significant bit, the bits are numbered from the least significant
+// bit of the first byte.
+func (s *signal) raw(data []byte) (uint64, bool) {
+	var v uint64
+	if !s.bigEndian {
+		if (s.StartBit+s.Length+7)/8 > len(data) {
+			return 0, false
+		}
+		for i := s.Length - 1; i >= 0; i-- {
+			pos := s.StartBit + i
+			v = v<<1 | uint64(data[pos/8]>>(pos%8)&1)
+		}
+		return v, true
+	}
+
+	pos := s.StartBit
+	for i := 0; i < s.Length; i++ {
+		if pos/8 >= len(data) {
+			return 0, false
+		}
+		v = v<<1 | uint64(data[pos/8]>>(pos%8)&1)
+		// the bit following the least significant bit of a byte is the most
+		// significant bit of the next byte
+		if pos%8 == 0 {
+			pos += 15
+		} else {
+			pos--
+		}
+	}
+	return v, true
+}
+
+// value returns the physical value of the raw value.
+func (s *signal) value(raw uint64) float64 {
+	if s.Signed && s.Length < 64 && raw&(1<<(s.Length-1)) != 0 {
+		return float64(int64(raw|^(1<<s.Length-1)))*s.Factor + s.Offset
+	}
+	if s.Signed {
+		return float64(int64(raw))*s.Factor + s.Offset
+	}
+	return float64(raw)*s.Factor + s.Offset
+}
+
+// available reports whether the raw value of a J1939 parameter is valid, the
+// highest values of the parameters are reserved for the errors and the
+// parameters not available.
+func (s *signal) available(raw uint64) bool {
+	switch {
+	case s.Length%8 == 0:
+		// 0xFB to 0xFF in the most significant byte
+		return raw>>(s.Length-8) < 0xFB
+	case s.Length <= 4:
+		// the highest values of the discrete parameters
+		return raw < 1<<s.Length-1
+	default:
+		return raw != 1<<s.Length-1
+	}
+}
+
+// decode decodes the signals of the data of a message into the fields, the
+// names of the signals not present are ignored.
+func (m *message) decode(data []byte, fields map[string]interface{}) {
+	var mux uint64
+	hasMux := false
+	for i := range m.Signals {
+		s := &m.Signals[i]
+		if !s.multiplexor {
+			continue
+		}
+		if raw, ok := s.raw(data); ok {
+			mux, hasMux = raw, true
+		}
+	}
+
+	for i := range m.Signals {
+		s := &m.Signals[i]
+		if s.multiplexed && (!hasMux || s.muxValue != mux) {
+			continue
+		}
+		raw, ok := s.raw(data)
+		if !ok || (m.j1939 && !s.available(raw)) {
+			continue
+		}
+		fields[s.Name] = s.value(raw)
+	}
+}
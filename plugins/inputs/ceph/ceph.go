@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -312,7 +311,7 @@ func flatten(data interface{}) []*metric {
 			}
 		}
 	default:
-		log.Printf("I! [inputs.ceph] ignoring unexpected type '%T' for value %v", val, val)
+		// the other values, like the strings, are not metrics
 	}
 
 	return metrics
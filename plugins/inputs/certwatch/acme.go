@@ -0,0 +1,86 @@
+package certwatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// acmeResolver is the storage of a certificate resolver of Traefik, the
+// whole file with Traefik v1.
+type acmeResolver struct {
+	Certificates []acmeCertificate `json:"certificates"`
+}
+
+type acmeCertificate struct {
+	Domain struct {
+		Main string   `json:"main"`
+		SANs []string `json:"sans"`
+	} `json:"domain"`
+	// PEM encoded, base64 encoded in the file
+	Certificate []byte `json:"certificate"`
+}
+
+// acmeCertificates returns the certificates of an ACME storage file of
+// Traefik.
+func acmeCertificates(path string) ([]certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("readfile: %w", err)
+	}
+	// the file is created empty before the first certificate is obtained
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var storage map[string]json.RawMessage
+	if err := json.Unmarshal(data, &storage); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	resolvers := map[string]acmeResolver{}
+	if _, ok := storage["Account"]; ok {
+		// Traefik v1 stores a single account and its certificates
+		var resolver acmeResolver
+		if err := json.Unmarshal(data, &resolver); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+		resolvers[""] = resolver
+	} else {
+		for name, raw := range storage {
+			var resolver acmeResolver
+			if err := json.Unmarshal(raw, &resolver); err != nil {
+				return nil, fmt.Errorf("unmarshal resolver %q: %w", name, err)
+			}
+			resolvers[name] = resolver
+		}
+	}
+
+	names := make([]string, 0, len(resolvers))
+	for name := range resolvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var certs []certificate
+	for _, name := range names {
+		for _, c := range resolvers[name].Certificates {
+			leaf, err := parseLeaf(c.Certificate)
+			if err != nil {
+				return certs, fmt.Errorf("certificate %q: %w", c.Domain.Main, err)
+			}
+			tags := map[string]string{
+				"source": "acme",
+				"name":   c.Domain.Main,
+				"path":   path,
+			}
+			if name != "" {
+				tags["resolver"] = name
+			}
+			certs = append(certs, certificate{cert: leaf, tags: tags})
+		}
+	}
+	return certs, nil
+}
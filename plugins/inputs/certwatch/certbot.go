@@ -0,0 +1,151 @@
+package certwatch
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var renewBeforeRe = regexp.MustCompile(`^(\d+)\s*(\w+)$`)
+
+// certbotCertificates returns the certificates of the live directory of a
+// certbot configuration directory.
+func certbotCertificates(dir string) ([]certificate, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, "live"))
+	if err != nil {
+		return nil, fmt.Errorf("readdir: %w", err)
+	}
+
+	var certs []certificate
+	var errs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		cert, err := certbotCertificate(dir, entry.Name())
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.Name(), err))
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	if len(errs) > 0 {
+		return certs, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return certs, nil
+}
+
+// certbotCertificate returns the current certificate of a lineage with its
+// renewal configuration.
+func certbotCertificate(dir, name string) (certificate, error) {
+	path := filepath.Join(dir, "live", name, "cert.pem")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return certificate{}, fmt.Errorf("readfile: %w", err)
+	}
+	leaf, err := parseLeaf(data)
+	if err != nil {
+		return certificate{}, err
+	}
+
+	cert := certificate{
+		cert: leaf,
+		tags: map[string]string{
+			"source": "certbot",
+			"name":   name,
+			"path":   dir,
+		},
+		fields: map[string]interface{}{},
+	}
+
+	// cert.pem links to the last version written to the archive
+	if info, err := os.Stat(path); err == nil {
+		cert.lastRenewal = info.ModTime()
+	}
+	versions, _ := filepath.Glob(filepath.Join(dir, "archive", name, "cert*.pem"))
+	cert.fields["versions"] = len(versions)
+
+	renewBefore, err := certbotRenewBefore(filepath.Join(dir, "renewal", name+".conf"))
+	switch {
+	case os.IsNotExist(err):
+		cert.fields["renewal_configured"] = false
+	case err != nil:
+		return certificate{}, err
+	default:
+		cert.fields["renewal_configured"] = true
+		cert.renewBefore = renewBefore
+	}
+
+	return cert, nil
+}
+
+// certbotRenewBefore reads the renew_before_expiry option of a renewal
+// configuration, zero when not set.
+func certbotRenewBefore(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err //nolint:wrapcheck // checked with os.IsNotExist
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := splitOption(scanner.Text())
+		if !ok || key != "renew_before_expiry" {
+			continue
+		}
+		d, err := parseRenewBefore(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+		return d, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("scan %s: %w", path, err)
+	}
+	return 0, nil
+}
+
+func splitOption(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+		return "", "", false
+	}
+	i := strings.Index(line, "=")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+}
+
+// parseRenewBefore parses the intervals of certbot, such as "30 days".
+func parseRenewBefore(value string) (time.Duration, error) {
+	m := renewBeforeRe.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, fmt.Errorf("invalid renew_before_expiry %q", value)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid renew_before_expiry %q: %w", value, err)
+	}
+
+	var unit time.Duration
+	switch strings.TrimSuffix(strings.ToLower(m[2]), "s") {
+	case "minute":
+		unit = time.Minute
+	case "hour":
+		unit = time.Hour
+	case "day":
+		unit = 24 * time.Hour
+	case "week":
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid renew_before_expiry %q: unknown unit", value)
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,264 @@
+// Package certwatch reports the expiry and the renewal status of the
+// certificates managed by ACME clients.
+package certwatch
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const (
+	defaultRenewBefore        = 30 * 24 * time.Hour
+	defaultServiceAccountPath = "/run/secrets/kubernetes.io/serviceaccount/token"
+
+	measurement = "certwatch"
+)
+
+// renewal status of a certificate
+const (
+	statusOK = iota
+	statusDue
+	statusExpired
+)
+
+var statusNames = []string{"ok", "due", "expired"}
+
+const sampleConfig = `
+  ## Certbot configuration directories, the certificates of the "live"
+  ## directory are reported with the renewal configuration of the "renewal"
+  ## directory.
+  # certbot_dirs = ["/etc/letsencrypt"]
+
+  ## ACME storage files of Traefik, v1 and v2 formats are supported.
+  # acme_files = ["/etc/traefik/acme.json"]
+
+  ## Time before the expiry a certificate is due for renewal, the
+  ## renew_before_expiry option of the certbot renewal configuration takes
+  ## priority.
+  # renew_before = "720h"
+
+  ## URL of the Kubernetes API to report the certificates of the TLS secrets,
+  ## disabled when empty.
+  # kubernetes_url = "https://kubernetes.default.svc"
+
+  ## Namespace of the TLS secrets. Set to "" to use all namespaces.
+  # namespace = ""
+
+  ## Use bearer token for authorization. ('bearer_token' takes priority)
+  ## If both of these are empty, we'll use the default serviceaccount:
+  ## at: /run/secrets/kubernetes.io/serviceaccount/token
+  # bearer_token = "/path/to/bearer/token"
+  ## OR
+  # bearer_token_string = "abc_123"
+
+  ## Set response_timeout (default 5 seconds)
+  # response_timeout = "5s"
+
+  ## Optional TLS Config for the Kubernetes API
+  # tls_ca = "/path/to/cafile"
+  # tls_cert = "/path/to/certfile"
+  # tls_key = "/path/to/keyfile"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// CertWatch holds the configuration of the plugin.
+type CertWatch struct {
+	CertbotDirs []string          `toml:"certbot_dirs"`
+	AcmeFiles   []string          `toml:"acme_files"`
+	RenewBefore internal.Duration `toml:"renew_before"`
+
+	KubernetesURL     string            `toml:"kubernetes_url"`
+	Namespace         string            `toml:"namespace"`
+	BearerToken       string            `toml:"bearer_token"`
+	BearerTokenString string            `toml:"bearer_token_string"`
+	ResponseTimeout   internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	Log cua.Logger `toml:"-"`
+
+	client *http.Client
+}
+
+// certificate is a certificate found by one of the sources, with the tags
+// identifying it.
+type certificate struct {
+	cert *x509.Certificate
+	tags map[string]string
+
+	// renewBefore overrides the renew_before option when not zero
+	renewBefore time.Duration
+	// lastRenewal is the time the certificate was written when known, the
+	// start of its validity otherwise
+	lastRenewal time.Time
+	// fields specific to the source
+	fields map[string]interface{}
+}
+
+// Description returns description of the plugin.
+func (c *CertWatch) Description() string {
+	return "Reports the expiry and renewal status of ACME certificates"
+}
+
+// SampleConfig returns configuration sample for the plugin.
+func (c *CertWatch) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CertWatch) Init() error {
+	if c.RenewBefore.Duration <= 0 {
+		c.RenewBefore.Duration = defaultRenewBefore
+	}
+
+	if c.KubernetesURL == "" {
+		return nil
+	}
+
+	// If neither are provided, use the default service account.
+	if c.BearerToken == "" && c.BearerTokenString == "" {
+		c.BearerToken = defaultServiceAccountPath
+	}
+
+	if c.BearerToken != "" {
+		token, err := os.ReadFile(c.BearerToken)
+		if err != nil {
+			return fmt.Errorf("readfile: %w", err)
+		}
+		c.BearerTokenString = strings.TrimSpace(string(token))
+	}
+
+	tlsCfg, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+	c.client = &http.Client{
+		Transport: &http.Transport{
+			TLSHandshakeTimeout: 5 * time.Second,
+			TLSClientConfig:     tlsCfg,
+		},
+		Timeout: c.ResponseTimeout.Duration,
+	}
+
+	return nil
+}
+
+// Gather adds metrics into the accumulator.
+func (c *CertWatch) Gather(acc cua.Accumulator) error {
+	now := time.Now()
+
+	for _, dir := range c.CertbotDirs {
+		certs, err := certbotCertificates(dir)
+		if err != nil {
+			acc.AddError(fmt.Errorf("certbot %q: %w", dir, err))
+		}
+		c.addCertificates(acc, certs, now)
+	}
+
+	for _, file := range c.AcmeFiles {
+		certs, err := acmeCertificates(file)
+		if err != nil {
+			acc.AddError(fmt.Errorf("acme %q: %w", file, err))
+		}
+		c.addCertificates(acc, certs, now)
+	}
+
+	if c.client != nil {
+		certs, err := c.kubernetesCertificates(context.Background())
+		if err != nil {
+			acc.AddError(fmt.Errorf("kubernetes: %w", err))
+		}
+		c.addCertificates(acc, certs, now)
+	}
+
+	return nil
+}
+
+func (c *CertWatch) addCertificates(acc cua.Accumulator, certs []certificate, now time.Time) {
+	for _, cert := range certs {
+		renewBefore := c.RenewBefore.Duration
+		if cert.renewBefore > 0 {
+			renewBefore = cert.renewBefore
+		}
+
+		status := statusOK
+		switch {
+		case !now.Before(cert.cert.NotAfter):
+			status = statusExpired
+		case cert.cert.NotAfter.Sub(now) <= renewBefore:
+			status = statusDue
+		}
+
+		lastRenewal := cert.lastRenewal
+		if lastRenewal.IsZero() {
+			lastRenewal = cert.cert.NotBefore
+		}
+
+		expiry := cert.cert.NotAfter.Sub(now)
+		fields := map[string]interface{}{
+			"expiry":              int64(expiry.Seconds()),
+			"days_to_expiry":      expiry.Hours() / 24,
+			"startdate":           cert.cert.NotBefore.Unix(),
+			"enddate":             cert.cert.NotAfter.Unix(),
+			"last_renewal":        lastRenewal.Unix(),
+			"renewal_status":      statusNames[status],
+			"renewal_status_code": status,
+		}
+		for k, v := range cert.fields {
+			fields[k] = v
+		}
+
+		tags := map[string]string{
+			"common_name":        cert.cert.Subject.CommonName,
+			"issuer_common_name": cert.cert.Issuer.CommonName,
+			"san":                strings.Join(cert.cert.DNSNames, ","),
+		}
+		for k, v := range cert.tags {
+			tags[k] = v
+		}
+
+		acc.AddFields(measurement, fields, tags)
+	}
+}
+
+// parseLeaf returns the first certificate of PEM encoded data, the leaf of
+// a chain.
+func parseLeaf(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(bytes.TrimSpace(data))
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse cert: %w", err)
+		}
+		return cert, nil
+	}
+}
+
+func init() {
+	inputs.Add("certwatch", func() cua.Input {
+		return &CertWatch{
+			CertbotDirs:     []string{},
+			AcmeFiles:       []string{},
+			RenewBefore:     internal.Duration{Duration: defaultRenewBefore},
+			ResponseTimeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package certwatch
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+)
+
+// generateCert returns a PEM encoded certificate of the domain expiring in
+// the given duration.
+func generateCert(t *testing.T, domain string, expiresIn time.Duration) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	notAfter := time.Now().Add(expiresIn)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain, "www." + domain},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+func gather(t *testing.T, c *CertWatch) *testutil.Accumulator {
+	c.Log = testutil.Logger{}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	return &acc
+}
+
+func findMetric(t *testing.T, acc *testutil.Accumulator, name string) *testutil.Metric {
+	for _, m := range acc.Metrics {
+		if m.Tags["name"] == name {
+			return m
+		}
+	}
+	require.FailNow(t, "metric not found", name)
+	return nil
+}
+
+func TestCertbot(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "live", "README"), []byte("certificates\n"))
+
+	// renewed recently, with the default renewal configuration
+	cert := generateCert(t, "example.com", 80*24*time.Hour)
+	writeFile(t, filepath.Join(dir, "archive", "example.com", "cert1.pem"), []byte("old"))
+	writeFile(t, filepath.Join(dir, "archive", "example.com", "cert2.pem"), cert)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "live", "example.com"), 0755))
+	require.NoError(t, os.Symlink("../../archive/example.com/cert2.pem", filepath.Join(dir, "live", "example.com", "cert.pem")))
+	writeFile(t, filepath.Join(dir, "renewal", "example.com.conf"), []byte(`# renew_before_expiry = 30 days
+version = 1.12.0
+archive_dir = /etc/letsencrypt/archive/example.com
+
+[renewalparams]
+authenticator = webroot
+`))
+
+	// renewal failing since 10 days with a renewal window of 2 weeks
+	writeFile(t, filepath.Join(dir, "live", "example.org", "cert.pem"), generateCert(t, "example.org", 4*24*time.Hour))
+	writeFile(t, filepath.Join(dir, "renewal", "example.org.conf"), []byte("renew_before_expiry = 2 weeks\n"))
+
+	// not renewed by certbot
+	writeFile(t, filepath.Join(dir, "live", "example.net", "cert.pem"), generateCert(t, "example.net", -time.Hour))
+
+	acc := gather(t, &CertWatch{CertbotDirs: []string{dir}})
+	require.Len(t, acc.Metrics, 3)
+
+	m := findMetric(t, acc, "example.com")
+	require.Equal(t, "certbot", m.Tags["source"])
+	require.Equal(t, dir, m.Tags["path"])
+	require.Equal(t, "example.com", m.Tags["common_name"])
+	require.Equal(t, "example.com,www.example.com", m.Tags["san"])
+	require.Equal(t, "ok", m.Fields["renewal_status"])
+	require.Equal(t, statusOK, m.Fields["renewal_status_code"])
+	require.Equal(t, true, m.Fields["renewal_configured"])
+	require.Equal(t, 2, m.Fields["versions"])
+	require.InDelta(t, 80, m.Fields["days_to_expiry"], 0.1)
+	require.InDelta(t, time.Now().Unix(), m.Fields["last_renewal"], 60)
+
+	m = findMetric(t, acc, "example.org")
+	require.Equal(t, "due", m.Fields["renewal_status"])
+	require.Equal(t, statusDue, m.Fields["renewal_status_code"])
+
+	m = findMetric(t, acc, "example.net")
+	require.Equal(t, "expired", m.Fields["renewal_status"])
+	require.Equal(t, false, m.Fields["renewal_configured"])
+	require.Less(t, m.Fields["expiry"].(int64), int64(0))
+}
+
+func TestCertbotErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "live", "example.com", "cert.pem"), generateCert(t, "example.com", 80*24*time.Hour))
+	writeFile(t, filepath.Join(dir, "live", "broken", "cert.pem"), []byte("garbage"))
+
+	acc := gather(t, &CertWatch{CertbotDirs: []string{dir, filepath.Join(dir, "missing")}})
+	require.Len(t, acc.Metrics, 1)
+	require.Len(t, acc.Errors, 2)
+}
+
+func TestParseRenewBefore(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"30 days", 30 * 24 * time.Hour},
+		{"1 week", 7 * 24 * time.Hour},
+		{"12 hours", 12 * time.Hour},
+		{"1day", 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		d, err := parseRenewBefore(tt.value)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, d, tt.value)
+	}
+
+	_, err := parseRenewBefore("soon")
+	require.Error(t, err)
+	_, err = parseRenewBefore("3 fortnights")
+	require.Error(t, err)
+}
+
+func TestAcmeV2(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "acme.json")
+	writeFile(t, file, []byte(fmt.Sprintf(`{
+  "letsencrypt": {
+    "Account": {"Email": "admin@example.com"},
+    "Certificates": [
+      {"domain": {"main": "example.com", "sans": ["www.example.com"]}, "certificate": %q, "key": "", "Store": "default"}
+    ]
+  },
+  "staging": {
+    "Account": {"Email": "admin@example.com"},
+    "Certificates": [
+      {"domain": {"main": "example.org"}, "certificate": %q, "key": "", "Store": "default"}
+    ]
+  }
+}`,
+		base64.StdEncoding.EncodeToString(generateCert(t, "example.com", 60*24*time.Hour)),
+		base64.StdEncoding.EncodeToString(generateCert(t, "example.org", 20*24*time.Hour)),
+	)))
+
+	acc := gather(t, &CertWatch{AcmeFiles: []string{file}})
+	require.Len(t, acc.Metrics, 2)
+
+	m := findMetric(t, acc, "example.com")
+	require.Equal(t, map[string]string{
+		"source":             "acme",
+		"name":               "example.com",
+		"path":               file,
+		"resolver":           "letsencrypt",
+		"common_name":        "example.com",
+		"issuer_common_name": "example.com",
+		"san":                "example.com,www.example.com",
+	}, m.Tags)
+	require.Equal(t, "ok", m.Fields["renewal_status"])
+
+	m = findMetric(t, acc, "example.org")
+	require.Equal(t, "staging", m.Tags["resolver"])
+	require.Equal(t, "due", m.Fields["renewal_status"])
+}
+
+func TestAcmeV1(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "acme.json")
+	writeFile(t, file, []byte(fmt.Sprintf(`{
+  "Account": {"Email": "admin@example.com"},
+  "Certificates": [
+    {"Domain": {"Main": "example.com", "SANs": null}, "Certificate": %q, "Key": ""}
+  ],
+  "HTTPChallenge": {}
+}`,
+		base64.StdEncoding.EncodeToString(generateCert(t, "example.com", 60*24*time.Hour)),
+	)))
+
+	acc := gather(t, &CertWatch{AcmeFiles: []string{file}})
+	require.Len(t, acc.Metrics, 1)
+	m := findMetric(t, acc, "example.com")
+	require.NotContains(t, m.Tags, "resolver")
+}
+
+func TestAcmeEmpty(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "acme.json")
+	writeFile(t, file, nil)
+
+	acc := gather(t, &CertWatch{AcmeFiles: []string{file}})
+	require.Empty(t, acc.Metrics)
+	require.Empty(t, acc.Errors)
+}
+
+func TestKubernetes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/namespaces/default/secrets", r.URL.Path)
+		require.Equal(t, "type=kubernetes.io/tls", r.URL.Query().Get("fieldSelector"))
+		require.Equal(t, "Bearer abc123", r.Header.Get("Authorization"))
+		fmt.Fprintf(w, `{"kind": "SecretList", "items": [
+  {"metadata": {"name": "web-tls", "namespace": "default", "annotations": {%q: "web"}},
+   "type": "kubernetes.io/tls", "data": {"tls.crt": %q, "tls.key": ""}},
+  {"metadata": {"name": "broken-tls", "namespace": "default"},
+   "type": "kubernetes.io/tls", "data": {"tls.crt": %q}}
+]}`,
+			certManagerCertificate,
+			base64.StdEncoding.EncodeToString(generateCert(t, "example.com", 40*24*time.Hour)),
+			base64.StdEncoding.EncodeToString([]byte("garbage")),
+		)
+	}))
+	defer ts.Close()
+
+	c := &CertWatch{
+		KubernetesURL:     ts.URL,
+		Namespace:         "default",
+		BearerTokenString: "abc123",
+		RenewBefore:       internal.Duration{Duration: 50 * 24 * time.Hour},
+		ResponseTimeout:   internal.Duration{Duration: 5 * time.Second},
+	}
+	acc := gather(t, c)
+	require.Len(t, acc.Metrics, 1)
+
+	m := findMetric(t, acc, "web-tls")
+	require.Equal(t, "kubernetes", m.Tags["source"])
+	require.Equal(t, "default", m.Tags["namespace"])
+	require.Equal(t, "web", m.Tags["certificate"])
+	require.Equal(t, "due", m.Fields["renewal_status"])
+}
+
+func TestKubernetesError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/secrets", r.URL.Path)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	acc := gather(t, &CertWatch{KubernetesURL: ts.URL, BearerTokenString: "abc123"})
+	require.Empty(t, acc.Metrics)
+	require.Len(t, acc.Errors, 1)
+}
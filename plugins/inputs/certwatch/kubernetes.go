@@ -0,0 +1,104 @@
+package certwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	secretTypeTLS = "kubernetes.io/tls"
+	secretKeyCert = "tls.crt"
+
+	// annotation set by cert-manager on the secrets it issues
+	certManagerCertificate = "cert-manager.io/certificate-name"
+)
+
+type secretList struct {
+	Items []secret `json:"items"`
+}
+
+type secret struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Type string `json:"type"`
+	// base64 encoded in the response
+	Data map[string][]byte `json:"data"`
+}
+
+// secretsURL returns the URL listing the TLS secrets of the namespace, or of
+// all the namespaces.
+func secretsURL(baseURL, namespace string) string {
+	path := "/api/v1/secrets"
+	if namespace != "" {
+		path = "/api/v1/namespaces/" + url.PathEscape(namespace) + "/secrets"
+	}
+	query := url.Values{"fieldSelector": []string{"type=" + secretTypeTLS}}
+	return strings.TrimSuffix(baseURL, "/") + path + "?" + query.Encode()
+}
+
+func (c *CertWatch) getSecrets(ctx context.Context) ([]secret, error) {
+	u := secretsURL(c.KubernetesURL, c.Namespace)
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http new req (%s): %w", u, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.BearerTokenString)
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making HTTP request to %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+
+	var list secretList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	return list.Items, nil
+}
+
+// kubernetesCertificates returns the certificates of the TLS secrets.
+func (c *CertWatch) kubernetesCertificates(ctx context.Context) ([]certificate, error) {
+	secrets, err := c.getSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []certificate
+	for _, secret := range secrets {
+		if secret.Type != secretTypeTLS {
+			continue
+		}
+		data := secret.Data[secretKeyCert]
+		if len(data) == 0 {
+			continue
+		}
+		leaf, err := parseLeaf(data)
+		if err != nil {
+			c.Log.Errorf("Secret %s/%s: %v", secret.Metadata.Namespace, secret.Metadata.Name, err)
+			continue
+		}
+
+		tags := map[string]string{
+			"source":    "kubernetes",
+			"name":      secret.Metadata.Name,
+			"namespace": secret.Metadata.Namespace,
+		}
+		if name, ok := secret.Metadata.Annotations[certManagerCertificate]; ok {
+			tags["certificate"] = name
+		}
+		certs = append(certs, certificate{cert: leaf, tags: tags})
+	}
+	return certs, nil
+}
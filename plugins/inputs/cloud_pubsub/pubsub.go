@@ -11,6 +11,7 @@ import (
 	"cloud.google.com/go/pubsub"
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/internal/cgroup"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
 	"golang.org/x/oauth2/google"
@@ -43,6 +44,8 @@ type PubSub struct {
 
 	Base64Data bool `toml:"base64_data"`
 
+	OrderingKeyTag string `toml:"ordering_key_tag"`
+
 	Log cua.Logger
 
 	sub     subscription
@@ -196,6 +199,12 @@ func (ps *PubSub) onMessage(ctx context.Context, msg message) error {
 		return nil
 	}
 
+	if ps.OrderingKeyTag != "" && msg.OrderingKey() != "" {
+		for _, m := range metrics {
+			m.AddTag(ps.OrderingKeyTag, msg.OrderingKey())
+		}
+	}
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -276,7 +285,7 @@ func (ps *PubSub) getGCPSubscription(subID string) (subscription, error) {
 	}
 	s := client.Subscription(subID)
 	s.ReceiveSettings = pubsub.ReceiveSettings{
-		NumGoroutines:          ps.MaxReceiverGoRoutines,
+		NumGoroutines:          ps.receiverGoRoutines(),
 		MaxExtension:           ps.MaxExtension.Duration,
 		MaxOutstandingMessages: ps.MaxOutstandingMessages,
 		MaxOutstandingBytes:    ps.MaxOutstandingBytes,
@@ -284,6 +293,15 @@ func (ps *PubSub) getGCPSubscription(subID string) (subscription, error) {
 	return &gcpSubscription{s}, nil
 }
 
+// receiverGoRoutines returns max_receiver_go_routines, or the number of CPUs
+// available to the agent when it is not set.
+func (ps *PubSub) receiverGoRoutines() int {
+	if ps.MaxReceiverGoRoutines < 1 {
+		return cgroup.AvailableCPUs()
+	}
+	return ps.MaxReceiverGoRoutines
+}
+
 func init() {
 	inputs.Add("cloud_pubsub", func() cua.Input {
 		ps := &PubSub{
@@ -357,12 +375,19 @@ const sampleConfig = `
 
   ## Optional. Max number of goroutines a PubSub Subscription receiver can spawn
   ## to pull messages from PubSub concurrently. This limit applies to each
-  ## subscription separately and is treated as the PubSub default if less than
-  ## 1. Note this setting does not limit the number of messages that can be
+  ## subscription separately and defaults to the number of CPUs available to
+  ## the agent (the cgroup CPU limit in a container) if less than 1. Note this
+  ## setting does not limit the number of messages that can be
   ## processed concurrently (use "max_outstanding_messages" instead).
   # max_receiver_go_routines = 0
 
   ## Optional. If true, the agent will attempt to base64 decode the 
   ## PubSub message data before parsing
   # base64_data = false
+
+  ## Optional. Name of a tag to add the ordering key of the PubSub message
+  ## to. Messages published without an ordering key are not tagged.
+  ## Ordered delivery itself is a property of the subscription, see
+  ## https://cloud.google.com/pubsub/docs/ordering
+  # ordering_key_tag = ""
 `
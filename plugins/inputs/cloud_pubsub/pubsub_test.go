@@ -5,6 +5,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/circonus-labs/circonus-unified-agent/internal/cgroup"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
@@ -103,6 +104,45 @@ func TestRunBase64(t *testing.T) {
 	validateTestInfluxMetric(t, metric)
 }
 
+func TestRunOrderingKeyTag(t *testing.T) {
+	subID := "sub-run-ordering-key"
+
+	testParser, _ := parsers.NewInfluxParser()
+
+	sub := &stubSub{
+		id:       subID,
+		messages: make(chan *testMsg, 100),
+	}
+	sub.receiver = testMessagesReceive(sub)
+
+	ps := &PubSub{
+		Log:                    testutil.Logger{},
+		parser:                 testParser,
+		stubSub:                func() subscription { return sub },
+		Project:                "projectIDontMatterForTests",
+		Subscription:           subID,
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		OrderingKeyTag:         "ordering_key",
+	}
+
+	acc := &testutil.Accumulator{}
+	if err := ps.Start(acc); err != nil {
+		t.Fatalf("test PubSub failed to start: %s", err)
+	}
+	defer ps.Stop()
+
+	sub.messages <- &testMsg{
+		value:       msgInflux,
+		orderingKey: "server01",
+		tracker:     &testTracker{},
+	}
+
+	acc.Wait(1)
+	tag, ok := acc.Metrics[0].Tags["ordering_key"]
+	assert.True(t, ok)
+	assert.Equal(t, "server01", tag)
+}
+
 func TestRunInvalidMessages(t *testing.T) {
 	subID := "sub-invalid-messages"
 
@@ -231,6 +271,14 @@ func TestRunErrorInSubscriber(t *testing.T) {
 	assert.Regexp(t, fakeErrStr, acc.Errors[0])
 }
 
+func TestReceiverGoRoutines(t *testing.T) {
+	ps := &PubSub{}
+	assert.Equal(t, cgroup.AvailableCPUs(), ps.receiverGoRoutines())
+
+	ps.MaxReceiverGoRoutines = 3
+	assert.Equal(t, 3, ps.receiverGoRoutines())
+}
+
 func validateTestInfluxMetric(t *testing.T, m *testutil.Metric) {
 	assert.Equal(t, "cpu_load_short", m.Measurement)
 	assert.Equal(t, "server01", m.Tags["host"])
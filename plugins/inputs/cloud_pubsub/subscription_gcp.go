@@ -20,6 +20,7 @@ type (
 		Data() []byte
 		Attributes() map[string]string
 		PublishTime() time.Time
+		OrderingKey() string
 	}
 
 	gcpSubscription struct {
@@ -67,3 +68,7 @@ func (env *gcpMessage) Attributes() map[string]string {
 func (env *gcpMessage) PublishTime() time.Time {
 	return env.msg.PublishTime
 }
+
+func (env *gcpMessage) OrderingKey() string {
+	return env.msg.OrderingKey
+}
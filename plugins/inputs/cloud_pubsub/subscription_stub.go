@@ -47,6 +47,7 @@ type testMsg struct {
 	value       string
 	attributes  map[string]string
 	publishTime time.Time
+	orderingKey string
 
 	tracker *testTracker
 }
@@ -75,6 +76,10 @@ func (tm *testMsg) PublishTime() time.Time {
 	return tm.publishTime
 }
 
+func (tm *testMsg) OrderingKey() string {
+	return tm.orderingKey
+}
+
 type testTracker struct {
 	sync.Mutex
 	*sync.Cond
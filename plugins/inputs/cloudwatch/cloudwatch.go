@@ -38,6 +38,7 @@ type CloudWatch struct {
 	Period         config.Duration `toml:"period"`
 	Delay          config.Duration `toml:"delay"`
 	Namespace      string          `toml:"namespace"`
+	Namespaces     []string        `toml:"namespaces"`
 	Metrics        []*Metric       `toml:"metrics"`
 	CacheTTL       config.Duration `toml:"cache_ttl"`
 	RateLimit      int             `toml:"ratelimit"`
@@ -49,6 +50,7 @@ type CloudWatch struct {
 	statFilter      filter.Filter
 	metricCache     *metricCache
 	queryDimensions map[string]*map[string]string
+	queryNamespaces map[string]string
 	windowStart     time.Time
 	windowEnd       time.Time
 }
@@ -134,8 +136,10 @@ func (c *CloudWatch) SampleConfig() string {
   ## Configure the TTL for the internal cache of metrics.
   # cache_ttl = "1h"
 
-  ## Metric Statistic Namespace (required)
-  namespace = "AWS/ELB"
+  ## Metric Statistic Namespaces (required)
+  namespaces = ["AWS/ELB"]
+  ## Single metric namespace; Deprecated - use 'namespaces'
+  # namespace = "AWS/ELB"
 
   ## Maximum requests per second. Note that the global default AWS rate limit is
   ## 50 reqs/sec, so if you define multiple namespaces, these should add up to a
@@ -312,11 +316,13 @@ func getFilteredMetrics(c *CloudWatch) ([]filteredMetric, error) {
 					}
 				}
 				for _, name := range m.MetricNames {
-					metrics = append(metrics, &cloudwatch.Metric{
-						Namespace:  aws.String(c.Namespace),
-						MetricName: aws.String(name),
-						Dimensions: dimensions,
-					})
+					for _, namespace := range c.getNamespaces() {
+						metrics = append(metrics, &cloudwatch.Metric{
+							Namespace:  aws.String(namespace),
+							MetricName: aws.String(name),
+							Dimensions: dimensions,
+						})
+					}
 				}
 			} else {
 				allMetrics, err := c.fetchNamespaceMetrics()
@@ -327,7 +333,7 @@ func getFilteredMetrics(c *CloudWatch) ([]filteredMetric, error) {
 					for _, metric := range allMetrics {
 						if isSelected(name, metric, m.Dimensions) {
 							metrics = append(metrics, &cloudwatch.Metric{
-								Namespace:  aws.String(c.Namespace),
+								Namespace:  metric.Namespace,
 								MetricName: aws.String(name),
 								Dimensions: metric.Dimensions,
 							})
@@ -373,12 +379,10 @@ func getFilteredMetrics(c *CloudWatch) ([]filteredMetric, error) {
 	return fMetrics, nil
 }
 
-// fetchNamespaceMetrics retrieves available metrics for a given CloudWatch namespace.
+// fetchNamespaceMetrics retrieves available metrics for the configured CloudWatch namespaces.
 func (c *CloudWatch) fetchNamespaceMetrics() ([]*cloudwatch.Metric, error) {
 	metrics := []*cloudwatch.Metric{}
 
-	var token *string
-	var params *cloudwatch.ListMetricsInput
 	var recentlyActive *string
 
 	switch c.RecentlyActive {
@@ -387,30 +391,48 @@ func (c *CloudWatch) fetchNamespaceMetrics() ([]*cloudwatch.Metric, error) {
 	default:
 		recentlyActive = nil
 	}
-	params = &cloudwatch.ListMetricsInput{
-		Namespace:      aws.String(c.Namespace),
-		Dimensions:     []*cloudwatch.DimensionFilter{},
-		NextToken:      token,
-		MetricName:     nil,
-		RecentlyActive: recentlyActive,
-	}
-	for {
-		resp, err := c.client.ListMetrics(params)
-		if err != nil {
-			return nil, fmt.Errorf("list metrics: %w", err)
-		}
 
-		metrics = append(metrics, resp.Metrics...)
-		if resp.NextToken == nil {
-			break
+	for _, namespace := range c.getNamespaces() {
+		params := &cloudwatch.ListMetricsInput{
+			Namespace:      aws.String(namespace),
+			Dimensions:     []*cloudwatch.DimensionFilter{},
+			NextToken:      nil,
+			MetricName:     nil,
+			RecentlyActive: recentlyActive,
 		}
+		for {
+			resp, err := c.client.ListMetrics(params)
+			if err != nil {
+				return nil, fmt.Errorf("list metrics (%s): %w", namespace, err)
+			}
 
-		params.NextToken = resp.NextToken
+			metrics = append(metrics, resp.Metrics...)
+			if resp.NextToken == nil {
+				break
+			}
+
+			params.NextToken = resp.NextToken
+		}
 	}
 
 	return metrics, nil
 }
 
+// getNamespaces returns the configured namespaces, including the deprecated
+// single 'namespace' setting, without duplicates.
+func (c *CloudWatch) getNamespaces() []string {
+	namespaces := make([]string, 0, len(c.Namespaces)+1)
+	seen := map[string]bool{}
+	for _, namespace := range append([]string{c.Namespace}, c.Namespaces...) {
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces
+}
+
 func (c *CloudWatch) updateWindow(relativeTo time.Time) {
 	windowEnd := relativeTo.Add(-time.Duration(c.Delay))
 
@@ -432,12 +454,16 @@ func (c *CloudWatch) getDataQueries(filteredMetrics []filteredMetric) []*cloudwa
 	}
 
 	c.queryDimensions = map[string]*map[string]string{}
+	c.queryNamespaces = map[string]string{}
 
 	dataQueries := []*cloudwatch.MetricDataQuery{}
 	for i, filtered := range filteredMetrics {
 		for j, metric := range filtered.metrics {
 			id := strconv.Itoa(j) + "_" + strconv.Itoa(i)
 			dimension := ctod(metric.Dimensions)
+			for _, stat := range []string{"average", "maximum", "minimum", "sum", "sample_count"} {
+				c.queryNamespaces[stat+"_"+id] = aws.StringValue(metric.Namespace)
+			}
 			if filtered.statFilter.Match("average") {
 				c.queryDimensions["average_"+id] = dimension
 				dataQueries = append(dataQueries, &cloudwatch.MetricDataQuery{
@@ -553,8 +579,8 @@ func (c *CloudWatch) aggregateMetrics(
 	metricDataResults []*cloudwatch.MetricDataResult,
 ) error {
 	var (
-		grouper   = metric.NewSeriesGrouper()
-		namespace = sanitizeMeasurement(c.Namespace)
+		grouper    = metric.NewSeriesGrouper()
+		namespaces = map[string]bool{}
 	)
 
 	for _, result := range metricDataResults {
@@ -565,6 +591,9 @@ func (c *CloudWatch) aggregateMetrics(
 		}
 		tags["region"] = c.Region
 
+		namespace := sanitizeMeasurement(c.queryNamespaces[*result.Id])
+		namespaces[namespace] = true
+
 		if len(result.Values) == 0 {
 			c.Log.Warnf("no values from AWS (sending null sample) for %s %s %v", namespace, *result.Label, tags)
 			_ = grouper.Add(namespace, tags, time.Now().UTC(), *result.Label, nil)
@@ -579,7 +608,9 @@ func (c *CloudWatch) aggregateMetrics(
 	}
 
 	// add configured period for dashboard calculations
-	acc.AddFields(namespace, map[string]interface{}{"period": time.Duration(c.Period).Seconds()}, nil, time.Now())
+	for namespace := range namespaces {
+		acc.AddFields(namespace, map[string]interface{}{"period": time.Duration(c.Period).Seconds()}, nil, time.Now())
+	}
 
 	return nil
 }
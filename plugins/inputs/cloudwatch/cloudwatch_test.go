@@ -109,6 +109,7 @@ func TestGather(t *testing.T) {
 		Delay:     internalDuration,
 		Period:    internalDuration,
 		RateLimit: 200,
+		Log:       testutil.Logger{},
 	}
 
 	var acc testutil.Accumulator
@@ -131,6 +132,35 @@ func TestGather(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, "cloudwatch_aws_elb", fields, tags)
 }
 
+func TestGatherMultipleNamespaces(t *testing.T) {
+	duration, _ := time.ParseDuration("1m")
+	internalDuration := config.Duration(duration)
+	c := &CloudWatch{
+		Region:     "us-east-1",
+		Namespace:  "AWS/ELB",
+		Namespaces: []string{"AWS/EC2", "AWS/ELB"},
+		Delay:      internalDuration,
+		Period:     internalDuration,
+		RateLimit:  200,
+		Log:        testutil.Logger{},
+	}
+	require.Equal(t, []string{"AWS/ELB", "AWS/EC2"}, c.getNamespaces())
+
+	var acc testutil.Accumulator
+	c.client = &mockGatherCloudWatchClient{}
+
+	metrics, err := c.fetchNamespaceMetrics()
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, "AWS/ELB", *metrics[0].Namespace)
+	require.Equal(t, "AWS/EC2", *metrics[1].Namespace)
+
+	require.NoError(t, acc.GatherError(c.Gather))
+	require.Equal(t, "AWS/ELB", c.queryNamespaces["average_0_0"])
+	require.Equal(t, "AWS/EC2", c.queryNamespaces["average_1_0"])
+	require.True(t, acc.HasMeasurement("cloudwatch_aws_elb"))
+}
+
 type mockSelectMetricsCloudWatchClient struct{}
 
 func (m *mockSelectMetricsCloudWatchClient) ListMetrics(params *cloudwatch.ListMetricsInput) (*cloudwatch.ListMetricsOutput, error) {
@@ -0,0 +1,181 @@
+package collectd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	collectdparser "github.com/circonus-labs/circonus-unified-agent/plugins/parsers/collectd"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+const (
+	defaultServiceAddress = ":25826"
+	// maxPacketSize is the largest datagram sent by collectd's network plugin.
+	maxPacketSize = 65535
+)
+
+const sampleConfig = `
+  ## Address and port to listen on for collectd network packets. Multicast
+  ## group addresses are joined on all interfaces.
+  ##   ex: service_address = "239.192.74.66:25826"
+  service_address = ":25826"
+
+  ## Authentication file for cryptographic security levels, in the format of
+  ## collectd's AuthFile, one "username: password" entry per line.
+  # collectd_auth_file = "/etc/collectd/auth_file"
+
+  ## Minimum security level accepted, one of "none" (default), "sign" or
+  ## "encrypt". Packets below this level are dropped.
+  # collectd_security_level = "none"
+
+  ## Paths of types.db specifications, used to name the values of each type.
+  # collectd_typesdb = ["/usr/share/collectd/types.db"]
+
+  ## Multi-value plugins can be handled two ways.
+  ## "split" will parse and store the multi-value plugin data into separate measurements
+  ## "join" will parse and store the multi-value plugin as a single multi-value measurement.
+  # collectd_parse_multivalue = "split"
+
+  ## Maximum socket buffer size (in bytes when no unit specified).
+  # read_buffer_size = "64KiB"
+`
+
+// Collectd listens for metrics sent by the collectd network plugin.
+type Collectd struct {
+	ServiceAddress  string        `toml:"service_address"`
+	AuthFile        string        `toml:"collectd_auth_file"`
+	SecurityLevel   string        `toml:"collectd_security_level"`
+	TypesDB         []string      `toml:"collectd_typesdb"`
+	ParseMultiValue string        `toml:"collectd_parse_multivalue"`
+	ReadBufferSize  internal.Size `toml:"read_buffer_size"`
+
+	Log cua.Logger `toml:"-"`
+
+	conn   *net.UDPConn
+	parser *collectdparser.Parser
+	acc    cua.Accumulator
+	wg     sync.WaitGroup
+
+	PacketsRecv  selfstat.Stat
+	ParseErrors  selfstat.Stat
+	MetricsAdded selfstat.Stat
+}
+
+func (*Collectd) Description() string {
+	return "Listener for the collectd binary network protocol"
+}
+
+func (*Collectd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Collectd) Init() error {
+	switch c.SecurityLevel {
+	case "", "none", "sign", "encrypt":
+	default:
+		return fmt.Errorf("invalid collectd_security_level %q", c.SecurityLevel)
+	}
+
+	switch c.ParseMultiValue {
+	case "", "split", "join":
+	default:
+		return fmt.Errorf("invalid collectd_parse_multivalue %q", c.ParseMultiValue)
+	}
+
+	parser, err := collectdparser.NewCollectdParser(c.AuthFile, c.SecurityLevel, c.TypesDB, c.ParseMultiValue)
+	if err != nil {
+		return fmt.Errorf("collectd parser: %w", err)
+	}
+	parser.Log = c.Log
+	c.parser = parser
+
+	tags := map[string]string{"address": c.ServiceAddress}
+	c.PacketsRecv = selfstat.Register("collectd", "packets_received", tags)
+	c.ParseErrors = selfstat.Register("collectd", "parse_errors", tags)
+	c.MetricsAdded = selfstat.Register("collectd", "metrics_added", tags)
+	return nil
+}
+
+func (*Collectd) Gather(_ cua.Accumulator) error {
+	return nil
+}
+
+func (c *Collectd) Start(acc cua.Accumulator) error {
+	c.acc = acc
+
+	addr, err := net.ResolveUDPAddr("udp", c.ServiceAddress)
+	if err != nil {
+		return fmt.Errorf("resolve (%s): %w", c.ServiceAddress, err)
+	}
+
+	if addr.IP != nil && addr.IP.IsMulticast() {
+		c.conn, err = net.ListenMulticastUDP("udp", nil, addr)
+	} else {
+		c.conn, err = net.ListenUDP("udp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("listen (%s): %w", c.ServiceAddress, err)
+	}
+
+	if c.ReadBufferSize.Size > 0 {
+		if err := c.conn.SetReadBuffer(int(c.ReadBufferSize.Size)); err != nil {
+			c.Log.Warnf("Unable to set read buffer: %s", err.Error())
+		}
+	}
+
+	c.Log.Infof("Listening on udp://%s", c.conn.LocalAddr())
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.listen()
+	}()
+
+	return nil
+}
+
+func (c *Collectd) Stop() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.wg.Wait()
+}
+
+func (c *Collectd) listen() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, src, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				c.Log.Error(err.Error())
+			}
+			return
+		}
+		c.PacketsRecv.Incr(1)
+
+		metrics, err := c.parser.Parse(buf[:n])
+		if err != nil {
+			c.ParseErrors.Incr(1)
+			c.Log.Errorf("Unable to parse packet from %s: %s", src, err.Error())
+			continue
+		}
+
+		for _, m := range metrics {
+			c.acc.AddMetric(m)
+		}
+		c.MetricsAdded.Incr(int64(len(metrics)))
+	}
+}
+
+func init() {
+	inputs.Add("collectd", func() cua.Input {
+		return &Collectd{
+			ServiceAddress: defaultServiceAddress,
+		}
+	})
+}
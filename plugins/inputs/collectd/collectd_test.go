@@ -0,0 +1,133 @@
+package collectd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"collectd.org/api"
+	"collectd.org/network"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCollectd(t *testing.T) *Collectd {
+	authFile := filepath.Join(t.TempDir(), "auth_file")
+	require.NoError(t, os.WriteFile(authFile, []byte("user: secret\n"), 0600))
+
+	return &Collectd{
+		ServiceAddress: "127.0.0.1:0",
+		AuthFile:       authFile,
+		Log:            testutil.Logger{},
+	}
+}
+
+func send(t *testing.T, c *Collectd, secure func(b *network.Buffer)) {
+	b := network.NewBuffer(0)
+	if secure != nil {
+		secure(b)
+	}
+	vl := &api.ValueList{
+		Identifier: api.Identifier{
+			Host:           "server1",
+			Plugin:         "cpu",
+			PluginInstance: "0",
+			Type:           "cpu",
+			TypeInstance:   "user",
+		},
+		Time:     time.Unix(1600000000, 0),
+		Interval: 10 * time.Second,
+		Values:   []api.Value{api.Derive(42)},
+	}
+	require.NoError(t, b.Write(context.Background(), vl))
+	data, err := b.Bytes()
+	require.NoError(t, err)
+
+	conn, err := net.Dial("udp", c.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(data)
+	require.NoError(t, err)
+}
+
+func TestCollectdSecurityLevels(t *testing.T) {
+	tests := []struct {
+		name          string
+		securityLevel string
+		secure        func(b *network.Buffer)
+		accepted      bool
+	}{
+		{
+			name:     "plain",
+			accepted: true,
+		},
+		{
+			name:          "signed",
+			securityLevel: "sign",
+			secure:        func(b *network.Buffer) { b.Sign("user", "secret") },
+			accepted:      true,
+		},
+		{
+			name:          "encrypted",
+			securityLevel: "encrypt",
+			secure:        func(b *network.Buffer) { b.Encrypt("user", "secret") },
+			accepted:      true,
+		},
+		{
+			name:          "unsigned rejected",
+			securityLevel: "sign",
+		},
+		{
+			name:          "wrong password rejected",
+			securityLevel: "encrypt",
+			secure:        func(b *network.Buffer) { b.Encrypt("user", "wrong") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollectd(t)
+			c.SecurityLevel = tt.securityLevel
+			require.NoError(t, c.Init())
+
+			acc := &testutil.Accumulator{}
+			require.NoError(t, c.Start(acc))
+			defer c.Stop()
+
+			send(t, c, tt.secure)
+
+			expected := []cua.Metric{
+				testutil.MustMetric("cpu_value",
+					map[string]string{
+						"host":          "server1",
+						"instance":      "0",
+						"type":          "cpu",
+						"type_instance": "user",
+					},
+					map[string]interface{}{"value": 42.0},
+					time.Unix(1600000000, 0)),
+			}
+
+			if !tt.accepted {
+				require.Eventually(t, func() bool {
+					return c.PacketsRecv.Get() > 0
+				}, time.Second, 10*time.Millisecond)
+				require.Empty(t, acc.GetCUAMetrics())
+				return
+			}
+
+			acc.Wait(1)
+			testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
+		})
+	}
+}
+
+func TestCollectdInvalidSecurityLevel(t *testing.T) {
+	c := newTestCollectd(t)
+	c.SecurityLevel = "paranoid"
+	require.Error(t, c.Init())
+}
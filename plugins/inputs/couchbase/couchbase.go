@@ -3,15 +3,28 @@ package couchbase
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"sync"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
 	couchbase "github.com/couchbase/go-couchbase"
 )
 
 type Couchbase struct {
 	Servers []string
+
+	// BucketInclude and BucketExclude select the buckets reported in the
+	// couchbase_bucket measurement; MaxBuckets caps how many are reported
+	// per cluster to bound series cardinality.
+	BucketInclude []string `toml:"bucket_include"`
+	BucketExclude []string `toml:"bucket_exclude"`
+	MaxBuckets    int      `toml:"max_buckets"`
+
+	Log cua.Logger `toml:"-"`
+
+	bucketFilter filter.Filter
 }
 
 var sampleConfig = `
@@ -25,6 +38,15 @@ var sampleConfig = `
   ## If no protocol is specified, HTTP is used.
   ## If no port is specified, 8091 is used.
   servers = ["http://localhost:8091"]
+
+  ## Filter the buckets to gather stats for, glob patterns are supported.
+  ## By default all buckets are gathered.
+  # bucket_include = []
+  # bucket_exclude = []
+
+  ## Maximum number of buckets to gather stats for per cluster, buckets are
+  ## selected in name order after filtering. Use 0 for no limit.
+  # max_buckets = 0
 `
 
 var regexpURI = regexp.MustCompile(`(\S+://)?(\S+\:\S+@)`)
@@ -37,6 +59,15 @@ func (r *Couchbase) Description() string {
 	return "Read metrics from one or many couchbase clusters"
 }
 
+func (r *Couchbase) Init() error {
+	f, err := filter.NewIncludeExcludeFilter(r.BucketInclude, r.BucketExclude)
+	if err != nil {
+		return fmt.Errorf("bucket filter: %w", err)
+	}
+	r.bucketFilter = f
+	return nil
+}
+
 // Reads stats from all configured clusters. Accumulates stats.
 // Returns one of the errors encountered while gathering stats (if any).
 func (r *Couchbase) Gather(acc cua.Accumulator) error {
@@ -77,17 +108,40 @@ func (r *Couchbase) gatherServer(addr string, acc cua.Accumulator, pool *couchba
 		pool = &p
 	}
 
+	cluster := regexpURI.ReplaceAllString(addr, "${1}")
+
+	healthy := 0
 	for i := 0; i < len(pool.Nodes); i++ {
 		node := pool.Nodes[i]
-		tags := map[string]string{"cluster": regexpURI.ReplaceAllString(addr, "${1}"), "hostname": node.Hostname}
+		if node.Status == "healthy" {
+			healthy++
+		}
+		tags := map[string]string{"cluster": cluster, "hostname": node.Hostname}
 		fields := make(map[string]interface{})
 		fields["memory_free"] = node.MemoryFree
 		fields["memory_total"] = node.MemoryTotal
+		fields["mcd_memory_allocated"] = node.MCDMemoryAllocated
+		fields["mcd_memory_reserved"] = node.MCDMemoryReserved
+		fields["uptime"] = node.Uptime
+		for k, v := range node.InterestingStats {
+			fields[k] = v
+		}
 		acc.AddFields("couchbase_node", fields, tags)
 	}
 
-	for bucketName := range pool.BucketMap {
-		tags := map[string]string{"cluster": regexpURI.ReplaceAllString(addr, "${1}"), "bucket": bucketName}
+	buckets := r.selectBuckets(pool)
+
+	acc.AddFields("couchbase_cluster",
+		map[string]interface{}{
+			"nodes":            len(pool.Nodes),
+			"nodes_healthy":    healthy,
+			"buckets":          len(pool.BucketMap),
+			"buckets_reported": len(buckets),
+		},
+		map[string]string{"cluster": cluster})
+
+	for _, bucketName := range buckets {
+		tags := map[string]string{"cluster": cluster, "bucket": bucketName}
 		bs := pool.BucketMap[bucketName].BasicStats
 		fields := make(map[string]interface{})
 		fields["quota_percent_used"] = bs["quotaPercentUsed"]
@@ -102,6 +156,27 @@ func (r *Couchbase) gatherServer(addr string, acc cua.Accumulator, pool *couchba
 	return nil
 }
 
+// selectBuckets returns the sorted names of the buckets passing the bucket
+// filter, truncated to MaxBuckets.
+func (r *Couchbase) selectBuckets(pool *couchbase.Pool) []string {
+	names := make([]string, 0, len(pool.BucketMap))
+	for name := range pool.BucketMap {
+		if r.bucketFilter != nil && !r.bucketFilter.Match(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if r.MaxBuckets > 0 && len(names) > r.MaxBuckets {
+		if r.Log != nil {
+			r.Log.Warnf("%d buckets selected, only reporting the first %d (max_buckets)", len(names), r.MaxBuckets)
+		}
+		names = names[:r.MaxBuckets]
+	}
+	return names
+}
+
 func init() {
 	inputs.Add("couchbase", func() cua.Input {
 		return &Couchbase{}
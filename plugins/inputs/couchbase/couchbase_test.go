@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
 
 	"github.com/couchbase/go-couchbase"
 )
@@ -22,11 +23,55 @@ func TestGatherServer(t *testing.T) {
 	var acc testutil.Accumulator
 	_ = cb.gatherServer("mycluster", &acc, &pool)
 	acc.AssertContainsTaggedFields(t, "couchbase_node",
-		map[string]interface{}{"memory_free": 23181365248.0, "memory_total": 64424656896.0},
+		map[string]interface{}{
+			"memory_free":                  23181365248.0,
+			"memory_total":                 64424656896.0,
+			"mcd_memory_allocated":         49152.0,
+			"mcd_memory_reserved":          49152.0,
+			"uptime":                       341236,
+			"cmd_get":                      17.98201798201798,
+			"couch_docs_actual_disk_size":  68506048063.0,
+			"couch_docs_data_size":         38718796110.0,
+			"couch_views_actual_disk_size": 0.0,
+			"couch_views_data_size":        0.0,
+			"curr_items":                   140158886.0,
+			"curr_items_tot":               279374646.0,
+			"ep_bg_fetched":                0.999000999000999,
+			"get_hits":                     10.98901098901099,
+			"mem_used":                     36497390640.0,
+			"ops":                          829.1708291708292,
+			"vb_replica_curr_items":        139215760.0,
+		},
 		map[string]string{"cluster": "mycluster", "hostname": "172.16.10.187:8091"})
 	acc.AssertContainsTaggedFields(t, "couchbase_node",
-		map[string]interface{}{"memory_free": 23665811456.0, "memory_total": 64424656896.0},
+		map[string]interface{}{
+			"memory_free":                  23665811456.0,
+			"memory_total":                 64424656896.0,
+			"mcd_memory_allocated":         49152.0,
+			"mcd_memory_reserved":          49152.0,
+			"uptime":                       341210,
+			"cmd_get":                      172.8271728271728,
+			"couch_docs_actual_disk_size":  79360565405.0,
+			"couch_docs_data_size":         38736382876.0,
+			"couch_views_actual_disk_size": 0.0,
+			"couch_views_data_size":        0.0,
+			"curr_items":                   140174377.0,
+			"curr_items_tot":               279383025.0,
+			"ep_bg_fetched":                0.999000999000999,
+			"get_hits":                     167.8321678321678,
+			"mem_used":                     36650059656.0,
+			"ops":                          1685.314685314685,
+			"vb_replica_curr_items":        139208648.0,
+		},
 		map[string]string{"cluster": "mycluster", "hostname": "172.16.10.65:8091"})
+	acc.AssertContainsTaggedFields(t, "couchbase_cluster",
+		map[string]interface{}{
+			"nodes":            7,
+			"nodes_healthy":    7,
+			"buckets":          1,
+			"buckets_reported": 1,
+		},
+		map[string]string{"cluster": "mycluster"})
 	acc.AssertContainsTaggedFields(t, "couchbase_bucket",
 		map[string]interface{}{
 			"quota_percent_used": 68.85424936294555,
@@ -40,6 +85,25 @@ func TestGatherServer(t *testing.T) {
 		map[string]string{"cluster": "mycluster", "bucket": "blastro-df"})
 }
 
+func TestSelectBuckets(t *testing.T) {
+	pool := &couchbase.Pool{
+		BucketMap: map[string]couchbase.Bucket{
+			"travel-sample": {},
+			"beer-sample":   {},
+			"tmp-1":         {},
+			"tmp-2":         {},
+		},
+	}
+
+	cb := &Couchbase{BucketExclude: []string{"tmp-*"}}
+	require.NoError(t, cb.Init())
+	require.Equal(t, []string{"beer-sample", "travel-sample"}, cb.selectBuckets(pool))
+
+	cb = &Couchbase{MaxBuckets: 3, Log: testutil.Logger{}}
+	require.NoError(t, cb.Init())
+	require.Equal(t, []string{"beer-sample", "tmp-1", "tmp-2"}, cb.selectBuckets(pool))
+}
+
 func TestSanitizeURI(t *testing.T) {
 
 	var sanitizeTest = []struct {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -28,6 +29,7 @@ type Client interface {
 	GetNodeMetrics(ctx context.Context, node string) (*Metrics, error)
 	GetContainerMetrics(ctx context.Context, node, container string) (*Metrics, error)
 	GetAppMetrics(ctx context.Context, node, container string) (*Metrics, error)
+	GetExecutors(ctx context.Context, node string) ([]Executor, error)
 }
 
 type APIError struct {
@@ -57,13 +59,21 @@ type LoginAuth struct {
 
 // Slave is a node in the cluster.
 type Slave struct {
-	ID string `json:"id"`
+	ID       string `json:"id"`
+	Hostname string `json:"hostname"`
+}
+
+// Framework is a framework registered with the cluster.
+type Framework struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
 // Summary provides high level cluster wide information.
 type Summary struct {
-	Cluster string
-	Slaves  []Slave
+	Cluster    string
+	Slaves     []Slave
+	Frameworks []Framework
 }
 
 // Container is a container on a node.
@@ -71,6 +81,17 @@ type Container struct {
 	ID string
 }
 
+// Executor is the resource usage of an executor running the tasks of a
+// framework on a node.
+type Executor struct {
+	ContainerID  string                 `json:"container_id"`
+	ExecutorID   string                 `json:"executor_id"`
+	ExecutorName string                 `json:"executor_name"`
+	FrameworkID  string                 `json:"framework_id"`
+	Source       string                 `json:"source"`
+	Statistics   map[string]interface{} `json:"statistics"`
+}
+
 type DataPoint struct {
 	Name  string            `json:"name"`
 	Tags  map[string]string `json:"tags"`
@@ -259,6 +280,22 @@ func (c *ClusterClient) GetAppMetrics(ctx context.Context, node, container strin
 	return c.getMetrics(ctx, c.url(path))
 }
 
+// GetExecutors returns the executors of a node from the /containers endpoint
+// of the Mesos agent, or from its /monitor/statistics endpoint on the agents
+// without it.
+func (c *ClusterClient) GetExecutors(ctx context.Context, node string) ([]Executor, error) {
+	executors := []Executor{}
+	err := c.doGet(ctx, c.url(fmt.Sprintf("/agent/%s/containers", node)), &executors)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		err = c.doGet(ctx, c.url(fmt.Sprintf("/agent/%s/monitor/statistics", node)), &executors)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return executors, nil
+}
+
 func createGetRequest(url string, token string) (*http.Request, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -232,3 +232,63 @@ func TestGetContainerMetrics(t *testing.T) {
 	}
 
 }
+
+func TestGetExecutors(t *testing.T) {
+	const statistics = `[{"executor_id":"web.1234","framework_id":"f1","source":"web.1234","statistics":{"cpus_nr_throttled":3}}]`
+
+	var tests = []struct {
+		name          string
+		handler       http.HandlerFunc
+		expectedValue []Executor
+	}{
+		{
+			name: "containers",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/agent/foo/containers", r.URL.Path)
+				fmt.Fprintln(w, `[{"container_id":"c1","executor_id":"web.1234","framework_id":"f1","statistics":{"mem_rss_bytes":1024}}]`)
+			},
+			expectedValue: []Executor{
+				{
+					ContainerID: "c1",
+					ExecutorID:  "web.1234",
+					FrameworkID: "f1",
+					Statistics:  map[string]interface{}{"mem_rss_bytes": 1024.0},
+				},
+			},
+		},
+		{
+			name: "monitor statistics fallback",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/agent/foo/monitor/statistics" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprintln(w, statistics)
+			},
+			expectedValue: []Executor{
+				{
+					ExecutorID:  "web.1234",
+					FrameworkID: "f1",
+					Source:      "web.1234",
+					Statistics:  map[string]interface{}{"cpus_nr_throttled": 3.0},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			defer ts.Close()
+
+			u, err := url.Parse(ts.URL)
+			require.NoError(t, err)
+
+			client := NewClusterClient(u, defaultResponseTimeout, 1, nil)
+			executors, err := client.GetExecutors(context.Background(), "foo")
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedValue, executors)
+		})
+	}
+}
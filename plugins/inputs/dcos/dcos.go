@@ -57,6 +57,12 @@ type DCOS struct {
 	AppInclude       []string
 	AppExclude       []string
 
+	TaskMetrics        bool `toml:"task_metrics"`
+	FrameworkInclude   []string
+	FrameworkExclude   []string
+	MarathonAppInclude []string
+	MarathonAppExclude []string
+
 	MaxConnections  int
 	ResponseTimeout internal.Duration
 	tls.ClientConfig
@@ -69,6 +75,8 @@ type DCOS struct {
 	containerFilter filter.Filter
 	appFilter       filter.Filter
 	// taskNameFilter  filter.Filter
+	frameworkFilter   filter.Filter
+	marathonAppFilter filter.Filter
 }
 
 func (d *DCOS) Description() string {
@@ -100,6 +108,16 @@ var sampleConfig = `
   ## Container IDs to collect app metrics from.
   # app_include = []
   # app_exclude = []
+  ## Framework names to collect container, app and task metrics from.
+  # framework_include = []
+  # framework_exclude = []
+  ## Marathon app IDs, such as "/group/app", to collect task metrics from.
+  # marathon_app_include = []
+  # marathon_app_exclude = []
+
+  ## Collect the resource usage of the tasks, such as their cpu throttling
+  ## and memory pressure, from the Mesos agents.
+  # task_metrics = false
 
   ## Maximum concurrent connections to the cluster.
   # max_connections = 10
@@ -141,6 +159,11 @@ func (d *DCOS) Gather(acc cua.Accumulator) error {
 		return fmt.Errorf("summary: %w", err)
 	}
 
+	frameworks := make(map[string]string, len(summary.Frameworks))
+	for _, f := range summary.Frameworks {
+		frameworks[f.ID] = f.Name
+	}
+
 	var wg sync.WaitGroup
 	for _, node := range summary.Slaves {
 		wg.Add(1)
@@ -148,6 +171,14 @@ func (d *DCOS) Gather(acc cua.Accumulator) error {
 			defer wg.Done()
 			d.GatherNode(ctx, acc, summary.Cluster, node)
 		}(node.ID)
+
+		if d.TaskMetrics && d.nodeFilter.Match(node.ID) {
+			wg.Add(1)
+			go func(node Slave) {
+				defer wg.Done()
+				d.GatherTasks(ctx, acc, summary.Cluster, node, frameworks)
+			}(node)
+		}
 	}
 	wg.Wait()
 
@@ -197,6 +228,9 @@ func (d *DCOS) GatherContainers(ctx context.Context, acc cua.Accumulator, cluste
 					acc.AddError(err)
 					return
 				}
+				if !d.matchFramework(m) {
+					return
+				}
 				d.addContainerMetrics(acc, cluster, m)
 			}(container.ID)
 		}
@@ -214,6 +248,9 @@ func (d *DCOS) GatherContainers(ctx context.Context, acc cua.Accumulator, cluste
 					acc.AddError(err)
 					return
 				}
+				if !d.matchFramework(m) {
+					return
+				}
 				d.addAppMetrics(acc, cluster, m)
 			}(container.ID)
 		}
@@ -221,6 +258,97 @@ func (d *DCOS) GatherContainers(ctx context.Context, acc cua.Accumulator, cluste
 	wg.Wait()
 }
 
+// matchFramework returns whether the framework of container or app metrics
+// passes the framework filter, the metrics without a framework always pass.
+func (d *DCOS) matchFramework(m *Metrics) bool {
+	name, ok := m.Dimensions["framework_name"].(string)
+	return !ok || d.frameworkFilter.Match(name)
+}
+
+// GatherTasks gathers the resource usage of the executors of the tasks
+// running on a node.
+func (d *DCOS) GatherTasks(ctx context.Context, acc cua.Accumulator, cluster string, node Slave, frameworks map[string]string) {
+	executors, err := d.client.GetExecutors(ctx, node.ID)
+	if err != nil {
+		acc.AddError(fmt.Errorf("executors of node %s: %w", node.ID, err))
+		return
+	}
+
+	tm := time.Now()
+	for _, e := range executors {
+		framework, ok := frameworks[e.FrameworkID]
+		if !ok {
+			framework = e.FrameworkID
+		}
+		if !d.frameworkFilter.Match(framework) {
+			continue
+		}
+
+		tags := map[string]string{
+			"cluster":        cluster,
+			"framework_name": framework,
+			"executor_id":    e.ExecutorID,
+		}
+		if node.Hostname != "" {
+			tags["hostname"] = node.Hostname
+		}
+		if e.ContainerID != "" {
+			tags["container_id"] = e.ContainerID
+		}
+		if strings.HasPrefix(framework, "marathon") {
+			taskID := e.Source
+			if taskID == "" {
+				taskID = e.ExecutorID
+			}
+			if appID := marathonAppID(taskID); appID != "" {
+				if !d.marathonAppFilter.Match(appID) {
+					continue
+				}
+				tags["marathon_app_id"] = appID
+			}
+		}
+
+		fields := taskFields(e.Statistics)
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields("dcos_task", fields, tags, tm)
+	}
+}
+
+// marathonAppID returns the ID of the Marathon app of a task. The ID of a
+// task is the ID of its app, with its slashes replaced by underscores,
+// followed by a dot and the ID of the instance.
+func marathonAppID(taskID string) string {
+	id := strings.TrimPrefix(taskID, "instance-")
+	if i := strings.Index(id, ".instance-"); i >= 0 {
+		id = id[:i]
+	} else if i := strings.LastIndex(id, "."); i >= 0 {
+		id = id[:i]
+	} else {
+		return ""
+	}
+	return "/" + strings.ReplaceAll(id, "_", "/")
+}
+
+// taskFields returns the numeric statistics of an executor, the sizes in
+// bytes are integers.
+func taskFields(stats map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(stats))
+	for k, v := range stats {
+		value, ok := v.(float64)
+		if !ok || k == "timestamp" {
+			continue
+		}
+		if strings.HasSuffix(k, "_bytes") {
+			fields[k] = int64(value)
+		} else {
+			fields[k] = value
+		}
+	}
+	return fields
+}
+
 type point struct {
 	tags   map[string]string
 	labels map[string]string
@@ -419,6 +547,16 @@ func (d *DCOS) createFilters() error {
 		return fmt.Errorf("new include/exclude filter: %w", err)
 	}
 
+	d.frameworkFilter, err = filter.NewIncludeExcludeFilter(d.FrameworkInclude, d.FrameworkExclude)
+	if err != nil {
+		return fmt.Errorf("new include/exclude filter: %w", err)
+	}
+
+	d.marathonAppFilter, err = filter.NewIncludeExcludeFilter(d.MarathonAppInclude, d.MarathonAppExclude)
+	if err != nil {
+		return fmt.Errorf("new include/exclude filter: %w", err)
+	}
+
 	return nil
 }
 
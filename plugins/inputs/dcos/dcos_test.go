@@ -17,6 +17,7 @@ type mockClient struct {
 	GetNodeMetricsF      func(ctx context.Context, node string) (*Metrics, error)
 	GetContainerMetricsF func(ctx context.Context, node, container string) (*Metrics, error)
 	GetAppMetricsF       func(ctx context.Context, node, container string) (*Metrics, error)
+	GetExecutorsF        func(ctx context.Context, node string) ([]Executor, error)
 }
 
 func (c *mockClient) SetToken(token string) {
@@ -47,6 +48,10 @@ func (c *mockClient) GetAppMetrics(ctx context.Context, node, container string)
 	return c.GetAppMetricsF(ctx, node, container)
 }
 
+func (c *mockClient) GetExecutors(ctx context.Context, node string) ([]Executor, error) {
+	return c.GetExecutorsF(ctx, node)
+}
+
 func TestAddNodeMetrics(t *testing.T) {
 	var tests = []struct {
 		name    string
@@ -443,3 +448,88 @@ func TestGatherFilterNode(t *testing.T) {
 		})
 	}
 }
+
+func TestMarathonAppID(t *testing.T) {
+	require.Equal(t, "/web", marathonAppID("web.8c7a9e2b-7d3a-11eb-9439-0242ac130002"))
+	require.Equal(t, "/prod/api", marathonAppID("prod_api.instance-8c7a9e2b-7d3a-11eb-9439-0242ac130002._app.1"))
+	require.Equal(t, "/prod/db", marathonAppID("instance-prod_db.8c7a9e2b-7d3a-11eb-9439-0242ac130002"))
+	require.Empty(t, marathonAppID("executor"))
+}
+
+func TestGatherTasks(t *testing.T) {
+	client := &mockClient{
+		SetTokenF: func(token string) {},
+		GetSummaryF: func(ctx context.Context) (*Summary, error) {
+			return &Summary{
+				Cluster:    "a",
+				Slaves:     []Slave{{ID: "x", Hostname: "192.168.122.18"}},
+				Frameworks: []Framework{{ID: "f1", Name: "marathon"}, {ID: "f2", Name: "spark"}},
+			}, nil
+		},
+		GetContainersF: func(ctx context.Context, node string) ([]Container, error) {
+			return []Container{}, nil
+		},
+		GetNodeMetricsF: func(ctx context.Context, node string) (*Metrics, error) {
+			return &Metrics{}, nil
+		},
+		GetExecutorsF: func(ctx context.Context, node string) ([]Executor, error) {
+			stats := map[string]interface{}{
+				"timestamp":                   1614592800.0,
+				"cpus_nr_throttled":           3.0,
+				"cpus_throttled_time_secs":    0.5,
+				"mem_rss_bytes":               1024.0,
+				"mem_medium_pressure_counter": 2.0,
+				"perf":                        map[string]interface{}{"cycles": 1.0},
+			}
+			return []Executor{
+				{ContainerID: "c1", ExecutorID: "prod_api.1234", Source: "prod_api.1234", FrameworkID: "f1", Statistics: stats},
+				{ContainerID: "c2", ExecutorID: "test_api.1234", Source: "test_api.1234", FrameworkID: "f1", Statistics: stats},
+				{ContainerID: "c3", ExecutorID: "driver-1", FrameworkID: "f2", Statistics: stats},
+				{ContainerID: "c4", ExecutorID: "job", FrameworkID: "f3", Statistics: stats},
+			}, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	dcos := &DCOS{
+		TaskMetrics:        true,
+		FrameworkExclude:   []string{"f3"},
+		MarathonAppInclude: []string{"/prod/*"},
+		client:             client,
+	}
+	require.NoError(t, dcos.Gather(&acc))
+	require.NoError(t, acc.FirstError())
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "dcos_task",
+		map[string]interface{}{
+			"cpus_nr_throttled":           3.0,
+			"cpus_throttled_time_secs":    0.5,
+			"mem_rss_bytes":               int64(1024),
+			"mem_medium_pressure_counter": 2.0,
+		},
+		map[string]string{
+			"cluster":         "a",
+			"hostname":        "192.168.122.18",
+			"framework_name":  "marathon",
+			"executor_id":     "prod_api.1234",
+			"container_id":    "c1",
+			"marathon_app_id": "/prod/api",
+		},
+	)
+	acc.AssertContainsTaggedFields(t, "dcos_task",
+		map[string]interface{}{
+			"cpus_nr_throttled":           3.0,
+			"cpus_throttled_time_secs":    0.5,
+			"mem_rss_bytes":               int64(1024),
+			"mem_medium_pressure_counter": 2.0,
+		},
+		map[string]string{
+			"cluster":        "a",
+			"hostname":       "192.168.122.18",
+			"framework_name": "spark",
+			"executor_id":    "driver-1",
+			"container_id":   "c3",
+		},
+	)
+}
@@ -0,0 +1,422 @@
+package directorymonitor
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/csv"
+)
+
+const sampleConfig = `
+  ## The directory to monitor for new files, files are not searched for in the
+  ## subdirectories.
+  directory = ""
+
+  ## The directory the files are moved to once processed.
+  finished_directory = ""
+
+  ## The directory the files are moved to when an error occurs while
+  ## processing them, they are moved to the finished_directory when empty.
+  # error_directory = ""
+
+  ## Regular expressions of the names of the files to process, all the files
+  ## are processed when empty.
+  # files_to_monitor = ["^.*\\.csv"]
+
+  ## Regular expressions of the names of the files to ignore.
+  # files_to_ignore = ["\\.DS_Store"]
+
+  ## Maximum number of metrics read but not yet written by the outputs, the
+  ## files are read more slowly when reached.
+  # max_buffered_metrics = 10000
+
+  ## Minimum time since a file was last modified to process it, to not read
+  ## files while they are being written.
+  # directory_duration_threshold = "50ms"
+
+  ## Maximum number of files waiting to be processed.
+  # file_queue_size = 100000
+
+  ## Name a tag containing the name of the file the metrics were read from,
+  ## leave empty to disable.
+  # file_tag = ""
+
+  ## Whether the files are parsed line by line, "line-by-line", or as a
+  ## whole, "at-once".  Formats spanning lines, such as JSON documents,
+  ## must be parsed at once.
+  # parse_method = "line-by-line"
+
+  ## Files with the ".gz" extension are decompressed.
+
+  ## The data format of the files.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/circonus-labs/circonus-unified-agent/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+const (
+	parseLineByLine = "line-by-line"
+	parseAtOnce     = "at-once"
+
+	// maxLineSize is the longest line read line by line.
+	maxLineSize = 10 * 1024 * 1024
+)
+
+type DirectoryMonitor struct {
+	Directory                  string            `toml:"directory"`
+	FinishedDirectory          string            `toml:"finished_directory"`
+	ErrorDirectory             string            `toml:"error_directory"`
+	FilesToMonitor             []string          `toml:"files_to_monitor"`
+	FilesToIgnore              []string          `toml:"files_to_ignore"`
+	MaxBufferedMetrics         int               `toml:"max_buffered_metrics"`
+	DirectoryDurationThreshold internal.Duration `toml:"directory_duration_threshold"`
+	FileQueueSize              int               `toml:"file_queue_size"`
+	FileTag                    string            `toml:"file_tag"`
+	ParseMethod                string            `toml:"parse_method"`
+
+	Log cua.Logger `toml:"-"`
+
+	parserFunc parsers.ParserFunc
+	monitor    []*regexp.Regexp
+	ignore     []*regexp.Regexp
+
+	acc    cua.TrackingAccumulator
+	sem    chan struct{}
+	queue  chan string
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// files queued or being processed
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func (d *DirectoryMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DirectoryMonitor) Description() string {
+	return "Ingest the files dropped in a directory"
+}
+
+func (d *DirectoryMonitor) SetParserFunc(fn parsers.ParserFunc) {
+	d.parserFunc = fn
+}
+
+func (d *DirectoryMonitor) Init() error {
+	if d.Directory == "" || d.FinishedDirectory == "" {
+		return errors.New("directory and finished_directory are required")
+	}
+	if d.ErrorDirectory == "" {
+		d.ErrorDirectory = d.FinishedDirectory
+	}
+	if d.MaxBufferedMetrics <= 0 {
+		return errors.New("max_buffered_metrics must be positive")
+	}
+	if d.FileQueueSize <= 0 {
+		return errors.New("file_queue_size must be positive")
+	}
+
+	switch d.ParseMethod {
+	case "":
+		d.ParseMethod = parseLineByLine
+	case parseLineByLine, parseAtOnce:
+	default:
+		return fmt.Errorf("unknown parse_method %q", d.ParseMethod)
+	}
+
+	for _, expr := range d.FilesToMonitor {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("files_to_monitor %q: %w", expr, err)
+		}
+		d.monitor = append(d.monitor, re)
+	}
+	for _, expr := range d.FilesToIgnore {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("files_to_ignore %q: %w", expr, err)
+		}
+		d.ignore = append(d.ignore, re)
+	}
+
+	for _, dir := range []string{d.Directory, d.FinishedDirectory, d.ErrorDirectory} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%q is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+func (d *DirectoryMonitor) Start(acc cua.Accumulator) error {
+	d.acc = acc.WithTracking(d.MaxBufferedMetrics)
+	d.sem = make(chan struct{}, d.MaxBufferedMetrics)
+	d.queue = make(chan string, d.FileQueueSize)
+	d.pending = make(map[string]bool)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-d.acc.Delivered():
+				<-d.sem
+			}
+		}
+	}()
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case name := <-d.queue:
+				d.processFile(name)
+				d.mu.Lock()
+				delete(d.pending, name)
+				d.mu.Unlock()
+			}
+		}
+	}()
+	return nil
+}
+
+func (d *DirectoryMonitor) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// Gather queues the new files of the directory.
+func (d *DirectoryMonitor) Gather(_ cua.Accumulator) error {
+	entries, err := os.ReadDir(d.Directory)
+	if err != nil {
+		return fmt.Errorf("reading directory: %w", err)
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !d.monitored(name) {
+			continue
+		}
+
+		// the file may still be written to
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < d.DirectoryDurationThreshold.Duration {
+			continue
+		}
+
+		d.mu.Lock()
+		queued := d.pending[name]
+		if !queued {
+			select {
+			case d.queue <- name:
+				d.pending[name] = true
+			default:
+				// the queue is full, the file is queued on a later gather
+			}
+		}
+		d.mu.Unlock()
+	}
+	return nil
+}
+
+func (d *DirectoryMonitor) monitored(name string) bool {
+	for _, re := range d.ignore {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(d.monitor) == 0 {
+		return true
+	}
+	for _, re := range d.monitor {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// processFile reads the metrics of the file and moves it to the finished or
+// error directory.
+func (d *DirectoryMonitor) processFile(name string) {
+	path := filepath.Join(d.Directory, name)
+
+	err := d.readFile(path)
+	if errors.Is(err, context.Canceled) {
+		// the agent is stopping, the file is read again on the next start
+		return
+	}
+
+	dest := d.FinishedDirectory
+	if err != nil {
+		d.acc.AddError(fmt.Errorf("processing %q: %w", name, err))
+		dest = d.ErrorDirectory
+	}
+	if err := moveFile(path, filepath.Join(dest, name)); err != nil {
+		d.acc.AddError(fmt.Errorf("moving %q: %w", name, err))
+	}
+}
+
+func (d *DirectoryMonitor) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	parser, err := d.parserFunc()
+	if err != nil {
+		return fmt.Errorf("creating parser: %w", err)
+	}
+
+	if d.ParseMethod == parseAtOnce {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+		metrics, err := parser.Parse(data)
+		if err != nil {
+			return fmt.Errorf("parse: %w", err)
+		}
+		return d.addMetrics(path, metrics)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	firstLine := true
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		metrics, err := parseLine(parser, line, firstLine)
+		if err != nil {
+			return fmt.Errorf("parse: %w", err)
+		}
+		firstLine = false
+		if err := d.addMetrics(path, metrics); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	return nil
+}
+
+// parseLine parses a line, the csv parser parses the header only on the
+// first line.
+func parseLine(parser parsers.Parser, line string, firstLine bool) ([]cua.Metric, error) {
+	if _, ok := parser.(*csv.Parser); ok && !firstLine {
+		m, err := parser.ParseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("parse line: %w", err)
+		}
+		if m == nil {
+			return nil, nil
+		}
+		return []cua.Metric{m}, nil
+	}
+	metrics, err := parser.Parse([]byte(line))
+	if err != nil {
+		return nil, fmt.Errorf("parse line: %w", err)
+	}
+	return metrics, nil
+}
+
+// addMetrics adds the metrics, waiting for the outputs to write the
+// metrics buffered.
+func (d *DirectoryMonitor) addMetrics(path string, metrics []cua.Metric) error {
+	for _, m := range metrics {
+		if d.FileTag != "" {
+			m.AddTag(d.FileTag, filepath.Base(path))
+		}
+		select {
+		case d.sem <- struct{}{}:
+			d.acc.AddTrackingMetric(m)
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		}
+	}
+	return nil
+}
+
+// moveFile renames the file, copying it when the directories are on
+// different file systems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("copy: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	in.Close()
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("remove: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("directory_monitor", func() cua.Input {
+		return &DirectoryMonitor{
+			FilesToIgnore:              []string{`\.DS_Store`},
+			MaxBufferedMetrics:         10000,
+			DirectoryDurationThreshold: internal.Duration{Duration: 50 * time.Millisecond},
+			FileQueueSize:              100000,
+			ParseMethod:                parseLineByLine,
+		}
+	})
+}
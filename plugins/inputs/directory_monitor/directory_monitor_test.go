@@ -0,0 +1,149 @@
+package directorymonitor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+)
+
+func newMonitor(t *testing.T) (*DirectoryMonitor, string, string, string) {
+	dir, finished, errDir := t.TempDir(), t.TempDir(), t.TempDir()
+	d := &DirectoryMonitor{
+		Directory:          dir,
+		FinishedDirectory:  finished,
+		ErrorDirectory:     errDir,
+		MaxBufferedMetrics: 1000,
+		FileQueueSize:      100,
+		Log:                testutil.Logger{},
+	}
+	return d, dir, finished, errDir
+}
+
+func waitMoved(t *testing.T, path string) {
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCSVLineByLine(t *testing.T) {
+	d, dir, finished, _ := newMonitor(t)
+	d.FileTag = "filename"
+	d.SetParserFunc(func() (parsers.Parser, error) {
+		return parsers.NewParser(&parsers.Config{
+			DataFormat:        "csv",
+			CSVHeaderRowCount: 1,
+			MetricName:        "batch",
+			CSVTagColumns:     []string{"host"},
+		})
+	})
+	require.NoError(t, d.Init())
+
+	data := "host,value\na,1\nb,2\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.csv"), []byte(data), 0600))
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Start(&acc))
+	defer d.Stop()
+	require.NoError(t, d.Gather(&acc))
+
+	acc.Wait(2)
+	waitMoved(t, filepath.Join(finished, "data.csv"))
+
+	acc.AssertContainsTaggedFields(t, "batch", map[string]interface{}{"value": int64(1)},
+		map[string]string{"host": "a", "filename": "data.csv"})
+	acc.AssertContainsTaggedFields(t, "batch", map[string]interface{}{"value": int64(2)},
+		map[string]string{"host": "b", "filename": "data.csv"})
+
+	_, err := os.Stat(filepath.Join(dir, "data.csv"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestGzipAtOnce(t *testing.T) {
+	d, dir, finished, _ := newMonitor(t)
+	d.ParseMethod = parseAtOnce
+	d.SetParserFunc(func() (parsers.Parser, error) {
+		return parsers.NewParser(&parsers.Config{
+			DataFormat: "json",
+			MetricName: "batch",
+		})
+	})
+	require.NoError(t, d.Init())
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte("[\n  {\"value\": 1},\n  {\"value\": 2}\n]\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.json.gz"), buf.Bytes(), 0600))
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Start(&acc))
+	defer d.Stop()
+	require.NoError(t, d.Gather(&acc))
+
+	acc.Wait(2)
+	waitMoved(t, filepath.Join(finished, "data.json.gz"))
+	require.Equal(t, uint64(2), acc.NMetrics())
+}
+
+func TestParseError(t *testing.T) {
+	d, dir, _, errDir := newMonitor(t)
+	d.SetParserFunc(parsers.NewInfluxParser)
+	require.NoError(t, d.Init())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.influx"), []byte("cpu value=1\nnot a metric\n"), 0600))
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Start(&acc))
+	defer d.Stop()
+	require.NoError(t, d.Gather(&acc))
+
+	waitMoved(t, filepath.Join(errDir, "bad.influx"))
+	require.Eventually(t, func() bool {
+		acc.Lock()
+		defer acc.Unlock()
+		return len(acc.Errors) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFileFilters(t *testing.T) {
+	d, dir, _, _ := newMonitor(t)
+	d.FilesToMonitor = []string{`\.influx$`}
+	d.FilesToIgnore = []string{`^skip`}
+	d.DirectoryDurationThreshold.Duration = time.Hour
+	d.SetParserFunc(parsers.NewInfluxParser)
+	require.NoError(t, d.Init())
+
+	require.True(t, d.monitored("cpu.influx"))
+	require.False(t, d.monitored("cpu.csv"))
+	require.False(t, d.monitored("skip.influx"))
+
+	// the file was modified too recently to be processed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cpu.influx"), []byte("cpu value=1\n"), 0600))
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Start(&acc))
+	defer d.Stop()
+	require.NoError(t, d.Gather(&acc))
+	require.Empty(t, d.queue)
+	require.Empty(t, d.pending)
+}
+
+func TestInitErrors(t *testing.T) {
+	d, _, _, _ := newMonitor(t)
+	d.ParseMethod = "sideways"
+	require.Error(t, d.Init())
+
+	d, _, _, _ = newMonitor(t)
+	d.FinishedDirectory = filepath.Join(d.Directory, "missing")
+	require.Error(t, d.Init())
+}
@@ -0,0 +1,149 @@
+package dnp3
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+var sampleConfig = `
+  ## Device name, added as the name tag.
+  name = "rtu1"
+
+  ## Address of the outstation, port 20000 by default.
+  address = "127.0.0.1:20000"
+
+  ## Link addresses of the agent (master) and of the outstation.
+  # master_address = 1
+  # outstation_address = 10
+
+  ## Timeout of the connection and of the poll.
+  # timeout = "10s"
+
+  ## Classes polled, 0 for the static data (integrity poll), 1 to 3 for the
+  ## events of the classes.
+  # classes = [0]
+
+  ## Optional names of the points, added as the point tag.  The types are
+  ## binary_input, double_bit_input, binary_output, counter, frozen_counter,
+  ## analog_input and analog_output.
+  # [[inputs.dnp3.points]]
+  #   type = "analog_input"
+  #   index = 0
+  #   name = "feeder1_current"
+`
+
+type point struct {
+	Type  string `toml:"type"`
+	Index int    `toml:"index"`
+	Name  string `toml:"name"`
+}
+
+type pointKey struct {
+	kind  string
+	index uint32
+}
+
+type DNP3 struct {
+	Name              string            `toml:"name"`
+	Address           string            `toml:"address"`
+	MasterAddress     int               `toml:"master_address"`
+	OutstationAddress int               `toml:"outstation_address"`
+	Timeout           internal.Duration `toml:"timeout"`
+	Classes           []int             `toml:"classes"`
+	Points            []point           `toml:"points"`
+
+	Log cua.Logger `toml:"-"`
+
+	points map[pointKey]string
+}
+
+func (d *DNP3) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DNP3) Description() string {
+	return "Poll the points of a DNP3 outstation over TCP"
+}
+
+func (d *DNP3) Init() error {
+	if d.Address == "" {
+		return errors.New("no address configured")
+	}
+	// the addresses from 0xFFF0 are reserved
+	for _, a := range []int{d.MasterAddress, d.OutstationAddress} {
+		if a < 0 || a >= 0xFFF0 {
+			return fmt.Errorf("invalid link address %d, expected 0 to 65519", a)
+		}
+	}
+	if len(d.Classes) == 0 {
+		return errors.New("no classes configured")
+	}
+	for _, class := range d.Classes {
+		if class < 0 || class > 3 {
+			return fmt.Errorf("invalid class %d, expected 0 to 3", class)
+		}
+	}
+	d.points = make(map[pointKey]string, len(d.Points))
+	for _, p := range d.Points {
+		if _, ok := specificFlags[p.Type]; !ok {
+			return fmt.Errorf("invalid type %q of point %q", p.Type, p.Name)
+		}
+		if p.Index < 0 || int64(p.Index) > 0xFFFFFFFF {
+			return fmt.Errorf("invalid index %d of point %q", p.Index, p.Name)
+		}
+		d.points[pointKey{kind: p.Type, index: uint32(p.Index)}] = p.Name
+	}
+	return nil
+}
+
+// Gather connects to the outstation and reads the classes configured, the
+// points are reported as they are received.
+func (d *DNP3) Gather(acc cua.Accumulator) error {
+	conn, err := net.DialTimeout("tcp", d.Address, d.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("dial (%s): %w", d.Address, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(d.Timeout.Duration)); err != nil {
+		return fmt.Errorf("set deadline: %w", err)
+	}
+
+	c := &session{
+		conn:   conn,
+		local:  uint16(d.MasterAddress),
+		remote: uint16(d.OutstationAddress),
+		dir:    linkDir,
+	}
+	outstation := strconv.Itoa(d.OutstationAddress)
+	return c.readClasses(d.Classes, func(p pointValue) {
+		tags := map[string]string{
+			"name":       d.Name,
+			"outstation": outstation,
+			"type":       p.kind,
+			"index":      strconv.FormatUint(uint64(p.index), 10),
+			"quality":    p.quality,
+		}
+		if name, ok := d.points[pointKey{kind: p.kind, index: p.index}]; ok && name != "" {
+			tags["point"] = name
+		}
+		acc.AddFields("dnp3", map[string]interface{}{"value": p.value}, tags)
+	})
+}
+
+func init() {
+	inputs.Add("dnp3", func() cua.Input {
+		return &DNP3{
+			MasterAddress:     1,
+			OutstationAddress: 10,
+			Timeout:           internal.Duration{Duration: 10 * time.Second},
+			Classes:           []int{0},
+		}
+	})
+}
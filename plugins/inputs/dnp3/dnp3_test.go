@@ -0,0 +1,145 @@
+package dnp3
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func float32Bytes(f float32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, math.Float32bits(f))
+	return b
+}
+
+// serve runs an outstation answering a read with an unsolicited response
+// followed by a response in 2 fragments, the first one sent in 2 transport
+// segments.  The fragments received from the agent are sent to received.
+func serve(t *testing.T, received chan<- []byte) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	first := []byte{appFir | appCon | 0, fcResponse, 0x00, 0x00,
+		// analog inputs 0-1, 32 bits with flags, the second over range
+		30, 1, 0x00, 0, 1, 0x01, 0xE8, 0x03, 0x00, 0x00, 0x21, 0xFF, 0xFF, 0xFF, 0x7F,
+		// binary inputs 0-2 with flags, the third offline
+		1, 2, 0x00, 0, 2, 0x81, 0x01, 0x80,
+	}
+	second := []byte{appFin | 1, fcResponse, 0x00, 0x00,
+		// counter 5, 32 bits with flags, with 2 bytes index prefix
+		20, 1, 0x28, 1, 0, 5, 0, 0x01, 0x10, 0x27, 0x00, 0x00,
+		// packed binary outputs 0-3
+		10, 1, 0x00, 0, 3, 0x05,
+		// time and date, skipped
+		50, 1, 0x07, 1, 0, 0, 0, 0, 0, 0,
+	}
+	second = append(second, 30, 5, 0x17, 1, 7, 0x01)
+	second = append(second, float32Bytes(-12.5)...)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		c := &session{conn: conn, local: 10, remote: 1}
+
+		request, err := c.readFragment()
+		if err != nil {
+			return
+		}
+		received <- request
+
+		unsolicited := []byte{appFir | appFin | appCon | appUns | 3, fcUnsolicited, 0x00, 0x00,
+			2, 1, 0x17, 1, 0, 0x81}
+		if c.sendFragment(unsolicited) != nil {
+			return
+		}
+		if confirm, err := c.readFragment(); err == nil {
+			received <- confirm
+		}
+
+		_ = c.sendLink(linkPrm|linkUnconfirmedData, append([]byte{transportFir | 0}, first[:20]...))
+		_ = c.sendLink(linkPrm|linkUnconfirmedData, append([]byte{transportFin | 1}, first[20:]...))
+		if confirm, err := c.readFragment(); err == nil {
+			received <- confirm
+		}
+		c.transportSeq = 2
+		_ = c.sendFragment(second)
+		_, _ = c.readFragment()
+	}()
+	return l.Addr().String()
+}
+
+func TestGather(t *testing.T) {
+	received := make(chan []byte, 3)
+	addr := serve(t, received)
+
+	d := &DNP3{
+		Name:              "rtu1",
+		Address:           addr,
+		MasterAddress:     1,
+		OutstationAddress: 10,
+		Timeout:           internal.Duration{Duration: 5 * time.Second},
+		Classes:           []int{0},
+		Points:            []point{{Type: "analog_input", Index: 0, Name: "feeder1_current"}},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, d.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, d.Gather(acc))
+	require.Equal(t, []byte{appFir | appFin, fcRead, 60, 1, 0x06}, <-received)
+	require.Equal(t, []byte{appFir | appFin | appUns | 3, fcConfirm}, <-received)
+	require.Equal(t, []byte{appFir | appFin, fcConfirm}, <-received)
+
+	m := func(kind, index, quality string, value interface{}) cua.Metric {
+		tags := map[string]string{"name": "rtu1", "outstation": "10", "type": kind, "index": index, "quality": quality}
+		if kind == "analog_input" && index == "0" {
+			tags["point"] = "feeder1_current"
+		}
+		return testutil.MustMetric("dnp3", tags, map[string]interface{}{"value": value}, time.Unix(0, 0))
+	}
+	expected := []cua.Metric{
+		m("analog_input", "0", "good", int64(1000)),
+		m("analog_input", "1", "over_range", int64(math.MaxInt32)),
+		m("binary_input", "0", "good", int64(1)),
+		m("binary_input", "1", "good", int64(0)),
+		m("binary_input", "2", "offline", int64(1)),
+		m("counter", "5", "good", int64(10000)),
+		m("binary_output", "0", "good", int64(1)),
+		m("binary_output", "1", "good", int64(0)),
+		m("binary_output", "2", "good", int64(1)),
+		m("binary_output", "3", "good", int64(0)),
+		m("analog_input", "7", "good", float64(-12.5)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+}
+
+func TestCRC(t *testing.T) {
+	// reset link states from the master 1024 to the outstation 1
+	require.Equal(t, uint16(0x21E9), crc([]byte{0x05, 0x64, 0x05, 0xC0, 0x01, 0x00, 0x00, 0x04}))
+}
+
+func TestParseObjectsInvalid(t *testing.T) {
+	fn := func(pointValue) {}
+	require.Error(t, parseObjects([]byte{30, 1, 0x00, 0, 1, 0x01, 0xE8, 0x03, 0x00, 0x00}, fn))
+	require.Error(t, parseObjects([]byte{30, 1, 0x00, 2, 1}, fn))
+	require.Error(t, parseObjects([]byte{99, 1, 0x07, 0}, fn))
+	require.Error(t, parseObjects([]byte{30, 1, 0x5B, 0}, fn))
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&DNP3{}).Init())
+	require.Error(t, (&DNP3{Address: "127.0.0.1:20000", Classes: []int{4}}).Init())
+	require.Error(t, (&DNP3{Address: "127.0.0.1:20000", OutstationAddress: 0xFFFF, Classes: []int{0}}).Init())
+	require.Error(t, (&DNP3{Address: "127.0.0.1:20000", Classes: []int{0}, Points: []point{{Type: "analog"}}}).Init())
+}
@@ -0,0 +1,628 @@
+package dnp3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+)
+
+// link layer
+const (
+	startByte0 = 0x05
+	startByte1 = 0x64
+
+	linkHeaderSize = 10
+	linkBlockSize  = 16
+	maxLinkData    = 250
+
+	linkDir = 0x80
+	linkPrm = 0x40
+
+	linkAck               = 0x00
+	linkStatus            = 0x0B
+	linkConfirmedData     = 0x03
+	linkUnconfirmedData   = 0x04
+	linkRequestLinkStatus = 0x09
+)
+
+// transport and application layers
+const (
+	transportFin = 0x80
+	transportFir = 0x40
+
+	appFir = 0x80
+	appFin = 0x40
+	appCon = 0x20
+	appUns = 0x10
+
+	fcConfirm      = 0x00
+	fcRead         = 0x01
+	fcResponse     = 0x81
+	fcUnsolicited  = 0x82
+	maxFragmentLen = 65536
+
+	// internal indications of the second octet reporting a request error
+	iin2Errors = 0x07
+)
+
+// flags of the objects
+const (
+	flagOnline       = 0x01
+	flagRestart      = 0x02
+	flagCommLost     = 0x04
+	flagRemoteForced = 0x08
+	flagLocalForced  = 0x10
+	flagSpecific1    = 0x20
+	flagSpecific2    = 0x40
+)
+
+// types of the points
+const (
+	binaryInput    = "binary_input"
+	doubleBitInput = "double_bit_input"
+	binaryOutput   = "binary_output"
+	counter        = "counter"
+	frozenCounter  = "frozen_counter"
+	analogInput    = "analog_input"
+	analogOutput   = "analog_output"
+)
+
+// specificFlags are the names of the flags depending on the type of point
+var specificFlags = map[string][2]string{
+	binaryInput:    {"chatter_filter", ""},
+	doubleBitInput: {"chatter_filter", ""},
+	binaryOutput:   {"", ""},
+	counter:        {"rollover", "discontinuity"},
+	frozenCounter:  {"rollover", "discontinuity"},
+	analogInput:    {"over_range", "reference_err"},
+	analogOutput:   {"over_range", "reference_err"},
+}
+
+type pointValue struct {
+	kind    string
+	index   uint32
+	value   interface{}
+	quality string
+}
+
+type decoder func(b []byte) interface{}
+
+// object describes a variation of an object group: the type of the points,
+// the size of the objects in bytes or in bits for the packed ones, whether
+// they start with flags, and the decoder of their value.  The objects without
+// type are skipped.
+type object struct {
+	kind   string
+	size   int
+	bits   int
+	flags  bool
+	decode decoder
+}
+
+func gv(group, variation byte) uint16 {
+	return uint16(group)<<8 | uint16(variation)
+}
+
+func uint32At(off int) decoder {
+	return func(b []byte) interface{} { return int64(binary.LittleEndian.Uint32(b[off:])) }
+}
+
+func uint16At(off int) decoder {
+	return func(b []byte) interface{} { return int64(binary.LittleEndian.Uint16(b[off:])) }
+}
+
+func int32At(off int) decoder {
+	return func(b []byte) interface{} { return int64(int32(binary.LittleEndian.Uint32(b[off:]))) }
+}
+
+func int16At(off int) decoder {
+	return func(b []byte) interface{} { return int64(int16(binary.LittleEndian.Uint16(b[off:]))) }
+}
+
+func float32At(off int) decoder {
+	return func(b []byte) interface{} { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b[off:]))) }
+}
+
+func float64At(off int) decoder {
+	return func(b []byte) interface{} { return math.Float64frombits(binary.LittleEndian.Uint64(b[off:])) }
+}
+
+// the state of the binaries with flags is their high bit, or high 2 bits for
+// the double bit binaries
+func binaryState(b []byte) interface{} {
+	return int64(b[0] >> 7)
+}
+
+func doubleBitState(b []byte) interface{} {
+	return int64(b[0] >> 6)
+}
+
+// the event objects have the same value as the static ones, followed by an
+// absolute (6 bytes) or relative (2 bytes) time which is not used
+var objects = map[uint16]object{
+	gv(1, 1): {kind: binaryInput, bits: 1},
+	gv(1, 2): {kind: binaryInput, size: 1, flags: true, decode: binaryState},
+	gv(2, 1): {kind: binaryInput, size: 1, flags: true, decode: binaryState},
+	gv(2, 2): {kind: binaryInput, size: 7, flags: true, decode: binaryState},
+	gv(2, 3): {kind: binaryInput, size: 3, flags: true, decode: binaryState},
+
+	gv(3, 1): {kind: doubleBitInput, bits: 2},
+	gv(3, 2): {kind: doubleBitInput, size: 1, flags: true, decode: doubleBitState},
+	gv(4, 1): {kind: doubleBitInput, size: 1, flags: true, decode: doubleBitState},
+	gv(4, 2): {kind: doubleBitInput, size: 7, flags: true, decode: doubleBitState},
+	gv(4, 3): {kind: doubleBitInput, size: 3, flags: true, decode: doubleBitState},
+
+	gv(10, 1): {kind: binaryOutput, bits: 1},
+	gv(10, 2): {kind: binaryOutput, size: 1, flags: true, decode: binaryState},
+	gv(11, 1): {kind: binaryOutput, size: 1, flags: true, decode: binaryState},
+	gv(11, 2): {kind: binaryOutput, size: 7, flags: true, decode: binaryState},
+
+	gv(20, 1):  {kind: counter, size: 5, flags: true, decode: uint32At(1)},
+	gv(20, 2):  {kind: counter, size: 3, flags: true, decode: uint16At(1)},
+	gv(20, 5):  {kind: counter, size: 4, decode: uint32At(0)},
+	gv(20, 6):  {kind: counter, size: 2, decode: uint16At(0)},
+	gv(21, 1):  {kind: frozenCounter, size: 5, flags: true, decode: uint32At(1)},
+	gv(21, 2):  {kind: frozenCounter, size: 3, flags: true, decode: uint16At(1)},
+	gv(21, 5):  {kind: frozenCounter, size: 11, flags: true, decode: uint32At(1)},
+	gv(21, 6):  {kind: frozenCounter, size: 9, flags: true, decode: uint16At(1)},
+	gv(21, 9):  {kind: frozenCounter, size: 4, decode: uint32At(0)},
+	gv(21, 10): {kind: frozenCounter, size: 2, decode: uint16At(0)},
+	gv(22, 1):  {kind: counter, size: 5, flags: true, decode: uint32At(1)},
+	gv(22, 2):  {kind: counter, size: 3, flags: true, decode: uint16At(1)},
+	gv(22, 5):  {kind: counter, size: 11, flags: true, decode: uint32At(1)},
+	gv(22, 6):  {kind: counter, size: 9, flags: true, decode: uint16At(1)},
+	gv(23, 1):  {kind: frozenCounter, size: 5, flags: true, decode: uint32At(1)},
+	gv(23, 2):  {kind: frozenCounter, size: 3, flags: true, decode: uint16At(1)},
+	gv(23, 5):  {kind: frozenCounter, size: 11, flags: true, decode: uint32At(1)},
+	gv(23, 6):  {kind: frozenCounter, size: 9, flags: true, decode: uint16At(1)},
+
+	gv(30, 1): {kind: analogInput, size: 5, flags: true, decode: int32At(1)},
+	gv(30, 2): {kind: analogInput, size: 3, flags: true, decode: int16At(1)},
+	gv(30, 3): {kind: analogInput, size: 4, decode: int32At(0)},
+	gv(30, 4): {kind: analogInput, size: 2, decode: int16At(0)},
+	gv(30, 5): {kind: analogInput, size: 5, flags: true, decode: float32At(1)},
+	gv(30, 6): {kind: analogInput, size: 9, flags: true, decode: float64At(1)},
+	gv(32, 1): {kind: analogInput, size: 5, flags: true, decode: int32At(1)},
+	gv(32, 2): {kind: analogInput, size: 3, flags: true, decode: int16At(1)},
+	gv(32, 3): {kind: analogInput, size: 11, flags: true, decode: int32At(1)},
+	gv(32, 4): {kind: analogInput, size: 9, flags: true, decode: int16At(1)},
+	gv(32, 5): {kind: analogInput, size: 5, flags: true, decode: float32At(1)},
+	gv(32, 6): {kind: analogInput, size: 9, flags: true, decode: float64At(1)},
+	gv(32, 7): {kind: analogInput, size: 11, flags: true, decode: float32At(1)},
+	gv(32, 8): {kind: analogInput, size: 15, flags: true, decode: float64At(1)},
+
+	gv(40, 1): {kind: analogOutput, size: 5, flags: true, decode: int32At(1)},
+	gv(40, 2): {kind: analogOutput, size: 3, flags: true, decode: int16At(1)},
+	gv(40, 3): {kind: analogOutput, size: 5, flags: true, decode: float32At(1)},
+	gv(40, 4): {kind: analogOutput, size: 9, flags: true, decode: float64At(1)},
+	gv(42, 1): {kind: analogOutput, size: 5, flags: true, decode: int32At(1)},
+	gv(42, 2): {kind: analogOutput, size: 3, flags: true, decode: int16At(1)},
+	gv(42, 3): {kind: analogOutput, size: 11, flags: true, decode: int32At(1)},
+	gv(42, 4): {kind: analogOutput, size: 9, flags: true, decode: int16At(1)},
+	gv(42, 5): {kind: analogOutput, size: 5, flags: true, decode: float32At(1)},
+	gv(42, 6): {kind: analogOutput, size: 9, flags: true, decode: float64At(1)},
+	gv(42, 7): {kind: analogOutput, size: 11, flags: true, decode: float32At(1)},
+	gv(42, 8): {kind: analogOutput, size: 15, flags: true, decode: float64At(1)},
+
+	// time and date, common time of occurrence and internal indications
+	gv(50, 1): {size: 6},
+	gv(51, 1): {size: 6},
+	gv(51, 2): {size: 6},
+	gv(52, 1): {size: 2},
+	gv(52, 2): {size: 2},
+	gv(80, 1): {bits: 1},
+}
+
+// quality returns the flags of the point, joined by commas, or good when the
+// point is online without any other flag set.
+func quality(kind string, f byte) string {
+	var flags []string
+	if f&flagOnline == 0 {
+		flags = append(flags, "offline")
+	}
+	if f&flagRestart != 0 {
+		flags = append(flags, "restart")
+	}
+	if f&flagCommLost != 0 {
+		flags = append(flags, "comm_lost")
+	}
+	if f&flagRemoteForced != 0 {
+		flags = append(flags, "remote_forced")
+	}
+	if f&flagLocalForced != 0 {
+		flags = append(flags, "local_forced")
+	}
+	specific := specificFlags[kind]
+	if f&flagSpecific1 != 0 && specific[0] != "" {
+		flags = append(flags, specific[0])
+	}
+	if f&flagSpecific2 != 0 && specific[1] != "" {
+		flags = append(flags, specific[1])
+	}
+	if len(flags) == 0 {
+		return "good"
+	}
+	return strings.Join(flags, ",")
+}
+
+func readUint(b []byte, size int) uint32 {
+	switch size {
+	case 1:
+		return uint32(b[0])
+	case 2:
+		return uint32(binary.LittleEndian.Uint16(b))
+	default:
+		return binary.LittleEndian.Uint32(b)
+	}
+}
+
+// qualifierSizes returns the size of the index prefixes and of the range
+// fields of an object header qualifier, and whether the range is a count.
+func qualifierSizes(qualifier byte) (prefix int, rng int, isCount bool, err error) {
+	switch (qualifier >> 4) & 0x07 {
+	case 0:
+		prefix = 0
+	case 1:
+		prefix = 1
+	case 2:
+		prefix = 2
+	case 3:
+		prefix = 4
+	default:
+		return 0, 0, false, fmt.Errorf("unsupported qualifier 0x%02x", qualifier)
+	}
+	switch qualifier & 0x0F {
+	case 0x00:
+		return prefix, 1, false, nil
+	case 0x01:
+		return prefix, 2, false, nil
+	case 0x02:
+		return prefix, 4, false, nil
+	case 0x07:
+		return prefix, 1, true, nil
+	case 0x08:
+		return prefix, 2, true, nil
+	case 0x09:
+		return prefix, 4, true, nil
+	}
+	return 0, 0, false, fmt.Errorf("unsupported qualifier 0x%02x", qualifier)
+}
+
+// parseObjects decodes the objects of a response, the points are passed to
+// fn as they are decoded.
+func parseObjects(b []byte, fn func(p pointValue)) error {
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return errors.New("truncated object header")
+		}
+		group, variation, qualifier := b[0], b[1], b[2]
+		b = b[3:]
+
+		prefix, rng, isCount, err := qualifierSizes(qualifier)
+		if err != nil {
+			return fmt.Errorf("g%dv%d: %w", group, variation, err)
+		}
+		var start, count uint32
+		if isCount {
+			if len(b) < rng {
+				return fmt.Errorf("g%dv%d: truncated range", group, variation)
+			}
+			count = readUint(b, rng)
+			b = b[rng:]
+		} else {
+			if len(b) < 2*rng {
+				return fmt.Errorf("g%dv%d: truncated range", group, variation)
+			}
+			start = readUint(b, rng)
+			stop := readUint(b[rng:], rng)
+			b = b[2*rng:]
+			if stop < start {
+				return fmt.Errorf("g%dv%d: invalid range %d-%d", group, variation, start, stop)
+			}
+			count = stop - start + 1
+		}
+
+		obj, ok := objects[gv(group, variation)]
+		if !ok {
+			return fmt.Errorf("unsupported object g%dv%d", group, variation)
+		}
+
+		if obj.bits > 0 {
+			if prefix != 0 {
+				return fmt.Errorf("g%dv%d: unsupported index prefix of packed objects", group, variation)
+			}
+			size := (uint64(count)*uint64(obj.bits) + 7) / 8
+			if uint64(len(b)) < size {
+				return fmt.Errorf("g%dv%d: truncated objects", group, variation)
+			}
+			if obj.kind != "" {
+				mask := byte(1)<<obj.bits - 1
+				for i := uint32(0); i < count; i++ {
+					bit := i * uint32(obj.bits)
+					value := b[bit/8] >> (bit % 8) & mask
+					fn(pointValue{kind: obj.kind, index: start + i, value: int64(value), quality: "good"})
+				}
+			}
+			b = b[size:]
+			continue
+		}
+
+		for i := uint32(0); i < count; i++ {
+			index := start + i
+			if prefix > 0 {
+				if len(b) < prefix {
+					return fmt.Errorf("g%dv%d: truncated index", group, variation)
+				}
+				index = readUint(b, prefix)
+				b = b[prefix:]
+			}
+			if len(b) < obj.size {
+				return fmt.Errorf("g%dv%d: truncated object %d", group, variation, index)
+			}
+			if obj.kind != "" {
+				q := "good"
+				if obj.flags {
+					q = quality(obj.kind, b[0])
+				}
+				fn(pointValue{kind: obj.kind, index: index, value: obj.decode(b[:obj.size]), quality: q})
+			}
+			b = b[obj.size:]
+		}
+	}
+	return nil
+}
+
+// crc returns the CRC of the link frame blocks.
+func crc(b []byte) uint16 {
+	var c uint16
+	for _, x := range b {
+		c ^= uint16(x)
+		for i := 0; i < 8; i++ {
+			if c&0x01 != 0 {
+				c = c>>1 ^ 0xA6BC
+			} else {
+				c >>= 1
+			}
+		}
+	}
+	return ^c
+}
+
+func appendBlock(b []byte, block []byte) []byte {
+	c := crc(block)
+	b = append(b, block...)
+	return append(b, byte(c), byte(c>>8))
+}
+
+// session is a link between the agent and an outstation, with the sequence
+// numbers of the transport segments and application fragments sent.
+type session struct {
+	conn   net.Conn
+	local  uint16
+	remote uint16
+	// direction bit of the frames sent, set by the master
+	dir byte
+
+	transportSeq byte
+	appSeq       byte
+	frameBuf     [linkHeaderSize + maxLinkData + 2*(maxLinkData/linkBlockSize+1)]byte
+}
+
+func (c *session) sendLink(control byte, data []byte) error {
+	header := []byte{startByte0, startByte1, byte(5 + len(data)), c.dir | control,
+		byte(c.remote), byte(c.remote >> 8), byte(c.local), byte(c.local >> 8)}
+	frame := appendBlock(make([]byte, 0, linkHeaderSize+len(data)+2*(len(data)/linkBlockSize+1)), header)
+	for len(data) > 0 {
+		n := len(data)
+		if n > linkBlockSize {
+			n = linkBlockSize
+		}
+		frame = appendBlock(frame, data[:n])
+		data = data[n:]
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+// sendFragment sends an application fragment, in as many transport segments
+// as needed.
+func (c *session) sendFragment(fragment []byte) error {
+	first := true
+	for {
+		n := len(fragment)
+		if n > maxLinkData-1 {
+			n = maxLinkData - 1
+		}
+		th := c.transportSeq
+		c.transportSeq = (c.transportSeq + 1) % 64
+		if first {
+			th |= transportFir
+		}
+		if n == len(fragment) {
+			th |= transportFin
+		}
+		if err := c.sendLink(linkPrm|linkUnconfirmedData, append([]byte{th}, fragment[:n]...)); err != nil {
+			return err
+		}
+		fragment = fragment[n:]
+		first = false
+		if len(fragment) == 0 {
+			return nil
+		}
+	}
+}
+
+// readSegment returns the next transport segment sent by the remote station,
+// answering the requests of the link layer.
+func (c *session) readSegment() ([]byte, error) {
+	for {
+		header := c.frameBuf[:linkHeaderSize]
+		if _, err := io.ReadFull(c.conn, header); err != nil {
+			return nil, fmt.Errorf("read: %w", err)
+		}
+		if header[0] != startByte0 || header[1] != startByte1 {
+			return nil, fmt.Errorf("invalid start bytes 0x%02x%02x", header[0], header[1])
+		}
+		if crc(header[:8]) != binary.LittleEndian.Uint16(header[8:]) {
+			return nil, errors.New("invalid CRC of the link header")
+		}
+		if header[2] < 5 {
+			return nil, fmt.Errorf("invalid link length %d", header[2])
+		}
+		size := int(header[2]) - 5
+		blocks := c.frameBuf[linkHeaderSize : linkHeaderSize+size+2*((size+linkBlockSize-1)/linkBlockSize)]
+		if _, err := io.ReadFull(c.conn, blocks); err != nil {
+			return nil, fmt.Errorf("read: %w", err)
+		}
+		data := make([]byte, 0, size)
+		for len(blocks) > 0 {
+			n := len(blocks) - 2
+			if n > linkBlockSize {
+				n = linkBlockSize
+			}
+			if crc(blocks[:n]) != binary.LittleEndian.Uint16(blocks[n:]) {
+				return nil, errors.New("invalid CRC of the link data")
+			}
+			data = append(data, blocks[:n]...)
+			blocks = blocks[n+2:]
+		}
+
+		control := header[3]
+		dest := binary.LittleEndian.Uint16(header[4:])
+		src := binary.LittleEndian.Uint16(header[6:])
+		if dest != c.local || src != c.remote || control&linkPrm == 0 {
+			continue
+		}
+		switch control & 0x0F {
+		case linkConfirmedData:
+			if err := c.sendLink(linkAck, nil); err != nil {
+				return nil, err
+			}
+		case linkUnconfirmedData:
+		case linkRequestLinkStatus:
+			if err := c.sendLink(linkStatus, nil); err != nil {
+				return nil, err
+			}
+			continue
+		default:
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		return data, nil
+	}
+}
+
+// readFragment returns the next application fragment sent by the remote
+// station, reassembled from its transport segments.
+func (c *session) readFragment() ([]byte, error) {
+	var fragment []byte
+	for {
+		segment, err := c.readSegment()
+		if err != nil {
+			return nil, err
+		}
+		th := segment[0]
+		if th&transportFir != 0 {
+			fragment = make([]byte, 0, len(segment)-1)
+		} else if fragment == nil {
+			// the first segments of the fragment were lost
+			continue
+		}
+		fragment = append(fragment, segment[1:]...)
+		if len(fragment) > maxFragmentLen {
+			return nil, errors.New("fragment too large")
+		}
+		if th&transportFin != 0 {
+			return fragment, nil
+		}
+	}
+}
+
+func (c *session) confirm(control byte) error {
+	return c.sendFragment([]byte{appFir | appFin | control&(appUns|0x0F), fcConfirm})
+}
+
+// readClasses reads the events of the classes 1 to 3, or the static data of
+// the class 0, the points of the response are passed to fn.  The unsolicited
+// responses received meanwhile are confirmed and ignored.
+func (c *session) readClasses(classes []int, fn func(p pointValue)) error {
+	seq := c.appSeq
+	c.appSeq = (c.appSeq + 1) % 16
+	request := []byte{appFir | appFin | seq, fcRead}
+	for _, class := range classes {
+		request = append(request, 60, byte(class+1), 0x06)
+	}
+	if err := c.sendFragment(request); err != nil {
+		return err
+	}
+
+	started := false
+	for {
+		fragment, err := c.readFragment()
+		if err != nil {
+			return err
+		}
+		if len(fragment) < 2 {
+			return errors.New("truncated application header")
+		}
+		control, function := fragment[0], fragment[1]
+		if function == fcUnsolicited {
+			if control&appCon != 0 {
+				if err := c.confirm(control); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if function != fcResponse {
+			continue
+		}
+		if !started {
+			// the first fragment of the response has the sequence number of
+			// the request
+			if control&appFir == 0 || control&0x0F != seq {
+				continue
+			}
+			started = true
+		}
+		if len(fragment) < 4 {
+			return errors.New("truncated application header")
+		}
+		if iin := fragment[3]; iin&iin2Errors != 0 {
+			return fmt.Errorf("request failed: %s", requestErrors(iin))
+		}
+		if err := parseObjects(fragment[4:], fn); err != nil {
+			return err
+		}
+		if control&appCon != 0 {
+			if err := c.confirm(control); err != nil {
+				return err
+			}
+		}
+		if control&appFin != 0 {
+			return nil
+		}
+	}
+}
+
+func requestErrors(iin byte) string {
+	var errs []string
+	if iin&0x01 != 0 {
+		errs = append(errs, "function code not supported")
+	}
+	if iin&0x02 != 0 {
+		errs = append(errs, "object unknown")
+	}
+	if iin&0x04 != 0 {
+		errs = append(errs, "parameter error")
+	}
+	return strings.Join(errs, ", ")
+}
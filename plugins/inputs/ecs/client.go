@@ -16,6 +16,7 @@ var (
 	ecsMetaStatsPath = "/v2/stats"
 
 	// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v3.html
+	// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v4.html
 	ecsMetadataPathV3  = "/task"
 	ecsMetaStatsPathV3 = "/task/stats"
 )
@@ -32,8 +33,8 @@ type httpClient interface {
 
 // NewClient constructs an ECS client with the passed configuration params
 func NewClient(timeout time.Duration, endpoint string, version int) (*Connection, error) {
-	if version != 2 && version != 3 {
-		const msg = "expected metadata version 2 or 3, got %d"
+	if version < 2 || version > 4 {
+		const msg = "expected metadata version 2, 3 or 4, got %d"
 		return nil, fmt.Errorf(msg, version)
 	}
 
@@ -60,7 +61,7 @@ func resolveTaskURL(base *url.URL, version int) string {
 	switch version {
 	case 2:
 		path = ecsMetadataPath
-	case 3:
+	case 3, 4:
 		path = ecsMetadataPathV3
 	default:
 		// Should never happen.
@@ -75,7 +76,7 @@ func resolveStatsURL(base *url.URL, version int) string {
 	switch version {
 	case 2:
 		path = ecsMetaStatsPath
-	case 3:
+	case 3, 4:
 		path = ecsMetaStatsPathV3
 	default:
 		// Should never happen.
@@ -268,6 +268,12 @@ func TestResolveTaskURL(t *testing.T) {
 			ver:  3,
 			exp:  "http://169.254.170.2/v3/metadata/task",
 		},
+		{
+			name: "v4 endpoint",
+			base: "http://169.254.170.2/v4/0123",
+			ver:  4,
+			exp:  "http://169.254.170.2/v4/0123/task",
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -306,6 +312,12 @@ func TestResolveStatsURL(t *testing.T) {
 			ver:  3,
 			exp:  "http://169.254.170.2/v3/metadata/task/stats",
 		},
+		{
+			name: "v4 endpoint",
+			base: "http://169.254.170.2/v4/0123",
+			ver:  4,
+			exp:  "http://169.254.170.2/v4/0123/task/stats",
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
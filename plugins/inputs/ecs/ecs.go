@@ -48,8 +48,8 @@ const (
 
 var sampleConfig = `
   ## ECS metadata url.
-  ## Metadata v2 API is used if set explicitly. Otherwise,
-  ## v3 metadata endpoint API is used if available.
+  ## Metadata v2 API is used if set explicitly. Otherwise, the v4 or v3
+  ## metadata endpoint API is used if available.
   # endpoint_url = ""
 
   ## Containers to include and exclude. Globs accepted.
@@ -75,7 +75,7 @@ var sampleConfig = `
 
 // Description describes ECS plugin
 func (ecs *Ecs) Description() string {
-	return "Read metrics about docker containers from Fargate/ECS v2, v3 meta endpoints."
+	return "Read metrics about docker containers from Fargate/ECS v2, v3, v4 meta endpoints."
 }
 
 // SampleConfig returns the ECS example config
@@ -103,6 +103,12 @@ func (ecs *Ecs) Gather(acc cua.Accumulator) error {
 		"family":   task.Family,
 		"revision": task.Revision,
 	}
+	if task.ServiceName != "" {
+		taskTags["service"] = task.ServiceName
+	}
+	if task.LaunchType != "" {
+		taskTags["launch_type"] = task.LaunchType
+	}
 
 	// accumulate metrics
 	ecs.accTask(task, taskTags, acc)
@@ -151,6 +157,16 @@ func resolveEndpoint(ecs *Ecs) {
 
 	// Auto-detect metadata endpoint version.
 
+	// Use metadata v4 if available, it is the only version available on
+	// Fargate platform version 1.4.0 and later.
+	// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v4.html
+	v4Endpoint := os.Getenv("ECS_CONTAINER_METADATA_URI_V4")
+	if v4Endpoint != "" {
+		ecs.EndpointURL = v4Endpoint
+		ecs.metadataVersion = 4
+		return
+	}
+
 	// Use metadata v3 if available.
 	// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v3.html
 	v3Endpoint := os.Getenv("ECS_CONTAINER_METADATA_URI")
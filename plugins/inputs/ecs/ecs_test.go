@@ -5,8 +5,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/docker/docker/api/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // codified golden objects for tests
@@ -813,6 +815,24 @@ func TestResolveEndpoint(t *testing.T) {
 				metadataVersion: 3,
 			},
 		},
+		{
+			name: "Endpoint is not set, ECS_CONTAINER_METADATA_URI_V4 is set => use v4 metadata",
+			preF: func() {
+				os.Setenv("ECS_CONTAINER_METADATA_URI", "v3-endpoint.local")
+				os.Setenv("ECS_CONTAINER_METADATA_URI_V4", "v4-endpoint.local")
+			},
+			afterF: func() {
+				os.Unsetenv("ECS_CONTAINER_METADATA_URI")
+				os.Unsetenv("ECS_CONTAINER_METADATA_URI_V4")
+			},
+			given: Ecs{
+				EndpointURL: "",
+			},
+			exp: Ecs{
+				EndpointURL:     "v4-endpoint.local",
+				metadataVersion: 4,
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -830,3 +850,42 @@ func TestResolveEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestGatherV4TaskTags(t *testing.T) {
+	r, err := os.Open("testdata/metadata_v4.golden")
+	require.NoError(t, err)
+	defer r.Close()
+	task, err := unmarshalTask(r)
+	require.NoError(t, err)
+
+	ecs := &Ecs{
+		client: &pollMock{
+			task: func() (*Task, error) {
+				return task, nil
+			},
+			stats: func() (map[string]types.StatsJSON, error) {
+				return map[string]types.StatsJSON{}, nil
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, ecs.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "ecs_task",
+		map[string]interface{}{
+			"revision":       "3",
+			"desired_status": "RUNNING",
+			"known_status":   "RUNNING",
+			"limit_cpu":      0.25,
+			"limit_mem":      512.0,
+		},
+		map[string]string{
+			"cluster":     "arn:aws:ecs:us-west-2:111122223333:cluster/default",
+			"task_arn":    "arn:aws:ecs:us-west-2:111122223333:task/default/e9028f8d5d8e4f258373e7b93ce9a3c3",
+			"family":      "curltest",
+			"revision":    "3",
+			"service":     "curltest-service",
+			"launch_type": "FARGATE",
+		},
+	)
+}
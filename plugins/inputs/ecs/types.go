@@ -16,6 +16,11 @@ type Task struct {
 	TaskARN       string
 	Family        string
 	Revision      string
+	// ServiceName, LaunchType and AvailabilityZone are only reported by the
+	// v4 metadata endpoint.
+	ServiceName      string
+	LaunchType       string
+	AvailabilityZone string
 	DesiredStatus string
 	KnownStatus   string
 	Containers    []Container
@@ -50,15 +55,19 @@ type Network struct {
 
 func unmarshalTask(r io.Reader) (*Task, error) {
 	task := &Task{}
-	err := json.NewDecoder(r).Decode(task)
-	return task, fmt.Errorf("json decode: %w", err)
+	if err := json.NewDecoder(r).Decode(task); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+	return task, nil
 }
 
 // docker parsers
 func unmarshalStats(r io.Reader) (map[string]types.StatsJSON, error) {
 	var statsMap map[string]types.StatsJSON
-	err := json.NewDecoder(r).Decode(&statsMap)
-	return statsMap, fmt.Errorf("json decode: %w", err)
+	if err := json.NewDecoder(r).Decode(&statsMap); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+	return statsMap, nil
 }
 
 // interleaves Stats in to the Container objects in the Task
@@ -17,6 +17,22 @@ func Test_parseTask(t *testing.T) {
 	require.Equal(t, validMeta, *parsed)
 }
 
+func Test_parseTaskV4(t *testing.T) {
+	r, err := os.Open("testdata/metadata_v4.golden")
+	require.NoError(t, err)
+
+	parsed, err := unmarshalTask(r)
+	require.NoError(t, err)
+
+	require.Equal(t, "arn:aws:ecs:us-west-2:111122223333:cluster/default", parsed.Cluster)
+	require.Equal(t, "curltest-service", parsed.ServiceName)
+	require.Equal(t, "FARGATE", parsed.LaunchType)
+	require.Equal(t, "us-west-2a", parsed.AvailabilityZone)
+	require.Equal(t, map[string]float64{"CPU": 0.25, "Memory": 512}, parsed.Limits)
+	require.Len(t, parsed.Containers, 1)
+	require.Equal(t, "curl", parsed.Containers[0].Name)
+}
+
 func Test_parseStats(t *testing.T) {
 	r, err := os.Open("testdata/stats.golden")
 	require.NoError(t, err)
@@ -2,7 +2,9 @@ package exec
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
@@ -15,6 +17,7 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/nagios"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/sensu"
 	"github.com/kballard/go-shellquote"
 )
 
@@ -29,6 +32,14 @@ const sampleConfig = `
   ## Timeout for each command to complete.
   timeout = "5s"
 
+  ## Environment variables of the commands, in the "NAME=value" form, added
+  ## to the environment of the agent.
+  # environment = []
+
+  ## Tag the metrics with the name of the command, the base name of the
+  ## executable or the name of the job.
+  # command_tag = "command"
+
   ## measurement name suffix (for separating different commands)
   name_suffix = "_mycollector"
 
@@ -37,14 +48,25 @@ const sampleConfig = `
   ## more about them here:
   ## https://github.com/circonus-labs/circonus-unified-agent/blob/master/docs/DATA_FORMATS_INPUT.md
   data_format = "influx"
+
+  ## Commands with their own name, timeout and environment, the environment
+  ## is added to the one of the plugin.
+  # [[inputs.exec.job]]
+  #   name = "backup"
+  #   command = "/usr/local/bin/backup_status --json"
+  #   timeout = "30s"
+  #   environment = ["BACKUP_DIR=/srv/backup"]
 `
 
 const MaxStderrBytes = 512
 
 type Exec struct {
-	Commands []string
-	Command  string
-	Timeout  internal.Duration
+	Commands    []string
+	Command     string
+	Timeout     internal.Duration
+	Environment []string `toml:"environment"`
+	CommandTag  string   `toml:"command_tag"`
+	Jobs        []*Job   `toml:"job"`
 
 	parser parsers.Parser
 
@@ -59,14 +81,23 @@ func NewExec() *Exec {
 	}
 }
 
+// Job is a command run with its own name, timeout and environment.
+type Job struct {
+	Name        string            `toml:"name"`
+	Command     string            `toml:"command"`
+	Timeout     internal.Duration `toml:"timeout"`
+	Environment []string          `toml:"environment"`
+}
+
 type Runner interface {
-	Run(string, time.Duration) ([]byte, []byte, error)
+	Run(string, []string, time.Duration) ([]byte, []byte, error)
 }
 
 type CommandRunner struct{}
 
 func (c CommandRunner) Run(
 	command string,
+	environment []string,
 	timeout time.Duration,
 ) ([]byte, []byte, error) {
 	splitCmd, err := shellquote.Split(command)
@@ -75,6 +106,10 @@ func (c CommandRunner) Run(
 	}
 
 	cmd := exec.Command(splitCmd[0], splitCmd[1:]...) //nolint:gosec // G204
+	if len(environment) > 0 {
+		// the last value of a variable is used
+		cmd.Env = append(os.Environ(), environment...)
+	}
 
 	var (
 		out    bytes.Buffer
@@ -144,13 +179,33 @@ func removeCarriageReturns(b bytes.Buffer) bytes.Buffer {
 
 }
 
-func (e *Exec) ProcessCommand(command string, acc cua.Accumulator, wg *sync.WaitGroup) {
+// ProcessCommand runs the command of the job, its glob pattern already
+// matched, and adds the metrics parsed from its output.
+func (e *Exec) ProcessCommand(job *Job, acc cua.Accumulator, wg *sync.WaitGroup) {
 	defer wg.Done()
 	_, isNagios := e.parser.(*nagios.Parser)
+	// the exit code of a check is its state, not a failure
+	_, isSensu := e.parser.(*sensu.Parser)
+	isCheck := isNagios || isSensu
 
-	out, errbuf, runErr := e.runner.Run(command, e.Timeout.Duration)
-	if !isNagios && runErr != nil {
-		err := fmt.Errorf("exec: %w for command '%s': %s", runErr, command, string(errbuf))
+	timeout := job.Timeout.Duration
+	if timeout == 0 {
+		timeout = e.Timeout.Duration
+	}
+	var environment []string
+	if len(job.Environment) > 0 {
+		environment = append(append(environment, e.Environment...), job.Environment...)
+	} else {
+		environment = e.Environment
+	}
+
+	out, errbuf, runErr := e.runner.Run(job.Command, environment, timeout)
+	if runErr != nil && (!isCheck || !isExitError(runErr)) {
+		if isNagios {
+			// the check did not complete, such as on a timeout
+			e.addUnknownState(acc, job, runErr)
+		}
+		err := fmt.Errorf("exec: %w for command '%s': %s", runErr, job.Command, string(errbuf))
 		acc.AddError(err)
 		return
 	}
@@ -169,6 +224,33 @@ func (e *Exec) ProcessCommand(command string, acc cua.Accumulator, wg *sync.Wait
 	}
 
 	for _, m := range metrics {
+		if e.CommandTag != "" {
+			m.AddTag(e.CommandTag, job.Name)
+		}
+		acc.AddMetric(m)
+	}
+}
+
+// isExitError returns whether the command ran and exited with a non-zero
+// exit code.
+func isExitError(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr)
+}
+
+// addUnknownState adds the UNKNOWN state of a nagios check which did not
+// run to completion.
+func (e *Exec) addUnknownState(acc cua.Accumulator, job *Job, runErr error) {
+	metrics, err := nagios.AddState(nil, nagios.StateUnknown)
+	if err != nil {
+		e.Log.Errorf("Failed to add nagios state: %s", err)
+		return
+	}
+	for _, m := range metrics {
+		m.AddField("service_output", runErr.Error())
+		if e.CommandTag != "" {
+			m.AddTag(e.CommandTag, job.Name)
+		}
 		acc.AddMetric(m)
 	}
 }
@@ -193,46 +275,67 @@ func (e *Exec) Gather(acc cua.Accumulator) error {
 		e.Command = ""
 	}
 
-	commands := make([]string, 0, len(e.Commands))
+	jobs := make([]*Job, 0, len(e.Commands)+len(e.Jobs))
 	for _, pattern := range e.Commands {
-		cmdAndArgs := strings.SplitN(pattern, " ", 2)
-		if len(cmdAndArgs) == 0 {
-			continue
-		}
-
-		matches, err := filepath.Glob(cmdAndArgs[0])
-		if err != nil {
-			acc.AddError(err)
-			continue
-		}
-
-		if len(matches) == 0 {
-			// There were no matches with the glob pattern, so let's assume
-			// that the command is in PATH and just run it as it is
-			commands = append(commands, pattern)
-		} else {
-			// There were matches, so we'll append each match together with
-			// the arguments to the commands slice
-			for _, match := range matches {
-				if len(cmdAndArgs) == 1 {
-					commands = append(commands, match)
-				} else {
-					commands = append(commands,
-						strings.Join([]string{match, cmdAndArgs[1]}, " "))
-				}
-			}
-		}
+		jobs = e.expand(acc, jobs, &Job{Command: pattern})
+	}
+	for _, job := range e.Jobs {
+		jobs = e.expand(acc, jobs, job)
 	}
 
-	wg.Add(len(commands))
-	for _, command := range commands {
-		go e.ProcessCommand(command, acc, &wg)
+	wg.Add(len(jobs))
+	for _, job := range jobs {
+		go e.ProcessCommand(job, acc, &wg)
 	}
 	wg.Wait()
 	return nil
 }
 
+// expand appends to jobs the job for each command matching the glob pattern
+// of the command of the job, named after the job or the executable.
+func (e *Exec) expand(acc cua.Accumulator, jobs []*Job, job *Job) []*Job {
+	cmdAndArgs := strings.SplitN(job.Command, " ", 2)
+	if len(cmdAndArgs) == 0 {
+		return jobs
+	}
+
+	matches, err := filepath.Glob(cmdAndArgs[0])
+	if err != nil {
+		acc.AddError(err)
+		return jobs
+	}
+
+	if len(matches) == 0 {
+		// There were no matches with the glob pattern, so let's assume
+		// that the command is in PATH and just run it as it is
+		matches = []string{cmdAndArgs[0]}
+	}
+	// each match is run with the arguments of the command
+	for _, match := range matches {
+		command := match
+		if len(cmdAndArgs) > 1 {
+			command = strings.Join([]string{match, cmdAndArgs[1]}, " ")
+		}
+		name := job.Name
+		if name == "" {
+			name = filepath.Base(match)
+		}
+		jobs = append(jobs, &Job{
+			Name:        name,
+			Command:     command,
+			Timeout:     job.Timeout,
+			Environment: job.Environment,
+		})
+	}
+	return jobs
+}
+
 func (e *Exec) Init() error {
+	for i, job := range e.Jobs {
+		if job.Command == "" {
+			return fmt.Errorf("job %d: command is required", i+1)
+		}
+	}
 	return nil
 }
 
@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"fmt"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/circonus-labs/circonus-unified-agent/internal"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
@@ -90,7 +92,7 @@ func newRunnerMock(out []byte, errout []byte, err error) Runner {
 	}
 }
 
-func (r runnerMock) Run(command string, _ time.Duration) ([]byte, []byte, error) {
+func (r runnerMock) Run(command string, _ []string, _ time.Duration) ([]byte, []byte, error) {
 	return r.out, r.errout, r.err
 }
 
@@ -284,3 +286,107 @@ func TestRemoveCarriageReturns(t *testing.T) {
 		}
 	}
 }
+
+type recordingRunner struct {
+	sync.Mutex
+	environments map[string][]string
+	timeouts     map[string]time.Duration
+}
+
+func (r *recordingRunner) Run(command string, environment []string, timeout time.Duration) ([]byte, []byte, error) {
+	r.Lock()
+	defer r.Unlock()
+	r.environments[command] = environment
+	r.timeouts[command] = timeout
+	return []byte("42"), nil, nil
+}
+
+func TestExecJobs(t *testing.T) {
+	parser, _ := parsers.NewValueParser("metric", "integer", nil)
+	runner := &recordingRunner{
+		environments: make(map[string][]string),
+		timeouts:     make(map[string]time.Duration),
+	}
+	e := NewExec()
+	e.Log = testutil.Logger{}
+	e.runner = runner
+	e.Commands = []string{"/usr/bin/collector --all"}
+	e.Environment = []string{"LANG=C", "MODE=fast"}
+	e.CommandTag = "command"
+	e.Jobs = []*Job{{
+		Name:        "backup",
+		Command:     "backup_status",
+		Timeout:     internal.Duration{Duration: time.Minute},
+		Environment: []string{"MODE=slow"},
+	}}
+	e.SetParser(parser)
+	require.NoError(t, e.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(e.Gather))
+
+	require.Equal(t, 5*time.Second, runner.timeouts["/usr/bin/collector --all"])
+	require.Equal(t, []string{"LANG=C", "MODE=fast"}, runner.environments["/usr/bin/collector --all"])
+	require.Equal(t, time.Minute, runner.timeouts["backup_status"])
+	require.Equal(t, []string{"LANG=C", "MODE=fast", "MODE=slow"}, runner.environments["backup_status"])
+
+	acc.AssertContainsTaggedFields(t, "metric", map[string]interface{}{"value": int64(42)}, map[string]string{"command": "collector"})
+	acc.AssertContainsTaggedFields(t, "metric", map[string]interface{}{"value": int64(42)}, map[string]string{"command": "backup"})
+}
+
+func TestExecJobRequiresCommand(t *testing.T) {
+	e := NewExec()
+	e.Jobs = []*Job{{Name: "empty"}}
+	require.Error(t, e.Init())
+}
+
+func TestExecEnvironment(t *testing.T) {
+	parser, _ := parsers.NewValueParser("metric", "string", nil)
+	e := NewExec()
+	e.Commands = []string{"printenv EXEC_TEST_VALUE"}
+	e.Environment = []string{"EXEC_TEST_VALUE=from_agent"}
+	e.SetParser(parser)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(e.Gather))
+	acc.AssertContainsFields(t, "metric", map[string]interface{}{"value": "from_agent"})
+}
+
+func TestExecNagiosTimeout(t *testing.T) {
+	parser, _ := parsers.NewNagiosParser()
+	e := &Exec{
+		Log:        testutil.Logger{},
+		runner:     newRunnerMock(nil, nil, fmt.Errorf("run error: %w", internal.ErrTimeout)),
+		Commands:   []string{"check_slow"},
+		CommandTag: "command",
+		parser:     parser,
+	}
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(e.Gather))
+	acc.AssertContainsTaggedFields(t, "nagios_state",
+		map[string]interface{}{
+			"state":          int64(3),
+			"status":         "UNKNOWN",
+			"service_output": "run error: command timed out",
+		},
+		map[string]string{"command": "check_slow"})
+}
+
+func TestExecSensuExitCode(t *testing.T) {
+	parser, _ := parsers.NewSensuParser(nil)
+	e := NewExec()
+	e.Log = testutil.Logger{}
+	e.Commands = []string{`sh -c 'echo "{\"name\": \"check_x\", \"status\": 2, \"output\": \"X CRITICAL\"}"; exit 2'`}
+	e.SetParser(parser)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(e.Gather))
+	acc.AssertContainsTaggedFields(t, "sensu_check",
+		map[string]interface{}{
+			"state":          int64(2),
+			"status":         "CRITICAL",
+			"service_output": "X CRITICAL",
+		},
+		map[string]string{"check": "check_x"})
+}
@@ -1,8 +1,10 @@
 package filestat
 
 import (
+	"crypto/md5" //nolint:gosec // G501 -- md5 is used to compare files, not for security
 	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 
@@ -23,11 +25,15 @@ const sampleConfig = `
   ##
   files = ["/var/log/**.log"]
 
+  ## If true, read the entire file and calculate an md5 checksum.
+  md5 = false
+
   ## If true, read the entire file and calculate an sha256 checksum.
   sha256 = false
 `
 
 type FileStat struct {
+	MD5    bool
 	SHA256 bool
 	Files  []string
 
@@ -95,6 +101,15 @@ func (f *FileStat) Gather(acc cua.Accumulator) error {
 				fields["modification_time"] = fileInfo.ModTime().UnixNano()
 			}
 
+			if f.MD5 {
+				sig, err := getMD5(fileName)
+				if err != nil {
+					acc.AddError(err)
+				} else {
+					fields["md5_sum"] = sig
+				}
+			}
+
 			if f.SHA256 {
 				sig, err := getSHA256(fileName)
 				if err != nil {
@@ -111,38 +126,29 @@ func (f *FileStat) Gather(acc cua.Accumulator) error {
 	return nil
 }
 
-// // Read given file and calculate an md5 hash.
-// func getMd5(file string) (string, error) {
-// 	of, err := os.Open(file)
-// 	if err != nil {
-// 		return "", err
-// 	}
-// 	defer of.Close()
-
-// 	hash := md5.New()
-// 	_, err = io.Copy(hash, of)
-// 	if err != nil {
-// 		// fatal error
-// 		return "", err
-// 	}
-// 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
-// }
+// Read given file and calculate an md5 hash.
+func getMD5(file string) (string, error) {
+	return getHash(file, md5.New()) //nolint:gosec // G401
+}
 
 // Read given file and calculate an sha256 hash.
 func getSHA256(file string) (string, error) {
+	return getHash(file, sha256.New())
+}
+
+func getHash(file string, h hash.Hash) (string, error) {
 	of, err := os.Open(file)
 	if err != nil {
 		return "", fmt.Errorf("open: %w", err)
 	}
 	defer of.Close()
 
-	hash := sha256.New()
-	_, err = io.Copy(hash, of)
+	_, err = io.Copy(h, of)
 	if err != nil {
 		// fatal error
 		return "", fmt.Errorf("copy: %w", err)
 	}
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
 func init() {
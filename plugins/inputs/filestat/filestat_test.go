@@ -176,6 +176,34 @@ func TestNoModificationTime(t *testing.T) {
 	require.False(t, acc.HasInt64Field("filestat", "modification_time"))
 }
 
+func TestGatherMD5(t *testing.T) {
+	fs := NewFileStat()
+	fs.Log = testutil.Logger{}
+	fs.MD5 = true
+	fs.SHA256 = true
+	fs.Files = []string{
+		filepath.Join(testdataDir, "log2.log"),
+	}
+
+	acc := testutil.Accumulator{}
+	require.NoError(t, acc.GatherError(fs.Gather))
+
+	tags := map[string]string{
+		"file": filepath.Join(testdataDir, "log2.log"),
+	}
+	require.True(t, acc.HasPoint("filestat", tags, "md5_sum", "82de23dd4ef24f9ae20c094613cd3ea7"))
+	require.True(t, acc.HasPoint("filestat", tags, "sha256_sum", "7c4604d03f399eac32a48edbb7be1710838b70c83ad0e94b60137920945d6c40"))
+}
+
+func TestGetMD5(t *testing.T) {
+	sig, err := getMD5(filepath.Join(testdataDir, "test.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "5a7e9b77fa25e7bb411dbd17cf403c1f", sig)
+
+	_, err = getMD5("/tmp/foo/bar/fooooo")
+	assert.Error(t, err)
+}
+
 func TestGetSHA256(t *testing.T) {
 	sig, err := getSHA256(filepath.Join(testdataDir, "test.conf"))
 	assert.NoError(t, err)
@@ -0,0 +1,545 @@
+package fluentforward
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	tlsint "github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const sampleConfig = `
+  ## Address and port to listen on for forward protocol connections.
+  ##   ex: service_address = "tcp://:24224"
+  ##       service_address = "unix:///tmp/fluent.sock"
+  service_address = "tcp://:24224"
+
+  ## Maximum number of concurrent connections.
+  ## 0 (default) is unlimited.
+  # max_connections = 1024
+
+  ## Read timeout.
+  ## 0 (default) is unlimited.
+  # read_timeout = "30s"
+
+  ## Period between keep alive probes.
+  ## 0 disables keep alive probes.
+  ## Defaults to the OS configuration.
+  # keep_alive_period = "5m"
+
+  ## Optional shared key; when set, clients must complete the forward
+  ## protocol handshake (fluent bit "Shared_Key", fluentd <security>).
+  # shared_key = ""
+
+  ## Hostname sent to clients during the handshake, defaults to the
+  ## system hostname.
+  # self_hostname = ""
+
+  ## Record keys to use as tags instead of fields.
+  # tag_keys = []
+
+  ## Record keys, glob patterns supported, whose string values are kept
+  ## as string fields; other string values are dropped.
+  # string_fields = []
+
+  ## Optional TLS configuration.
+  # tls_cert = "/opt/circonus/unified-agent/etc/cert.pem"
+  # tls_key  = "/opt/circonus/unified-agent/etc/key.pem"
+  ## Enables client authentication if set.
+  # tls_allowed_cacerts = ["/opt/circonus/unified-agent/etc/clientca.pem"]
+`
+
+// eventTimeExt is the msgpack extension type used by the forward protocol
+// to carry nanosecond precision timestamps.
+const eventTimeExt = 0
+
+// FluentForward is a listener for the Fluent forward protocol.
+type FluentForward struct {
+	ServiceAddress  string             `toml:"service_address"`
+	MaxConnections  int                `toml:"max_connections"`
+	ReadTimeout     *internal.Duration `toml:"read_timeout"`
+	KeepAlivePeriod *internal.Duration `toml:"keep_alive_period"`
+	SharedKey       string             `toml:"shared_key" secret:"true"`
+	SelfHostname    string             `toml:"self_hostname"`
+	TagKeys         []string           `toml:"tag_keys"`
+	StringFields    []string           `toml:"string_fields"`
+	tlsint.ServerConfig
+
+	Log cua.Logger `toml:"-"`
+
+	listener     net.Listener
+	acc          cua.Accumulator
+	stringFilter filter.Filter
+	tagKeys      map[string]bool
+
+	connections map[net.Conn]struct{}
+	connMu      sync.Mutex
+	wg          sync.WaitGroup
+}
+
+// eventTime is the EventTime extension, a big-endian pair of 32 bit
+// seconds and nanoseconds.
+type eventTime struct {
+	time.Time
+}
+
+func (et *eventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(et.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(et.Nanosecond()))
+	return b, nil
+}
+
+func (et *eventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("invalid EventTime length %d", len(b))
+	}
+	sec := binary.BigEndian.Uint32(b)
+	nsec := binary.BigEndian.Uint32(b[4:])
+	et.Time = time.Unix(int64(sec), int64(nsec))
+	return nil
+}
+
+func (*FluentForward) Description() string {
+	return "Accept events from Fluent Bit and Fluentd using the forward protocol"
+}
+
+func (*FluentForward) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *FluentForward) Init() error {
+	var err error
+	f.stringFilter, err = filter.Compile(f.StringFields)
+	if err != nil {
+		return fmt.Errorf("compiling string_fields: %w", err)
+	}
+
+	f.tagKeys = make(map[string]bool, len(f.TagKeys))
+	for _, k := range f.TagKeys {
+		f.tagKeys[k] = true
+	}
+
+	if f.SelfHostname == "" {
+		f.SelfHostname, err = os.Hostname()
+		if err != nil {
+			return fmt.Errorf("hostname: %w", err)
+		}
+	}
+	return nil
+}
+
+func (*FluentForward) Gather(_ cua.Accumulator) error {
+	return nil
+}
+
+func (f *FluentForward) Start(acc cua.Accumulator) error {
+	f.acc = acc
+
+	spl := strings.SplitN(f.ServiceAddress, "://", 2)
+	if len(spl) != 2 {
+		return fmt.Errorf("invalid service address: %s", f.ServiceAddress)
+	}
+	protocol, addr := spl[0], spl[1]
+
+	switch protocol {
+	case "tcp", "tcp4", "tcp6":
+	case "unix":
+		// no good way of testing for "file does not exist".
+		// Instead just ignore error and blow up when we try to listen, which will
+		// indicate "address already in use" if file existed and we couldn't remove.
+		_ = os.Remove(addr)
+	default:
+		return fmt.Errorf("unknown protocol '%s' in '%s'", protocol, f.ServiceAddress)
+	}
+
+	tlsCfg, err := f.ServerConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+
+	var l net.Listener
+	if tlsCfg == nil {
+		l, err = net.Listen(protocol, addr)
+	} else {
+		l, err = tls.Listen(protocol, addr, tlsCfg)
+	}
+	if err != nil {
+		return fmt.Errorf("listen (%s): %w", addr, err)
+	}
+	f.listener = l
+	f.connections = make(map[net.Conn]struct{})
+
+	f.Log.Infof("Listening on %s://%s", protocol, l.Addr())
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.listen()
+	}()
+
+	return nil
+}
+
+func (f *FluentForward) Stop() {
+	if f.listener != nil {
+		f.listener.Close()
+	}
+
+	f.connMu.Lock()
+	for c := range f.connections {
+		c.Close()
+	}
+	f.connMu.Unlock()
+
+	f.wg.Wait()
+}
+
+func (f *FluentForward) listen() {
+	for {
+		c, err := f.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				f.Log.Error(err.Error())
+			}
+			return
+		}
+
+		f.connMu.Lock()
+		if f.MaxConnections > 0 && len(f.connections) >= f.MaxConnections {
+			f.connMu.Unlock()
+			c.Close()
+			continue
+		}
+		f.connections[c] = struct{}{}
+		f.connMu.Unlock()
+
+		if err := f.setKeepAlive(c); err != nil {
+			f.Log.Errorf("Unable to configure keep alive %q: %s", f.ServiceAddress, err.Error())
+		}
+
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			f.handleConn(c)
+		}()
+	}
+}
+
+func (f *FluentForward) setKeepAlive(c net.Conn) error {
+	if f.KeepAlivePeriod == nil {
+		return nil
+	}
+	tcpc, ok := c.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if f.KeepAlivePeriod.Duration == 0 {
+		return tcpc.SetKeepAlive(false)
+	}
+	if err := tcpc.SetKeepAlive(true); err != nil {
+		return fmt.Errorf("set keep alive: %w", err)
+	}
+	return tcpc.SetKeepAlivePeriod(f.KeepAlivePeriod.Duration)
+}
+
+func (f *FluentForward) setDeadline(c net.Conn) {
+	if f.ReadTimeout != nil && f.ReadTimeout.Duration > 0 {
+		_ = c.SetReadDeadline(time.Now().Add(f.ReadTimeout.Duration))
+	}
+}
+
+func (f *FluentForward) handleConn(c net.Conn) {
+	defer func() {
+		f.connMu.Lock()
+		delete(f.connections, c)
+		f.connMu.Unlock()
+		c.Close()
+	}()
+
+	dec := msgpack.NewDecoder(bufio.NewReader(c))
+	dec.UseLooseInterfaceDecoding(true)
+	enc := msgpack.NewEncoder(c)
+
+	if f.SharedKey != "" {
+		f.setDeadline(c)
+		if err := f.handshake(dec, enc); err != nil {
+			f.Log.Errorf("Handshake with %s failed: %s", c.RemoteAddr(), err.Error())
+			return
+		}
+	}
+
+	for {
+		f.setDeadline(c)
+		v, err := dec.DecodeInterfaceLoose()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				f.Log.Errorf("Reading from %s: %s", c.RemoteAddr(), err.Error())
+			}
+			return
+		}
+
+		entry, ok := v.([]interface{})
+		if !ok {
+			f.Log.Errorf("Unexpected %T from %s, closing connection", v, c.RemoteAddr())
+			return
+		}
+
+		chunk, err := f.processEntry(entry)
+		if err != nil {
+			f.Log.Errorf("Invalid entry from %s: %s", c.RemoteAddr(), err.Error())
+			return
+		}
+
+		if chunk != "" {
+			if err := enc.Encode(map[string]string{"ack": chunk}); err != nil {
+				f.Log.Errorf("Sending ack to %s: %s", c.RemoteAddr(), err.Error())
+				return
+			}
+		}
+	}
+}
+
+// handshake performs the HELO/PING/PONG exchange of the forward protocol,
+// authenticating the client with the shared key.
+func (f *FluentForward) handshake(dec *msgpack.Decoder, enc *msgpack.Encoder) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("nonce: %w", err)
+	}
+
+	helo := []interface{}{"HELO", map[string]interface{}{
+		"nonce":     nonce,
+		"auth":      []byte{},
+		"keepalive": true,
+	}}
+	if err := enc.Encode(helo); err != nil {
+		return fmt.Errorf("sending HELO: %w", err)
+	}
+
+	v, err := dec.DecodeInterfaceLoose()
+	if err != nil {
+		return fmt.Errorf("reading PING: %w", err)
+	}
+	ping, ok := v.([]interface{})
+	if !ok || len(ping) < 4 || ping[0] != "PING" {
+		return fmt.Errorf("expected PING, got %v", v)
+	}
+	hostname, _ := ping[1].(string)
+	salt, _ := ping[2].(string)
+	digest, _ := ping[3].(string)
+
+	expected := sharedKeyDigest(salt, hostname, nonce, f.SharedKey)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(digest)) != 1 {
+		pong := []interface{}{"PONG", false, "shared_key mismatch", "", ""}
+		_ = enc.Encode(pong)
+		return fmt.Errorf("shared key mismatch from %q", hostname)
+	}
+
+	pong := []interface{}{"PONG", true, "", f.SelfHostname, sharedKeyDigest(salt, f.SelfHostname, nonce, f.SharedKey)}
+	if err := enc.Encode(pong); err != nil {
+		return fmt.Errorf("sending PONG: %w", err)
+	}
+	return nil
+}
+
+func sharedKeyDigest(salt, hostname string, nonce []byte, key string) string {
+	h := sha512.New()
+	h.Write([]byte(salt))
+	h.Write([]byte(hostname))
+	h.Write(nonce)
+	h.Write([]byte(key))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// processEntry handles an event in any of the Message, Forward or
+// PackedForward modes, returning the chunk id to acknowledge if requested.
+func (f *FluentForward) processEntry(entry []interface{}) (string, error) {
+	if len(entry) < 2 {
+		return "", fmt.Errorf("entry too short (%d)", len(entry))
+	}
+	tag, ok := entry[0].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid tag type %T", entry[0])
+	}
+
+	var option map[string]interface{}
+	switch events := entry[1].(type) {
+	case []interface{}:
+		// Forward mode: [tag, [[time, record], ...], option]
+		option = optionAt(entry, 2)
+		for _, e := range events {
+			event, ok := e.([]interface{})
+			if !ok || len(event) < 2 {
+				return "", fmt.Errorf("invalid event %v", e)
+			}
+			if err := f.addEvent(tag, event[0], event[1]); err != nil {
+				return "", err
+			}
+		}
+	case string:
+		// PackedForward mode: [tag, msgpack stream of [time, record], option]
+		option = optionAt(entry, 2)
+		data := []byte(events)
+		if compressed, _ := option["compressed"].(string); compressed == "gzip" {
+			var err error
+			data, err = gunzip(data)
+			if err != nil {
+				return "", err
+			}
+		}
+		if err := f.addPacked(tag, data); err != nil {
+			return "", err
+		}
+	default:
+		// Message mode: [tag, time, record, option]
+		if len(entry) < 3 {
+			return "", fmt.Errorf("entry too short (%d)", len(entry))
+		}
+		option = optionAt(entry, 3)
+		if err := f.addEvent(tag, entry[1], entry[2]); err != nil {
+			return "", err
+		}
+	}
+
+	chunk, _ := option["chunk"].(string)
+	return chunk, nil
+}
+
+func optionAt(entry []interface{}, i int) map[string]interface{} {
+	if len(entry) <= i {
+		return nil
+	}
+	option, _ := entry[i].(map[string]interface{})
+	return option
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	// CompressedPackedForward may contain several concatenated gzip members,
+	// which gzip.Reader reads transparently.
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %w", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %w", err)
+	}
+	return b, nil
+}
+
+func (f *FluentForward) addPacked(tag string, data []byte) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.UseLooseInterfaceDecoding(true)
+	for {
+		v, err := dec.DecodeInterfaceLoose()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("packed entries: %w", err)
+		}
+		event, ok := v.([]interface{})
+		if !ok || len(event) < 2 {
+			return fmt.Errorf("invalid event %v", v)
+		}
+		if err := f.addEvent(tag, event[0], event[1]); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *FluentForward) addEvent(tag string, ts, rec interface{}) error {
+	t, err := parseTime(ts)
+	if err != nil {
+		return err
+	}
+	record, ok := rec.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid record type %T", rec)
+	}
+
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	f.flatten("", record, tags, fields)
+
+	if len(fields) == 0 {
+		f.Log.Debugf("Dropping event %q without numeric fields", tag)
+		return nil
+	}
+	f.acc.AddFields(tag, fields, tags, t)
+	return nil
+}
+
+// flatten converts a record into tags and fields, nested maps are joined
+// with an underscore.
+func (f *FluentForward) flatten(prefix string, record map[string]interface{}, tags map[string]string, fields map[string]interface{}) {
+	for k, v := range record {
+		if prefix != "" {
+			k = prefix + "_" + k
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			f.flatten(k, v, tags, fields)
+		case string:
+			switch {
+			case f.tagKeys[k]:
+				tags[k] = v
+			case f.stringFilter != nil && f.stringFilter.Match(k):
+				fields[k] = v
+			}
+		case int64, uint64, float64, bool:
+			if f.tagKeys[k] {
+				tags[k] = fmt.Sprint(v)
+				continue
+			}
+			fields[k] = v
+		}
+	}
+}
+
+func parseTime(v interface{}) (time.Time, error) {
+	switch ts := v.(type) {
+	case *eventTime:
+		return ts.Time, nil
+	case int64:
+		return time.Unix(ts, 0), nil
+	case uint64:
+		return time.Unix(int64(ts), 0), nil
+	case float64:
+		sec := int64(ts)
+		return time.Unix(sec, int64((ts-float64(sec))*1e9)), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid time type %T", v)
+	}
+}
+
+func init() {
+	msgpack.RegisterExt(eventTimeExt, (*eventTime)(nil))
+
+	inputs.Add("fluent_forward", func() cua.Input {
+		return &FluentForward{
+			ServiceAddress: "tcp://:24224",
+		}
+	})
+}
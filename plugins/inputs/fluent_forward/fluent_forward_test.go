@@ -0,0 +1,207 @@
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func newTestListener(t *testing.T) (*FluentForward, *testutil.Accumulator) {
+	f := &FluentForward{
+		ServiceAddress: "tcp://127.0.0.1:0",
+		TagKeys:        []string{"host"},
+		StringFields:   []string{"msg"},
+		SelfHostname:   "server",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, f.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, f.Start(acc))
+	return f, acc
+}
+
+func dial(t *testing.T, f *FluentForward) (net.Conn, *msgpack.Encoder, *msgpack.Decoder) {
+	c, err := net.Dial("tcp", f.listener.Addr().String())
+	require.NoError(t, err)
+	dec := msgpack.NewDecoder(c)
+	dec.UseLooseInterfaceDecoding(true)
+	return c, msgpack.NewEncoder(c), dec
+}
+
+func TestMessageMode(t *testing.T) {
+	f, acc := newTestListener(t)
+	defer f.Stop()
+
+	c, enc, dec := dial(t, f)
+	defer c.Close()
+
+	ts := &eventTime{time.Unix(1600000000, 500)}
+	record := map[string]interface{}{
+		"host":  "a",
+		"value": 42,
+		"msg":   "hello",
+		"other": "dropped",
+		"mem":   map[string]interface{}{"used": 1.5},
+	}
+	require.NoError(t, enc.Encode([]interface{}{"cpu.local", ts, record, map[string]interface{}{"chunk": "abc"}}))
+
+	var ack map[string]string
+	require.NoError(t, dec.Decode(&ack))
+	require.Equal(t, "abc", ack["ack"])
+
+	acc.Wait(1)
+	expected := []cua.Metric{
+		testutil.MustMetric("cpu.local",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(42), "msg": "hello", "mem_used": 1.5},
+			time.Unix(1600000000, 500)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
+}
+
+func TestForwardMode(t *testing.T) {
+	f, acc := newTestListener(t)
+	defer f.Stop()
+
+	c, enc, _ := dial(t, f)
+	defer c.Close()
+
+	events := []interface{}{
+		[]interface{}{1600000000, map[string]interface{}{"value": 1}},
+		[]interface{}{1600000001, map[string]interface{}{"value": 2}},
+	}
+	require.NoError(t, enc.Encode([]interface{}{"mem", events}))
+
+	acc.Wait(2)
+	expected := []cua.Metric{
+		testutil.MustMetric("mem", map[string]string{},
+			map[string]interface{}{"value": int64(1)}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("mem", map[string]string{},
+			map[string]interface{}{"value": int64(2)}, time.Unix(1600000001, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
+}
+
+func TestPackedForwardMode(t *testing.T) {
+	var packed bytes.Buffer
+	enc := msgpack.NewEncoder(&packed)
+	require.NoError(t, enc.Encode([]interface{}{&eventTime{time.Unix(1600000000, 0)}, map[string]interface{}{"value": 1}}))
+	require.NoError(t, enc.Encode([]interface{}{&eventTime{time.Unix(1600000001, 0)}, map[string]interface{}{"value": 2}}))
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err := zw.Write(packed.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	tests := []struct {
+		name   string
+		data   []byte
+		option map[string]interface{}
+	}{
+		{
+			name: "packed",
+			data: packed.Bytes(),
+		},
+		{
+			name:   "compressed",
+			data:   compressed.Bytes(),
+			option: map[string]interface{}{"compressed": "gzip"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, acc := newTestListener(t)
+			defer f.Stop()
+
+			c, enc, _ := dial(t, f)
+			defer c.Close()
+
+			entry := []interface{}{"mem", tt.data}
+			if tt.option != nil {
+				entry = append(entry, tt.option)
+			}
+			require.NoError(t, enc.Encode(entry))
+
+			acc.Wait(2)
+			expected := []cua.Metric{
+				testutil.MustMetric("mem", map[string]string{},
+					map[string]interface{}{"value": int64(1)}, time.Unix(1600000000, 0)),
+				testutil.MustMetric("mem", map[string]string{},
+					map[string]interface{}{"value": int64(2)}, time.Unix(1600000001, 0)),
+			}
+			testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
+		})
+	}
+}
+
+func TestSharedKeyHandshake(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		ok   bool
+	}{
+		{name: "valid key", key: "secret", ok: true},
+		{name: "invalid key", key: "wrong", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &FluentForward{
+				ServiceAddress: "tcp://127.0.0.1:0",
+				SharedKey:      "secret",
+				SelfHostname:   "server",
+				Log:            testutil.Logger{},
+			}
+			require.NoError(t, f.Init())
+			acc := &testutil.Accumulator{}
+			require.NoError(t, f.Start(acc))
+			defer f.Stop()
+
+			c, enc, dec := dial(t, f)
+			defer c.Close()
+
+			v, err := dec.DecodeInterfaceLoose()
+			require.NoError(t, err)
+			helo := v.([]interface{})
+			require.Equal(t, "HELO", helo[0])
+			nonce := helo[1].(map[string]interface{})["nonce"].(string)
+
+			digest := sharedKeyDigest("salt", "client", []byte(nonce), tt.key)
+			require.NoError(t, enc.Encode([]interface{}{"PING", "client", "salt", digest, "", ""}))
+
+			v, err = dec.DecodeInterfaceLoose()
+			require.NoError(t, err)
+			pong := v.([]interface{})
+			require.Equal(t, "PONG", pong[0])
+			require.Equal(t, tt.ok, pong[1])
+			if !tt.ok {
+				return
+			}
+			require.Equal(t, "server", pong[3])
+			require.Equal(t, sharedKeyDigest("salt", "server", []byte(nonce), "secret"), pong[4])
+
+			require.NoError(t, enc.Encode([]interface{}{"cpu", 1600000000, map[string]interface{}{"value": 1}}))
+			acc.Wait(1)
+			acc.AssertContainsFields(t, "cpu", map[string]interface{}{"value": int64(1)})
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	ts, err := parseTime(1.5)
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1, 500000000), ts)
+
+	_, err = parseTime("now")
+	require.Error(t, err)
+}
@@ -0,0 +1,207 @@
+package gpsd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Address of the gpsd daemon.
+  # address = "localhost:2947"
+
+  ## Timeout of the connection and of the poll.
+  # timeout = "5s"
+
+  ## Add the position as the latitude and longitude tags, rounded to the
+  ## number of decimals of position_precision (3 decimals is about 100m).
+  ## The position is always reported in the fields.
+  # position_tags = false
+  # position_precision = 3
+`
+
+// the fix modes of the TPV reports
+var modes = []string{"unknown", "no_fix", "2d", "3d"}
+
+// tpv is a time-position-velocity report.
+type tpv struct {
+	Device string   `json:"device"`
+	Mode   int      `json:"mode"`
+	Time   string   `json:"time"`
+	Lat    *float64 `json:"lat"`
+	Lon    *float64 `json:"lon"`
+	Alt    *float64 `json:"alt"`
+	AltMSL *float64 `json:"altMSL"`
+	Speed  *float64 `json:"speed"`
+	Track  *float64 `json:"track"`
+	Climb  *float64 `json:"climb"`
+	Epx    *float64 `json:"epx"`
+	Epy    *float64 `json:"epy"`
+	Epv    *float64 `json:"epv"`
+}
+
+// sky is a report of the satellites in view.
+type sky struct {
+	Device     string   `json:"device"`
+	Hdop       *float64 `json:"hdop"`
+	Vdop       *float64 `json:"vdop"`
+	Pdop       *float64 `json:"pdop"`
+	Satellites []struct {
+		Used bool `json:"used"`
+	} `json:"satellites"`
+}
+
+type report struct {
+	Class  string `json:"class"`
+	Active int    `json:"active"`
+	TPV    []tpv  `json:"tpv"`
+	Sky    []sky  `json:"sky"`
+}
+
+type GPSd struct {
+	Address           string            `toml:"address"`
+	Timeout           internal.Duration `toml:"timeout"`
+	PositionTags      bool              `toml:"position_tags"`
+	PositionPrecision int               `toml:"position_precision"`
+
+	Log cua.Logger `toml:"-"`
+}
+
+func (g *GPSd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GPSd) Description() string {
+	return "Read the fix, position and satellites of the GPS receivers from gpsd"
+}
+
+func (g *GPSd) Init() error {
+	if g.PositionPrecision < 0 || g.PositionPrecision > 8 {
+		return fmt.Errorf("invalid position_precision %d", g.PositionPrecision)
+	}
+	return nil
+}
+
+func (g *GPSd) Gather(acc cua.Accumulator) error {
+	poll, err := g.poll()
+	if err != nil {
+		return err
+	}
+
+	skies := make(map[string]sky, len(poll.Sky))
+	for _, s := range poll.Sky {
+		skies[s.Device] = s
+	}
+	for _, t := range poll.TPV {
+		g.addFix(acc, t, skies[t.Device])
+	}
+	return nil
+}
+
+// poll enables the reports and polls the last fix of the devices.
+func (g *GPSd) poll() (*report, error) {
+	conn, err := net.DialTimeout("tcp", g.Address, g.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(g.Timeout.Duration)); err != nil {
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+
+	if _, err := conn.Write([]byte(`?WATCH={"enable":true};?POLL;` + "\n")); err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r report
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("invalid report: %w", err)
+		}
+		switch r.Class {
+		case "POLL":
+			return &r, nil
+		case "ERROR":
+			return nil, fmt.Errorf("gpsd error: %s", scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return nil, fmt.Errorf("connection closed before the poll response")
+}
+
+func (g *GPSd) addFix(acc cua.Accumulator, t tpv, s sky) {
+	mode := "unknown"
+	if t.Mode >= 0 && t.Mode < len(modes) {
+		mode = modes[t.Mode]
+	}
+	tags := map[string]string{
+		"device": t.Device,
+		"fix":    mode,
+	}
+	fields := map[string]interface{}{
+		"mode": t.Mode,
+	}
+
+	add := func(name string, v *float64) {
+		if v != nil {
+			fields[name] = *v
+		}
+	}
+	add("latitude", t.Lat)
+	add("longitude", t.Lon)
+	add("altitude", t.Alt)
+	if t.AltMSL != nil {
+		fields["altitude"] = *t.AltMSL
+	}
+	add("speed", t.Speed)
+	add("track", t.Track)
+	add("climb", t.Climb)
+	add("error_longitude", t.Epx)
+	add("error_latitude", t.Epy)
+	add("error_altitude", t.Epv)
+	add("hdop", s.Hdop)
+	add("vdop", s.Vdop)
+	add("pdop", s.Pdop)
+	if s.Satellites != nil {
+		used := 0
+		for _, sat := range s.Satellites {
+			if sat.Used {
+				used++
+			}
+		}
+		fields["satellites_visible"] = len(s.Satellites)
+		fields["satellites_used"] = used
+	}
+
+	if g.PositionTags && t.Lat != nil && t.Lon != nil {
+		tags["latitude"] = strconv.FormatFloat(*t.Lat, 'f', g.PositionPrecision, 64)
+		tags["longitude"] = strconv.FormatFloat(*t.Lon, 'f', g.PositionPrecision, 64)
+	}
+
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339Nano, t.Time); err == nil {
+		ts = parsed
+	}
+	acc.AddFields("gpsd", fields, tags, ts)
+}
+
+func init() {
+	inputs.Add("gpsd", func() cua.Input {
+		return &GPSd{
+			Address:           "localhost:2947",
+			Timeout:           internal.Duration{Duration: 5 * time.Second},
+			PositionPrecision: 3,
+		}
+	})
+}
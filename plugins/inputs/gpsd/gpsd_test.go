@@ -0,0 +1,100 @@
+package gpsd
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const pollResponse = `{"class":"POLL","time":"2021-03-01T10:00:00.000Z","active":2,` +
+	`"tpv":[{"class":"TPV","device":"/dev/ttyUSB0","mode":3,"time":"2021-03-01T10:00:00.000Z",` +
+	`"ept":0.005,"lat":46.498293369,"lon":7.567411672,"alt":1343.127,"epx":2.5,"epy":3.1,"epv":8.2,` +
+	`"track":10.3,"speed":0.091,"climb":-0.085},` +
+	`{"class":"TPV","device":"/dev/ttyUSB1","mode":1}],` +
+	`"sky":[{"class":"SKY","device":"/dev/ttyUSB0","hdop":1.21,"vdop":1.5,"pdop":1.93,` +
+	`"satellites":[{"PRN":10,"used":true},{"PRN":20,"used":true},{"PRN":30,"used":false}]}]}`
+
+func serve(t *testing.T, response string) (string, chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte(`{"class":"VERSION","release":"3.22","proto_major":3,"proto_minor":14}` + "\n"))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+		received <- line
+		_, _ = conn.Write([]byte(`{"class":"DEVICES","devices":[]}` + "\n"))
+		_, _ = conn.Write([]byte(`{"class":"WATCH","enable":true}` + "\n"))
+		_, _ = conn.Write([]byte(response + "\n"))
+	}()
+	return l.Addr().String(), received
+}
+
+func TestGather(t *testing.T) {
+	addr, received := serve(t, pollResponse)
+
+	g := &GPSd{
+		Address:           addr,
+		Timeout:           internal.Duration{Duration: 5 * time.Second},
+		PositionTags:      true,
+		PositionPrecision: 3,
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, g.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, g.Gather(acc))
+	require.Equal(t, `?WATCH={"enable":true};?POLL;`+"\n", <-received)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("gpsd",
+			map[string]string{"device": "/dev/ttyUSB0", "fix": "3d", "latitude": "46.498", "longitude": "7.567"},
+			map[string]interface{}{
+				"mode":               3,
+				"latitude":           46.498293369,
+				"longitude":          7.567411672,
+				"altitude":           1343.127,
+				"speed":              0.091,
+				"track":              10.3,
+				"climb":              -0.085,
+				"error_longitude":    2.5,
+				"error_latitude":     3.1,
+				"error_altitude":     8.2,
+				"hdop":               1.21,
+				"vdop":               1.5,
+				"pdop":               1.93,
+				"satellites_visible": 3,
+				"satellites_used":    2,
+			},
+			time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)),
+		testutil.MustMetric("gpsd",
+			map[string]string{"device": "/dev/ttyUSB1", "fix": "no_fix"},
+			map[string]interface{}{"mode": 1},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+	require.Equal(t, time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC), acc.Metrics[0].Time)
+}
+
+func TestGatherError(t *testing.T) {
+	addr, _ := serve(t, `{"class":"ERROR","message":"Unrecognized request '?POL'"}`)
+
+	g := &GPSd{Address: addr, Timeout: internal.Duration{Duration: 5 * time.Second}, Log: testutil.Logger{}}
+	require.NoError(t, g.Init())
+	require.Error(t, g.Gather(&testutil.Accumulator{}))
+}
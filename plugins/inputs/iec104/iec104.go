@@ -0,0 +1,145 @@
+package iec104
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+var sampleConfig = `
+  ## Device name, added as the name tag.
+  name = "substation1"
+
+  ## Address of the controlled station (server), port 2404 by default.
+  address = "127.0.0.1:2404"
+
+  ## Common address of the ASDUs (station address), 65535 for all the
+  ## stations of the server.
+  # common_address = 1
+
+  ## Originator address of the interrogations, 0 when not used.
+  # originator_address = 0
+
+  ## Timeout of the connection and of the general interrogation.
+  # timeout = "10s"
+
+  ## Optional names of the information objects, added as the point tag.
+  # [[inputs.iec104.points]]
+  #   ioa = 1001
+  #   name = "voltage_l1"
+`
+
+type point struct {
+	IOA  int    `toml:"ioa"`
+	Name string `toml:"name"`
+}
+
+type IEC104 struct {
+	Name              string            `toml:"name"`
+	Address           string            `toml:"address"`
+	CommonAddress     int               `toml:"common_address"`
+	OriginatorAddress int               `toml:"originator_address"`
+	Timeout           internal.Duration `toml:"timeout"`
+	Points            []point           `toml:"points"`
+
+	Log cua.Logger `toml:"-"`
+
+	points map[uint32]string
+}
+
+func (s *IEC104) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *IEC104) Description() string {
+	return "Read the points of an IEC 60870-5-104 controlled station with a general interrogation"
+}
+
+func (s *IEC104) Init() error {
+	if s.Address == "" {
+		return errors.New("no address configured")
+	}
+	if s.CommonAddress < 1 || s.CommonAddress > 0xFFFF {
+		return fmt.Errorf("invalid common_address %d, expected 1 to 65535", s.CommonAddress)
+	}
+	if s.OriginatorAddress < 0 || s.OriginatorAddress > 0xFF {
+		return fmt.Errorf("invalid originator_address %d, expected 0 to 255", s.OriginatorAddress)
+	}
+	s.points = make(map[uint32]string, len(s.Points))
+	for _, p := range s.Points {
+		if p.IOA < 0 || p.IOA > 0xFFFFFF {
+			return fmt.Errorf("invalid ioa %d of point %q", p.IOA, p.Name)
+		}
+		s.points[uint32(p.IOA)] = p.Name
+	}
+	return nil
+}
+
+// Gather connects to the station and runs a general interrogation, the
+// points are reported as they are received until the activation
+// termination of the interrogation.
+func (s *IEC104) Gather(acc cua.Accumulator) error {
+	conn, err := net.DialTimeout("tcp", s.Address, s.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("dial (%s): %w", s.Address, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.Timeout.Duration)); err != nil {
+		return fmt.Errorf("set deadline: %w", err)
+	}
+
+	c := &session{conn: conn}
+	if err := c.startDataTransfer(); err != nil {
+		return err
+	}
+	defer c.stopDataTransfer()
+
+	if err := c.sendASDU(interrogationCommand(uint16(s.CommonAddress), byte(s.OriginatorAddress), causeActivation)); err != nil {
+		return err
+	}
+
+	for {
+		a, err := c.readASDU()
+		if err != nil {
+			return err
+		}
+		if a.typeID == typeInterrogation {
+			if a.negative {
+				return fmt.Errorf("interrogation rejected with cause %d", a.cause)
+			}
+			if a.cause == causeActivationTermination {
+				return nil
+			}
+			continue
+		}
+
+		for _, o := range a.objects {
+			tags := map[string]string{
+				"name":           s.Name,
+				"common_address": strconv.Itoa(int(a.commonAddress)),
+				"ioa":            strconv.FormatUint(uint64(o.ioa), 10),
+				"type":           o.kind,
+				"quality":        o.quality,
+			}
+			if name, ok := s.points[o.ioa]; ok && name != "" {
+				tags["point"] = name
+			}
+			acc.AddFields("iec104", map[string]interface{}{"value": o.value}, tags)
+		}
+	}
+}
+
+func init() {
+	inputs.Add("iec104", func() cua.Input {
+		return &IEC104{
+			CommonAddress: 1,
+			Timeout:       internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
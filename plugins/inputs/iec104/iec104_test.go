@@ -0,0 +1,152 @@
+package iec104
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// iFrame returns an I-frame holding the ASDU, with the sequence numbers left
+// to 0 as they are not checked by the agent.
+func iFrame(asdu ...byte) []byte {
+	return append([]byte{startByte, byte(4 + len(asdu)), 0, 0, 0, 0}, asdu...)
+}
+
+func floatBytes(f float32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, math.Float32bits(f))
+	return b
+}
+
+func serve(t *testing.T, frames [][]byte, interrogation chan<- []byte) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 6)
+		if _, err := io.ReadFull(conn, buf); err != nil || buf[2] != uStartDTAct {
+			return
+		}
+		// a test frame is answered while the data transfer is started
+		_, _ = conn.Write([]byte{startByte, 4, uTestFRAct, 0, 0, 0})
+		if _, err := io.ReadFull(conn, buf); err != nil || buf[2] != uTestFRCon {
+			return
+		}
+		_, _ = conn.Write([]byte{startByte, 4, uStartDTCon, 0, 0, 0})
+
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		command := make([]byte, int(buf[1])-4)
+		if _, err := io.ReadFull(conn, command); err != nil {
+			return
+		}
+		interrogation <- command
+
+		for _, frame := range frames {
+			_, _ = conn.Write(frame)
+		}
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+	return l.Addr().String()
+}
+
+func TestGather(t *testing.T) {
+	measurand := append([]byte{13, 0x01, 20, 0, 1, 0, 0xE9, 0x03, 0x00}, floatBytes(230.5)...)
+	measurand = append(measurand, 0x00)
+	frames := [][]byte{
+		// activation confirmation
+		iFrame(100, 0x01, 7, 0, 1, 0, 0, 0, 0, 20),
+		// float measured value, ioa 1001
+		iFrame(measurand...),
+		// sequence of 2 single points from ioa 2001, the second invalid
+		iFrame(1, 0x82, 20, 0, 1, 0, 0xD1, 0x07, 0x00, 0x01, 0x80),
+		// integrated total with a carry, ioa 3001
+		iFrame(15, 0x01, 20, 0, 1, 0, 0xB9, 0x0B, 0x00, 0x10, 0x27, 0x00, 0x00, 0x20),
+		// not supported type, skipped
+		iFrame(45, 0x01, 20, 0, 1, 0, 0x01, 0x00, 0x00, 0x01),
+		// activation termination
+		iFrame(100, 0x01, 10, 0, 1, 0, 0, 0, 0, 20),
+	}
+	interrogation := make(chan []byte, 1)
+	addr := serve(t, frames, interrogation)
+
+	s := &IEC104{
+		Name:          "substation1",
+		Address:       addr,
+		CommonAddress: 1,
+		Timeout:       internal.Duration{Duration: 5 * time.Second},
+		Points:        []point{{IOA: 1001, Name: "voltage_l1"}},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	require.Equal(t, []byte{100, 0x01, 6, 0, 1, 0, 0, 0, 0, 20}, <-interrogation)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("iec104",
+			map[string]string{"name": "substation1", "common_address": "1", "ioa": "1001", "type": "float", "quality": "good", "point": "voltage_l1"},
+			map[string]interface{}{"value": float64(230.5)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("iec104",
+			map[string]string{"name": "substation1", "common_address": "1", "ioa": "2001", "type": "single_point", "quality": "good"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("iec104",
+			map[string]string{"name": "substation1", "common_address": "1", "ioa": "2002", "type": "single_point", "quality": "invalid"},
+			map[string]interface{}{"value": int64(0)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("iec104",
+			map[string]string{"name": "substation1", "common_address": "1", "ioa": "3001", "type": "integrated_total", "quality": "carry"},
+			map[string]interface{}{"value": int64(10000)},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherRejected(t *testing.T) {
+	frames := [][]byte{
+		// negative activation confirmation
+		iFrame(100, 0x01, 0x40|7, 0, 1, 0, 0, 0, 0, 20),
+	}
+	addr := serve(t, frames, make(chan []byte, 1))
+
+	s := &IEC104{Address: addr, CommonAddress: 1, Timeout: internal.Duration{Duration: 5 * time.Second}, Log: testutil.Logger{}}
+	require.NoError(t, s.Init())
+	require.Error(t, s.Gather(&testutil.Accumulator{}))
+}
+
+func TestParseASDU(t *testing.T) {
+	// normalized value with time tag, -0.5 not topical
+	a, err := parseASDU([]byte{34, 0x01, 3, 0, 7, 0, 0x0A, 0x00, 0x00, 0x00, 0xC0, 0x40, 1, 2, 3, 4, 5, 6, 7})
+	require.NoError(t, err)
+	require.Equal(t, uint16(7), a.commonAddress)
+	require.Equal(t, byte(3), a.cause)
+	require.Equal(t, []infoObject{{ioa: 10, kind: "normalized", value: -0.5, quality: "not_topical"}}, a.objects)
+
+	_, err = parseASDU([]byte{13, 0x01, 3, 0, 7, 0, 0x0A, 0x00, 0x00, 0x00})
+	require.Error(t, err)
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&IEC104{}).Init())
+	require.Error(t, (&IEC104{Address: "127.0.0.1:2404"}).Init())
+	require.Error(t, (&IEC104{Address: "127.0.0.1:2404", CommonAddress: 1, Points: []point{{IOA: -1}}}).Init())
+}
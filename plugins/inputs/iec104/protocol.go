@@ -0,0 +1,341 @@
+package iec104
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+)
+
+// APCI start byte and U-format functions
+const (
+	startByte = 0x68
+
+	uStartDTAct = 0x07
+	uStartDTCon = 0x0B
+	uStopDTAct  = 0x13
+	uStopDTCon  = 0x23
+	uTestFRAct  = 0x43
+	uTestFRCon  = 0x83
+)
+
+// causes of transmission
+const (
+	causeActivation            = 6
+	causeActivationTermination = 10
+)
+
+const (
+	typeInterrogation = 100
+	qoiStation        = 20
+
+	// the received I-frames are acknowledged at least every ackWindow frames
+	ackWindow = 8
+)
+
+// quality descriptor bits
+const (
+	qualityOverflow    = 0x01
+	qualityBlocked     = 0x10
+	qualitySubstituted = 0x20
+	qualityNotTopical  = 0x40
+	qualityInvalid     = 0x80
+
+	// bits of the sequence byte of the integrated totals
+	counterCarry    = 0x20
+	counterAdjusted = 0x40
+)
+
+type infoObject struct {
+	ioa     uint32
+	kind    string
+	value   interface{}
+	quality string
+}
+
+type asdu struct {
+	typeID        byte
+	cause         byte
+	negative      bool
+	commonAddress uint16
+	objects       []infoObject
+}
+
+// element describes the information elements of a type of ASDU: their kind,
+// size without the time tag, and decoder returning the value and quality.
+type element struct {
+	kind    string
+	size    int
+	timeTag bool
+	decode  func(b []byte) (interface{}, string)
+}
+
+var elements = map[byte]element{
+	1:  {"single_point", 1, false, decodeSinglePoint},
+	3:  {"double_point", 1, false, decodeDoublePoint},
+	5:  {"step_position", 2, false, decodeStepPosition},
+	7:  {"bitstring", 5, false, decodeBitstring},
+	9:  {"normalized", 3, false, decodeNormalized},
+	11: {"scaled", 3, false, decodeScaled},
+	13: {"float", 5, false, decodeFloat},
+	15: {"integrated_total", 5, false, decodeIntegratedTotal},
+	21: {"normalized", 2, false, decodeNormalizedNoQuality},
+	30: {"single_point", 1, true, decodeSinglePoint},
+	31: {"double_point", 1, true, decodeDoublePoint},
+	32: {"step_position", 2, true, decodeStepPosition},
+	33: {"bitstring", 5, true, decodeBitstring},
+	34: {"normalized", 3, true, decodeNormalized},
+	35: {"scaled", 3, true, decodeScaled},
+	36: {"float", 5, true, decodeFloat},
+	37: {"integrated_total", 5, true, decodeIntegratedTotal},
+}
+
+// cp56Size is the size of the CP56Time2a time tags
+const cp56Size = 7
+
+// the low bits of the quality descriptors of the points hold their state
+func decodeSinglePoint(b []byte) (interface{}, string) {
+	return int64(b[0] & 0x01), quality(b[0] &^ 0x0F)
+}
+
+func decodeDoublePoint(b []byte) (interface{}, string) {
+	return int64(b[0] & 0x03), quality(b[0] &^ 0x0F)
+}
+
+func decodeStepPosition(b []byte) (interface{}, string) {
+	// 7 bits signed value, the high bit is the transient state
+	return int64(int8(b[0]<<1) >> 1), quality(b[1])
+}
+
+func decodeBitstring(b []byte) (interface{}, string) {
+	return int64(binary.LittleEndian.Uint32(b)), quality(b[4])
+}
+
+func decodeNormalized(b []byte) (interface{}, string) {
+	return float64(int16(binary.LittleEndian.Uint16(b))) / 32768, quality(b[2])
+}
+
+func decodeNormalizedNoQuality(b []byte) (interface{}, string) {
+	return float64(int16(binary.LittleEndian.Uint16(b))) / 32768, "good"
+}
+
+func decodeScaled(b []byte) (interface{}, string) {
+	return int64(int16(binary.LittleEndian.Uint16(b))), quality(b[2])
+}
+
+func decodeFloat(b []byte) (interface{}, string) {
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), quality(b[4])
+}
+
+func decodeIntegratedTotal(b []byte) (interface{}, string) {
+	var flags []string
+	if b[4]&qualityInvalid != 0 {
+		flags = append(flags, "invalid")
+	}
+	if b[4]&counterAdjusted != 0 {
+		flags = append(flags, "adjusted")
+	}
+	if b[4]&counterCarry != 0 {
+		flags = append(flags, "carry")
+	}
+	return int64(int32(binary.LittleEndian.Uint32(b))), qualityString(flags)
+}
+
+// quality returns the flags of the quality descriptor, joined by commas, or
+// good when none is set.
+func quality(q byte) string {
+	var flags []string
+	if q&qualityInvalid != 0 {
+		flags = append(flags, "invalid")
+	}
+	if q&qualityNotTopical != 0 {
+		flags = append(flags, "not_topical")
+	}
+	if q&qualitySubstituted != 0 {
+		flags = append(flags, "substituted")
+	}
+	if q&qualityBlocked != 0 {
+		flags = append(flags, "blocked")
+	}
+	if q&qualityOverflow != 0 {
+		flags = append(flags, "overflow")
+	}
+	return qualityString(flags)
+}
+
+func qualityString(flags []string) string {
+	if len(flags) == 0 {
+		return "good"
+	}
+	return strings.Join(flags, ",")
+}
+
+// parseASDU decodes an ASDU with the 2 bytes causes of transmission and
+// common addresses, and the 3 bytes information object addresses of the
+// IEC 60870-5-104 profile.  The objects of the types not supported are
+// skipped.
+func parseASDU(b []byte) (*asdu, error) {
+	if len(b) < 6 {
+		return nil, fmt.Errorf("ASDU too short: %d bytes", len(b))
+	}
+	a := &asdu{
+		typeID:        b[0],
+		cause:         b[2] & 0x3F,
+		negative:      b[2]&0x40 != 0,
+		commonAddress: binary.LittleEndian.Uint16(b[4:6]),
+	}
+	sequence := b[1]&0x80 != 0
+	count := int(b[1] & 0x7F)
+
+	el, ok := elements[a.typeID]
+	if !ok {
+		return a, nil
+	}
+	size := el.size
+	if el.timeTag {
+		size += cp56Size
+	}
+
+	data := b[6:]
+	var ioa uint32
+	for i := 0; i < count; i++ {
+		if !sequence || i == 0 {
+			if len(data) < 3 {
+				return nil, errors.New("truncated information object address")
+			}
+			ioa = uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+			data = data[3:]
+		} else {
+			ioa++
+		}
+		if len(data) < size {
+			return nil, fmt.Errorf("truncated information object %d", ioa)
+		}
+		value, q := el.decode(data[:el.size])
+		a.objects = append(a.objects, infoObject{ioa: ioa, kind: el.kind, value: value, quality: q})
+		data = data[size:]
+	}
+	return a, nil
+}
+
+// interrogationCommand returns the ASDU of a station interrogation command.
+func interrogationCommand(commonAddress uint16, originator byte, cause byte) []byte {
+	b := []byte{typeInterrogation, 0x01, cause, originator, 0, 0, 0, 0, 0, qoiStation}
+	binary.LittleEndian.PutUint16(b[4:6], commonAddress)
+	return b
+}
+
+// session is the APCI layer of a connection, with the sequence numbers of
+// the I-frames sent and received.
+type session struct {
+	conn     net.Conn
+	sendSeq  uint16
+	recvSeq  uint16
+	unacked  int
+	frameBuf [255]byte
+}
+
+func (c *session) write(b []byte) error {
+	if _, err := c.conn.Write(b); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+func (c *session) sendU(function byte) error {
+	return c.write([]byte{startByte, 4, function, 0, 0, 0})
+}
+
+func (c *session) sendS() error {
+	c.unacked = 0
+	return c.write([]byte{startByte, 4, 0x01, 0, byte(c.recvSeq << 1), byte(c.recvSeq >> 7)})
+}
+
+func (c *session) sendASDU(a []byte) error {
+	b := make([]byte, 0, 6+len(a))
+	b = append(b, startByte, byte(4+len(a)),
+		byte(c.sendSeq<<1), byte(c.sendSeq>>7), byte(c.recvSeq<<1), byte(c.recvSeq>>7))
+	b = append(b, a...)
+	c.sendSeq = (c.sendSeq + 1) % 32768
+	c.unacked = 0
+	return c.write(b)
+}
+
+// readFrame returns the control field and the ASDU of the next frame.
+func (c *session) readFrame() ([]byte, []byte, error) {
+	if _, err := io.ReadFull(c.conn, c.frameBuf[:2]); err != nil {
+		return nil, nil, fmt.Errorf("read: %w", err)
+	}
+	if c.frameBuf[0] != startByte {
+		return nil, nil, fmt.Errorf("invalid start byte 0x%02x", c.frameBuf[0])
+	}
+	length := int(c.frameBuf[1])
+	if length < 4 {
+		return nil, nil, fmt.Errorf("invalid APDU length %d", length)
+	}
+	frame := c.frameBuf[2 : 2+length]
+	if _, err := io.ReadFull(c.conn, frame); err != nil {
+		return nil, nil, fmt.Errorf("read: %w", err)
+	}
+	return frame[:4], frame[4:], nil
+}
+
+// startDataTransfer activates the data transfer and waits its confirmation.
+func (c *session) startDataTransfer() error {
+	if err := c.sendU(uStartDTAct); err != nil {
+		return err
+	}
+	for {
+		control, _, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		switch control[0] {
+		case uStartDTCon:
+			return nil
+		case uTestFRAct:
+			if err := c.sendU(uTestFRCon); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// stopDataTransfer acknowledges the frames received and stops the data
+// transfer, the connection is closed without waiting the confirmation.
+func (c *session) stopDataTransfer() {
+	if c.unacked > 0 {
+		_ = c.sendS()
+	}
+	_ = c.sendU(uStopDTAct)
+}
+
+// readASDU returns the ASDU of the next I-frame, answering the test frames
+// and acknowledging the I-frames received.
+func (c *session) readASDU() (*asdu, error) {
+	for {
+		control, data, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case control[0]&0x01 == 0:
+			// I-format
+			c.recvSeq = (c.recvSeq + 1) % 32768
+			c.unacked++
+			if c.unacked >= ackWindow {
+				if err := c.sendS(); err != nil {
+					return nil, err
+				}
+			}
+			return parseASDU(data)
+		case control[0] == uTestFRAct:
+			if err := c.sendU(uTestFRCon); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
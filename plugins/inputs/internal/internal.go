@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	inter "github.com/circonus-labs/circonus-unified-agent/internal"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
 	"github.com/circonus-labs/circonus-unified-agent/selfstat"
 )
@@ -51,25 +52,57 @@ func (s *Self) Gather(acc cua.Accumulator) error {
 			"heap_released_bytes": m.HeapReleased, // bytes released to the OS
 			"heap_objects":        m.HeapObjects,  // total number of allocated objects
 			"num_gc":              m.NumGC,
+			"gc_pause_total_ns":   m.PauseTotalNs, // cumulative stop-the-world pause
+			"next_gc_bytes":       m.NextGC,       // target heap size of the next GC
+			"goroutines":          int64(runtime.NumGoroutine()),
 		}
 		acc.AddFields("internal_memstats", fields, map[string]string{"__rollup": "false"})
 	}
 
-	// agentVersion := inter.Version()
+	agentVersion := inter.Version()
 	goVersion := strings.TrimPrefix(runtime.Version(), "go")
 
 	for _, m := range selfstat.Metrics() {
-		if m.Name() == "internal_agent" {
+		switch m.Name() {
+		case "internal_agent":
 			m.AddTag("go_version", goVersion)
+		case "internal_write":
+			addBufferFullness(m)
 		}
-		// m.AddTag("version", agentVersion)
+		m.AddTag("version", agentVersion)
 		m.AddTag("__rollup", "false")
 		acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
 	}
 
+	for _, m := range selfstat.HistogramMetrics() {
+		m.AddTag("version", agentVersion)
+		m.AddTag("__rollup", "false")
+		acc.AddHistogram(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+
 	return nil
 }
 
+// addBufferFullness adds the ratio of the buffer of an output in use, from 0
+// to 1, a sustained value near 1 means the output cannot keep up and metrics
+// are about to be dropped.
+func addBufferFullness(m cua.Metric) {
+	size, ok := m.GetField("buffer_size")
+	if !ok {
+		return
+	}
+	limit, ok := m.GetField("buffer_limit")
+	if !ok {
+		return
+	}
+	s, ok1 := size.(int64)
+	l, ok2 := limit.(int64)
+	if !ok1 || !ok2 || l <= 0 {
+		return
+	}
+	m.AddField("buffer_fullness", float64(s)/float64(l))
+}
+
 func init() {
 	inputs.Add("internal", NewSelf)
 }
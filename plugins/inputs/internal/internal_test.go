@@ -26,8 +26,9 @@ func TestSelfPlugin(t *testing.T) {
 			"test": int64(3),
 		},
 		map[string]string{
-			"test":    "foo",
-			"version": "",
+			"test":     "foo",
+			"version":  "",
+			"__rollup": "false",
 		},
 	)
 	acc.ClearMetrics()
@@ -40,8 +41,9 @@ func TestSelfPlugin(t *testing.T) {
 			"test": int64(101),
 		},
 		map[string]string{
-			"test":    "foo",
-			"version": "",
+			"test":     "foo",
+			"version":  "",
+			"__rollup": "false",
 		},
 	)
 	acc.ClearMetrics()
@@ -58,8 +60,35 @@ func TestSelfPlugin(t *testing.T) {
 			"test_ns": int64(150),
 		},
 		map[string]string{
-			"test":    "foo",
-			"version": "",
+			"test":     "foo",
+			"version":  "",
+			"__rollup": "false",
 		},
 	)
 }
+
+func TestBufferFullness(t *testing.T) {
+	s := NewSelf()
+	acc := &testutil.Accumulator{}
+
+	tags := map[string]string{"output": "fullness"}
+	selfstat.Register("write", "buffer_size", tags).Set(250)
+	selfstat.Register("write", "buffer_limit", tags).Set(1000)
+	_ = s.Gather(acc)
+
+	acc.AssertContainsTaggedFields(t, "internal_write",
+		map[string]interface{}{
+			"buffer_size":     int64(250),
+			"buffer_limit":    int64(1000),
+			"buffer_fullness": float64(0.25),
+		},
+		map[string]string{
+			"output":   "fullness",
+			"version":  "",
+			"__rollup": "false",
+		},
+	)
+	memstats, ok := acc.Get("internal_memstats")
+	assert.True(t, ok)
+	assert.Contains(t, memstats.Fields, "goroutines")
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
-	"log"
 	"os/exec"
 	"regexp"
 	"strconv"
@@ -254,7 +253,6 @@ func extractFieldsFromRegex(re *regexp.Regexp, input string) map[string]string {
 	results := make(map[string]string)
 	subexpNames := re.SubexpNames()
 	if len(subexpNames) > len(submatches) {
-		log.Printf("D! No matches found in '%s'", input)
 		return results
 	}
 	for i, name := range subexpNames {
@@ -3,7 +3,6 @@ package kafkaconsumer
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 	"sync"
 	"time"
@@ -177,7 +176,7 @@ func (k *KafkaConsumer) Init() error {
 	// Kafka version 0.10.2.0 is required for consumer groups.
 	config.Version = sarama.V0_10_2_0
 
-	if err := k.SetConfig(config); err != nil {
+	if err := k.SetConfig(config, k.Log); err != nil {
 		return fmt.Errorf("set config: %w", err)
 	}
 
@@ -329,7 +328,7 @@ func (h *ConsumerGroupHandler) onDelivery(track cua.DeliveryInfo) {
 
 	msg, ok := h.undelivered[track.ID()]
 	if !ok {
-		log.Printf("E! [inputs.kafka_consumer] Could not mark message delivered: %d", track.ID())
+		h.acc.AddError(fmt.Errorf("could not mark message delivered: %d", track.ID()))
 		return
 	}
 
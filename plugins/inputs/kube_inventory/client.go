@@ -6,113 +6,98 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
-	"github.com/ericchiang/k8s"
-	v1APPS "github.com/ericchiang/k8s/apis/apps/v1"
-	v1 "github.com/ericchiang/k8s/apis/core/v1"
-	v1beta1EXT "github.com/ericchiang/k8s/apis/extensions/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1beta1EXT "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 type client struct {
 	namespace string
 	timeout   time.Duration
-	*k8s.Client
+	*kubernetes.Clientset
 }
 
 func newClient(baseURL, namespace, bearerToken string, timeout time.Duration, tlsConfig tls.ClientConfig) (*client, error) {
-	c, err := k8s.NewClient(&k8s.Config{
-		Clusters: []k8s.NamedCluster{{Name: "cluster", Cluster: k8s.Cluster{
-			Server:                baseURL,
-			InsecureSkipTLSVerify: tlsConfig.InsecureSkipVerify,
-			CertificateAuthority:  tlsConfig.TLSCA,
-		}}},
-		Contexts: []k8s.NamedContext{{Name: "context", Context: k8s.Context{
-			Cluster:   "cluster",
-			AuthInfo:  "auth",
-			Namespace: namespace,
-		}}},
-		AuthInfos: []k8s.NamedAuthInfo{{Name: "auth", AuthInfo: k8s.AuthInfo{
-			Token:             bearerToken,
-			ClientCertificate: tlsConfig.TLSCert,
-			ClientKey:         tlsConfig.TLSKey,
-		}}},
+	c, err := kubernetes.NewForConfig(&rest.Config{
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure: tlsConfig.InsecureSkipVerify,
+			CAFile:   tlsConfig.TLSCA,
+			CertFile: tlsConfig.TLSCert,
+			KeyFile:  tlsConfig.TLSKey,
+		},
+		Host:        baseURL,
+		BearerToken: bearerToken,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("new k8s client: %w", err)
 	}
 
 	return &client{
-		Client:    c,
+		Clientset: c,
 		timeout:   timeout,
 		namespace: namespace,
 	}, nil
 }
 
-func (c *client) getDaemonSets(ctx context.Context) (*v1APPS.DaemonSetList, error) {
-	list := new(v1APPS.DaemonSetList)
+func (c *client) getDaemonSets(ctx context.Context) (*appsv1.DaemonSetList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, c.namespace, list)
+	return c.AppsV1().DaemonSets(c.namespace).List(ctx, metav1.ListOptions{})
 }
 
-func (c *client) getDeployments(ctx context.Context) (*v1APPS.DeploymentList, error) {
-	list := &v1APPS.DeploymentList{}
+func (c *client) getDeployments(ctx context.Context) (*appsv1.DeploymentList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, c.namespace, list)
+	return c.AppsV1().Deployments(c.namespace).List(ctx, metav1.ListOptions{})
 }
 
-func (c *client) getEndpoints(ctx context.Context) (*v1.EndpointsList, error) {
-	list := new(v1.EndpointsList)
+func (c *client) getEndpoints(ctx context.Context) (*corev1.EndpointsList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, c.namespace, list)
+	return c.CoreV1().Endpoints(c.namespace).List(ctx, metav1.ListOptions{})
 }
 
 func (c *client) getIngress(ctx context.Context) (*v1beta1EXT.IngressList, error) {
-	list := new(v1beta1EXT.IngressList)
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, c.namespace, list)
+	return c.ExtensionsV1beta1().Ingresses(c.namespace).List(ctx, metav1.ListOptions{})
 }
 
-func (c *client) getNodes(ctx context.Context) (*v1.NodeList, error) {
-	list := new(v1.NodeList)
+func (c *client) getNodes(ctx context.Context) (*corev1.NodeList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, "", list)
+	return c.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 }
 
-func (c *client) getPersistentVolumes(ctx context.Context) (*v1.PersistentVolumeList, error) {
-	list := new(v1.PersistentVolumeList)
+func (c *client) getPersistentVolumes(ctx context.Context) (*corev1.PersistentVolumeList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, "", list)
+	return c.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
 }
 
-func (c *client) getPersistentVolumeClaims(ctx context.Context) (*v1.PersistentVolumeClaimList, error) {
-	list := new(v1.PersistentVolumeClaimList)
+func (c *client) getPersistentVolumeClaims(ctx context.Context) (*corev1.PersistentVolumeClaimList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, c.namespace, list)
+	return c.CoreV1().PersistentVolumeClaims(c.namespace).List(ctx, metav1.ListOptions{})
 }
 
-func (c *client) getPods(ctx context.Context) (*v1.PodList, error) {
-	list := new(v1.PodList)
+func (c *client) getPods(ctx context.Context) (*corev1.PodList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, c.namespace, list)
+	return c.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{})
 }
 
-func (c *client) getServices(ctx context.Context) (*v1.ServiceList, error) {
-	list := new(v1.ServiceList)
+func (c *client) getServices(ctx context.Context) (*corev1.ServiceList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, c.namespace, list)
+	return c.CoreV1().Services(c.namespace).List(ctx, metav1.ListOptions{})
 }
 
-func (c *client) getStatefulSets(ctx context.Context) (*v1APPS.StatefulSetList, error) {
-	list := new(v1APPS.StatefulSetList)
+func (c *client) getStatefulSets(ctx context.Context) (*appsv1.StatefulSetList, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
-	return list, c.List(ctx, c.namespace, list)
+	return c.AppsV1().StatefulSets(c.namespace).List(ctx, metav1.ListOptions{})
 }
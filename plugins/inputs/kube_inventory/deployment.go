@@ -2,10 +2,9 @@ package kubeinventory
 
 import (
 	"context"
-	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
-	v1 "github.com/ericchiang/k8s/apis/apps/v1"
+	v1 "k8s.io/api/apps/v1"
 )
 
 func collectDeployments(ctx context.Context, acc cua.Accumulator, ki *KubernetesInventory) {
@@ -15,8 +14,8 @@ func collectDeployments(ctx context.Context, acc cua.Accumulator, ki *Kubernetes
 		return
 	}
 	for _, d := range list.Items {
-		ki.gatherDeployment(*d, acc)
-		// if err = ki.gatherDeployment(*d, acc); err != nil {
+		ki.gatherDeployment(d, acc)
+		// if err = ki.gatherDeployment(d, acc); err != nil {
 		// 	acc.AddError(err)
 		// 	return
 		// }
@@ -25,17 +24,19 @@ func collectDeployments(ctx context.Context, acc cua.Accumulator, ki *Kubernetes
 
 func (ki *KubernetesInventory) gatherDeployment(d v1.Deployment, acc cua.Accumulator) {
 	fields := map[string]interface{}{
-		"replicas_available":   d.Status.GetAvailableReplicas(),
-		"replicas_unavailable": d.Status.GetUnavailableReplicas(),
-		"created":              time.Unix(d.Metadata.CreationTimestamp.GetSeconds(), int64(d.Metadata.CreationTimestamp.GetNanos())).UnixNano(),
+		"replicas_available":   d.Status.AvailableReplicas,
+		"replicas_unavailable": d.Status.UnavailableReplicas,
+		"created":              d.CreationTimestamp.UnixNano(),
 	}
 	tags := map[string]string{
-		"deployment_name": d.Metadata.GetName(),
-		"namespace":       d.Metadata.GetNamespace(),
+		"deployment_name": d.Name,
+		"namespace":       d.Namespace,
 	}
-	for key, val := range d.GetSpec().GetSelector().GetMatchLabels() {
-		if ki.selectorFilter.Match(key) {
-			tags["selector_"+key] = val
+	if d.Spec.Selector != nil {
+		for key, val := range d.Spec.Selector.MatchLabels {
+			if ki.selectorFilter.Match(key) {
+				tags["selector_"+key] = val
+			}
 		}
 	}
 
@@ -3,10 +3,9 @@ package kubeinventory
 import (
 	"context"
 	"strings"
-	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
-	v1 "github.com/ericchiang/k8s/apis/core/v1"
+	v1 "k8s.io/api/core/v1"
 )
 
 func collectEndpoints(ctx context.Context, acc cua.Accumulator, ki *KubernetesInventory) {
@@ -16,8 +15,8 @@ func collectEndpoints(ctx context.Context, acc cua.Accumulator, ki *KubernetesIn
 		return
 	}
 	for _, i := range list.Items {
-		ki.gatherEndpoint(*i, acc)
-		// if err = ki.gatherEndpoint(*i, acc); err != nil {
+		ki.gatherEndpoint(i, acc)
+		// if err = ki.gatherEndpoint(i, acc); err != nil {
 		// 	acc.AddError(err)
 		// 	return
 		// }
@@ -25,53 +24,57 @@ func collectEndpoints(ctx context.Context, acc cua.Accumulator, ki *KubernetesIn
 }
 
 func (ki *KubernetesInventory) gatherEndpoint(e v1.Endpoints, acc cua.Accumulator) {
-	if e.Metadata.CreationTimestamp.GetSeconds() == 0 && e.Metadata.CreationTimestamp.GetNanos() == 0 {
+	if e.CreationTimestamp.IsZero() {
 		return
 	}
 
 	fields := map[string]interface{}{
-		"created":    time.Unix(e.Metadata.CreationTimestamp.GetSeconds(), int64(e.Metadata.CreationTimestamp.GetNanos())).UnixNano(),
-		"generation": e.Metadata.GetGeneration(),
+		"created":    e.CreationTimestamp.UnixNano(),
+		"generation": e.Generation,
 	}
 
 	tags := map[string]string{
-		"endpoint_name": e.Metadata.GetName(),
-		"namespace":     e.Metadata.GetNamespace(),
+		"endpoint_name": e.Name,
+		"namespace":     e.Namespace,
 	}
 
-	for _, endpoint := range e.GetSubsets() {
-		for _, readyAddr := range endpoint.GetAddresses() {
+	for _, endpoint := range e.Subsets {
+		for _, readyAddr := range endpoint.Addresses {
 			fields["ready"] = true
 
-			tags["hostname"] = readyAddr.GetHostname()
-			tags["node_name"] = readyAddr.GetNodeName()
+			tags["hostname"] = readyAddr.Hostname
+			if readyAddr.NodeName != nil {
+				tags["node_name"] = *readyAddr.NodeName
+			}
 			if readyAddr.TargetRef != nil {
-				tags[strings.ToLower(readyAddr.GetTargetRef().GetKind())] = readyAddr.GetTargetRef().GetName()
+				tags[strings.ToLower(readyAddr.TargetRef.Kind)] = readyAddr.TargetRef.Name
 			}
 
-			for _, port := range endpoint.GetPorts() {
-				fields["port"] = port.GetPort()
+			for _, port := range endpoint.Ports {
+				fields["port"] = port.Port
 
-				tags["port_name"] = port.GetName()
-				tags["port_protocol"] = port.GetProtocol()
+				tags["port_name"] = port.Name
+				tags["port_protocol"] = string(port.Protocol)
 
 				acc.AddFields(endpointMeasurement, fields, tags)
 			}
 		}
-		for _, notReadyAddr := range endpoint.GetNotReadyAddresses() {
+		for _, notReadyAddr := range endpoint.NotReadyAddresses {
 			fields["ready"] = false
 
-			tags["hostname"] = notReadyAddr.GetHostname()
-			tags["node_name"] = notReadyAddr.GetNodeName()
+			tags["hostname"] = notReadyAddr.Hostname
+			if notReadyAddr.NodeName != nil {
+				tags["node_name"] = *notReadyAddr.NodeName
+			}
 			if notReadyAddr.TargetRef != nil {
-				tags[strings.ToLower(notReadyAddr.GetTargetRef().GetKind())] = notReadyAddr.GetTargetRef().GetName()
+				tags[strings.ToLower(notReadyAddr.TargetRef.Kind)] = notReadyAddr.TargetRef.Name
 			}
 
-			for _, port := range endpoint.GetPorts() {
-				fields["port"] = port.GetPort()
+			for _, port := range endpoint.Ports {
+				fields["port"] = port.Port
 
-				tags["port_name"] = port.GetName()
-				tags["port_protocol"] = port.GetProtocol()
+				tags["port_name"] = port.Name
+				tags["port_protocol"] = string(port.Protocol)
 
 				acc.AddFields(endpointMeasurement, fields, tags)
 			}
@@ -4,7 +4,7 @@ import (
 	"context"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
-	v1 "github.com/ericchiang/k8s/apis/core/v1"
+	v1 "k8s.io/api/core/v1"
 )
 
 const (
@@ -20,8 +20,8 @@ func collectNodes(ctx context.Context, acc cua.Accumulator, ki *KubernetesInvent
 		return
 	}
 	for _, n := range list.Items {
-		ki.gatherNode(*n, acc)
-		// if err = ki.gatherNode(*n, acc); err != nil {
+		ki.gatherNode(n, acc)
+		// if err = ki.gatherNode(n, acc); err != nil {
 		// 	acc.AddError(err)
 		// 	return
 		// }
@@ -31,28 +31,28 @@ func collectNodes(ctx context.Context, acc cua.Accumulator, ki *KubernetesInvent
 func (ki *KubernetesInventory) gatherNode(n v1.Node, acc cua.Accumulator) {
 	fields := map[string]interface{}{}
 	tags := map[string]string{
-		"node_name": *n.Metadata.Name,
+		"node_name": n.Name,
 	}
 
 	for resourceName, val := range n.Status.Capacity {
 		switch resourceName {
 		case resourceCPU:
-			fields["capacity_cpu_cores"] = atoi(val.GetString_())
+			fields["capacity_cpu_cores"] = atoi(val.String())
 		case resourceMemory:
-			fields["capacity_memory_bytes"] = convertQuantity(val.GetString_(), 1)
+			fields["capacity_memory_bytes"] = ki.convertQuantity(val.String(), 1)
 		case resourcePods:
-			fields["capacity_pods"] = atoi(val.GetString_())
+			fields["capacity_pods"] = atoi(val.String())
 		}
 	}
 
 	for resourceName, val := range n.Status.Allocatable {
 		switch resourceName {
 		case resourceCPU:
-			fields["allocatable_cpu_cores"] = atoi(val.GetString_())
+			fields["allocatable_cpu_cores"] = atoi(val.String())
 		case resourceMemory:
-			fields["allocatable_memory_bytes"] = convertQuantity(val.GetString_(), 1)
+			fields["allocatable_memory_bytes"] = ki.convertQuantity(val.String(), 1)
 		case resourcePods:
-			fields["allocatable_pods"] = atoi(val.GetString_())
+			fields["allocatable_pods"] = atoi(val.String())
 		}
 	}
 
@@ -5,9 +5,9 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
-	v1 "github.com/ericchiang/k8s/apis/core/v1"
-	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
-	"github.com/ericchiang/k8s/apis/resource"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestNode(t *testing.T) {
@@ -35,62 +35,62 @@ func TestNode(t *testing.T) {
 			handler: &mockHandler{
 				responseMap: map[string]interface{}{
 					"/nodes/": &v1.NodeList{
-						Items: []*v1.Node{
+						Items: []v1.Node{
 							{
-								Status: &v1.NodeStatus{
-									NodeInfo: &v1.NodeSystemInfo{
-										KernelVersion:           toStrPtr("4.14.48-coreos-r2"),
-										OsImage:                 toStrPtr("Container Linux by CoreOS 1745.7.0 (Rhyolite)"),
-										ContainerRuntimeVersion: toStrPtr("docker://18.3.1"),
-										KubeletVersion:          toStrPtr("v1.10.3"),
-										KubeProxyVersion:        toStrPtr("v1.10.3"),
+								Status: v1.NodeStatus{
+									NodeInfo: v1.NodeSystemInfo{
+										KernelVersion:           "4.14.48-coreos-r2",
+										OSImage:                 "Container Linux by CoreOS 1745.7.0 (Rhyolite)",
+										ContainerRuntimeVersion: "docker://18.3.1",
+										KubeletVersion:          "v1.10.3",
+										KubeProxyVersion:        "v1.10.3",
 									},
-									Phase: toStrPtr("Running"),
-									Capacity: map[string]*resource.Quantity{
-										"cpu":                     {String_: toStrPtr("16")},
-										"ephemeral_storage_bytes": {String_: toStrPtr("49536401408")},
-										"hugepages_1Gi_bytes":     {String_: toStrPtr("0")},
-										"hugepages_2Mi_bytes":     {String_: toStrPtr("0")},
-										"memory":                  {String_: toStrPtr("125817904Ki")},
-										"pods":                    {String_: toStrPtr("110")},
+									Phase: "Running",
+									Capacity: v1.ResourceList{
+										"cpu":                     resource.MustParse("16"),
+										"ephemeral_storage_bytes": resource.MustParse("49536401408"),
+										"hugepages_1Gi_bytes":     resource.MustParse("0"),
+										"hugepages_2Mi_bytes":     resource.MustParse("0"),
+										"memory":                  resource.MustParse("125817904Ki"),
+										"pods":                    resource.MustParse("110"),
 									},
-									Allocatable: map[string]*resource.Quantity{
-										"cpu":                     {String_: toStrPtr("16")},
-										"ephemeral_storage_bytes": {String_: toStrPtr("44582761194")},
-										"hugepages_1Gi_bytes":     {String_: toStrPtr("0")},
-										"hugepages_2Mi_bytes":     {String_: toStrPtr("0")},
-										"memory":                  {String_: toStrPtr("125715504Ki")},
-										"pods":                    {String_: toStrPtr("110")},
+									Allocatable: v1.ResourceList{
+										"cpu":                     resource.MustParse("16"),
+										"ephemeral_storage_bytes": resource.MustParse("44582761194"),
+										"hugepages_1Gi_bytes":     resource.MustParse("0"),
+										"hugepages_2Mi_bytes":     resource.MustParse("0"),
+										"memory":                  resource.MustParse("125715504Ki"),
+										"pods":                    resource.MustParse("110"),
 									},
-									Conditions: []*v1.NodeCondition{
-										{Type: toStrPtr("Ready"), Status: toStrPtr("true"), LastTransitionTime: &metav1.Time{Seconds: toInt64Ptr(now.Unix())}},
-										{Type: toStrPtr("OutOfDisk"), Status: toStrPtr("false"), LastTransitionTime: &metav1.Time{Seconds: toInt64Ptr(created.Unix())}},
+									Conditions: []v1.NodeCondition{
+										{Type: "Ready", Status: "true", LastTransitionTime: metav1.Time{Time: now}},
+										{Type: "OutOfDisk", Status: "false", LastTransitionTime: metav1.Time{Time: created}},
 									},
 								},
-								Spec: &v1.NodeSpec{
-									ProviderID: toStrPtr("aws:///us-east-1c/i-0c00"),
-									Taints: []*v1.Taint{
+								Spec: v1.NodeSpec{
+									ProviderID: "aws:///us-east-1c/i-0c00",
+									Taints: []v1.Taint{
 										{
-											Key:    toStrPtr("k1"),
-											Value:  toStrPtr("v1"),
-											Effect: toStrPtr("NoExecute"),
+											Key:    "k1",
+											Value:  "v1",
+											Effect: "NoExecute",
 										},
 										{
-											Key:    toStrPtr("k2"),
-											Value:  toStrPtr("v2"),
-											Effect: toStrPtr("NoSchedule"),
+											Key:    "k2",
+											Value:  "v2",
+											Effect: "NoSchedule",
 										},
 									},
 								},
-								Metadata: &metav1.ObjectMeta{
-									Generation: toInt64Ptr(int64(11232)),
-									Namespace:  toStrPtr("ns1"),
-									Name:       toStrPtr("node1"),
+								ObjectMeta: metav1.ObjectMeta{
+									Generation: int64(11232),
+									Namespace:  "ns1",
+									Name:       "node1",
 									Labels: map[string]string{
 										"lab1": "v1",
 										"lab2": "v2",
 									},
-									CreationTimestamp: &metav1.Time{Seconds: toInt64Ptr(created.Unix())},
+									CreationTimestamp: metav1.Time{Time: created},
 								},
 							},
 						},
@@ -125,7 +125,7 @@ func TestNode(t *testing.T) {
 		}
 		acc := new(testutil.Accumulator)
 		for _, node := range ((v.handler.responseMap["/nodes/"]).(*v1.NodeList)).Items {
-			ks.gatherNode(*node, acc)
+			ks.gatherNode(node, acc)
 			// if err != nil {
 			// 	t.Errorf("Failed to gather node - %s", err.Error())
 			// }
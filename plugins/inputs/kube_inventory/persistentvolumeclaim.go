@@ -5,7 +5,7 @@ import (
 	"strings"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
-	v1 "github.com/ericchiang/k8s/apis/core/v1"
+	v1 "k8s.io/api/core/v1"
 )
 
 func collectPersistentVolumeClaims(ctx context.Context, acc cua.Accumulator, ki *KubernetesInventory) {
@@ -15,8 +15,8 @@ func collectPersistentVolumeClaims(ctx context.Context, acc cua.Accumulator, ki
 		return
 	}
 	for _, pvc := range list.Items {
-		ki.gatherPersistentVolumeClaim(*pvc, acc)
-		// if err = ki.gatherPersistentVolumeClaim(*pvc, acc); err != nil {
+		ki.gatherPersistentVolumeClaim(pvc, acc)
+		// if err = ki.gatherPersistentVolumeClaim(pvc, acc); err != nil {
 		// 	acc.AddError(err)
 		// 	return
 		// }
@@ -25,7 +25,7 @@ func collectPersistentVolumeClaims(ctx context.Context, acc cua.Accumulator, ki
 
 func (ki *KubernetesInventory) gatherPersistentVolumeClaim(pvc v1.PersistentVolumeClaim, acc cua.Accumulator) {
 	phaseType := 3
-	switch strings.ToLower(pvc.Status.GetPhase()) {
+	switch strings.ToLower(string(pvc.Status.Phase)) {
 	case "bound":
 		phaseType = 0
 	case "lost":
@@ -36,15 +36,21 @@ func (ki *KubernetesInventory) gatherPersistentVolumeClaim(pvc v1.PersistentVolu
 	fields := map[string]interface{}{
 		"phase_type": phaseType,
 	}
+	storageClass := ""
+	if pvc.Spec.StorageClassName != nil {
+		storageClass = *pvc.Spec.StorageClassName
+	}
 	tags := map[string]string{
-		"pvc_name":     pvc.Metadata.GetName(),
-		"namespace":    pvc.Metadata.GetNamespace(),
-		"phase":        pvc.Status.GetPhase(),
-		"storageclass": pvc.Spec.GetStorageClassName(),
+		"pvc_name":     pvc.Name,
+		"namespace":    pvc.Namespace,
+		"phase":        string(pvc.Status.Phase),
+		"storageclass": storageClass,
 	}
-	for key, val := range pvc.GetSpec().GetSelector().GetMatchLabels() {
-		if ki.selectorFilter.Match(key) {
-			tags["selector_"+key] = val
+	if pvc.Spec.Selector != nil {
+		for key, val := range pvc.Spec.Selector.MatchLabels {
+			if ki.selectorFilter.Match(key) {
+				tags["selector_"+key] = val
+			}
 		}
 	}
 
@@ -0,0 +1,292 @@
+package macos
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+)
+
+const sampleConfig = `
+  ## Gather the CPU, GPU and ANE power and the thermal pressure with
+  ## powermetrics, it must run as root, see use_sudo.
+  # powermetrics = true
+
+  ## Sampling duration of powermetrics, the power is averaged over it.
+  # powermetrics_sample = "1s"
+
+  ## Labels of the launchd services to report the status of, supports glob
+  ## patterns.  The services of the domain of the user running the agent are
+  ## listed, the system services when running as root.
+  # launchd_services = ["com.example.*"]
+
+  ## Mount points of the volumes to report the space and SMART status of with
+  ## diskutil.
+  # diskutil_volumes = ["/"]
+
+  ## Run powermetrics with sudo, sudo must be configured to allow it without
+  ## a password.
+  # use_sudo = false
+
+  ## Timeout of each command.
+  # timeout = "5s"
+`
+
+// runCmd runs a command and returns its output, it is replaced in tests.
+var runCmd = func(timeout time.Duration, sudo bool, command string, args ...string) ([]byte, error) {
+	cmd := exec.Command(command, args...)
+	if sudo {
+		cmd = exec.Command("sudo", append([]string{"-n", command}, args...)...) //nolint:gosec // G204
+	}
+	return internal.StdOutputTimeout(cmd, timeout)
+}
+
+// MacOS gathers the host metrics specific to macOS.
+type MacOS struct {
+	Powermetrics       bool              `toml:"powermetrics"`
+	PowermetricsSample internal.Duration `toml:"powermetrics_sample"`
+	LaunchdServices    []string          `toml:"launchd_services"`
+	DiskutilVolumes    []string          `toml:"diskutil_volumes"`
+	UseSudo            bool              `toml:"use_sudo"`
+	Timeout            internal.Duration `toml:"timeout"`
+	Log                cua.Logger        `toml:"-"`
+
+	services filter.Filter
+}
+
+func (m *MacOS) Description() string {
+	return "Gather power, thermal, launchd service and volume metrics of macOS hosts"
+}
+
+func (m *MacOS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *MacOS) init() error {
+	if m.PowermetricsSample.Duration <= 0 {
+		return fmt.Errorf("powermetrics_sample must be positive")
+	}
+	var err error
+	if m.services, err = filter.Compile(m.LaunchdServices); err != nil {
+		return fmt.Errorf("launchd_services: %w", err)
+	}
+	return nil
+}
+
+func (m *MacOS) gather(acc cua.Accumulator) error {
+	if m.Powermetrics {
+		if err := m.gatherPowermetrics(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	if m.services != nil {
+		if err := m.gatherLaunchd(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	for _, volume := range m.DiskutilVolumes {
+		if err := m.gatherDiskutil(acc, volume); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func (m *MacOS) gatherPowermetrics(acc cua.Accumulator) error {
+	// the samplers are not all available on every model, the missing ones are
+	// ignored by powermetrics
+	ms := strconv.FormatInt(m.PowermetricsSample.Duration.Milliseconds(), 10)
+	out, err := runCmd(m.PowermetricsSample.Duration+m.Timeout.Duration, m.UseSudo,
+		"powermetrics", "--samplers", "cpu_power,gpu_power,thermal", "-i", ms, "-n", "1")
+	if err != nil {
+		return fmt.Errorf("powermetrics: %w", err)
+	}
+	fields, clusters := parsePowermetrics(out)
+	if len(fields) != 0 {
+		acc.AddGauge("macos_power", fields, nil)
+	}
+	for cluster, fields := range clusters {
+		acc.AddGauge("macos_cpu_cluster", fields, map[string]string{"cluster": cluster})
+	}
+	return nil
+}
+
+func (m *MacOS) gatherLaunchd(acc cua.Accumulator) error {
+	out, err := runCmd(m.Timeout.Duration, false, "launchctl", "list")
+	if err != nil {
+		return fmt.Errorf("launchctl: %w", err)
+	}
+	for _, s := range parseLaunchctlList(out) {
+		if !m.services.Match(s.label) {
+			continue
+		}
+		fields := map[string]interface{}{
+			"running":          s.pid != 0,
+			"last_exit_status": s.status,
+		}
+		if s.pid != 0 {
+			fields["pid"] = s.pid
+		}
+		acc.AddFields("macos_launchd", fields, map[string]string{"label": s.label})
+	}
+	return nil
+}
+
+func (m *MacOS) gatherDiskutil(acc cua.Accumulator, volume string) error {
+	out, err := runCmd(m.Timeout.Duration, false, "diskutil", "info", volume)
+	if err != nil {
+		return fmt.Errorf("diskutil (%s): %w", volume, err)
+	}
+	fields, tags := parseDiskutilInfo(out)
+	tags["volume"] = volume
+	acc.AddGauge("macos_volume", fields, tags)
+	return nil
+}
+
+var (
+	// "CPU Power: 1234 mW", "Combined Power (CPU + GPU + ANE): 1300 mW"
+	powerLine = regexp.MustCompile(`^([A-Za-z ]+?) Power(?: \([^)]*\))?: ([0-9.]+) mW$`)
+	// "Intel energy model derived package power (CPUs+GT+SA): 3.12W"
+	intelPackageLine = regexp.MustCompile(`^Intel energy model derived package power \([^)]*\): ([0-9.]+)W$`)
+	// "E-Cluster HW active frequency: 1020 MHz"
+	clusterFrequencyLine = regexp.MustCompile(`^([A-Za-z0-9-]+)-Cluster HW active frequency: ([0-9.]+) MHz$`)
+	// "E-Cluster HW active residency:  45.32% (600 MHz: 28% ...)"
+	clusterResidencyLine = regexp.MustCompile(`^([A-Za-z0-9-]+)-Cluster HW active residency: +([0-9.]+)%`)
+	// "Current pressure level: Nominal"
+	thermalLine = regexp.MustCompile(`^Current pressure level: (\w+)$`)
+
+	thermalLevels = map[string]int64{
+		"Nominal":  0,
+		"Moderate": 1,
+		"Heavy":    2,
+		"Trapping": 3,
+		"Sleeping": 4,
+	}
+)
+
+// parsePowermetrics parses the text output of powermetrics, it returns the
+// host fields and the fields of the CPU clusters of Apple Silicon.
+func parsePowermetrics(out []byte) (map[string]interface{}, map[string]map[string]interface{}) {
+	fields := make(map[string]interface{})
+	clusters := make(map[string]map[string]interface{})
+	cluster := func(name string) map[string]interface{} {
+		name = strings.ToLower(name)
+		if _, ok := clusters[name]; !ok {
+			clusters[name] = make(map[string]interface{})
+		}
+		return clusters[name]
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := powerLine.FindStringSubmatch(line); match != nil {
+			if value, err := strconv.ParseFloat(match[2], 64); err == nil {
+				key := strings.ToLower(strings.ReplaceAll(match[1], " ", "_"))
+				fields[key+"_power_mw"] = value
+			}
+		} else if match := intelPackageLine.FindStringSubmatch(line); match != nil {
+			if value, err := strconv.ParseFloat(match[1], 64); err == nil {
+				fields["package_power_mw"] = value * 1000
+			}
+		} else if match := clusterFrequencyLine.FindStringSubmatch(line); match != nil {
+			if value, err := strconv.ParseFloat(match[2], 64); err == nil {
+				cluster(match[1])["frequency_mhz"] = value
+			}
+		} else if match := clusterResidencyLine.FindStringSubmatch(line); match != nil {
+			if value, err := strconv.ParseFloat(match[2], 64); err == nil {
+				cluster(match[1])["active_residency_percent"] = value
+			}
+		} else if match := thermalLine.FindStringSubmatch(line); match != nil {
+			fields["thermal_pressure"] = match[1]
+			if level, ok := thermalLevels[match[1]]; ok {
+				fields["thermal_pressure_level"] = level
+			}
+		}
+	}
+	return fields, clusters
+}
+
+type launchdService struct {
+	label  string
+	pid    int64
+	status int64
+}
+
+// parseLaunchctlList parses the output of "launchctl list", the PID is "-"
+// for the services not running, the status is the last exit status or the
+// negative signal that terminated the service.
+func parseLaunchctlList(out []byte) []launchdService {
+	var services []launchdService
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 3 || parts[0] == "PID" {
+			continue
+		}
+		s := launchdService{label: parts[2]}
+		if parts[0] != "-" {
+			s.pid, _ = strconv.ParseInt(parts[0], 10, 64)
+		}
+		if parts[1] != "-" {
+			s.status, _ = strconv.ParseInt(parts[1], 10, 64)
+		}
+		services = append(services, s)
+	}
+	return services
+}
+
+// "494.4 GB (494384795648 Bytes) (exactly 965595304 512-Byte-Units)"
+var diskutilBytes = regexp.MustCompile(`\((\d+) Bytes\)`)
+
+// parseDiskutilInfo parses the output of "diskutil info <volume>".
+func parseDiskutilInfo(out []byte) (map[string]interface{}, map[string]string) {
+	fields := make(map[string]interface{})
+	tags := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch key {
+		case "Device Identifier":
+			tags["device"] = value
+		case "Volume Name":
+			tags["volume_name"] = value
+		case "File System Personality":
+			tags["fstype"] = value
+		case "SMART Status":
+			fields["smart_status"] = value
+			fields["smart_ok"] = value == "Verified"
+		case "Container Total Space", "Container Free Space", "Volume Used Space", "Volume Free Space", "Disk Size":
+			if match := diskutilBytes.FindStringSubmatch(value); match != nil {
+				if n, err := strconv.ParseUint(match[1], 10, 64); err == nil {
+					fields[strings.ToLower(strings.ReplaceAll(key, " ", "_"))+"_bytes"] = n
+				}
+			}
+		case "Solid State":
+			fields["solid_state"] = value == "Yes"
+		}
+	}
+	return fields, tags
+}
+
+func newMacOS() *MacOS {
+	return &MacOS{
+		Powermetrics:       true,
+		PowermetricsSample: internal.Duration{Duration: time.Second},
+		DiskutilVolumes:    []string{"/"},
+		Timeout:            internal.Duration{Duration: 5 * time.Second},
+	}
+}
@@ -0,0 +1,22 @@
+// +build darwin
+
+package macos
+
+import (
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+func (m *MacOS) Init() error {
+	return m.init()
+}
+
+func (m *MacOS) Gather(acc cua.Accumulator) error {
+	return m.gather(acc)
+}
+
+func init() {
+	inputs.Add("macos", func() cua.Input {
+		return newMacOS()
+	})
+}
@@ -0,0 +1,23 @@
+// +build !darwin
+
+package macos
+
+import (
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+func (m *MacOS) Init() error {
+	m.Log.Warn("Current platform is not supported")
+	return nil
+}
+
+func (m *MacOS) Gather(acc cua.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("macos", func() cua.Input {
+		return newMacOS()
+	})
+}
@@ -0,0 +1,184 @@
+package macos
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const powermetricsAppleSilicon = `Machine model: MacBookPro18,3
+OS version: 21G72
+
+*** Sampled system activity (Wed Sep 14 10:00:00 2022 +0200) (1003.12ms elapsed) ***
+
+**** Processor usage ****
+
+E-Cluster HW active frequency: 1020 MHz
+E-Cluster HW active residency:  45.32% (600 MHz:  28% 972 MHz:  12%)
+CPU 0 frequency: 1071 MHz
+P0-Cluster HW active frequency: 2345 MHz
+P0-Cluster HW active residency:  12.50% (600 MHz:   0%)
+
+CPU Power: 1234 mW
+GPU Power: 56 mW
+ANE Power: 0 mW
+Combined Power (CPU + GPU + ANE): 1290 mW
+
+**** GPU usage ****
+
+GPU HW active frequency: 389 MHz
+
+**** Thermal pressure ****
+
+Current pressure level: Moderate
+`
+
+const powermetricsIntel = `**** Processor usage ****
+
+Intel energy model derived package power (CPUs+GT+SA): 3.12W
+
+**** Thermal pressure ****
+
+Current pressure level: Nominal
+`
+
+const launchctlList = `PID	Status	Label
+-	0	com.apple.SafariHistoryServiceAgent
+327	0	com.example.agent
+-	-9	com.example.crashed
+-	78	com.example.failed
+`
+
+const diskutilInfo = `   Device Identifier:         disk3s1s1
+   Device Node:               /dev/disk3s1s1
+   Whole:                     No
+   Part of Whole:             disk3
+
+   Volume Name:               Macintosh HD
+   Mounted:                   Yes
+   Mount Point:               /
+
+   File System Personality:   APFS
+   SMART Status:              Verified
+
+   Disk Size:                 494.4 GB (494384795648 Bytes) (exactly 965595304 512-Byte-Units)
+   Container Total Space:     494.4 GB (494384795648 Bytes) (exactly 965595304 512-Byte-Units)
+   Container Free Space:      300.1 GB (300123456789 Bytes) (exactly 586178626 512-Byte-Units)
+
+   Solid State:               Yes
+`
+
+func TestParsePowermetrics(t *testing.T) {
+	fields, clusters := parsePowermetrics([]byte(powermetricsAppleSilicon))
+	require.Equal(t, map[string]interface{}{
+		"cpu_power_mw":           1234.0,
+		"gpu_power_mw":           56.0,
+		"ane_power_mw":           0.0,
+		"combined_power_mw":      1290.0,
+		"thermal_pressure":       "Moderate",
+		"thermal_pressure_level": int64(1),
+	}, fields)
+	require.Equal(t, map[string]map[string]interface{}{
+		"e":  {"frequency_mhz": 1020.0, "active_residency_percent": 45.32},
+		"p0": {"frequency_mhz": 2345.0, "active_residency_percent": 12.5},
+	}, clusters)
+
+	fields, clusters = parsePowermetrics([]byte(powermetricsIntel))
+	require.Equal(t, map[string]interface{}{
+		"package_power_mw":       3120.0,
+		"thermal_pressure":       "Nominal",
+		"thermal_pressure_level": int64(0),
+	}, fields)
+	require.Empty(t, clusters)
+}
+
+func TestGather(t *testing.T) {
+	defer func(f func(time.Duration, bool, string, ...string) ([]byte, error)) { runCmd = f }(runCmd)
+	var commands []string
+	runCmd = func(timeout time.Duration, sudo bool, command string, args ...string) ([]byte, error) {
+		commands = append(commands, strings.Join(append([]string{command}, args...), " "))
+		switch command {
+		case "powermetrics":
+			require.True(t, sudo)
+			return []byte(powermetricsAppleSilicon), nil
+		case "launchctl":
+			return []byte(launchctlList), nil
+		case "diskutil":
+			if args[1] == "/Volumes/Missing" {
+				return nil, errors.New("exit status 1")
+			}
+			return []byte(diskutilInfo), nil
+		}
+		return nil, errors.New("unexpected command")
+	}
+
+	m := newMacOS()
+	m.UseSudo = true
+	m.LaunchdServices = []string{"com.example.*"}
+	m.DiskutilVolumes = []string{"/", "/Volumes/Missing"}
+	m.Log = testutil.Logger{}
+	require.NoError(t, m.init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{
+		"powermetrics --samplers cpu_power,gpu_power,thermal -i 1000 -n 1",
+		"launchctl list",
+		"diskutil info /",
+		"diskutil info /Volumes/Missing",
+	}, commands)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("macos_power", map[string]string{},
+			map[string]interface{}{
+				"cpu_power_mw":           1234.0,
+				"gpu_power_mw":           56.0,
+				"ane_power_mw":           0.0,
+				"combined_power_mw":      1290.0,
+				"thermal_pressure":       "Moderate",
+				"thermal_pressure_level": int64(1),
+			}, time.Unix(0, 0), cua.Gauge),
+		testutil.MustMetric("macos_cpu_cluster", map[string]string{"cluster": "e"},
+			map[string]interface{}{"frequency_mhz": 1020.0, "active_residency_percent": 45.32},
+			time.Unix(0, 0), cua.Gauge),
+		testutil.MustMetric("macos_cpu_cluster", map[string]string{"cluster": "p0"},
+			map[string]interface{}{"frequency_mhz": 2345.0, "active_residency_percent": 12.5},
+			time.Unix(0, 0), cua.Gauge),
+		testutil.MustMetric("macos_launchd", map[string]string{"label": "com.example.agent"},
+			map[string]interface{}{"running": true, "pid": int64(327), "last_exit_status": int64(0)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("macos_launchd", map[string]string{"label": "com.example.crashed"},
+			map[string]interface{}{"running": false, "last_exit_status": int64(-9)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("macos_launchd", map[string]string{"label": "com.example.failed"},
+			map[string]interface{}{"running": false, "last_exit_status": int64(78)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("macos_volume",
+			map[string]string{"volume": "/", "device": "disk3s1s1", "volume_name": "Macintosh HD", "fstype": "APFS"},
+			map[string]interface{}{
+				"smart_status":                "Verified",
+				"smart_ok":                    true,
+				"disk_size_bytes":             uint64(494384795648),
+				"container_total_space_bytes": uint64(494384795648),
+				"container_free_space_bytes":  uint64(300123456789),
+				"solid_state":                 true,
+			}, time.Unix(0, 0), cua.Gauge),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestInitErrors(t *testing.T) {
+	m := newMacOS()
+	m.LaunchdServices = []string{"com.[example"}
+	require.Error(t, m.init())
+
+	m = newMacOS()
+	m.PowermetricsSample.Duration = 0
+	require.Error(t, m.init())
+}
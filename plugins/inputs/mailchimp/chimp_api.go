@@ -5,12 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"regexp"
 	"sync"
 	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
 )
 
 const (
@@ -23,6 +24,7 @@ var mailchimpDatacenter = regexp.MustCompile("[a-z]+[0-9]+$")
 type ChimpAPI struct {
 	Transport http.RoundTripper
 	Debug     bool
+	Log       cua.Logger
 
 	sync.Mutex
 
@@ -53,12 +55,12 @@ func (p *ReportsParams) String() string {
 	return v.Encode()
 }
 
-func NewChimpAPI(apiKey string) *ChimpAPI {
+func NewChimpAPI(apiKey string, log cua.Logger) *ChimpAPI {
 	u := &url.URL{}
 	u.Scheme = "https"
 	u.Host = fmt.Sprintf("%s.api.mailchimp.com", mailchimpDatacenter.FindString(apiKey))
 	u.User = url.UserPassword("", apiKey)
-	return &ChimpAPI{url: u}
+	return &ChimpAPI{url: u, Log: log}
 }
 
 type APIError struct {
@@ -134,7 +136,7 @@ func runChimp(api *ChimpAPI, params ReportsParams) ([]byte, error) {
 	req.URL.RawQuery = params.String()
 	req.Header.Set("User-Agent", "Circonus-MailChimp-Plugin")
 	if api.Debug {
-		log.Printf("D! [inputs.mailchimp] request URL: %s", req.URL.String())
+		api.Log.Debugf("request URL: %s", req.URL.String())
 	}
 
 	resp, err := client.Do(req)
@@ -154,7 +156,7 @@ func runChimp(api *ChimpAPI, params ReportsParams) ([]byte, error) {
 		return nil, fmt.Errorf("readall: %w", err)
 	}
 	if api.Debug {
-		log.Printf("D! [inputs.mailchimp] response Body: %q", string(body))
+		api.Log.Debugf("response Body: %q", string(body))
 	}
 
 	if err = chimpErrorCheck(body); err != nil {
@@ -14,6 +14,8 @@ type MailChimp struct {
 	APIKey     string
 	DaysOld    int
 	CampaignID string
+
+	Log cua.Logger `toml:"-"`
 }
 
 var sampleConfig = `
@@ -37,7 +39,7 @@ func (m *MailChimp) Description() string {
 
 func (m *MailChimp) Gather(acc cua.Accumulator) error {
 	if m.api == nil {
-		m.api = NewChimpAPI(m.APIKey)
+		m.api = NewChimpAPI(m.APIKey, m.Log)
 	}
 	m.api.Debug = false
 
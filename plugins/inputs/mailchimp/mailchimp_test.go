@@ -27,6 +27,7 @@ func TestMailChimpGatherReports(t *testing.T) {
 	api := &ChimpAPI{
 		url:   u,
 		Debug: true,
+		Log:   testutil.Logger{},
 	}
 	m := MailChimp{
 		api: api,
@@ -90,6 +91,7 @@ func TestMailChimpGatherReport(t *testing.T) {
 	api := &ChimpAPI{
 		url:   u,
 		Debug: true,
+		Log:   testutil.Logger{},
 	}
 	m := MailChimp{
 		api:        api,
@@ -155,6 +157,7 @@ func TestMailChimpGatherError(t *testing.T) {
 	api := &ChimpAPI{
 		url:   u,
 		Debug: true,
+		Log:   testutil.Logger{},
 	}
 	m := MailChimp{
 		api:        api,
@@ -45,6 +45,9 @@ func (s *Stats) Gather(acc cua.Accumulator) error {
 		fields["free"] = vm.Free
 		fields["inactive"] = vm.Inactive
 		fields["wired"] = vm.Wired
+	case "aix":
+		fields["free"] = vm.Free
+		fields["wired"] = vm.Wired
 	case "openbsd":
 		fields["active"] = vm.Active
 		fields["cached"] = vm.Cached
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
@@ -101,7 +100,7 @@ func (m *Mesos) Description() string {
 	return "Plugin for gathering metrics from N Mesos masters"
 }
 
-func parseURL(s string, role Role) (*url.URL, error) {
+func (m *Mesos) parseURL(s string, role Role) (*url.URL, error) {
 	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
 		host, port, err := net.SplitHostPort(s)
 		// no port specified
@@ -116,7 +115,7 @@ func parseURL(s string, role Role) (*url.URL, error) {
 		}
 
 		s = "http://" + host + ":" + port
-		log.Printf("W! [inputs.mesos] using %q as connection URL; please update your configuration to use an URL", s)
+		m.Log.Warnf("using %q as connection URL; please update your configuration to use an URL", s)
 	}
 
 	return url.Parse(s)
@@ -140,7 +139,7 @@ func (m *Mesos) initialize() error {
 
 	m.masterURLs = make([]*url.URL, 0, len(m.Masters))
 	for _, master := range m.Masters {
-		u, err := parseURL(master, MASTER)
+		u, err := m.parseURL(master, MASTER)
 		if err != nil {
 			return err
 		}
@@ -151,7 +150,7 @@ func (m *Mesos) initialize() error {
 
 	m.slaveURLs = make([]*url.URL, 0, len(m.Slaves))
 	for _, slave := range m.Slaves {
-		u, err := parseURL(slave, SLAVE)
+		u, err := m.parseURL(slave, SLAVE)
 		if err != nil {
 			return err
 		}
@@ -478,7 +477,6 @@ func getMetrics(role Role, group string) []string {
 	ret, ok := m[group]
 
 	if !ok {
-		log.Printf("I! [inputs.mesos] unknown role %q metrics group: %s", role, group)
 		return []string{}
 	}
 
@@ -513,7 +511,11 @@ func (m *Mesos) filterMetrics(role Role, metrics *map[string]interface{}) {
 
 		// All other metrics have predictable names. We can use getMetrics() to retrieve them.
 		default:
-			for _, v := range getMetrics(role, k) {
+			groupMetrics := getMetrics(role, k)
+			if len(groupMetrics) == 0 {
+				m.Log.Infof("unknown role %q metrics group: %s", role, k)
+			}
+			for _, v := range groupMetrics {
 				if _, ok = (*metrics)[v]; ok {
 					delete((*metrics), v)
 				}
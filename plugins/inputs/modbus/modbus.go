@@ -4,7 +4,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"net"
 	"net/url"
@@ -41,6 +40,8 @@ type Modbus struct {
 	rtuHandler       *mb.RTUClientHandler
 	asciiHandler     *mb.ASCIIClientHandler
 	client           mb.Client
+
+	Log cua.Logger `toml:"-"`
 }
 
 type register struct {
@@ -699,7 +700,7 @@ func (m *Modbus) Gather(acc cua.Accumulator) error {
 			var mberr *mb.ModbusError
 			if errors.As(err, &mberr) {
 				if mberr.ExceptionCode == mb.ExceptionCodeServerDeviceBusy && retry < m.Retries {
-					log.Printf("I! [inputs.modbus] device busy! Retrying %d more time(s)...", m.Retries-retry)
+					m.Log.Infof("device busy! Retrying %d more time(s)...", m.Retries-retry)
 					time.Sleep(m.RetriesWaitTime.Duration)
 					continue
 				}
@@ -96,6 +96,7 @@ func TestCoils(t *testing.T) {
 			modbus := Modbus{
 				Name:       "TestCoils",
 				Controller: "tcp://localhost:1502",
+				Log:        testutil.Logger{},
 				SlaveID:    1,
 				Coils: []fieldContainer{
 					{
@@ -632,6 +633,7 @@ func TestHoldingRegisters(t *testing.T) {
 			modbus := Modbus{
 				Name:       "TestHoldingRegisters",
 				Controller: "tcp://localhost:1502",
+				Log:        testutil.Logger{},
 				SlaveID:    1,
 				HoldingRegisters: []fieldContainer{
 					{
@@ -688,6 +690,7 @@ func TestRetrySuccessful(t *testing.T) {
 		modbus := Modbus{
 			Name:       "TestRetry",
 			Controller: "tcp://localhost:1502",
+			Log:        testutil.Logger{},
 			SlaveID:    1,
 			Retries:    maxretries,
 			Coils: []fieldContainer{
@@ -733,6 +736,7 @@ func TestRetryFail(t *testing.T) {
 		modbus := Modbus{
 			Name:       "TestRetryFail",
 			Controller: "tcp://localhost:1502",
+			Log:        testutil.Logger{},
 			SlaveID:    1,
 			Retries:    maxretries,
 			Coils: []fieldContainer{
@@ -766,6 +770,7 @@ func TestRetryFail(t *testing.T) {
 		modbus := Modbus{
 			Name:       "TestRetryFail",
 			Controller: "tcp://localhost:1502",
+			Log:        testutil.Logger{},
 			SlaveID:    1,
 			Retries:    maxretries,
 			Coils: []fieldContainer{
@@ -0,0 +1,256 @@
+package modem
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Path of the ModemManager command line client.
+  # binary = "mmcli"
+
+  ## Run mmcli with sudo, ModemManager may restrict the access to the
+  ## modems to root.
+  # use_sudo = false
+
+  ## Timeout of each mmcli command.
+  # timeout = "5s"
+
+  ## Refresh rate of the extended signal information (RSSI, RSRP, RSRQ...)
+  ## set up on the modems at the first gather, "0s" to leave the modems
+  ## configuration unchanged.
+  # signal_refresh_rate = "10s"
+`
+
+// modemInfo is the output of mmcli -m <modem> -J.
+type modemInfo struct {
+	Modem struct {
+		ThreeGPP struct {
+			OperatorName      string `json:"operator-name"`
+			RegistrationState string `json:"registration-state"`
+		} `json:"3gpp"`
+		Generic struct {
+			AccessTechnologies []string `json:"access-technologies"`
+			Bearers            []string `json:"bearers"`
+			CurrentBands       []string `json:"current-bands"`
+			Manufacturer       string   `json:"manufacturer"`
+			Model              string   `json:"model"`
+			SignalQuality      struct {
+				Value string `json:"value"`
+			} `json:"signal-quality"`
+			State string `json:"state"`
+		} `json:"generic"`
+	} `json:"modem"`
+}
+
+// signalInfo is the output of mmcli -m <modem> --signal-get -J, the values of
+// the access technologies.
+type signalInfo struct {
+	Modem struct {
+		Signal map[string]map[string]string `json:"signal"`
+	} `json:"modem"`
+}
+
+// bearerInfo is the output of mmcli -b <bearer> -J.
+type bearerInfo struct {
+	Bearer struct {
+		Stats struct {
+			BytesRx  string `json:"bytes-rx"`
+			BytesTx  string `json:"bytes-tx"`
+			Duration string `json:"duration"`
+		} `json:"stats"`
+		Status struct {
+			Connected string `json:"connected"`
+		} `json:"status"`
+	} `json:"bearer"`
+}
+
+type Modem struct {
+	Binary            string            `toml:"binary"`
+	UseSudo           bool              `toml:"use_sudo"`
+	Timeout           internal.Duration `toml:"timeout"`
+	SignalRefreshRate internal.Duration `toml:"signal_refresh_rate"`
+
+	Log cua.Logger `toml:"-"`
+
+	// modems with the extended signal information set up
+	signalSetup map[string]bool
+}
+
+func (m *Modem) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Modem) Description() string {
+	return "Read the signal, registration and data usage of the cellular modems from ModemManager"
+}
+
+func (m *Modem) Init() error {
+	if m.SignalRefreshRate.Duration < 0 {
+		return fmt.Errorf("invalid signal_refresh_rate %s", m.SignalRefreshRate.Duration)
+	}
+	m.signalSetup = make(map[string]bool)
+	return nil
+}
+
+// runCmd runs mmcli and returns its standard output.
+var runCmd = func(timeout internal.Duration, sudo bool, command string, args ...string) ([]byte, error) {
+	cmd := exec.Command(command, args...)
+	if sudo {
+		cmd = exec.Command("sudo", append([]string{"-n", command}, args...)...) //nolint:gosec // G204
+	}
+	return internal.StdOutputTimeout(cmd, timeout.Duration)
+}
+
+// mmcli runs mmcli with the JSON output and decodes it.
+func (m *Modem) mmcli(v interface{}, args ...string) error {
+	out, err := runCmd(m.Timeout, m.UseSudo, m.Binary, append(args, "-J")...)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", m.Binary, strings.Join(args, " "), err)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("%s %s: invalid output: %w", m.Binary, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func (m *Modem) Gather(acc cua.Accumulator) error {
+	var list struct {
+		Modems []string `json:"modem-list"`
+	}
+	if err := m.mmcli(&list, "-L"); err != nil {
+		return err
+	}
+	for _, modem := range list.Modems {
+		if err := m.gatherModem(acc, path.Base(modem)); err != nil {
+			acc.AddError(fmt.Errorf("modem %s: %w", path.Base(modem), err))
+		}
+	}
+	return nil
+}
+
+func (m *Modem) gatherModem(acc cua.Accumulator, modem string) error {
+	var info modemInfo
+	if err := m.mmcli(&info, "-m", modem); err != nil {
+		return err
+	}
+	generic := info.Modem.Generic
+
+	tags := map[string]string{
+		"modem":        modem,
+		"manufacturer": generic.Manufacturer,
+		"model":        generic.Model,
+	}
+	if operator := value(info.Modem.ThreeGPP.OperatorName); operator != "" {
+		tags["operator"] = operator
+	}
+	if len(generic.AccessTechnologies) != 0 {
+		tags["access_technology"] = strings.Join(generic.AccessTechnologies, ",")
+	}
+
+	fields := map[string]interface{}{
+		"state": generic.State,
+	}
+	if state := value(info.Modem.ThreeGPP.RegistrationState); state != "" {
+		fields["registration_state"] = state
+	}
+	if quality, err := strconv.Atoi(generic.SignalQuality.Value); err == nil {
+		fields["signal_quality"] = quality
+	}
+	if len(generic.CurrentBands) != 0 {
+		fields["bands"] = strings.Join(generic.CurrentBands, ",")
+	}
+
+	if err := m.addSignal(modem, fields); err != nil {
+		acc.AddError(fmt.Errorf("modem %s: %w", modem, err))
+	}
+
+	connected := false
+	var rx, tx, duration int64
+	for _, bearer := range generic.Bearers {
+		var b bearerInfo
+		if err := m.mmcli(&b, "-b", path.Base(bearer)); err != nil {
+			acc.AddError(fmt.Errorf("modem %s: %w", modem, err))
+			continue
+		}
+		if b.Bearer.Status.Connected != "yes" {
+			continue
+		}
+		connected = true
+		rx += parseInt(b.Bearer.Stats.BytesRx)
+		tx += parseInt(b.Bearer.Stats.BytesTx)
+		duration += parseInt(b.Bearer.Stats.Duration)
+	}
+	fields["connected"] = connected
+	if connected {
+		fields["bytes_received"] = rx
+		fields["bytes_sent"] = tx
+		fields["connection_duration"] = duration
+	}
+
+	acc.AddFields("modem", fields, tags)
+	return nil
+}
+
+// addSignal adds the extended signal information of the access technologies,
+// as the <technology>_<value> fields such as lte_rsrp.
+func (m *Modem) addSignal(modem string, fields map[string]interface{}) error {
+	if m.SignalRefreshRate.Duration > 0 && !m.signalSetup[modem] {
+		rate := strconv.Itoa(int(m.SignalRefreshRate.Duration / time.Second))
+		if err := m.mmcli(nil, "-m", modem, "--signal-setup="+rate); err != nil {
+			return err
+		}
+		m.signalSetup[modem] = true
+	}
+
+	var signal signalInfo
+	if err := m.mmcli(&signal, "-m", modem, "--signal-get"); err != nil {
+		return err
+	}
+	for technology, values := range signal.Modem.Signal {
+		if technology == "refresh" || technology == "threshold" {
+			continue
+		}
+		for name, v := range values {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				fields[technology+"_"+strings.ReplaceAll(name, "-", "_")] = f
+			}
+		}
+	}
+	return nil
+}
+
+// value returns the value of mmcli, empty for the values not available.
+func value(v string) string {
+	if v == "--" {
+		return ""
+	}
+	return v
+}
+
+func parseInt(v string) int64 {
+	i, _ := strconv.ParseInt(v, 10, 64)
+	return i
+}
+
+func init() {
+	inputs.Add("modem", func() cua.Input {
+		return &Modem{
+			Binary:            "mmcli",
+			Timeout:           internal.Duration{Duration: 5 * time.Second},
+			SignalRefreshRate: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
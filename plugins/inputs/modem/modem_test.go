@@ -0,0 +1,87 @@
+package modem
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var outputs = map[string]string{
+	"-L -J": `{"modem-list":["/org/freedesktop/ModemManager1/Modem/0"]}`,
+	"-m 0 -J": `{"modem":{"3gpp":{"imei":"867698040000000","operator-code":"310260",` +
+		`"operator-name":"T-Mobile","registration-state":"home"},"generic":{"access-technologies":["lte"],` +
+		`"bearers":["/org/freedesktop/ModemManager1/Bearer/0","/org/freedesktop/ModemManager1/Bearer/1"],` +
+		`"current-bands":["utran-2","eutran-2","eutran-4"],"manufacturer":"QUALCOMM INCORPORATED",` +
+		`"model":"QUECTEL Mobile Broadband Module","signal-quality":{"recent":"yes","value":"67"},` +
+		`"state":"connected"}}}`,
+	"-m 0 --signal-setup=10 -J": ``,
+	"-m 0 --signal-get -J": `{"modem":{"signal":{"5g":{"error-rate":"--","rsrp":"--","rsrq":"--","snr":"--"},` +
+		`"gsm":{"error-rate":"--","rssi":"--"},"lte":{"error-rate":"--","rsrp":"-95.00","rsrq":"-11.00",` +
+		`"rssi":"-65.00","snr":"8.00"},"refresh":{"rate":"10"},"threshold":{"error-rate":"no","rssi":"0"}}}}`,
+	"-b 0 -J": `{"bearer":{"stats":{"attempts":"1","bytes-rx":"123456","bytes-tx":"7890","duration":"3600"},` +
+		`"status":{"connected":"yes","interface":"wwan0"}}}`,
+	"-b 1 -J": `{"bearer":{"stats":{"bytes-rx":"--","bytes-tx":"--","duration":"--"},"status":{"connected":"no"}}}`,
+}
+
+func TestGather(t *testing.T) {
+	var commands []string
+	runCmd = func(timeout internal.Duration, sudo bool, command string, args ...string) ([]byte, error) {
+		cmd := strings.Join(args, " ")
+		commands = append(commands, cmd)
+		out, ok := outputs[cmd]
+		if !ok {
+			return nil, errors.New("unexpected command")
+		}
+		return []byte(out), nil
+	}
+
+	m := &Modem{
+		Binary:            "mmcli",
+		Timeout:           internal.Duration{Duration: 5 * time.Second},
+		SignalRefreshRate: internal.Duration{Duration: 10 * time.Second},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, m.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, m.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("modem",
+			map[string]string{
+				"modem":             "0",
+				"manufacturer":      "QUALCOMM INCORPORATED",
+				"model":             "QUECTEL Mobile Broadband Module",
+				"operator":          "T-Mobile",
+				"access_technology": "lte",
+			},
+			map[string]interface{}{
+				"state":               "connected",
+				"registration_state":  "home",
+				"signal_quality":      67,
+				"bands":               "utran-2,eutran-2,eutran-4",
+				"lte_rsrp":            -95.0,
+				"lte_rsrq":            -11.0,
+				"lte_rssi":            -65.0,
+				"lte_snr":             8.0,
+				"connected":           true,
+				"bytes_received":      int64(123456),
+				"bytes_sent":          int64(7890),
+				"connection_duration": int64(3600),
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+
+	// the signal is only set up once
+	commands = nil
+	require.NoError(t, m.Gather(&testutil.Accumulator{}))
+	require.NotContains(t, commands, "-m 0 --signal-setup=10 -J")
+}
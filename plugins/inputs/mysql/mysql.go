@@ -23,6 +23,9 @@ type Mysql struct {
 	PerfEventsStatementsDigestTextLimit int64    `toml:"perf_events_statements_digest_text_limit"`
 	PerfEventsStatementsLimit           int64    `toml:"perf_events_statements_limit"`
 	PerfEventsStatementsTimeLimit       int64    `toml:"perf_events_statements_time_limit"`
+	PerfEventsStatementsQuantiles       bool     `toml:"perf_events_statements_quantiles"`
+	SlowQueryLog                        string   `toml:"slow_query_log"`
+	SlowQueryLogLimit                   int      `toml:"slow_query_log_limit"`
 	TableSchemaDatabases                []string `toml:"table_schema_databases"`
 	GatherProcessList                   bool     `toml:"gather_process_list"`
 	GatherUserStatistics                bool     `toml:"gather_user_statistics"`
@@ -46,6 +49,7 @@ type Mysql struct {
 	lastT            time.Time
 	initDone         bool
 	scanIntervalSlow uint32
+	slowLog          *slowLog
 }
 
 const sampleConfig = `
@@ -120,6 +124,16 @@ const sampleConfig = `
   # perf_events_statements_limit = 250
   # perf_events_statements_time_limit = 86400
 
+  ## add the 95th, 99th and 99.9th percentiles and the maximum of the
+  ## statement latency to perf_events_statements, requires MySQL 8.0.3+
+  # perf_events_statements_quantiles = false
+
+  ## path of the slow query log of a local server, the queries logged
+  ## between gathers are grouped by fingerprint and the slow_query_log_limit
+  ## digests with the highest total query time are reported
+  # slow_query_log = "/var/log/mysql/mysql-slow.log"
+  # slow_query_log_limit = 100
+
   ## Some queries we may want to run less often (such as SHOW GLOBAL VARIABLES)
   ##   example: interval_slow = "30m"
   # interval_slow = ""
@@ -161,6 +175,13 @@ func (m *Mysql) InitMysql() {
 }
 
 func (m *Mysql) Gather(acc cua.Accumulator) error {
+	if m.SlowQueryLog != "" {
+		if m.slowLog == nil {
+			m.slowLog = newSlowLog(m.SlowQueryLog, m.SlowQueryLogLimit, int(m.PerfEventsStatementsDigestTextLimit))
+		}
+		acc.AddError(m.slowLog.gather(acc))
+	}
+
 	if len(m.Servers) == 0 {
 		// default to localhost if nothing specified.
 		return m.gatherServer(localhost, acc)
@@ -368,13 +389,18 @@ const (
             SUM_CREATED_TMP_TABLES,
             SUM_SORT_MERGE_PASSES,
             SUM_SORT_ROWS,
-            SUM_NO_INDEX_USED
+            SUM_NO_INDEX_USED%s
         FROM performance_schema.events_statements_summary_by_digest
         WHERE SCHEMA_NAME NOT IN ('mysql', 'performance_schema', 'information_schema')
             AND last_seen > DATE_SUB(NOW(), INTERVAL %d SECOND)
         ORDER BY SUM_TIMER_WAIT DESC
         LIMIT %d
     `
+	perfEventsStatementsQuantiles = `,
+            QUANTILE_95,
+            QUANTILE_99,
+            QUANTILE_999,
+            MAX_TIMER_WAIT`
 	perfEventWaitsQuery = `
         SELECT EVENT_NAME, COUNT_STAR, SUM_TIMER_WAIT
         FROM performance_schema.events_waits_summary_global_by_event_name
@@ -1484,9 +1510,14 @@ func (m *Mysql) gatherPerfFileEventsStatuses(db *sql.DB, serv string, acc cua.Ac
 
 // gatherPerfEventsStatements can be used to get attributes of each event
 func (m *Mysql) gatherPerfEventsStatements(db *sql.DB, serv string, acc cua.Accumulator) error {
+	quantiles := ""
+	if m.PerfEventsStatementsQuantiles {
+		quantiles = perfEventsStatementsQuantiles
+	}
 	query := fmt.Sprintf(
 		perfEventsStatementsQuery,
 		m.PerfEventsStatementsDigestTextLimit,
+		quantiles,
 		m.PerfEventsStatementsTimeLimit,
 		m.PerfEventsStatementsLimit,
 	)
@@ -1505,6 +1536,7 @@ func (m *Mysql) gatherPerfEventsStatements(db *sql.DB, serv string, acc cua.Accu
 		tmpTables, tmpDiskTables             float64
 		sortMergePasses, sortRows            float64
 		noIndexUsed                          float64
+		p95, p99, p999, maxTime              float64
 	)
 
 	servtag := getDSNTag(serv)
@@ -1513,14 +1545,18 @@ func (m *Mysql) gatherPerfEventsStatements(db *sql.DB, serv string, acc cua.Accu
 	}
 
 	for rows.Next() {
-		err = rows.Scan(
+		dest := []interface{}{
 			&schemaName, &digest, &digestText,
 			&count, &queryTime, &errors, &warnings,
 			&rowsAffected, &rowsSent, &rowsExamined,
 			&tmpTables, &tmpDiskTables,
 			&sortMergePasses, &sortRows,
 			&noIndexUsed,
-		)
+		}
+		if m.PerfEventsStatementsQuantiles {
+			dest = append(dest, &p95, &p99, &p999, &maxTime)
+		}
+		err = rows.Scan(dest...)
 
 		if err != nil {
 			return fmt.Errorf("row scan (event stmnts): %w", err)
@@ -1543,6 +1579,12 @@ func (m *Mysql) gatherPerfEventsStatements(db *sql.DB, serv string, acc cua.Accu
 			"events_statements_sort_rows_total":         sortRows,
 			"events_statements_no_index_used_total":     noIndexUsed,
 		}
+		if m.PerfEventsStatementsQuantiles {
+			fields["events_statements_seconds_p95"] = p95 / picoSeconds
+			fields["events_statements_seconds_p99"] = p99 / picoSeconds
+			fields["events_statements_seconds_p999"] = p999 / picoSeconds
+			fields["events_statements_seconds_max"] = maxTime / picoSeconds
+		}
 
 		acc.AddFields("mysql_perf_schema", fields, tags)
 	}
@@ -1771,6 +1813,7 @@ func init() {
 			PerfEventsStatementsDigestTextLimit: defaultPerfEventsStatementsDigestTextLimit,
 			PerfEventsStatementsLimit:           defaultPerfEventsStatementsLimit,
 			PerfEventsStatementsTimeLimit:       defaultPerfEventsStatementsTimeLimit,
+			SlowQueryLogLimit:                   defaultSlowQueryLogLimit,
 			GatherGlobalVars:                    defaultGatherGlobalVars,
 		}
 	})
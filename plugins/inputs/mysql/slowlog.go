@@ -0,0 +1,299 @@
+package mysql
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+)
+
+const (
+	defaultSlowQueryLogLimit = 100
+
+	// slowLogMaxSamples caps the query times kept for the percentiles of
+	// a digest in an interval, the samples are then chosen at random.
+	slowLogMaxSamples = 1000
+	// slowLogMaxRead caps the bytes of the log read in one gather.
+	slowLogMaxRead = 64 * 1024 * 1024
+)
+
+var (
+	fingerprintComment = regexp.MustCompile(`(?s)/\*.*?\*/|(?m)(-- |#)[^\n]*$`)
+	fingerprintString  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+	fingerprintNumber  = regexp.MustCompile(`\b0x[0-9a-f]+\b|[-+]?\b\d+(\.\d+)?(e[-+]?\d+)?\b`)
+	fingerprintSpace   = regexp.MustCompile(`\s+`)
+	fingerprintIn      = regexp.MustCompile(`\bin ?\((\? ?, ?)*\? ?\)`)
+	fingerprintValues  = regexp.MustCompile(`\bvalues ?\([^)]*\)( ?, ?\([^)]*\))*`)
+)
+
+// fingerprint normalizes a query: the literals are replaced by "?", the
+// comments are removed, the lists of values are collapsed and the query is
+// lowercased.
+func fingerprint(query string) string {
+	q := fingerprintString.ReplaceAllString(query, "?")
+	q = fingerprintComment.ReplaceAllString(q, "")
+	q = strings.ToLower(q)
+	q = fingerprintNumber.ReplaceAllString(q, "?")
+	q = strings.TrimSpace(fingerprintSpace.ReplaceAllString(q, " "))
+	q = strings.TrimSpace(strings.TrimRight(q, ";"))
+	q = fingerprintIn.ReplaceAllString(q, "in(?+)")
+	q = fingerprintValues.ReplaceAllString(q, "values(?+)")
+	return q
+}
+
+// fingerprintHash returns the hash of a fingerprint used as the digest tag.
+func fingerprintHash(fp string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(fp))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// slowEntry is a query of the slow query log.
+type slowEntry struct {
+	schema       string
+	queryTime    float64
+	lockTime     float64
+	rowsSent     float64
+	rowsExamined float64
+	timed        bool
+	query        []string
+}
+
+// slowDigest aggregates the queries of a fingerprint over an interval.
+type slowDigest struct {
+	schema       string
+	hash         string
+	text         string
+	count        int64
+	queryTime    float64
+	maxTime      float64
+	lockTime     float64
+	rowsSent     float64
+	rowsExamined float64
+	samples      []float64
+}
+
+func (d *slowDigest) add(e *slowEntry) {
+	d.count++
+	d.queryTime += e.queryTime
+	d.maxTime = math.Max(d.maxTime, e.queryTime)
+	d.lockTime += e.lockTime
+	d.rowsSent += e.rowsSent
+	d.rowsExamined += e.rowsExamined
+	if len(d.samples) < slowLogMaxSamples {
+		d.samples = append(d.samples, e.queryTime)
+	} else if i := rand.Int63n(d.count); i < slowLogMaxSamples { //nolint:gosec // sampling only
+		d.samples[i] = e.queryTime
+	}
+}
+
+// percentile returns the nearest-rank percentile of the sorted samples.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// slowLog reads the queries appended to the slow query log since the
+// previous gather.
+type slowLog struct {
+	path      string
+	limit     int
+	textLimit int
+
+	info    os.FileInfo
+	offset  int64
+	entry   *slowEntry
+	digests map[string]*slowDigest
+}
+
+func newSlowLog(path string, limit, textLimit int) *slowLog {
+	return &slowLog{
+		path:      path,
+		limit:     limit,
+		textLimit: textLimit,
+		digests:   make(map[string]*slowDigest),
+	}
+}
+
+// read parses the lines appended to the log, the log is read from its end
+// when first opened and from its start when it has been rotated.
+func (l *slowLog) read() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("opening slow query log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat slow query log: %w", err)
+	}
+	switch {
+	case l.info == nil:
+		l.offset = info.Size()
+	case !os.SameFile(l.info, info) || info.Size() < l.offset:
+		l.offset = 0
+		l.entry = nil
+	}
+	l.info = info
+	if info.Size() == l.offset {
+		return nil
+	}
+
+	size := info.Size() - l.offset
+	if size > slowLogMaxRead {
+		size = slowLogMaxRead
+	}
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, l.offset)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("reading slow query log: %w", err)
+	}
+	buf = buf[:n]
+
+	// the last line is parsed once complete
+	end := bytes.LastIndexByte(buf, '\n')
+	if end < 0 {
+		return nil
+	}
+	l.offset += int64(end + 1)
+	for _, line := range strings.Split(string(buf[:end]), "\n") {
+		l.parseLine(strings.TrimRight(line, "\r"))
+	}
+	return nil
+}
+
+func (l *slowLog) parseLine(line string) {
+	if strings.HasPrefix(line, "# ") {
+		// a header after a query starts the next entry
+		if l.entry == nil || len(l.entry.query) > 0 || strings.HasPrefix(line, "# Time:") {
+			l.entry = &slowEntry{}
+		}
+		if strings.HasPrefix(line, "# administrator command:") {
+			l.entry = nil
+			return
+		}
+		l.entry.parseHeader(line[2:])
+		return
+	}
+	if l.entry == nil || !l.entry.timed {
+		// the server banner and the lines of unknown entries
+		return
+	}
+
+	trimmed := strings.TrimSpace(line)
+	lower := strings.ToLower(trimmed)
+	switch {
+	case trimmed == "":
+		return
+	case len(l.entry.query) == 0 && strings.HasPrefix(lower, "use "):
+		l.entry.schema = strings.Trim(strings.TrimSuffix(trimmed[4:], ";"), " `")
+		return
+	case len(l.entry.query) == 0 && strings.HasPrefix(lower, "set timestamp="):
+		return
+	}
+	l.entry.query = append(l.entry.query, line)
+	if strings.HasSuffix(trimmed, ";") {
+		l.add(l.entry)
+		l.entry = nil
+	}
+}
+
+// parseHeader reads the "Name: value" pairs of a header line.
+func (e *slowEntry) parseHeader(header string) {
+	words := strings.Fields(header)
+	for i := 0; i+1 < len(words); i++ {
+		if !strings.HasSuffix(words[i], ":") {
+			continue
+		}
+		value := words[i+1]
+		switch strings.TrimSuffix(words[i], ":") {
+		case "Schema":
+			e.schema = value
+		case "Query_time":
+			e.queryTime, _ = strconv.ParseFloat(value, 64)
+			e.timed = true
+		case "Lock_time":
+			e.lockTime, _ = strconv.ParseFloat(value, 64)
+		case "Rows_sent":
+			e.rowsSent, _ = strconv.ParseFloat(value, 64)
+		case "Rows_examined":
+			e.rowsExamined, _ = strconv.ParseFloat(value, 64)
+		}
+		i++
+	}
+}
+
+func (l *slowLog) add(e *slowEntry) {
+	fp := fingerprint(strings.Join(e.query, "\n"))
+	schema := e.schema
+	if schema == "" {
+		schema = "NONE"
+	}
+	key := schema + "\x00" + fp
+	d, ok := l.digests[key]
+	if !ok {
+		text := fp
+		if l.textLimit > 0 && len(text) > l.textLimit {
+			text = text[:l.textLimit]
+		}
+		d = &slowDigest{schema: schema, hash: fingerprintHash(fp), text: text}
+		l.digests[key] = d
+	}
+	d.add(e)
+}
+
+// gather reads the log and emits the digests of the interval with the
+// highest total query time.
+func (l *slowLog) gather(acc cua.Accumulator) error {
+	err := l.read()
+
+	digests := make([]*slowDigest, 0, len(l.digests))
+	for _, d := range l.digests {
+		digests = append(digests, d)
+	}
+	l.digests = make(map[string]*slowDigest)
+	sort.Slice(digests, func(i, j int) bool {
+		return digests[i].queryTime > digests[j].queryTime
+	})
+	if l.limit > 0 && len(digests) > l.limit {
+		digests = digests[:l.limit]
+	}
+
+	for _, d := range digests {
+		sort.Float64s(d.samples)
+		tags := map[string]string{
+			"schema":      d.schema,
+			"digest":      d.hash,
+			"digest_text": d.text,
+		}
+		fields := map[string]interface{}{
+			"count":                    d.count,
+			"query_time_seconds_total": d.queryTime,
+			"query_time_seconds_max":   d.maxTime,
+			"query_time_seconds_p50":   percentile(d.samples, 0.50),
+			"query_time_seconds_p95":   percentile(d.samples, 0.95),
+			"query_time_seconds_p99":   percentile(d.samples, 0.99),
+			"lock_time_seconds_total":  d.lockTime,
+			"rows_sent_total":          d.rowsSent,
+			"rows_examined_total":      d.rowsExamined,
+		}
+		acc.AddFields("mysql_slow_query", fields, tags)
+	}
+	return err
+}
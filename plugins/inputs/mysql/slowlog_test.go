@@ -0,0 +1,135 @@
+package mysql
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const slowLogBanner = `/usr/sbin/mysqld, Version: 8.0.23 (MySQL Community Server - GPL). started with:
+Tcp port: 3306  Unix socket: /var/run/mysqld/mysqld.sock
+Time                 Id Command    Argument
+`
+
+func slowLogEntry(queryTime float64, query string) string {
+	return fmt.Sprintf(`# Time: 2021-03-01T10:00:00.123456Z
+# User@Host: app[app] @ localhost []  Id:    12
+# Query_time: %f  Lock_time: 0.500000 Rows_sent: 1  Rows_examined: 1000
+use shop;
+SET timestamp=1614592800;
+%s
+`, queryTime, query)
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "SELECT * FROM orders WHERE id = 42;",
+			expected: "select * from orders where id = ?",
+		},
+		{
+			query:    "select  *\n from t1 where name='it''s' and v = -1.5e3 /* hint */",
+			expected: "select * from t1 where name=? and v = ?",
+		},
+		{
+			query:    "SELECT a FROM t WHERE id IN (1, 2, 3) -- trailing",
+			expected: "select a from t where id in(?+)",
+		},
+		{
+			query:    "INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y');",
+			expected: "insert into t (a, b) values(?+)",
+		},
+		{
+			query:    "SELECT 0xFF, \"str\"",
+			expected: "select ?, ?",
+		},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, fingerprint(tt.query))
+	}
+	require.Len(t, fingerprintHash("select ?"), 16)
+}
+
+func TestSlowLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysql-slow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "slow.log")
+
+	// the entries logged before the first gather are skipped
+	require.NoError(t, ioutil.WriteFile(path, []byte(slowLogBanner+slowLogEntry(9, "SELECT 1;")), 0600))
+
+	l := newSlowLog(path, 2, 35)
+	var acc testutil.Accumulator
+	require.NoError(t, l.gather(&acc))
+	require.Empty(t, acc.Metrics)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	defer f.Close()
+	for i := 1; i <= 10; i++ {
+		_, err = f.WriteString(slowLogEntry(float64(i), fmt.Sprintf("SELECT * FROM orders WHERE id = %d;", i)))
+		require.NoError(t, err)
+	}
+	_, err = f.WriteString(slowLogEntry(0.5, "UPDATE orders\n  SET state = 'done'\n  WHERE id = 7;"))
+	require.NoError(t, err)
+	_, err = f.WriteString(slowLogEntry(0.1, "DELETE FROM carts WHERE id = 1;"))
+	require.NoError(t, err)
+	_, err = f.WriteString("# Time: 2021-03-01T10:00:00.123456Z\n# User@Host: app[app] @ localhost []\n# Query_time: 3.0  Lock_time: 0.1")
+	require.NoError(t, err)
+
+	acc.ClearMetrics()
+	require.NoError(t, l.gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+
+	tags := map[string]string{
+		"schema":      "shop",
+		"digest":      fingerprintHash("select * from orders where id = ?"),
+		"digest_text": "select * from orders where id = ?",
+	}
+	acc.AssertContainsTaggedFields(t, "mysql_slow_query", map[string]interface{}{
+		"count":                    int64(10),
+		"query_time_seconds_total": 55.0,
+		"query_time_seconds_max":   10.0,
+		"query_time_seconds_p50":   5.0,
+		"query_time_seconds_p95":   10.0,
+		"query_time_seconds_p99":   10.0,
+		"lock_time_seconds_total":  5.0,
+		"rows_sent_total":          10.0,
+		"rows_examined_total":      10000.0,
+	}, tags)
+	require.Equal(t, "update orders set state = ? where i", acc.Metrics[1].Tags["digest_text"])
+
+	// the partial entry is completed and the interval is reset
+	_, err = f.WriteString(" Rows_sent: 1  Rows_examined: 1\nSELECT 2;\n")
+	require.NoError(t, err)
+	acc.ClearMetrics()
+	require.NoError(t, l.gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, int64(1), acc.Metrics[0].Fields["count"])
+	require.Equal(t, 3.0, acc.Metrics[0].Fields["query_time_seconds_total"])
+	require.Equal(t, "NONE", acc.Metrics[0].Tags["schema"])
+
+	// a rotated log is read from its start
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, ioutil.WriteFile(path, []byte(slowLogBanner+slowLogEntry(2, "SELECT 3;")), 0600))
+	acc.ClearMetrics()
+	require.NoError(t, l.gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "shop", acc.Metrics[0].Tags["schema"])
+}
+
+func TestPercentile(t *testing.T) {
+	require.Zero(t, percentile(nil, 0.5))
+	require.Equal(t, 1.0, percentile([]float64{1}, 0.99))
+	require.Equal(t, 2.0, percentile([]float64{1, 2, 3, 4}, 0.5))
+	require.Equal(t, 4.0, percentile([]float64{1, 2, 3, 4}, 0.95))
+}
@@ -38,7 +38,7 @@ type natsConsumer struct {
 	Secure      bool     `toml:"secure"`
 	Username    string   `toml:"username"`
 	Password    string   `toml:"password"`
-	Credentials string   `toml:"credentials"`
+	Credentials string   `toml:"credentials" secret:"true"`
 
 	tls.ClientConfig
 
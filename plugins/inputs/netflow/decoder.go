@@ -0,0 +1,480 @@
+package netflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs/sflow"
+)
+
+const (
+	versionV5    = "netflow v5"
+	versionV9    = "netflow v9"
+	versionIPFIX = "ipfix"
+	versionSFlow = "sflow v5"
+
+	// variableLength is the length of the IPFIX fields of variable length
+	variableLength = 0xffff
+)
+
+// flow is a decoded flow record, with the sampling rate of its exporter,
+// zero when unknown.
+type flow struct {
+	tags   map[string]string
+	fields map[string]interface{}
+	rate   uint64
+	// start and end of the flow in milliseconds, since the boot of the
+	// exporter or the epoch
+	start, end uint64
+}
+
+func newFlow(exporter, version string) flow {
+	return flow{
+		tags:   map[string]string{"source": exporter, "version": version},
+		fields: make(map[string]interface{}),
+	}
+}
+
+// templateKey identifies a template: the template IDs are scoped by the
+// exporter and the source ID of NetFlow v9 or the observation domain of
+// IPFIX.
+type templateKey struct {
+	exporter string
+	domain   uint32
+	id       uint16
+}
+
+// samplerKey identifies the sampler of an exporter, zero for the sampling
+// rate of the whole exporter.
+type samplerKey struct {
+	exporter string
+	domain   uint32
+	sampler  uint64
+}
+
+type templateField struct {
+	id         uint16
+	enterprise uint32
+	length     uint16
+}
+
+type template struct {
+	fields []templateField
+	// options templates describe options records, such as the sampling
+	// rate of the exporter, rather than flows
+	options bool
+}
+
+// minLength returns the minimum length of a record, the fields of variable
+// length being at least one byte.
+func (t *template) minLength() int {
+	n := 0
+	for _, f := range t.fields {
+		if f.length == variableLength {
+			n++
+		} else {
+			n += int(f.length)
+		}
+	}
+	return n
+}
+
+// decoder decodes the datagrams. The templates of NetFlow v9 and IPFIX, and
+// the sampling rates of their options records, are cached across datagrams
+// so the decoder must not be used concurrently.
+type decoder struct {
+	templates map[templateKey]*template
+	rates     map[samplerKey]uint64
+	sflow     *sflow.PacketDecoder
+	log       cua.Logger
+}
+
+func newDecoder(log cua.Logger) *decoder {
+	d := &decoder{
+		templates: make(map[templateKey]*template),
+		rates:     make(map[samplerKey]uint64),
+		sflow:     sflow.NewDecoder(),
+		log:       log,
+	}
+	d.sflow.Log = log
+	return d
+}
+
+// decode returns the flows of a datagram, told apart by its version.
+func (d *decoder) decode(exporter string, buf []byte) ([]flow, error) {
+	if len(buf) < 4 {
+		return nil, fmt.Errorf("datagram too short (%d bytes)", len(buf))
+	}
+	switch version := binary.BigEndian.Uint16(buf); version {
+	case 5:
+		return d.decodeV5(exporter, buf)
+	case 9:
+		return d.decodeV9(exporter, buf)
+	case 10:
+		return d.decodeIPFIX(exporter, buf)
+	case 0:
+		// the version of sFlow is a 32 bits integer
+		if binary.BigEndian.Uint32(buf) == 5 {
+			return d.decodeSFlow(exporter, buf)
+		}
+		return nil, fmt.Errorf("unsupported version %d", binary.BigEndian.Uint32(buf))
+	default:
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+}
+
+// decodeV5 decodes a NetFlow v5 datagram, made of fixed flow records.
+// https://www.cisco.com/c/en/us/td/docs/net_mgmt/netflow_collection_engine/3-6/user/guide/format.html
+func (d *decoder) decodeV5(exporter string, buf []byte) ([]flow, error) {
+	const headerLength, recordLength = 24, 48
+	if len(buf) < headerLength {
+		return nil, fmt.Errorf("netflow v5 header too short (%d bytes)", len(buf))
+	}
+	count := int(binary.BigEndian.Uint16(buf[2:]))
+	// the two most significant bits are the sampling mode
+	rate := uint64(binary.BigEndian.Uint16(buf[22:]) & 0x3fff)
+	if len(buf) < headerLength+count*recordLength {
+		return nil, fmt.Errorf("netflow v5 datagram truncated, %d bytes for %d records", len(buf), count)
+	}
+
+	flows := make([]flow, 0, count)
+	for i := 0; i < count; i++ {
+		r := buf[headerLength+i*recordLength:]
+		f := newFlow(exporter, versionV5)
+		f.rate = rate
+		f.tags["src"] = formatIP(r[0:4])
+		f.tags["dst"] = formatIP(r[4:8])
+		f.tags["src_port"] = formatUint(r[32:34])
+		f.tags["dst_port"] = formatUint(r[34:36])
+		f.tags["protocol"] = protocolName(r[38])
+		f.fields["next_hop"] = formatIP(r[8:12])
+		f.fields["in_if"] = decodeUint(r[12:14])
+		f.fields["out_if"] = decodeUint(r[14:16])
+		f.fields["packets"] = decodeUint(r[16:20])
+		f.fields["bytes"] = decodeUint(r[20:24])
+		f.fields["duration_ms"] = uint64(binary.BigEndian.Uint32(r[28:]) - binary.BigEndian.Uint32(r[24:]))
+		f.fields["tcp_flags"] = uint64(r[37])
+		f.fields["tos"] = uint64(r[39])
+		f.fields["src_as"] = decodeUint(r[40:42])
+		f.fields["dst_as"] = decodeUint(r[42:44])
+		f.fields["src_mask"] = uint64(r[44])
+		f.fields["dst_mask"] = uint64(r[45])
+		flows = append(flows, f)
+	}
+	return flows, nil
+}
+
+// decodeV9 decodes a NetFlow v9 datagram, made of template, options
+// template and data flowsets.
+// https://www.ietf.org/rfc/rfc3954.txt
+func (d *decoder) decodeV9(exporter string, buf []byte) ([]flow, error) {
+	const headerLength = 20
+	if len(buf) < headerLength {
+		return nil, fmt.Errorf("netflow v9 header too short (%d bytes)", len(buf))
+	}
+	domain := binary.BigEndian.Uint32(buf[16:])
+	return d.decodeSets(exporter, versionV9, domain, buf[headerLength:])
+}
+
+// decodeIPFIX decodes an IPFIX message, made of template, options template
+// and data sets.
+// https://www.ietf.org/rfc/rfc7011.txt
+func (d *decoder) decodeIPFIX(exporter string, buf []byte) ([]flow, error) {
+	const headerLength = 16
+	if len(buf) < headerLength {
+		return nil, fmt.Errorf("ipfix header too short (%d bytes)", len(buf))
+	}
+	length := int(binary.BigEndian.Uint16(buf[2:]))
+	if length < headerLength || length > len(buf) {
+		return nil, fmt.Errorf("invalid ipfix message length %d for %d bytes", length, len(buf))
+	}
+	domain := binary.BigEndian.Uint32(buf[12:])
+	return d.decodeSets(exporter, versionIPFIX, domain, buf[headerLength:length])
+}
+
+// decodeSets decodes the sets of NetFlow v9 and IPFIX, which only differ
+// by the IDs of the template sets and the encoding of the templates.
+func (d *decoder) decodeSets(exporter, version string, domain uint32, buf []byte) ([]flow, error) {
+	var flows []flow
+	for len(buf) >= 4 {
+		id := binary.BigEndian.Uint16(buf)
+		length := int(binary.BigEndian.Uint16(buf[2:]))
+		if length < 4 || length > len(buf) {
+			return flows, fmt.Errorf("invalid %s set length %d for %d bytes", version, length, len(buf))
+		}
+		body := buf[4:length]
+		buf = buf[length:]
+
+		var err error
+		switch {
+		case version == versionV9 && id == 0:
+			err = d.decodeTemplates(exporter, domain, body, false)
+		case version == versionV9 && id == 1:
+			err = d.decodeV9OptionsTemplates(exporter, domain, body)
+		case version == versionIPFIX && id == 2:
+			err = d.decodeTemplates(exporter, domain, body, true)
+		case version == versionIPFIX && id == 3:
+			err = d.decodeIPFIXOptionsTemplates(exporter, domain, body)
+		case id >= 256:
+			var f []flow
+			f, err = d.decodeData(exporter, version, domain, id, body)
+			flows = append(flows, f...)
+		default:
+			d.log.Debugf("Ignoring %s set %d from %s", version, id, exporter)
+		}
+		if err != nil {
+			return flows, err
+		}
+	}
+	return flows, nil
+}
+
+// decodeTemplates decodes the templates of a template set.
+func (d *decoder) decodeTemplates(exporter string, domain uint32, buf []byte, ipfix bool) error {
+	// the set may be padded with zeros
+	for len(buf) >= 4 {
+		id := binary.BigEndian.Uint16(buf)
+		count := int(binary.BigEndian.Uint16(buf[2:]))
+		buf = buf[4:]
+		if id == 0 {
+			break
+		}
+		key := templateKey{exporter: exporter, domain: domain, id: id}
+		if count == 0 {
+			// an IPFIX template withdrawal
+			delete(d.templates, key)
+			continue
+		}
+		fields, rest, err := decodeTemplateFields(buf, count, ipfix)
+		if err != nil {
+			return fmt.Errorf("template %d: %w", id, err)
+		}
+		buf = rest
+		d.templates[key] = &template{fields: fields}
+	}
+	return nil
+}
+
+// decodeV9OptionsTemplates decodes the options templates of a NetFlow v9
+// options template flowset, whose scope and option lengths are in bytes.
+func (d *decoder) decodeV9OptionsTemplates(exporter string, domain uint32, buf []byte) error {
+	for len(buf) >= 6 {
+		id := binary.BigEndian.Uint16(buf)
+		scopeLength := int(binary.BigEndian.Uint16(buf[2:]))
+		optionLength := int(binary.BigEndian.Uint16(buf[4:]))
+		buf = buf[6:]
+		if id == 0 {
+			break
+		}
+		fields, rest, err := decodeTemplateFields(buf, (scopeLength+optionLength)/4, false)
+		if err != nil {
+			return fmt.Errorf("options template %d: %w", id, err)
+		}
+		buf = rest
+		// the scope field types overlap the option field types, the scopes
+		// are ignored not to mistake them for options
+		for i := 0; i < scopeLength/4; i++ {
+			fields[i].id = 0
+		}
+		d.templates[templateKey{exporter: exporter, domain: domain, id: id}] = &template{fields: fields, options: true}
+	}
+	return nil
+}
+
+// decodeIPFIXOptionsTemplates decodes the options templates of an IPFIX
+// options template set, whose scope fields are information elements like
+// the options.
+func (d *decoder) decodeIPFIXOptionsTemplates(exporter string, domain uint32, buf []byte) error {
+	for len(buf) >= 6 {
+		id := binary.BigEndian.Uint16(buf)
+		count := int(binary.BigEndian.Uint16(buf[2:]))
+		buf = buf[6:]
+		if id == 0 {
+			break
+		}
+		key := templateKey{exporter: exporter, domain: domain, id: id}
+		if count == 0 {
+			delete(d.templates, key)
+			continue
+		}
+		fields, rest, err := decodeTemplateFields(buf, count, true)
+		if err != nil {
+			return fmt.Errorf("options template %d: %w", id, err)
+		}
+		buf = rest
+		d.templates[key] = &template{fields: fields, options: true}
+	}
+	return nil
+}
+
+// decodeTemplateFields decodes count field specifiers, with an enterprise
+// number for the enterprise specific information elements of IPFIX.
+func decodeTemplateFields(buf []byte, count int, ipfix bool) ([]templateField, []byte, error) {
+	fields := make([]templateField, 0, count)
+	for i := 0; i < count; i++ {
+		if len(buf) < 4 {
+			return nil, nil, fmt.Errorf("truncated after %d of %d fields", i, count)
+		}
+		f := templateField{
+			id:     binary.BigEndian.Uint16(buf),
+			length: binary.BigEndian.Uint16(buf[2:]),
+		}
+		buf = buf[4:]
+		if ipfix && f.id&0x8000 != 0 {
+			if len(buf) < 4 {
+				return nil, nil, fmt.Errorf("truncated enterprise number of field %d", i)
+			}
+			f.id &= 0x7fff
+			f.enterprise = binary.BigEndian.Uint32(buf)
+			buf = buf[4:]
+		}
+		fields = append(fields, f)
+	}
+	return fields, buf, nil
+}
+
+// decodeData decodes the records of a data set, the flows being returned
+// and the options records updating the sampling rates.
+func (d *decoder) decodeData(exporter, version string, domain uint32, id uint16, buf []byte) ([]flow, error) {
+	t, ok := d.templates[templateKey{exporter: exporter, domain: domain, id: id}]
+	if !ok {
+		d.log.Debugf("Dropping %s data set from %s with unknown template %d", version, exporter, id)
+		return nil, nil
+	}
+	minLength := t.minLength()
+	if minLength == 0 {
+		return nil, nil
+	}
+
+	var flows []flow
+	// the set may be padded with fewer bytes than a record
+	for len(buf) >= minLength {
+		f := newFlow(exporter, version)
+		var sampler uint64
+		for _, field := range t.fields {
+			length := int(field.length)
+			if field.length == variableLength {
+				var err error
+				length, buf, err = variableFieldLength(buf)
+				if err != nil {
+					return flows, err
+				}
+			}
+			if length > len(buf) {
+				return flows, fmt.Errorf("%s record of template %d truncated", version, id)
+			}
+			value := buf[:length]
+			buf = buf[length:]
+			if field.enterprise != 0 {
+				continue
+			}
+			switch field.id {
+			case fieldSamplingInterval, fieldSamplerRandomInterval, fieldSamplingPacketInterval:
+				f.rate = decodeUint(value)
+			case fieldSamplerID, fieldSelectorID:
+				sampler = decodeUint(value)
+			default:
+				if !t.options {
+					setField(&f, field.id, value)
+				}
+			}
+		}
+
+		if t.options {
+			if f.rate > 0 {
+				d.rates[samplerKey{exporter: exporter, domain: domain, sampler: sampler}] = f.rate
+			}
+			continue
+		}
+		if f.rate == 0 {
+			f.rate = d.samplingRate(exporter, domain, sampler)
+		}
+		finishFlow(&f)
+		flows = append(flows, f)
+	}
+	return flows, nil
+}
+
+// samplingRate returns the sampling rate of a sampler of an exporter, or
+// of the exporter.
+func (d *decoder) samplingRate(exporter string, domain uint32, sampler uint64) uint64 {
+	if rate, ok := d.rates[samplerKey{exporter: exporter, domain: domain, sampler: sampler}]; ok {
+		return rate
+	}
+	return d.rates[samplerKey{exporter: exporter, domain: domain}]
+}
+
+// variableFieldLength returns the length of an IPFIX field of variable
+// length, encoded on one byte or on three bytes for 255 bytes or more.
+func variableFieldLength(buf []byte) (int, []byte, error) {
+	if len(buf) < 1 {
+		return 0, nil, fmt.Errorf("truncated variable length")
+	}
+	if buf[0] < 255 {
+		return int(buf[0]), buf[1:], nil
+	}
+	if len(buf) < 3 {
+		return 0, nil, fmt.Errorf("truncated variable length")
+	}
+	return int(binary.BigEndian.Uint16(buf[1:])), buf[3:], nil
+}
+
+// decodeSFlow decodes the flow samples of an sFlow v5 datagram, whose
+// frames are sampled one at a time.
+func (d *decoder) decodeSFlow(exporter string, buf []byte) ([]flow, error) {
+	p, err := d.sflow.DecodeOnePacket(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("sflow: %w", err)
+	}
+
+	var flows []flow
+	for _, s := range p.Samples {
+		for _, r := range s.SampleData.FlowRecords {
+			h, ok := r.FlowData.(sflow.RawPacketHeaderFlowData)
+			if !ok {
+				continue
+			}
+			f := newFlow(exporter, versionSFlow)
+			f.rate = uint64(s.SampleData.SamplingRate)
+			f.fields["bytes"] = uint64(h.FrameLength)
+			f.fields["packets"] = uint64(1)
+			f.fields["in_if"] = uint64(s.SampleData.InputIfIndex)
+			f.fields["out_if"] = uint64(s.SampleData.OutputIfIndex)
+			if eth, ok := h.Header.(sflow.EthHeader); ok {
+				setSFlowIPHeader(&f, eth.IPHeader)
+			}
+			finishFlow(&f)
+			flows = append(flows, f)
+		}
+	}
+	return flows, nil
+}
+
+func setSFlowIPHeader(f *flow, h sflow.IPHeader) {
+	var l4 sflow.ProtocolHeader
+	switch ip := h.(type) {
+	case sflow.IPV4Header:
+		f.tags["src"] = formatIP(ip.SourceIP[:])
+		f.tags["dst"] = formatIP(ip.DestIP[:])
+		f.tags["protocol"] = protocolName(ip.Protocol)
+		f.fields["tos"] = uint64(ip.DSCP<<2 | ip.ECN)
+		l4 = ip.ProtocolHeader
+	case sflow.IPV6Header:
+		f.tags["src"] = formatIP(ip.SourceIP[:])
+		f.tags["dst"] = formatIP(ip.DestIP[:])
+		f.tags["protocol"] = protocolName(ip.NextHeaderProto)
+		f.fields["tos"] = uint64(ip.DSCP<<2 | ip.ECN)
+		l4 = ip.ProtocolHeader
+	}
+	switch p := l4.(type) {
+	case sflow.TCPHeader:
+		f.tags["src_port"] = fmt.Sprint(p.SourcePort)
+		f.tags["dst_port"] = fmt.Sprint(p.DestinationPort)
+		f.fields["tcp_flags"] = uint64(p.Flags & 0xff)
+	case sflow.UDPHeader:
+		f.tags["src_port"] = fmt.Sprint(p.SourcePort)
+		f.tags["dst_port"] = fmt.Sprint(p.DestinationPort)
+	}
+}
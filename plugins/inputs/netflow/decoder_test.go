@@ -0,0 +1,198 @@
+package netflow
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// packet builds a datagram from big endian integers and byte slices.
+type packet []byte
+
+func (p packet) u8(v uint8) packet   { return append(p, v) }
+func (p packet) u16(v uint16) packet { return append(p, byte(v>>8), byte(v)) }
+func (p packet) u32(v uint32) packet {
+	return append(p, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+func (p packet) bytes(v ...byte) packet { return append(p, v...) }
+
+// set appends a NetFlow v9 or IPFIX set.
+func (p packet) set(id uint16, body packet) packet {
+	return p.u16(id).u16(uint16(4 + len(body))).bytes(body...)
+}
+
+func v9Header(count uint16, sourceID uint32) packet {
+	return packet{}.u16(9).u16(count).u32(1000).u32(1614556800).u32(1).u32(sourceID)
+}
+
+// ipfixMessage returns an IPFIX message with its length.
+func ipfixMessage(domain uint32, sets packet) packet {
+	p := packet{}.u16(10).u16(uint16(16 + len(sets))).u32(1614556800).u32(1).u32(domain)
+	return p.bytes(sets...)
+}
+
+func TestDecodeV5(t *testing.T) {
+	p := packet{}.u16(5).u16(2).u32(1000).u32(1614556800).u32(0).u32(1).u8(0).u8(0).u16(0x4000 | 100)
+	for i := 0; i < 2; i++ {
+		p = p.bytes(10, 0, 0, byte(i+1)).bytes(192, 168, 1, 1).bytes(10, 0, 0, 254) // src, dst, next hop
+		p = p.u16(3).u16(4).u32(10).u32(1500).u32(5000).u32(7500)                   // interfaces, packets, bytes, first, last
+		p = p.u16(51000).u16(443).u8(0).u8(0x12).u8(6).u8(0)                        // ports, pad, tcp flags, protocol, tos
+		p = p.u16(64512).u16(15169).u8(24).u8(16).u16(0)                            // as, masks, pad
+	}
+
+	d := newDecoder(testutil.Logger{})
+	flows, err := d.decode("10.0.0.254", p)
+	require.NoError(t, err)
+	require.Len(t, flows, 2)
+	require.Equal(t, map[string]string{
+		"source":   "10.0.0.254",
+		"version":  "netflow v5",
+		"src":      "10.0.0.2",
+		"dst":      "192.168.1.1",
+		"src_port": "51000",
+		"dst_port": "443",
+		"protocol": "tcp",
+	}, flows[1].tags)
+	require.Equal(t, map[string]interface{}{
+		"next_hop":    "10.0.0.254",
+		"in_if":       uint64(3),
+		"out_if":      uint64(4),
+		"packets":     uint64(10),
+		"bytes":       uint64(1500),
+		"duration_ms": uint64(2500),
+		"tcp_flags":   uint64(0x12),
+		"tos":         uint64(0),
+		"src_as":      uint64(64512),
+		"dst_as":      uint64(15169),
+		"src_mask":    uint64(24),
+		"dst_mask":    uint64(16),
+	}, flows[1].fields)
+	require.Equal(t, uint64(100), flows[1].rate)
+
+	_, err = d.decode("10.0.0.254", p[:len(p)-1])
+	require.Error(t, err)
+}
+
+func TestDecodeV9(t *testing.T) {
+	d := newDecoder(testutil.Logger{})
+
+	data := packet{}.
+		bytes(10, 1, 1, 1).bytes(10, 2, 2, 2).u16(53).u16(40000).u8(17).
+		u32(2).u32(180).u8(5).
+		bytes(10, 1, 1, 3).bytes(10, 2, 2, 2).u16(53).u16(40001).u8(17).
+		u32(1).u32(90).u8(5).
+		bytes(0, 0, 0) // padding
+	// the data of an unknown template is dropped
+	flows, err := d.decode("192.0.2.1", v9Header(1, 7).set(256, data))
+	require.NoError(t, err)
+	require.Empty(t, flows)
+
+	tmpl := packet{}.u16(256).u16(8).
+		u16(fieldSourceIPv4Address).u16(4).
+		u16(fieldDestinationIPv4Address).u16(4).
+		u16(fieldSourceTransportPort).u16(2).
+		u16(fieldDestinationTransportPort).u16(2).
+		u16(fieldProtocolIdentifier).u16(1).
+		u16(fieldPacketDeltaCount).u16(4).
+		u16(fieldOctetDeltaCount).u16(4).
+		u16(fieldSamplerID).u16(1)
+	// options template scoped by system with a sampler ID and interval
+	options := packet{}.u16(257).u16(4).u16(12).
+		u16(1).u16(4).
+		u16(fieldSamplerID).u16(1).
+		u16(fieldSamplingInterval).u16(4).
+		u16(35).u16(1).
+		u16(0) // padding
+	optionsData := packet{}.u32(0).u8(5).u32(512).u8(2).bytes(0, 0)
+
+	p := v9Header(4, 7).set(0, tmpl).set(1, options).set(257, optionsData).set(256, data)
+	flows, err = d.decode("192.0.2.1", p)
+	require.NoError(t, err)
+	require.Len(t, flows, 2)
+	require.Equal(t, map[string]string{
+		"source":   "192.0.2.1",
+		"version":  "netflow v9",
+		"src":      "10.1.1.3",
+		"dst":      "10.2.2.2",
+		"src_port": "53",
+		"dst_port": "40001",
+		"protocol": "udp",
+	}, flows[1].tags)
+	require.Equal(t, map[string]interface{}{
+		"packets": uint64(1),
+		"bytes":   uint64(90),
+	}, flows[1].fields)
+	require.Equal(t, uint64(512), flows[1].rate)
+
+	// the templates are scoped by the exporter and source ID
+	flows, err = d.decode("192.0.2.1", v9Header(1, 8).set(256, data))
+	require.NoError(t, err)
+	require.Empty(t, flows)
+	flows, err = d.decode("192.0.2.2", v9Header(1, 7).set(256, data))
+	require.NoError(t, err)
+	require.Empty(t, flows)
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	d := newDecoder(testutil.Logger{})
+
+	tmpl := packet{}.u16(300).u16(6).
+		u16(fieldSourceIPv6Address).u16(16).
+		u16(fieldDestinationIPv6Address).u16(16).
+		u16(fieldProtocolIdentifier).u16(1).
+		u16(fieldOctetDeltaCount).u16(8).
+		u16(0x8000 | 1).u16(0xffff).u32(9). // enterprise specific, variable length
+		u16(fieldFlowDirection).u16(1)
+	src := []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	dst := []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2}
+	data := packet{}.bytes(src...).bytes(dst...).u8(58).u32(0).u32(4242).
+		u8(3).bytes('a', 'b', 'c').u8(1)
+	options := packet{}.u16(301).u16(2).u16(1).
+		u16(149).u16(4). // observation domain scope
+		u16(fieldSamplingPacketInterval).u16(4)
+	optionsData := packet{}.u32(1).u32(1000)
+
+	p := ipfixMessage(1, packet{}.set(2, tmpl).set(3, options).set(301, optionsData).set(300, data))
+	// a trailing byte beyond the length of the message is ignored
+	flows, err := d.decode("2001:db8::fe", append(p, 0))
+	require.NoError(t, err)
+	require.Len(t, flows, 1)
+	require.Equal(t, map[string]string{
+		"source":   "2001:db8::fe",
+		"version":  "ipfix",
+		"src":      "2001:db8::1",
+		"dst":      "2001:db8::2",
+		"protocol": "ipv6-icmp",
+	}, flows[0].tags)
+	require.Equal(t, map[string]interface{}{
+		"bytes":     uint64(4242),
+		"direction": "egress",
+	}, flows[0].fields)
+	require.Equal(t, uint64(1000), flows[0].rate)
+
+	// the withdrawn templates are forgotten
+	flows, err = d.decode("2001:db8::fe", ipfixMessage(1, packet{}.set(2, packet{}.u16(300).u16(0)).set(300, data)))
+	require.NoError(t, err)
+	require.Empty(t, flows)
+
+	// a set longer than the message is invalid
+	bad := ipfixMessage(1, packet{}.u16(300).u16(100))
+	_, err = d.decode("2001:db8::fe", bad)
+	require.Error(t, err)
+}
+
+func TestDecodeUint(t *testing.T) {
+	require.Equal(t, uint64(0x0102), decodeUint([]byte{1, 2}))
+	require.Equal(t, uint64(0x010203), decodeUint([]byte{1, 2, 3}))
+	require.Equal(t, uint64(0x0203040506070809), decodeUint([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9}))
+	require.Equal(t, uint64(0), decodeUint(nil))
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	d := newDecoder(testutil.Logger{})
+	_, err := d.decode("192.0.2.1", packet{}.u16(7).u16(0))
+	require.Error(t, err)
+	_, err = d.decode("192.0.2.1", packet{}.u16(5))
+	require.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+)
+
+// Information elements of NetFlow v9 and IPFIX.
+// https://www.iana.org/assignments/ipfix/ipfix.xhtml
+const (
+	fieldOctetDeltaCount          = 1
+	fieldPacketDeltaCount         = 2
+	fieldProtocolIdentifier       = 4
+	fieldIPClassOfService         = 5
+	fieldTCPControlBits           = 6
+	fieldSourceTransportPort      = 7
+	fieldSourceIPv4Address        = 8
+	fieldSourceIPv4PrefixLength   = 9
+	fieldIngressInterface         = 10
+	fieldDestinationTransportPort = 11
+	fieldDestinationIPv4Address   = 12
+	fieldDestinationIPv4Prefix    = 13
+	fieldEgressInterface          = 14
+	fieldIPNextHopIPv4Address     = 15
+	fieldBGPSourceASNumber        = 16
+	fieldBGPDestinationASNumber   = 17
+	fieldFlowEndSysUpTime         = 21
+	fieldFlowStartSysUpTime       = 22
+	fieldSourceIPv6Address        = 27
+	fieldDestinationIPv6Address   = 28
+	fieldSourceIPv6PrefixLength   = 29
+	fieldDestinationIPv6Prefix    = 30
+	fieldSamplingInterval         = 34
+	fieldSamplerID                = 48
+	fieldSamplerRandomInterval    = 50
+	fieldVLANID                   = 58
+	fieldFlowDirection            = 61
+	fieldIPNextHopIPv6Address     = 62
+	fieldOctetTotalCount          = 85
+	fieldPacketTotalCount         = 86
+	fieldFlowStartMilliseconds    = 152
+	fieldFlowEndMilliseconds      = 153
+	fieldSelectorID               = 302
+	fieldSamplingPacketInterval   = 305
+)
+
+// setField sets the tag or field of an information element of a flow, the
+// other elements are ignored.
+func setField(f *flow, id uint16, v []byte) {
+	switch id {
+	case fieldOctetDeltaCount:
+		f.fields["bytes"] = decodeUint(v)
+	case fieldOctetTotalCount:
+		if _, ok := f.fields["bytes"]; !ok {
+			f.fields["bytes"] = decodeUint(v)
+		}
+	case fieldPacketDeltaCount:
+		f.fields["packets"] = decodeUint(v)
+	case fieldPacketTotalCount:
+		if _, ok := f.fields["packets"]; !ok {
+			f.fields["packets"] = decodeUint(v)
+		}
+	case fieldProtocolIdentifier:
+		f.tags["protocol"] = protocolName(uint8(decodeUint(v)))
+	case fieldIPClassOfService:
+		f.fields["tos"] = decodeUint(v)
+	case fieldTCPControlBits:
+		f.fields["tcp_flags"] = decodeUint(v)
+	case fieldSourceTransportPort:
+		f.tags["src_port"] = formatUint(v)
+	case fieldDestinationTransportPort:
+		f.tags["dst_port"] = formatUint(v)
+	case fieldSourceIPv4Address, fieldSourceIPv6Address:
+		f.tags["src"] = formatIP(v)
+	case fieldDestinationIPv4Address, fieldDestinationIPv6Address:
+		f.tags["dst"] = formatIP(v)
+	case fieldSourceIPv4PrefixLength, fieldSourceIPv6PrefixLength:
+		f.fields["src_mask"] = decodeUint(v)
+	case fieldDestinationIPv4Prefix, fieldDestinationIPv6Prefix:
+		f.fields["dst_mask"] = decodeUint(v)
+	case fieldIngressInterface:
+		f.fields["in_if"] = decodeUint(v)
+	case fieldEgressInterface:
+		f.fields["out_if"] = decodeUint(v)
+	case fieldIPNextHopIPv4Address, fieldIPNextHopIPv6Address:
+		f.fields["next_hop"] = formatIP(v)
+	case fieldBGPSourceASNumber:
+		f.fields["src_as"] = decodeUint(v)
+	case fieldBGPDestinationASNumber:
+		f.fields["dst_as"] = decodeUint(v)
+	case fieldVLANID:
+		f.fields["vlan"] = decodeUint(v)
+	case fieldFlowDirection:
+		if decodeUint(v) == 0 {
+			f.fields["direction"] = "ingress"
+		} else {
+			f.fields["direction"] = "egress"
+		}
+	case fieldFlowStartSysUpTime, fieldFlowStartMilliseconds:
+		f.start = decodeUint(v)
+	case fieldFlowEndSysUpTime, fieldFlowEndMilliseconds:
+		f.end = decodeUint(v)
+	}
+}
+
+// finishFlow adds the fields computed from several elements.
+func finishFlow(f *flow) {
+	if f.end != 0 && f.end >= f.start {
+		f.fields["duration_ms"] = f.end - f.start
+	}
+}
+
+// decodeUint decodes an unsigned integer of up to 8 bytes, the integers
+// being encoded with fewer bytes than their type by some exporters.
+func decodeUint(v []byte) uint64 {
+	if len(v) > 8 {
+		v = v[len(v)-8:]
+	}
+	var buf [8]byte
+	copy(buf[8-len(v):], v)
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+func formatUint(v []byte) string {
+	return strconv.FormatUint(decodeUint(v), 10)
+}
+
+func formatIP(v []byte) string {
+	switch len(v) {
+	case net.IPv4len, net.IPv6len:
+		return net.IP(v).String()
+	default:
+		return ""
+	}
+}
+
+var protocols = map[uint8]string{
+	1:   "icmp",
+	2:   "igmp",
+	6:   "tcp",
+	17:  "udp",
+	47:  "gre",
+	50:  "esp",
+	51:  "ah",
+	58:  "ipv6-icmp",
+	89:  "ospf",
+	132: "sctp",
+}
+
+// protocolName returns the name of an IP protocol, or its number.
+func protocolName(p uint8) string {
+	if name, ok := protocols[p]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(p), 10)
+}
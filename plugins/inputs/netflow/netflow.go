@@ -0,0 +1,179 @@
+package netflow
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Address to listen for flow datagrams, the NetFlow v5, NetFlow v9, IPFIX
+  ## and sFlow v5 datagrams are told apart by their version.
+  ##   example: service_address = "udp://:2055"
+  ##            service_address = "udp4://:4739"
+  ##            service_address = "udp6://:6343"
+  service_address = "udp://:2055"
+
+  ## Set the size of the operating system's receive buffer.
+  ##   example: read_buffer_size = "64KiB"
+  # read_buffer_size = ""
+
+  ## Multiply the bytes and packets of the flows by the sampling rate of
+  ## their exporter, to estimate the traffic rather than report the sampled
+  ## traffic.
+  # normalize_sampling = true
+
+  ## Sampling rate of the flows whose exporter does not report it, in the
+  ## NetFlow v5 header or in the options records of NetFlow v9 and IPFIX.
+  # default_sampling_rate = 1
+`
+
+const (
+	maxPacketSize = 64 * 1024
+)
+
+// NetFlow is a collector of NetFlow v5/v9, IPFIX and sFlow v5 datagrams.
+type NetFlow struct {
+	ServiceAddress      string        `toml:"service_address"`
+	ReadBufferSize      internal.Size `toml:"read_buffer_size"`
+	NormalizeSampling   bool          `toml:"normalize_sampling"`
+	DefaultSamplingRate uint64        `toml:"default_sampling_rate"`
+
+	Log cua.Logger `toml:"-"`
+
+	addr    net.Addr
+	decoder *decoder
+	conn    *net.UDPConn
+	wg      sync.WaitGroup
+}
+
+// Description answers a description of this input plugin
+func (n *NetFlow) Description() string {
+	return "NetFlow v5/v9, IPFIX and sFlow v5 collector"
+}
+
+// SampleConfig answers a sample configuration
+func (n *NetFlow) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NetFlow) Init() error {
+	if n.DefaultSamplingRate == 0 {
+		n.DefaultSamplingRate = 1
+	}
+	n.decoder = newDecoder(n.Log)
+	return nil
+}
+
+// Start starts listening for the flow datagrams
+func (n *NetFlow) Start(acc cua.Accumulator) error {
+	u, err := url.Parse(n.ServiceAddress)
+	if err != nil {
+		return fmt.Errorf("url parse (%s): %w", n.ServiceAddress, err)
+	}
+
+	conn, err := listenUDP(u.Scheme, u.Host)
+	if err != nil {
+		return err
+	}
+	n.conn = conn
+	n.addr = conn.LocalAddr()
+
+	if n.ReadBufferSize.Size > 0 {
+		_ = conn.SetReadBuffer(int(n.ReadBufferSize.Size))
+	}
+
+	n.Log.Infof("Listening on %s://%s", n.addr.Network(), n.addr.String())
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.read(acc)
+	}()
+
+	return nil
+}
+
+// Gather is a NOOP as the datagrams are received asynchronously
+func (n *NetFlow) Gather(_ cua.Accumulator) error {
+	return nil
+}
+
+func (n *NetFlow) Stop() {
+	if n.conn != nil {
+		n.conn.Close()
+	}
+	n.wg.Wait()
+}
+
+func (n *NetFlow) Address() net.Addr {
+	return n.addr
+}
+
+func (n *NetFlow) read(acc cua.Accumulator) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		count, addr, err := n.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				acc.AddError(err)
+			}
+			break
+		}
+		n.process(acc, addr.IP.String(), buf[:count])
+	}
+}
+
+// process decodes a datagram of an exporter and adds its flows.
+func (n *NetFlow) process(acc cua.Accumulator, exporter string, buf []byte) {
+	flows, err := n.decoder.decode(exporter, buf)
+	if err != nil {
+		acc.AddError(fmt.Errorf("unable to parse datagram from %s: %w", exporter, err))
+	}
+
+	now := time.Now()
+	for _, f := range flows {
+		rate := f.rate
+		if rate == 0 {
+			rate = n.DefaultSamplingRate
+		}
+		f.fields["sampling_rate"] = rate
+		if n.NormalizeSampling && rate > 1 {
+			for _, k := range []string{"bytes", "packets"} {
+				if v, ok := f.fields[k].(uint64); ok {
+					f.fields[k] = v * rate
+				}
+			}
+		}
+		acc.AddFields("netflow", f.fields, f.tags, now)
+	}
+}
+
+func listenUDP(network string, address string) (*net.UDPConn, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+		addr, err := net.ResolveUDPAddr(network, address)
+		if err != nil {
+			return nil, fmt.Errorf("resolve udp addr (%s): %w", address, err)
+		}
+		return net.ListenUDP(network, addr)
+	default:
+		return nil, fmt.Errorf("unsupported network type: %s", network)
+	}
+}
+
+func init() {
+	inputs.Add("netflow", func() cua.Input {
+		return &NetFlow{
+			NormalizeSampling:   true,
+			DefaultSamplingRate: 1,
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package netflow
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetFlow(t *testing.T) {
+	n := &NetFlow{
+		ServiceAddress:    "udp://127.0.0.1:0",
+		NormalizeSampling: true,
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, n.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Start(&acc))
+	defer n.Stop()
+
+	client, err := net.Dial(n.Address().Network(), n.Address().String())
+	require.NoError(t, err)
+
+	// a NetFlow v5 flow sampled 1 out of 10, and an unsampled one
+	for _, interval := range []uint16{0x4000 | 10, 0} {
+		p := packet{}.u16(5).u16(1).u32(1000).u32(1614556800).u32(0).u32(1).u8(0).u8(0).u16(interval)
+		p = p.bytes(10, 0, 0, 1).bytes(192, 168, 1, 1).bytes(0, 0, 0, 0)
+		p = p.u16(3).u16(4).u32(2).u32(120).u32(5000).u32(5000)
+		p = p.u16(123).u16(123).u8(0).u8(0).u8(17).u8(0)
+		p = p.u16(0).u16(0).u8(0).u8(0).u16(0)
+		_, err = client.Write(p)
+		require.NoError(t, err)
+		acc.Wait(1)
+	}
+	acc.Wait(2)
+
+	tags := map[string]string{
+		"source":   "127.0.0.1",
+		"version":  "netflow v5",
+		"src":      "10.0.0.1",
+		"dst":      "192.168.1.1",
+		"src_port": "123",
+		"dst_port": "123",
+		"protocol": "udp",
+	}
+	fields := map[string]interface{}{
+		"next_hop":      "0.0.0.0",
+		"in_if":         uint64(3),
+		"out_if":        uint64(4),
+		"packets":       uint64(20),
+		"bytes":         uint64(1200),
+		"duration_ms":   uint64(0),
+		"tcp_flags":     uint64(0),
+		"tos":           uint64(0),
+		"src_as":        uint64(0),
+		"dst_as":        uint64(0),
+		"src_mask":      uint64(0),
+		"dst_mask":      uint64(0),
+		"sampling_rate": uint64(10),
+	}
+	expected := []cua.Metric{testutil.MustMetric("netflow", tags, fields, time.Unix(0, 0))}
+	unsampled := make(map[string]interface{})
+	for k, v := range fields {
+		unsampled[k] = v
+	}
+	unsampled["packets"] = uint64(2)
+	unsampled["bytes"] = uint64(120)
+	unsampled["sampling_rate"] = uint64(1)
+	expected = append(expected, testutil.MustMetric("netflow", tags, unsampled, time.Unix(0, 0)))
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+}
+
+func TestSFlow(t *testing.T) {
+	n := &NetFlow{Log: testutil.Logger{}}
+	require.NoError(t, n.Init())
+
+	packetBytes, err := hex.DecodeString("0000000500000001c0a80102000000100000f3d40bfa047f0000000200000001000000d00001210a000001fe000004000484240000000000000001fe00000200000000020000000100000090000000010000010b0000000400000080000c2936d3d694c691aa97600800450000f9f19040004011b4f5c0a80913c0a8090a00a1ba0500e5641f3081da02010104066d6f746f6770a281cc02047b46462e0201000201003081bd3012060d2b06010201190501010281dc710201003013060d2b06010201190501010281e66802025acc3012060d2b0601020119050101000003e9000000100000000900000000000000090000000000000001000000d00000e3cc000002100000400048eb740000000000000002100000020000000002000000010000009000000001000000970000000400000080000c2936d3d6fcecda44008f81000009080045000081186440003f119098c0a80815c0a8090a9a690202006d23083c33303e4170722031312030393a33333a3031206b6e6f64653120736e6d70645b313039385d3a20436f6e6e656374696f6e2066726f6d205544503a205b3139322e3136382e392e31305d3a34393233362d000003e90000001000000009000000000000000900000000")
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	n.process(&acc, "192.168.1.2", packetBytes)
+	require.Empty(t, acc.Errors)
+
+	expected := []cua.Metric{
+		testutil.MustMetric(
+			"netflow",
+			map[string]string{
+				"source":   "192.168.1.2",
+				"version":  "sflow v5",
+				"src":      "192.168.9.19",
+				"dst":      "192.168.9.10",
+				"src_port": "161",
+				"dst_port": "47621",
+				"protocol": "udp",
+			},
+			map[string]interface{}{
+				"bytes":         uint64(267),
+				"packets":       uint64(1),
+				"in_if":         uint64(510),
+				"out_if":        uint64(512),
+				"tos":           uint64(0),
+				"sampling_rate": uint64(1024),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"netflow",
+			map[string]string{
+				"source":   "192.168.1.2",
+				"version":  "sflow v5",
+				"src":      "192.168.8.21",
+				"dst":      "192.168.9.10",
+				"src_port": "39529",
+				"dst_port": "514",
+				"protocol": "udp",
+			},
+			map[string]interface{}{
+				"bytes":         uint64(151),
+				"packets":       uint64(1),
+				"in_if":         uint64(528),
+				"out_if":        uint64(512),
+				"tos":           uint64(0),
+				"sampling_rate": uint64(16384),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+}
@@ -6,10 +6,13 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
 	"github.com/circonus-labs/circonus-unified-agent/internal"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
@@ -20,6 +23,23 @@ const sampleConfig = `
   ## It should be set to return a JSON formatted response
   url = "http://127.0.0.1/status?format=json"
 
+  ## The source of the upstream states, "upstream_check" for the status page
+  ## of the module or "plus" for the NGINX Plus API. With "plus" the url is
+  ## the base of the API, e.g. "http://127.0.0.1/api", and the upstreams are
+  ## discovered from it.
+  # source = "upstream_check"
+
+  ## Version of the NGINX Plus API
+  # api_version = 6
+
+  ## Upstreams to collect, by default all the upstreams are collected
+  # upstream_include = []
+  # upstream_exclude = []
+
+  ## Emit an nginx_upstream_check_transition event when the status of a
+  ## server changes
+  # transitions = true
+
   ## HTTP method
   # method = "GET"
 
@@ -46,9 +66,24 @@ const sampleConfig = `
 
 const description = "Read nginx_upstream_check module status information (https://github.com/yaoweibin/nginx_upstream_check_module)"
 
+const (
+	sourceUpstreamCheck = "upstream_check"
+	sourcePlus          = "plus"
+
+	// statusRemoved is the status of the transition event of a server which
+	// is no longer in the upstreams.
+	statusRemoved = "removed"
+)
+
 type UpstreamCheck struct {
 	URL string `toml:"url"`
 
+	Source          string   `toml:"source"`
+	APIVersion      int64    `toml:"api_version"`
+	UpstreamInclude []string `toml:"upstream_include"`
+	UpstreamExclude []string `toml:"upstream_exclude"`
+	Transitions     bool     `toml:"transitions"`
+
 	Username   string            `toml:"username"`
 	Password   string            `toml:"password"`
 	Method     string            `toml:"method"`
@@ -56,17 +91,33 @@ type UpstreamCheck struct {
 	HostHeader string            `toml:"host_header"`
 	Timeout    internal.Duration `toml:"timeout"`
 
+	Log cua.Logger `toml:"-"`
+
 	tls.ClientConfig
 	client *http.Client
+
+	upstreamFilter filter.Filter
+	// states are the last status of the servers, by serverKey
+	states map[string]serverState
+}
+
+// serverState is the last status of a server, with its tags for the
+// transition event when the server is removed.
+type serverState struct {
+	status string
+	tags   map[string]string
 }
 
 func NewUpstreamCheck() *UpstreamCheck {
 	return &UpstreamCheck{
-		URL:        "http://127.0.0.1/status?format=json",
-		Method:     "GET",
-		Headers:    make(map[string]string),
-		HostHeader: "",
-		Timeout:    internal.Duration{Duration: time.Second * 5},
+		URL:         "http://127.0.0.1/status?format=json",
+		Method:      "GET",
+		Headers:     make(map[string]string),
+		HostHeader:  "",
+		Timeout:     internal.Duration{Duration: time.Second * 5},
+		Source:      sourceUpstreamCheck,
+		APIVersion:  6,
+		Transitions: true,
 	}
 }
 
@@ -84,6 +135,26 @@ func (check *UpstreamCheck) Description() string {
 	return description
 }
 
+func (check *UpstreamCheck) Init() error {
+	switch check.Source {
+	case "":
+		check.Source = sourceUpstreamCheck
+	case sourceUpstreamCheck, sourcePlus:
+	default:
+		return fmt.Errorf("invalid source %q, must be %s or %s", check.Source, sourceUpstreamCheck, sourcePlus)
+	}
+	if check.APIVersion == 0 {
+		check.APIVersion = 6
+	}
+
+	f, err := filter.NewIncludeExcludeFilter(check.UpstreamInclude, check.UpstreamExclude)
+	if err != nil {
+		return fmt.Errorf("upstream filter: %w", err)
+	}
+	check.upstreamFilter = f
+	return nil
+}
+
 type UpstreamCheckData struct {
 	Servers struct {
 		Total      uint64                `json:"total"`
@@ -103,6 +174,22 @@ type UpstreamCheckServer struct {
 	Port     uint16 `json:"port"`
 }
 
+// PlusUpstreams are the http upstreams of the NGINX Plus API, by name.
+type PlusUpstreams map[string]struct {
+	Peers []PlusPeer `json:"peers"`
+}
+
+type PlusPeer struct {
+	Server       string `json:"server"`
+	Backup       bool   `json:"backup"`
+	State        string `json:"state"`
+	HealthChecks struct {
+		Checks    uint64 `json:"checks"`
+		Fails     uint64 `json:"fails"`
+		Unhealthy uint64 `json:"unhealthy"`
+	} `json:"health_checks"`
+}
+
 // createHTTPClient create a clients to access API
 func (check *UpstreamCheck) createHTTPClient() (*http.Client, error) {
 	tlsConfig, err := check.ClientConfig.TLSConfig()
@@ -174,19 +261,21 @@ func (check *UpstreamCheck) Gather(accumulator cua.Accumulator) error {
 		}
 		check.client = client
 	}
+	if check.upstreamFilter == nil {
+		if err := check.Init(); err != nil {
+			return err
+		}
+	}
 
 	statusURL, err := url.Parse(check.URL)
 	if err != nil {
 		return fmt.Errorf("url parse (%s): %w", check.URL, err)
 	}
 
-	err = check.gatherStatusData(statusURL.String(), accumulator)
-	if err != nil {
-		return err
+	if check.Source == sourcePlus {
+		return check.gatherPlusData(statusURL, accumulator)
 	}
-
-	return nil
-
+	return check.gatherStatusData(statusURL.String(), accumulator)
 }
 
 func (check *UpstreamCheck) gatherStatusData(url string, accumulator cua.Accumulator) error {
@@ -197,7 +286,11 @@ func (check *UpstreamCheck) gatherStatusData(url string, accumulator cua.Accumul
 		return err
 	}
 
+	seen := make(map[string]bool)
 	for _, server := range checkData.Servers.Server {
+		if !check.upstreamFilter.Match(server.Upstream) {
+			continue
+		}
 
 		tags := map[string]string{
 			"upstream": server.Upstream,
@@ -215,16 +308,117 @@ func (check *UpstreamCheck) gatherStatusData(url string, accumulator cua.Accumul
 		}
 
 		accumulator.AddFields("nginx_upstream_check", fields, tags)
+		check.updateState(accumulator, seen, server.Status, tags)
+	}
+	check.removeStates(accumulator, seen)
+
+	return nil
+}
+
+// gatherPlusData discovers the upstreams from the NGINX Plus API and reports
+// the state of their peers.
+func (check *UpstreamCheck) gatherPlusData(base *url.URL, accumulator cua.Accumulator) error {
+	apiURL := *base
+	apiURL.Path = fmt.Sprintf("%s/%d/http/upstreams", strings.TrimSuffix(base.Path, "/"), check.APIVersion)
+
+	upstreams := PlusUpstreams{}
+	if err := check.gatherJSONData(apiURL.String(), &upstreams); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(upstreams))
+	for name := range upstreams {
+		if check.upstreamFilter.Match(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		for _, peer := range upstreams[name].Peers {
+			tags := map[string]string{
+				"upstream": name,
+				"type":     "http",
+				"name":     peer.Server,
+				"port":     "0",
+				"url":      base.String(),
+			}
+
+			fields := map[string]interface{}{
+				"status":      peer.State,
+				"status_code": check.getStatusCode(peer.State),
+				"backup":      peer.Backup,
+				"checks":      peer.HealthChecks.Checks,
+				"fails":       peer.HealthChecks.Fails,
+				"unhealthy":   peer.HealthChecks.Unhealthy,
+			}
+
+			accumulator.AddFields("nginx_upstream_check", fields, tags)
+			check.updateState(accumulator, seen, peer.State, tags)
+		}
 	}
+	check.removeStates(accumulator, seen)
 
 	return nil
 }
 
+func serverKey(tags map[string]string) string {
+	return tags["upstream"] + "\x00" + tags["name"] + "\x00" + tags["port"]
+}
+
+// updateState records the status of the server and emits a transition event
+// when it differs from the previous one. The first status of a server is not
+// a transition.
+func (check *UpstreamCheck) updateState(accumulator cua.Accumulator, seen map[string]bool, status string, tags map[string]string) {
+	key := serverKey(tags)
+	seen[key] = true
+
+	if check.states == nil {
+		check.states = make(map[string]serverState)
+	}
+	previous, ok := check.states[key]
+	check.states[key] = serverState{status: status, tags: tags}
+	if !ok || previous.status == status {
+		return
+	}
+
+	check.Log.Debugf("server %s of upstream %s changed from %s to %s", tags["name"], tags["upstream"], previous.status, status)
+	check.addTransition(accumulator, previous.status, status, tags)
+}
+
+// removeStates forgets the servers which were not seen in the last gather,
+// with a transition event to the removed status.
+func (check *UpstreamCheck) removeStates(accumulator cua.Accumulator, seen map[string]bool) {
+	for key, state := range check.states {
+		if seen[key] {
+			continue
+		}
+		delete(check.states, key)
+		check.Log.Debugf("server %s of upstream %s was removed", state.tags["name"], state.tags["upstream"])
+		check.addTransition(accumulator, state.status, statusRemoved, state.tags)
+	}
+}
+
+func (check *UpstreamCheck) addTransition(accumulator cua.Accumulator, from, to string, tags map[string]string) {
+	if !check.Transitions {
+		return
+	}
+	fields := map[string]interface{}{
+		"previous_status": from,
+		"status":          to,
+		"status_code":     check.getStatusCode(to),
+	}
+	accumulator.AddFields("nginx_upstream_check_transition", fields, tags)
+}
+
+// getStatusCode maps the status of the module and the states of the NGINX
+// Plus peers to a code.
 func (check *UpstreamCheck) getStatusCode(status string) uint8 {
 	switch status {
 	case "up":
 		return 1
-	case "down":
+	case "down", "unhealthy", "unavail":
 		return 2
 	default:
 		return 0
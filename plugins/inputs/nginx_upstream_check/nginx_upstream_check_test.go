@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
@@ -133,3 +134,129 @@ func TestNginxUpstreamCheckRequest(test *testing.T) {
 	checkError := check.Gather(&accumulator)
 	require.NoError(test, checkError)
 }
+
+func TestNginxUpstreamCheckTransitions(test *testing.T) {
+	response := sampleStatusResponse
+	testServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		fmt.Fprintln(responseWriter, response)
+	}))
+	defer testServer.Close()
+
+	check := NewUpstreamCheck()
+	check.URL = fmt.Sprintf("%s/status", testServer.URL)
+	check.Log = testutil.Logger{}
+	check.UpstreamExclude = []string{"upstream-2"}
+	require.NoError(test, check.Init())
+
+	var accumulator testutil.Accumulator
+	require.NoError(test, check.Gather(&accumulator))
+	require.False(test, accumulator.HasMeasurement("nginx_upstream_check_transition"))
+	require.Equal(test, 1, len(accumulator.Metrics))
+
+	tags := map[string]string{
+		"upstream": "upstream-1",
+		"type":     "http",
+		"name":     "127.0.0.1:8081",
+		"port":     "0",
+		"url":      check.URL,
+	}
+
+	response = strings.Replace(sampleStatusResponse, `"status": "up"`, `"status": "down"`, 1)
+	accumulator.ClearMetrics()
+	require.NoError(test, check.Gather(&accumulator))
+	accumulator.AssertContainsTaggedFields(test, "nginx_upstream_check_transition",
+		map[string]interface{}{
+			"previous_status": "up",
+			"status":          "down",
+			"status_code":     uint8(2),
+		}, tags)
+
+	// no transition while the status is unchanged
+	accumulator.ClearMetrics()
+	require.NoError(test, check.Gather(&accumulator))
+	require.False(test, accumulator.HasMeasurement("nginx_upstream_check_transition"))
+
+	response = `{"servers": {"total": 0, "generation": 2, "server": []}}`
+	accumulator.ClearMetrics()
+	require.NoError(test, check.Gather(&accumulator))
+	accumulator.AssertContainsTaggedFields(test, "nginx_upstream_check_transition",
+		map[string]interface{}{
+			"previous_status": "down",
+			"status":          "removed",
+			"status_code":     uint8(0),
+		}, tags)
+}
+
+const samplePlusResponse = `
+{
+  "backend": {
+    "peers": [
+      {
+        "id": 0,
+        "server": "10.0.0.1:80",
+        "backup": false,
+        "state": "up",
+        "health_checks": {"checks": 10, "fails": 1, "unhealthy": 0}
+      },
+      {
+        "id": 1,
+        "server": "10.0.0.2:80",
+        "backup": true,
+        "state": "unhealthy",
+        "health_checks": {"checks": 10, "fails": 4, "unhealthy": 1}
+      }
+    ]
+  },
+  "other": {
+    "peers": [
+      {"id": 0, "server": "10.0.1.1:80", "state": "up"}
+    ]
+  }
+}
+`
+
+func TestNginxUpstreamCheckPlus(test *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/api/5/http/upstreams" {
+			http.NotFound(responseWriter, request)
+			return
+		}
+		fmt.Fprint(responseWriter, samplePlusResponse)
+	}))
+	defer testServer.Close()
+
+	check := NewUpstreamCheck()
+	check.URL = fmt.Sprintf("%s/api/", testServer.URL)
+	check.Source = "plus"
+	check.APIVersion = 5
+	check.UpstreamInclude = []string{"back*"}
+	check.Log = testutil.Logger{}
+	require.NoError(test, check.Init())
+
+	var accumulator testutil.Accumulator
+	require.NoError(test, check.Gather(&accumulator))
+	require.Equal(test, 2, len(accumulator.Metrics))
+
+	accumulator.AssertContainsTaggedFields(test, "nginx_upstream_check",
+		map[string]interface{}{
+			"status":      "unhealthy",
+			"status_code": uint8(2),
+			"backup":      true,
+			"checks":      uint64(10),
+			"fails":       uint64(4),
+			"unhealthy":   uint64(1),
+		},
+		map[string]string{
+			"upstream": "backend",
+			"type":     "http",
+			"name":     "10.0.0.2:80",
+			"port":     "0",
+			"url":      check.URL,
+		})
+}
+
+func TestNginxUpstreamCheckInvalidSource(test *testing.T) {
+	check := NewUpstreamCheck()
+	check.Source = "stub_status"
+	require.Error(test, check.Init())
+}
@@ -0,0 +1,320 @@
+package nsxt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+var sampleConfig = `
+  ## URLs of the NSX-T managers, or of the virtual IP of the manager cluster.
+  managers = ["https://nsx-manager.local"]
+  username = "admin"
+  password = "secret"
+
+  ## Collections:
+  ##   edge_nodes:     status, CPU, memory and file systems of the edge
+  ##                   transport nodes, tagged by edge cluster.
+  ##   firewall_rules: hit, packet, byte and session counts of the
+  ##                   distributed and gateway firewall rules.
+  # collect = ["edge_nodes", "firewall_rules"]
+
+  ## Policy domains of the firewall rules.
+  # firewall_domains = ["default"]
+
+  ## HTTP response timeout.
+  # response_timeout = "10s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/circonus-unified-agent/ca.pem"
+  # tls_cert = "/etc/circonus-unified-agent/cert.pem"
+  # tls_key = "/etc/circonus-unified-agent/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	collectEdgeNodes     = "edge_nodes"
+	collectFirewallRules = "firewall_rules"
+)
+
+type NSXT struct {
+	Managers        []string          `toml:"managers"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	Collect         []string          `toml:"collect"`
+	FirewallDomains []string          `toml:"firewall_domains"`
+	ResponseTimeout internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	Log cua.Logger `toml:"-"`
+
+	client *http.Client
+}
+
+func (n *NSXT) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NSXT) Description() string {
+	return "Gather the edge node utilization and firewall rule statistics of NSX-T managers"
+}
+
+func (n *NSXT) Init() error {
+	if len(n.Managers) == 0 {
+		return errors.New("no managers configured")
+	}
+	for _, c := range n.Collect {
+		if c != collectEdgeNodes && c != collectFirewallRules {
+			return fmt.Errorf("unknown collection %q, expected %s or %s", c, collectEdgeNodes, collectFirewallRules)
+		}
+	}
+
+	tlsCfg, err := n.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+	n.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: n.ResponseTimeout.Duration,
+	}
+	return nil
+}
+
+func (n *NSXT) collects(c string) bool {
+	for _, collect := range n.Collect {
+		if collect == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *NSXT) Gather(acc cua.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, manager := range n.Managers {
+		wg.Add(1)
+		go func(manager string) {
+			defer wg.Done()
+			if n.collects(collectEdgeNodes) {
+				if err := n.gatherEdgeNodes(acc, manager); err != nil {
+					acc.AddError(fmt.Errorf("manager %s: %w", manager, err))
+				}
+			}
+			if n.collects(collectFirewallRules) {
+				for _, domain := range n.FirewallDomains {
+					if err := n.gatherFirewallRules(acc, manager, domain); err != nil {
+						acc.AddError(fmt.Errorf("manager %s: domain %s: %w", manager, domain, err))
+					}
+				}
+			}
+		}(manager)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (n *NSXT) gatherEdgeNodes(acc cua.Accumulator, manager string) error {
+	// the edge clusters give the cluster of each edge node
+	clusters := map[string]string{}
+	var edgeClusters []edgeCluster
+	if err := n.list(manager, "/api/v1/edge-clusters", &edgeClusters); err != nil {
+		return err
+	}
+	for _, c := range edgeClusters {
+		for _, member := range c.Members {
+			clusters[member.TransportNodeID] = c.DisplayName
+		}
+	}
+
+	var nodes []transportNode
+	if err := n.list(manager, "/api/v1/transport-nodes?node_types=EdgeNode", &nodes); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node.NodeDeploymentInfo.ResourceType != "EdgeNode" {
+			continue
+		}
+		var status transportNodeStatus
+		if err := n.get(manager, "/api/v1/transport-nodes/"+url.PathEscape(node.ID)+"/status", &status); err != nil {
+			acc.AddError(fmt.Errorf("manager %s: transport node %s: %w", manager, node.DisplayName, err))
+			continue
+		}
+
+		tags := map[string]string{
+			"manager":           managerHost(manager),
+			"transport_node":    node.DisplayName,
+			"transport_node_id": node.ID,
+		}
+		if cluster, ok := clusters[node.ID]; ok {
+			tags["edge_cluster"] = cluster
+		}
+
+		system := status.NodeStatus.SystemStatus
+		fields := map[string]interface{}{
+			"status":         status.Status,
+			"up":             status.Status == "UP",
+			"cpu_cores":      system.CPUCores,
+			"mem_total_kb":   system.MemTotal,
+			"mem_used_kb":    system.MemUsed,
+			"mem_cache_kb":   system.MemCache,
+			"swap_total_kb":  system.SwapTotal,
+			"swap_used_kb":   system.SwapUsed,
+			"uptime_ms":      system.Uptime,
+			"control_status": status.ControlConnectionStatus.Status,
+		}
+		if system.MemTotal > 0 {
+			fields["mem_used_percent"] = 100 * float64(system.MemUsed) / float64(system.MemTotal)
+		}
+		for i, name := range []string{"load1", "load5", "load15"} {
+			if i < len(system.LoadAverage) {
+				fields[name] = system.LoadAverage[i]
+			}
+		}
+		acc.AddFields("nsxt_edge_node", fields, tags)
+
+		for _, fs := range system.FileSystems {
+			fsTags := map[string]string{"mount": fs.Mount}
+			for k, v := range tags {
+				fsTags[k] = v
+			}
+			fsFields := map[string]interface{}{
+				"total_kb": fs.Total,
+				"used_kb":  fs.Used,
+			}
+			if fs.Total > 0 {
+				fsFields["used_percent"] = 100 * float64(fs.Used) / float64(fs.Total)
+			}
+			acc.AddFields("nsxt_edge_node_filesystem", fsFields, fsTags)
+		}
+	}
+	return nil
+}
+
+func (n *NSXT) gatherFirewallRules(acc cua.Accumulator, manager, domain string) error {
+	base := "/policy/api/v1/infra/domains/" + url.PathEscape(domain) + "/security-policies"
+	var policies []securityPolicy
+	if err := n.list(manager, base, &policies); err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		var stats []securityPolicyStatistics
+		if err := n.list(manager, base+"/"+url.PathEscape(policy.ID)+"/statistics", &stats); err != nil {
+			acc.AddError(fmt.Errorf("manager %s: policy %s: %w", manager, policy.DisplayName, err))
+			continue
+		}
+		for _, s := range stats {
+			for _, rule := range s.Statistics.Results {
+				tags := map[string]string{
+					"manager": managerHost(manager),
+					"domain":  domain,
+					"policy":  policy.DisplayName,
+					"rule":    path.Base(rule.Rule),
+					"rule_id": rule.InternalRuleID,
+				}
+				if s.EnforcementPointPath != "" {
+					tags["enforcement_point"] = path.Base(s.EnforcementPointPath)
+				}
+				acc.AddFields("nsxt_firewall_rule", map[string]interface{}{
+					"hit_count":         rule.HitCount,
+					"packet_count":      rule.PacketCount,
+					"byte_count":        rule.ByteCount,
+					"session_count":     rule.SessionCount,
+					"max_session_count": rule.MaxSessionCount,
+					"popularity_index":  rule.PopularityIndex,
+				}, tags)
+			}
+		}
+	}
+	return nil
+}
+
+// list reads all the pages of a list result into results.
+func (n *NSXT) list(manager, uri string, results interface{}) error {
+	var all []json.RawMessage
+	cursor := ""
+	for {
+		pageURI := uri
+		if cursor != "" {
+			sep := "?"
+			if strings.Contains(uri, "?") {
+				sep = "&"
+			}
+			pageURI += sep + "cursor=" + url.QueryEscape(cursor)
+		}
+		var page listResult
+		if err := n.get(manager, pageURI, &page); err != nil {
+			return err
+		}
+		all = append(all, page.Results...)
+		if page.Cursor == "" || len(page.Results) == 0 {
+			break
+		}
+		cursor = page.Cursor
+	}
+
+	b, err := json.Marshal(all)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	if err := json.Unmarshal(b, results); err != nil {
+		return fmt.Errorf("json unmarshal (%s): %w", uri, err)
+	}
+	return nil
+}
+
+func (n *NSXT) get(manager, uri string, v interface{}) error {
+	u := strings.TrimSuffix(manager, "/") + uri
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("http new req (%s): %w", u, err)
+	}
+	req.SetBasicAuth(n.Username, n.Password)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned HTTP status %s: %s", uri, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("json decode (%s): %w", uri, err)
+	}
+	return nil
+}
+
+func managerHost(manager string) string {
+	if u, err := url.Parse(manager); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return manager
+}
+
+func init() {
+	inputs.Add("nsx_t", func() cua.Input {
+		return &NSXT{
+			Collect:         []string{collectEdgeNodes, collectFirewallRules},
+			FirewallDomains: []string{"default"},
+			ResponseTimeout: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package nsxt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var responses = map[string]string{
+	"/api/v1/edge-clusters": `{"results": [{"id": "ec1", "display_name": "edge-cluster-1",
+		"members": [{"transport_node_id": "tn1"}]}], "result_count": 1}`,
+	"/api/v1/transport-nodes?node_types=EdgeNode": `{"results": [{"id": "tn1", "display_name": "edge-01",
+		"node_deployment_info": {"resource_type": "EdgeNode"}}], "cursor": "c1"}`,
+	"/api/v1/transport-nodes?node_types=EdgeNode&cursor=c1": `{"results": [{"id": "tn2", "display_name": "esx-01",
+		"node_deployment_info": {"resource_type": "HostNode"}}]}`,
+	"/api/v1/transport-nodes/tn1/status": `{"status": "UP", "control_connection_status": {"status": "UP"},
+		"node_status": {"system_status": {"cpu_cores": 4, "load_average": [0.5, 0.25, 0.125],
+		"mem_total": 8000, "mem_used": 2000, "mem_cache": 1000, "swap_total": 0, "swap_used": 0, "uptime": 3600000,
+		"file_systems": [{"mount": "/", "total": 1000, "used": 250}]}}}`,
+	"/policy/api/v1/infra/domains/default/security-policies": `{"results": [{"id": "web", "display_name": "Web"}]}`,
+	"/policy/api/v1/infra/domains/default/security-policies/web/statistics": `{"results": [{
+		"enforcement_point_path": "/infra/sites/default/enforcement-points/default",
+		"statistics": {"results": [{"rule": "/infra/domains/default/security-policies/web/rules/allow-https",
+		"internal_rule_id": "1002", "hit_count": 10, "packet_count": 20, "byte_count": 3000,
+		"session_count": 5, "max_session_count": 7, "popularity_index": 1}]}}]}`,
+}
+
+func TestGather(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	n := &NSXT{
+		Managers:        []string{ts.URL},
+		Username:        "admin",
+		Password:        "secret",
+		Collect:         []string{collectEdgeNodes, collectFirewallRules},
+		FirewallDomains: []string{"default"},
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, n.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, n.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	manager := ts.Listener.Addr().String()
+	expected := []cua.Metric{
+		testutil.MustMetric("nsxt_edge_node",
+			map[string]string{
+				"manager":           manager,
+				"transport_node":    "edge-01",
+				"transport_node_id": "tn1",
+				"edge_cluster":      "edge-cluster-1",
+			},
+			map[string]interface{}{
+				"status":           "UP",
+				"up":               true,
+				"cpu_cores":        int64(4),
+				"mem_total_kb":     int64(8000),
+				"mem_used_kb":      int64(2000),
+				"mem_cache_kb":     int64(1000),
+				"mem_used_percent": float64(25),
+				"swap_total_kb":    int64(0),
+				"swap_used_kb":     int64(0),
+				"uptime_ms":        int64(3600000),
+				"control_status":   "UP",
+				"load1":            0.5,
+				"load5":            0.25,
+				"load15":           0.125,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("nsxt_edge_node_filesystem",
+			map[string]string{
+				"manager":           manager,
+				"transport_node":    "edge-01",
+				"transport_node_id": "tn1",
+				"edge_cluster":      "edge-cluster-1",
+				"mount":             "/",
+			},
+			map[string]interface{}{
+				"total_kb":     int64(1000),
+				"used_kb":      int64(250),
+				"used_percent": float64(25),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("nsxt_firewall_rule",
+			map[string]string{
+				"manager":           manager,
+				"domain":            "default",
+				"policy":            "Web",
+				"rule":              "allow-https",
+				"rule_id":           "1002",
+				"enforcement_point": "default",
+			},
+			map[string]interface{}{
+				"hit_count":         int64(10),
+				"packet_count":      int64(20),
+				"byte_count":        int64(3000),
+				"session_count":     int64(5),
+				"max_session_count": int64(7),
+				"popularity_index":  int64(1),
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	n := &NSXT{
+		Managers: []string{ts.URL},
+		Collect:  []string{collectEdgeNodes},
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, n.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, n.Gather(acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "403 Forbidden")
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&NSXT{}).Init())
+	require.Error(t, (&NSXT{Managers: []string{"https://nsx"}, Collect: []string{"vms"}}).Init())
+}
@@ -0,0 +1,73 @@
+package nsxt
+
+import "encoding/json"
+
+// listResult is a page of the list APIs, the next page is requested with
+// the cursor.
+type listResult struct {
+	Results []json.RawMessage `json:"results"`
+	Cursor  string            `json:"cursor"`
+}
+
+type edgeCluster struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	Members     []struct {
+		TransportNodeID string `json:"transport_node_id"`
+	} `json:"members"`
+}
+
+type transportNode struct {
+	ID                 string `json:"id"`
+	DisplayName        string `json:"display_name"`
+	NodeDeploymentInfo struct {
+		ResourceType string `json:"resource_type"`
+	} `json:"node_deployment_info"`
+}
+
+type transportNodeStatus struct {
+	Status                  string `json:"status"`
+	ControlConnectionStatus struct {
+		Status string `json:"status"`
+	} `json:"control_connection_status"`
+	NodeStatus struct {
+		SystemStatus struct {
+			CPUCores    int64     `json:"cpu_cores"`
+			LoadAverage []float64 `json:"load_average"`
+			MemTotal    int64     `json:"mem_total"`
+			MemUsed     int64     `json:"mem_used"`
+			MemCache    int64     `json:"mem_cache"`
+			SwapTotal   int64     `json:"swap_total"`
+			SwapUsed    int64     `json:"swap_used"`
+			Uptime      int64     `json:"uptime"`
+			FileSystems []struct {
+				Mount string `json:"mount"`
+				Total int64  `json:"total"`
+				Used  int64  `json:"used"`
+			} `json:"file_systems"`
+		} `json:"system_status"`
+	} `json:"node_status"`
+}
+
+type securityPolicy struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+}
+
+type securityPolicyStatistics struct {
+	EnforcementPointPath string `json:"enforcement_point_path"`
+	Statistics           struct {
+		Results []ruleStatistics `json:"results"`
+	} `json:"statistics"`
+}
+
+type ruleStatistics struct {
+	Rule            string `json:"rule"`
+	InternalRuleID  string `json:"internal_rule_id"`
+	HitCount        int64  `json:"hit_count"`
+	PacketCount     int64  `json:"packet_count"`
+	ByteCount       int64  `json:"byte_count"`
+	SessionCount    int64  `json:"session_count"`
+	MaxSessionCount int64  `json:"max_session_count"`
+	PopularityIndex int64  `json:"popularity_index"`
+}
@@ -0,0 +1,361 @@
+package ntppeer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Time synchronization daemons to track the selected source of, "ntpd"
+  ## (ntpq), "chrony" (chronyc) and "ptp" (linuxptp pmc).  The daemons with
+  ## their client installed are tracked when empty.
+  # sources = []
+
+  ## Domain number and ptp4l UNIX socket queried by pmc.
+  # ptp_domain = 0
+  # ptp_socket = "/var/run/ptp4l"
+
+  ## Timeout of each command.
+  # timeout = "5s"
+`
+
+// commands of the sources
+var sourceCommands = map[string]string{
+	"ntpd":   "ntpq",
+	"chrony": "chronyc",
+	"ptp":    "pmc",
+}
+
+// runCmd runs a command and returns its output, it is replaced in tests.
+var runCmd = func(timeout time.Duration, command string, args ...string) ([]byte, error) {
+	return internal.StdOutputTimeout(exec.Command(command, args...), timeout)
+}
+
+// lookPath finds the commands of the sources, it is replaced in tests.
+var lookPath = exec.LookPath
+
+// NTPPeer tracks the source the time daemons are synchronized to and emits
+// an event each time it changes.
+type NTPPeer struct {
+	Sources   []string          `toml:"sources"`
+	PTPDomain int               `toml:"ptp_domain"`
+	PTPSocket string            `toml:"ptp_socket"`
+	Timeout   internal.Duration `toml:"timeout"`
+	Log       cua.Logger        `toml:"-"`
+
+	states map[string]*peerState
+}
+
+// selection is the source selected by a daemon, peer is empty when the
+// daemon is not synchronized.
+type selection struct {
+	peer   string
+	fields map[string]interface{}
+}
+
+// peerState is the peer last selected by a daemon.
+type peerState struct {
+	peer    string
+	since   time.Time
+	changes int64
+}
+
+func (n *NTPPeer) Description() string {
+	return "Track the NTP system peer and PTP grandmaster, emitting an event on each change"
+}
+
+func (n *NTPPeer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NTPPeer) Init() error {
+	if len(n.Sources) == 0 {
+		for _, source := range []string{"ntpd", "chrony", "ptp"} {
+			if _, err := lookPath(sourceCommands[source]); err == nil {
+				n.Sources = append(n.Sources, source)
+			}
+		}
+		if len(n.Sources) == 0 {
+			return errors.New("none of ntpq, chronyc or pmc found: verify that a time daemon is installed and that its client is in your PATH")
+		}
+		n.Log.Debugf("Tracking %s", strings.Join(n.Sources, ", "))
+		return nil
+	}
+	for _, source := range n.Sources {
+		command, ok := sourceCommands[source]
+		if !ok {
+			return fmt.Errorf("unknown source %q, expected ntpd, chrony or ptp", source)
+		}
+		if _, err := lookPath(command); err != nil {
+			return fmt.Errorf("%s not found: %w", command, err)
+		}
+	}
+	return nil
+}
+
+func (n *NTPPeer) Gather(acc cua.Accumulator) error {
+	now := time.Now()
+	for _, source := range n.Sources {
+		sel, err := n.query(source)
+		if err != nil {
+			acc.AddError(fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		n.track(acc, source, sel, now)
+	}
+	return nil
+}
+
+func (n *NTPPeer) query(source string) (*selection, error) {
+	var args []string
+	switch source {
+	case "ntpd":
+		args = []string{"-p", "-n"}
+	case "chrony":
+		args = []string{"-n", "tracking"}
+	case "ptp":
+		args = []string{"-u", "-b", "0", "-d", strconv.Itoa(n.PTPDomain)}
+		if n.PTPSocket != "" {
+			args = append(args, "-s", n.PTPSocket)
+		}
+		args = append(args, "GET PARENT_DATA_SET", "GET CURRENT_DATA_SET")
+	}
+	command := sourceCommands[source]
+	out, err := runCmd(n.Timeout.Duration, command, args...)
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %w", command, err)
+	}
+	switch source {
+	case "ntpd":
+		return parseNtpq(out)
+	case "chrony":
+		return parseChronyc(out)
+	default:
+		return parsePmc(out)
+	}
+}
+
+// track reports the selected peer of a source, along with a change event
+// when it differs from the one of the previous gather.
+func (n *NTPPeer) track(acc cua.Accumulator, source string, sel *selection, now time.Time) {
+	state, ok := n.states[source]
+	if !ok {
+		state = &peerState{peer: sel.peer, since: now}
+		n.states[source] = state
+	}
+	if sel.peer != state.peer {
+		message := fmt.Sprintf("%s changed from %s to %s", selectedName(source), peerName(state.peer), peerName(sel.peer))
+		n.Log.Infof("%s", message)
+		acc.AddFields("ntp_peer_change",
+			map[string]interface{}{
+				"message":           message,
+				"previous_peer_age": now.Sub(state.since).Seconds(),
+			},
+			peerTags(source, sel.peer, state.peer), now)
+		state.peer = sel.peer
+		state.since = now
+		state.changes++
+	}
+
+	fields := map[string]interface{}{
+		"synchronized": int64(0),
+		"peer_changes": state.changes,
+		"peer_age":     now.Sub(state.since).Seconds(),
+	}
+	if sel.peer != "" {
+		fields["synchronized"] = int64(1)
+	}
+	for k, v := range sel.fields {
+		fields[k] = v
+	}
+	acc.AddFields("ntp_peer", fields, peerTags(source, sel.peer, ""), now)
+}
+
+// peerTags omits the peers of the unsynchronized daemons.
+func peerTags(source, peer, previous string) map[string]string {
+	tags := map[string]string{"source": source}
+	if peer != "" {
+		tags["peer"] = peer
+	}
+	if previous != "" {
+		tags["previous_peer"] = previous
+	}
+	return tags
+}
+
+func selectedName(source string) string {
+	if source == "ptp" {
+		return "PTP grandmaster"
+	}
+	return source + " system peer"
+}
+
+func peerName(peer string) string {
+	if peer == "" {
+		return "none"
+	}
+	return peer
+}
+
+// parseNtpq parses the peers listed by ntpq -p, the system peer is the one
+// marked with a '*', or with an 'o' when it is a PPS peer.
+func parseNtpq(out []byte) (*selection, error) {
+	sel := &selection{fields: map[string]interface{}{}}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	var header []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if header == nil {
+			header = strings.Fields(line)
+			continue
+		}
+		if line == "" || (line[0] != '*' && line[0] != 'o') {
+			continue
+		}
+		columns := strings.Fields(line[1:])
+		if len(columns) != len(header) {
+			return nil, fmt.Errorf("unexpected ntpq line %q", line)
+		}
+		for i, name := range header {
+			switch name {
+			case "remote":
+				sel.peer = columns[i]
+			case "st":
+				stratum, err := strconv.ParseInt(columns[i], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("parsing stratum %q: %w", columns[i], err)
+				}
+				sel.fields["stratum"] = stratum
+			case "offset":
+				offset, err := strconv.ParseFloat(columns[i], 64)
+				if err != nil {
+					return nil, fmt.Errorf("parsing offset %q: %w", columns[i], err)
+				}
+				sel.fields["offset"] = offset
+			}
+		}
+		break
+	}
+	if header == nil {
+		return nil, errors.New("no output from ntpq")
+	}
+	return sel, nil
+}
+
+// parseChronyc parses the output of chronyc tracking, like:
+//
+//     Reference ID    : C0A80116 (192.168.1.22)
+//     Stratum         : 3
+//     Last offset     : +0.000012651 seconds
+//
+// The reference is not set when chrony is not synchronized.
+func parseChronyc(out []byte) (*selection, error) {
+	sel := &selection{fields: map[string]interface{}{}}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "Reference ID":
+			// prefer the address in parentheses to the hex reference
+			if i := strings.Index(value, "("); i > 0 {
+				if addr := strings.TrimSuffix(value[i+1:], ")"); addr != "" {
+					value = addr
+				} else {
+					value = strings.TrimSpace(value[:i])
+				}
+			}
+			if value != "" && value != "00000000" && value != "0.0.0.0" {
+				sel.peer = value
+			}
+		case "Stratum":
+			stratum, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing stratum %q: %w", value, err)
+			}
+			sel.fields["stratum"] = stratum
+		case "Last offset":
+			offset, err := strconv.ParseFloat(strings.TrimSuffix(value, " seconds"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing offset %q: %w", value, err)
+			}
+			// milliseconds as ntpq
+			sel.fields["offset"] = offset * 1e3
+		}
+	}
+	if _, ok := sel.fields["stratum"]; !ok {
+		return nil, errors.New("unexpected chronyc output, stratum not found")
+	}
+	return sel, nil
+}
+
+// parsePmc parses the parent and current data sets returned by pmc, the
+// peer is the grandmaster clock identity.
+func parsePmc(out []byte) (*selection, error) {
+	sel := &selection{fields: map[string]interface{}{}}
+	responses := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		columns := strings.Fields(scanner.Text())
+		if len(columns) < 2 {
+			continue
+		}
+		if strings.Contains(scanner.Text(), "RESPONSE MANAGEMENT") {
+			responses++
+			continue
+		}
+		switch columns[0] {
+		case "grandmasterIdentity":
+			sel.peer = columns[1]
+		case "gm.ClockClass":
+			class, err := strconv.ParseInt(columns[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing clock class %q: %w", columns[1], err)
+			}
+			sel.fields["clock_class"] = class
+		case "stepsRemoved":
+			steps, err := strconv.ParseInt(columns[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing steps removed %q: %w", columns[1], err)
+			}
+			sel.fields["steps_removed"] = steps
+		case "offsetFromMaster":
+			offset, err := strconv.ParseFloat(columns[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing offset %q: %w", columns[1], err)
+			}
+			// nanoseconds to milliseconds as ntpq
+			sel.fields["offset"] = offset / 1e6
+		}
+	}
+	if responses == 0 {
+		return nil, errors.New("no response from ptp4l")
+	}
+	return sel, nil
+}
+
+func newNTPPeer() *NTPPeer {
+	return &NTPPeer{
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		states:  map[string]*peerState{},
+	}
+}
+
+func init() {
+	inputs.Add("ntp_peer", func() cua.Input {
+		return newNTPPeer()
+	})
+}
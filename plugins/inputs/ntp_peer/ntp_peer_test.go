@@ -0,0 +1,154 @@
+package ntppeer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const ntpqOutput = `     remote           refid      st t when poll reach   delay   offset  jitter
+==============================================================================
++192.168.1.21    .GPS.            1 u   62   64  377    0.523   -0.071   0.042
+*192.168.1.22    .PPS.            1 u   13   64  377    0.412    0.105   0.031
+ 192.168.1.23    .INIT.          16 u    -   64    0    0.000    0.000   0.000
+`
+
+const chronycOutput = `Reference ID    : C0A80116 (192.168.1.22)
+Stratum         : 3
+Ref time (UTC)  : Thu May 12 14:27:07 2016
+System time     : 0.000020390 seconds fast of NTP time
+Last offset     : +0.000012651 seconds
+RMS offset      : 0.000025577 seconds
+Leap status     : Normal
+`
+
+const pmcOutput = `sending: GET PARENT_DATA_SET
+	90e2ba.fffe.0a0b0c-1 seq 0 RESPONSE MANAGEMENT PARENT_DATA_SET
+		parentPortIdentity                    001122.fffe.334455-1
+		parentStats                           0
+		gm.ClockClass                         6
+		gm.ClockAccuracy                      0x21
+		grandmasterPriority2                  128
+		grandmasterIdentity                   001122.fffe.334455
+sending: GET CURRENT_DATA_SET
+	90e2ba.fffe.0a0b0c-1 seq 1 RESPONSE MANAGEMENT CURRENT_DATA_SET
+		stepsRemoved     1
+		offsetFromMaster -12.0
+		meanPathDelay    540.0
+`
+
+func TestParseNtpq(t *testing.T) {
+	sel, err := parseNtpq([]byte(ntpqOutput))
+	require.NoError(t, err)
+	require.Equal(t, "192.168.1.22", sel.peer)
+	require.Equal(t, map[string]interface{}{"stratum": int64(1), "offset": 0.105}, sel.fields)
+
+	// no system peer while ntpd is starting
+	sel, err = parseNtpq([]byte(ntpqOutput[:len(ntpqOutput)-240]))
+	require.NoError(t, err)
+	require.Empty(t, sel.peer)
+}
+
+func TestParseChronyc(t *testing.T) {
+	sel, err := parseChronyc([]byte(chronycOutput))
+	require.NoError(t, err)
+	require.Equal(t, "192.168.1.22", sel.peer)
+	require.Equal(t, int64(3), sel.fields["stratum"])
+	require.InDelta(t, 0.012651, sel.fields["offset"], 1e-9)
+
+	sel, err = parseChronyc([]byte("Reference ID    : 00000000 ()\nStratum         : 0\n"))
+	require.NoError(t, err)
+	require.Empty(t, sel.peer)
+
+	_, err = parseChronyc([]byte("506 Cannot talk to daemon\n"))
+	require.Error(t, err)
+}
+
+func TestParsePmc(t *testing.T) {
+	sel, err := parsePmc([]byte(pmcOutput))
+	require.NoError(t, err)
+	require.Equal(t, "001122.fffe.334455", sel.peer)
+	require.Equal(t, map[string]interface{}{
+		"clock_class":   int64(6),
+		"steps_removed": int64(1),
+		"offset":        -0.000012,
+	}, sel.fields)
+
+	_, err = parsePmc([]byte("sending: GET PARENT_DATA_SET\n"))
+	require.Error(t, err)
+}
+
+func TestInit(t *testing.T) {
+	orig := lookPath
+	t.Cleanup(func() { lookPath = orig })
+	lookPath = func(file string) (string, error) {
+		if file == "chronyc" {
+			return "/usr/bin/chronyc", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	n := newNTPPeer()
+	n.Log = testutil.Logger{}
+	require.NoError(t, n.Init())
+	require.Equal(t, []string{"chrony"}, n.Sources)
+
+	n = newNTPPeer()
+	n.Sources = []string{"ptp"}
+	require.Error(t, n.Init())
+
+	n = newNTPPeer()
+	n.Sources = []string{"gps"}
+	require.Error(t, n.Init())
+}
+
+func TestGatherPeerChange(t *testing.T) {
+	peer := "001122.fffe.334455"
+	orig := runCmd
+	t.Cleanup(func() { runCmd = orig })
+	runCmd = func(timeout time.Duration, command string, args ...string) ([]byte, error) {
+		require.Equal(t, "pmc", command)
+		require.Equal(t, []string{"-u", "-b", "0", "-d", "24", "-s", "/run/ptp4l", "GET PARENT_DATA_SET", "GET CURRENT_DATA_SET"}, args)
+		return []byte("RESPONSE MANAGEMENT\ngrandmasterIdentity " + peer + "\n"), nil
+	}
+
+	n := newNTPPeer()
+	n.Log = testutil.Logger{}
+	n.Sources = []string{"ptp"}
+	n.PTPDomain = 24
+	n.PTPSocket = "/run/ptp4l"
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.False(t, acc.HasMeasurement("ntp_peer_change"))
+
+	start := n.states["ptp"].since
+	n.states["ptp"].since = start.Add(-time.Minute)
+	peer = "001122.fffe.667788"
+	acc.ClearMetrics()
+	require.NoError(t, n.Gather(&acc))
+	change, ok := acc.Get("ntp_peer_change")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"source": "ptp", "peer": "001122.fffe.667788", "previous_peer": "001122.fffe.334455"}, change.Tags)
+	require.Equal(t, "PTP grandmaster changed from 001122.fffe.334455 to 001122.fffe.667788", change.Fields["message"])
+	require.GreaterOrEqual(t, change.Fields["previous_peer_age"], 60.0)
+	acc.AssertContainsTaggedFields(t, "ntp_peer", map[string]interface{}{
+		"synchronized": int64(1),
+		"peer_changes": int64(1),
+		"peer_age":     float64(0),
+	}, map[string]string{"source": "ptp", "peer": "001122.fffe.667788"})
+
+	// losing the grandmaster is an event too
+	peer = ""
+	acc.ClearMetrics()
+	require.NoError(t, n.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "ntp_peer", map[string]interface{}{
+		"synchronized": int64(0),
+		"peer_changes": int64(2),
+		"peer_age":     float64(0),
+	}, map[string]string{"source": "ptp"})
+	require.Equal(t, "PTP grandmaster changed from 001122.fffe.667788 to none", acc.Metrics[0].Fields["message"])
+}
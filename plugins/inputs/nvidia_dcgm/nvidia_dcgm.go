@@ -0,0 +1,274 @@
+package nvidiadcgm
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const measurement = "nvidia_dcgm"
+
+// tagNames maps the dcgm-exporter GPU labels to tag keys, the other labels
+// (e.g. the kubernetes pod attribution) are used as is.
+var tagNames = map[string]string{
+	"gpu":       "index",
+	"UUID":      "uuid",
+	"device":    "device",
+	"modelName": "name",
+	"Hostname":  "",
+	"err_msg":   "",
+}
+
+// NvidiaDCGM reads the GPU metrics of the NVIDIA Data Center GPU Manager
+// through dcgm-exporter.
+type NvidiaDCGM struct {
+	URLs            []string          `toml:"urls"`
+	ResponseTimeout internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	Log cua.Logger
+
+	clients map[string]*http.Client
+}
+
+var sampleConfig = `
+  ## dcgm-exporter endpoints, a unix socket is given as
+  ## "unix:///path/to/socket" with an optional "?path=/metrics" query.
+  urls = ["http://localhost:9400/metrics"]
+
+  ## Timeout of the requests
+  # response_timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/cua/ca.pem"
+  # tls_cert = "/etc/cua/cert.pem"
+  # tls_key = "/etc/cua/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// Description returns the description of the NvidiaDCGM plugin
+func (d *NvidiaDCGM) Description() string {
+	return "Read datacenter GPU health and profiling metrics from NVIDIA DCGM"
+}
+
+// SampleConfig returns the sample configuration for the NvidiaDCGM plugin
+func (d *NvidiaDCGM) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *NvidiaDCGM) Init() error {
+	if len(d.URLs) == 0 {
+		return fmt.Errorf("no urls configured")
+	}
+	tlsCfg, err := d.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+
+	d.clients = make(map[string]*http.Client, len(d.URLs))
+	for _, addr := range d.URLs {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return fmt.Errorf("parsing url %q: %w", addr, err)
+		}
+		transport := &http.Transport{TLSClientConfig: tlsCfg}
+		if u.Scheme == "unix" {
+			socket := u.Path
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var dialer net.Dialer
+				c, err := dialer.DialContext(ctx, "unix", socket)
+				if err != nil {
+					return nil, fmt.Errorf("dial unix (%s): %w", socket, err)
+				}
+				return c, nil
+			}
+		}
+		d.clients[addr] = &http.Client{
+			Transport: transport,
+			Timeout:   d.ResponseTimeout.Duration,
+		}
+	}
+	return nil
+}
+
+// Gather implements the Input interface
+func (d *NvidiaDCGM) Gather(acc cua.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, addr := range d.URLs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			if err := d.gatherURL(addr, acc); err != nil {
+				acc.AddError(err)
+			}
+		}(addr)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (d *NvidiaDCGM) gatherURL(addr string, acc cua.Accumulator) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("parsing url %q: %w", addr, err)
+	}
+	reqURL := addr
+	if u.Scheme == "unix" {
+		path := u.Query().Get("path")
+		if path == "" {
+			path = "/metrics"
+		}
+		reqURL = "http://localhost" + path
+	}
+
+	resp, err := d.clients[addr].Get(reqURL)
+	if err != nil {
+		return fmt.Errorf("error making HTTP request to %s: %w", addr, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", addr, resp.Status)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading metrics for %s: %w", addr, err)
+	}
+
+	now := time.Now()
+	for _, gm := range groupByGPU(families) {
+		acc.AddFields(measurement, gm.fields, gm.tags, now)
+	}
+	return nil
+}
+
+type gpuMetric struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// groupByGPU merges the DCGM fields of a GPU, and of the workload it is
+// attributed to, into a single metric.
+func groupByGPU(families map[string]*dto.MetricFamily) []*gpuMetric {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var order []string
+	metrics := make(map[string]*gpuMetric)
+	for _, name := range names {
+		field, ok := fieldName(name)
+		if !ok {
+			continue
+		}
+		for _, m := range families[name].Metric {
+			value, ok := sampleValue(m)
+			if !ok {
+				continue
+			}
+			tags := makeTags(m)
+			id := seriesID(tags)
+			gm, ok := metrics[id]
+			if !ok {
+				gm = &gpuMetric{tags: tags, fields: make(map[string]interface{})}
+				metrics[id] = gm
+				order = append(order, id)
+			}
+			gm.fields[field] = value
+		}
+	}
+
+	result := make([]*gpuMetric, 0, len(order))
+	for _, id := range order {
+		result = append(result, metrics[id])
+	}
+	return result
+}
+
+// fieldName converts a DCGM field identifier to a field key, device fields
+// lose their prefix while profiling fields are kept apart with "prof_":
+// DCGM_FI_DEV_XID_ERRORS is xid_errors and DCGM_FI_PROF_NVLINK_TX_BYTES is
+// prof_nvlink_tx_bytes.
+func fieldName(name string) (string, bool) {
+	switch {
+	case strings.HasPrefix(name, "DCGM_FI_DEV_"):
+		name = strings.TrimPrefix(name, "DCGM_FI_DEV_")
+	case strings.HasPrefix(name, "DCGM_FI_PROF_"):
+		name = "prof_" + strings.TrimPrefix(name, "DCGM_FI_PROF_")
+	case strings.HasPrefix(name, "DCGM_FI_"):
+		name = strings.TrimPrefix(name, "DCGM_FI_")
+	default:
+		return "", false
+	}
+	return strings.ToLower(name), true
+}
+
+func makeTags(m *dto.Metric) map[string]string {
+	tags := make(map[string]string, len(m.Label))
+	for _, lp := range m.Label {
+		key := lp.GetName()
+		if name, ok := tagNames[key]; ok {
+			key = name
+		}
+		if key == "" || lp.GetValue() == "" {
+			continue
+		}
+		tags[key] = lp.GetValue()
+	}
+	return tags
+}
+
+func seriesID(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(tags[k])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+func sampleValue(m *dto.Metric) (float64, bool) {
+	switch {
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue(), true
+	case m.Counter != nil:
+		return m.GetCounter().GetValue(), true
+	case m.Untyped != nil:
+		return m.GetUntyped().GetValue(), true
+	}
+	return 0, false
+}
+
+func init() {
+	inputs.Add("nvidia_dcgm", func() cua.Input {
+		return &NvidiaDCGM{
+			ResponseTimeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package nvidiadcgm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	body, err := os.ReadFile("testdata/metrics.txt")
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer ts.Close()
+
+	d := &NvidiaDCGM{
+		URLs:            []string{ts.URL + "/metrics"},
+		ResponseTimeout: internal.Duration{Duration: time.Second},
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(d.Gather))
+
+	expected := []cua.Metric{
+		testutil.MustMetric(
+			"nvidia_dcgm",
+			map[string]string{
+				"index":  "0",
+				"uuid":   "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"device": "nvidia0",
+				"name":   "Tesla V100-SXM2-16GB",
+			},
+			map[string]interface{}{
+				"nvlink_bandwidth_total": 12345.0,
+				"sm_clock":               1530.0,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"nvidia_dcgm",
+			map[string]string{
+				"index":     "1",
+				"uuid":      "GPU-88e5d5b1-1d9d-8c1f-2dc4-5f3c0e4b2a1d",
+				"device":    "nvidia1",
+				"name":      "Tesla V100-SXM2-16GB",
+				"namespace": "ml",
+				"pod":       "trainer-0",
+				"container": "trainer",
+			},
+			map[string]interface{}{
+				"prof_sm_active": 0.75,
+				"sm_clock":       1312.0,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"nvidia_dcgm",
+			map[string]string{
+				"index":    "0",
+				"uuid":     "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"device":   "nvidia0",
+				"name":     "Tesla V100-SXM2-16GB",
+				"err_code": "0",
+			},
+			map[string]interface{}{
+				"xid_errors": 0.0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherStatusError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	d := &NvidiaDCGM{URLs: []string{ts.URL}}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(d.Gather))
+}
+
+func TestFieldName(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		ok    bool
+	}{
+		{"DCGM_FI_DEV_XID_ERRORS", "xid_errors", true},
+		{"DCGM_FI_PROF_NVLINK_TX_BYTES", "prof_nvlink_tx_bytes", true},
+		{"DCGM_FI_DRIVER_VERSION", "driver_version", true},
+		{"go_goroutines", "", false},
+	}
+	for _, tt := range tests {
+		field, ok := fieldName(tt.name)
+		require.Equal(t, tt.ok, ok, tt.name)
+		require.Equal(t, tt.field, field, tt.name)
+	}
+}
@@ -3,7 +3,6 @@ package opcuaclient
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/url"
 	"strings"
 	"time"
@@ -22,18 +21,21 @@ const (
 
 // OpcUA type
 type OpcUA struct {
-	Name           string          `toml:"name"`
-	Endpoint       string          `toml:"endpoint"`
-	SecurityPolicy string          `toml:"security_policy"`
-	SecurityMode   string          `toml:"security_mode"`
-	Certificate    string          `toml:"certificate"`
-	PrivateKey     string          `toml:"private_key"`
-	Username       string          `toml:"username"`
-	Password       string          `toml:"password"`
-	AuthMethod     string          `toml:"auth_method"`
-	ConnectTimeout config.Duration `toml:"connect_timeout"`
-	RequestTimeout config.Duration `toml:"request_timeout"`
-	NodeList       []OPCTag        `toml:"nodes"`
+	Name            string          `toml:"name"`
+	Endpoint        string          `toml:"endpoint"`
+	SecurityPolicy  string          `toml:"security_policy"`
+	SecurityMode    string          `toml:"security_mode"`
+	Certificate     string          `toml:"certificate"`
+	PrivateKey      string          `toml:"private_key"`
+	Username        string          `toml:"username"`
+	Password        string          `toml:"password"`
+	AuthMethod      string          `toml:"auth_method"`
+	ConnectTimeout  config.Duration `toml:"connect_timeout"`
+	RequestTimeout  config.Duration `toml:"request_timeout"`
+	RequestMaxNodes int             `toml:"request_max_nodes"`
+	StatusCode      string          `toml:"status_code"`
+	Timestamp       string          `toml:"timestamp"`
+	NodeList        []OPCTag        `toml:"nodes"`
 
 	Nodes       []string     `toml:"-"`
 	NodeData    []OPCData    `toml:"-"`
@@ -46,9 +48,11 @@ type OpcUA struct {
 	ReadError    int `toml:"-"`
 	NumberOfTags int `toml:"-"`
 
+	Log cua.Logger `toml:"-"`
+
 	// internal values
 	client *opcua.Client
-	req    *ua.ReadRequest
+	reqs   []*ua.ReadRequest
 	opts   []opcua.Option
 }
 
@@ -67,8 +71,8 @@ type OPCData struct {
 	TagName   string
 	Value     interface{}
 	Quality   ua.StatusCode
-	TimeStamp string
-	Time      string
+	TimeStamp time.Time
+	Time      time.Time
 	DataType  ua.TypeID
 }
 
@@ -99,6 +103,20 @@ const sampleConfig = `
   ## Maximum time allowed for a request over the estabilished connection.
   # request_timeout = "5s"
   #
+  ## Maximum number of nodes read by a single request, the nodes are read in
+  ## several requests when there are more. 0 reads all the nodes at once.
+  # request_max_nodes = 100
+  #
+  ## Status code of the nodes to add to the metrics, besides the Quality
+  ## field: "tag" for a status_code tag with its name, such as "OK" or
+  ## "BadNodeIDUnknown", "field" for a status_code integer field, or "none".
+  # status_code = "none"
+  #
+  ## Time of the metrics: "gather" for the time of the gather, "server" for
+  ## the server timestamp or "source" for the source timestamp of the values.
+  ## The time of the gather is used for the nodes without a timestamp.
+  # timestamp = "gather"
+  #
   ## Security policy, one of "None", "Basic128Rsa15", "Basic256",
   ## "Basic256Sha256", or "auto"
   # security_policy = "auto"
@@ -158,14 +176,32 @@ func (o *OpcUA) Init() error {
 		return err
 	}
 
+	if o.RequestMaxNodes < 0 {
+		return fmt.Errorf("request_max_nodes must not be negative")
+	}
+
+	switch o.StatusCode {
+	case "":
+		o.StatusCode = "none"
+	case "none", "tag", "field":
+	default:
+		return fmt.Errorf("invalid status_code %q, expected tag, field or none", o.StatusCode)
+	}
+
+	switch o.Timestamp {
+	case "":
+		o.Timestamp = "gather"
+	case "gather", "server", "source":
+	default:
+		return fmt.Errorf("invalid timestamp %q, expected gather, server or source", o.Timestamp)
+	}
+
 	err = o.InitNodes()
 	if err != nil {
 		return err
 	}
 	o.NumberOfTags = len(o.NodeList)
 
-	o.setupOptions()
-
 	return nil
 
 }
@@ -273,6 +309,14 @@ func Connect(o *OpcUA) error {
 	case "opc.tcp":
 		o.state = Connecting
 
+		// the endpoints are discovered at the first connection, retried at
+		// the next gather when the server is unavailable
+		if o.opts == nil {
+			if err := o.setupOptions(); err != nil {
+				return err
+			}
+		}
+
 		if o.client != nil {
 			_ = o.client.CloseSession()
 		}
@@ -291,11 +335,7 @@ func Connect(o *OpcUA) error {
 			return fmt.Errorf("RegisterNodes failed: %w", err)
 		}
 
-		o.req = &ua.ReadRequest{
-			MaxAge:             2000,
-			NodesToRead:        readvalues(regResp.RegisteredNodeIDs),
-			TimestampsToReturn: ua.TimestampsToReturnBoth,
-		}
+		o.reqs = readRequests(regResp.RegisteredNodeIDs, o.RequestMaxNodes)
 
 		err = o.getData()
 		if err != nil {
@@ -308,46 +348,87 @@ func Connect(o *OpcUA) error {
 	return nil
 }
 
-func (o *OpcUA) setupOptions() {
+func (o *OpcUA) setupOptions() error {
 
 	// Get a list of the endpoints for our target server
 	endpoints, err := opcua.GetEndpoints(o.Endpoint)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("get endpoints (%s): %w", o.Endpoint, err)
 	}
 
 	if o.Certificate == "" && o.PrivateKey == "" {
 		if o.SecurityPolicy != none || o.SecurityMode != none {
-			o.Certificate, o.PrivateKey = generateCert("urn:circonus:gopcua:client", 2048, o.Certificate, o.PrivateKey, (365 * 24 * time.Hour))
+			o.Certificate, o.PrivateKey, err = generateCert("urn:circonus:gopcua:client", 2048, o.Certificate, o.PrivateKey, (365 * 24 * time.Hour))
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	o.opts = generateClientOpts(endpoints, o.Certificate, o.PrivateKey, o.SecurityPolicy, o.SecurityMode, o.AuthMethod, o.Username, o.Password, time.Duration(o.RequestTimeout))
+	o.opts, err = generateClientOpts(o.Log, endpoints, o.Certificate, o.PrivateKey, o.SecurityPolicy, o.SecurityMode, o.AuthMethod, o.Username, o.Password, time.Duration(o.RequestTimeout))
+	return err
 }
 
+// getData reads the nodes, one request per chunk. An error reading a chunk
+// is returned as the session is likely unusable, a bad status of a node only
+// is reported by its quality.
 func (o *OpcUA) getData() error {
-	resp, err := o.client.Read(o.req)
-	if err != nil {
-		o.ReadError++
-		return fmt.Errorf("RegisterNodes Read failed: %w", err)
-	}
-	o.ReadSuccess++
-	for i, d := range resp.Results {
-		if d.Status != ua.StatusOK {
-			return fmt.Errorf("Status not OK: %v", d.Status)
+	i := 0
+	for _, req := range o.reqs {
+		resp, err := o.client.Read(req)
+		if err != nil {
+			o.ReadError++
+			return fmt.Errorf("RegisterNodes Read failed: %w", err)
+		}
+		if len(resp.Results) != len(req.NodesToRead) {
+			o.ReadError++
+			return fmt.Errorf("read returned %d results for %d nodes", len(resp.Results), len(req.NodesToRead))
 		}
-		o.NodeData[i].TagName = o.NodeList[i].Name
-		if d.Value != nil {
-			o.NodeData[i].Value = d.Value.Value()
-			o.NodeData[i].DataType = d.Value.Type()
+		for _, d := range resp.Results {
+			o.setData(i, d)
+			i++
 		}
-		o.NodeData[i].Quality = d.Status
-		o.NodeData[i].TimeStamp = d.ServerTimestamp.String()
-		o.NodeData[i].Time = d.SourceTimestamp.String()
 	}
+	o.ReadSuccess++
 	return nil
 }
 
+// setData updates the data of the i-th node from the result of a read.
+func (o *OpcUA) setData(i int, d *ua.DataValue) {
+	o.NodeData[i].TagName = o.NodeList[i].Name
+	o.NodeData[i].Value = nil
+	if d.Status != ua.StatusOK {
+		o.Log.Debugf("Status of node %q not OK: %v", o.NodeList[i].Name, d.Status)
+	} else if d.Value != nil {
+		o.NodeData[i].Value = d.Value.Value()
+		o.NodeData[i].DataType = d.Value.Type()
+	}
+	o.NodeData[i].Quality = d.Status
+	o.NodeData[i].TimeStamp = d.ServerTimestamp
+	o.NodeData[i].Time = d.SourceTimestamp
+}
+
+// readRequests splits the reads of the nodes in requests of at most
+// maxNodes nodes, or a single request when maxNodes is 0.
+func readRequests(ids []*ua.NodeID, maxNodes int) []*ua.ReadRequest {
+	if maxNodes <= 0 || maxNodes > len(ids) {
+		maxNodes = len(ids)
+	}
+	var reqs []*ua.ReadRequest
+	for start := 0; start < len(ids); start += maxNodes {
+		end := start + maxNodes
+		if end > len(ids) {
+			end = len(ids)
+		}
+		reqs = append(reqs, &ua.ReadRequest{
+			MaxAge:             2000,
+			NodesToRead:        readvalues(ids[start:end]),
+			TimestampsToReturn: ua.TimestampsToReturnBoth,
+		})
+	}
+	return reqs
+}
+
 func readvalues(ids []*ua.NodeID) []*ua.ReadValueID {
 	rvids := make([]*ua.ReadValueID, len(ids))
 	for i, v := range ids {
@@ -368,60 +449,99 @@ func disconnect(o *OpcUA) error {
 	switch u.Scheme {
 	case "opc.tcp":
 		o.state = Disconnected
-		o.client.Close()
-		return nil
+		if o.client == nil {
+			return nil
+		}
+		err := o.client.Close()
+		o.client = nil
+		return err
 	default:
 		return fmt.Errorf("invalid controller")
 	}
 }
 
-// Gather defines what data the plugin will gather.
+// Gather defines what data the plugin will gather. The session is kept
+// across gathers, it is only reestablished after a failed read.
 func (o *OpcUA) Gather(acc cua.Accumulator) error {
 	if o.state == Disconnected {
+		// Connect reads the nodes once connected
 		o.state = Connecting
 		err := Connect(o)
 		if err != nil {
 			o.state = Disconnected
+			_ = disconnect(o)
 			return err
 		}
-	}
-
-	o.state = Connected
-
-	err := o.getData()
-	if err != nil && o.state == Connected {
-		o.state = Disconnected
+		o.state = Connected
+	} else if err := o.getData(); err != nil {
 		_ = disconnect(o)
 		return err
 	}
 
+	o.addMetrics(acc, time.Now())
+	return nil
+}
+
+// addMetrics adds a metric per node, at the time of the gather or at the
+// timestamp of the value.
+func (o *OpcUA) addMetrics(acc cua.Accumulator, now time.Time) {
 	for i, n := range o.NodeList {
+		data := o.NodeData[i]
 		fields := make(map[string]interface{})
 		tags := map[string]string{
 			"name": n.Name,
 			"id":   BuildNodeID(n),
 		}
 
-		fields[o.NodeData[i].TagName] = o.NodeData[i].Value
-		fields["Quality"] = strings.TrimSpace(fmt.Sprint(o.NodeData[i].Quality))
-		acc.AddFields(o.Name, fields, tags)
+		fields[data.TagName] = data.Value
+		fields["Quality"] = strings.TrimSpace(fmt.Sprint(data.Quality))
+		switch o.StatusCode {
+		case "tag":
+			tags["status_code"] = statusName(data.Quality)
+		case "field":
+			fields["status_code"] = int64(data.Quality)
+		}
+
+		t := now
+		switch o.Timestamp {
+		case "server":
+			if !data.TimeStamp.IsZero() {
+				t = data.TimeStamp
+			}
+		case "source":
+			if !data.Time.IsZero() {
+				t = data.Time
+			}
+		}
+		acc.AddFields(o.Name, fields, tags, t)
 	}
-	return nil
+}
+
+// statusName returns the name of a status code, such as "OK" or
+// "BadNodeIDUnknown", without its info bits, or its hexadecimal value when
+// it is unknown.
+func statusName(code ua.StatusCode) string {
+	d, ok := ua.StatusCodes[code&0xFFFF0000]
+	if !ok {
+		return fmt.Sprintf("0x%08X", uint32(code))
+	}
+	return strings.TrimPrefix(d.Name, "Status")
 }
 
 // Add this plugin
 func init() {
 	inputs.Add("opcua", func() cua.Input {
 		return &OpcUA{
-			Name:           "localhost",
-			Endpoint:       "opc.tcp://localhost:4840",
-			SecurityPolicy: auto,
-			SecurityMode:   auto,
-			RequestTimeout: config.Duration(5 * time.Second),
-			ConnectTimeout: config.Duration(10 * time.Second),
-			Certificate:    "/etc/circonus-unified-agent/cert.pem",
-			PrivateKey:     "/etc/circonus-unified-agent/key.pem",
-			AuthMethod:     "Anonymous",
+			Name:            "localhost",
+			Endpoint:        "opc.tcp://localhost:4840",
+			SecurityPolicy:  auto,
+			SecurityMode:    auto,
+			RequestTimeout:  config.Duration(5 * time.Second),
+			RequestMaxNodes: 100,
+			ConnectTimeout:  config.Duration(10 * time.Second),
+			Certificate:     "/etc/circonus-unified-agent/cert.pem",
+			PrivateKey:      "/etc/circonus-unified-agent/key.pem",
+			AuthMethod:      "Anonymous",
 		}
 	})
 }
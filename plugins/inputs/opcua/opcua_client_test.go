@@ -3,10 +3,13 @@ package opcuaclient
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/gopcua/opcua/ua"
 	"github.com/stretchr/testify/require"
 )
 
@@ -108,3 +111,113 @@ nodes = [
 	require.Equal(t, o.NodeList[0].Name, "name")
 	require.Equal(t, o.NodeList[1].Name, "name2")
 }
+
+func TestReadRequests(t *testing.T) {
+	var ids []*ua.NodeID
+	for i := 0; i < 250; i++ {
+		ids = append(ids, ua.NewNumericNodeID(0, uint32(i)))
+	}
+
+	reqs := readRequests(ids, 100)
+	require.Len(t, reqs, 3)
+	require.Len(t, reqs[0].NodesToRead, 100)
+	require.Len(t, reqs[1].NodesToRead, 100)
+	require.Len(t, reqs[2].NodesToRead, 50)
+	require.Equal(t, ids[100], reqs[1].NodesToRead[0].NodeID)
+	require.Equal(t, ids[249], reqs[2].NodesToRead[49].NodeID)
+
+	reqs = readRequests(ids, 0)
+	require.Len(t, reqs, 1)
+	require.Len(t, reqs[0].NodesToRead, 250)
+
+	require.Empty(t, readRequests(nil, 100))
+}
+
+func TestSetData(t *testing.T) {
+	o := OpcUA{
+		NodeList: []OPCTag{{Name: "good"}, {Name: "bad"}},
+		NodeData: make([]OPCData, 2),
+		Log:      testutil.Logger{},
+	}
+	o.setData(0, &ua.DataValue{Value: ua.MustVariant(42.0), Status: ua.StatusOK})
+	o.setData(1, &ua.DataValue{Value: ua.MustVariant(1.0), Status: ua.StatusBadNodeIDUnknown})
+
+	require.Equal(t, "good", o.NodeData[0].TagName)
+	require.Equal(t, 42.0, o.NodeData[0].Value)
+	require.Equal(t, "bad", o.NodeData[1].TagName)
+	require.Nil(t, o.NodeData[1].Value)
+	require.Equal(t, ua.StatusBadNodeIDUnknown, o.NodeData[1].Quality)
+}
+
+func TestInitOptions(t *testing.T) {
+	o := OpcUA{
+		Name:           "testing",
+		Endpoint:       "opc.tcp://localhost:4840",
+		SecurityPolicy: "None",
+		SecurityMode:   "None",
+	}
+	require.NoError(t, o.Init())
+	require.Equal(t, "none", o.StatusCode)
+	require.Equal(t, "gather", o.Timestamp)
+
+	o.StatusCode = "label"
+	require.Error(t, o.Init())
+
+	o.StatusCode = "tag"
+	o.Timestamp = "device"
+	require.Error(t, o.Init())
+}
+
+func TestAddMetrics(t *testing.T) {
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	server := now.Add(-time.Second)
+	source := now.Add(-2 * time.Second)
+
+	o := OpcUA{
+		Name:     "plc",
+		NodeList: []OPCTag{{Name: "temp", Namespace: "3", IdentifierType: "s", Identifier: "Temperature"}},
+		NodeData: []OPCData{{
+			TagName:   "temp",
+			Value:     79.0,
+			Quality:   ua.StatusUncertainInitialValue | 0x0400,
+			TimeStamp: server,
+			Time:      source,
+		}},
+	}
+
+	o.StatusCode = "tag"
+	o.Timestamp = "source"
+	var acc testutil.Accumulator
+	o.addMetrics(&acc, now)
+	acc.AssertContainsTaggedFields(t, "plc",
+		map[string]interface{}{
+			"temp":    79.0,
+			"Quality": strings.TrimSpace(fmt.Sprint(o.NodeData[0].Quality)),
+		},
+		map[string]string{
+			"name":        "temp",
+			"id":          "ns=3;s=Temperature",
+			"status_code": "UncertainInitialValue",
+		})
+	require.Equal(t, source, acc.Metrics[0].Time)
+
+	o.StatusCode = "field"
+	o.Timestamp = "server"
+	acc.ClearMetrics()
+	o.addMetrics(&acc, now)
+	require.Equal(t, int64(0x40920400), acc.Metrics[0].Fields["status_code"])
+	require.NotContains(t, acc.Metrics[0].Tags, "status_code")
+	require.Equal(t, server, acc.Metrics[0].Time)
+
+	// the time of the gather is used without timestamp
+	o.NodeData[0].TimeStamp = time.Time{}
+	acc.ClearMetrics()
+	o.addMetrics(&acc, now)
+	require.Equal(t, now, acc.Metrics[0].Time)
+}
+
+func TestStatusName(t *testing.T) {
+	require.Equal(t, "OK", statusName(ua.StatusOK))
+	require.Equal(t, "BadNodeIDUnknown", statusName(ua.StatusBadNodeIDUnknown))
+	require.Equal(t, "0x80FF0000", statusName(0x80FF0000))
+}
@@ -9,7 +9,6 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
-	"log"
 	"math/big"
 	"net"
 	"net/url"
@@ -17,6 +16,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/gopcua/opcua"
 	"github.com/gopcua/opcua/debug"
 	"github.com/gopcua/opcua/ua"
@@ -30,12 +30,12 @@ func newTempDir() (string, error) {
 	return dir, fmt.Errorf("temp dir: %w", err)
 }
 
-func generateCert(host string, rsaBits int, certFile, keyFile string, dur time.Duration) (string, string) {
+func generateCert(host string, rsaBits int, certFile, keyFile string, dur time.Duration) (string, string, error) {
 
 	dir, _ := newTempDir()
 
 	if len(host) == 0 {
-		log.Fatalf("Missing required host parameter")
+		return "", "", fmt.Errorf("missing required host parameter")
 	}
 	if rsaBits == 0 {
 		rsaBits = 2048
@@ -49,7 +49,7 @@ func generateCert(host string, rsaBits int, certFile, keyFile string, dur time.D
 
 	priv, err := rsa.GenerateKey(rand.Reader, rsaBits)
 	if err != nil {
-		log.Fatalf("failed to generate private key: %s", err)
+		return "", "", fmt.Errorf("failed to generate private key: %w", err)
 	}
 
 	notBefore := time.Now()
@@ -58,7 +58,7 @@ func generateCert(host string, rsaBits int, certFile, keyFile string, dur time.D
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 	if err != nil {
-		log.Fatalf("failed to generate serial number: %s", err)
+		return "", "", fmt.Errorf("failed to generate serial number: %w", err)
 	}
 
 	template := x509.Certificate{
@@ -88,33 +88,32 @@ func generateCert(host string, rsaBits int, certFile, keyFile string, dur time.D
 
 	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, publicKey(priv), priv)
 	if err != nil {
-		log.Fatalf("Failed to create certificate: %s", err)
+		return "", "", fmt.Errorf("failed to create certificate: %w", err)
 	}
 
 	certOut, err := os.Create(certFile)
 	if err != nil {
-		log.Fatalf("failed to open %s for writing: %s", certFile, err)
+		return "", "", fmt.Errorf("failed to open %s for writing: %w", certFile, err)
 	}
 	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
-		log.Fatalf("failed to write data to %s: %s", certFile, err)
+		return "", "", fmt.Errorf("failed to write data to %s: %w", certFile, err)
 	}
 	if err := certOut.Close(); err != nil {
-		log.Fatalf("error closing %s: %s", certFile, err)
+		return "", "", fmt.Errorf("error closing %s: %w", certFile, err)
 	}
 
 	keyOut, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
-		log.Printf("failed to open %s for writing: %s", keyFile, err)
-		return "", ""
+		return "", "", fmt.Errorf("failed to open %s for writing: %w", keyFile, err)
 	}
 	if err := pem.Encode(keyOut, pemBlockForKey(priv)); err != nil {
-		log.Fatalf("failed to write data to %s: %s", keyFile, err)
+		return "", "", fmt.Errorf("failed to write data to %s: %w", keyFile, err)
 	}
 	if err := keyOut.Close(); err != nil {
-		log.Fatalf("error closing %s: %s", keyFile, err)
+		return "", "", fmt.Errorf("error closing %s: %w", keyFile, err)
 	}
 
-	return certFile, keyFile
+	return certFile, keyFile, nil
 }
 
 func publicKey(priv interface{}) interface{} {
@@ -146,7 +145,7 @@ func pemBlockForKey(priv interface{}) *pem.Block {
 
 // OPT FUNCTIONS
 
-func generateClientOpts(endpoints []*ua.EndpointDescription, certFile, keyFile, policy, mode, auth, username, password string, requestTimeout time.Duration) []opcua.Option {
+func generateClientOpts(log cua.Logger, endpoints []*ua.EndpointDescription, certFile, keyFile, policy, mode, auth, username, password string, requestTimeout time.Duration) ([]opcua.Option, error) {
 	opts := []opcua.Option{}
 	appuri := "urn:circonus:gopcua:client"
 	appname := "Circonus"
@@ -159,7 +158,11 @@ func generateClientOpts(endpoints []*ua.EndpointDescription, certFile, keyFile,
 
 	if certFile == "" && keyFile == "" {
 		if policy != none || mode != none {
-			certFile, keyFile = generateCert(appuri, 2048, certFile, keyFile, (365 * 24 * time.Hour))
+			var err error
+			certFile, keyFile, err = generateCert(appuri, 2048, certFile, keyFile, (365 * 24 * time.Hour))
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -168,11 +171,11 @@ func generateClientOpts(endpoints []*ua.EndpointDescription, certFile, keyFile,
 		debug.Printf("Loading cert/key from %s/%s", certFile, keyFile)
 		c, err := tls.LoadX509KeyPair(certFile, keyFile)
 		if err != nil {
-			log.Printf("Failed to load certificate: %s", err)
+			log.Warnf("Failed to load certificate: %s", err)
 		} else {
 			pk, ok := c.PrivateKey.(*rsa.PrivateKey)
 			if !ok {
-				log.Fatalf("Invalid private key")
+				return nil, fmt.Errorf("invalid private key")
 			}
 			cert = c.Certificate[0]
 			opts = append(opts, opcua.PrivateKey(pk), opcua.Certificate(cert))
@@ -190,11 +193,11 @@ func generateClientOpts(endpoints []*ua.EndpointDescription, certFile, keyFile,
 		secPolicy = ua.SecurityPolicyURIPrefix + policy
 		policy = ""
 	default:
-		log.Fatalf("Invalid security policy: %s", policy)
+		return nil, fmt.Errorf("invalid security policy: %s", policy)
 	}
 
 	// Select the most appropriate authentication mode from server capabilities and user input
-	authMode, authOption := generateAuth(auth, cert, username, password)
+	authMode, authOption := generateAuth(log, auth, cert, username, password)
 	opts = append(opts, authOption)
 
 	var secMode ua.MessageSecurityMode
@@ -210,7 +213,7 @@ func generateClientOpts(endpoints []*ua.EndpointDescription, certFile, keyFile,
 		secMode = ua.MessageSecurityModeSignAndEncrypt
 		mode = ""
 	default:
-		log.Fatalf("Invalid security mode: %s", mode)
+		return nil, fmt.Errorf("invalid security mode: %s", mode)
 	}
 
 	// Allow input of only one of sec-mode,sec-policy when choosing 'None'
@@ -252,26 +255,22 @@ func generateClientOpts(endpoints []*ua.EndpointDescription, certFile, keyFile,
 	}
 
 	if serverEndpoint == nil { // Didn't find an endpoint with matching policy and mode.
-		log.Printf("unable to find suitable server endpoint with selected sec-policy and sec-mode")
-		log.Fatalf("quitting")
-	} else {
-		secPolicy = serverEndpoint.SecurityPolicyURI
-		secMode = serverEndpoint.SecurityMode
+		return nil, fmt.Errorf("unable to find suitable server endpoint with selected sec-policy and sec-mode")
 	}
+	secPolicy = serverEndpoint.SecurityPolicyURI
+	secMode = serverEndpoint.SecurityMode
 
 	// Check that the selected endpoint is a valid combo
 	err := validateEndpointConfig(endpoints, secPolicy, secMode, authMode)
 	if err != nil {
-		log.Fatalf("error validating input: %s", err)
+		return nil, fmt.Errorf("error validating input: %w", err)
 	}
 
 	opts = append(opts, opcua.SecurityFromEndpoint(serverEndpoint, authMode))
-	return opts
+	return opts, nil
 }
 
-func generateAuth(a string, cert []byte, un, pw string) (ua.UserTokenType, opcua.Option) {
-	var err error
-
+func generateAuth(log cua.Logger, a string, cert []byte, un, pw string) (ua.UserTokenType, opcua.Option) {
 	var authMode ua.UserTokenType
 	var authOption opcua.Option
 	switch strings.ToLower(a) {
@@ -281,19 +280,6 @@ func generateAuth(a string, cert []byte, un, pw string) (ua.UserTokenType, opcua
 
 	case "username":
 		authMode = ua.UserTokenTypeUserName
-
-		if un == "" {
-			if err != nil {
-				log.Fatalf("error reading username input: %s", err)
-			}
-		}
-
-		if pw == "" {
-			if err != nil {
-				log.Fatalf("error reading username input: %s", err)
-			}
-		}
-
 		authOption = opcua.AuthUsername(un, pw)
 
 	case "certificate":
@@ -306,7 +292,7 @@ func generateAuth(a string, cert []byte, un, pw string) (ua.UserTokenType, opcua
 		authOption = opcua.AuthIssuedToken([]byte(nil))
 
 	default:
-		log.Printf("unknown auth-mode, defaulting to Anonymous")
+		log.Warnf("Unknown auth-mode %q, defaulting to Anonymous", a)
 		authMode = ua.UserTokenTypeAnonymous
 		authOption = opcua.AuthAnonymous()
 
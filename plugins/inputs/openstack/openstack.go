@@ -0,0 +1,353 @@
+package openstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+var sampleConfig = `
+  ## URL of the identity (keystone) API v3.
+  authentication_endpoint = "https://keystone.local:5000/v3"
+
+  ## Application credential used to authenticate, the services are read with
+  ## the admin APIs so the credential needs the admin role, or a reader role
+  ## allowed by the policies of the services.
+  application_credential_id = ""
+  application_credential_secret = ""
+
+  ## Region and interface of the endpoints of the services in the catalog.
+  # region = ""
+  # interface = "public"
+
+  ## Collections:
+  ##   hypervisors:    compute (nova) hypervisor usage and state
+  ##   storage_pools:  block storage (cinder) pool capacity
+  ##   network_agents: network (neutron) agent health
+  ## The latency of the token issued each gather is always reported.
+  # collect = ["hypervisors", "storage_pools", "network_agents"]
+
+  ## HTTP response timeout.
+  # response_timeout = "10s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/circonus-unified-agent/ca.pem"
+  # tls_cert = "/etc/circonus-unified-agent/cert.pem"
+  # tls_key = "/etc/circonus-unified-agent/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	collectHypervisors   = "hypervisors"
+	collectStoragePools  = "storage_pools"
+	collectNetworkAgents = "network_agents"
+)
+
+type OpenStack struct {
+	AuthenticationEndpoint      string            `toml:"authentication_endpoint"`
+	ApplicationCredentialID     string            `toml:"application_credential_id"`
+	ApplicationCredentialSecret string            `toml:"application_credential_secret"`
+	Region                      string            `toml:"region"`
+	Interface                   string            `toml:"interface"`
+	Collect                     []string          `toml:"collect"`
+	ResponseTimeout             internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	Log cua.Logger `toml:"-"`
+
+	client *http.Client
+}
+
+// session is an authenticated session, with the endpoints of the catalog.
+type session struct {
+	token     string
+	endpoints map[string]string
+}
+
+func (o *OpenStack) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenStack) Description() string {
+	return "Gather the hypervisor, storage pool, network agent and identity metrics of an OpenStack cloud"
+}
+
+func (o *OpenStack) Init() error {
+	if o.AuthenticationEndpoint == "" {
+		return errors.New("no authentication_endpoint configured")
+	}
+	if o.ApplicationCredentialID == "" || o.ApplicationCredentialSecret == "" {
+		return errors.New("application_credential_id and application_credential_secret are required")
+	}
+	if o.Interface == "" {
+		o.Interface = "public"
+	}
+	for _, c := range o.Collect {
+		if c != collectHypervisors && c != collectStoragePools && c != collectNetworkAgents {
+			return fmt.Errorf("unknown collection %q, expected %s, %s or %s", c, collectHypervisors, collectStoragePools, collectNetworkAgents)
+		}
+	}
+
+	tlsCfg, err := o.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+	o.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: o.ResponseTimeout.Duration,
+	}
+	return nil
+}
+
+func (o *OpenStack) collects(c string) bool {
+	for _, collect := range o.Collect {
+		if collect == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *OpenStack) Gather(acc cua.Accumulator) error {
+	// a token is issued each gather, its latency is the health of keystone
+	start := time.Now()
+	s, err := o.authenticate()
+	if err != nil {
+		return err
+	}
+	acc.AddFields("openstack_identity", map[string]interface{}{
+		"token_latency_ms": float64(time.Since(start)) / float64(time.Millisecond),
+	}, o.tags(nil))
+
+	if o.collects(collectHypervisors) {
+		if err := o.gatherHypervisors(acc, s); err != nil {
+			acc.AddError(fmt.Errorf("hypervisors: %w", err))
+		}
+	}
+	if o.collects(collectStoragePools) {
+		if err := o.gatherStoragePools(acc, s); err != nil {
+			acc.AddError(fmt.Errorf("storage pools: %w", err))
+		}
+	}
+	if o.collects(collectNetworkAgents) {
+		if err := o.gatherNetworkAgents(acc, s); err != nil {
+			acc.AddError(fmt.Errorf("network agents: %w", err))
+		}
+	}
+	return nil
+}
+
+// authenticate issues a token for the application credential and reads the
+// endpoints of the region and interface from the catalog.
+func (o *OpenStack) authenticate() (*session, error) {
+	var body authRequest
+	body.Auth.Identity.Methods = []string{"application_credential"}
+	body.Auth.Identity.ApplicationCredential.ID = o.ApplicationCredentialID
+	body.Auth.Identity.ApplicationCredential.Secret = o.ApplicationCredentialSecret
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("json marshal: %w", err)
+	}
+
+	u := strings.TrimSuffix(o.AuthenticationEndpoint, "/") + "/auth/tokens"
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("http new req (%s): %w", u, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http do: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("authentication returned HTTP status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var auth authResponse
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+
+	s := &session{
+		token:     resp.Header.Get("X-Subject-Token"),
+		endpoints: map[string]string{},
+	}
+	for _, service := range auth.Token.Catalog {
+		for _, e := range service.Endpoints {
+			if e.Interface != o.Interface || (o.Region != "" && e.RegionID != o.Region) {
+				continue
+			}
+			s.endpoints[service.Type] = strings.TrimSuffix(e.URL, "/")
+		}
+	}
+	return s, nil
+}
+
+// endpoint returns the endpoint of the first of the service types found in
+// the catalog.
+func (s *session) endpoint(types ...string) (string, error) {
+	for _, t := range types {
+		if u, ok := s.endpoints[t]; ok {
+			return u, nil
+		}
+	}
+	return "", fmt.Errorf("no %s endpoint in the catalog", types[0])
+}
+
+func (o *OpenStack) gatherHypervisors(acc cua.Accumulator, s *session) error {
+	endpoint, err := s.endpoint("compute")
+	if err != nil {
+		return err
+	}
+	var result hypervisors
+	if err := o.get(s, endpoint+"/os-hypervisors/detail", &result); err != nil {
+		return err
+	}
+	for _, h := range result.Hypervisors {
+		acc.AddFields("openstack_hypervisor", map[string]interface{}{
+			"state":                h.State,
+			"status":               h.Status,
+			"up":                   h.State == "up",
+			"vcpus":                h.VCPUs,
+			"vcpus_used":           h.VCPUsUsed,
+			"memory_mb":            h.MemoryMB,
+			"memory_mb_used":       h.MemoryMBUsed,
+			"free_ram_mb":          h.FreeRAMMB,
+			"local_gb":             h.LocalGB,
+			"local_gb_used":        h.LocalGBUsed,
+			"free_disk_gb":         h.FreeDiskGB,
+			"disk_available_least": h.DiskAvailableLeast,
+			"running_vms":          h.RunningVMs,
+			"current_workload":     h.CurrentWorkload,
+		}, o.tags(map[string]string{
+			"hypervisor":      h.HypervisorHostname,
+			"hypervisor_id":   rawID(h.ID),
+			"hypervisor_type": h.HypervisorType,
+		}))
+	}
+	return nil
+}
+
+func (o *OpenStack) gatherStoragePools(acc cua.Accumulator, s *session) error {
+	endpoint, err := s.endpoint("volumev3", "block-storage")
+	if err != nil {
+		return err
+	}
+	var result storagePools
+	if err := o.get(s, endpoint+"/scheduler-stats/get_pools?detail=true", &result); err != nil {
+		return err
+	}
+	for _, p := range result.Pools {
+		fields := map[string]interface{}{}
+		capacities := map[string]interface{}{
+			"total_capacity_gb":       p.Capabilities.TotalCapacityGB,
+			"free_capacity_gb":        p.Capabilities.FreeCapacityGB,
+			"allocated_capacity_gb":   p.Capabilities.AllocatedCapacityGB,
+			"provisioned_capacity_gb": p.Capabilities.ProvisionedCapacityGB,
+		}
+		for name, v := range capacities {
+			// "infinite" and "unknown" capacities are not reported
+			if f, ok := v.(float64); ok {
+				fields[name] = f
+			}
+		}
+		total, ok1 := fields["total_capacity_gb"].(float64)
+		free, ok2 := fields["free_capacity_gb"].(float64)
+		if ok1 && ok2 && total > 0 {
+			fields["used_percent"] = 100 * (total - free) / total
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields("openstack_storage_pool", fields, o.tags(map[string]string{
+			"pool":    p.Name,
+			"backend": p.Capabilities.VolumeBackendName,
+		}))
+	}
+	return nil
+}
+
+func (o *OpenStack) gatherNetworkAgents(acc cua.Accumulator, s *session) error {
+	endpoint, err := s.endpoint("network")
+	if err != nil {
+		return err
+	}
+	var result networkAgents
+	if err := o.get(s, endpoint+"/v2.0/agents", &result); err != nil {
+		return err
+	}
+	for _, a := range result.Agents {
+		tags := map[string]string{
+			"agent_type": a.AgentType,
+			"binary":     a.Binary,
+			"host":       a.Host,
+		}
+		if a.AvailabilityZone != "" {
+			tags["availability_zone"] = a.AvailabilityZone
+		}
+		acc.AddFields("openstack_network_agent", map[string]interface{}{
+			"alive":          a.Alive,
+			"admin_state_up": a.AdminStateUp,
+		}, o.tags(tags))
+	}
+	return nil
+}
+
+// tags adds the region to the tags.
+func (o *OpenStack) tags(tags map[string]string) map[string]string {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	if o.Region != "" {
+		tags["region"] = o.Region
+	}
+	return tags
+}
+
+func (o *OpenStack) get(s *session, u string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("http new req (%s): %w", u, err)
+	}
+	req.Header.Set("X-Auth-Token", s.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned HTTP status %s: %s", u, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("json decode (%s): %w", u, err)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("openstack", func() cua.Input {
+		return &OpenStack{
+			Interface:       "public",
+			Collect:         []string{collectHypervisors, collectStoragePools, collectNetworkAgents},
+			ResponseTimeout: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
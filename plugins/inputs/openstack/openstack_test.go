@@ -0,0 +1,165 @@
+package openstack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/identity/v3/auth/tokens" {
+			var auth authRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&auth))
+			if auth.Auth.Identity.ApplicationCredential.Secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-Subject-Token", "token1")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(strings.ReplaceAll(`{"token": {"catalog": [
+				{"type": "compute", "endpoints": [
+					{"interface": "public", "region_id": "RegionOne", "url": "URL/compute/v2.1"},
+					{"interface": "internal", "region_id": "RegionOne", "url": "URL/internal"}]},
+				{"type": "volumev3", "endpoints": [{"interface": "public", "region_id": "RegionOne", "url": "URL/volume/v3/p1"}]},
+				{"type": "network", "endpoints": [{"interface": "public", "region_id": "RegionOne", "url": "URL/network/"}]}]}}`,
+				"URL", ts.URL)))
+			return
+		}
+
+		if r.Header.Get("X-Auth-Token") != "token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.RequestURI() {
+		case "/compute/v2.1/os-hypervisors/detail":
+			_, _ = w.Write([]byte(`{"hypervisors": [{"id": 1, "hypervisor_hostname": "compute1",
+				"hypervisor_type": "QEMU", "state": "up", "status": "enabled", "vcpus": 32, "vcpus_used": 8,
+				"memory_mb": 131072, "memory_mb_used": 32768, "free_ram_mb": 98304, "local_gb": 1000,
+				"local_gb_used": 200, "free_disk_gb": 800, "disk_available_least": 750,
+				"running_vms": 4, "current_workload": 0}]}`))
+		case "/volume/v3/p1/scheduler-stats/get_pools?detail=true":
+			_, _ = w.Write([]byte(`{"pools": [
+				{"name": "block1@lvm#lvm", "capabilities": {"volume_backend_name": "lvm",
+				"total_capacity_gb": 1000, "free_capacity_gb": 250.5, "allocated_capacity_gb": 700,
+				"provisioned_capacity_gb": 750}},
+				{"name": "ceph@rbd#rbd", "capabilities": {"volume_backend_name": "rbd",
+				"total_capacity_gb": "infinite", "free_capacity_gb": "infinite", "allocated_capacity_gb": 100}}]}`))
+		case "/network/v2.0/agents":
+			_, _ = w.Write([]byte(`{"agents": [{"id": "a1", "agent_type": "L3 agent", "binary": "neutron-l3-agent",
+				"host": "network1", "availability_zone": "nova", "alive": false, "admin_state_up": true}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ts
+}
+
+func TestGather(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	o := &OpenStack{
+		AuthenticationEndpoint:      ts.URL + "/identity/v3",
+		ApplicationCredentialID:     "id1",
+		ApplicationCredentialSecret: "secret",
+		Region:                      "RegionOne",
+		Collect:                     []string{collectHypervisors, collectStoragePools, collectNetworkAgents},
+		Log:                         testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, o.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.True(t, acc.HasFloatField("openstack_identity", "token_latency_ms"))
+	expected := []cua.Metric{
+		testutil.MustMetric("openstack_hypervisor",
+			map[string]string{"region": "RegionOne", "hypervisor": "compute1", "hypervisor_id": "1", "hypervisor_type": "QEMU"},
+			map[string]interface{}{
+				"state":                "up",
+				"status":               "enabled",
+				"up":                   true,
+				"vcpus":                int64(32),
+				"vcpus_used":           int64(8),
+				"memory_mb":            int64(131072),
+				"memory_mb_used":       int64(32768),
+				"free_ram_mb":          int64(98304),
+				"local_gb":             int64(1000),
+				"local_gb_used":        int64(200),
+				"free_disk_gb":         int64(800),
+				"disk_available_least": int64(750),
+				"running_vms":          int64(4),
+				"current_workload":     int64(0),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("openstack_storage_pool",
+			map[string]string{"region": "RegionOne", "pool": "block1@lvm#lvm", "backend": "lvm"},
+			map[string]interface{}{
+				"total_capacity_gb":       float64(1000),
+				"free_capacity_gb":        250.5,
+				"allocated_capacity_gb":   float64(700),
+				"provisioned_capacity_gb": float64(750),
+				"used_percent":            74.95,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("openstack_storage_pool",
+			map[string]string{"region": "RegionOne", "pool": "ceph@rbd#rbd", "backend": "rbd"},
+			map[string]interface{}{
+				"allocated_capacity_gb": float64(100),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("openstack_network_agent",
+			map[string]string{"region": "RegionOne", "agent_type": "L3 agent", "binary": "neutron-l3-agent",
+				"host": "network1", "availability_zone": "nova"},
+			map[string]interface{}{
+				"alive":          false,
+				"admin_state_up": true,
+			},
+			time.Unix(0, 0)),
+	}
+	var actual []cua.Metric
+	for _, m := range acc.GetCUAMetrics() {
+		if m.Name() != "openstack_identity" {
+			actual = append(actual, m)
+		}
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherAuthenticationFailure(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	o := &OpenStack{
+		AuthenticationEndpoint:      ts.URL + "/identity/v3",
+		ApplicationCredentialID:     "id1",
+		ApplicationCredentialSecret: "wrong",
+		Log:                         testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	acc := &testutil.Accumulator{}
+	require.Error(t, o.Gather(acc))
+	require.Empty(t, acc.GetCUAMetrics())
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&OpenStack{}).Init())
+	require.Error(t, (&OpenStack{AuthenticationEndpoint: "https://keystone/v3"}).Init())
+	require.Error(t, (&OpenStack{
+		AuthenticationEndpoint:      "https://keystone/v3",
+		ApplicationCredentialID:     "id1",
+		ApplicationCredentialSecret: "secret",
+		Collect:                     []string{"images"},
+	}).Init())
+}
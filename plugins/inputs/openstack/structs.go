@@ -0,0 +1,92 @@
+package openstack
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type authRequest struct {
+	Auth struct {
+		Identity struct {
+			Methods               []string `json:"methods"`
+			ApplicationCredential struct {
+				ID     string `json:"id"`
+				Secret string `json:"secret"`
+			} `json:"application_credential"`
+		} `json:"identity"`
+	} `json:"auth"`
+}
+
+type authResponse struct {
+	Token struct {
+		Catalog []struct {
+			Type      string `json:"type"`
+			Endpoints []struct {
+				Interface string `json:"interface"`
+				RegionID  string `json:"region_id"`
+				URL       string `json:"url"`
+			} `json:"endpoints"`
+		} `json:"catalog"`
+	} `json:"token"`
+}
+
+type hypervisors struct {
+	Hypervisors []struct {
+		// an integer before compute API microversion 2.53, a uuid after
+		ID                 json.RawMessage `json:"id"`
+		HypervisorHostname string          `json:"hypervisor_hostname"`
+		HypervisorType     string          `json:"hypervisor_type"`
+		State              string          `json:"state"`
+		Status             string          `json:"status"`
+		VCPUs              int64           `json:"vcpus"`
+		VCPUsUsed          int64           `json:"vcpus_used"`
+		MemoryMB           int64           `json:"memory_mb"`
+		MemoryMBUsed       int64           `json:"memory_mb_used"`
+		FreeRAMMB          int64           `json:"free_ram_mb"`
+		LocalGB            int64           `json:"local_gb"`
+		LocalGBUsed        int64           `json:"local_gb_used"`
+		FreeDiskGB         int64           `json:"free_disk_gb"`
+		DiskAvailableLeast int64           `json:"disk_available_least"`
+		RunningVMs         int64           `json:"running_vms"`
+		CurrentWorkload    int64           `json:"current_workload"`
+	} `json:"hypervisors"`
+}
+
+type storagePools struct {
+	Pools []struct {
+		Name         string `json:"name"`
+		Capabilities struct {
+			VolumeBackendName string `json:"volume_backend_name"`
+			// the capacities are numbers, or "infinite" and "unknown"
+			TotalCapacityGB       interface{} `json:"total_capacity_gb"`
+			FreeCapacityGB        interface{} `json:"free_capacity_gb"`
+			AllocatedCapacityGB   interface{} `json:"allocated_capacity_gb"`
+			ProvisionedCapacityGB interface{} `json:"provisioned_capacity_gb"`
+		} `json:"capabilities"`
+	} `json:"pools"`
+}
+
+type networkAgents struct {
+	Agents []struct {
+		ID               string `json:"id"`
+		AgentType        string `json:"agent_type"`
+		Binary           string `json:"binary"`
+		Host             string `json:"host"`
+		AvailabilityZone string `json:"availability_zone"`
+		Alive            bool   `json:"alive"`
+		AdminStateUp     bool   `json:"admin_state_up"`
+	} `json:"agents"`
+}
+
+// rawID returns an id which is either a JSON string or number.
+func rawID(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return fmt.Sprintf("%s", raw)
+}
@@ -0,0 +1,556 @@
+package opentsdblistener
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	tlsint "github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+const (
+	defaultServiceAddress = ":4242"
+	defaultMaxBodySize    = 32 * 1024 * 1024
+	defaultMaxLineSize    = 64 * 1024
+)
+
+const sampleConfig = `
+  ## Address and port to listen on. The telnet style and HTTP APIs are
+  ## served on the same port, as OpenTSDB does.
+  service_address = ":4242"
+
+  ## Maximum number of concurrent telnet connections.
+  ## 0 (default) is unlimited.
+  # max_connections = 0
+
+  ## Read timeout for telnet connections and HTTP requests.
+  ## 0 (default) is unlimited.
+  # read_timeout = "30s"
+
+  ## Maximum allowed HTTP request body size in bytes.
+  # max_body_size = "32MiB"
+
+  ## Maximum length of a telnet line.
+  # max_line_size = "64KiB"
+
+  ## Optional TLS configuration.
+  # tls_cert = "/opt/circonus/unified-agent/etc/cert.pem"
+  # tls_key  = "/opt/circonus/unified-agent/etc/key.pem"
+  ## Enables client authentication if set.
+  # tls_allowed_cacerts = ["/opt/circonus/unified-agent/etc/clientca.pem"]
+`
+
+// OpenTSDBListener accepts data points using the OpenTSDB telnet style
+// "put" command and the HTTP /api/put endpoint.
+type OpenTSDBListener struct {
+	ServiceAddress string             `toml:"service_address"`
+	MaxConnections int                `toml:"max_connections"`
+	ReadTimeout    *internal.Duration `toml:"read_timeout"`
+	MaxBodySize    internal.Size      `toml:"max_body_size"`
+	MaxLineSize    internal.Size      `toml:"max_line_size"`
+	tlsint.ServerConfig
+
+	Log cua.Logger `toml:"-"`
+
+	acc      cua.Accumulator
+	listener net.Listener
+	httpLn   *connListener
+	server   *http.Server
+
+	connections map[net.Conn]struct{}
+	connMu      sync.Mutex
+	wg          sync.WaitGroup
+
+	PointsRecv  selfstat.Stat
+	PointsError selfstat.Stat
+}
+
+// dataPoint is a single OpenTSDB data point as sent to /api/put.
+type dataPoint struct {
+	Metric    string            `json:"metric"`
+	Timestamp json.RawMessage   `json:"timestamp"`
+	Value     json.RawMessage   `json:"value"`
+	Tags      map[string]string `json:"tags"`
+}
+
+type putError struct {
+	Datapoint json.RawMessage `json:"datapoint"`
+	Error     string          `json:"error"`
+}
+
+type putResponse struct {
+	Failed  int        `json:"failed"`
+	Success int        `json:"success"`
+	Errors  []putError `json:"errors,omitempty"`
+}
+
+func (*OpenTSDBListener) Description() string {
+	return "Accept data points using the OpenTSDB telnet and HTTP APIs"
+}
+
+func (*OpenTSDBListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*OpenTSDBListener) Gather(_ cua.Accumulator) error {
+	return nil
+}
+
+func (o *OpenTSDBListener) Start(acc cua.Accumulator) error {
+	o.acc = acc
+
+	if o.MaxBodySize.Size == 0 {
+		o.MaxBodySize.Size = defaultMaxBodySize
+	}
+	if o.MaxLineSize.Size == 0 {
+		o.MaxLineSize.Size = defaultMaxLineSize
+	}
+
+	tags := map[string]string{"address": o.ServiceAddress}
+	o.PointsRecv = selfstat.Register("opentsdb_listener", "points_received", tags)
+	o.PointsError = selfstat.Register("opentsdb_listener", "points_errors", tags)
+
+	tlsConf, err := o.ServerConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+
+	var l net.Listener
+	if tlsConf != nil {
+		l, err = tls.Listen("tcp", o.ServiceAddress, tlsConf)
+	} else {
+		l, err = net.Listen("tcp", o.ServiceAddress)
+	}
+	if err != nil {
+		return fmt.Errorf("listen (%s): %w", o.ServiceAddress, err)
+	}
+	o.listener = l
+	o.connections = make(map[net.Conn]struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/put", o.servePut)
+	mux.HandleFunc("/api/version", o.serveVersion)
+
+	o.httpLn = newConnListener(l.Addr())
+	o.server = &http.Server{
+		Handler: mux,
+	}
+	if o.ReadTimeout != nil {
+		o.server.ReadTimeout = o.ReadTimeout.Duration
+	}
+
+	o.wg.Add(2)
+	go func() {
+		defer o.wg.Done()
+		if err := o.server.Serve(o.httpLn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			o.Log.Errorf("Serve failed: %v", err)
+		}
+	}()
+	go func() {
+		defer o.wg.Done()
+		o.listen()
+	}()
+
+	o.Log.Infof("Listening on %s", l.Addr().String())
+
+	return nil
+}
+
+func (o *OpenTSDBListener) Stop() {
+	if o.listener != nil {
+		o.listener.Close()
+	}
+	if o.server != nil {
+		o.server.Close()
+	}
+
+	o.connMu.Lock()
+	for c := range o.connections {
+		c.Close()
+	}
+	o.connMu.Unlock()
+
+	o.wg.Wait()
+}
+
+// listen accepts connections and dispatches them to the HTTP server or
+// the telnet handler. HTTP requests start with an upper case method while
+// telnet commands are lower case.
+func (o *OpenTSDBListener) listen() {
+	for {
+		c, err := o.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				o.Log.Error(err.Error())
+			}
+			return
+		}
+
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			o.dispatch(c)
+		}()
+	}
+}
+
+func (o *OpenTSDBListener) dispatch(c net.Conn) {
+	o.setDeadline(c)
+	r := bufio.NewReader(c)
+	b, err := r.Peek(1)
+	if err != nil {
+		c.Close()
+		return
+	}
+	bc := &bufferedConn{Conn: c, r: r}
+
+	if b[0] >= 'A' && b[0] <= 'Z' {
+		if !o.httpLn.push(bc) {
+			c.Close()
+		}
+		return
+	}
+
+	o.connMu.Lock()
+	if o.MaxConnections > 0 && len(o.connections) >= o.MaxConnections {
+		o.connMu.Unlock()
+		c.Close()
+		return
+	}
+	o.connections[c] = struct{}{}
+	o.connMu.Unlock()
+
+	o.handleTelnet(bc)
+}
+
+func (o *OpenTSDBListener) setDeadline(c net.Conn) {
+	if o.ReadTimeout != nil && o.ReadTimeout.Duration > 0 {
+		_ = c.SetReadDeadline(time.Now().Add(o.ReadTimeout.Duration))
+	}
+}
+
+func (o *OpenTSDBListener) handleTelnet(c *bufferedConn) {
+	defer func() {
+		o.connMu.Lock()
+		delete(o.connections, c.Conn)
+		o.connMu.Unlock()
+		c.Close()
+	}()
+
+	scanner := bufio.NewScanner(c)
+	scanner.Buffer(make([]byte, 0, 4096), int(o.MaxLineSize.Size))
+
+	for {
+		o.setDeadline(c)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+				o.Log.Debugf("Reading from %s: %s", c.RemoteAddr(), err.Error())
+			}
+			return
+		}
+
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+
+		var resp string
+		switch words[0] {
+		case "put":
+			o.PointsRecv.Incr(1)
+			if err := o.putTelnet(words[1:]); err != nil {
+				o.PointsError.Incr(1)
+				resp = fmt.Sprintf("put: %s\n", err.Error())
+			}
+		case "version":
+			resp = fmt.Sprintf("%s\n", internal.ProductToken())
+		case "help":
+			resp = "available commands: exit help put version\n"
+		case "exit":
+			return
+		default:
+			resp = fmt.Sprintf("unknown command: %s.  Try `help'.\n", words[0])
+		}
+
+		if resp != "" {
+			if _, err := io.WriteString(c, resp); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// putTelnet handles "put <metric> <timestamp> <value> <tagk=tagv> ...".
+func (o *OpenTSDBListener) putTelnet(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("illegal argument: not enough arguments (need at least 4, got %d)", len(args)+1)
+	}
+
+	ts, err := parseTimestamp(args[1])
+	if err != nil {
+		return err
+	}
+	value, err := parseValue(args[2])
+	if err != nil {
+		return err
+	}
+
+	tags := make(map[string]string, len(args)-3)
+	for _, tag := range args[3:] {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return fmt.Errorf("invalid tag: %s", tag)
+		}
+		tags[kv[0]] = kv[1]
+	}
+
+	return o.addPoint(args[0], ts, value, tags)
+}
+
+func (o *OpenTSDBListener) addPoint(metric string, ts time.Time, value float64, tags map[string]string) error {
+	if metric == "" {
+		return fmt.Errorf("empty metric name")
+	}
+	o.acc.AddFields(metric, map[string]interface{}{"value": value}, tags, ts)
+	return nil
+}
+
+func (o *OpenTSDBListener) serveVersion(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(res).Encode(map[string]string{
+		"version": internal.Version(),
+		"product": internal.ProductToken(),
+	})
+}
+
+func (o *OpenTSDBListener) servePut(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.Header().Set("Allow", http.MethodPost)
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.ContentLength > o.MaxBodySize.Size {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := http.MaxBytesReader(res, req.Body, o.MaxBodySize.Size)
+	var r io.Reader = body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		r = io.LimitReader(gz, o.MaxBodySize.Size)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	points, err := decodePoints(data)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := req.URL.Query()
+	_, details := query["details"]
+	_, summary := query["summary"]
+
+	var resp putResponse
+	for _, raw := range points {
+		o.PointsRecv.Incr(1)
+		if err := o.putJSON(raw); err != nil {
+			o.PointsError.Incr(1)
+			resp.Failed++
+			if details {
+				resp.Errors = append(resp.Errors, putError{Datapoint: raw, Error: err.Error()})
+			}
+			continue
+		}
+		resp.Success++
+	}
+
+	status := http.StatusNoContent
+	if resp.Failed > 0 {
+		status = http.StatusBadRequest
+	}
+	if !details && !summary {
+		if resp.Failed > 0 {
+			http.Error(res, fmt.Sprintf("%d data points failed to store", resp.Failed), status)
+			return
+		}
+		res.WriteHeader(status)
+		return
+	}
+
+	if status == http.StatusNoContent {
+		status = http.StatusOK
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	_ = json.NewEncoder(res).Encode(resp)
+}
+
+// decodePoints accepts either a single data point object or an array of them.
+func decodePoints(data []byte) ([]json.RawMessage, error) {
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) == 0 {
+		return nil, fmt.Errorf("missing request content")
+	}
+	if data[0] != '[' {
+		return []json.RawMessage{data}, nil
+	}
+	var points []json.RawMessage
+	if err := json.Unmarshal(data, &points); err != nil {
+		return nil, fmt.Errorf("unable to parse the given JSON: %w", err)
+	}
+	return points, nil
+}
+
+func (o *OpenTSDBListener) putJSON(raw json.RawMessage) error {
+	var dp dataPoint
+	if err := json.Unmarshal(raw, &dp); err != nil {
+		return fmt.Errorf("unable to parse data point: %w", err)
+	}
+
+	ts, err := parseTimestamp(jsonScalar(dp.Timestamp))
+	if err != nil {
+		return err
+	}
+	value, err := parseValue(jsonScalar(dp.Value))
+	if err != nil {
+		return err
+	}
+	return o.addPoint(dp.Metric, ts, value, dp.Tags)
+}
+
+// jsonScalar returns a JSON number or string as a string, OpenTSDB accepts
+// both for timestamps and values.
+func jsonScalar(raw json.RawMessage) string {
+	s := strings.TrimSpace(string(raw))
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// parseTimestamp accepts seconds or milliseconds since the epoch, either as
+// an integer or with a fractional part.
+func parseTimestamp(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+
+	if strings.Contains(s, ".") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f <= 0 {
+			return time.Time{}, fmt.Errorf("invalid timestamp: %s", s)
+		}
+		if f > 1e10 {
+			// milliseconds with fractional part
+			return time.Unix(0, int64(f*1e6)), nil
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).Round(time.Millisecond), nil
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %s", s)
+	}
+	if len(s) > 10 {
+		return time.Unix(0, n*int64(time.Millisecond)), nil
+	}
+	return time.Unix(n, 0), nil
+}
+
+func parseValue(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %s", s)
+	}
+	return v, nil
+}
+
+// bufferedConn is a connection whose first bytes have already been peeked.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// connListener hands connections dispatched by the telnet listener to the
+// HTTP server.
+type connListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *connListener) push(c net.Conn) bool {
+	select {
+	case l.conns <- c:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
+
+func init() {
+	inputs.Add("opentsdb_listener", func() cua.Input {
+		return &OpenTSDBListener{
+			ServiceAddress: defaultServiceAddress,
+		}
+	})
+}
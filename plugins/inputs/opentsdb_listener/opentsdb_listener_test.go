@@ -0,0 +1,153 @@
+package opentsdblistener
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestListener(t *testing.T) (*OpenTSDBListener, *testutil.Accumulator) {
+	o := &OpenTSDBListener{
+		ServiceAddress: "127.0.0.1:0",
+		Log:            testutil.Logger{},
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, o.Start(acc))
+	return o, acc
+}
+
+func TestTelnetPut(t *testing.T) {
+	o, acc := newTestListener(t)
+	defer o.Stop()
+
+	c, err := net.Dial("tcp", o.listener.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	fmt.Fprint(c, "put sys.cpu.user 1356998400 42.5 host=webserver01 cpu=0\n")
+	fmt.Fprint(c, "put sys.cpu.user 1356998400500 1 host=webserver01\n")
+
+	fmt.Fprint(c, "put sys.cpu.user 1356998400\n")
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	require.Contains(t, line, "put: illegal argument")
+
+	fmt.Fprint(c, "bogus\n")
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "unknown command: bogus.  Try `help'.\n", line)
+
+	fmt.Fprint(c, "version\n")
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	require.Contains(t, line, "circonus-unified-agent")
+
+	acc.Wait(2)
+	expected := []cua.Metric{
+		testutil.MustMetric("sys.cpu.user",
+			map[string]string{"host": "webserver01", "cpu": "0"},
+			map[string]interface{}{"value": 42.5},
+			time.Unix(1356998400, 0)),
+		testutil.MustMetric("sys.cpu.user",
+			map[string]string{"host": "webserver01"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1356998400, 500*int64(time.Millisecond))),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
+
+	fmt.Fprint(c, "exit\n")
+	_, err = r.ReadString('\n')
+	require.Equal(t, io.EOF, err)
+}
+
+func TestHTTPPut(t *testing.T) {
+	o, acc := newTestListener(t)
+	defer o.Stop()
+
+	url := "http://" + o.listener.Addr().String() + "/api/put"
+
+	single := `{"metric":"sys.cpu.nice","timestamp":1346846400,"value":18,"tags":{"host":"web01"}}`
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(single))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err = gz.Write([]byte(`[
+		{"metric":"sys.cpu.nice","timestamp":1346846400000,"value":"9.5","tags":{"host":"web02"}},
+		{"metric":"sys.cpu.nice","timestamp":1346846400,"value":"NaNish","tags":{"host":"web03"}}
+	]`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req, err := http.NewRequest(http.MethodPost, url+"?details", &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var details putResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&details))
+	require.Equal(t, 1, details.Success)
+	require.Equal(t, 1, details.Failed)
+	require.Len(t, details.Errors, 1)
+	require.Equal(t, "invalid value: NaNish", details.Errors[0].Error)
+
+	acc.Wait(2)
+	expected := []cua.Metric{
+		testutil.MustMetric("sys.cpu.nice",
+			map[string]string{"host": "web01"},
+			map[string]interface{}{"value": 18.0},
+			time.Unix(1346846400, 0)),
+		testutil.MustMetric("sys.cpu.nice",
+			map[string]string{"host": "web02"},
+			map[string]interface{}{"value": 9.5},
+			time.Unix(1346846400, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
+
+	resp, err = http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Time
+		err      bool
+	}{
+		{in: "1356998400", expected: time.Unix(1356998400, 0)},
+		{in: "1356998400123", expected: time.Unix(1356998400, 123*int64(time.Millisecond))},
+		{in: "1356998400.123", expected: time.Unix(1356998400, 123*int64(time.Millisecond))},
+		{in: "", err: true},
+		{in: "-1", err: true},
+		{in: "now", err: true},
+	}
+
+	for _, tt := range tests {
+		ts, err := parseTimestamp(tt.in)
+		if tt.err {
+			require.Error(t, err, tt.in)
+			continue
+		}
+		require.NoError(t, err, tt.in)
+		require.True(t, tt.expected.Equal(ts), "%s: %v != %v", tt.in, tt.expected, ts)
+	}
+}
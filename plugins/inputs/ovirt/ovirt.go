@@ -0,0 +1,261 @@
+package ovirt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+var sampleConfig = `
+  ## URL of the API of the oVirt or RHV engine.
+  url = "https://ovirt-engine.local/ovirt-engine/api"
+  username = "admin@internal"
+  password = "secret"
+
+  ## Collections:
+  ##   hosts:           status and statistics of the hosts
+  ##   vms:             status and statistics of the virtual machines
+  ##   storage_domains: status and usage of the storage domains
+  # collect = ["hosts", "vms", "storage_domains"]
+
+  ## HTTP response timeout.
+  # response_timeout = "30s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/circonus-unified-agent/ca.pem"
+  # tls_cert = "/etc/circonus-unified-agent/cert.pem"
+  # tls_key = "/etc/circonus-unified-agent/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	collectHosts          = "hosts"
+	collectVMs            = "vms"
+	collectStorageDomains = "storage_domains"
+)
+
+type Ovirt struct {
+	URL             string            `toml:"url"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	Collect         []string          `toml:"collect"`
+	ResponseTimeout internal.Duration `toml:"response_timeout"`
+	tls.ClientConfig
+
+	Log cua.Logger `toml:"-"`
+
+	client *http.Client
+	engine string
+}
+
+func (o *Ovirt) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *Ovirt) Description() string {
+	return "Gather the hosts, virtual machines and storage domains metrics of an oVirt or RHV engine"
+}
+
+func (o *Ovirt) Init() error {
+	if o.URL == "" {
+		return errors.New("no url configured")
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return fmt.Errorf("parse url (%s): %w", o.URL, err)
+	}
+	o.engine = u.Host
+	for _, c := range o.Collect {
+		if c != collectHosts && c != collectVMs && c != collectStorageDomains {
+			return fmt.Errorf("unknown collection %q, expected %s, %s or %s", c, collectHosts, collectVMs, collectStorageDomains)
+		}
+	}
+
+	tlsCfg, err := o.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+	o.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: o.ResponseTimeout.Duration,
+	}
+	return nil
+}
+
+func (o *Ovirt) collects(c string) bool {
+	for _, collect := range o.Collect {
+		if collect == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Ovirt) Gather(acc cua.Accumulator) error {
+	if !o.collects(collectHosts) && !o.collects(collectVMs) {
+		if o.collects(collectStorageDomains) {
+			return o.gatherStorageDomains(acc)
+		}
+		return nil
+	}
+
+	// the hosts and the virtual machines are tagged with the name of their
+	// cluster
+	var clusters clusterList
+	if err := o.get("/clusters", &clusters); err != nil {
+		return err
+	}
+	clusterNames := make(map[string]string, len(clusters.Cluster))
+	for _, c := range clusters.Cluster {
+		clusterNames[c.ID] = c.Name
+	}
+
+	var hosts hostList
+	if err := o.get("/hosts?follow=statistics", &hosts); err != nil {
+		return err
+	}
+	hostNames := make(map[string]string, len(hosts.Host))
+	for _, h := range hosts.Host {
+		hostNames[h.ID] = h.Name
+	}
+
+	if o.collects(collectHosts) {
+		for _, h := range hosts.Host {
+			fields := statisticFields(h.Statistics)
+			fields["status"] = h.Status
+			fields["up"] = h.Status == "up"
+			acc.AddFields("ovirt_host", fields, map[string]string{
+				"engine":  o.engine,
+				"cluster": clusterNames[h.Cluster.ID],
+				"host":    h.Name,
+				"host_id": h.ID,
+			})
+		}
+	}
+
+	if o.collects(collectVMs) {
+		var vms vmList
+		if err := o.get("/vms?follow=statistics", &vms); err != nil {
+			acc.AddError(err)
+		}
+		for _, vm := range vms.VM {
+			fields := statisticFields(vm.Statistics)
+			fields["status"] = vm.Status
+			fields["up"] = vm.Status == "up"
+			tags := map[string]string{
+				"engine":  o.engine,
+				"cluster": clusterNames[vm.Cluster.ID],
+				"vm":      vm.Name,
+				"vm_id":   vm.ID,
+			}
+			// a virtual machine which is down runs on no host
+			if host, ok := hostNames[vm.Host.ID]; ok {
+				tags["host"] = host
+			}
+			acc.AddFields("ovirt_vm", fields, tags)
+		}
+	}
+
+	if o.collects(collectStorageDomains) {
+		if err := o.gatherStorageDomains(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func (o *Ovirt) gatherStorageDomains(acc cua.Accumulator) error {
+	var domains storageDomainList
+	if err := o.get("/storagedomains", &domains); err != nil {
+		return err
+	}
+	for _, d := range domains.StorageDomain {
+		available, _ := d.Available.Int64()
+		used, _ := d.Used.Int64()
+		committed, _ := d.Committed.Int64()
+		fields := map[string]interface{}{
+			"available": available,
+			"used":      used,
+			"committed": committed,
+		}
+		if d.ExternalStatus != "" {
+			fields["external_status"] = d.ExternalStatus
+		}
+		if total := available + used; total > 0 {
+			fields["used_percent"] = 100 * float64(used) / float64(total)
+		}
+		acc.AddFields("ovirt_storage_domain", fields, map[string]string{
+			"engine":         o.engine,
+			"storage_domain": d.Name,
+			"type":           d.Type,
+		})
+	}
+	return nil
+}
+
+// statisticFields returns a field per statistic, named after the statistic
+// with the dots replaced by underscores, such as memory_used.
+func statisticFields(stats statistics) map[string]interface{} {
+	fields := make(map[string]interface{}, len(stats.Statistic)+2)
+	for _, s := range stats.Statistic {
+		if len(s.Values.Value) == 0 {
+			continue
+		}
+		name := strings.ReplaceAll(s.Name, ".", "_")
+		datum := s.Values.Value[0].Datum
+		if i, err := datum.Int64(); err == nil {
+			fields[name] = i
+		} else if f, err := datum.Float64(); err == nil {
+			fields[name] = f
+		}
+	}
+	return fields
+}
+
+func (o *Ovirt) get(uri string, v interface{}) error {
+	u := strings.TrimSuffix(o.URL, "/") + uri
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("http new req (%s): %w", u, err)
+	}
+	req.SetBasicAuth(o.Username, o.Password)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Version", "4")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned HTTP status %s: %s", uri, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("json decode (%s): %w", uri, err)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("ovirt", func() cua.Input {
+		return &Ovirt{
+			Collect:         []string{collectHosts, collectVMs, collectStorageDomains},
+			ResponseTimeout: internal.Duration{Duration: 30 * time.Second},
+		}
+	})
+}
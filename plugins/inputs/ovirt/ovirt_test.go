@@ -0,0 +1,122 @@
+package ovirt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var responses = map[string]string{
+	"/ovirt-engine/api/clusters": `{"cluster": [{"id": "c1", "name": "Default"}]}`,
+	"/ovirt-engine/api/hosts?follow=statistics": `{"host": [{"id": "h1", "name": "host1", "status": "up",
+		"cluster": {"id": "c1"}, "statistics": {"statistic": [
+		{"name": "memory.total", "values": {"value": [{"datum": 68719476736}]}},
+		{"name": "cpu.current.user", "values": {"value": [{"datum": "12.5"}]}},
+		{"name": "cpu.load.avg.5m", "values": {"value": []}}]}}]}`,
+	"/ovirt-engine/api/vms?follow=statistics": `{"vm": [
+		{"id": "v1", "name": "vm1", "status": "up", "cluster": {"id": "c1"}, "host": {"id": "h1"},
+		"statistics": {"statistic": [{"name": "memory.used", "values": {"value": [{"datum": 1073741824}]}}]}},
+		{"id": "v2", "name": "vm2", "status": "down", "cluster": {"id": "c1"}, "statistics": {}}]}`,
+	"/ovirt-engine/api/storagedomains": `{"storage_domain": [{"id": "s1", "name": "data1", "type": "data",
+		"external_status": "ok", "available": "750", "used": "250", "committed": "500"}]}`,
+}
+
+func TestGather(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "admin@internal" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "application/json", r.Header.Get("Accept"))
+		body, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	o := &Ovirt{
+		URL:      ts.URL + "/ovirt-engine/api",
+		Username: "admin@internal",
+		Password: "secret",
+		Collect:  []string{collectHosts, collectVMs, collectStorageDomains},
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, o.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	engine := ts.Listener.Addr().String()
+	expected := []cua.Metric{
+		testutil.MustMetric("ovirt_host",
+			map[string]string{"engine": engine, "cluster": "Default", "host": "host1", "host_id": "h1"},
+			map[string]interface{}{
+				"status":           "up",
+				"up":               true,
+				"memory_total":     int64(68719476736),
+				"cpu_current_user": float64(12.5),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ovirt_vm",
+			map[string]string{"engine": engine, "cluster": "Default", "host": "host1", "vm": "vm1", "vm_id": "v1"},
+			map[string]interface{}{
+				"status":      "up",
+				"up":          true,
+				"memory_used": int64(1073741824),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ovirt_vm",
+			map[string]string{"engine": engine, "cluster": "Default", "vm": "vm2", "vm_id": "v2"},
+			map[string]interface{}{
+				"status": "down",
+				"up":     false,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ovirt_storage_domain",
+			map[string]string{"engine": engine, "storage_domain": "data1", "type": "data"},
+			map[string]interface{}{
+				"available":       int64(750),
+				"used":            int64(250),
+				"committed":       int64(500),
+				"used_percent":    float64(25),
+				"external_status": "ok",
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	o := &Ovirt{
+		URL:     ts.URL + "/ovirt-engine/api",
+		Collect: []string{collectHosts},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	acc := &testutil.Accumulator{}
+	require.Error(t, o.Gather(acc))
+}
+
+func TestInitInvalid(t *testing.T) {
+	o := &Ovirt{}
+	require.Error(t, o.Init())
+
+	o = &Ovirt{URL: "https://engine/ovirt-engine/api", Collect: []string{"networks"}}
+	require.Error(t, o.Init())
+}
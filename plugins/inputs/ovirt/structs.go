@@ -0,0 +1,62 @@
+package ovirt
+
+import "encoding/json"
+
+// The JSON bodies of the oVirt engine API v4, lists are wrapped in an
+// object named by the type of their elements.  The numbers are decoded as
+// json.Number, the engine renders some of them as strings.
+
+type ref struct {
+	ID string `json:"id"`
+}
+
+type statistics struct {
+	Statistic []struct {
+		Name   string `json:"name"`
+		Values struct {
+			Value []struct {
+				Datum json.Number `json:"datum"`
+			} `json:"value"`
+		} `json:"values"`
+	} `json:"statistic"`
+}
+
+type clusterList struct {
+	Cluster []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"cluster"`
+}
+
+type hostList struct {
+	Host []struct {
+		ID         string     `json:"id"`
+		Name       string     `json:"name"`
+		Status     string     `json:"status"`
+		Cluster    ref        `json:"cluster"`
+		Statistics statistics `json:"statistics"`
+	} `json:"host"`
+}
+
+type vmList struct {
+	VM []struct {
+		ID         string     `json:"id"`
+		Name       string     `json:"name"`
+		Status     string     `json:"status"`
+		Cluster    ref        `json:"cluster"`
+		Host       ref        `json:"host"`
+		Statistics statistics `json:"statistics"`
+	} `json:"vm"`
+}
+
+type storageDomainList struct {
+	StorageDomain []struct {
+		ID             string      `json:"id"`
+		Name           string      `json:"name"`
+		Type           string      `json:"type"`
+		ExternalStatus string      `json:"external_status"`
+		Available      json.Number `json:"available"`
+		Used           json.Number `json:"used"`
+		Committed      json.Number `json:"committed"`
+	} `json:"storage_domain"`
+}
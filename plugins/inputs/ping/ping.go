@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"net"
 	"os/exec"
@@ -70,6 +69,8 @@ type Ping struct {
 
 	// listenAddr is the address associated with the interface defined.
 	listenAddr string
+
+	Log cua.Logger `toml:"-"`
 }
 
 func (*Ping) Description() string {
@@ -348,7 +349,7 @@ finish:
 	r.Wait()
 
 	if doErr != nil && strings.Contains(doErr.Error(), "not permitted") {
-		log.Printf("D! [inputs.ping] %s", doErr.Error())
+		p.Log.Debugf("%s", doErr.Error())
 	}
 
 	tags, fields := onFin(packetsSent, rsps, doErr, destination)
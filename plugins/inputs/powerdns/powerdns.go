@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"strconv"
 	"strings"
@@ -17,6 +16,8 @@ import (
 
 type Powerdns struct {
 	UnixSockets []string
+
+	Log cua.Logger `toml:"-"`
 }
 
 var sampleConfig = `
@@ -88,7 +89,7 @@ func (p *Powerdns) gatherServer(address string, acc cua.Accumulator) error {
 	metrics := string(buf)
 
 	// Process data
-	fields := parseResponse(metrics)
+	fields := parseResponse(p.Log, metrics)
 
 	// Add server socket as a tag
 	tags := map[string]string{"server": address}
@@ -98,7 +99,7 @@ func (p *Powerdns) gatherServer(address string, acc cua.Accumulator) error {
 	return nil
 }
 
-func parseResponse(metrics string) map[string]interface{} {
+func parseResponse(log cua.Logger, metrics string) map[string]interface{} {
 	values := make(map[string]interface{})
 
 	s := strings.Split(metrics, ",")
@@ -111,7 +112,7 @@ func parseResponse(metrics string) map[string]interface{} {
 
 		i, err := strconv.ParseInt(m[1], 10, 64)
 		if err != nil {
-			log.Printf("E! [inputs.powerdns] error parsing integer for metric %q: %s",
+			log.Errorf("error parsing integer for metric %q: %s",
 				metric, err.Error())
 			continue
 		}
@@ -85,6 +85,7 @@ func TestPowerdnsGeneratesMetrics(t *testing.T) {
 	go s.serverSocket(socket)
 
 	p := &Powerdns{
+		Log:         testutil.Logger{},
 		UnixSockets: []string{fmt.Sprintf("/tmp/pdns%d.controlsocket", randomNumber)},
 	}
 
@@ -109,7 +110,7 @@ func TestPowerdnsGeneratesMetrics(t *testing.T) {
 }
 
 func TestPowerdnsParseMetrics(t *testing.T) {
-	values := parseResponse(metrics)
+	values := parseResponse(testutil.Logger{}, metrics)
 
 	tests := []struct {
 		key   string
@@ -169,7 +170,7 @@ func TestPowerdnsParseMetrics(t *testing.T) {
 }
 
 func TestPowerdnsParseCorruptMetrics(t *testing.T) {
-	values := parseResponse(corruptMetrics)
+	values := parseResponse(testutil.Logger{}, corruptMetrics)
 
 	tests := []struct {
 		key   string
@@ -228,7 +229,7 @@ func TestPowerdnsParseCorruptMetrics(t *testing.T) {
 }
 
 func TestPowerdnsParseIntOverflowMetrics(t *testing.T) {
-	values := parseResponse(intOverflowMetrics)
+	values := parseResponse(testutil.Logger{}, intOverflowMetrics)
 
 	tests := []struct {
 		key   string
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"net"
 	"os"
@@ -22,6 +21,8 @@ type PowerdnsRecursor struct {
 	SocketDir   string   `toml:"socket_dir"`
 	SocketMode  string   `toml:"socket_mode"`
 
+	Log cua.Logger `toml:"-"`
+
 	mode uint32
 }
 
@@ -123,7 +124,7 @@ func (p *PowerdnsRecursor) gatherServer(address string, acc cua.Accumulator) err
 	metrics := string(buf)
 
 	// Process data
-	fields := parseResponse(metrics)
+	fields := parseResponse(p.Log, metrics)
 
 	// Add server socket as a tag
 	tags := map[string]string{"server": address}
@@ -135,7 +136,7 @@ func (p *PowerdnsRecursor) gatherServer(address string, acc cua.Accumulator) err
 	return nil
 }
 
-func parseResponse(metrics string) map[string]interface{} {
+func parseResponse(log cua.Logger, metrics string) map[string]interface{} {
 	values := make(map[string]interface{})
 
 	s := strings.Split(metrics, "\n")
@@ -148,7 +149,7 @@ func parseResponse(metrics string) map[string]interface{} {
 
 		i, err := strconv.ParseInt(m[1], 10, 64)
 		if err != nil {
-			log.Printf("E! [inputs.powerdns_recursor] error parsing integer for metric %q: %s",
+			log.Errorf("error parsing integer for metric %q: %s",
 				metric, err.Error())
 			continue
 		}
@@ -141,6 +141,7 @@ func TestPowerdnsRecursorGeneratesMetrics(t *testing.T) {
 	}()
 
 	p := &PowerdnsRecursor{
+		Log:         testutil.Logger{},
 		UnixSockets: []string{controlSocket},
 		SocketDir:   "/tmp",
 		SocketMode:  "0666",
@@ -185,7 +186,7 @@ func TestPowerdnsRecursorGeneratesMetrics(t *testing.T) {
 }
 
 func TestPowerdnsRecursorParseMetrics(t *testing.T) {
-	values := parseResponse(metrics)
+	values := parseResponse(testutil.Logger{}, metrics)
 
 	tests := []struct {
 		key   string
@@ -311,7 +312,7 @@ func TestPowerdnsRecursorParseMetrics(t *testing.T) {
 }
 
 func TestPowerdnsRecursorParseCorruptMetrics(t *testing.T) {
-	values := parseResponse(corruptMetrics)
+	values := parseResponse(testutil.Logger{}, corruptMetrics)
 
 	tests := []struct {
 		key   string
@@ -436,7 +437,7 @@ func TestPowerdnsRecursorParseCorruptMetrics(t *testing.T) {
 }
 
 func TestPowerdnsRecursorParseIntOverflowMetrics(t *testing.T) {
-	values := parseResponse(intOverflowMetrics)
+	values := parseResponse(testutil.Logger{}, intOverflowMetrics)
 
 	tests := []struct {
 		key   string
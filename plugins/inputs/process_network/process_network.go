@@ -0,0 +1,304 @@
+//go:build linux
+// +build linux
+
+package processnetwork
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const (
+	defaultHostProc = "/proc"
+	envProc         = "HOST_PROC"
+
+	methodSockDiag = "sock_diag"
+	methodNetns    = "netns"
+
+	groupByProcess = "process"
+	groupByPID     = "pid"
+	groupByCgroup  = "cgroup"
+)
+
+type ProcessNetwork struct {
+	Method           string     `toml:"method"`
+	GroupBy          string     `toml:"group_by"`
+	Processes        []string   `toml:"processes"`
+	IncludeHostNetns bool       `toml:"include_host_netns"`
+	HostProc         string     `toml:"host_proc"`
+	Log              cua.Logger `toml:"-"`
+
+	filter      filter.Filter
+	listSockets func() ([]tcpSocket, error)
+
+	// counters of the sockets at the previous collection
+	sockets map[socketKey]tcpSocket
+	// bytes attributed to the groups since the first collection
+	totals   map[groupKey]*groupTotals
+	gathered bool
+}
+
+type socketKey struct {
+	inode  uint32
+	cookie uint64
+}
+
+type groupKey struct {
+	name   string
+	pid    int
+	cgroup string
+}
+
+type groupTotals struct {
+	sent     uint64
+	received uint64
+}
+
+const sampleConfig = `
+  ## Attribution method:
+  ##   "sock_diag": attribute the bytes of the TCP connections to the
+  ##                processes owning them, the bytes of the connections
+  ##                opened and closed between two collections are missed.
+  ##   "netns":     report the interface counters of each network namespace,
+  ##                with the processes running in it; accurate for
+  ##                containers and cheaper, but does not tell apart the
+  ##                processes sharing a namespace.
+  # method = "sock_diag"
+
+  ## Grouping of the processes with the sock_diag method, "process" for the
+  ## process name, "pid" for each process or "cgroup".
+  # group_by = "process"
+
+  ## Process names to report, as glob patterns, all the processes when
+  ## empty.
+  # processes = []
+
+  ## Report the host network namespace with the netns method.
+  # include_host_netns = false
+
+  ## Path of the proc file system, the HOST_PROC environment variable is
+  ## used when empty, and "/proc" when unset.
+  # host_proc = ""
+`
+
+func (p *ProcessNetwork) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *ProcessNetwork) Description() string {
+	return "Attribute the network traffic to the processes, cgroups or network namespaces"
+}
+
+func (p *ProcessNetwork) Init() error {
+	switch p.Method {
+	case "":
+		p.Method = methodSockDiag
+	case methodSockDiag, methodNetns:
+	default:
+		return fmt.Errorf("unknown method %q", p.Method)
+	}
+
+	switch p.GroupBy {
+	case "":
+		p.GroupBy = groupByProcess
+	case groupByProcess, groupByPID, groupByCgroup:
+	default:
+		return fmt.Errorf("unknown group_by %q", p.GroupBy)
+	}
+
+	if p.HostProc == "" {
+		p.HostProc = os.Getenv(envProc)
+	}
+	if p.HostProc == "" {
+		p.HostProc = defaultHostProc
+	}
+
+	if len(p.Processes) > 0 {
+		var err error
+		if p.filter, err = filter.Compile(p.Processes); err != nil {
+			return fmt.Errorf("compiling processes filter: %w", err)
+		}
+	}
+
+	if p.listSockets == nil {
+		p.listSockets = listTCPSockets
+	}
+	p.sockets = make(map[socketKey]tcpSocket)
+	p.totals = make(map[groupKey]*groupTotals)
+	return nil
+}
+
+func (p *ProcessNetwork) Gather(acc cua.Accumulator) error {
+	if p.Method == methodNetns {
+		return p.gatherNetns(acc)
+	}
+	return p.gatherSockDiag(acc)
+}
+
+func (p *ProcessNetwork) match(name string) bool {
+	return p.filter == nil || p.filter.Match(name)
+}
+
+// gatherSockDiag attributes the bytes transferred by the TCP sockets since
+// the previous collection to the groups of their processes.
+func (p *ProcessNetwork) gatherSockDiag(acc cua.Accumulator) error {
+	sockets, err := p.listSockets()
+	if err != nil {
+		return fmt.Errorf("listing sockets: %w", err)
+	}
+	owners, err := socketOwners(p.HostProc)
+	if err != nil {
+		return err
+	}
+
+	procs := make(map[int]*procInfo)
+	connections := make(map[groupKey]int64)
+	current := make(map[socketKey]tcpSocket, len(sockets))
+	for _, s := range sockets {
+		key := socketKey{inode: s.inode, cookie: s.cookie}
+		current[key] = s
+
+		pid, ok := owners[s.inode]
+		if !ok {
+			continue
+		}
+		info, ok := procs[pid]
+		if !ok {
+			info = readProcInfo(p.HostProc, pid)
+			procs[pid] = info
+		}
+		if info == nil || !p.match(info.name) {
+			continue
+		}
+
+		group := p.groupKey(pid, info)
+		connections[group]++
+		totals, ok := p.totals[group]
+		if !ok {
+			totals = &groupTotals{}
+			p.totals[group] = totals
+		}
+
+		// the bytes of the sockets open at the first collection were
+		// transferred before, the new sockets were opened since
+		prev, seen := p.sockets[key]
+		switch {
+		case seen:
+			if s.bytesSent >= prev.bytesSent {
+				totals.sent += s.bytesSent - prev.bytesSent
+			}
+			if s.bytesReceived >= prev.bytesReceived {
+				totals.received += s.bytesReceived - prev.bytesReceived
+			}
+		case p.gathered:
+			totals.sent += s.bytesSent
+			totals.received += s.bytesReceived
+		}
+	}
+	p.sockets = current
+	p.gathered = true
+
+	for group, totals := range p.totals {
+		// the groups without connections are forgotten
+		if connections[group] == 0 {
+			delete(p.totals, group)
+			continue
+		}
+		tags := map[string]string{}
+		switch p.GroupBy {
+		case groupByProcess:
+			tags["process_name"] = group.name
+		case groupByPID:
+			tags["process_name"] = group.name
+			tags["pid"] = strconv.Itoa(group.pid)
+		case groupByCgroup:
+			tags["cgroup"] = group.cgroup
+		}
+		acc.AddCounter("process_network", map[string]interface{}{
+			"tcp_bytes_sent":     totals.sent,
+			"tcp_bytes_received": totals.received,
+			"tcp_connections":    connections[group],
+		}, tags)
+	}
+	return nil
+}
+
+func (p *ProcessNetwork) groupKey(pid int, info *procInfo) groupKey {
+	switch p.GroupBy {
+	case groupByPID:
+		return groupKey{name: info.name, pid: pid}
+	case groupByCgroup:
+		return groupKey{cgroup: info.cgroup}
+	default:
+		return groupKey{name: info.name}
+	}
+}
+
+// gatherNetns reports the interface counters of the network namespaces.
+func (p *ProcessNetwork) gatherNetns(acc cua.Accumulator) error {
+	namespaces, err := netNamespaces(p.HostProc)
+	if err != nil {
+		return err
+	}
+	hostNetns, _ := readNetns(p.HostProc, 1)
+
+	for netns, pids := range namespaces {
+		if netns == hostNetns && !p.IncludeHostNetns {
+			continue
+		}
+
+		// the namespace is reported with its first process, usually the
+		// init process of the container
+		var first *procInfo
+		firstPID := 0
+		matched := false
+		for _, pid := range pids {
+			info := readProcInfo(p.HostProc, pid)
+			if info == nil {
+				continue
+			}
+			if first == nil || pid < firstPID {
+				first, firstPID = info, pid
+			}
+			matched = matched || p.match(info.name)
+		}
+		if first == nil || !matched {
+			continue
+		}
+
+		stats, err := readNetDev(p.HostProc, firstPID)
+		if errors.Is(err, os.ErrNotExist) {
+			// the process exited
+			continue
+		}
+		if err != nil {
+			acc.AddError(fmt.Errorf("netns %s: %w", netns, err))
+			continue
+		}
+
+		acc.AddCounter("process_network", map[string]interface{}{
+			"bytes_sent":       stats.bytesSent,
+			"bytes_received":   stats.bytesReceived,
+			"packets_sent":     stats.packetsSent,
+			"packets_received": stats.packetsReceived,
+			"processes":        int64(len(pids)),
+		}, map[string]string{
+			"netns":        netns,
+			"process_name": first.name,
+			"cgroup":       first.cgroup,
+		})
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("process_network", func() cua.Input {
+		return &ProcessNetwork{}
+	})
+}
@@ -0,0 +1,222 @@
+// +build linux
+
+package processnetwork
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+)
+
+type fakeProcess struct {
+	pid     int
+	name    string
+	cgroup  string
+	netns   string
+	sockets []uint32
+	netDev  string
+}
+
+func procTree(t *testing.T, procs []fakeProcess) string {
+	root := t.TempDir()
+	for _, p := range procs {
+		dir := filepath.Join(root, strconv.Itoa(p.pid))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "fd"), 0755))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "ns"), 0755))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "net"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "comm"), []byte(p.name+"\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup"), []byte("0::"+p.cgroup+"\n"), 0644))
+		require.NoError(t, os.Symlink("net:["+p.netns+"]", filepath.Join(dir, "ns", "net")))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "net", "dev"), []byte(p.netDev), 0644))
+		require.NoError(t, os.Symlink("/dev/null", filepath.Join(dir, "fd", "0")))
+		for i, inode := range p.sockets {
+			link := "socket:[" + strconv.Itoa(int(inode)) + "]"
+			require.NoError(t, os.Symlink(link, filepath.Join(dir, "fd", strconv.Itoa(i+3))))
+		}
+	}
+	return root
+}
+
+const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:   50000     500    0    0    0     0          0         0    50000     500    0    0    0     0       0          0
+  eth0:   12000     100    0    0    0     0          0         0     3000      40    0    0    0     0       0          0
+  eth1:    1000      10    0    0    0     0          0         0      500       5    0    0    0     0       0          0
+`
+
+func TestGatherSockDiag(t *testing.T) {
+	root := procTree(t, []fakeProcess{
+		{pid: 10, name: "nginx", cgroup: "/system.slice/nginx.service", netns: "1", sockets: []uint32{100, 101}},
+		{pid: 11, name: "nginx", cgroup: "/system.slice/nginx.service", netns: "1", sockets: []uint32{102}},
+		{pid: 20, name: "postgres", cgroup: "/system.slice/postgresql.service", netns: "1", sockets: []uint32{200}},
+	})
+
+	sockets := []tcpSocket{
+		{inode: 100, cookie: 1, bytesSent: 1000, bytesReceived: 100},
+		{inode: 101, cookie: 2, bytesSent: 2000, bytesReceived: 200},
+		{inode: 200, cookie: 3, bytesSent: 5000, bytesReceived: 500},
+		// not owned by a process of the tree
+		{inode: 900, cookie: 4, bytesSent: 9000, bytesReceived: 900},
+	}
+	p := &ProcessNetwork{
+		HostProc:    root,
+		Log:         testutil.Logger{},
+		listSockets: func() ([]tcpSocket, error) { return sockets, nil },
+	}
+	require.NoError(t, p.Init())
+
+	// the first collection sets the baseline of the open sockets
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+
+	sockets = []tcpSocket{
+		{inode: 100, cookie: 1, bytesSent: 1500, bytesReceived: 150},
+		// socket 101 closed, socket 102 opened
+		{inode: 102, cookie: 5, bytesSent: 300, bytesReceived: 30},
+		{inode: 200, cookie: 3, bytesSent: 5000, bytesReceived: 800},
+	}
+	acc.ClearMetrics()
+	require.NoError(t, p.Gather(&acc))
+
+	expected := []cua.Metric{
+		testutil.MustMetric("process_network",
+			map[string]string{"process_name": "nginx"},
+			map[string]interface{}{
+				"tcp_bytes_sent":     uint64(800),
+				"tcp_bytes_received": uint64(80),
+				"tcp_connections":    int64(2),
+			},
+			time.Unix(0, 0), cua.Counter),
+		testutil.MustMetric("process_network",
+			map[string]string{"process_name": "postgres"},
+			map[string]interface{}{
+				"tcp_bytes_sent":     uint64(0),
+				"tcp_bytes_received": uint64(300),
+				"tcp_connections":    int64(1),
+			},
+			time.Unix(0, 0), cua.Counter),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherSockDiagGroupByCgroup(t *testing.T) {
+	root := procTree(t, []fakeProcess{
+		{pid: 10, name: "nginx", cgroup: "/kubepods/pod1", netns: "1", sockets: []uint32{100}},
+		{pid: 11, name: "sidecar", cgroup: "/kubepods/pod1", netns: "1", sockets: []uint32{101}},
+		{pid: 20, name: "postgres", cgroup: "/kubepods/pod2", netns: "1", sockets: []uint32{200}},
+	})
+
+	p := &ProcessNetwork{
+		HostProc:  root,
+		GroupBy:   "cgroup",
+		Processes: []string{"nginx", "side*"},
+		Log:       testutil.Logger{},
+		listSockets: func() ([]tcpSocket, error) {
+			return []tcpSocket{{inode: 100}, {inode: 101}, {inode: 200}}, nil
+		},
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Equal(t, uint64(1), acc.NMetrics())
+	require.True(t, acc.HasTag("process_network", "cgroup"))
+	value, ok := acc.Get("process_network")
+	require.True(t, ok)
+	require.Equal(t, "/kubepods/pod1", value.Tags["cgroup"])
+	require.Equal(t, int64(2), value.Fields["tcp_connections"])
+}
+
+func TestGatherNetns(t *testing.T) {
+	root := procTree(t, []fakeProcess{
+		{pid: 1, name: "systemd", cgroup: "/init.scope", netns: "4026531992", netDev: netDev},
+		{pid: 300, name: "pause", cgroup: "/kubepods/pod1", netns: "4026532500", netDev: netDev},
+		{pid: 301, name: "nginx", cgroup: "/kubepods/pod1/nginx", netns: "4026532500", netDev: netDev},
+	})
+
+	p := &ProcessNetwork{
+		HostProc: root,
+		Method:   "netns",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+
+	expected := []cua.Metric{
+		testutil.MustMetric("process_network",
+			map[string]string{"netns": "4026532500", "process_name": "pause", "cgroup": "/kubepods/pod1"},
+			map[string]interface{}{
+				"bytes_received":   uint64(13000),
+				"packets_received": uint64(110),
+				"bytes_sent":       uint64(3500),
+				"packets_sent":     uint64(45),
+				"processes":        int64(2),
+			},
+			time.Unix(0, 0), cua.Counter),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime())
+
+	p.IncludeHostNetns = true
+	acc.ClearMetrics()
+	require.NoError(t, p.Gather(&acc))
+	require.Equal(t, uint64(2), acc.NMetrics())
+}
+
+func TestParseCgroup(t *testing.T) {
+	require.Equal(t, "/user.slice", parseCgroup([]byte("0::/user.slice\n")))
+	require.Equal(t, "/docker/abc", parseCgroup([]byte("12:cpu,cpuacct:/docker/abc\n11:memory:/docker/abc\n")))
+	require.Equal(t, "/docker/abc", parseCgroup([]byte("1:name=systemd:/docker/abc\n0::/docker/abc\n")))
+}
+
+func TestParseInetDiagMsg(t *testing.T) {
+	data := make([]byte, inetDiagMsgSize)
+	nativeEndian.PutUint64(data[44:52], 42)
+	nativeEndian.PutUint32(data[68:72], 1234)
+
+	// an attribute before the tcp_info
+	other := make([]byte, 8)
+	nativeEndian.PutUint16(other[0:2], 5)
+	nativeEndian.PutUint16(other[2:4], 1)
+	data = append(data, other...)
+
+	info := make([]byte, 4+232)
+	nativeEndian.PutUint16(info[0:2], uint16(len(info)))
+	nativeEndian.PutUint16(info[2:4], inetDiagInfo)
+	nativeEndian.PutUint64(info[4+tcpInfoBytesAcked:], 1000)
+	nativeEndian.PutUint64(info[4+tcpInfoBytesReceived:], 2000)
+	data = append(data, info...)
+
+	s, ok := parseInetDiagMsg(data)
+	require.True(t, ok)
+	require.Equal(t, tcpSocket{inode: 1234, cookie: 42, bytesSent: 1000, bytesReceived: 2000}, s)
+
+	_, ok = parseInetDiagMsg(data[:10])
+	require.False(t, ok)
+}
+
+func TestListTCPSockets(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	sockets, err := listTCPSockets()
+	if err != nil {
+		t.Skipf("sock_diag unavailable: %v", err)
+	}
+	require.NotEmpty(t, sockets)
+}
+
+func TestInitErrors(t *testing.T) {
+	require.Error(t, (&ProcessNetwork{Method: "ebpf"}).Init())
+	require.Error(t, (&ProcessNetwork{GroupBy: "user"}).Init())
+}
@@ -0,0 +1,178 @@
+//go:build linux
+// +build linux
+
+package processnetwork
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type procInfo struct {
+	name   string
+	cgroup string
+}
+
+type netDevStats struct {
+	bytesReceived   uint64
+	packetsReceived uint64
+	bytesSent       uint64
+	packetsSent     uint64
+}
+
+// pids returns the ids of the processes.
+func pids(hostProc string) ([]int, error) {
+	entries, err := os.ReadDir(hostProc)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", hostProc, err)
+	}
+	var pids []int
+	for _, entry := range entries {
+		if pid, err := strconv.Atoi(entry.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// socketOwners maps the inodes of the sockets to the processes holding
+// them, a socket shared by several processes is attributed to the first.
+func socketOwners(hostProc string) (map[uint32]int, error) {
+	pids, err := pids(hostProc)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[uint32]int)
+	for _, pid := range pids {
+		fdDir := filepath.Join(hostProc, strconv.Itoa(pid), "fd")
+		entries, err := os.ReadDir(fdDir)
+		if err != nil {
+			// the process exited or is not readable
+			continue
+		}
+		for _, entry := range entries {
+			link, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 32)
+			if err != nil {
+				continue
+			}
+			if _, ok := owners[uint32(inode)]; !ok {
+				owners[uint32(inode)] = pid
+			}
+		}
+	}
+	return owners, nil
+}
+
+// readProcInfo returns the name and cgroup of the process, or nil when the
+// process exited.
+func readProcInfo(hostProc string, pid int) *procInfo {
+	dir := filepath.Join(hostProc, strconv.Itoa(pid))
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return nil
+	}
+	info := &procInfo{name: strings.TrimSpace(string(comm))}
+	if data, err := os.ReadFile(filepath.Join(dir, "cgroup")); err == nil {
+		info.cgroup = parseCgroup(data)
+	}
+	return info
+}
+
+// parseCgroup returns the path of the unified hierarchy, or of the first
+// hierarchy when cgroup v2 is not used.
+func parseCgroup(data []byte) string {
+	var first string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2]
+		}
+		if first == "" {
+			first = parts[2]
+		}
+	}
+	return first
+}
+
+// readNetns returns the id of the network namespace of the process.
+func readNetns(hostProc string, pid int) (string, error) {
+	link, err := os.Readlink(filepath.Join(hostProc, strconv.Itoa(pid), "ns", "net"))
+	if err != nil {
+		return "", fmt.Errorf("reading netns: %w", err)
+	}
+	// net:[4026531992]
+	return strings.TrimSuffix(strings.TrimPrefix(link, "net:["), "]"), nil
+}
+
+// netNamespaces returns the processes of each network namespace.
+func netNamespaces(hostProc string) (map[string][]int, error) {
+	pids, err := pids(hostProc)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string][]int)
+	for _, pid := range pids {
+		netns, err := readNetns(hostProc, pid)
+		if err != nil {
+			continue
+		}
+		namespaces[netns] = append(namespaces[netns], pid)
+	}
+	return namespaces, nil
+}
+
+// readNetDev sums the counters of the interfaces of the network namespace
+// of the process, except the loopback.
+func readNetDev(hostProc string, pid int) (*netDevStats, error) {
+	data, err := os.ReadFile(filepath.Join(hostProc, strconv.Itoa(pid), "net", "dev"))
+	if err != nil {
+		return nil, fmt.Errorf("reading net/dev: %w", err)
+	}
+	return parseNetDev(data)
+}
+
+func parseNetDev(data []byte) (*netDevStats, error) {
+	stats := &netDevStats{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// "  eth0: 1234 10 0 0 0 0 0 0 5678 20 0 0 0 0 0 0"
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if strings.TrimSpace(parts[0]) == "lo" {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 10 {
+			continue
+		}
+		var values [4]uint64
+		for i, idx := range []int{0, 1, 8, 9} {
+			v, err := strconv.ParseUint(fields[idx], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing net/dev: %w", err)
+			}
+			values[i] = v
+		}
+		stats.bytesReceived += values[0]
+		stats.packetsReceived += values[1]
+		stats.bytesSent += values[2]
+		stats.packetsSent += values[3]
+	}
+	return stats, nil
+}
@@ -0,0 +1,145 @@
+//go:build linux
+// +build linux
+
+package processnetwork
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// The sock_diag netlink interface, see sock_diag(7).
+const (
+	sockDiagByFamily = 20
+	inetDiagInfo     = 2
+
+	inetDiagReqSize = 56
+	inetDiagMsgSize = 72
+
+	// offsets of the byte counters in struct tcp_info
+	tcpInfoBytesAcked    = 120
+	tcpInfoBytesReceived = 128
+)
+
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// tcpSocket are the counters of a TCP socket.
+type tcpSocket struct {
+	inode         uint32
+	cookie        uint64
+	bytesSent     uint64
+	bytesReceived uint64
+}
+
+// listTCPSockets lists the IPv4 and IPv6 TCP sockets of the network
+// namespace of the agent with their counters.
+func listTCPSockets() ([]tcpSocket, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_SOCK_DIAG)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	var sockets []tcpSocket
+	for seq, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		req := inetDiagRequest(family, uint32(seq+1))
+		if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+			return nil, fmt.Errorf("netlink send: %w", err)
+		}
+		s, err := receiveSockets(fd)
+		if err != nil {
+			return nil, err
+		}
+		sockets = append(sockets, s...)
+	}
+	return sockets, nil
+}
+
+// inetDiagRequest builds a dump request of the TCP sockets in any state
+// with their tcp_info.
+func inetDiagRequest(family uint8, seq uint32) []byte {
+	b := make([]byte, unix.NLMSG_HDRLEN+inetDiagReqSize)
+	nativeEndian.PutUint32(b[0:4], uint32(len(b)))
+	nativeEndian.PutUint16(b[4:6], sockDiagByFamily)
+	nativeEndian.PutUint16(b[6:8], unix.NLM_F_REQUEST|unix.NLM_F_DUMP)
+	nativeEndian.PutUint32(b[8:12], seq)
+
+	req := b[unix.NLMSG_HDRLEN:]
+	req[0] = family
+	req[1] = unix.IPPROTO_TCP
+	req[2] = 1 << (inetDiagInfo - 1)
+	nativeEndian.PutUint32(req[4:8], 0xffffffff)
+	return b
+}
+
+func receiveSockets(fd int) ([]tcpSocket, error) {
+	var sockets []tcpSocket
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("netlink receive: %w", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("netlink parse: %w", err)
+		}
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case unix.NLMSG_DONE:
+				return sockets, nil
+			case unix.NLMSG_ERROR:
+				if len(msg.Data) >= 4 {
+					errno := int32(nativeEndian.Uint32(msg.Data[0:4]))
+					return nil, fmt.Errorf("sock_diag: %w", syscall.Errno(-errno))
+				}
+				return nil, errors.New("sock_diag: truncated error")
+			}
+			if s, ok := parseInetDiagMsg(msg.Data); ok {
+				sockets = append(sockets, s)
+			}
+		}
+	}
+}
+
+// parseInetDiagMsg parses a struct inet_diag_msg and its attributes.
+func parseInetDiagMsg(data []byte) (tcpSocket, bool) {
+	if len(data) < inetDiagMsgSize {
+		return tcpSocket{}, false
+	}
+	s := tcpSocket{
+		cookie: nativeEndian.Uint64(data[44:52]),
+		inode:  nativeEndian.Uint32(data[68:72]),
+	}
+
+	attrs := data[inetDiagMsgSize:]
+	for len(attrs) >= unix.SizeofRtAttr {
+		length := int(nativeEndian.Uint16(attrs[0:2]))
+		typ := nativeEndian.Uint16(attrs[2:4])
+		if length < unix.SizeofRtAttr || length > len(attrs) {
+			break
+		}
+		value := attrs[unix.SizeofRtAttr:length]
+		if typ == inetDiagInfo && len(value) >= tcpInfoBytesReceived+8 {
+			s.bytesSent = nativeEndian.Uint64(value[tcpInfoBytesAcked:])
+			s.bytesReceived = nativeEndian.Uint64(value[tcpInfoBytesReceived:])
+		}
+		aligned := (length + unix.RTA_ALIGNTO - 1) &^ (unix.RTA_ALIGNTO - 1)
+		if aligned > len(attrs) {
+			break
+		}
+		attrs = attrs[aligned:]
+	}
+	return s, true
+}
@@ -3,18 +3,20 @@ package prometheus
 import (
 	"context"
 	"fmt"
-	"log"
 	"net"
 	"net/url"
-	"os"
 	"os/user"
 	"path/filepath"
 	"sync"
 	"time"
 
-	"github.com/ericchiang/k8s"
-	corev1 "github.com/ericchiang/k8s/apis/core/v1"
-	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // type payload struct {
@@ -23,23 +25,35 @@ import (
 // }
 
 // loadClient parses a kubeconfig from a file and returns a Kubernetes
-// client. It does not support extensions or client auth providers.
-func loadClient(kubeconfigPath string) (*k8s.Client, error) {
-	data, err := os.ReadFile(kubeconfigPath)
+// client.
+func loadClient(kubeconfigPath string) (*kubernetes.Clientset, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed reading '%s': %w", kubeconfigPath, err)
 	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("new k8s client: %w", err)
+	}
+	return client, nil
+}
 
-	// Unmarshal YAML into a Kubernetes config object.
-	var config k8s.Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("yaml unmarshal: %w", err)
+// inClusterClient returns a Kubernetes client of the service account of the
+// pod the agent runs in.
+func inClusterClient() (*kubernetes.Clientset, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("in cluster config: %w", err)
 	}
-	return k8s.NewClient(&config)
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("new k8s client: %w", err)
+	}
+	return client, nil
 }
 
 func (p *Prometheus) start(ctx context.Context) error {
-	client, err := k8s.NewInClusterClient()
+	client, err := inClusterClient()
 	if err != nil {
 		u, err := user.Current()
 		if err != nil {
@@ -81,42 +95,44 @@ func (p *Prometheus) start(ctx context.Context) error {
 // (without the scrape annotations). K8s may re-assign the old pod ip to the non-scrape
 // pod, causing errors in the logs. This is only true if the pod going offline is not
 // directed to do so by K8s.
-func (p *Prometheus) watch(ctx context.Context, client *k8s.Client) error {
+func (p *Prometheus) watch(ctx context.Context, client *kubernetes.Clientset) error {
 
-	selectors := podSelector(p)
-
-	// pod := &corev1.Pod{}
-	watcher, err := client.Watch(ctx, p.PodNamespace, &corev1.Pod{}, selectors...)
+	watcher, err := client.CoreV1().Pods(p.PodNamespace).Watch(ctx, podSelector(p))
 	if err != nil {
 		return fmt.Errorf("k8s watch: %w", err)
 	}
-	defer watcher.Close()
+	defer watcher.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		default:
-			pod := &corev1.Pod{}
-			// An error here means we need to reconnect the watcher.
-			eventType, err := watcher.Next(pod)
-			if err != nil {
-				return fmt.Errorf("k8s watch next: %w", err)
+		case event, ok := <-watcher.ResultChan():
+			// A closed channel means we need to reconnect the watcher.
+			if !ok {
+				return fmt.Errorf("k8s watch closed")
+			}
+			if event.Type == watch.Error {
+				return fmt.Errorf("k8s watch: %w", apierrors.FromObject(event.Object))
+			}
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
 			}
 
 			// If the pod is not "ready", there will be no ip associated with it.
-			if pod.GetMetadata().GetAnnotations()["prometheus.io/scrape"] != "true" ||
-				!podReady(pod.Status.GetContainerStatuses()) {
+			if pod.Annotations["prometheus.io/scrape"] != "true" ||
+				!podReady(pod.Status.ContainerStatuses) {
 				continue
 			}
 
-			switch eventType {
-			case k8s.EventAdded:
+			switch event.Type {
+			case watch.Added:
 				registerPod(pod, p)
-			case k8s.EventModified:
+			case watch.Modified:
 				// To avoid multiple actions for each event, unregister on the first event
 				// in the delete sequence, when the containers are still "ready".
-				if pod.Metadata.GetDeletionTimestamp() != nil {
+				if pod.DeletionTimestamp != nil {
 					unregisterPod(pod, p)
 				} else {
 					registerPod(pod, p)
@@ -126,31 +142,23 @@ func (p *Prometheus) watch(ctx context.Context, client *k8s.Client) error {
 	}
 }
 
-func podReady(statuss []*corev1.ContainerStatus) bool {
+func podReady(statuss []corev1.ContainerStatus) bool {
 	if len(statuss) == 0 {
 		return false
 	}
 	for _, cs := range statuss {
-		if !cs.GetReady() {
+		if !cs.Ready {
 			return false
 		}
 	}
 	return true
 }
 
-func podSelector(p *Prometheus) []k8s.Option {
-	options := []k8s.Option{}
-
-	if len(p.KubernetesLabelSelector) > 0 {
-		options = append(options, k8s.QueryParam("labelSelector", p.KubernetesLabelSelector))
+func podSelector(p *Prometheus) metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector: p.KubernetesLabelSelector,
+		FieldSelector: p.KubernetesFieldSelector,
 	}
-
-	if len(p.KubernetesFieldSelector) > 0 {
-		options = append(options, k8s.QueryParam("fieldSelector", p.KubernetesFieldSelector))
-	}
-
-	return options
-
 }
 
 func registerPod(pod *corev1.Pod, p *Prometheus) {
@@ -162,21 +170,21 @@ func registerPod(pod *corev1.Pod, p *Prometheus) {
 		return
 	}
 
-	log.Printf("D! [inputs.prometheus] will scrape metrics from %q", *targetURL)
+	p.Log.Debugf("will scrape metrics from %q", *targetURL)
 	// add annotation as metrics tags
-	tags := pod.GetMetadata().GetAnnotations()
+	tags := pod.Annotations
 	if tags == nil {
 		tags = map[string]string{}
 	}
-	tags["pod_name"] = pod.GetMetadata().GetName()
-	tags["namespace"] = pod.GetMetadata().GetNamespace()
+	tags["pod_name"] = pod.Name
+	tags["namespace"] = pod.Namespace
 	// add labels as metrics tags
-	for k, v := range pod.GetMetadata().GetLabels() {
+	for k, v := range pod.Labels {
 		tags[k] = v
 	}
 	URL, err := url.Parse(*targetURL)
 	if err != nil {
-		log.Printf("E! [inputs.prometheus] could not parse URL %q: %s", *targetURL, err.Error())
+		p.Log.Errorf("could not parse URL %q: %s", *targetURL, err.Error())
 		return
 	}
 	podURL := p.AddressToURL(URL, URL.Hostname())
@@ -191,16 +199,16 @@ func registerPod(pod *corev1.Pod, p *Prometheus) {
 }
 
 func getScrapeURL(pod *corev1.Pod) *string {
-	ip := pod.Status.GetPodIP()
+	ip := pod.Status.PodIP
 	if ip == "" {
 		// return as if scrape was disabled, we will be notified again once the pod
 		// has an IP
 		return nil
 	}
 
-	scheme := pod.GetMetadata().GetAnnotations()["prometheus.io/scheme"]
-	path := pod.GetMetadata().GetAnnotations()["prometheus.io/path"]
-	port := pod.GetMetadata().GetAnnotations()["prometheus.io/port"]
+	scheme := pod.Annotations["prometheus.io/scheme"]
+	path := pod.Annotations["prometheus.io/path"]
+	port := pod.Annotations["prometheus.io/port"]
 
 	if scheme == "" {
 		scheme = "http"
@@ -229,13 +237,13 @@ func unregisterPod(pod *corev1.Pod, p *Prometheus) {
 		return
 	}
 
-	log.Printf("D! [inputs.prometheus] registered a delete request for %q in namespace %q",
-		pod.GetMetadata().GetName(), pod.GetMetadata().GetNamespace())
+	p.Log.Debugf("registered a delete request for %q in namespace %q",
+		pod.Name, pod.Namespace)
 
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	if _, ok := p.kubernetesPods[*url]; ok {
 		delete(p.kubernetesPods, *url)
-		log.Printf("D! [inputs.prometheus] will stop scraping for %q", *url)
+		p.Log.Debugf("will stop scraping for %q", *url)
 	}
 }
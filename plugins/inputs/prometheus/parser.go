@@ -94,7 +94,7 @@ func ParseV2(buf []byte, header http.Header) ([]cua.Metric, error) {
 		}
 	}
 
-	return metrics, fmt.Errorf("metric new: %w", err)
+	return metrics, nil
 }
 
 // Get Quantiles for summary metric & Buckets for histogram
@@ -236,7 +236,7 @@ func Parse(buf []byte, header http.Header) ([]cua.Metric, error) {
 		}
 	}
 
-	return metrics, fmt.Errorf("metric new: %w", err)
+	return metrics, nil
 }
 
 func valueType(mt dto.MetricType) cua.ValueType {
@@ -164,3 +164,25 @@ func TestParseValidPrometheus(t *testing.T) {
 		metrics[0].Tags())
 
 }
+
+func TestParseErrors(t *testing.T) {
+	// the valid documents are parsed without error
+	metrics, err := Parse([]byte(validUniqueCounter), http.Header{})
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	metrics, err = ParseV2([]byte(validUniqueCounter), http.Header{})
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+
+	// an invalid content type is parsed as text
+	header := http.Header{"Content-Type": []string{"not a media type"}}
+	metrics, err = ParseV2([]byte(validUniqueGauge), header)
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+
+	// the invalid documents are not
+	_, err = Parse([]byte("get_token_fail_count{ 0\n"), http.Header{})
+	assert.Error(t, err)
+	_, err = ParseV2([]byte("get_token_fail_count{ 0\n"), http.Header{})
+	assert.Error(t, err)
+}
@@ -49,7 +49,7 @@ go_goroutines 15 1490802350000
 
 func TestPrometheusGeneratesMetrics(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, sampleTextFormat)
+		fmt.Fprint(w, sampleTextFormat)
 	}))
 	defer ts.Close()
 
@@ -74,7 +74,7 @@ func TestPrometheusGeneratesMetrics(t *testing.T) {
 
 func TestPrometheusGeneratesMetricsWithHostNameTag(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, sampleTextFormat)
+		fmt.Fprint(w, sampleTextFormat)
 	}))
 	defer ts.Close()
 
@@ -105,7 +105,7 @@ func TestPrometheusGeneratesMetricsAlthoughFirstDNSFails(t *testing.T) {
 	}
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, sampleTextFormat)
+		fmt.Fprint(w, sampleTextFormat)
 	}))
 	defer ts.Close()
 
@@ -128,7 +128,7 @@ func TestPrometheusGeneratesMetricsAlthoughFirstDNSFails(t *testing.T) {
 
 func TestPrometheusGeneratesSummaryMetricsV2(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, sampleSummaryTextFormat)
+		fmt.Fprint(w, sampleSummaryTextFormat)
 	}))
 	defer ts.Close()
 
@@ -159,7 +159,7 @@ go_gc_duration_seconds_sum 42.0
 go_gc_duration_seconds_count 42
 `
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, data)
+		fmt.Fprint(w, data)
 	}))
 	defer ts.Close()
 
@@ -215,7 +215,7 @@ go_gc_duration_seconds_count 42
 
 func TestPrometheusGeneratesGaugeMetricsV2(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, sampleGaugeTextFormat)
+		fmt.Fprint(w, sampleGaugeTextFormat)
 	}))
 	defer ts.Close()
 
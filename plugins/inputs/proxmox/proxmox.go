@@ -29,6 +29,12 @@ var sampleConfig = `
 
   # HTTP response timeout (default: 5s)
   response_timeout = "5s"
+
+  ## Collections:
+  ##   vms:     status and resource usage of the VMs and containers of the node
+  ##   node:    status and resource usage of the node
+  ##   cluster: quorum of the cluster and online state of its nodes
+  # collect = ["vms"]
 `
 
 func (px *Proxmox) SampleConfig() string {
@@ -45,13 +51,42 @@ func (px *Proxmox) Gather(acc cua.Accumulator) error {
 		return err
 	}
 
-	gatherLxcData(px, acc)
-	gatherQemuData(px, acc)
+	if px.collects(collectVMs) {
+		gatherLxcData(px, acc)
+		gatherQemuData(px, acc)
+	}
+	if px.collects(collectNode) {
+		if err := gatherNodeData(px, acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	if px.collects(collectCluster) {
+		if err := gatherClusterData(px, acc); err != nil {
+			acc.AddError(err)
+		}
+	}
 
 	return nil
 }
 
+func (px *Proxmox) collects(c string) bool {
+	for _, collect := range px.Collect {
+		if collect == c {
+			return true
+		}
+	}
+	return false
+}
+
 func (px *Proxmox) Init() error {
+	if len(px.Collect) == 0 {
+		px.Collect = []string{collectVMs}
+	}
+	for _, c := range px.Collect {
+		if c != collectVMs && c != collectNode && c != collectCluster {
+			return fmt.Errorf("unknown collection %q, expected %s, %s or %s", c, collectVMs, collectNode, collectCluster)
+		}
+	}
 	if px.NodeName == "" {
 		hostname, _ := os.Hostname()
 		px.NodeName = hostname
@@ -160,6 +195,98 @@ func gatherVMData(px *Proxmox, acc cua.Accumulator, rt ResourceType) {
 	}
 }
 
+func gatherNodeData(px *Proxmox, acc cua.Accumulator) error {
+	jsonData, err := px.requestFunction(px, "/nodes/"+px.NodeName+"/status", http.MethodGet, nil)
+	if err != nil {
+		return err
+	}
+	var nodeStatus NodeStatus
+	if err := json.Unmarshal(jsonData, &nodeStatus); err != nil {
+		return fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	status := nodeStatus.Data
+	memTotal, memUsed, memFree, memUsedPercentage := getByteMetrics(status.Memory.Total, status.Memory.Used)
+	swapTotal, swapUsed, swapFree, swapUsedPercentage := getByteMetrics(status.Swap.Total, status.Swap.Used)
+	diskTotal, diskUsed, diskFree, diskUsedPercentage := getByteMetrics(status.RootFS.Total, status.RootFS.Used)
+	fields := map[string]interface{}{
+		"uptime":               jsonNumberToInt64(status.Uptime),
+		"cpuload":              jsonNumberToFloat64(status.CPULoad),
+		"cpus":                 jsonNumberToInt64(status.CPUInfo.CPUs),
+		"mem_used":             memUsed,
+		"mem_total":            memTotal,
+		"mem_free":             memFree,
+		"mem_used_percentage":  memUsedPercentage,
+		"swap_used":            swapUsed,
+		"swap_total":           swapTotal,
+		"swap_free":            swapFree,
+		"swap_used_percentage": swapUsedPercentage,
+		"disk_used":            diskUsed,
+		"disk_total":           diskTotal,
+		"disk_free":            diskFree,
+		"disk_used_percentage": diskUsedPercentage,
+	}
+	for i, name := range []string{"load1", "load5", "load15"} {
+		if i < len(status.LoadAverage) {
+			fields[name] = jsonNumberToFloat64(status.LoadAverage[i])
+		}
+	}
+
+	acc.AddFields("proxmox_node", fields, map[string]string{
+		"node_fqdn": px.NodeName + "." + px.nodeSearchDomain,
+		"node":      px.NodeName,
+	})
+	return nil
+}
+
+func gatherClusterData(px *Proxmox, acc cua.Accumulator) error {
+	jsonData, err := px.requestFunction(px, "/cluster/status", http.MethodGet, nil)
+	if err != nil {
+		return err
+	}
+	var clusterStatus ClusterStatus
+	if err := json.Unmarshal(jsonData, &clusterStatus); err != nil {
+		return fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	// a standalone node has no cluster entry
+	name := ""
+	var cluster *ClusterStatusEntry
+	for i, entry := range clusterStatus.Data {
+		if entry.Type == "cluster" {
+			cluster = &clusterStatus.Data[i]
+			name = entry.Name
+		}
+	}
+
+	online := int64(0)
+	for _, entry := range clusterStatus.Data {
+		if entry.Type != "node" {
+			continue
+		}
+		if entry.Online == 1 {
+			online++
+		}
+		acc.AddFields("proxmox_cluster_node", map[string]interface{}{
+			"online": entry.Online == 1,
+		}, map[string]string{
+			"cluster": name,
+			"node":    entry.Name,
+		})
+	}
+
+	if cluster != nil {
+		acc.AddFields("proxmox_cluster", map[string]interface{}{
+			"quorate":      cluster.Quorate == 1,
+			"nodes":        cluster.Nodes,
+			"nodes_online": online,
+		}, map[string]string{
+			"cluster": name,
+		})
+	}
+	return nil
+}
+
 func getCurrentVMStatus(px *Proxmox, rt ResourceType, id string) (VMStat, error) {
 	apiURL := "/nodes/" + px.NodeName + "/" + string(rt) + "/" + id + "/status/current"
 
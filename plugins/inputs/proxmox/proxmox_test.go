@@ -17,6 +17,8 @@ var lxcTestData = `{"data":[{"vmid":"111","type":"lxc","uptime":2078164,"swap":9
 var lxcConfigTestData = `{"data":{"hostname":"container1","searchdomain":"test.example.com"}}`
 var lxcCurrentStatusTestData = `{"data":{"vmid":"111","type":"lxc","uptime":2078164,"swap":9412608,"disk":"744189952","maxmem":536870912,"mem":98500608,"maxswap":536870912,"cpu":0.00371567669193613,"status":"running","maxdisk":"5217320960","name":"container1"}}`
 var qemuCurrentStatusTestData = `{"data":{"name":"qemu1","status":"running","maxdisk":10737418240,"cpu":0.029336643550795,"vmid":"113","uptime":2159739,"disk":0,"maxmem":2147483648,"mem":1722451796}}`
+var nodeStatusTestData = `{"data":{"uptime":86400,"cpu":0.05,"loadavg":["0.10","0.20","0.30"],"cpuinfo":{"cpus":8},"memory":{"total":16777216000,"used":4194304000,"free":12582912000},"swap":{"total":0,"used":0,"free":0},"rootfs":{"total":100000000000,"used":25000000000,"free":75000000000,"avail":70000000000}}}`
+var clusterStatusTestData = `{"data":[{"type":"cluster","id":"cluster","name":"pve-cluster","nodes":2,"quorate":1,"version":4},{"type":"node","id":"node/testnode","name":"testnode","online":1,"local":1,"nodeid":1},{"type":"node","id":"node/othernode","name":"othernode","online":0,"local":0,"nodeid":2}]}`
 
 func performTestRequest(px *Proxmox, apiURL string, method string, data url.Values) ([]byte, error) {
 	var bytedata = []byte("")
@@ -36,6 +38,10 @@ func performTestRequest(px *Proxmox, apiURL string, method string, data url.Valu
 		bytedata = []byte(lxcCurrentStatusTestData)
 	case strings.HasSuffix(apiURL, "113/status/current"):
 		bytedata = []byte(qemuCurrentStatusTestData)
+	case strings.HasSuffix(apiURL, "testnode/status"):
+		bytedata = []byte(nodeStatusTestData)
+	case strings.HasSuffix(apiURL, "cluster/status"):
+		bytedata = []byte(clusterStatusTestData)
 	}
 
 	return bytedata, nil
@@ -143,3 +149,59 @@ func TestGather(t *testing.T) {
 	// Results from both tests above
 	assert.Equal(t, acc.NFields(), 30)
 }
+
+func TestGatherNodeData(t *testing.T) {
+	px := setUp(t)
+	px.nodeSearchDomain = "test.example.com"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, gatherNodeData(px, acc))
+
+	testFields := map[string]interface{}{
+		"uptime":               int64(86400),
+		"cpuload":              float64(0.05),
+		"cpus":                 int64(8),
+		"load1":                float64(0.1),
+		"load5":                float64(0.2),
+		"load15":               float64(0.3),
+		"mem_used":             int64(4194304000),
+		"mem_total":            int64(16777216000),
+		"mem_free":             int64(12582912000),
+		"mem_used_percentage":  float64(25),
+		"swap_used":            int64(0),
+		"swap_total":           int64(0),
+		"swap_free":            int64(0),
+		"swap_used_percentage": float64(0),
+		"disk_used":            int64(25000000000),
+		"disk_total":           int64(100000000000),
+		"disk_free":            int64(75000000000),
+		"disk_used_percentage": float64(25),
+	}
+	testTags := map[string]string{
+		"node_fqdn": "testnode.test.example.com",
+		"node":      "testnode",
+	}
+	acc.AssertContainsTaggedFields(t, "proxmox_node", testFields, testTags)
+}
+
+func TestGatherClusterData(t *testing.T) {
+	px := setUp(t)
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, gatherClusterData(px, acc))
+
+	acc.AssertContainsTaggedFields(t, "proxmox_cluster",
+		map[string]interface{}{"quorate": true, "nodes": int64(2), "nodes_online": int64(1)},
+		map[string]string{"cluster": "pve-cluster"})
+	acc.AssertContainsTaggedFields(t, "proxmox_cluster_node",
+		map[string]interface{}{"online": true},
+		map[string]string{"cluster": "pve-cluster", "node": "testnode"})
+	acc.AssertContainsTaggedFields(t, "proxmox_cluster_node",
+		map[string]interface{}{"online": false},
+		map[string]string{"cluster": "pve-cluster", "node": "othernode"})
+}
+
+func TestInitInvalidCollect(t *testing.T) {
+	px := &Proxmox{NodeName: "testnode", Collect: []string{"storage"}}
+	require.Error(t, px.Init())
+}
@@ -15,6 +15,7 @@ type Proxmox struct {
 	APIToken        string            `toml:"api_token"`
 	ResponseTimeout internal.Duration `toml:"response_timeout"`
 	NodeName        string            `toml:"node_name"`
+	Collect         []string          `toml:"collect"`
 
 	tls.ClientConfig
 
@@ -25,6 +26,12 @@ type Proxmox struct {
 	Log             cua.Logger `toml:"-"`
 }
 
+const (
+	collectVMs     = "vms"
+	collectNode    = "node"
+	collectCluster = "cluster"
+)
+
 type VMCurrentStats struct {
 	Data VMStat `json:"data"`
 }
@@ -67,3 +74,35 @@ type NodeDNS struct {
 		Searchdomain string `json:"search"`
 	} `json:"data"`
 }
+
+type NodeStatus struct {
+	Data struct {
+		Uptime      json.Number   `json:"uptime"`
+		CPULoad     json.Number   `json:"cpu"`
+		LoadAverage []json.Number `json:"loadavg"`
+		CPUInfo     struct {
+			CPUs json.Number `json:"cpus"`
+		} `json:"cpuinfo"`
+		Memory ByteUsage `json:"memory"`
+		Swap   ByteUsage `json:"swap"`
+		RootFS ByteUsage `json:"rootfs"`
+	} `json:"data"`
+}
+
+type ByteUsage struct {
+	Total json.Number `json:"total"`
+	Used  json.Number `json:"used"`
+}
+
+type ClusterStatus struct {
+	Data []ClusterStatusEntry `json:"data"`
+}
+
+// ClusterStatusEntry is either the cluster or one of its nodes.
+type ClusterStatusEntry struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Quorate int64  `json:"quorate"`
+	Nodes   int64  `json:"nodes"`
+	Online  int64  `json:"online"`
+}
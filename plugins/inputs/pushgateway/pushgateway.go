@@ -0,0 +1,391 @@
+package pushgateway
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	tlsint "github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs/prometheus"
+)
+
+// defaultMaxBodySize is the default maximum request body size, in bytes.
+// if the request body is over this size, we will return an HTTP 413 error.
+// 32 MB
+const defaultMaxBodySize = 32 * 1024 * 1024
+
+const (
+	// metricsPrefix is the path prefix used by the Pushgateway API, it is
+	// followed by the job and optional grouping labels.
+	metricsPrefix = "/metrics/"
+	// base64Suffix marks a grouping label whose value is base64url encoded.
+	base64Suffix = "@base64"
+)
+
+// Pushgateway is an input plugin that accepts metrics pushed using the
+// Prometheus Pushgateway protocol.
+type Pushgateway struct {
+	ServiceAddress string            `toml:"service_address"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	WriteTimeout   internal.Duration `toml:"write_timeout"`
+	MaxBodySize    internal.Size     `toml:"max_body_size"`
+	BasicUsername  string            `toml:"basic_username"`
+	BasicPassword  string            `toml:"basic_password"`
+	MetricVersion  int               `toml:"metric_version"`
+	RetainGroups   bool              `toml:"retain_groups"`
+	GroupTTL       internal.Duration `toml:"group_ttl"`
+	tlsint.ServerConfig
+
+	Log cua.Logger `toml:"-"`
+
+	wg       sync.WaitGroup
+	listener net.Listener
+	acc      cua.Accumulator
+
+	mu     sync.Mutex
+	groups map[string]*group
+}
+
+// group holds the most recently pushed metrics of a grouping key.
+type group struct {
+	series  map[string]cua.Metric
+	updated time.Time
+}
+
+const sampleConfig = `
+  ## Address and port to host the Pushgateway compatible listener on
+  service_address = ":9091"
+
+  ## maximum duration before timing out read of the request
+  # read_timeout = "10s"
+  ## maximum duration before timing out write of the response
+  # write_timeout = "10s"
+
+  ## Maximum allowed http request body size in bytes.
+  ## 0 means to use the default of 32MiB
+  # max_body_size = "32MiB"
+
+  ## Metric version controls the mapping from Prometheus metrics into
+  ## the agent's metrics, see the prometheus input for details.
+  # metric_version = 1
+
+  ## By default pushed metrics are emitted once, when they are received.
+  ## Enable retain_groups to keep the last push of every grouping key and
+  ## emit it on every collection interval, as a scrape of a Pushgateway
+  ## would. Groups are removed by a DELETE request or, when group_ttl is
+  ## set, once they have not been pushed to within the ttl.
+  # retain_groups = false
+  # group_ttl = "0s"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/circonus-unified-agent/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/circonus-unified-agent/cert.pem"
+  # tls_key = "/etc/circonus-unified-agent/key.pem"
+
+  ## Optional username and password to accept for HTTP basic authentication.
+  ## You probably want to make sure you have TLS configured above for this.
+  # basic_username = "foobar"
+  # basic_password = "barfoo"
+`
+
+func (p *Pushgateway) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Pushgateway) Description() string {
+	return "Accept metrics pushed using the Prometheus Pushgateway protocol"
+}
+
+// Gather emits the retained groups, if enabled.
+func (p *Pushgateway) Gather(acc cua.Accumulator) error {
+	if !p.RetainGroups {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for key, g := range p.groups {
+		if p.GroupTTL.Duration > 0 && now.Sub(g.updated) > p.GroupTTL.Duration {
+			delete(p.groups, key)
+			continue
+		}
+		for _, m := range g.series {
+			c := m.Copy()
+			c.SetTime(now)
+			acc.AddMetric(c)
+		}
+	}
+
+	return nil
+}
+
+// Start starts the http listener service.
+func (p *Pushgateway) Start(acc cua.Accumulator) error {
+	if p.MaxBodySize.Size == 0 {
+		p.MaxBodySize.Size = defaultMaxBodySize
+	}
+
+	if p.ReadTimeout.Duration < time.Second {
+		p.ReadTimeout.Duration = time.Second * 10
+	}
+	if p.WriteTimeout.Duration < time.Second {
+		p.WriteTimeout.Duration = time.Second * 10
+	}
+
+	p.acc = acc
+	p.groups = make(map[string]*group)
+
+	tlsConf, err := p.ServerConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+
+	server := &http.Server{
+		Addr:         p.ServiceAddress,
+		Handler:      p,
+		ReadTimeout:  p.ReadTimeout.Duration,
+		WriteTimeout: p.WriteTimeout.Duration,
+		TLSConfig:    tlsConf,
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", p.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", p.ServiceAddress)
+	}
+	if err != nil {
+		return fmt.Errorf("listen (%s): %w", p.ServiceAddress, err)
+	}
+	p.listener = listener
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := server.Serve(p.listener); err != nil && !errors.Is(err, net.ErrClosed) {
+			p.Log.Error(err)
+		}
+	}()
+
+	p.Log.Infof("Listening on %s", listener.Addr().String())
+
+	return nil
+}
+
+// Stop cleans up all resources
+func (p *Pushgateway) Stop() {
+	p.listener.Close()
+	p.wg.Wait()
+}
+
+func (p *Pushgateway) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if !p.authenticated(req) {
+		http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case req.URL.Path == "/-/healthy" || req.URL.Path == "/-/ready":
+		res.WriteHeader(http.StatusOK)
+	case strings.HasPrefix(req.URL.Path, metricsPrefix):
+		p.serveMetrics(res, req)
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+func (p *Pushgateway) serveMetrics(res http.ResponseWriter, req *http.Request) {
+	labels, err := parseGroupingKey(strings.TrimPrefix(req.URL.Path, metricsPrefix))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut, http.MethodPost:
+		p.servePush(res, req, labels)
+	case http.MethodDelete:
+		p.mu.Lock()
+		delete(p.groups, groupKey(labels))
+		p.mu.Unlock()
+		res.WriteHeader(http.StatusAccepted)
+	default:
+		res.Header().Set("Allow", "PUT, POST, DELETE")
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (p *Pushgateway) servePush(res http.ResponseWriter, req *http.Request, labels map[string]string) {
+	if req.ContentLength > p.MaxBodySize.Size {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	buf, err := io.ReadAll(http.MaxBytesReader(res, body, p.MaxBodySize.Size))
+	if err != nil {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var metrics []cua.Metric
+	if p.MetricVersion == 2 {
+		metrics, err = prometheus.ParseV2(buf, req.Header)
+	} else {
+		metrics, err = prometheus.Parse(buf, req.Header)
+	}
+	if err != nil {
+		p.Log.Debugf("Parse error: %s", err.Error())
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Grouping labels take precedence over labels of the pushed metrics.
+	for _, m := range metrics {
+		for k, v := range labels {
+			m.AddTag(k, v)
+		}
+	}
+
+	if !p.RetainGroups {
+		for _, m := range metrics {
+			p.acc.AddMetric(m)
+		}
+		res.WriteHeader(http.StatusOK)
+		return
+	}
+
+	key := groupKey(labels)
+
+	p.mu.Lock()
+	g, ok := p.groups[key]
+	// PUT replaces the whole group, POST only the pushed series.
+	if !ok || req.Method == http.MethodPut {
+		g = &group{series: make(map[string]cua.Metric)}
+		p.groups[key] = g
+	}
+	for _, m := range metrics {
+		g.series[seriesKey(m)] = m
+	}
+	g.updated = time.Now()
+	p.mu.Unlock()
+
+	res.WriteHeader(http.StatusOK)
+}
+
+func (p *Pushgateway) authenticated(req *http.Request) bool {
+	if p.BasicUsername == "" || p.BasicPassword == "" {
+		return true
+	}
+	username, password, ok := req.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(p.BasicUsername)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(p.BasicPassword)) == 1
+}
+
+// parseGroupingKey parses the "job/<job>{/<label>/<value>}" part of a push
+// path into a label set containing the job and all grouping labels.
+func parseGroupingKey(path string) (map[string]string, error) {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if parts[0] != "job" && parts[0] != "job"+base64Suffix {
+		return nil, fmt.Errorf("job name is required")
+	}
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("odd number of components in label string %q", path)
+	}
+
+	labels := make(map[string]string, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		name, value := parts[i], parts[i+1]
+		if strings.HasSuffix(name, base64Suffix) {
+			name = strings.TrimSuffix(name, base64Suffix)
+			// The Pushgateway allows both padded and unpadded values.
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 encoding for label %s=%q: %w", name, value, err)
+			}
+			value = string(decoded)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty label name in %q", path)
+		}
+		if name == "job" && value == "" {
+			return nil, fmt.Errorf("job name is required")
+		}
+		labels[name] = value
+	}
+
+	return labels, nil
+}
+
+// groupKey returns a stable identifier for a set of grouping labels.
+func groupKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, k := range names {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// seriesKey identifies a metric by name, tags and field keys within a group.
+func seriesKey(m cua.Metric) string {
+	var b strings.Builder
+	b.WriteString(m.Name())
+	for _, t := range m.TagList() {
+		b.WriteByte(',')
+		b.WriteString(t.Key)
+		b.WriteByte('=')
+		b.WriteString(t.Value)
+	}
+	for _, f := range m.FieldList() {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+	}
+	return b.String()
+}
+
+func init() {
+	inputs.Add("pushgateway", func() cua.Input {
+		return &Pushgateway{
+			ServiceAddress: ":9091",
+			MetricVersion:  1,
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package pushgateway
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	basicUsername = "test-username-please-ignore"
+	basicPassword = "super-secure-password!"
+
+	testMetrics = `# TYPE some_metric counter
+some_metric{label="val1"} 42
+# TYPE another_metric gauge
+another_metric 2.398
+`
+)
+
+func newTestPushgateway() *Pushgateway {
+	return &Pushgateway{
+		Log:            testutil.Logger{},
+		ServiceAddress: "localhost:0",
+		MetricVersion:  1,
+	}
+}
+
+func pushURL(p *Pushgateway, path string) string {
+	return "http://" + p.listener.Addr().String() + path
+}
+
+func doRequest(t *testing.T, method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestParseGroupingKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected map[string]string
+		err      bool
+	}{
+		{
+			name:     "job only",
+			path:     "job/some_job",
+			expected: map[string]string{"job": "some_job"},
+		},
+		{
+			name:     "job and labels",
+			path:     "job/some_job/instance/some_instance/zone/a",
+			expected: map[string]string{"job": "some_job", "instance": "some_instance", "zone": "a"},
+		},
+		{
+			name:     "base64 label",
+			path:     "job/some_job/path@base64/L3Zhci90bXA",
+			expected: map[string]string{"job": "some_job", "path": "/var/tmp"},
+		},
+		{
+			name:     "base64 job padded",
+			path:     "job@base64/c29tZV9qb2I=",
+			expected: map[string]string{"job": "some_job"},
+		},
+		{
+			name:     "empty base64 value",
+			path:     "job/some_job/instance@base64/=",
+			expected: map[string]string{"job": "some_job", "instance": ""},
+		},
+		{
+			name: "missing job",
+			path: "job/",
+			err:  true,
+		},
+		{
+			name: "missing job label",
+			path: "instance/some_instance",
+			err:  true,
+		},
+		{
+			name: "odd components",
+			path: "job/some_job/instance",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := parseGroupingKey(tt.path)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, labels)
+		})
+	}
+}
+
+func TestPushEmitsMetrics(t *testing.T) {
+	p := newTestPushgateway()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	resp := doRequest(t, http.MethodPost, pushURL(p, "/metrics/job/batch/instance/host1"), testMetrics)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	acc.Wait(2)
+	acc.AssertContainsTaggedFields(t, "some_metric",
+		map[string]interface{}{"counter": 42.0},
+		map[string]string{"job": "batch", "instance": "host1", "label": "val1"})
+	acc.AssertContainsTaggedFields(t, "another_metric",
+		map[string]interface{}{"gauge": 2.398},
+		map[string]string{"job": "batch", "instance": "host1"})
+
+	// Nothing is retained by default.
+	acc.ClearMetrics()
+	require.NoError(t, p.Gather(acc))
+	require.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestPushRetainGroups(t *testing.T) {
+	p := newTestPushgateway()
+	p.RetainGroups = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	resp := doRequest(t, http.MethodPut, pushURL(p, "/metrics/job/batch"), testMetrics)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, p.Gather(acc))
+	require.Equal(t, 2, len(acc.Metrics))
+
+	// POST only replaces the pushed series.
+	resp = doRequest(t, http.MethodPost, pushURL(p, "/metrics/job/batch"), "# TYPE another_metric gauge\nanother_metric 1\n")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	acc.ClearMetrics()
+	require.NoError(t, p.Gather(acc))
+	require.Equal(t, 2, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "another_metric",
+		map[string]interface{}{"gauge": 1.0},
+		map[string]string{"job": "batch"})
+
+	// PUT replaces the whole group.
+	resp = doRequest(t, http.MethodPut, pushURL(p, "/metrics/job/batch"), "# TYPE another_metric gauge\nanother_metric 3\n")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	acc.ClearMetrics()
+	require.NoError(t, p.Gather(acc))
+	require.Equal(t, 1, len(acc.Metrics))
+
+	resp = doRequest(t, http.MethodDelete, pushURL(p, "/metrics/job/batch"), "")
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	acc.ClearMetrics()
+	require.NoError(t, p.Gather(acc))
+	require.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestPushBadRequests(t *testing.T) {
+	p := newTestPushgateway()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	resp := doRequest(t, http.MethodPost, pushURL(p, "/metrics/job/batch/instance"), testMetrics)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, pushURL(p, "/metrics/job/batch"), "not a metric{")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, pushURL(p, "/metrics/job/batch"), "")
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodGet, pushURL(p, "/-/healthy"), "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestPushBasicAuth(t *testing.T) {
+	p := newTestPushgateway()
+	p.BasicUsername = basicUsername
+	p.BasicPassword = basicPassword
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	resp := doRequest(t, http.MethodPost, pushURL(p, "/metrics/job/batch"), testMetrics)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPost, pushURL(p, "/metrics/job/batch"), bytes.NewBufferString(testMetrics))
+	require.NoError(t, err)
+	req.SetBasicAuth(basicUsername, basicPassword)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
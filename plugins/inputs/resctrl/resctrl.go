@@ -0,0 +1,267 @@
+// +build linux
+
+package resctrl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const (
+	defaultPath = "/sys/fs/resctrl"
+
+	// defaultGroup is the name of the control group at the root of the
+	// file system.
+	defaultGroup = "default"
+)
+
+// monFiles are the monitoring event files and their field names.
+var monFiles = map[string]string{
+	"llc_occupancy":   "llc_occupancy_bytes",
+	"mbm_total_bytes": "mbm_total_bytes",
+	"mbm_local_bytes": "mbm_local_bytes",
+}
+
+// rateFields are the counters the bandwidth is computed from.
+var rateFields = map[string]string{
+	"mbm_total_bytes": "mbm_total_bandwidth",
+	"mbm_local_bytes": "mbm_local_bandwidth",
+}
+
+type Resctrl struct {
+	Path   string     `toml:"path"`
+	Groups []string   `toml:"groups"`
+	Log    cua.Logger `toml:"-"`
+
+	filter filter.Filter
+	// previous samples of the counters for the bandwidth
+	samples map[string]sample
+}
+
+type sample struct {
+	value uint64
+	time  time.Time
+}
+
+type group struct {
+	name     string
+	ctrl     string
+	mon      string
+	dir      string
+	ctrlOnly bool
+}
+
+const sampleConfig = `
+  ## Mount point of the resctrl file system.
+  # path = "/sys/fs/resctrl"
+
+  ## Groups to gather, as glob patterns matched against the control group
+  ## name, or "<control group>/<monitoring group>" for the monitoring groups.
+  ## The control group at the root of the file system is named "default".
+  ##   ex: groups = ["default", "tenant_*", "tenant_*/*"]
+  # groups = ["*", "*/*"]
+`
+
+func (r *Resctrl) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Resctrl) Description() string {
+	return "Gather the cache occupancy and memory bandwidth of the resctrl monitoring groups"
+}
+
+func (r *Resctrl) Init() error {
+	if r.Path == "" {
+		r.Path = defaultPath
+	}
+	if len(r.Groups) == 0 {
+		r.Groups = []string{"*", "*/*"}
+	}
+
+	var err error
+	if r.filter, err = filter.Compile(r.Groups); err != nil {
+		return fmt.Errorf("compiling groups filter: %w", err)
+	}
+	r.samples = make(map[string]sample)
+	return nil
+}
+
+func (r *Resctrl) Gather(acc cua.Accumulator) error {
+	if _, err := os.Stat(filepath.Join(r.Path, "mon_data")); err != nil {
+		return fmt.Errorf("resctrl monitoring not available at %q: %w", r.Path, err)
+	}
+
+	groups, err := r.groups()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		if err := r.gatherGroup(acc, g, now, seen); err != nil {
+			acc.AddError(fmt.Errorf("group %q: %w", g.name, err))
+		}
+	}
+
+	// forget the samples of the groups removed
+	for key := range r.samples {
+		if !seen[key] {
+			delete(r.samples, key)
+		}
+	}
+	return nil
+}
+
+// groups returns the control groups and their monitoring groups matching
+// the filter.
+func (r *Resctrl) groups() ([]group, error) {
+	ctrlDirs := map[string]string{defaultGroup: r.Path}
+
+	entries, err := os.ReadDir(r.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading resctrl: %w", err)
+	}
+	for _, entry := range entries {
+		switch name := entry.Name(); {
+		case !entry.IsDir(), name == "info", name == "mon_groups", name == "mon_data":
+		default:
+			ctrlDirs[name] = filepath.Join(r.Path, name)
+		}
+	}
+
+	var groups []group
+	for ctrl, dir := range ctrlDirs {
+		if r.filter.Match(ctrl) {
+			groups = append(groups, group{name: ctrl, ctrl: ctrl, dir: dir, ctrlOnly: true})
+		}
+
+		entries, err := os.ReadDir(filepath.Join(dir, "mon_groups"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading monitoring groups: %w", err)
+		}
+		for _, entry := range entries {
+			name := ctrl + "/" + entry.Name()
+			if entry.IsDir() && r.filter.Match(name) {
+				groups = append(groups, group{
+					name: name,
+					ctrl: ctrl,
+					mon:  entry.Name(),
+					dir:  filepath.Join(dir, "mon_groups", entry.Name()),
+				})
+			}
+		}
+	}
+	return groups, nil
+}
+
+// gatherGroup adds a metric for each cache domain of the group.
+func (r *Resctrl) gatherGroup(acc cua.Accumulator, g group, now time.Time, seen map[string]bool) error {
+	domains, err := os.ReadDir(filepath.Join(g.dir, "mon_data"))
+	if err != nil {
+		return fmt.Errorf("reading monitoring data: %w", err)
+	}
+
+	tasks, err := countTasks(filepath.Join(g.dir, "tasks"))
+	if err != nil {
+		return err
+	}
+	cpus, _ := readString(filepath.Join(g.dir, "cpus_list"))
+
+	for _, domain := range domains {
+		// mon_L3_<cache id>
+		if !domain.IsDir() || !strings.HasPrefix(domain.Name(), "mon_L3_") {
+			continue
+		}
+		cacheID := strings.TrimLeft(strings.TrimPrefix(domain.Name(), "mon_L3_"), "0")
+		if cacheID == "" {
+			cacheID = "0"
+		}
+
+		fields := map[string]interface{}{
+			"tasks": tasks,
+		}
+		for file, field := range monFiles {
+			value, ok := readCounter(filepath.Join(g.dir, "mon_data", domain.Name(), file))
+			if !ok {
+				continue
+			}
+			fields[field] = value
+
+			rate, ok := rateFields[file]
+			if !ok {
+				continue
+			}
+			key := g.name + "\x00" + cacheID + "\x00" + file
+			seen[key] = true
+			if prev, ok := r.samples[key]; ok && value >= prev.value && now.After(prev.time) {
+				fields[rate] = float64(value-prev.value) / now.Sub(prev.time).Seconds()
+			}
+			r.samples[key] = sample{value: value, time: now}
+		}
+
+		tags := map[string]string{
+			"ctrl_group": g.ctrl,
+			"cache_id":   cacheID,
+		}
+		if !g.ctrlOnly {
+			tags["mon_group"] = g.mon
+		}
+		if cpus != "" {
+			tags["cpus"] = cpus
+		}
+		acc.AddFields("resctrl", fields, tags, now)
+	}
+	return nil
+}
+
+// readCounter reads an event file, it holds "Unavailable" when the event
+// cannot be read.
+func readCounter(path string) (uint64, bool) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+func readString(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", filepath.Base(path), err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func countTasks(path string) (int64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	if s == "" {
+		return 0, nil
+	}
+	return int64(strings.Count(s, "\n") + 1), nil
+}
+
+func init() {
+	inputs.Add("resctrl", func() cua.Input {
+		return &Resctrl{}
+	})
+}
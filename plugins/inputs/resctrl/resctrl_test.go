@@ -0,0 +1,113 @@
+//go:build linux
+// +build linux
+
+package resctrl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func resctrlTree(t *testing.T) string {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"info/L3_MON/num_rmids":                            "128\n",
+		"tasks":                                            "1\n2\n3\n",
+		"cpus_list":                                        "0-3\n",
+		"mon_data/mon_L3_00/llc_occupancy":                 "1048576\n",
+		"mon_data/mon_L3_00/mbm_total_bytes":               "1000\n",
+		"mon_data/mon_L3_00/mbm_local_bytes":               "Unavailable\n",
+		"mon_groups/pod1/tasks":                            "42\n",
+		"mon_groups/pod1/cpus_list":                        "\n",
+		"mon_groups/pod1/mon_data/mon_L3_00/llc_occupancy": "65536\n",
+		"tenant_a/tasks":                                   "",
+		"tenant_a/cpus_list":                               "\n",
+		"tenant_a/mon_data/mon_L3_01/llc_occupancy":        "0\n",
+		"tenant_a/mon_data/mon_L3_01/mbm_total_bytes":      "5000\n",
+	})
+	return root
+}
+
+func TestGather(t *testing.T) {
+	r := &Resctrl{Path: resctrlTree(t), Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	expected := []cua.Metric{
+		testutil.MustMetric("resctrl",
+			map[string]string{"ctrl_group": "default", "cache_id": "0", "cpus": "0-3"},
+			map[string]interface{}{
+				"tasks":               int64(3),
+				"llc_occupancy_bytes": uint64(1048576),
+				"mbm_total_bytes":     uint64(1000),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("resctrl",
+			map[string]string{"ctrl_group": "default", "mon_group": "pod1", "cache_id": "0"},
+			map[string]interface{}{
+				"tasks":               int64(1),
+				"llc_occupancy_bytes": uint64(65536),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("resctrl",
+			map[string]string{"ctrl_group": "tenant_a", "cache_id": "1"},
+			map[string]interface{}{
+				"tasks":               int64(0),
+				"llc_occupancy_bytes": uint64(0),
+				"mbm_total_bytes":     uint64(5000),
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherBandwidth(t *testing.T) {
+	root := resctrlTree(t)
+	r := &Resctrl{Path: root, Groups: []string{"tenant_a"}, Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+	require.Equal(t, uint64(1), acc.NMetrics())
+	require.False(t, acc.HasField("resctrl", "mbm_total_bandwidth"))
+
+	// go back in time instead of waiting
+	for key, s := range r.samples {
+		s.time = s.time.Add(-2 * time.Second)
+		r.samples[key] = s
+	}
+	writeFiles(t, root, map[string]string{
+		"tenant_a/mon_data/mon_L3_01/mbm_total_bytes": "9000\n",
+	})
+
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(r.Gather))
+	bandwidth, ok := acc.FloatField("resctrl", "mbm_total_bandwidth")
+	require.True(t, ok)
+	require.InDelta(t, 2000, bandwidth, 10)
+}
+
+func TestGatherNotMounted(t *testing.T) {
+	r := &Resctrl{Path: t.TempDir(), Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, r.Gather(&acc))
+}
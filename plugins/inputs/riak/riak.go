@@ -1,8 +1,10 @@
 package riak
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"time"
@@ -15,6 +17,9 @@ import (
 type Riak struct {
 	// Servers is a slice of servers as http addresses (ex. http://127.0.0.1:8098)
 	Servers []string
+	// AllStats reports every numeric value returned by /stats instead of
+	// the fixed set of fields below.
+	AllStats bool `toml:"all_stats"`
 
 	client *http.Client
 }
@@ -83,6 +88,11 @@ type riakStats struct {
 const sampleConfig = `
   # Specify a list of one or more riak http servers
   servers = ["http://localhost:8098"]
+
+  ## Report every numeric value returned by the /stats endpoint instead of
+  ## the default set of fields. Combine with fieldpass/fielddrop to select
+  ## the stats of interest.
+  # all_stats = false
 `
 
 // Returns a sample configuration for the plugin
@@ -130,9 +140,14 @@ func (r *Riak) gatherServer(s string, acc cua.Accumulator) error {
 		return fmt.Errorf("riak responded with unexpected status code %d", resp.StatusCode)
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read riak response: %w", err)
+	}
+
 	// Decode the response JSON into a new stats struct
 	stats := &riakStats{}
-	if err := json.NewDecoder(resp.Body).Decode(stats); err != nil {
+	if err := json.Unmarshal(body, stats); err != nil {
 		return fmt.Errorf("unable to decode riak response: %w", err)
 	}
 
@@ -191,12 +206,46 @@ func (r *Riak) gatherServer(s string, acc cua.Accumulator) error {
 		"read_repairs_total":           stats.ReadRepairsTotal,
 	}
 
+	if r.AllStats {
+		all, err := allStats(body)
+		if err != nil {
+			return err
+		}
+		for k, v := range all {
+			fields[k] = v
+		}
+	}
+
 	// Accumulate the tags and values
 	acc.AddFields("riak", fields, tags)
 
 	return nil
 }
 
+// allStats returns every numeric value of a /stats response.
+func allStats(body []byte) (map[string]interface{}, error) {
+	var raw map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("unable to decode riak response: %w", err)
+	}
+
+	fields := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		n, ok := v.(json.Number)
+		if !ok {
+			continue
+		}
+		if i, err := n.Int64(); err == nil {
+			fields[k] = i
+		} else if f, err := n.Float64(); err == nil {
+			fields[k] = f
+		}
+	}
+	return fields, nil
+}
+
 func init() {
 	inputs.Add("riak", func() cua.Input {
 		return NewRiak()
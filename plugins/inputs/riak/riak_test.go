@@ -92,6 +92,27 @@ func TestRiak(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, "riak", expectFields, expectTags)
 }
 
+func TestRiakAllStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, response)
+	}))
+	defer ts.Close()
+
+	riak := NewRiak()
+	riak.Servers = []string{ts.URL}
+	riak.AllStats = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, riak.Gather(acc))
+
+	require.True(t, acc.HasInt64Field("riak", "vnode_gets"))
+	require.True(t, acc.HasInt64Field("riak", "riak_kv_vnodes_running"))
+	require.True(t, acc.HasInt64Field("riak", "vnode_index_refreshes_total"))
+	require.False(t, acc.HasField("riak", "nodename"))
+	require.False(t, acc.HasField("riak", "kernel_version"))
+}
+
 var response = `
 {
   "riak_kv_stat_ts": 1455908558,
@@ -7,7 +7,6 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"os"
 	"os/signal"
@@ -156,13 +155,6 @@ func readMessages(r io.Reader, p []byte) error {
 	return nil
 }
 
-func checkError(err error) {
-	log.Println("The error is")
-	if err != nil {
-		log.Println(err.Error())
-	}
-}
-
 func (rsl *riemannListener) read(conn net.Conn) {
 	defer rsl.removeConnection(conn)
 	defer conn.Close()
@@ -179,7 +171,7 @@ func (rsl *riemannListener) read(conn net.Conn) {
 		if err = binary.Read(conn, binary.BigEndian, &header); err != nil {
 			if err.Error() != "EOF" {
 				rsl.Log.Debugf("Failed to read header")
-				riemannReturnErrorResponse(conn, err.Error())
+				rsl.riemannReturnErrorResponse(conn, err.Error())
 				return
 			}
 			return
@@ -188,19 +180,19 @@ func (rsl *riemannListener) read(conn net.Conn) {
 
 		if err = readMessages(conn, data); err != nil {
 			rsl.Log.Debugf("Failed to read body: %s", err.Error())
-			riemannReturnErrorResponse(conn, "Failed to read body")
+			rsl.riemannReturnErrorResponse(conn, "Failed to read body")
 			return
 		}
 		if err = proto.Unmarshal(data, messagePb); err != nil {
 			rsl.Log.Debugf("Failed to unmarshal: %s", err.Error())
-			riemannReturnErrorResponse(conn, "Failed to unmarshal")
+			rsl.riemannReturnErrorResponse(conn, "Failed to unmarshal")
 			return
 		}
 		riemannEvents := riemanngo.ProtocolBuffersToEvents(messagePb.Events)
 
 		for _, m := range riemannEvents {
 			if m.Service == "" {
-				riemannReturnErrorResponse(conn, "No Service Name")
+				rsl.riemannReturnErrorResponse(conn, "No Service Name")
 				return
 			}
 			tags := make(map[string]string)
@@ -216,61 +208,62 @@ func (rsl *riemannListener) read(conn net.Conn) {
 			singleMetric, err := metric.New(m.Service, tags, fieldValues, m.Time, cua.Untyped)
 			if err != nil {
 				rsl.Log.Debugf("Could not create metric for service %s at %s", m.Service, m.Time.String())
-				riemannReturnErrorResponse(conn, "Could not create metric")
+				rsl.riemannReturnErrorResponse(conn, "Could not create metric")
 				return
 			}
 
 			rsl.AddMetric(singleMetric)
 		}
-		riemannReturnResponse(conn)
+		rsl.riemannReturnResponse(conn)
 
 	}
 
 }
 
-func riemannReturnResponse(conn io.Writer) {
+func (rsl *riemannListener) riemannReturnResponse(conn io.Writer) {
 	t := true
 	message := new(riemangoProto.Msg)
 	message.Ok = &t
 	returnData, err := proto.Marshal(message)
 	if err != nil {
-		checkError(err)
+		rsl.Log.Errorf("Marshaling the response: %v", err)
 		return
 	}
 	b := new(bytes.Buffer)
 	if err = binary.Write(b, binary.BigEndian, uint32(len(returnData))); err != nil {
-		checkError(err)
+		rsl.Log.Errorf("Writing the response length: %v", err)
 	}
 	// send the msg length
 	if _, err = conn.Write(b.Bytes()); err != nil {
-		checkError(err)
+		rsl.Log.Errorf("Writing the response length: %v", err)
+		return
 	}
 	if _, err = conn.Write(returnData); err != nil {
-		checkError(err)
+		rsl.Log.Errorf("Writing the response: %v", err)
 	}
 }
 
-func riemannReturnErrorResponse(conn io.Writer, errorMessage string) {
+func (rsl *riemannListener) riemannReturnErrorResponse(conn io.Writer, errorMessage string) {
 	t := false
 	message := new(riemangoProto.Msg)
 	message.Ok = &t
 	message.Error = &errorMessage
 	returnData, err := proto.Marshal(message)
 	if err != nil {
-		checkError(err)
+		rsl.Log.Errorf("Marshaling the response: %v", err)
 		return
 	}
 	b := new(bytes.Buffer)
 	if err = binary.Write(b, binary.BigEndian, uint32(len(returnData))); err != nil {
-		checkError(err)
+		rsl.Log.Errorf("Writing the response length: %v", err)
 	}
 	// send the msg length
 	if _, err = conn.Write(b.Bytes()); err != nil {
-		checkError(err)
+		rsl.Log.Errorf("Writing the response length: %v", err)
+		return
 	}
 	if _, err = conn.Write(returnData); err != nil {
-		log.Println("Something")
-		checkError(err)
+		rsl.Log.Errorf("Writing the response: %v", err)
 	}
 }
 
@@ -313,7 +306,7 @@ func (rsl *RiemannSocketListener) Gather(_ cua.Accumulator) error {
 
 func (rsl *RiemannSocketListener) Start(acc cua.Accumulator) error {
 	ctx, cancelFunc := context.WithCancel(context.Background())
-	go processOsSignals(cancelFunc)
+	go rsl.processOsSignals(cancelFunc)
 	rsl.Accumulator = acc
 	if rsl.ServiceAddress == "" {
 		rsl.Log.Warnf("Using default service_address tcp://:5555")
@@ -367,13 +360,13 @@ func (rsl *RiemannSocketListener) Start(acc cua.Accumulator) error {
 }
 
 // Handle cancellations from the process
-func processOsSignals(cancelFunc context.CancelFunc) {
+func (rsl *RiemannSocketListener) processOsSignals(cancelFunc context.CancelFunc) {
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt)
 	for {
 		sig := <-signalChan
 		if sig == os.Interrupt {
-			log.Println("Signal SIGINT is received, probably due to `Ctrl-C`, exiting ...")
+			rsl.Log.Info("Signal SIGINT is received, probably due to `Ctrl-C`, exiting ...")
 			cancelFunc()
 			return
 		}
@@ -0,0 +1,252 @@
+package snmp
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// sysObjectIDOid is the OID of SNMPv2-MIB::sysObjectID.0, identifying the
+// vendor and model of a device.
+const sysObjectIDOid = ".1.3.6.1.2.1.1.2.0"
+
+// builtinProfiles are the device profiles shipped with the agent.
+//
+//go:embed profiles/*.yaml
+var builtinProfiles embed.FS
+
+// Profile holds the fields and tables collected from the devices whose
+// sysObjectID matches one of its patterns.
+type Profile struct {
+	// Name identifies the profile, it defaults to the name of the file.
+	Name string `yaml:"name"`
+	// SysObjectID are the patterns matched against the sysObjectID of the
+	// devices, an OID, an OID prefix followed by ".*", or "*" for any device.
+	// A profile without patterns is only used through extends.
+	SysObjectID []string `yaml:"sysobjectid"`
+	// Extends are the names of the profiles whose fields and tables are
+	// collected along with the ones of this profile.
+	Extends []string       `yaml:"extends"`
+	Fields  []profileField `yaml:"fields"`
+	Tables  []profileTable `yaml:"tables"`
+
+	fields []Field
+	tables []Table
+
+	resolved bool
+	once     sync.Once
+	err      error
+}
+
+type profileField struct {
+	Name           string `yaml:"name"`
+	Oid            string `yaml:"oid"`
+	OidIndexSuffix string `yaml:"oid_index_suffix"`
+	OidIndexLength int    `yaml:"oid_index_length"`
+	IsTag          bool   `yaml:"is_tag"`
+	Conversion     string `yaml:"conversion"`
+	Translate      bool   `yaml:"translate"`
+}
+
+type profileTable struct {
+	Name        string         `yaml:"name"`
+	InheritTags []string       `yaml:"inherit_tags"`
+	IndexAsTag  bool           `yaml:"index_as_tag"`
+	Fields      []profileField `yaml:"fields"`
+}
+
+func (f profileField) field() Field {
+	return Field{
+		Name:           f.Name,
+		Oid:            f.Oid,
+		OidIndexSuffix: f.OidIndexSuffix,
+		OidIndexLength: f.OidIndexLength,
+		IsTag:          f.IsTag,
+		Conversion:     f.Conversion,
+		Translate:      f.Translate,
+	}
+}
+
+func (t profileTable) table() Table {
+	table := Table{
+		Name:        t.Name,
+		InheritTags: t.InheritTags,
+		IndexAsTag:  t.IndexAsTag,
+	}
+	for _, f := range t.Fields {
+		table.Fields = append(table.Fields, f.field())
+	}
+	return table
+}
+
+// loadProfiles reads the builtin profiles, then the profiles of dirs which
+// replace the builtin profiles of the same name.
+func loadProfiles(dirs []string) (map[string]*Profile, error) {
+	profiles := map[string]*Profile{}
+
+	entries, err := builtinProfiles.ReadDir("profiles")
+	if err != nil {
+		return nil, fmt.Errorf("reading builtin profiles: %w", err)
+	}
+	for _, entry := range entries {
+		b, err := builtinProfiles.ReadFile("profiles/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("reading builtin profile %s: %w", entry.Name(), err)
+		}
+		if err := addProfile(profiles, entry.Name(), b); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("reading profiles: %w", err)
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("reading profile: %w", err)
+			}
+			if err := addProfile(profiles, path, b); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, p := range profiles {
+		if err := p.resolve(profiles, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+func addProfile(profiles map[string]*Profile, path string, b []byte) error {
+	p := &Profile{}
+	if err := yaml.UnmarshalStrict(b, p); err != nil {
+		return fmt.Errorf("parsing profile %s: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	profiles[p.Name] = p
+	return nil
+}
+
+// resolve builds the fields and tables of a profile from the ones of the
+// profiles it extends, a table replaces an extended table of the same name.
+func (p *Profile) resolve(profiles map[string]*Profile, visiting map[string]bool) error {
+	if p.resolved {
+		return nil
+	}
+	if visiting[p.Name] {
+		return fmt.Errorf("profile %s: circular extends", p.Name)
+	}
+	visiting[p.Name] = true
+
+	var tables []Table
+	addTable := func(t Table) {
+		for i := range tables {
+			if tables[i].Name == t.Name {
+				tables[i] = t
+				return
+			}
+		}
+		tables = append(tables, t)
+	}
+
+	for _, name := range p.Extends {
+		base, ok := profiles[name]
+		if !ok {
+			return fmt.Errorf("profile %s: unknown profile %q in extends", p.Name, name)
+		}
+		if err := base.resolve(profiles, visiting); err != nil {
+			return err
+		}
+		p.fields = append(p.fields, base.fields...)
+		for _, t := range base.tables {
+			// copy the fields, each profile initializes its own tables
+			t.Fields = append([]Field(nil), t.Fields...)
+			addTable(t)
+		}
+	}
+	for _, f := range p.Fields {
+		p.fields = append(p.fields, f.field())
+	}
+	for _, t := range p.Tables {
+		addTable(t.table())
+	}
+	p.tables = tables
+
+	p.resolved = true
+	return nil
+}
+
+// init translates the OIDs of the profile on its first use, the profiles
+// may be shared by the agents gathered concurrently.
+func (p *Profile) init() error {
+	p.once.Do(func() {
+		for i := range p.fields {
+			if err := p.fields[i].init(); err != nil {
+				p.err = fmt.Errorf("profile %s: initializing field %s: %w", p.Name, p.fields[i].Name, err)
+				return
+			}
+		}
+		for i := range p.tables {
+			if err := p.tables[i].Init(); err != nil {
+				p.err = fmt.Errorf("profile %s: initializing table %s: %w", p.Name, p.tables[i].Name, err)
+				return
+			}
+		}
+	})
+	return p.err
+}
+
+// matchProfile returns the profile with the most specific pattern matching
+// the sysObjectID, an exact OID being more specific than any prefix.
+func matchProfile(profiles map[string]*Profile, sysObjectID string) *Profile {
+	id := strings.TrimPrefix(sysObjectID, ".")
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var match *Profile
+	best := -1
+	for _, name := range names {
+		p := profiles[name]
+		for _, pattern := range p.SysObjectID {
+			pattern = strings.TrimPrefix(pattern, ".")
+			score := -1
+			switch {
+			case pattern == "*":
+				score = 0
+			case strings.HasSuffix(pattern, ".*"):
+				if strings.HasPrefix(id, strings.TrimSuffix(pattern, "*")) {
+					score = 2 * len(pattern)
+				}
+			case pattern == id:
+				score = 2*len(pattern) + 1
+			}
+			if score > best {
+				best = score
+				match = p
+			}
+		}
+	}
+	return match
+}
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"net"
 	"os/exec"
@@ -17,6 +16,7 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/internal"
 	"github.com/circonus-labs/circonus-unified-agent/internal/snmp"
+	"github.com/circonus-labs/circonus-unified-agent/models"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
 	"github.com/influxdata/wlog"
 	"github.com/soniah/gosnmp"
@@ -64,6 +64,15 @@ const sampleConfig = `
   ## Privacy password used for encrypted messages.
   # priv_password = ""
 
+  ## Collect the fields and tables of the device profiles matched by the
+  ## sysObjectID of the agents, like the interfaces, CPU, memory and
+  ## temperatures of common switches and routers.
+  # use_profiles = false
+
+  ## Directories of YAML device profiles, replacing the profiles shipped
+  ## with the agent of the same name.
+  # profile_dirs = ["/etc/circonus-unified-agent/snmp_profiles"]
+
   ## Add fields and tables defining the variables you wish to collect.  This
   ## example collects the system uptime and interface variables.  Reference the
   ## full plugin documentation for configuration details.
@@ -72,6 +81,10 @@ const sampleConfig = `
 // execCommand is so tests can mock out exec.Command usage.
 var execCommand = exec.Command
 
+// execLog logs the commands, they run for the translation caches shared by
+// all the snmp inputs rather than for a single plugin.
+var execLog cua.Logger = models.NewLogger("inputs", "snmp", "")
+
 // execCmd executes the specified command, returning the STDOUT content.
 // If command exits with error status, the output is captured into the returned error.
 func execCmd(arg0 string, args ...string) ([]byte, error) {
@@ -80,7 +93,7 @@ func execCmd(arg0 string, args ...string) ([]byte, error) {
 		for _, arg := range args {
 			quoted = append(quoted, fmt.Sprintf("%q", arg))
 		}
-		log.Printf("D! [inputs.snmp] executing %q %s", arg0, strings.Join(quoted, " "))
+		execLog.Debugf("Executing %q %s", arg0, strings.Join(quoted, " "))
 	}
 
 	out, err := execCommand(arg0, args...).Output()
@@ -113,8 +126,18 @@ type Snmp struct {
 	Name   string  // deprecated in 1.14; use name_override
 	Fields []Field `toml:"field"`
 
+	// UseProfiles enables the device profiles matched by sysObjectID.
+	UseProfiles bool     `toml:"use_profiles"`
+	ProfileDirs []string `toml:"profile_dirs"`
+
+	Log cua.Logger `toml:"-"`
+
 	connectionCache []snmpConnection
 	initialized     bool
+
+	profiles       map[string]*Profile
+	agentProfiles  []*Profile
+	profileMatched []bool
 }
 
 func (s *Snmp) init() error {
@@ -140,6 +163,16 @@ func (s *Snmp) init() error {
 		s.AgentHostTag = "agent_host"
 	}
 
+	if s.UseProfiles {
+		profiles, err := loadProfiles(s.ProfileDirs)
+		if err != nil {
+			return err
+		}
+		s.profiles = profiles
+		s.agentProfiles = make([]*Profile, len(s.Agents))
+		s.profileMatched = make([]bool, len(s.Agents))
+	}
+
 	s.initialized = true
 	return nil
 }
@@ -350,13 +383,23 @@ func (s *Snmp) Gather(acc cua.Accumulator) error {
 				Name:   s.Name,
 				Fields: s.Fields,
 			}
+			tables := s.Tables
+			p, err := s.agentProfile(i, gs)
+			if err != nil {
+				acc.AddError(fmt.Errorf("agent %s: %w", agent, err))
+			}
+			if p != nil {
+				// the full slice expressions do not let append modify the configured fields and tables
+				t.Fields = append(t.Fields[:len(t.Fields):len(t.Fields)], p.fields...)
+				tables = append(tables[:len(tables):len(tables)], p.tables...)
+			}
 			topTags := map[string]string{}
 			if err := s.gatherTable(acc, gs, t, topTags, false); err != nil {
 				acc.AddError(fmt.Errorf("agent %s: %w", agent, err))
 			}
 
 			// Now is the real tables.
-			for _, t := range s.Tables {
+			for _, t := range tables {
 				if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
 					acc.AddError(fmt.Errorf("agent %s: gathering table %s: %w", agent, t.Name, err))
 				}
@@ -368,6 +411,42 @@ func (s *Snmp) Gather(acc cua.Accumulator) error {
 	return nil
 }
 
+// agentProfile returns the device profile matching the sysObjectID of an
+// agent, the match is kept for the following gathers.
+func (s *Snmp) agentProfile(idx int, gs snmpConnection) (*Profile, error) {
+	if s.profiles == nil {
+		return nil, nil
+	}
+	if s.profileMatched[idx] {
+		return s.agentProfiles[idx], nil
+	}
+
+	pkt, err := gs.Get([]string{sysObjectIDOid})
+	if err != nil {
+		return nil, fmt.Errorf("getting sysObjectID: %w", err)
+	}
+	if len(pkt.Variables) == 0 {
+		return nil, errors.New("getting sysObjectID: no value")
+	}
+	sysObjectID, ok := pkt.Variables[0].Value.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected sysObjectID %v", pkt.Variables[0].Value)
+	}
+
+	p := matchProfile(s.profiles, sysObjectID)
+	if p == nil {
+		s.Log.Debugf("Agent %s: no profile matching sysObjectID %s", s.Agents[idx], sysObjectID)
+	} else {
+		if err := p.init(); err != nil {
+			return nil, err
+		}
+		s.Log.Debugf("Agent %s: sysObjectID %s matches profile %s", s.Agents[idx], sysObjectID, p.Name)
+	}
+	s.agentProfiles[idx] = p
+	s.profileMatched[idx] = true
+	return p, nil
+}
+
 func (s *Snmp) gatherTable(acc cua.Accumulator, gs snmpConnection, t Table, topTags map[string]string, walk bool) error {
 	rt, err := t.Build(gs, walk)
 	if err != nil {
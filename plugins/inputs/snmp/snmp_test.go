@@ -4,7 +4,9 @@ package snmp
 import (
 	"fmt"
 	"net"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -814,3 +816,119 @@ func TestSnmpTableCache_hit(t *testing.T) {
 	assert.Equal(t, []Field{{Name: "d"}}, fields)
 	assert.Equal(t, fmt.Errorf("e"), err)
 }
+
+func TestLoadProfiles(t *testing.T) {
+	profiles, err := loadProfiles(nil)
+	require.NoError(t, err)
+
+	cisco := matchProfile(profiles, ".1.3.6.1.4.1.9.1.1208")
+	require.NotNil(t, cisco)
+	assert.Equal(t, "cisco", cisco.Name)
+	tables := []string{}
+	for _, table := range cisco.tables {
+		tables = append(tables, table.Name)
+	}
+	assert.Equal(t, []string{"snmp_interface", "snmp_cpu", "snmp_memory", "snmp_temperature"}, tables)
+	assert.Len(t, cisco.fields, 2)
+
+	// the profiles only extended are never matched
+	assert.Equal(t, "generic", matchProfile(profiles, ".1.3.6.1.4.1.99999.1").Name)
+	assert.Equal(t, "net-snmp", matchProfile(profiles, ".1.3.6.1.4.1.8072.3.2.10").Name)
+}
+
+func TestLoadProfiles_dirs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mydevice.yaml"), []byte(`
+sysobjectid:
+  - .1.3.6.1.4.1.9.1.1208
+extends:
+  - cisco
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "generic.yml"), []byte(`
+name: generic
+sysobjectid:
+  - "*"
+`), 0600))
+
+	profiles, err := loadProfiles([]string{dir})
+	require.NoError(t, err)
+	p := matchProfile(profiles, "1.3.6.1.4.1.9.1.1208")
+	require.NotNil(t, p)
+	assert.Equal(t, "mydevice", p.Name)
+	// the extended cisco profile uses the replaced generic profile
+	assert.Empty(t, p.fields)
+	assert.Len(t, p.tables, 3)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "loop.yaml"), []byte(`
+extends:
+  - loop
+`), 0600))
+	_, err = loadProfiles([]string{dir})
+	require.Error(t, err)
+}
+
+func TestGather_profile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mydevice.yaml"), []byte(`
+name: mydevice
+sysobjectid:
+  - .1.0.0.9.*
+fields:
+  - name: myProfileField
+    oid: .1.0.0.1.2
+tables:
+  - name: myProfileTable
+    inherit_tags:
+      - myfield1
+    fields:
+      - name: myProfileTableField
+        oid: .1.0.0.0.1.5
+`), 0600))
+	TranslateForce(".1.0.0.1.2", "", ".1.0.0.1.2", "", "")
+	TranslateForce(".1.0.0.0.1.5", "", ".1.0.0.0.1.5", "", "")
+
+	conn := &testSNMPConnection{
+		host:   "tsc",
+		values: map[string]interface{}{sysObjectIDOid: ".1.0.0.9.1"},
+	}
+	for oid, v := range tsc.values {
+		conn.values[oid] = v
+	}
+
+	s := &Snmp{
+		Agents: []string{"TestGather"},
+		Name:   "mytable",
+		Fields: []Field{
+			{
+				Name:        "myfield1",
+				Oid:         ".1.0.0.1.1",
+				IsTag:       true,
+				initialized: true,
+			},
+		},
+		UseProfiles:     true,
+		ProfileDirs:     []string{dir},
+		Log:             testutil.Logger{},
+		connectionCache: []snmpConnection{conn},
+	}
+	s.profiles, _ = loadProfiles(s.ProfileDirs)
+	s.agentProfiles = make([]*Profile, 1)
+	s.profileMatched = make([]bool, 1)
+	s.initialized = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	m, ok := acc.Get("mytable")
+	require.True(t, ok)
+	assert.Equal(t, "baz", m.Tags["myfield1"])
+	assert.Equal(t, 234, m.Fields["myProfileField"])
+	m, ok = acc.Get("myProfileTable")
+	require.True(t, ok)
+	assert.Equal(t, "baz", m.Tags["myfield1"])
+	assert.Equal(t, 123456, m.Fields["myProfileTableField"])
+
+	// the configured fields are not extended by the profile
+	assert.Len(t, s.Fields, 1)
+}
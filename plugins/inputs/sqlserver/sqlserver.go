@@ -3,7 +3,6 @@ package sqlserver
 import (
 	"database/sql"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
@@ -23,6 +22,8 @@ type SQLServer struct {
 	ExcludeQuery  []string `toml:"exclude_query"`
 	queries       MapQuery
 	isInitialized bool
+
+	Log cua.Logger `toml:"-"`
 }
 
 // Query struct
@@ -121,7 +122,7 @@ type scanner interface {
 func initQueries(s *SQLServer) error {
 	s.queries = make(MapQuery)
 	queries := s.queries
-	log.Printf("I! [inputs.sqlserver] Config: database_type: %s , query_version:%d , azuredb: %t", s.DatabaseType, s.QueryVersion, s.AzureDB)
+	s.Log.Infof("Config: database_type: %s , query_version:%d , azuredb: %t", s.DatabaseType, s.QueryVersion, s.AzureDB)
 
 	// New config option database_type
 	// To prevent query definition conflicts
@@ -168,7 +169,7 @@ func initQueries(s *SQLServer) error {
 		}
 		// Decide if we want to run version 1 or version 2 queries
 		if s.QueryVersion == 2 {
-			log.Println("W! DEPRECATION NOTICE: query_version=2 is being deprecated in favor of database_type.")
+			s.Log.Warn("DEPRECATION NOTICE: query_version=2 is being deprecated in favor of database_type.")
 			queries["PerformanceCounters"] = Query{ScriptName: "PerformanceCounters", Script: sqlPerformanceCountersV2, ResultByRow: true}
 			queries["WaitStatsCategorized"] = Query{ScriptName: "WaitStatsCategorized", Script: sqlWaitStatsCategorizedV2, ResultByRow: false}
 			queries["DatabaseIO"] = Query{ScriptName: "DatabaseIO", Script: sqlDatabaseIOV2, ResultByRow: false}
@@ -179,7 +180,7 @@ func initQueries(s *SQLServer) error {
 			queries["VolumeSpace"] = Query{ScriptName: "VolumeSpace", Script: sqlServerVolumeSpaceV2, ResultByRow: false}
 			queries["Cpu"] = Query{ScriptName: "Cpu", Script: sqlServerCPUV2, ResultByRow: false}
 		} else {
-			log.Println("W! DEPRECATED: query_version=1 has been deprecated in favor of database_type.")
+			s.Log.Warn("DEPRECATED: query_version=1 has been deprecated in favor of database_type.")
 			queries["PerformanceCounters"] = Query{ScriptName: "PerformanceCounters", Script: sqlPerformanceCounters, ResultByRow: true}
 			queries["WaitStatsCategorized"] = Query{ScriptName: "WaitStatsCategorized", Script: sqlWaitStatsCategorized, ResultByRow: false}
 			queries["CPUHistory"] = Query{ScriptName: "CPUHistory", Script: sqlCPUHistory, ResultByRow: false}
@@ -210,7 +211,7 @@ func initQueries(s *SQLServer) error {
 	for query := range queries {
 		querylist = append(querylist, query)
 	}
-	log.Printf("I! [inputs.sqlserver] Config: Effective Queries: %#v\n", querylist)
+	s.Log.Infof("Config: Effective Queries: %#v", querylist)
 
 	return nil
 }
@@ -29,6 +29,7 @@ func TestSqlServer_QueriesInclusionExclusion(t *testing.T) {
 
 	for _, test := range cases {
 		s := SQLServer{
+			Log:          testutil.Logger{},
 			QueryVersion: 2,
 			IncludeQuery: test["IncludeQuery"].([]string),
 			ExcludeQuery: test["ExcludeQuery"].([]string),
@@ -120,10 +121,12 @@ func TestSqlServer_MultipleInstance(t *testing.T) {
 	}
 	testServer := "Server=127.0.0.1;Port=1433;User Id=SA;Password=ABCabc01;app name=cua;log=1"
 	s := &SQLServer{
+		Log:          testutil.Logger{},
 		Servers:      []string{testServer},
 		ExcludeQuery: []string{"MemoryClerk"},
 	}
 	s2 := &SQLServer{
+		Log:          testutil.Logger{},
 		Servers:      []string{testServer},
 		ExcludeQuery: []string{"DatabaseSize"},
 	}
@@ -150,8 +153,9 @@ func TestSqlServer_MultipleInstance(t *testing.T) {
 
 func TestSqlServer_MultipleInit(t *testing.T) {
 
-	s := &SQLServer{}
+	s := &SQLServer{Log: testutil.Logger{}}
 	s2 := &SQLServer{
+		Log:          testutil.Logger{},
 		ExcludeQuery: []string{"DatabaseSize"},
 	}
 
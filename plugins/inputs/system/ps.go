@@ -54,14 +54,14 @@ type SysPSDisk struct{}
 func (s *SysPS) CPUTimes(perCPU, totalCPU bool) ([]cpu.TimesStat, error) {
 	var cpuTimes []cpu.TimesStat
 	if perCPU {
-		if perCPUTimes, err := cpu.Times(true); err == nil {
+		if perCPUTimes, err := cpuTimeStats(true); err == nil {
 			cpuTimes = append(cpuTimes, perCPUTimes...)
 		} else {
 			return nil, fmt.Errorf("cpu times (percpu): %w", err)
 		}
 	}
 	if totalCPU {
-		if totalCPUTimes, err := cpu.Times(false); err == nil {
+		if totalCPUTimes, err := cpuTimeStats(false); err == nil {
 			cpuTimes = append(cpuTimes, totalCPUTimes...)
 		} else {
 			return nil, fmt.Errorf("cpu times (tot): %w", err)
@@ -146,7 +146,7 @@ func (s *SysPS) NetProto() ([]net.ProtoCountersStat, error) {
 }
 
 func (s *SysPS) NetIO() ([]net.IOCountersStat, error) {
-	return net.IOCounters(true)
+	return netIOCounters()
 }
 
 func (s *SysPS) NetConnections() ([]net.ConnectionStat, error) {
@@ -154,7 +154,7 @@ func (s *SysPS) NetConnections() ([]net.ConnectionStat, error) {
 }
 
 func (s *SysPS) DiskIO(names []string) (map[string]disk.IOCountersStat, error) {
-	m, err := disk.IOCounters(names...)
+	m, err := diskIOCounters(names...)
 	if err != nil {
 		if errors.Is(err, internal.ErrNotImplemented) {
 			return nil, nil
@@ -166,11 +166,11 @@ func (s *SysPS) DiskIO(names []string) (map[string]disk.IOCountersStat, error) {
 }
 
 func (s *SysPS) VMStat() (*mem.VirtualMemoryStat, error) {
-	return mem.VirtualMemory()
+	return virtualMemory()
 }
 
 func (s *SysPS) SwapStat() (*mem.SwapMemoryStat, error) {
-	return mem.SwapMemory()
+	return swapMemory()
 }
 
 func (s *SysPS) Temperature() ([]host.TemperatureStat, error) {
@@ -185,7 +185,7 @@ func (s *SysPS) Temperature() ([]host.TemperatureStat, error) {
 }
 
 func (s *SysPSDisk) Partitions(all bool) ([]disk.PartitionStat, error) {
-	return disk.Partitions(all)
+	return diskPartitions(all)
 }
 
 func (s *SysPSDisk) OSGetenv(key string) string {
@@ -197,5 +197,5 @@ func (s *SysPSDisk) OSStat(name string) (os.FileInfo, error) {
 }
 
 func (s *SysPSDisk) PSDiskUsage(path string) (*disk.UsageStat, error) {
-	return disk.Usage(path)
+	return diskUsage(path)
 }
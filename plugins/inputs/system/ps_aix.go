@@ -0,0 +1,77 @@
+// +build aix
+
+package system
+
+import (
+	"fmt"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+	"golang.org/x/sys/unix"
+)
+
+// gopsutil only reads the network counters on AIX, the cpu times and the
+// memory are read with the vmstat(1) and svmon(1) commands, the file systems
+// with mount(1) and statfs(2).  The perfstat library requires cgo, which the
+// agent is built without.
+
+func cpuTimeStats(perCPU bool) ([]cpu.TimesStat, error) {
+	if perCPU {
+		// vmstat only reports the totals
+		return nil, nil
+	}
+	out, err := runCmd("vmstat", "-s")
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseVmstatSum(out)
+	if err != nil {
+		return nil, err
+	}
+	return []cpu.TimesStat{*t}, nil
+}
+
+func virtualMemory() (*mem.VirtualMemoryStat, error) {
+	out, err := runCmd("svmon", "-G", "-O", "unit=KB")
+	if err != nil {
+		return nil, err
+	}
+	vm, _, err := parseSvmon(out)
+	return vm, err
+}
+
+func swapMemory() (*mem.SwapMemoryStat, error) {
+	out, err := runCmd("svmon", "-G", "-O", "unit=KB")
+	if err != nil {
+		return nil, err
+	}
+	_, swap, err := parseSvmon(out)
+	return swap, err
+}
+
+func diskPartitions(all bool) ([]disk.PartitionStat, error) {
+	out, err := runCmd("mount")
+	if err != nil {
+		return nil, err
+	}
+	return parseAIXMount(out, all), nil
+}
+
+func diskUsage(path string) (*disk.UsageStat, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return nil, fmt.Errorf("statfs (%s): %w", path, err)
+	}
+	return usageFromStatfs(path, stat.Bsize, stat.Blocks, stat.Bfree, stat.Bavail, stat.Files, stat.Ffree), nil
+}
+
+func diskIOCounters(names ...string) (map[string]disk.IOCountersStat, error) {
+	return nil, internal.ErrNotImplemented
+}
+
+func netIOCounters() ([]net.IOCountersStat, error) {
+	return net.IOCounters(true)
+}
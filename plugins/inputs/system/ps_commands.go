@@ -0,0 +1,349 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+)
+
+// The statistics gopsutil does not implement on Solaris, illumos and AIX are
+// read from the output of the system commands, the parsers are kept free of
+// build constraints to be tested on every platform.
+
+const (
+	cmdTimeout = 5 * time.Second
+	// clock ticks per second of the cpu times reported by AIX
+	aixClockTicks = 100
+)
+
+// runCmd runs a command and returns its standard output, replaced by the tests.
+var runCmd = func(name string, args ...string) ([]byte, error) { //nolint:unused // used on solaris and aix
+	out, err := internal.StdOutputTimeout(exec.Command(name, args...), cmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
+
+// kstatStat is a statistic of the parseable output of kstat -p, such as
+// "sd:0:sd0:nread	1024".
+type kstatStat struct {
+	module string
+	name   string
+	stat   string
+	value  string
+}
+
+func parseKstat(out []byte) []kstatStat {
+	var stats []kstatStat
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		key := strings.SplitN(fields[0], ":", 4)
+		if len(key) != 4 {
+			continue
+		}
+		stats = append(stats, kstatStat{module: key[0], name: key[2], stat: key[3], value: fields[1]})
+	}
+	return stats
+}
+
+// kstatMillis converts a kstat high resolution time to milliseconds, kstat
+// prints the times in seconds with a fractional part, older versions in
+// nanoseconds.
+func kstatMillis(value string) uint64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	if strings.Contains(value, ".") {
+		return uint64(v * 1e3)
+	}
+	return uint64(v / 1e6)
+}
+
+// parseKstatDisk parses the io statistics of the disk class, the names
+// limit the disks returned when not empty.
+func parseKstatDisk(out []byte, names []string) map[string]disk.IOCountersStat {
+	counters := make(map[string]disk.IOCountersStat)
+	for _, s := range parseKstat(out) {
+		if len(names) > 0 && !contains(names, s.name) {
+			continue
+		}
+		c := counters[s.name]
+		c.Name = s.name
+		value, _ := strconv.ParseUint(s.value, 10, 64)
+		switch s.stat {
+		case "reads":
+			c.ReadCount = value
+		case "writes":
+			c.WriteCount = value
+		case "nread":
+			c.ReadBytes = value
+		case "nwritten":
+			c.WriteBytes = value
+		case "rcnt":
+			c.IopsInProgress = value
+		case "rtime":
+			c.IoTime = kstatMillis(s.value)
+		case "rlentime":
+			c.WeightedIO = kstatMillis(s.value)
+		default:
+			continue
+		}
+		counters[s.name] = c
+	}
+	return counters
+}
+
+// parseKstatNet parses the statistics of the link module.
+func parseKstatNet(out []byte) []net.IOCountersStat {
+	links := make(map[string]*net.IOCountersStat)
+	for _, s := range parseKstat(out) {
+		if s.module != "link" {
+			continue
+		}
+		value, err := strconv.ParseUint(s.value, 10, 64)
+		if err != nil {
+			continue
+		}
+		c, ok := links[s.name]
+		if !ok {
+			c = &net.IOCountersStat{Name: s.name}
+			links[s.name] = c
+		}
+		switch s.stat {
+		case "rbytes64":
+			c.BytesRecv = value
+		case "obytes64":
+			c.BytesSent = value
+		case "ipackets64":
+			c.PacketsRecv = value
+		case "opackets64":
+			c.PacketsSent = value
+		case "ierrors":
+			c.Errin = value
+		case "oerrors":
+			c.Errout = value
+		case "norcvbuf":
+			c.Dropin = value
+		case "noxmtbuf":
+			c.Dropout = value
+		}
+	}
+
+	counters := make([]net.IOCountersStat, 0, len(links))
+	for _, c := range links {
+		counters = append(counters, *c)
+	}
+	sort.Slice(counters, func(i, j int) bool { return counters[i].Name < counters[j].Name })
+	return counters
+}
+
+var swapSummaryRe = regexp.MustCompile(`=\s*(\d+)k used,\s*(\d+)k available`)
+
+// parseSwapSummary parses the output of swap -s, such as "total: 143632k
+// bytes allocated + 12468k reserved = 156100k used, 4032648k available".
+func parseSwapSummary(out []byte) (*mem.SwapMemoryStat, error) {
+	m := swapSummaryRe.FindSubmatch(out)
+	if m == nil {
+		return nil, fmt.Errorf("unexpected swap summary %q", strings.TrimSpace(string(out)))
+	}
+	used, _ := strconv.ParseUint(string(m[1]), 10, 64)
+	free, _ := strconv.ParseUint(string(m[2]), 10, 64)
+	swap := &mem.SwapMemoryStat{
+		Total: (used + free) * 1024,
+		Used:  used * 1024,
+		Free:  free * 1024,
+	}
+	if swap.Total > 0 {
+		swap.UsedPercent = float64(swap.Used) / float64(swap.Total) * 100
+	}
+	return swap, nil
+}
+
+// parseVmstatSum parses the cpu ticks of the output of the AIX vmstat -s.
+func parseVmstatSum(out []byte) (*cpu.TimesStat, error) {
+	t := &cpu.TimesStat{CPU: "cpu-total"}
+	var found bool
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasSuffix(line, "cpu ticks") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		ticks, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || len(fields) != 2 {
+			continue
+		}
+		seconds := ticks / aixClockTicks
+		switch strings.TrimSpace(strings.TrimSuffix(fields[1], "cpu ticks")) {
+		case "user":
+			t.User = seconds
+		case "system":
+			t.System = seconds
+		case "idle":
+			t.Idle = seconds
+		case "I/O wait":
+			t.Iowait = seconds
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("no cpu ticks in vmstat output")
+	}
+	return t, nil
+}
+
+// parseSvmon parses the memory and paging space of the output of the AIX
+// svmon -G, in the unit of its header or in 4 KB pages without header.
+func parseSvmon(out []byte) (*mem.VirtualMemoryStat, *mem.SwapMemoryStat, error) {
+	var vm *mem.VirtualMemoryStat
+	var swap *mem.SwapMemoryStat
+	unit := uint64(4096)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "Unit:") && len(fields) == 2:
+			switch fields[1] {
+			case "KB":
+				unit = 1 << 10
+			case "MB":
+				unit = 1 << 20
+			case "GB":
+				unit = 1 << 30
+			}
+		case strings.HasPrefix(line, "memory") && len(fields) >= 5:
+			values := parseUints(fields[1:], unit)
+			vm = &mem.VirtualMemoryStat{
+				Total:     values[0],
+				Used:      values[1],
+				Free:      values[2],
+				Wired:     values[3],
+				Available: values[2],
+			}
+			if len(values) > 5 {
+				vm.Available = values[5]
+			}
+			if vm.Total > 0 {
+				vm.UsedPercent = float64(vm.Used) / float64(vm.Total) * 100
+			}
+		case strings.HasPrefix(line, "pg space") && len(fields) >= 4:
+			values := parseUints(fields[2:], unit)
+			swap = &mem.SwapMemoryStat{Total: values[0], Used: values[1]}
+			if swap.Total >= swap.Used {
+				swap.Free = swap.Total - swap.Used
+			}
+			if swap.Total > 0 {
+				swap.UsedPercent = float64(swap.Used) / float64(swap.Total) * 100
+			}
+		}
+	}
+	if vm == nil || swap == nil {
+		return nil, nil, fmt.Errorf("no memory or paging space in svmon output")
+	}
+	return vm, swap, nil
+}
+
+// parseUints parses the leading numeric fields multiplied by the unit.
+func parseUints(fields []string, unit uint64) []uint64 {
+	values := make([]uint64, 0, len(fields))
+	for _, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			break
+		}
+		values = append(values, v*unit)
+	}
+	return values
+}
+
+// pseudo file systems excluded from the partitions unless all are requested
+var aixPseudoFS = map[string]bool{"procfs": true, "ahafs": true, "namefs": true, "autofs": true}
+
+// parseAIXMount parses the output of the AIX mount, the remote file systems
+// have a node in the first column.
+func parseAIXMount(out []byte, all bool) []disk.PartitionStat {
+	var parts []disk.PartitionStat
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] == "node" || strings.HasPrefix(fields[0], "---") {
+			continue
+		}
+
+		var p disk.PartitionStat
+		remote := line[0] != ' ' && line[0] != '\t'
+		if remote {
+			if len(fields) < 4 {
+				continue
+			}
+			p = disk.PartitionStat{Device: fields[0] + ":" + fields[1], Mountpoint: fields[2], Fstype: fields[3]}
+			fields = fields[1:]
+		} else {
+			p = disk.PartitionStat{Device: fields[0], Mountpoint: fields[1], Fstype: fields[2]}
+		}
+		// device, mount point, vfs, the three fields of the date, options
+		if len(fields) >= 7 {
+			p.Opts = fields[6]
+		}
+		if !all && (remote || aixPseudoFS[p.Fstype]) {
+			continue
+		}
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// usageFromStatfs computes the usage of a file system the way gopsutil does.
+func usageFromStatfs(path string, bsize, blocks, bfree, bavail, files, ffree uint64) *disk.UsageStat { //nolint:unused // used on aix
+	u := &disk.UsageStat{
+		Path:        path,
+		Total:       blocks * bsize,
+		Free:        bavail * bsize,
+		Used:        (blocks - bfree) * bsize,
+		InodesTotal: files,
+		InodesFree:  ffree,
+	}
+	if files >= ffree {
+		u.InodesUsed = files - ffree
+	}
+	if u.Used+u.Free > 0 {
+		u.UsedPercent = float64(u.Used) / float64(u.Used+u.Free) * 100
+	}
+	if u.InodesTotal > 0 {
+		u.InodesUsedPercent = float64(u.InodesUsed) / float64(u.InodesTotal) * 100
+	}
+	return u
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+	"github.com/stretchr/testify/require"
+)
+
+const kstatDiskOutput = `sd:0:sd0:class	disk
+sd:0:sd0:crtime	31.662584416
+sd:0:sd0:nread	1937920
+sd:0:sd0:nwritten	524288
+sd:0:sd0:rcnt	1
+sd:0:sd0:reads	207
+sd:0:sd0:rlentime	0.523811253
+sd:0:sd0:rtime	0.412331072
+sd:0:sd0:writes	32
+zvblk:1:zvblk1:nread	4096
+zvblk:1:zvblk1:reads	1
+zvblk:1:zvblk1:rtime	1500000000
+`
+
+func TestParseKstatDisk(t *testing.T) {
+	counters := parseKstatDisk([]byte(kstatDiskOutput), nil)
+	require.Equal(t, map[string]disk.IOCountersStat{
+		"sd0": {
+			Name:           "sd0",
+			ReadCount:      207,
+			WriteCount:     32,
+			ReadBytes:      1937920,
+			WriteBytes:     524288,
+			IopsInProgress: 1,
+			IoTime:         412,
+			WeightedIO:     523,
+		},
+		"zvblk1": {
+			Name:      "zvblk1",
+			ReadCount: 1,
+			ReadBytes: 4096,
+			IoTime:    1500,
+		},
+	}, counters)
+
+	counters = parseKstatDisk([]byte(kstatDiskOutput), []string{"zvblk1"})
+	require.Len(t, counters, 1)
+	require.Contains(t, counters, "zvblk1")
+}
+
+func TestParseKstatNet(t *testing.T) {
+	out := `link:0:net1:ipackets64	12
+link:0:net1:rbytes64	1200
+link:0:net0:class	net
+link:0:net0:ierrors	1
+link:0:net0:ipackets64	1500
+link:0:net0:norcvbuf	2
+link:0:net0:noxmtbuf	3
+link:0:net0:obytes64	640000
+link:0:net0:oerrors	4
+link:0:net0:opackets64	900
+link:0:net0:rbytes64	2100000
+lo:0:lo0:ipackets	10
+`
+	require.Equal(t, []net.IOCountersStat{
+		{
+			Name:        "net0",
+			BytesSent:   640000,
+			BytesRecv:   2100000,
+			PacketsSent: 900,
+			PacketsRecv: 1500,
+			Errin:       1,
+			Errout:      4,
+			Dropin:      2,
+			Dropout:     3,
+		},
+		{Name: "net1", BytesRecv: 1200, PacketsRecv: 12},
+	}, parseKstatNet([]byte(out)))
+}
+
+func TestParseSwapSummary(t *testing.T) {
+	swap, err := parseSwapSummary([]byte("total: 143632k bytes allocated + 12468k reserved = 156100k used, 4032648k available\n"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(156100*1024), swap.Used)
+	require.Equal(t, uint64(4032648*1024), swap.Free)
+	require.Equal(t, uint64((156100+4032648)*1024), swap.Total)
+	require.InDelta(t, 3.7265, swap.UsedPercent, 0.001)
+
+	_, err = parseSwapSummary([]byte("swap: command not found"))
+	require.Error(t, err)
+}
+
+func TestParseVmstatSum(t *testing.T) {
+	out := `           2165826 total address trans. faults
+            101215 page ins
+          19284163 user cpu ticks
+           8372112 system cpu ticks
+        1893201637 idle cpu ticks
+            902133 I/O wait cpu ticks
+`
+	times, err := parseVmstatSum([]byte(out))
+	require.NoError(t, err)
+	require.Equal(t, &cpu.TimesStat{
+		CPU:    "cpu-total",
+		User:   192841.63,
+		System: 83721.12,
+		Idle:   18932016.37,
+		Iowait: 9021.33,
+	}, times)
+
+	_, err = parseVmstatSum([]byte("           101215 page ins\n"))
+	require.Error(t, err)
+}
+
+func TestParseSvmon(t *testing.T) {
+	out := `Unit: KB
+--------------------------------------------------------------------------------------
+               size       inuse        free         pin     virtual  available   mmode
+memory      4194304     3034384     1159920     1127640     2186536     1806236     Ded
+pg space    1048576        8556
+
+               work        pers        clnt       other
+pin          815568           0           0      312072
+in use      2186536           0      847848
+`
+	vm, swap, err := parseSvmon([]byte(out))
+	require.NoError(t, err)
+	require.Equal(t, &mem.VirtualMemoryStat{
+		Total:       4194304 * 1024,
+		Available:   1806236 * 1024,
+		Used:        3034384 * 1024,
+		UsedPercent: float64(3034384) / 4194304 * 100,
+		Free:        1159920 * 1024,
+		Wired:       1127640 * 1024,
+	}, vm)
+	require.Equal(t, &mem.SwapMemoryStat{
+		Total:       1048576 * 1024,
+		Used:        8556 * 1024,
+		Free:        (1048576 - 8556) * 1024,
+		UsedPercent: float64(8556) / 1048576 * 100,
+	}, swap)
+
+	// older versions report 4 KB pages without the available memory
+	vm, _, err = parseSvmon([]byte(`               size      inuse       free        pin    virtual
+memory       262144     200000      62144      50000     150000
+pg space     131072       1000
+`))
+	require.NoError(t, err)
+	require.Equal(t, uint64(262144*4096), vm.Total)
+	require.Equal(t, uint64(62144*4096), vm.Available)
+
+	_, _, err = parseSvmon([]byte("svmon: not authorized\n"))
+	require.Error(t, err)
+}
+
+func TestParseAIXMount(t *testing.T) {
+	out := `  node       mounted        mounted over    vfs       date        options
+-------- ---------------  ---------------  ------ ------------ ---------------
+         /dev/hd4         /                jfs2   Jan 12 08:41 rw,log=/dev/hd8
+         /dev/hd2         /usr             jfs2   Jan 12 08:41 rw,log=/dev/hd8
+         /proc            /proc            procfs Jan 12 08:41 rw
+nfssrv   /export/home     /home            nfs3   Jan 12 08:45 bg,hard,intr
+`
+	require.Equal(t, []disk.PartitionStat{
+		{Device: "/dev/hd4", Mountpoint: "/", Fstype: "jfs2", Opts: "rw,log=/dev/hd8"},
+		{Device: "/dev/hd2", Mountpoint: "/usr", Fstype: "jfs2", Opts: "rw,log=/dev/hd8"},
+	}, parseAIXMount([]byte(out), false))
+
+	parts := parseAIXMount([]byte(out), true)
+	require.Len(t, parts, 4)
+	require.Equal(t, disk.PartitionStat{
+		Device: "nfssrv:/export/home", Mountpoint: "/home", Fstype: "nfs3", Opts: "bg,hard,intr",
+	}, parts[3])
+}
+
+func TestUsageFromStatfs(t *testing.T) {
+	u := usageFromStatfs("/", 4096, 1000, 400, 300, 100, 25)
+	require.Equal(t, uint64(4096000), u.Total)
+	require.Equal(t, uint64(600*4096), u.Used)
+	require.Equal(t, uint64(300*4096), u.Free)
+	require.InDelta(t, 66.667, u.UsedPercent, 0.001)
+	require.Equal(t, uint64(75), u.InodesUsed)
+	require.InDelta(t, 75.0, u.InodesUsedPercent, 0.001)
+}
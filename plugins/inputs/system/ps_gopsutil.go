@@ -0,0 +1,38 @@
+// +build !solaris,!aix
+
+package system
+
+import (
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+)
+
+func cpuTimeStats(perCPU bool) ([]cpu.TimesStat, error) {
+	return cpu.Times(perCPU)
+}
+
+func virtualMemory() (*mem.VirtualMemoryStat, error) {
+	return mem.VirtualMemory()
+}
+
+func swapMemory() (*mem.SwapMemoryStat, error) {
+	return mem.SwapMemory()
+}
+
+func diskPartitions(all bool) ([]disk.PartitionStat, error) {
+	return disk.Partitions(all)
+}
+
+func diskUsage(path string) (*disk.UsageStat, error) {
+	return disk.Usage(path)
+}
+
+func diskIOCounters(names ...string) (map[string]disk.IOCountersStat, error) {
+	return disk.IOCounters(names...)
+}
+
+func netIOCounters() ([]net.IOCountersStat, error) {
+	return net.IOCounters(true)
+}
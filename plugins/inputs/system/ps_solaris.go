@@ -0,0 +1,54 @@
+// +build solaris
+
+package system
+
+import (
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+)
+
+// gopsutil reads the cpu times, the memory and the file systems on
+// Solaris and illumos, the swap, disk and network counters are read with
+// the swap(1M) and kstat(1M) commands.
+
+func cpuTimeStats(perCPU bool) ([]cpu.TimesStat, error) {
+	return cpu.Times(perCPU)
+}
+
+func virtualMemory() (*mem.VirtualMemoryStat, error) {
+	return mem.VirtualMemory()
+}
+
+func swapMemory() (*mem.SwapMemoryStat, error) {
+	out, err := runCmd("swap", "-s")
+	if err != nil {
+		return nil, err
+	}
+	return parseSwapSummary(out)
+}
+
+func diskPartitions(all bool) ([]disk.PartitionStat, error) {
+	return disk.Partitions(all)
+}
+
+func diskUsage(path string) (*disk.UsageStat, error) {
+	return disk.Usage(path)
+}
+
+func diskIOCounters(names ...string) (map[string]disk.IOCountersStat, error) {
+	out, err := runCmd("kstat", "-p", "-c", "disk")
+	if err != nil {
+		return nil, err
+	}
+	return parseKstatDisk(out, names), nil
+}
+
+func netIOCounters() ([]net.IOCountersStat, error) {
+	out, err := runCmd("kstat", "-p", "-c", "net", "link:::")
+	if err != nil {
+		return nil, err
+	}
+	return parseKstatNet(out), nil
+}
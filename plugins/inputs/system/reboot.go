@@ -0,0 +1,104 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/shirou/gopsutil/host"
+)
+
+// bootTimeSlack is the difference of boot times which is not a reboot, the
+// boot time is computed from the uptime on some platforms.
+const bootTimeSlack = 10 * time.Second
+
+// bootTime returns the boot time of the host in seconds since the epoch.
+var bootTime = host.BootTime
+
+// bootState is the state kept across the restarts of the agent.
+type bootState struct {
+	// BootTime is the boot time of the host, in seconds since the epoch.
+	BootTime uint64 `json:"boot_time"`
+	// Time is the time of the last collection, in seconds since the epoch.
+	Time int64 `json:"time"`
+	// Stopped is set when the agent stopped after the last collection.
+	Stopped bool `json:"stopped"`
+}
+
+func loadBootState(path string) (*bootState, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+	state := &bootState{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("parse state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// save writes the state to a temporary file renamed over the path, so a
+// crash never leaves a partial state.
+func (b *bootState) save(path string) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("state dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename state: %w", err)
+	}
+	return nil
+}
+
+// gatherReboot emits a system_reboot event, at the boot time, when the host
+// booted since the last collection recorded in the state file.
+func (s *Stats) gatherReboot(acc cua.Accumulator, now time.Time) error {
+	boot, err := bootTime()
+	if err != nil {
+		return fmt.Errorf("boot time: %w", err)
+	}
+
+	previous := s.state
+	if previous == nil {
+		previous, err = loadBootState(s.StateFile)
+		if err != nil && !os.IsNotExist(err) {
+			s.Log.Warnf("Ignoring the state: %s", err)
+		}
+	}
+
+	if previous != nil && boot > previous.BootTime+uint64(bootTimeSlack/time.Second) {
+		reason := "unexpected"
+		if previous.Stopped {
+			reason = "shutdown"
+		}
+		fields := map[string]interface{}{
+			"boot_time":          boot,
+			"previous_boot_time": previous.BootTime,
+			"shutdown_reason":    reason,
+		}
+		// the uptime and the downtime are bounded by the last collection
+		// before the reboot
+		if last := uint64(previous.Time); last >= previous.BootTime && boot >= last {
+			fields["previous_uptime"] = last - previous.BootTime
+			fields["downtime"] = boot - last
+		}
+		s.Log.Infof("Host rebooted at %s, shutdown was %s", time.Unix(int64(boot), 0).UTC().Format(time.RFC3339), reason)
+		acc.AddFields("system_reboot", fields, nil, time.Unix(int64(boot), 0))
+	}
+
+	s.state = &bootState{BootTime: boot, Time: now.Unix()}
+	if err := s.state.save(s.StateFile); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package system
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherReboot(t *testing.T) {
+	boot := uint64(1600000000)
+	orig := bootTime
+	bootTime = func() (uint64, error) { return boot, nil }
+	defer func() { bootTime = orig }()
+
+	path := filepath.Join(t.TempDir(), "state", "system.json")
+	newStats := func() *Stats {
+		s := &Stats{RebootEvents: true, StateFile: path, Log: testutil.Logger{}}
+		require.NoError(t, s.Init())
+		return s
+	}
+
+	// the first collection only records the boot time
+	s := newStats()
+	var acc testutil.Accumulator
+	require.NoError(t, s.gatherReboot(&acc, time.Unix(1600000100, 0)))
+	require.Empty(t, acc.Metrics)
+
+	// the agent is restarted without a reboot
+	s.Stop()
+	s = newStats()
+	require.NoError(t, s.gatherReboot(&acc, time.Unix(1600000200, 0)))
+	require.Empty(t, acc.Metrics)
+
+	// the host crashed and booted again
+	boot = 1600000500
+	s = newStats()
+	require.NoError(t, s.gatherReboot(&acc, time.Unix(1600000600, 0)))
+	acc.AssertContainsFields(t, "system_reboot", map[string]interface{}{
+		"boot_time":          uint64(1600000500),
+		"previous_boot_time": uint64(1600000000),
+		"previous_uptime":    uint64(200),
+		"downtime":           uint64(300),
+		"shutdown_reason":    "unexpected",
+	})
+	require.Equal(t, time.Unix(1600000500, 0), acc.Metrics[0].Time)
+
+	// the agent was stopped before the reboot
+	acc.ClearMetrics()
+	s.Stop()
+	boot = 1600001000
+	s = newStats()
+	require.NoError(t, s.gatherReboot(&acc, time.Unix(1600001010, 0)))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "shutdown", acc.Metrics[0].Fields["shutdown_reason"])
+
+	// the boot time computed from the uptime may drift
+	acc.ClearMetrics()
+	boot++
+	require.NoError(t, s.gatherReboot(&acc, time.Unix(1600001020, 0)))
+	require.Empty(t, acc.Metrics)
+}
+
+func TestRebootEventsRequireStateFile(t *testing.T) {
+	require.Error(t, (&Stats{RebootEvents: true}).Init())
+	require.NoError(t, (&Stats{}).Init())
+}
@@ -16,7 +16,12 @@ import (
 )
 
 type Stats struct {
+	RebootEvents bool   `toml:"reboot_events"`
+	StateFile    string `toml:"state_file"`
+
 	Log cua.Logger
+
+	state *bootState
 }
 
 func (*Stats) Description() string {
@@ -27,9 +32,37 @@ func (*Stats) SampleConfig() string {
 	return `
   ## Uncomment to remove deprecated metrics.
   # fielddrop = ["uptime_format"]
+
+  ## Emit a system_reboot event when the host booted since the last
+  ## collection, the boot time is kept in the state file across restarts.
+  # reboot_events = false
+  # state_file = "/opt/circonus/unified-agent/state/system.json"
 `
 }
 
+func (s *Stats) Init() error {
+	if s.RebootEvents && s.StateFile == "" {
+		return fmt.Errorf("state_file is required with reboot_events")
+	}
+	return nil
+}
+
+func (*Stats) Start(cua.Accumulator) error {
+	return nil
+}
+
+// Stop records that the agent stopped after the last collection, a reboot
+// following it was an orderly shutdown.
+func (s *Stats) Stop() {
+	if s.state == nil {
+		return
+	}
+	s.state.Stopped = true
+	if err := s.state.save(s.StateFile); err != nil {
+		s.Log.Errorf("Saving state: %s", err)
+	}
+}
+
 func (s *Stats) Gather(acc cua.Accumulator) error {
 	loadavg, err := load.Avg()
 	if err != nil && !strings.Contains(err.Error(), "not implemented") {
@@ -73,6 +106,9 @@ func (s *Stats) Gather(acc cua.Accumulator) error {
 		"uptime_format": formatUptime(uptime),
 	}, nil, now)
 
+	if s.RebootEvents {
+		return s.gatherReboot(acc, now)
+	}
 	return nil
 }
 
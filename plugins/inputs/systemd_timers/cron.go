@@ -0,0 +1,231 @@
+package systemdtimers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+)
+
+const (
+	// largest amount of a log read at each gather
+	cronMaxRead = 16 << 20
+	// runs without end line forgotten after
+	cronRunMaxAge = 24 * time.Hour
+)
+
+var (
+	// syslog line of cron, with the traditional or the RFC 3339 timestamp,
+	// such as "Jan 17 06:25:01 host CRON[1234]: (root) CMD (command)"
+	cronLineRe = regexp.MustCompile(`^(\w{3}\s+\d{1,2} \d\d:\d\d:\d\d|\d{4}-\d\d-\d\dT\S+)\s+\S+\s+(?i:crond?)\[(\d+)\]:\s+(.*)$`)
+	cronCmdRe  = regexp.MustCompile(`^\((\S+)\) CMD \((.*)\)$`)
+	// logged by ISC cron with -L 2 and cronie
+	cronEndRe = regexp.MustCompile(`^\((\S+)\) (?:END|CMDEND) \((.*)\)$`)
+	// logged by ISC cron when the job exits with an error
+	cronFailedRe = regexp.MustCompile(`^\(CRON\) error \(grandchild #\d+ failed with exit status (\d+)\)$`)
+)
+
+type cronJob struct {
+	user       string
+	command    string
+	runs       int64
+	failures   int64
+	lastRun    time.Time
+	duration   time.Duration
+	exitStatus int
+	// duration and exit status of the last run known
+	durationKnown bool
+	statusKnown   bool
+}
+
+// cronRun is a run of a job, identified by the pid of the cron process
+// logging it.
+type cronRun struct {
+	job    *cronJob
+	start  time.Time
+	failed bool
+}
+
+type cronLog struct {
+	path   string
+	info   os.FileInfo
+	offset int64
+}
+
+// cronTracker follows the cron lines appended to the syslog files.
+type cronTracker struct {
+	logs []*cronLog
+	jobs map[string]*cronJob
+	runs map[string]*cronRun
+}
+
+func newCronTracker(paths []string) *cronTracker {
+	c := &cronTracker{
+		jobs: make(map[string]*cronJob),
+		runs: make(map[string]*cronRun),
+	}
+	for _, path := range paths {
+		c.logs = append(c.logs, &cronLog{path: path, offset: -1})
+	}
+	return c
+}
+
+// update parses the lines appended to the logs since the previous update,
+// the existing lines are skipped by the first update.
+func (c *cronTracker) update(now time.Time) []error {
+	var errs []error
+	for _, l := range c.logs {
+		data, err := l.read()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.parse(data, now)
+	}
+
+	for pid, run := range c.runs {
+		if now.Sub(run.start) > cronRunMaxAge {
+			delete(c.runs, pid)
+		}
+	}
+	return errs
+}
+
+// read returns the complete lines appended to the log since the previous
+// read, from the start of the log when it was rotated or truncated.
+func (l *cronLog) read() ([]byte, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("open cron log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat cron log: %w", err)
+	}
+	switch {
+	case l.offset < 0:
+		// first read, skip the history
+		l.offset = info.Size()
+	case l.info != nil && !os.SameFile(l.info, info), info.Size() < l.offset:
+		l.offset = 0
+	}
+	l.info = info
+	if info.Size() == l.offset {
+		return nil, nil
+	}
+
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek cron log: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, cronMaxRead))
+	if err != nil {
+		return nil, fmt.Errorf("read cron log: %w", err)
+	}
+	// keep the incomplete last line for the next read
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[:i+1]
+	} else {
+		data = nil
+	}
+	l.offset += int64(len(data))
+	return data, nil
+}
+
+func (c *cronTracker) parse(data []byte, now time.Time) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		m := cronLineRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		ts, ok := parseSyslogTime(m[1], now)
+		if !ok {
+			continue
+		}
+		pid, msg := m[2], m[3]
+
+		if cmd := cronCmdRe.FindStringSubmatch(msg); cmd != nil {
+			key := cmd[1] + "\x00" + cmd[2]
+			job, ok := c.jobs[key]
+			if !ok {
+				job = &cronJob{user: cmd[1], command: cmd[2]}
+				c.jobs[key] = job
+			}
+			job.runs++
+			job.lastRun = ts
+			job.durationKnown = false
+			job.statusKnown = false
+			c.runs[pid] = &cronRun{job: job, start: ts}
+			continue
+		}
+
+		run, ok := c.runs[pid]
+		if !ok {
+			continue
+		}
+		if failed := cronFailedRe.FindStringSubmatch(msg); failed != nil {
+			status, _ := strconv.Atoi(failed[1])
+			run.failed = true
+			run.job.failures++
+			run.job.exitStatus = status
+			run.job.statusKnown = true
+			continue
+		}
+		if cronEndRe.MatchString(msg) {
+			if !ts.Before(run.start) {
+				run.job.duration = ts.Sub(run.start)
+				run.job.durationKnown = true
+			}
+			if !run.failed {
+				run.job.exitStatus = 0
+				run.job.statusKnown = true
+			}
+			delete(c.runs, pid)
+		}
+	}
+}
+
+func (c *cronTracker) addMetrics(acc cua.Accumulator, now time.Time) {
+	for _, job := range c.jobs {
+		tags := map[string]string{"user": job.user, "command": job.command}
+		fields := map[string]interface{}{
+			"runs":         job.runs,
+			"failures":     job.failures,
+			"last_run":     job.lastRun.Unix(),
+			"last_run_age": int64(now.Sub(job.lastRun).Seconds()),
+		}
+		if job.durationKnown {
+			fields["last_duration"] = job.duration.Seconds()
+		}
+		if job.statusKnown {
+			fields["last_exit_status"] = job.exitStatus
+		}
+		acc.AddCounter("cron_jobs", fields, tags, now)
+	}
+}
+
+// parseSyslogTime parses the timestamp of a syslog line, the traditional
+// timestamp without year is in the current year unless that is in the
+// future.
+func parseSyslogTime(value string, now time.Time) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, true
+	}
+	t, err := time.ParseInLocation(time.Stamp, value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
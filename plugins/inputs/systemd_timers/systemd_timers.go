@@ -0,0 +1,257 @@
+package systemdtimers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+)
+
+const sampleConfig = `
+  ## Timers to report the last run of, supports glob patterns, all the
+  ## timers when empty.
+  # timers = []
+
+  ## Timeout of each systemctl command.
+  # timeout = "5s"
+
+  ## Syslog files to track the cron jobs from, the CMD, END and failure lines
+  ## logged by cron since the previous gather are parsed.
+  # cron_log_files = ["/var/log/syslog"]
+`
+
+// timer and service properties read with systemctl show
+var (
+	timerProperties   = "Id,Unit,LastTriggerUSec,NextElapseUSecRealtime"
+	serviceProperties = "Id,Result,ActiveState,ExecMainStartTimestamp,ExecMainExitTimestamp,ExecMainStatus"
+)
+
+// Result of the services as defined in
+// https://github.com/systemd/systemd/blob/main/src/core/service.c
+var resultMap = map[string]int{
+	"success":         0,
+	"protocol":        1,
+	"timeout":         2,
+	"exit-code":       3,
+	"signal":          4,
+	"core-dump":       5,
+	"watchdog":        6,
+	"start-limit-hit": 7,
+	"resources":       8,
+	"oom-kill":        9,
+	"skip-condition":  10,
+}
+
+// runCmd runs a command and returns its output, it is replaced in tests.
+var runCmd = func(timeout time.Duration, command string, args ...string) ([]byte, error) {
+	return internal.StdOutputTimeout(exec.Command(command, args...), timeout)
+}
+
+// SystemdTimers gathers the last run of the systemd timers and of the cron
+// jobs.
+type SystemdTimers struct {
+	Timers       []string          `toml:"timers"`
+	Timeout      internal.Duration `toml:"timeout"`
+	CronLogFiles []string          `toml:"cron_log_files"`
+	Log          cua.Logger        `toml:"-"`
+
+	timers filter.Filter
+	cron   *cronTracker
+}
+
+func (s *SystemdTimers) Description() string {
+	return "Gather the last run, result and duration of systemd timers and cron jobs"
+}
+
+func (s *SystemdTimers) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SystemdTimers) init() error {
+	var err error
+	if s.timers, err = filter.Compile(s.Timers); err != nil {
+		return fmt.Errorf("timers: %w", err)
+	}
+	if len(s.CronLogFiles) > 0 {
+		s.cron = newCronTracker(s.CronLogFiles)
+	}
+	return nil
+}
+
+func (s *SystemdTimers) gather(acc cua.Accumulator) error {
+	now := time.Now()
+	if err := s.gatherTimers(acc, now); err != nil {
+		acc.AddError(err)
+	}
+	if s.cron != nil {
+		for _, err := range s.cron.update(now) {
+			acc.AddError(err)
+		}
+		s.cron.addMetrics(acc, now)
+	}
+	return nil
+}
+
+func (s *SystemdTimers) systemctl(args ...string) ([]byte, error) {
+	out, err := runCmd(s.Timeout.Duration, "systemctl", args...)
+	if err != nil {
+		return nil, fmt.Errorf("systemctl %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+func (s *SystemdTimers) gatherTimers(acc cua.Accumulator, now time.Time) error {
+	out, err := s.systemctl("list-units", "--type=timer", "--all", "--plain", "--no-legend", "--no-pager")
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, name := range parseUnitList(out) {
+		if s.timers == nil || s.timers.Match(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	out, err = s.systemctl(append([]string{"show", "--property=" + timerProperties}, names...)...)
+	if err != nil {
+		return err
+	}
+	timers := parseShow(out)
+
+	var services []string
+	for _, timer := range timers {
+		if timer["Unit"] != "" {
+			services = append(services, timer["Unit"])
+		}
+	}
+	byID := make(map[string]map[string]string)
+	if len(services) > 0 {
+		out, err = s.systemctl(append([]string{"show", "--property=" + serviceProperties}, services...)...)
+		if err != nil {
+			return err
+		}
+		for _, service := range parseShow(out) {
+			byID[service["Id"]] = service
+		}
+	}
+
+	for _, timer := range timers {
+		addTimer(acc, timer, byID[timer["Unit"]], now)
+	}
+	return nil
+}
+
+// addTimer adds the metric of a timer and of the last run of its service.
+func addTimer(acc cua.Accumulator, timer, service map[string]string, now time.Time) {
+	tags := map[string]string{"timer": timer["Id"], "unit": timer["Unit"]}
+	fields := make(map[string]interface{})
+
+	if last, ok := parseTimestamp(timer["LastTriggerUSec"]); ok {
+		fields["last_trigger"] = last.Unix()
+		fields["last_trigger_age"] = int64(now.Sub(last).Seconds())
+	}
+	if next, ok := parseTimestamp(timer["NextElapseUSecRealtime"]); ok {
+		fields["next_trigger"] = next.Unix()
+	}
+
+	if service != nil {
+		if result := service["Result"]; result != "" {
+			tags["result"] = result
+			if code, ok := resultMap[result]; ok {
+				fields["result_code"] = code
+			}
+		}
+		switch service["ActiveState"] {
+		case "active", "activating", "reloading", "deactivating":
+			fields["running"] = true
+		default:
+			fields["running"] = false
+		}
+		if status, err := strconv.Atoi(service["ExecMainStatus"]); err == nil {
+			fields["exit_status"] = status
+		}
+		start, startOK := parseTimestamp(service["ExecMainStartTimestamp"])
+		exit, exitOK := parseTimestamp(service["ExecMainExitTimestamp"])
+		if startOK && exitOK && !exit.Before(start) {
+			fields["last_duration"] = exit.Sub(start).Seconds()
+		}
+	}
+
+	acc.AddFields("systemd_timers", fields, tags)
+}
+
+// parseUnitList returns the units of the output of systemctl list-units
+// --plain --no-legend.
+func parseUnitList(out []byte) []string {
+	var units []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			units = append(units, fields[0])
+		}
+	}
+	return units
+}
+
+// parseShow returns the properties of each unit of the output of systemctl
+// show, the units are separated by an empty line.
+func parseShow(out []byte) []map[string]string {
+	var units []map[string]string
+	var unit map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			unit = nil
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			continue
+		}
+		if unit == nil {
+			unit = make(map[string]string)
+			units = append(units, unit)
+		}
+		unit[line[:i]] = line[i+1:]
+	}
+	return units
+}
+
+// parseTimestamp parses a timestamp of systemctl show, such as "Sun
+// 2021-01-17 06:42:01 UTC" in the local time zone or "@1610865721" with
+// --timestamp=unix, "n/a" and empty values are not set.
+func parseTimestamp(value string) (time.Time, bool) {
+	if value == "" || value == "n/a" || value == "0" {
+		return time.Time{}, false
+	}
+	if strings.HasPrefix(value, "@") {
+		sec, err := strconv.ParseInt(value[1:], 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(sec, 0), true
+	}
+	t, err := time.ParseInLocation("Mon 2006-01-02 15:04:05 MST", value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func newSystemdTimers() *SystemdTimers {
+	return &SystemdTimers{
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+}
@@ -0,0 +1,22 @@
+// +build linux
+
+package systemdtimers
+
+import (
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+func (s *SystemdTimers) Init() error {
+	return s.init()
+}
+
+func (s *SystemdTimers) Gather(acc cua.Accumulator) error {
+	return s.gather(acc)
+}
+
+func init() {
+	inputs.Add("systemd_timers", func() cua.Input {
+		return newSystemdTimers()
+	})
+}
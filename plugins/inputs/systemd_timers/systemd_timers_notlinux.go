@@ -0,0 +1,23 @@
+// +build !linux
+
+package systemdtimers
+
+import (
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+func (s *SystemdTimers) Init() error {
+	s.Log.Warn("Current platform is not supported")
+	return nil
+}
+
+func (s *SystemdTimers) Gather(acc cua.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("systemd_timers", func() cua.Input {
+		return newSystemdTimers()
+	})
+}
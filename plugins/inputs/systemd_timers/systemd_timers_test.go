@@ -0,0 +1,204 @@
+package systemdtimers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const listUnitsOutput = `apt-daily.timer          loaded active waiting Daily apt download activities
+logrotate.timer          loaded active waiting Daily rotation of log files
+fstrim.timer             loaded active waiting Discard unused blocks once a week
+`
+
+const showTimersOutput = `Id=apt-daily.timer
+Unit=apt-daily.service
+LastTriggerUSec=@1610865721
+NextElapseUSecRealtime=@1610908921
+
+Id=logrotate.timer
+Unit=logrotate.service
+LastTriggerUSec=n/a
+NextElapseUSecRealtime=@1610928000
+`
+
+const showServicesOutput = `Id=apt-daily.service
+Result=exit-code
+ActiveState=failed
+ExecMainStartTimestamp=@1610865721
+ExecMainExitTimestamp=@1610865754
+ExecMainStatus=100
+
+Id=logrotate.service
+Result=success
+ActiveState=inactive
+ExecMainStartTimestamp=n/a
+ExecMainExitTimestamp=n/a
+ExecMainStatus=0
+`
+
+func mockSystemctl(t *testing.T) {
+	orig := runCmd
+	t.Cleanup(func() { runCmd = orig })
+	runCmd = func(timeout time.Duration, command string, args ...string) ([]byte, error) {
+		require.Equal(t, "systemctl", command)
+		switch args[0] {
+		case "list-units":
+			return []byte(listUnitsOutput), nil
+		case "show":
+			if args[1] == "--property="+timerProperties {
+				require.Equal(t, []string{"apt-daily.timer", "logrotate.timer"}, args[2:])
+				return []byte(showTimersOutput), nil
+			}
+			require.Equal(t, []string{"apt-daily.service", "logrotate.service"}, args[2:])
+			return []byte(showServicesOutput), nil
+		}
+		return nil, fmt.Errorf("unexpected command %v", args)
+	}
+}
+
+func TestGatherTimers(t *testing.T) {
+	mockSystemctl(t)
+	s := newSystemdTimers()
+	s.Timers = []string{"apt-*", "logrotate.timer"}
+	require.NoError(t, s.init())
+
+	var acc testutil.Accumulator
+	now := time.Unix(1610865821, 0)
+	require.NoError(t, s.gatherTimers(&acc, now))
+	testutil.RequireMetricsEqual(t, []cua.Metric{
+		testutil.MustMetric("systemd_timers",
+			map[string]string{"timer": "apt-daily.timer", "unit": "apt-daily.service", "result": "exit-code"},
+			map[string]interface{}{
+				"last_trigger":     int64(1610865721),
+				"last_trigger_age": int64(100),
+				"next_trigger":     int64(1610908921),
+				"result_code":      3,
+				"running":          false,
+				"exit_status":      100,
+				"last_duration":    33.0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("systemd_timers",
+			map[string]string{"timer": "logrotate.timer", "unit": "logrotate.service", "result": "success"},
+			map[string]interface{}{
+				"next_trigger": int64(1610928000),
+				"result_code":  0,
+				"running":      false,
+				"exit_status":  0,
+			},
+			time.Unix(0, 0)),
+	}, acc.GetCUAMetrics(), testutil.IgnoreTime())
+}
+
+func TestParseTimestamp(t *testing.T) {
+	ts, ok := parseTimestamp("@1610865721")
+	require.True(t, ok)
+	require.Equal(t, int64(1610865721), ts.Unix())
+
+	ts, ok = parseTimestamp(time.Unix(1610865721, 0).Local().Format("Mon 2006-01-02 15:04:05 MST"))
+	require.True(t, ok)
+	require.Equal(t, int64(1610865721), ts.Unix())
+
+	for _, value := range []string{"", "n/a", "0", "yesterday"} {
+		_, ok = parseTimestamp(value)
+		require.False(t, ok, value)
+	}
+}
+
+func TestCronTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog")
+	require.NoError(t, os.WriteFile(path, []byte("Jan 17 05:00:01 host CRON[100]: (root) CMD (old job)\n"), 0600))
+
+	c := newCronTracker([]string{path})
+	now := time.Date(2021, 1, 17, 7, 0, 0, 0, time.Local)
+	require.Empty(t, c.update(now))
+	// the history is skipped
+	require.Empty(t, c.jobs)
+
+	appendText(t, path, strings.Join([]string{
+		"Jan 17 06:25:01 host CRON[1234]: (root) CMD (backup.sh)",
+		"Jan 17 06:25:01 host CRON[1235]: (www) CMD (cleanup.sh)",
+		"Jan 17 06:25:31 host CRON[1234]: (CRON) error (grandchild #1240 failed with exit status 2)",
+		"Jan 17 06:25:31 host CRON[1234]: (root) END (backup.sh)",
+		"Jan 17 06:25:02 host sshd[99]: Accepted publickey for root",
+		"2021-01-17T06:30:01.000000+00:00 host CROND[2000]: (www) CMD (cleanup.sh)",
+		"2021-01-17T06:30:05.500000+00:00 host CROND[2000]: (www) CMDEND (cleanup.sh)",
+		"Jan 17 06:31:01 host CRON[3000]: (root) CMD (part",
+	}, "\n"))
+	require.Empty(t, c.update(now))
+
+	var acc testutil.Accumulator
+	c.addMetrics(&acc, now)
+	backupRun := time.Date(2021, 1, 17, 6, 25, 1, 0, time.Local)
+	cleanupRun := time.Date(2021, 1, 17, 6, 30, 1, 0, time.UTC)
+	testutil.RequireMetricsEqual(t, []cua.Metric{
+		testutil.MustMetric("cron_jobs",
+			map[string]string{"user": "root", "command": "backup.sh"},
+			map[string]interface{}{
+				"runs":             int64(1),
+				"failures":         int64(1),
+				"last_run":         backupRun.Unix(),
+				"last_run_age":     int64(now.Sub(backupRun).Seconds()),
+				"last_duration":    30.0,
+				"last_exit_status": 2,
+			},
+			now, cua.Counter),
+		testutil.MustMetric("cron_jobs",
+			map[string]string{"user": "www", "command": "cleanup.sh"},
+			map[string]interface{}{
+				"runs":             int64(2),
+				"failures":         int64(0),
+				"last_run":         cleanupRun.Unix(),
+				"last_run_age":     int64(now.Sub(cleanupRun).Seconds()),
+				"last_duration":    4.5,
+				"last_exit_status": 0,
+			},
+			now, cua.Counter),
+	}, acc.GetCUAMetrics(), testutil.SortMetrics())
+
+	// the incomplete line is parsed once complete, a rotated log is read
+	// from its start
+	require.NotContains(t, c.jobs, "root\x00part")
+	appendText(t, path, "ial)\n")
+	require.Empty(t, c.update(now))
+	require.Contains(t, c.jobs, "root\x00partial")
+
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("Jan 17 06:40:01 host CRON[4000]: (root) CMD (backup.sh)\n"), 0600))
+	require.Empty(t, c.update(now))
+	require.Equal(t, int64(2), c.jobs["root\x00backup.sh"].runs)
+	require.False(t, c.jobs["root\x00backup.sh"].statusKnown)
+
+	require.NoError(t, os.Remove(path))
+	require.Len(t, c.update(now), 1)
+}
+
+func TestParseSyslogTime(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 10, 0, 0, time.Local)
+	ts, ok := parseSyslogTime("Dec 31 23:59:59", now)
+	require.True(t, ok)
+	require.Equal(t, time.Date(2020, 12, 31, 23, 59, 59, 0, time.Local), ts)
+
+	ts, ok = parseSyslogTime("Jan  1 00:05:00", now)
+	require.True(t, ok)
+	require.Equal(t, time.Date(2021, 1, 1, 0, 5, 0, 0, time.Local), ts)
+
+	_, ok = parseSyslogTime("yesterday", now)
+	require.False(t, ok)
+}
+
+func appendText(t *testing.T, path string, text string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(text)
+	require.NoError(t, err)
+}
@@ -0,0 +1,216 @@
+// +build !solaris
+
+package tail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+)
+
+// fileID identifies a file independently of its path, it tells a rotated
+// file apart from the file replacing it.  It is zero when unknown.
+type fileID struct {
+	Device uint64 `json:"device"`
+	Inode  uint64 `json:"inode"`
+}
+
+func (id fileID) known() bool {
+	return id != fileID{}
+}
+
+// position is the offset following a line of a file.
+type position struct {
+	File   fileID `json:"file"`
+	Offset int64  `json:"offset"`
+}
+
+type checkpointState struct {
+	Files map[string]position `json:"files"`
+}
+
+type trackedLines struct {
+	pos       position
+	delivered bool
+}
+
+// checkpoint records the position of the last line of each file whose
+// metrics, and those of all the previous lines, were delivered by the
+// outputs.  The tail resumes from these positions when the agent restarts.
+type checkpoint struct {
+	path string
+
+	mu sync.Mutex
+	// positions loaded from the checkpoint file
+	loaded map[string]position
+	// positions of the delivered lines
+	committed map[string]position
+	// lines pending delivery of each file, in the order they were read
+	queues map[string][]*trackedLines
+	// files and lines of the metric groups pending delivery
+	groups map[cua.TrackingID]string
+	lines  map[cua.TrackingID]*trackedLines
+	// groups delivered before being added
+	early map[cua.TrackingID]bool
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{
+		path:      path,
+		loaded:    make(map[string]position),
+		committed: make(map[string]position),
+		queues:    make(map[string][]*trackedLines),
+		groups:    make(map[cua.TrackingID]string),
+		lines:     make(map[cua.TrackingID]*trackedLines),
+		early:     make(map[cua.TrackingID]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decoding checkpoint %q: %w", path, err)
+	}
+	for file, pos := range state.Files {
+		c.loaded[file] = pos
+		c.committed[file] = pos
+	}
+	return c, nil
+}
+
+// resume returns the offset to start tailing the file from, if the file or
+// the file it replaced was tailed before.
+func (c *checkpoint) resume(file string) (int64, bool) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0, false
+	}
+	id := getFileID(info)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pos, ok := c.loaded[file]
+	if ok && (pos.File == id || !id.known()) && pos.Offset <= info.Size() {
+		return pos.Offset, true
+	}
+
+	// the file was tailed with another name before being rotated
+	if id.known() {
+		for _, other := range c.loaded {
+			if other.File == id && other.Offset <= info.Size() {
+				return other.Offset, true
+			}
+		}
+	}
+
+	// the file replaced or truncated the tailed file, it is read from the
+	// beginning to not lose its lines
+	return 0, ok
+}
+
+// add tracks the metric group of the lines ending at pos.
+func (c *checkpoint) add(file string, id cua.TrackingID, pos position) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lines := &trackedLines{pos: pos}
+	c.queues[file] = append(c.queues[file], lines)
+	if c.early[id] {
+		delete(c.early, id)
+		lines.delivered = true
+		c.commit(file)
+		return
+	}
+	c.groups[id] = file
+	c.lines[id] = lines
+}
+
+// skip records lines without metrics to deliver.
+func (c *checkpoint) skip(file string, pos position) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queues[file] = append(c.queues[file], &trackedLines{pos: pos, delivered: true})
+	c.commit(file)
+}
+
+// delivered records the delivery of a metric group.
+func (c *checkpoint) delivered(id cua.TrackingID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file, ok := c.groups[id]
+	if !ok {
+		c.early[id] = true
+		return
+	}
+	c.lines[id].delivered = true
+	delete(c.groups, id)
+	delete(c.lines, id)
+	c.commit(file)
+}
+
+// commit moves the position of the file past the delivered lines not
+// preceded by lines pending delivery.
+func (c *checkpoint) commit(file string) {
+	queue := c.queues[file]
+	n := 0
+	for ; n < len(queue) && queue[n].delivered; n++ {
+		c.committed[file] = queue[n].pos
+	}
+	c.queues[file] = queue[n:]
+}
+
+// save writes the positions to the checkpoint file, the files that no
+// longer exist are forgotten.
+func (c *checkpoint) save() error {
+	state := checkpointState{Files: make(map[string]position)}
+
+	c.mu.Lock()
+	for file, pos := range c.committed {
+		state.Files[file] = pos
+	}
+	c.mu.Unlock()
+
+	for file := range state.Files {
+		if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+			delete(state.Files, file)
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+
+	// replace the checkpoint atomically to not leave a partial file behind
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// +build !solaris
+
+package tail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+)
+
+func TestCheckpointCommitOrder(t *testing.T) {
+	c, err := loadCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+	require.NoError(t, err)
+
+	c.add("a.log", 1, position{Offset: 10})
+	c.add("a.log", 2, position{Offset: 20})
+	c.skip("a.log", position{Offset: 25})
+	c.add("a.log", 3, position{Offset: 30})
+
+	// the lines before are pending delivery
+	c.delivered(2)
+	require.Equal(t, position{}, c.committed["a.log"])
+
+	c.delivered(1)
+	require.Equal(t, position{Offset: 25}, c.committed["a.log"])
+
+	// delivered before being added
+	c.delivered(4)
+	c.delivered(3)
+	c.add("a.log", 4, position{Offset: 40})
+	require.Equal(t, position{Offset: 40}, c.committed["a.log"])
+	require.Empty(t, c.queues["a.log"])
+	require.Empty(t, c.early)
+}
+
+func TestCheckpointResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checkpoint.json")
+	file := filepath.Join(dir, "a.log")
+	require.NoError(t, os.WriteFile(file, []byte("line 1\nline 2\n"), 0600))
+
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	id := getFileID(info)
+
+	c, err := loadCheckpoint(path)
+	require.NoError(t, err)
+	c.skip(file, position{File: id, Offset: 7})
+	c.skip(filepath.Join(dir, "removed.log"), position{Offset: 7})
+	require.NoError(t, c.save())
+
+	c, err = loadCheckpoint(path)
+	require.NoError(t, err)
+	require.Len(t, c.loaded, 1)
+
+	offset, ok := c.resume(file)
+	require.True(t, ok)
+	require.Equal(t, int64(7), offset)
+
+	_, ok = c.resume(filepath.Join(dir, "b.log"))
+	require.False(t, ok)
+
+	if !id.known() {
+		return
+	}
+
+	// the file is rotated and replaced
+	rotated := filepath.Join(dir, "a.log.1")
+	require.NoError(t, os.Rename(file, rotated))
+	require.NoError(t, os.WriteFile(file, []byte("line 3\n"), 0600))
+
+	offset, ok = c.resume(rotated)
+	require.True(t, ok)
+	require.Equal(t, int64(7), offset)
+
+	offset, ok = c.resume(file)
+	require.True(t, ok)
+	require.Equal(t, int64(0), offset)
+}
+
+// deliveringAccumulator delivers the metrics as soon as they are added.
+type deliveringAccumulator struct {
+	testutil.Accumulator
+	delivered chan cua.DeliveryInfo
+}
+
+func (a *deliveringAccumulator) WithTracking(_ int) cua.TrackingAccumulator {
+	return a
+}
+
+func (a *deliveringAccumulator) AddTrackingMetricGroup(group []cua.Metric) cua.TrackingID {
+	db, id := metric.WithGroupTracking(group, func(info cua.DeliveryInfo) {
+		a.delivered <- info
+	})
+	for _, m := range db {
+		a.AddMetric(m)
+		m.Accept()
+	}
+	return id
+}
+
+func (a *deliveringAccumulator) Delivered() <-chan cua.DeliveryInfo {
+	return a.delivered
+}
+
+func TestTailCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "metrics.influx")
+	require.NoError(t, os.WriteFile(file, []byte("cpu value=1\ncpu value=2\n"), 0600))
+
+	run := func(expected int) *deliveringAccumulator {
+		tt := NewTail()
+		tt.Log = testutil.Logger{}
+		tt.FromBeginning = true
+		tt.Files = []string{file}
+		tt.CheckpointFile = filepath.Join(dir, "checkpoint.json")
+		tt.SetParserFunc(parsers.NewInfluxParser)
+		require.NoError(t, tt.Init())
+
+		acc := &deliveringAccumulator{delivered: make(chan cua.DeliveryInfo, 10)}
+		require.NoError(t, tt.Start(acc))
+		acc.Wait(expected)
+		tt.Stop()
+		return acc
+	}
+
+	acc := run(2)
+	require.Equal(t, uint64(2), acc.NMetrics())
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString("cpu value=3\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// the lines read before the restart are not read again
+	acc = run(1)
+	require.Equal(t, uint64(1), acc.NMetrics())
+	require.Equal(t, 3.0, acc.Metrics[0].Fields["value"])
+}
+
+func TestTailCheckpointInvalidEncoding(t *testing.T) {
+	tt := NewTail()
+	tt.CheckpointFile = "checkpoint.json"
+	tt.CharacterEncoding = "utf-16le"
+	require.Error(t, tt.Init())
+}
@@ -0,0 +1,19 @@
+// +build !solaris,!windows
+
+package tail
+
+import (
+	"os"
+	"syscall"
+)
+
+func getFileID(info os.FileInfo) fileID {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}
+	}
+	return fileID{
+		Device: uint64(st.Dev), //nolint:unconvert // int32 on some platforms
+		Inode:  st.Ino,
+	}
+}
@@ -0,0 +1,10 @@
+// +build windows
+
+package tail
+
+import "os"
+
+// getFileID returns the zero id, the files are only known by their path.
+func getFileID(_ os.FileInfo) fileID {
+	return fileID{}
+}
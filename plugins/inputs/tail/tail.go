@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"github.com/influxdata/tail"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
 	"github.com/circonus-labs/circonus-unified-agent/internal/globpath"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/common/encoding"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
@@ -46,6 +48,9 @@ type Tail struct {
 	MaxUndeliveredLines int      `toml:"max_undelivered_lines"`
 	CharacterEncoding   string   `toml:"character_encoding"`
 
+	CheckpointFile     string            `toml:"checkpoint_file"`
+	CheckpointInterval internal.Duration `toml:"checkpoint_interval"`
+
 	Log        cua.Logger `toml:"-"`
 	tailers    map[string]*tail.Tail
 	offsets    map[string]int64
@@ -61,6 +66,8 @@ type Tail struct {
 	cancel  context.CancelFunc
 	sem     semaphore
 	decoder *encoding.Decoder
+
+	checkpoint *checkpoint
 }
 
 func NewTail() *Tail {
@@ -74,6 +81,7 @@ func NewTail() *Tail {
 	return &Tail{
 		FromBeginning:       false,
 		MaxUndeliveredLines: 1000,
+		CheckpointInterval:  internal.Duration{Duration: 10 * time.Second},
 		offsets:             offsetsCopy,
 	}
 }
@@ -113,6 +121,16 @@ const sampleConfig = `
   ##       character_encoding = ""
   # character_encoding = ""
 
+  ## File recording the position of the lines delivered by the outputs, the
+  ## files are resumed from these positions when the agent restarts.  When
+  ## set, from_beginning only applies to the files tailed for the first time.
+  ## Requires a character_encoding of "" or "utf-8".
+  # checkpoint_file = ""
+
+  ## Interval to write the checkpoint file at, it is also written when the
+  ## agent stops.
+  # checkpoint_interval = "10s"
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -157,7 +175,25 @@ func (t *Tail) Init() error {
 
 	var err error
 	t.decoder, err = encoding.NewDecoder(t.CharacterEncoding)
-	return fmt.Errorf("new decoder: %w", err)
+	if err != nil {
+		return fmt.Errorf("new decoder: %w", err)
+	}
+
+	if t.CheckpointFile != "" {
+		if t.Pipe {
+			return errors.New("checkpoint_file cannot be used with pipes")
+		}
+		// the offsets are counted on the decoded lines
+		switch t.CharacterEncoding {
+		case "", "none", "utf-8":
+		default:
+			return fmt.Errorf("checkpoint_file cannot be used with character_encoding %q", t.CharacterEncoding)
+		}
+		if t.CheckpointInterval.Duration <= 0 {
+			return errors.New("checkpoint_interval must be positive")
+		}
+	}
+	return nil
 }
 
 func (t *Tail) Gather(acc cua.Accumulator) error {
@@ -169,6 +205,13 @@ func (t *Tail) Start(acc cua.Accumulator) error {
 
 	t.ctx, t.cancel = context.WithCancel(context.Background())
 
+	if t.CheckpointFile != "" {
+		var err error
+		if t.checkpoint, err = loadCheckpoint(t.CheckpointFile); err != nil {
+			return err
+		}
+	}
+
 	t.wg.Add(1)
 	go func() {
 		defer t.wg.Done()
@@ -176,12 +219,34 @@ func (t *Tail) Start(acc cua.Accumulator) error {
 			select {
 			case <-t.ctx.Done():
 				return
-			case <-t.acc.Delivered():
+			case info := <-t.acc.Delivered():
 				<-t.sem
+				if t.checkpoint != nil {
+					t.checkpoint.delivered(info.ID())
+				}
 			}
 		}
 	}()
 
+	if t.checkpoint != nil {
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			ticker := time.NewTicker(t.CheckpointInterval.Duration)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-t.ctx.Done():
+					return
+				case <-ticker.C:
+					if err := t.checkpoint.save(); err != nil {
+						t.Log.Errorf("Saving checkpoint: %s", err.Error())
+					}
+				}
+			}
+		}()
+	}
+
 	var err error
 	t.multiline, err = t.MultilineConfig.NewMultiline()
 
@@ -222,7 +287,16 @@ func (t *Tail) tailNewFiles(fromBeginning bool) error {
 			}
 
 			var seek *tail.SeekInfo
-			if !t.Pipe && !fromBeginning {
+			if t.checkpoint != nil {
+				if offset, ok := t.checkpoint.resume(file); ok {
+					t.Log.Debugf("Using checkpoint offset %d for %q", offset, file)
+					seek = &tail.SeekInfo{
+						Whence: 0,
+						Offset: offset,
+					}
+				}
+			}
+			if seek == nil && !t.Pipe && !fromBeginning {
 				if offset, ok := t.offsets[file]; ok {
 					t.Log.Debugf("Using offset %d for %q", offset, file)
 					seek = &tail.SeekInfo{
@@ -237,6 +311,15 @@ func (t *Tail) tailNewFiles(fromBeginning bool) error {
 				}
 			}
 
+			// with a checkpoint the receiver is told the position each time
+			// the file is opened, it is reopened after a rotation or a
+			// truncation
+			var opened chan position
+			done := make(chan struct{})
+			if t.checkpoint != nil {
+				opened = make(chan position)
+			}
+
 			tailer, err := tail.TailFile(file,
 				tail.Config{
 					ReOpen:    true,
@@ -247,7 +330,17 @@ func (t *Tail) tailNewFiles(fromBeginning bool) error {
 					Pipe:      t.Pipe,
 					Logger:    tail.DiscardingLogger,
 					OpenReaderFunc: func(rd io.Reader) io.Reader {
-						r, _ := utfbom.Skip(t.decoder.Reader(rd))
+						pos := openPosition(rd)
+						r, enc := utfbom.Skip(t.decoder.Reader(rd))
+						if opened != nil {
+							if enc == utfbom.UTF8 {
+								pos.Offset += 3
+							}
+							select {
+							case opened <- pos:
+							case <-done:
+							}
+						}
 						return r
 					},
 				})
@@ -270,7 +363,8 @@ func (t *Tail) tailNewFiles(fromBeginning bool) error {
 
 			go func() {
 				defer t.wg.Done()
-				t.receiver(parser, tailer)
+				defer close(done)
+				t.receiver(parser, tailer, opened)
 
 				t.Log.Debugf("Tail removed for %q", tailer.Filename)
 
@@ -285,6 +379,20 @@ func (t *Tail) tailNewFiles(fromBeginning bool) error {
 	return nil
 }
 
+// openPosition returns the position the file is read from.
+func openPosition(rd io.Reader) position {
+	f, ok := rd.(*os.File)
+	if !ok {
+		return position{}
+	}
+	var pos position
+	if info, err := f.Stat(); err == nil {
+		pos.File = getFileID(info)
+	}
+	pos.Offset, _ = f.Seek(0, io.SeekCurrent)
+	return pos
+}
+
 // ParseLine parses a line of text.
 func parseLine(parser parsers.Parser, line string, firstLine bool) ([]cua.Metric, error) {
 	switch parser.(type) {
@@ -312,8 +420,9 @@ func parseLine(parser parsers.Parser, line string, firstLine bool) ([]cua.Metric
 }
 
 // Receiver is launched as a goroutine to continuously watch a tailed logfile
-// for changes, parse any incoming msgs, and add to the accumulator.
-func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail) {
+// for changes, parse any incoming msgs, and add to the accumulator.  The
+// positions of the file opened by the tailer are received on opened.
+func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail, opened <-chan position) {
 	var firstLine = true
 
 	// holds the individual lines of multi-line log entries.
@@ -333,9 +442,19 @@ func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail) {
 	tailerOpen := true
 	var line *tail.Line
 
+	// the positions following the last line read and the line before, and
+	// the position of a file opened by the tailer
+	var pos, prevPos position
+	var reopened *position
+
 	for {
 		line = nil
 
+		if reopened != nil {
+			pos, prevPos = *reopened, *reopened
+			reopened = nil
+		}
+
 		if timer != nil {
 			timer.Reset(t.MultilineConfig.Timeout.Duration)
 		}
@@ -348,11 +467,21 @@ func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail) {
 				channelOpen = false
 			}
 		case <-timeout:
+		case p := <-opened:
+			// the lines buffered are flushed before moving to the file
+			reopened = &p
 		}
 
 		var text string
+		linePos := pos
 
 		if line != nil {
+			if line.Err == nil {
+				prevPos = pos
+				pos.Offset += int64(len(line.Text)) + 1
+				linePos = pos
+			}
+
 			// Fix up files with Windows line endings.
 			text = strings.TrimRight(line.Text, "\r")
 
@@ -360,6 +489,11 @@ func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail) {
 				if text = t.multiline.ProcessLine(text, &buffer); text == "" {
 					continue
 				}
+				// the previous lines are returned when the line starts the
+				// next event
+				if t.MultilineConfig.MatchWhichLine == Previous {
+					linePos = prevPos
+				}
 			}
 		}
 		if line == nil || !channelOpen || !tailerOpen {
@@ -381,10 +515,17 @@ func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail) {
 		if err != nil {
 			t.Log.Errorf("Malformed log line in %q: [%q]: %s",
 				tailer.Filename, text, err.Error())
+			t.skipLines(tailer.Filename, linePos)
 			continue
 		}
 		firstLine = false
 
+		// an empty group is never delivered
+		if len(metrics) == 0 {
+			t.skipLines(tailer.Filename, linePos)
+			continue
+		}
+
 		for _, metric := range metrics {
 			metric.AddTag("path", tailer.Filename)
 		}
@@ -392,7 +533,7 @@ func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail) {
 		// try writing out metric first without blocking
 		select {
 		case t.sem <- empty{}:
-			t.acc.AddTrackingMetricGroup(metrics)
+			t.addMetrics(tailer.Filename, metrics, linePos)
 			if t.ctx.Err() != nil {
 				return // exit!
 			}
@@ -406,18 +547,34 @@ func (t *Tail) receiver(parser parsers.Parser, tailer *tail.Tail) {
 		case <-t.ctx.Done():
 			return
 		case t.sem <- empty{}:
-			t.acc.AddTrackingMetricGroup(metrics)
+			t.addMetrics(tailer.Filename, metrics, linePos)
 		}
 	}
 }
 
+// addMetrics adds the metrics of the lines ending at pos.
+func (t *Tail) addMetrics(file string, metrics []cua.Metric, pos position) {
+	id := t.acc.AddTrackingMetricGroup(metrics)
+	if t.checkpoint != nil {
+		t.checkpoint.add(file, id, pos)
+	}
+}
+
+// skipLines records the lines ending at pos as processed.
+func (t *Tail) skipLines(file string, pos position) {
+	if t.checkpoint != nil {
+		t.checkpoint.skip(file, pos)
+	}
+}
+
 func (t *Tail) Stop() {
 	for _, tailer := range t.tailers {
-		if !t.Pipe && !t.FromBeginning {
+		if !t.Pipe && !t.FromBeginning && t.checkpoint == nil {
 			// store offset for resume
 			offset, err := tailer.Tell()
 			if err == nil {
 				t.Log.Debugf("Recording offset %d for %q", offset, tailer.Filename)
+				t.offsets[tailer.Filename] = offset
 			} else {
 				t.Log.Errorf("Recording offset for %q: %s", tailer.Filename, err.Error())
 			}
@@ -431,6 +588,12 @@ func (t *Tail) Stop() {
 	t.cancel()
 	t.wg.Wait()
 
+	if t.checkpoint != nil {
+		if err := t.checkpoint.save(); err != nil {
+			t.Log.Errorf("Saving checkpoint: %s", err.Error())
+		}
+	}
+
 	// persist offsets
 	offsetsMutex.Lock()
 	for k, v := range t.offsets {
@@ -257,7 +257,10 @@ func createGrokParser() (parsers.Parser, error) {
 		DataFormat:             "grok",
 	}
 	parser, err := parsers.NewParser(grokConfig)
-	return parser, fmt.Errorf("new parser: %w", err)
+	if err != nil {
+		return nil, fmt.Errorf("new parser: %w", err)
+	}
+	return parser, nil
 }
 
 // The csv parser should only parse the header line once per file.
@@ -0,0 +1,175 @@
+package upsd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// client speaks the network protocol of upsd, the server of Network UPS
+// Tools: https://networkupstools.org/docs/developer-guide.chunked/ar01s09.html
+type client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dial(ctx context.Context, address string, timeout time.Duration) (*client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("dial (%s): %w", address, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+	return &client{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (c *client) Close() error {
+	// the server closes the connection on logout, its reply is not needed
+	_, _ = fmt.Fprint(c.conn, "LOGOUT\n")
+	return c.conn.Close()
+}
+
+// command sends the command and returns the first line of the reply.
+func (c *client) command(cmd string) (string, error) {
+	if _, err := fmt.Fprintf(c.conn, "%s\n", cmd); err != nil {
+		return "", fmt.Errorf("send %s: %w", strings.Fields(cmd)[0], err)
+	}
+	return c.readLine()
+}
+
+func (c *client) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "ERR ") {
+		return "", fmt.Errorf("upsd error: %s", strings.TrimPrefix(line, "ERR "))
+	}
+	return line, nil
+}
+
+// login authenticates the client, the variables are readable without it
+// unless upsd is configured otherwise.
+func (c *client) login(username, password string) error {
+	for _, cmd := range []string{"USERNAME " + quote(username), "PASSWORD " + quote(password)} {
+		reply, err := c.command(cmd)
+		if err != nil {
+			return err
+		}
+		if reply != "OK" {
+			return fmt.Errorf("unexpected reply to %s: %q", strings.Fields(cmd)[0], reply)
+		}
+	}
+	return nil
+}
+
+// list runs a LIST command and returns the fields of the lines between its
+// BEGIN and END lines, without their type.
+func (c *client) list(query string) ([][]string, error) {
+	reply, err := c.command("LIST " + query)
+	if err != nil {
+		return nil, err
+	}
+	if reply != "BEGIN LIST "+query {
+		return nil, fmt.Errorf("unexpected reply to LIST %s: %q", query, reply)
+	}
+
+	var items [][]string
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END LIST "+query {
+			return items, nil
+		}
+		fields, err := splitLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) > 1 {
+			items = append(items, fields[1:])
+		}
+	}
+}
+
+// listUPS returns the names of the UPSes of the server.
+func (c *client) listUPS() ([]string, error) {
+	items, err := c.list("UPS")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item[0])
+	}
+	return names, nil
+}
+
+// listVars returns the variables of the UPS.
+func (c *client) listVars(ups string) (map[string]string, error) {
+	items, err := c.list("VAR " + ups)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(items))
+	for _, item := range items {
+		// VAR <ups> <name> "<value>"
+		if len(item) == 3 {
+			vars[item[1]] = item[2]
+		}
+	}
+	return vars, nil
+}
+
+// splitLine splits the line on spaces, except in the quoted values whose
+// escaped quotes and backslashes are unescaped.
+func splitLine(line string) ([]string, error) {
+	var (
+		fields  []string
+		field   strings.Builder
+		quoted  bool
+		escaped bool
+		started bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case r == ' ' && !quoted:
+			if started {
+				fields = append(fields, field.String())
+				field.Reset()
+				started = false
+			}
+		default:
+			field.WriteRune(r)
+			started = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if started {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
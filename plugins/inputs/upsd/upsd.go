@@ -0,0 +1,238 @@
+package upsd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+)
+
+const (
+	defaultAddress = "tcp://127.0.0.1:3493"
+	defaultPort    = "3493"
+)
+
+var defaultTimeout = internal.Duration{Duration: time.Second * 5}
+
+// floatVars are the variables reported as float fields, the fields are
+// named as the ones of the apcupsd input.
+var floatVars = map[string]string{
+	"battery.charge":          "battery_charge_percent",
+	"battery.voltage":         "battery_voltage",
+	"battery.voltage.nominal": "nominal_battery_voltage",
+	"input.frequency":         "input_frequency",
+	"input.voltage":           "input_voltage",
+	"input.voltage.nominal":   "nominal_input_voltage",
+	"output.voltage":          "output_voltage",
+	"ups.load":                "load_percent",
+	"ups.temperature":         "internal_temp",
+}
+
+// statusFlags are the bits of the apcupsd status flags of the ups.status
+// values.
+var statusFlags = map[string]uint64{
+	"CAL":   0x1,
+	"TRIM":  0x2,
+	"BOOST": 0x4,
+	"OL":    0x8,
+	"OB":    0x10,
+	"OVER":  0x20,
+	"LB":    0x40,
+	"RB":    0x80,
+	"FSD":   0x200,
+}
+
+type Upsd struct {
+	Servers    []string          `toml:"servers"`
+	Username   string            `toml:"username"`
+	Password   string            `toml:"password"`
+	UPSInclude []string          `toml:"ups_include"`
+	UPSExclude []string          `toml:"ups_exclude"`
+	Timeout    internal.Duration `toml:"timeout"`
+
+	Log cua.Logger `toml:"-"`
+
+	upsFilter filter.Filter
+}
+
+func (*Upsd) Description() string {
+	return "Monitor the UPSes of Network UPS Tools servers"
+}
+
+var sampleConfig = `
+  ## upsd servers to connect to, the port defaults to 3493
+  servers = ["tcp://127.0.0.1:3493"]
+
+  ## Credentials of a user of upsd.users, required only when upsd restricts
+  ## the access to the variables
+  # username = ""
+  # password = ""
+
+  ## UPSes to collect, by name, all the UPSes of the servers by default
+  # ups_include = []
+  # ups_exclude = []
+
+  ## Timeout for the connection and the queries of a server
+  timeout = "5s"
+`
+
+func (*Upsd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (u *Upsd) Init() error {
+	if len(u.Servers) == 0 {
+		u.Servers = []string{defaultAddress}
+	}
+	if u.Timeout.Duration == 0 {
+		u.Timeout = defaultTimeout
+	}
+
+	f, err := filter.NewIncludeExcludeFilter(u.UPSInclude, u.UPSExclude)
+	if err != nil {
+		return fmt.Errorf("ups filter: %w", err)
+	}
+	u.upsFilter = f
+	return nil
+}
+
+func (u *Upsd) Gather(acc cua.Accumulator) error {
+	for _, server := range u.Servers {
+		if err := u.gatherServer(acc, server); err != nil {
+			acc.AddError(fmt.Errorf("server %s: %w", server, err))
+		}
+	}
+	return nil
+}
+
+func (u *Upsd) gatherServer(acc cua.Accumulator, server string) error {
+	address, err := serverAddress(server)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), u.Timeout.Duration)
+	defer cancel()
+
+	c, err := dial(ctx, address, u.Timeout.Duration)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if u.Username != "" {
+		if err := c.login(u.Username, u.Password); err != nil {
+			return fmt.Errorf("login: %w", err)
+		}
+	}
+
+	names, err := c.listUPS()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !u.upsFilter.Match(name) {
+			continue
+		}
+		vars, err := c.listVars(name)
+		if err != nil {
+			return fmt.Errorf("ups %s: %w", name, err)
+		}
+		fields, tags := u.metric(vars)
+		tags["ups_name"] = name
+		tags["server"] = address
+		acc.AddFields("upsd", fields, tags)
+	}
+	return nil
+}
+
+// metric returns the fields and the tags of the variables of a UPS.
+func (u *Upsd) metric(vars map[string]string) (map[string]interface{}, map[string]string) {
+	tags := map[string]string{
+		"status": vars["ups.status"],
+		"serial": firstVar(vars, "ups.serial", "device.serial"),
+		"model":  firstVar(vars, "ups.model", "device.model"),
+	}
+
+	var flags uint64
+	for _, status := range strings.Fields(vars["ups.status"]) {
+		flags |= statusFlags[status]
+	}
+	fields := map[string]interface{}{
+		"status_flags": flags,
+	}
+
+	for name, field := range floatVars {
+		value, ok := vars[name]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			u.Log.Debugf("Skipping %s: %s", name, err)
+			continue
+		}
+		fields[field] = v
+	}
+	if value, ok := vars["battery.runtime"]; ok {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			fields["time_left_ns"] = int64(v * float64(time.Second))
+		}
+	}
+	if value, ok := vars["ups.realpower.nominal"]; ok {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			fields["nominal_power"] = int(v)
+		}
+	}
+	if v := vars["ups.firmware"]; v != "" {
+		fields["firmware"] = v
+	}
+	if v := firstVar(vars, "battery.date", "battery.mfr.date"); v != "" {
+		fields["battery_date"] = v
+	}
+	if v := vars["input.transfer.reason"]; v != "" {
+		fields["last_transfer"] = v
+	}
+	return fields, tags
+}
+
+func firstVar(vars map[string]string, names ...string) string {
+	for _, name := range names {
+		if v := vars[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// serverAddress returns the host:port of the server URL.
+func serverAddress(server string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("url parse (%s): %w", server, err)
+	}
+	if u.Scheme != "tcp" || u.Host == "" {
+		return "", fmt.Errorf("invalid server %q, must be tcp://host[:port]", server)
+	}
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), defaultPort), nil
+	}
+	return u.Host, nil
+}
+
+func init() {
+	inputs.Add("upsd", func() cua.Input {
+		return &Upsd{
+			Servers: []string{defaultAddress},
+			Timeout: defaultTimeout,
+		}
+	})
+}
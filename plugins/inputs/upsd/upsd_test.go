@@ -0,0 +1,149 @@
+package upsd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUpsd answers the commands of a client with the replies, by command.
+func fakeUpsd(t *testing.T, replies map[string]string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					cmd := scanner.Text()
+					if cmd == "LOGOUT" {
+						fmt.Fprint(conn, "OK Goodbye\n")
+						return
+					}
+					reply, ok := replies[cmd]
+					if !ok {
+						reply = "ERR UNKNOWN-COMMAND\n"
+					}
+					fmt.Fprint(conn, reply)
+				}
+			}()
+		}
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+var upsdReplies = map[string]string{
+	`USERNAME "monitor"`: "OK\n",
+	`PASSWORD "secret"`:  "OK\n",
+	"LIST UPS": `BEGIN LIST UPS
+UPS rack "Rack UPS"
+UPS desk "Desk \"small\" UPS"
+END LIST UPS
+`,
+	"LIST VAR rack": `BEGIN LIST VAR rack
+VAR rack battery.charge "87"
+VAR rack battery.runtime "1260"
+VAR rack battery.voltage "26.9"
+VAR rack device.model "Smart-UPS 1500"
+VAR rack device.serial "AS1234"
+VAR rack input.transfer.reason "input voltage out of range"
+VAR rack input.voltage "229.5"
+VAR rack ups.load "31"
+VAR rack ups.realpower.nominal "980"
+VAR rack ups.status "OB DISCHRG LB"
+VAR rack ups.firmware "UPS 09.3"
+END LIST VAR rack
+`,
+	"LIST VAR desk": "ERR DATA-STALE\n",
+}
+
+func TestGather(t *testing.T) {
+	server := fakeUpsd(t, upsdReplies)
+	u := &Upsd{
+		Servers:    []string{server},
+		Username:   "monitor",
+		Password:   "secret",
+		UPSExclude: []string{"desk"},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, u.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(u.Gather))
+	acc.AssertContainsTaggedFields(t, "upsd",
+		map[string]interface{}{
+			"status_flags":           uint64(0x50),
+			"battery_charge_percent": float64(87),
+			"battery_voltage":        float64(26.9),
+			"input_voltage":          float64(229.5),
+			"load_percent":           float64(31),
+			"time_left_ns":           int64(21 * time.Minute),
+			"nominal_power":          int(980),
+			"firmware":               "UPS 09.3",
+			"last_transfer":          "input voltage out of range",
+		},
+		map[string]string{
+			"ups_name": "rack",
+			"server":   strings.TrimPrefix(server, "tcp://"),
+			"status":   "OB DISCHRG LB",
+			"serial":   "AS1234",
+			"model":    "Smart-UPS 1500",
+		})
+}
+
+func TestGatherErrors(t *testing.T) {
+	server := fakeUpsd(t, upsdReplies)
+	u := &Upsd{
+		Servers: []string{server, "tcp://127.0.0.1:1", "127.0.0.1:3493"},
+		Timeout: defaultTimeout,
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, u.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, u.Gather(&acc))
+	// the stale UPS, the unreachable and the invalid servers
+	require.Len(t, acc.Errors, 3)
+	require.Contains(t, acc.Errors[0].Error(), "DATA-STALE")
+	// the UPSes before the error are reported
+	require.True(t, acc.HasMeasurement("upsd"))
+}
+
+func TestSplitLine(t *testing.T) {
+	fields, err := splitLine(`VAR desk ups.mfr "Cyber \"Power\" \\ Systems"`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"VAR", "desk", "ups.mfr", `Cyber "Power" \ Systems`}, fields)
+
+	fields, err = splitLine(`VAR desk ups.id ""`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"VAR", "desk", "ups.id", ""}, fields)
+
+	_, err = splitLine(`VAR desk ups.mfr "unterminated`)
+	require.Error(t, err)
+}
+
+func TestServerAddress(t *testing.T) {
+	address, err := serverAddress("tcp://ups.local")
+	require.NoError(t, err)
+	require.Equal(t, "ups.local:3493", address)
+
+	address, err = serverAddress("tcp://[::1]:4000")
+	require.NoError(t, err)
+	require.Equal(t, "[::1]:4000", address)
+
+	_, err = serverAddress("ups.local:3493")
+	require.Error(t, err)
+}
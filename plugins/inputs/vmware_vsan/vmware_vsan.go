@@ -0,0 +1,399 @@
+package vmwarevsan
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	itls "github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+var sampleConfig = `
+  ## List of vCenter URLs to be monitored.
+  vcenters = [ "https://vcenter.local/sdk" ]
+  username = "user@corp.local"
+  password = "secret"
+
+  ## Clusters to collect, as a list of glob patterns on the cluster names.
+  ## Clusters without vSAN enabled are always skipped.
+  # cluster_include = ["*"]
+
+  ## Entity types of the vSAN performance service to collect, the
+  ## performance service must be enabled on the clusters.
+  # perf_entity_types = ["cluster-domclient", "cluster-domcompmgr", "host-domclient", "host-domcompmgr"]
+
+  ## Collect the summary of the last vSAN health check of the clusters.
+  # collect_health = true
+
+  ## Timeout of the API calls.
+  # timeout = "60s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/circonus-unified-agent/ca.pem"
+  # tls_cert = "/etc/circonus-unified-agent/cert.pem"
+  # tls_key = "/etc/circonus-unified-agent/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// the vSAN performance service keeps a sample every 5 minutes, each gather
+// reads the samples of the last lookback.
+const lookback = 15 * time.Minute
+
+// the timestamps of the samples, in UTC
+const sampleTimeLayout = "2006-01-02 15:04:05"
+
+type VSAN struct {
+	Vcenters        []string          `toml:"vcenters"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	ClusterInclude  []string          `toml:"cluster_include"`
+	PerfEntityTypes []string          `toml:"perf_entity_types"`
+	CollectHealth   bool              `toml:"collect_health"`
+	Timeout         internal.Duration `toml:"timeout"`
+	itls.ClientConfig
+
+	Log cua.Logger `toml:"-"`
+
+	clusterFilter filter.Filter
+	endpoints     []*endpoint
+}
+
+// endpoint is a connection to a vCenter.
+type endpoint struct {
+	url    *url.URL
+	client *govmomi.Client
+	vsan   *soap.Client
+
+	// lastSample is the time of the last sample read per cluster and
+	// entity, so that a sample is only reported once.
+	lastSample map[string]time.Time
+}
+
+type cluster struct {
+	name string
+	ref  types.ManagedObjectReference
+}
+
+func (v *VSAN) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *VSAN) Description() string {
+	return "Read the vSAN performance and health metrics of the clusters of vCenters"
+}
+
+func (v *VSAN) Init() error {
+	if len(v.Vcenters) == 0 {
+		return errors.New("no vcenters configured")
+	}
+	include := v.ClusterInclude
+	if len(include) == 0 {
+		include = []string{"*"}
+	}
+	f, err := filter.Compile(include)
+	if err != nil {
+		return fmt.Errorf("cluster_include: %w", err)
+	}
+	v.clusterFilter = f
+
+	for _, vcenter := range v.Vcenters {
+		u, err := soap.ParseURL(vcenter)
+		if err != nil {
+			return fmt.Errorf("parse url (%s): %w", vcenter, err)
+		}
+		if u == nil {
+			return fmt.Errorf("invalid vcenter url %q", vcenter)
+		}
+		v.endpoints = append(v.endpoints, &endpoint{url: u, lastSample: map[string]time.Time{}})
+	}
+	return nil
+}
+
+func (v *VSAN) Gather(acc cua.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, e := range v.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			if err := v.gatherEndpoint(acc, e); err != nil {
+				acc.AddError(fmt.Errorf("vcenter %s: %w", e.url.Host, err))
+			}
+		}(e)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (v *VSAN) gatherEndpoint(acc cua.Accumulator, e *endpoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), v.Timeout.Duration)
+	defer cancel()
+
+	if e.client == nil {
+		if err := v.connect(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	clusters, err := v.vsanClusters(ctx, e.client.Client)
+	if err != nil {
+		// reconnect at the next gather, the session may have expired
+		v.disconnect(e)
+		return err
+	}
+
+	now := time.Now()
+	for _, c := range clusters {
+		if len(v.PerfEntityTypes) > 0 {
+			if err := v.gatherPerf(ctx, acc, e, c, now); err != nil {
+				acc.AddError(fmt.Errorf("vcenter %s: cluster %s: %w", e.url.Host, c.name, err))
+			}
+		}
+		if v.CollectHealth {
+			if err := v.gatherHealth(ctx, acc, e, c); err != nil {
+				acc.AddError(fmt.Errorf("vcenter %s: cluster %s: %w", e.url.Host, c.name, err))
+			}
+		}
+	}
+	return nil
+}
+
+func (v *VSAN) connect(ctx context.Context, e *endpoint) error {
+	tlsCfg, err := v.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	soapClient := soap.NewClient(e.url, tlsCfg.InsecureSkipVerify)
+	if len(tlsCfg.Certificates) > 0 {
+		soapClient.SetCertificate(tlsCfg.Certificates[0])
+	}
+	if v.TLSCA != "" {
+		if err := soapClient.SetRootCAs(v.TLSCA); err != nil {
+			return fmt.Errorf("set root CAs: %w", err)
+		}
+	}
+
+	vimClient, err := vim25.NewClient(ctx, soapClient)
+	if err != nil {
+		return fmt.Errorf("new vim25 client: %w", err)
+	}
+	c := &govmomi.Client{
+		Client:         vimClient,
+		SessionManager: session.NewManager(vimClient),
+	}
+	if err := c.Login(ctx, url.UserPassword(v.Username, v.Password)); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	// the vSAN service shares the session of the vim25 client
+	vsan := vimClient.Client.NewServiceClient(vsanPath, vsanNamespace)
+	vsan.Version = vsanVersion
+	if v.TLSCA != "" {
+		if err := vsan.SetRootCAs(v.TLSCA); err != nil {
+			return fmt.Errorf("set root CAs: %w", err)
+		}
+	}
+
+	e.client = c
+	e.vsan = vsan
+	v.Log.Debugf("Connected to %s", e.url.Host)
+	return nil
+}
+
+func (v *VSAN) disconnect(e *endpoint) {
+	if e.client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), v.Timeout.Duration)
+	defer cancel()
+	_ = e.client.Logout(ctx)
+	e.client = nil
+	e.vsan = nil
+}
+
+// vsanClusters returns the included clusters with vSAN enabled.
+func (v *VSAN) vsanClusters(ctx context.Context, c *vim25.Client) ([]cluster, error) {
+	m := view.NewManager(c)
+	cv, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"ClusterComputeResource"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("create container view: %w", err)
+	}
+	defer func() {
+		_ = cv.Destroy(ctx)
+	}()
+
+	var objects []mo.ClusterComputeResource
+	if err := cv.Retrieve(ctx, []string{"ClusterComputeResource"}, []string{"name", "configurationEx"}, &objects); err != nil {
+		return nil, fmt.Errorf("retrieve clusters: %w", err)
+	}
+
+	var clusters []cluster
+	for _, o := range objects {
+		if !v.clusterFilter.Match(o.Name) {
+			continue
+		}
+		cfg, ok := o.ConfigurationEx.(*types.ClusterConfigInfoEx)
+		if !ok || cfg.VsanConfigInfo == nil || cfg.VsanConfigInfo.Enabled == nil || !*cfg.VsanConfigInfo.Enabled {
+			continue
+		}
+		clusters = append(clusters, cluster{name: o.Name, ref: o.Reference()})
+	}
+	return clusters, nil
+}
+
+func (v *VSAN) gatherPerf(ctx context.Context, acc cua.Accumulator, e *endpoint, c cluster, now time.Time) error {
+	// the samples already reported are skipped per entity
+	start := now.Add(-lookback)
+	specs := make([]vsanPerfQuerySpec, 0, len(v.PerfEntityTypes))
+	for _, entityType := range v.PerfEntityTypes {
+		specs = append(specs, vsanPerfQuerySpec{
+			EntityRefID: entityType + ":*",
+			StartTime:   &start,
+			EndTime:     &now,
+		})
+	}
+
+	entities, err := queryPerf(ctx, e.vsan, c.ref, specs)
+	if err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		key := c.ref.Value + "/" + entity.EntityRefID
+		last := perfMetrics(acc, entity, e.lastSample[key], map[string]string{
+			"vcenter": e.url.Host,
+			"cluster": c.name,
+		})
+		if last.After(e.lastSample[key]) {
+			e.lastSample[key] = last
+		}
+	}
+	return nil
+}
+
+// perfMetrics adds a vsan_perf metric per sample of the entity newer than
+// since and returns the time of the last sample.
+func perfMetrics(acc cua.Accumulator, entity vsanPerfEntityMetricCSV, since time.Time, tags map[string]string) time.Time {
+	entityType, entityID := entity.EntityRefID, ""
+	if i := strings.Index(entity.EntityRefID, ":"); i >= 0 {
+		entityType, entityID = entity.EntityRefID[:i], entity.EntityRefID[i+1:]
+	}
+
+	var timestamps []time.Time
+	for _, s := range strings.Split(entity.SampleInfo, ",") {
+		t, err := time.Parse(sampleTimeLayout, strings.TrimSpace(s))
+		if err != nil {
+			return since
+		}
+		timestamps = append(timestamps, t)
+	}
+	values := make([][]string, len(entity.Value))
+	for i, series := range entity.Value {
+		values[i] = strings.Split(series.Values, ",")
+	}
+
+	last := since
+	for i, t := range timestamps {
+		if !t.After(since) {
+			continue
+		}
+		fields := map[string]interface{}{}
+		for j, series := range entity.Value {
+			if i >= len(values[j]) {
+				continue
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(values[j][i]), 64); err == nil {
+				fields[series.MetricID.Label] = f
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		metricTags := map[string]string{
+			"entity_type": entityType,
+			"entity_id":   entityID,
+		}
+		for k, v := range tags {
+			metricTags[k] = v
+		}
+		acc.AddFields("vsan_perf", fields, metricTags, t)
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+func (v *VSAN) gatherHealth(ctx context.Context, acc cua.Accumulator, e *endpoint, c cluster) error {
+	summary, err := queryHealthSummary(ctx, e.vsan, c.ref)
+	if err != nil {
+		return err
+	}
+	healthMetrics(acc, summary, map[string]string{
+		"vcenter": e.url.Host,
+		"cluster": c.name,
+	})
+	return nil
+}
+
+func healthMetrics(acc cua.Accumulator, summary *vsanClusterHealthSummary, tags map[string]string) {
+	acc.AddFields("vsan_health", map[string]interface{}{
+		"status":      summary.OverallHealth,
+		"status_code": healthCode(summary.OverallHealth),
+	}, tags)
+
+	for _, group := range summary.Groups {
+		groupTags := map[string]string{"group": group.GroupName}
+		for k, v := range tags {
+			groupTags[k] = v
+		}
+		acc.AddFields("vsan_health_group", map[string]interface{}{
+			"status":      group.GroupHealth,
+			"status_code": healthCode(group.GroupHealth),
+		}, groupTags)
+	}
+}
+
+// healthCode maps a health status to a number, higher is worse.
+func healthCode(status string) int64 {
+	switch status {
+	case "green":
+		return 0
+	case "yellow":
+		return 1
+	case "red":
+		return 2
+	default:
+		return 3
+	}
+}
+
+func init() {
+	inputs.Add("vmware_vsan", func() cua.Input {
+		return &VSAN{
+			PerfEntityTypes: []string{"cluster-domclient", "cluster-domcompmgr", "host-domclient", "host-domcompmgr"},
+			CollectHealth:   true,
+			Timeout:         internal.Duration{Duration: 60 * time.Second},
+		}
+	})
+}
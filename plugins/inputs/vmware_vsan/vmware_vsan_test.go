@@ -0,0 +1,154 @@
+package vmwarevsan
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+const perfResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<soapenv:Body>
+<VsanPerfQueryPerfResponse xmlns="urn:vsan">
+  <returnval>
+    <entityRefId>cluster-domclient:52a8e8a0-2f5c-4b4c-9d67-1f9b1f1a2b3c</entityRefId>
+    <sampleInfo>2021-03-01 10:00:00,2021-03-01 10:05:00</sampleInfo>
+    <value>
+      <metricId><label>iopsRead</label></metricId>
+      <values>10,20</values>
+    </value>
+    <value>
+      <metricId><label>latencyAvgRead</label></metricId>
+      <values>500,</values>
+    </value>
+  </returnval>
+</VsanPerfQueryPerfResponse>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+const healthResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<soapenv:Body>
+<VsanQueryVcClusterHealthSummaryResponse xmlns="urn:vsan">
+  <returnval>
+    <overallHealth>yellow</overallHealth>
+    <overallHealthDescription>Some checks have warnings</overallHealthDescription>
+    <groups>
+      <groupId>com.vmware.vsan.health.test.network</groupId>
+      <groupName>Network</groupName>
+      <groupHealth>green</groupHealth>
+    </groups>
+    <groups>
+      <groupId>com.vmware.vsan.health.test.physicaldisks</groupId>
+      <groupName>Physical disk</groupName>
+      <groupHealth>yellow</groupHealth>
+    </groups>
+  </returnval>
+</VsanQueryVcClusterHealthSummaryResponse>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+func newVsanServer(t *testing.T) *soap.Client {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "urn:vsan/"+vsanVersion, strings.Trim(r.Header.Get("SOAPAction"), `"`))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "text/xml")
+		switch {
+		case strings.Contains(string(body), "VsanPerfQueryPerf"):
+			require.Contains(t, string(body), "<entityRefId>cluster-domclient:*</entityRefId>")
+			_, _ = w.Write([]byte(perfResponse))
+		case strings.Contains(string(body), "VsanQueryVcClusterHealthSummary"):
+			require.Contains(t, string(body), "<fetchFromCache>true</fetchFromCache>")
+			_, _ = w.Write([]byte(healthResponse))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	u, err := soap.ParseURL(ts.URL + "/sdk")
+	require.NoError(t, err)
+	vsan := soap.NewClient(u, true).NewServiceClient(vsanPath, vsanNamespace)
+	vsan.Version = vsanVersion
+	return vsan
+}
+
+func TestGatherPerf(t *testing.T) {
+	vsan := newVsanServer(t)
+	v := &VSAN{PerfEntityTypes: []string{"cluster-domclient"}}
+	e := &endpoint{vsan: vsan, lastSample: map[string]time.Time{}}
+	e.url, _ = soap.ParseURL("https://vcenter.local/sdk")
+	c := cluster{name: "cluster1", ref: types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "domain-c7"}}
+
+	var acc testutil.Accumulator
+	require.NoError(t, v.gatherPerf(context.Background(), &acc, e, c, time.Now()))
+
+	tags := map[string]string{
+		"vcenter":     "vcenter.local",
+		"cluster":     "cluster1",
+		"entity_type": "cluster-domclient",
+		"entity_id":   "52a8e8a0-2f5c-4b4c-9d67-1f9b1f1a2b3c",
+	}
+	expected := []cua.Metric{
+		testutil.MustMetric("vsan_perf", tags,
+			map[string]interface{}{"iopsRead": float64(10), "latencyAvgRead": float64(500)},
+			time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)),
+		testutil.MustMetric("vsan_perf", tags,
+			map[string]interface{}{"iopsRead": float64(20)},
+			time.Date(2021, 3, 1, 10, 5, 0, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.SortMetrics())
+
+	// the samples are only reported once
+	acc.ClearMetrics()
+	require.NoError(t, v.gatherPerf(context.Background(), &acc, e, c, time.Now()))
+	require.Empty(t, acc.GetCUAMetrics())
+}
+
+func TestGatherHealth(t *testing.T) {
+	vsan := newVsanServer(t)
+	v := &VSAN{}
+	e := &endpoint{vsan: vsan}
+	e.url, _ = soap.ParseURL("https://vcenter.local/sdk")
+	c := cluster{name: "cluster1", ref: types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "domain-c7"}}
+
+	var acc testutil.Accumulator
+	require.NoError(t, v.gatherHealth(context.Background(), &acc, e, c))
+
+	expected := []cua.Metric{
+		testutil.MustMetric("vsan_health",
+			map[string]string{"vcenter": "vcenter.local", "cluster": "cluster1"},
+			map[string]interface{}{"status": "yellow", "status_code": int64(1)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("vsan_health_group",
+			map[string]string{"vcenter": "vcenter.local", "cluster": "cluster1", "group": "Network"},
+			map[string]interface{}{"status": "green", "status_code": int64(0)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("vsan_health_group",
+			map[string]string{"vcenter": "vcenter.local", "cluster": "cluster1", "group": "Physical disk"},
+			map[string]interface{}{"status": "yellow", "status_code": int64(1)},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestInit(t *testing.T) {
+	v := &VSAN{}
+	require.Error(t, v.Init())
+
+	v = &VSAN{Vcenters: []string{"https://vcenter.local/sdk"}}
+	require.NoError(t, v.Init())
+	require.Len(t, v.endpoints, 1)
+	require.True(t, v.clusterFilter.Match("any"))
+}
@@ -0,0 +1,131 @@
+package vmwarevsan
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// The vSAN management API of vCenter is a SOAP service of its own, not
+// covered by the vim25 methods of govmomi.
+const (
+	vsanNamespace = "vsan"
+	vsanPath      = "/vsanHealth"
+	vsanVersion   = "6.7"
+)
+
+var (
+	vsanPerformanceManager = types.ManagedObjectReference{
+		Type:  "VsanPerformanceManager",
+		Value: "vsan-performance-manager",
+	}
+	vsanClusterHealthSystem = types.ManagedObjectReference{
+		Type:  "VsanVcClusterHealthSystem",
+		Value: "vsan-cluster-health-system",
+	}
+)
+
+type vsanPerfQuerySpec struct {
+	EntityRefID string     `xml:"entityRefId"`
+	StartTime   *time.Time `xml:"startTime,omitempty"`
+	EndTime     *time.Time `xml:"endTime,omitempty"`
+}
+
+type vsanPerfQueryPerf struct {
+	This       types.ManagedObjectReference  `xml:"_this"`
+	QuerySpecs []vsanPerfQuerySpec           `xml:"querySpecs"`
+	Cluster    *types.ManagedObjectReference `xml:"cluster,omitempty"`
+}
+
+// vsanPerfEntityMetricCSV holds the samples of an entity, the timestamps of
+// the samples and the values of each metric are comma separated.
+type vsanPerfEntityMetricCSV struct {
+	EntityRefID string `xml:"entityRefId"`
+	SampleInfo  string `xml:"sampleInfo"`
+	Value       []struct {
+		MetricID struct {
+			Label string `xml:"label"`
+		} `xml:"metricId"`
+		Values string `xml:"values"`
+	} `xml:"value"`
+}
+
+type vsanPerfQueryPerfResponse struct {
+	Returnval []vsanPerfEntityMetricCSV `xml:"returnval"`
+}
+
+type vsanPerfQueryPerfBody struct {
+	Req *vsanPerfQueryPerf         `xml:"urn:vsan VsanPerfQueryPerf,omitempty"`
+	Res *vsanPerfQueryPerfResponse `xml:"urn:vsan VsanPerfQueryPerfResponse,omitempty"`
+	Err *soap.Fault                `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *vsanPerfQueryPerfBody) Fault() *soap.Fault { return b.Err }
+
+func queryPerf(ctx context.Context, r soap.RoundTripper, cluster types.ManagedObjectReference, specs []vsanPerfQuerySpec) ([]vsanPerfEntityMetricCSV, error) {
+	var reqBody, resBody vsanPerfQueryPerfBody
+	reqBody.Req = &vsanPerfQueryPerf{
+		This:       vsanPerformanceManager,
+		QuerySpecs: specs,
+		Cluster:    &cluster,
+	}
+	if err := r.RoundTrip(ctx, &reqBody, &resBody); err != nil {
+		return nil, fmt.Errorf("VsanPerfQueryPerf: %w", err)
+	}
+	if resBody.Res == nil {
+		return nil, nil
+	}
+	return resBody.Res.Returnval, nil
+}
+
+type vsanQueryVcClusterHealthSummary struct {
+	This           types.ManagedObjectReference  `xml:"_this"`
+	Cluster        *types.ManagedObjectReference `xml:"cluster,omitempty"`
+	Fields         []string                      `xml:"fields,omitempty"`
+	FetchFromCache *bool                         `xml:"fetchFromCache,omitempty"`
+}
+
+type vsanClusterHealthSummary struct {
+	OverallHealth            string `xml:"overallHealth"`
+	OverallHealthDescription string `xml:"overallHealthDescription"`
+	Groups                   []struct {
+		GroupID     string `xml:"groupId"`
+		GroupName   string `xml:"groupName"`
+		GroupHealth string `xml:"groupHealth"`
+	} `xml:"groups"`
+}
+
+type vsanQueryVcClusterHealthSummaryResponse struct {
+	Returnval vsanClusterHealthSummary `xml:"returnval"`
+}
+
+type vsanQueryVcClusterHealthSummaryBody struct {
+	Req *vsanQueryVcClusterHealthSummary         `xml:"urn:vsan VsanQueryVcClusterHealthSummary,omitempty"`
+	Res *vsanQueryVcClusterHealthSummaryResponse `xml:"urn:vsan VsanQueryVcClusterHealthSummaryResponse,omitempty"`
+	Err *soap.Fault                              `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *vsanQueryVcClusterHealthSummaryBody) Fault() *soap.Fault { return b.Err }
+
+// queryHealthSummary returns the health of a cluster from the cache of the
+// last health check run by vCenter, without triggering a new check.
+func queryHealthSummary(ctx context.Context, r soap.RoundTripper, cluster types.ManagedObjectReference) (*vsanClusterHealthSummary, error) {
+	fromCache := true
+	var reqBody, resBody vsanQueryVcClusterHealthSummaryBody
+	reqBody.Req = &vsanQueryVcClusterHealthSummary{
+		This:           vsanClusterHealthSystem,
+		Cluster:        &cluster,
+		Fields:         []string{"overallHealth", "overallHealthDescription", "groups"},
+		FetchFromCache: &fromCache,
+	}
+	if err := r.RoundTrip(ctx, &reqBody, &resBody); err != nil {
+		return nil, fmt.Errorf("VsanQueryVcClusterHealthSummary: %w", err)
+	}
+	if resBody.Res == nil {
+		return nil, fmt.Errorf("VsanQueryVcClusterHealthSummary: empty response")
+	}
+	return &resBody.Res.Returnval, nil
+}
@@ -120,7 +120,7 @@ func NewEndpoint(ctx context.Context, parent *VSphere, url *url.URL, log cua.Log
 	e := Endpoint{
 		URL:               url,
 		Parent:            parent,
-		hwMarks:           NewTSCache(hwMarkTTL),
+		hwMarks:           NewTSCache(hwMarkTTL, log),
 		lun2ds:            make(map[string]string),
 		initialized:       false,
 		clientFactory:     NewClientFactory(ctx, url, parent),
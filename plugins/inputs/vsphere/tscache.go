@@ -1,9 +1,10 @@
 package vsphere
 
 import (
-	"log"
 	"sync"
 	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
 )
 
 // TSCache is a cache of timestamps used to determine the validity of datapoints
@@ -11,13 +12,15 @@ type TSCache struct {
 	ttl   time.Duration
 	table map[string]time.Time
 	mux   sync.RWMutex
+	log   cua.Logger
 }
 
 // NewTSCache creates a new TSCache with a specified time-to-live after which timestamps are discarded.
-func NewTSCache(ttl time.Duration) *TSCache {
+func NewTSCache(ttl time.Duration, log cua.Logger) *TSCache {
 	return &TSCache{
 		ttl:   ttl,
 		table: make(map[string]time.Time),
+		log:   log,
 	}
 }
 
@@ -32,7 +35,7 @@ func (t *TSCache) Purge() {
 			n++
 		}
 	}
-	log.Printf("D! [inputs.vsphere] purged timestamp cache. %d deleted with %d remaining", n, len(t.table))
+	t.log.Debugf("purged timestamp cache. %d deleted with %d remaining", n, len(t.table))
 }
 
 // IsNew returns true if the supplied timestamp for the supplied key is more recent than the
@@ -3,7 +3,6 @@ package filestack
 import (
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
@@ -16,10 +15,10 @@ type Webhook struct {
 	acc  cua.Accumulator
 }
 
-func (fs *Webhook) Register(router *mux.Router, acc cua.Accumulator) {
+func (fs *Webhook) Register(router *mux.Router, acc cua.Accumulator, log cua.Logger) {
 	router.HandleFunc(fs.Path, fs.eventHandler).Methods("POST")
 
-	log.Printf("I! Started the webhooks_filestack on %s\n", fs.Path)
+	log.Infof("Started the webhooks_filestack on %s", fs.Path)
 	fs.acc = acc
 }
 
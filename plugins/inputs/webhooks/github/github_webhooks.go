@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
@@ -18,12 +17,14 @@ type Webhook struct {
 	Path   string
 	Secret string
 	acc    cua.Accumulator
+	log    cua.Logger
 }
 
-func (gh *Webhook) Register(router *mux.Router, acc cua.Accumulator) {
+func (gh *Webhook) Register(router *mux.Router, acc cua.Accumulator, log cua.Logger) {
 	router.HandleFunc(gh.Path, gh.eventHandler).Methods("POST")
-	log.Printf("I! Started the webhooks_github on %s\n", gh.Path)
+	log.Infof("Started the webhooks_github on %s", gh.Path)
 	gh.acc = acc
+	gh.log = log
 }
 
 func (gh *Webhook) eventHandler(w http.ResponseWriter, r *http.Request) {
@@ -36,18 +37,24 @@ func (gh *Webhook) eventHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if gh.Secret != "" && !checkSignature(gh.Secret, data, r.Header.Get("X-Hub-Signature")) {
-		log.Printf("E! Fail to check the github webhook signature\n")
+		gh.log.Error("Fail to check the github webhook signature")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	gh.log.Debugf("New %v event received", eventType)
 	e, err := NewEvent(data, eventType)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 	if e != nil {
-		p := e.NewMetric()
+		p, err := e.NewMetric()
+		if err != nil {
+			gh.acc.AddError(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		gh.acc.AddFields("github_webhooks", p.Fields(), p.Tags(), p.Time())
 	}
 
@@ -71,7 +78,6 @@ func (e *newEventError) Error() string {
 }
 
 func NewEvent(data []byte, name string) (Event, error) {
-	log.Printf("D! New %v event received", name)
 	switch name {
 	case "commit_comment":
 		return generateEvent(data, &CommitCommentEvent{})
@@ -2,7 +2,6 @@ package github
 
 import (
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
@@ -12,7 +11,7 @@ import (
 const meas = "github_webhooks"
 
 type Event interface {
-	NewMetric() cua.Metric
+	NewMetric() (cua.Metric, error)
 }
 
 type Repository struct {
@@ -91,7 +90,7 @@ type CommitCommentEvent struct {
 	Sender     Sender        `json:"sender"`
 }
 
-func (s CommitCommentEvent) NewMetric() cua.Metric {
+func (s CommitCommentEvent) NewMetric() (cua.Metric, error) {
 	event := "commit_comment"
 	t := map[string]string{
 		"event":      event,
@@ -109,9 +108,9 @@ func (s CommitCommentEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type CreateEvent struct {
@@ -121,7 +120,7 @@ type CreateEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s CreateEvent) NewMetric() cua.Metric {
+func (s CreateEvent) NewMetric() (cua.Metric, error) {
 	event := "create"
 	t := map[string]string{
 		"event":      event,
@@ -139,9 +138,9 @@ func (s CreateEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type DeleteEvent struct {
@@ -151,7 +150,7 @@ type DeleteEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s DeleteEvent) NewMetric() cua.Metric {
+func (s DeleteEvent) NewMetric() (cua.Metric, error) {
 	event := "delete"
 	t := map[string]string{
 		"event":      event,
@@ -169,9 +168,9 @@ func (s DeleteEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type DeploymentEvent struct {
@@ -180,7 +179,7 @@ type DeploymentEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s DeploymentEvent) NewMetric() cua.Metric {
+func (s DeploymentEvent) NewMetric() (cua.Metric, error) {
 	event := "deployment"
 	t := map[string]string{
 		"event":      event,
@@ -200,9 +199,9 @@ func (s DeploymentEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type DeploymentStatusEvent struct {
@@ -212,7 +211,7 @@ type DeploymentStatusEvent struct {
 	Sender           Sender           `json:"sender"`
 }
 
-func (s DeploymentStatusEvent) NewMetric() cua.Metric {
+func (s DeploymentStatusEvent) NewMetric() (cua.Metric, error) {
 	event := "delete"
 	t := map[string]string{
 		"event":      event,
@@ -234,9 +233,9 @@ func (s DeploymentStatusEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type ForkEvent struct {
@@ -245,7 +244,7 @@ type ForkEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s ForkEvent) NewMetric() cua.Metric {
+func (s ForkEvent) NewMetric() (cua.Metric, error) {
 	event := "fork"
 	t := map[string]string{
 		"event":      event,
@@ -262,9 +261,9 @@ func (s ForkEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type GollumEvent struct {
@@ -274,7 +273,7 @@ type GollumEvent struct {
 }
 
 // REVIEW: Going to be lazy and not deal with the pages.
-func (s GollumEvent) NewMetric() cua.Metric {
+func (s GollumEvent) NewMetric() (cua.Metric, error) {
 	event := "gollum"
 	t := map[string]string{
 		"event":      event,
@@ -290,9 +289,9 @@ func (s GollumEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type IssueCommentEvent struct {
@@ -302,7 +301,7 @@ type IssueCommentEvent struct {
 	Sender     Sender       `json:"sender"`
 }
 
-func (s IssueCommentEvent) NewMetric() cua.Metric {
+func (s IssueCommentEvent) NewMetric() (cua.Metric, error) {
 	event := "issue_comment"
 	t := map[string]string{
 		"event":      event,
@@ -322,9 +321,9 @@ func (s IssueCommentEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type IssuesEvent struct {
@@ -334,7 +333,7 @@ type IssuesEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s IssuesEvent) NewMetric() cua.Metric {
+func (s IssuesEvent) NewMetric() (cua.Metric, error) {
 	event := "issue"
 	t := map[string]string{
 		"event":      event,
@@ -354,9 +353,9 @@ func (s IssuesEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type MemberEvent struct {
@@ -365,7 +364,7 @@ type MemberEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s MemberEvent) NewMetric() cua.Metric {
+func (s MemberEvent) NewMetric() (cua.Metric, error) {
 	event := "member"
 	t := map[string]string{
 		"event":      event,
@@ -383,9 +382,9 @@ func (s MemberEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type MembershipEvent struct {
@@ -395,7 +394,7 @@ type MembershipEvent struct {
 	Team   Team   `json:"team"`
 }
 
-func (s MembershipEvent) NewMetric() cua.Metric {
+func (s MembershipEvent) NewMetric() (cua.Metric, error) {
 	event := "membership"
 	t := map[string]string{
 		"event":  event,
@@ -409,9 +408,9 @@ func (s MembershipEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PageBuildEvent struct {
@@ -419,7 +418,7 @@ type PageBuildEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s PageBuildEvent) NewMetric() cua.Metric {
+func (s PageBuildEvent) NewMetric() (cua.Metric, error) {
 	event := "page_build"
 	t := map[string]string{
 		"event":      event,
@@ -435,9 +434,9 @@ func (s PageBuildEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PublicEvent struct {
@@ -445,7 +444,7 @@ type PublicEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s PublicEvent) NewMetric() cua.Metric {
+func (s PublicEvent) NewMetric() (cua.Metric, error) {
 	event := "public"
 	t := map[string]string{
 		"event":      event,
@@ -461,9 +460,9 @@ func (s PublicEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PullRequestEvent struct {
@@ -473,7 +472,7 @@ type PullRequestEvent struct {
 	Sender      Sender      `json:"sender"`
 }
 
-func (s PullRequestEvent) NewMetric() cua.Metric {
+func (s PullRequestEvent) NewMetric() (cua.Metric, error) {
 	event := "pull_request"
 	t := map[string]string{
 		"event":      event,
@@ -498,9 +497,9 @@ func (s PullRequestEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PullRequestReviewCommentEvent struct {
@@ -510,7 +509,7 @@ type PullRequestReviewCommentEvent struct {
 	Sender      Sender                   `json:"sender"`
 }
 
-func (s PullRequestReviewCommentEvent) NewMetric() cua.Metric {
+func (s PullRequestReviewCommentEvent) NewMetric() (cua.Metric, error) {
 	event := "pull_request_review_comment"
 	t := map[string]string{
 		"event":      event,
@@ -536,9 +535,9 @@ func (s PullRequestReviewCommentEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PushEvent struct {
@@ -549,7 +548,7 @@ type PushEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s PushEvent) NewMetric() cua.Metric {
+func (s PushEvent) NewMetric() (cua.Metric, error) {
 	event := "push"
 	t := map[string]string{
 		"event":      event,
@@ -568,9 +567,9 @@ func (s PushEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type ReleaseEvent struct {
@@ -579,7 +578,7 @@ type ReleaseEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s ReleaseEvent) NewMetric() cua.Metric {
+func (s ReleaseEvent) NewMetric() (cua.Metric, error) {
 	event := "release"
 	t := map[string]string{
 		"event":      event,
@@ -596,9 +595,9 @@ func (s ReleaseEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type RepositoryEvent struct {
@@ -606,7 +605,7 @@ type RepositoryEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s RepositoryEvent) NewMetric() cua.Metric {
+func (s RepositoryEvent) NewMetric() (cua.Metric, error) {
 	event := "repository"
 	t := map[string]string{
 		"event":      event,
@@ -622,9 +621,9 @@ func (s RepositoryEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type StatusEvent struct {
@@ -634,7 +633,7 @@ type StatusEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s StatusEvent) NewMetric() cua.Metric {
+func (s StatusEvent) NewMetric() (cua.Metric, error) {
 	event := "status"
 	t := map[string]string{
 		"event":      event,
@@ -652,9 +651,9 @@ func (s StatusEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type TeamAddEvent struct {
@@ -663,7 +662,7 @@ type TeamAddEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s TeamAddEvent) NewMetric() cua.Metric {
+func (s TeamAddEvent) NewMetric() (cua.Metric, error) {
 	event := "team_add"
 	t := map[string]string{
 		"event":      event,
@@ -680,9 +679,9 @@ func (s TeamAddEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type WatchEvent struct {
@@ -690,7 +689,7 @@ type WatchEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s WatchEvent) NewMetric() cua.Metric {
+func (s WatchEvent) NewMetric() (cua.Metric, error) {
 	event := "delete"
 	t := map[string]string{
 		"event":      event,
@@ -706,7 +705,7 @@ func (s WatchEvent) NewMetric() cua.Metric {
 	}
 	m, err := metric.New(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %w", event, err)
 	}
-	return m
+	return m, nil
 }
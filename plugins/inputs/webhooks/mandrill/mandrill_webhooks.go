@@ -3,7 +3,6 @@ package mandrill
 import (
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"time"
@@ -17,11 +16,11 @@ type Webhook struct {
 	acc  cua.Accumulator
 }
 
-func (md *Webhook) Register(router *mux.Router, acc cua.Accumulator) {
+func (md *Webhook) Register(router *mux.Router, acc cua.Accumulator, log cua.Logger) {
 	router.HandleFunc(md.Path, md.returnOK).Methods("HEAD")
 	router.HandleFunc(md.Path, md.eventHandler).Methods("POST")
 
-	log.Printf("I! Started the webhooks_mandrill on %s\n", md.Path)
+	log.Infof("Started the webhooks_mandrill on %s", md.Path)
 	md.acc = acc
 }
 
@@ -2,7 +2,6 @@ package papertrail
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"time"
 
@@ -15,9 +14,9 @@ type Webhook struct {
 	acc  cua.Accumulator
 }
 
-func (pt *Webhook) Register(router *mux.Router, acc cua.Accumulator) {
+func (pt *Webhook) Register(router *mux.Router, acc cua.Accumulator, log cua.Logger) {
 	router.HandleFunc(pt.Path, pt.eventHandler).Methods("POST")
-	log.Printf("I! Started the papertrail_webhook on %s", pt.Path)
+	log.Infof("Started the papertrail_webhook on %s", pt.Path)
 	pt.acc = acc
 }
 
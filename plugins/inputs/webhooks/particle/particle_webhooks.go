@@ -40,7 +40,7 @@ type Webhook struct {
 	acc  cua.Accumulator
 }
 
-func (rb *Webhook) Register(router *mux.Router, acc cua.Accumulator) {
+func (rb *Webhook) Register(router *mux.Router, acc cua.Accumulator, _ cua.Logger) {
 	router.HandleFunc(rb.Path, rb.eventHandler).Methods("POST")
 	rb.acc = acc
 }
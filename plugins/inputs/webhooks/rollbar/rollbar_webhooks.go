@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
@@ -18,9 +17,9 @@ type Webhook struct {
 	acc  cua.Accumulator
 }
 
-func (rb *Webhook) Register(router *mux.Router, acc cua.Accumulator) {
+func (rb *Webhook) Register(router *mux.Router, acc cua.Accumulator, log cua.Logger) {
 	router.HandleFunc(rb.Path, rb.eventHandler).Methods("POST")
-	log.Printf("I! Started the webhooks_rollbar on %s\n", rb.Path)
+	log.Infof("Started the webhooks_rollbar on %s", rb.Path)
 	rb.acc = acc
 }
 
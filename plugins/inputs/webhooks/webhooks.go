@@ -3,7 +3,6 @@ package webhooks
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"reflect"
@@ -20,7 +19,7 @@ import (
 )
 
 type Webhook interface {
-	Register(router *mux.Router, acc cua.Accumulator)
+	Register(router *mux.Router, acc cua.Accumulator, log cua.Logger)
 }
 
 func init() {
@@ -37,6 +36,8 @@ type Webhooks struct {
 	Papertrail *papertrail.Webhook
 	Particle   *particle.Webhook
 
+	Log cua.Logger `toml:"-"`
+
 	srv *http.Server
 }
 
@@ -103,32 +104,30 @@ func (wh *Webhooks) Start(acc cua.Accumulator) error {
 	r := mux.NewRouter()
 
 	for _, webhook := range wh.AvailableWebhooks() {
-		webhook.Register(r, acc)
+		webhook.Register(r, acc, wh.Log)
 	}
 
 	wh.srv = &http.Server{Handler: r}
 
 	ln, err := net.Listen("tcp", wh.ServiceAddress)
 	if err != nil {
-		log.Fatalf("E! Error starting server: %v", err)
 		return fmt.Errorf("listen (%s): %w", wh.ServiceAddress, err)
-
 	}
 
 	go func() {
 		if err := wh.srv.Serve(ln); err != nil {
 			if !errors.Is(err, http.ErrServerClosed) {
-				acc.AddError(fmt.Errorf("E! Error listening: %w", err))
+				acc.AddError(fmt.Errorf("error listening: %w", err))
 			}
 		}
 	}()
 
-	log.Printf("I! Started the webhooks service on %s\n", wh.ServiceAddress)
+	wh.Log.Infof("Started the webhooks service on %s", wh.ServiceAddress)
 
 	return nil
 }
 
 func (wh *Webhooks) Stop() {
 	wh.srv.Close()
-	log.Println("I! Stopping the Webhooks service")
+	wh.Log.Info("Stopping the Webhooks service")
 }
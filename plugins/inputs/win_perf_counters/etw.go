@@ -0,0 +1,392 @@
+// +build windows
+
+package winperfcounters
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	wnodeFlagTracedGUID        = 0x00020000
+	eventTraceRealTimeMode     = 0x00000100
+	eventTraceControlQuery     = 0
+	eventTraceControlStop      = 1
+	eventControlEnableProvider = 1
+	processTraceModeRealTime   = 0x00000100
+	processTraceModeEventRec   = 0x10000000
+	// system time timestamps
+	wnodeClientContextSystemTime = 2
+
+	errorAlreadyExists = windows.Errno(183)
+	errorCtxClosed     = windows.Errno(6)
+	errorCancelled     = windows.Errno(1223)
+)
+
+var (
+	advapi32            = windows.NewLazySystemDLL("advapi32.dll")
+	procStartTraceW     = advapi32.NewProc("StartTraceW")
+	procControlTraceW   = advapi32.NewProc("ControlTraceW")
+	procEnableTraceEx2  = advapi32.NewProc("EnableTraceEx2")
+	procOpenTraceW      = advapi32.NewProc("OpenTraceW")
+	procProcessTrace    = advapi32.NewProc("ProcessTrace")
+	procCloseTrace      = advapi32.NewProc("CloseTrace")
+	invalidTraceHandle  = uint64(^uintptr(0))
+	etwCallback         uintptr
+	etwCallbackOnce     sync.Once
+	etwSessions         = make(map[uintptr]*etwSession)
+	etwSessionsMu       sync.RWMutex
+	etwSessionIDCounter uintptr
+)
+
+type wnodeHeader struct {
+	BufferSize        uint32
+	ProviderID        uint32
+	HistoricalContext uint64
+	TimeStamp         int64
+	GUID              windows.GUID
+	ClientContext     uint32
+	Flags             uint32
+}
+
+type eventTraceProperties struct {
+	Wnode               wnodeHeader
+	BufferSize          uint32
+	MinimumBuffers      uint32
+	MaximumBuffers      uint32
+	MaximumFileSize     uint32
+	LogFileMode         uint32
+	FlushTimer          uint32
+	EnableFlags         uint32
+	AgeLimit            int32
+	NumberOfBuffers     uint32
+	FreeBuffers         uint32
+	EventsLost          uint32
+	BuffersWritten      uint32
+	LogBuffersLost      uint32
+	RealTimeBuffersLost uint32
+	LoggerThreadID      uintptr
+	LogFileNameOffset   uint32
+	LoggerNameOffset    uint32
+}
+
+// eventTracePropertiesBuf has room for the session name after the
+// properties, as required by StartTrace and ControlTrace.
+type eventTracePropertiesBuf struct {
+	eventTraceProperties
+	name [1024]uint16
+}
+
+type eventTraceHeader struct {
+	Size           uint16
+	FieldTypeFlags uint16
+	Version        uint32
+	ThreadID       uint32
+	ProcessID      uint32
+	TimeStamp      int64
+	GUID           windows.GUID
+	ProcessorTime  uint64
+}
+
+type eventTrace struct {
+	Header           eventTraceHeader
+	InstanceID       uint32
+	ParentInstanceID uint32
+	ParentGUID       windows.GUID
+	MofData          uintptr
+	MofLength        uint32
+	ClientContext    uint32
+}
+
+type traceLogfileHeader struct {
+	BufferSize         uint32
+	Version            uint32
+	ProviderVersion    uint32
+	NumberOfProcessors uint32
+	EndTime            int64
+	TimerResolution    uint32
+	MaximumFileSize    uint32
+	LogFileMode        uint32
+	BuffersWritten     uint32
+	LogInstanceGUID    windows.GUID
+	LoggerName         uintptr
+	LogFileName        uintptr
+	TimeZone           windows.Timezoneinformation
+	BootTime           int64
+	PerfFreq           int64
+	StartTime          int64
+	ReservedFlags      uint32
+	BuffersLost        uint32
+}
+
+type eventTraceLogfile struct {
+	LogFileName         uintptr
+	LoggerName          uintptr
+	CurrentTime         int64
+	BuffersRead         uint32
+	ProcessTraceMode    uint32
+	CurrentEvent        eventTrace
+	LogfileHeader       traceLogfileHeader
+	BufferCallback      uintptr
+	BufferSize          uint32
+	Filled              uint32
+	EventsLost          uint32
+	EventRecordCallback uintptr
+	IsKernelTrace       uint32
+	Context             uintptr
+}
+
+type eventDescriptor struct {
+	ID      uint16
+	Version uint8
+	Channel uint8
+	Level   uint8
+	Opcode  uint8
+	Task    uint16
+	Keyword uint64
+}
+
+type eventHeader struct {
+	Size            uint16
+	HeaderType      uint16
+	Flags           uint16
+	EventProperty   uint16
+	ThreadID        uint32
+	ProcessID       uint32
+	TimeStamp       int64
+	ProviderID      windows.GUID
+	EventDescriptor eventDescriptor
+	ProcessorTime   uint64
+	ActivityID      windows.GUID
+}
+
+type eventRecord struct {
+	EventHeader       eventHeader
+	BufferContext     uint32
+	ExtendedDataCount uint16
+	UserDataLength    uint16
+	ExtendedData      uintptr
+	UserData          uintptr
+	UserContext       uintptr
+}
+
+// etwSession is a real-time ETW trace session consuming the events of the
+// configured providers.
+type etwSession struct {
+	name       string
+	providers  map[windows.GUID]*etwProvider
+	aggregator *etwAggregator
+
+	id            uintptr
+	sessionHandle uint64
+	traceHandle   uint64
+	done          chan struct{}
+}
+
+func newETWSession(name string, providers []*etwProvider) (*etwSession, error) {
+	s := &etwSession{
+		name:       name,
+		providers:  make(map[windows.GUID]*etwProvider, len(providers)),
+		aggregator: newETWAggregator(),
+		done:       make(chan struct{}),
+	}
+	for _, p := range providers {
+		guid, err := windows.GUIDFromString(p.guid)
+		if err != nil {
+			return nil, fmt.Errorf("etw provider %q: %w", p.Provider, err)
+		}
+		s.providers[guid] = p
+	}
+	return s, nil
+}
+
+func (s *etwSession) properties() (*eventTracePropertiesBuf, error) {
+	name, err := windows.UTF16FromString(s.name)
+	if err != nil {
+		return nil, fmt.Errorf("etw session name: %w", err)
+	}
+	props := &eventTracePropertiesBuf{}
+	if len(name) > len(props.name) {
+		return nil, fmt.Errorf("etw session name too long")
+	}
+	props.Wnode.BufferSize = uint32(unsafe.Sizeof(*props))
+	props.Wnode.Flags = wnodeFlagTracedGUID
+	props.Wnode.ClientContext = wnodeClientContextSystemTime
+	props.LogFileMode = eventTraceRealTimeMode
+	props.LoggerNameOffset = uint32(unsafe.Sizeof(props.eventTraceProperties))
+	return props, nil
+}
+
+// start creates the session, enables the providers and processes the events
+// in the background.
+func (s *etwSession) start() error {
+	props, err := s.properties()
+	if err != nil {
+		return err
+	}
+	name, _ := windows.UTF16PtrFromString(s.name)
+
+	r, _, _ := procStartTraceW.Call(
+		uintptr(unsafe.Pointer(&s.sessionHandle)),
+		uintptr(unsafe.Pointer(name)),
+		uintptr(unsafe.Pointer(props)))
+	if windows.Errno(r) == errorAlreadyExists {
+		// a session left over by a previous run, stop it and try again
+		if err := s.control(eventTraceControlStop); err != nil {
+			return err
+		}
+		props, _ = s.properties()
+		r, _, _ = procStartTraceW.Call(
+			uintptr(unsafe.Pointer(&s.sessionHandle)),
+			uintptr(unsafe.Pointer(name)),
+			uintptr(unsafe.Pointer(props)))
+	}
+	if r != 0 {
+		return fmt.Errorf("StartTrace %s: %w", s.name, windows.Errno(r))
+	}
+
+	for guid, p := range s.providers {
+		guid := guid
+		args := append(u64Args(s.sessionHandle),
+			uintptr(unsafe.Pointer(&guid)),
+			eventControlEnableProvider,
+			uintptr(p.Level))
+		args = append(args, u64Args(p.MatchAnyKeyword)...)
+		args = append(args, u64Args(p.MatchAllKeyword)...)
+		args = append(args, 0, 0)
+		if r, _, _ := procEnableTraceEx2.Call(args...); r != 0 {
+			_ = s.control(eventTraceControlStop)
+			return fmt.Errorf("EnableTraceEx2 %s: %w", p.Provider, windows.Errno(r))
+		}
+	}
+
+	etwCallbackOnce.Do(func() {
+		etwCallback = windows.NewCallback(etwEventCallback)
+	})
+
+	etwSessionsMu.Lock()
+	etwSessionIDCounter++
+	s.id = etwSessionIDCounter
+	etwSessions[s.id] = s
+	etwSessionsMu.Unlock()
+
+	logfile := &eventTraceLogfile{
+		LoggerName:          uintptr(unsafe.Pointer(name)),
+		ProcessTraceMode:    processTraceModeRealTime | processTraceModeEventRec,
+		EventRecordCallback: etwCallback,
+		Context:             s.id,
+	}
+	r1, r2, _ := procOpenTraceW.Call(uintptr(unsafe.Pointer(logfile)))
+	s.traceHandle = u64Result(r1, r2)
+	if s.traceHandle == invalidTraceHandle {
+		s.unregister()
+		_ = s.control(eventTraceControlStop)
+		return fmt.Errorf("OpenTrace %s: %w", s.name, windows.GetLastError())
+	}
+
+	go func() {
+		defer close(s.done)
+		handle := s.traceHandle
+		args := []uintptr{uintptr(unsafe.Pointer(&handle)), 1, 0, 0}
+		// ProcessTrace blocks until the session is closed
+		_, _, _ = procProcessTrace.Call(args...)
+	}()
+
+	return nil
+}
+
+// stop closes the trace and stops the session.
+func (s *etwSession) stop() error {
+	var errs []error
+	if r, _, _ := procCloseTrace.Call(u64Args(s.traceHandle)...); r != 0 &&
+		windows.Errno(r) != errorCtxClosed && windows.Errno(r) != errorCancelled {
+		errs = append(errs, fmt.Errorf("CloseTrace: %w", windows.Errno(r)))
+	}
+	if err := s.control(eventTraceControlStop); err != nil {
+		errs = append(errs, err)
+	}
+	<-s.done
+	s.unregister()
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// eventsLost returns the number of events lost by the session.
+func (s *etwSession) eventsLost() (uint32, error) {
+	props, err := s.properties()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.controlWith(eventTraceControlQuery, props); err != nil {
+		return 0, err
+	}
+	return props.EventsLost + props.RealTimeBuffersLost, nil
+}
+
+func (s *etwSession) control(code uint32) error {
+	props, err := s.properties()
+	if err != nil {
+		return err
+	}
+	return s.controlWith(code, props)
+}
+
+func (s *etwSession) controlWith(code uint32, props *eventTracePropertiesBuf) error {
+	name, _ := windows.UTF16PtrFromString(s.name)
+	// the session is addressed by name so it also works for sessions left
+	// over by a previous run
+	args := append(u64Args(0),
+		uintptr(unsafe.Pointer(name)),
+		uintptr(unsafe.Pointer(props)),
+		uintptr(code))
+	if r, _, _ := procControlTraceW.Call(args...); r != 0 {
+		return fmt.Errorf("ControlTrace %s: %w", s.name, windows.Errno(r))
+	}
+	return nil
+}
+
+func (s *etwSession) unregister() {
+	etwSessionsMu.Lock()
+	delete(etwSessions, s.id)
+	etwSessionsMu.Unlock()
+}
+
+func etwEventCallback(rec *eventRecord) uintptr {
+	etwSessionsMu.RLock()
+	s, ok := etwSessions[rec.UserContext]
+	etwSessionsMu.RUnlock()
+	if !ok {
+		return 0
+	}
+
+	p, ok := s.providers[rec.EventHeader.ProviderID]
+	if !ok {
+		return 0
+	}
+	d := rec.EventHeader.EventDescriptor
+	s.aggregator.add(p, d.ID, d.Opcode, d.Level, rec.EventHeader.ProcessID)
+	return 0
+}
+
+// u64Args passes a 64 bit argument, split in two on 32 bit platforms.
+func u64Args(v uint64) []uintptr {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return []uintptr{uintptr(v)}
+	}
+	return []uintptr{uintptr(uint32(v)), uintptr(uint32(v >> 32))}
+}
+
+// u64Result joins a 64 bit return value, returned in two registers on 32
+// bit platforms.
+func u64Result(r1, r2 uintptr) uint64 {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return uint64(r1)
+	}
+	return uint64(uint32(r1)) | uint64(uint32(r2))<<32
+}
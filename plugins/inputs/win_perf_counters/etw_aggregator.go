@@ -0,0 +1,148 @@
+package winperfcounters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+)
+
+// knownETWProviders maps the names of the supported providers to their GUID.
+var knownETWProviders = map[string]string{
+	"microsoft-windows-tcpip":          "{2F07E2EE-15DB-40F1-90EF-9D7BA282188A}",
+	"microsoft-windows-kernel-process": "{22FB2CD6-0E7B-422B-A0C7-2FAD1FD0E716}",
+	"microsoft-windows-dns-client":     "{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}",
+}
+
+// etwProvider is a provider enabled in the ETW real-time session.
+type etwProvider struct {
+	// Provider is the name of a known provider or a GUID in registry format.
+	Provider        string
+	Level           uint8
+	MatchAnyKeyword uint64
+	MatchAllKeyword uint64
+	EventIDs        []uint16
+	Measurement     string
+	PerProcess      bool
+
+	guid     string
+	eventIDs map[uint16]bool
+}
+
+// init validates the provider and resolves its GUID.
+func (p *etwProvider) init() error {
+	if p.Provider == "" {
+		return fmt.Errorf("etw provider is required")
+	}
+
+	if guid, ok := knownETWProviders[strings.ToLower(p.Provider)]; ok {
+		p.guid = guid
+	} else if isGUID(p.Provider) {
+		p.guid = strings.ToUpper(p.Provider)
+	} else {
+		return fmt.Errorf("unknown etw provider %q", p.Provider)
+	}
+
+	if p.Level > 5 {
+		return fmt.Errorf("invalid etw level %d for provider %q", p.Level, p.Provider)
+	}
+	if p.Level == 0 {
+		// TRACE_LEVEL_INFORMATION
+		p.Level = 4
+	}
+	if p.Measurement == "" {
+		p.Measurement = "win_etw"
+	}
+
+	if len(p.EventIDs) > 0 {
+		p.eventIDs = make(map[uint16]bool, len(p.EventIDs))
+		for _, id := range p.EventIDs {
+			p.eventIDs[id] = true
+		}
+	}
+	return nil
+}
+
+// accepts reports whether events with the id are collected.
+func (p *etwProvider) accepts(id uint16) bool {
+	return p.eventIDs == nil || p.eventIDs[id]
+}
+
+// isGUID checks for the "{XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX}" format.
+func isGUID(s string) bool {
+	if len(s) != 38 || s[0] != '{' || s[37] != '}' {
+		return false
+	}
+	for i, c := range s[1:37] {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type etwKey struct {
+	provider  *etwProvider
+	eventID   uint16
+	opcode    uint8
+	level     uint8
+	processID uint32
+}
+
+// etwAggregator counts the events received between two gathers.
+type etwAggregator struct {
+	mu     sync.Mutex
+	counts map[etwKey]uint64
+}
+
+func newETWAggregator() *etwAggregator {
+	return &etwAggregator{counts: make(map[etwKey]uint64)}
+}
+
+func (a *etwAggregator) add(p *etwProvider, eventID uint16, opcode, level uint8, processID uint32) {
+	if !p.accepts(eventID) {
+		return
+	}
+	key := etwKey{provider: p, eventID: eventID, opcode: opcode, level: level}
+	if p.PerProcess {
+		key.processID = processID
+	}
+
+	a.mu.Lock()
+	a.counts[key]++
+	a.mu.Unlock()
+}
+
+// flush adds a metric per provider, event id, opcode and level, and per
+// process if enabled, with the number of events since the last flush.
+func (a *etwAggregator) flush(acc cua.Accumulator, now time.Time, eventsLost uint32) {
+	a.mu.Lock()
+	counts := a.counts
+	a.counts = make(map[etwKey]uint64, len(counts))
+	a.mu.Unlock()
+
+	for key, count := range counts {
+		tags := map[string]string{
+			"provider": key.provider.Provider,
+			"event_id": strconv.Itoa(int(key.eventID)),
+			"opcode":   strconv.Itoa(int(key.opcode)),
+			"level":    strconv.Itoa(int(key.level)),
+		}
+		if key.provider.PerProcess {
+			tags["process_id"] = strconv.FormatUint(uint64(key.processID), 10)
+		}
+		acc.AddCounter(key.provider.Measurement, map[string]interface{}{"events": count}, tags, now)
+	}
+
+	acc.AddFields("win_etw_session", map[string]interface{}{"events_lost": eventsLost}, nil, now)
+}
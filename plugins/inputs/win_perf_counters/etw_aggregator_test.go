@@ -0,0 +1,88 @@
+package winperfcounters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETWProviderInit(t *testing.T) {
+	p := &etwProvider{Provider: "Microsoft-Windows-DNS-Client"}
+	require.NoError(t, p.init())
+	require.Equal(t, "{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}", p.guid)
+	require.Equal(t, uint8(4), p.Level)
+	require.Equal(t, "win_etw", p.Measurement)
+
+	p = &etwProvider{Provider: "{2f07e2ee-15db-40f1-90ef-9d7ba282188a}", Level: 5}
+	require.NoError(t, p.init())
+	require.Equal(t, "{2F07E2EE-15DB-40F1-90EF-9D7BA282188A}", p.guid)
+	require.Equal(t, uint8(5), p.Level)
+
+	require.Error(t, (&etwProvider{}).init())
+	require.Error(t, (&etwProvider{Provider: "Microsoft-Windows-Unknown"}).init())
+	require.Error(t, (&etwProvider{Provider: "2F07E2EE-15DB-40F1-90EF-9D7BA282188A"}).init())
+	require.Error(t, (&etwProvider{Provider: "Microsoft-Windows-TCPIP", Level: 6}).init())
+}
+
+func TestETWAggregator(t *testing.T) {
+	dns := &etwProvider{Provider: "Microsoft-Windows-DNS-Client", EventIDs: []uint16{3008}}
+	require.NoError(t, dns.init())
+	proc := &etwProvider{Provider: "Microsoft-Windows-Kernel-Process", Measurement: "win_etw_process", PerProcess: true}
+	require.NoError(t, proc.init())
+
+	a := newETWAggregator()
+	a.add(dns, 3008, 0, 4, 100)
+	a.add(dns, 3008, 0, 4, 200)
+	a.add(dns, 3020, 0, 4, 100)
+	a.add(proc, 1, 1, 4, 100)
+	a.add(proc, 1, 1, 4, 200)
+
+	now := time.Unix(0, 0)
+	var acc testutil.Accumulator
+	a.flush(&acc, now, 3)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("win_etw",
+			map[string]string{
+				"provider": "Microsoft-Windows-DNS-Client",
+				"event_id": "3008",
+				"opcode":   "0",
+				"level":    "4",
+			},
+			map[string]interface{}{"events": uint64(2)},
+			now, cua.Counter),
+		testutil.MustMetric("win_etw_process",
+			map[string]string{
+				"provider":   "Microsoft-Windows-Kernel-Process",
+				"event_id":   "1",
+				"opcode":     "1",
+				"level":      "4",
+				"process_id": "100",
+			},
+			map[string]interface{}{"events": uint64(1)},
+			now, cua.Counter),
+		testutil.MustMetric("win_etw_process",
+			map[string]string{
+				"provider":   "Microsoft-Windows-Kernel-Process",
+				"event_id":   "1",
+				"opcode":     "1",
+				"level":      "4",
+				"process_id": "200",
+			},
+			map[string]interface{}{"events": uint64(1)},
+			now, cua.Counter),
+		testutil.MustMetric("win_etw_session",
+			map[string]string{},
+			map[string]interface{}{"events_lost": uint64(3)},
+			now),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics(), testutil.SortMetrics())
+
+	// counts are reset by the flush
+	acc.ClearMetrics()
+	a.flush(&acc, now, 0)
+	require.Len(t, acc.GetCUAMetrics(), 1)
+}
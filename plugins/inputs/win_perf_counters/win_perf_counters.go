@@ -135,6 +135,27 @@ var sampleConfig = `
     ]
     Instances = ["_Total"]
     Measurement = "win_swap"
+
+  ## ETW real-time mode, counts the events of select providers between two
+  ## gathers. Requires the agent to run as administrator or as a member of
+  ## the "Performance Log Users" group.
+  # ETWSessionName = "cua-win-perf-counters"
+  # [[inputs.win_perf_counters.etw]]
+  #   ## Known providers are "Microsoft-Windows-TCPIP",
+  #   ## "Microsoft-Windows-Kernel-Process" and "Microsoft-Windows-DNS-Client",
+  #   ## other providers can be enabled by GUID.
+  #   Provider = "Microsoft-Windows-DNS-Client"
+  #   ## Maximum event level, 1 (critical) to 5 (verbose), default 4.
+  #   # Level = 4
+  #   ## Keyword filters of the provider.
+  #   # MatchAnyKeyword = 0
+  #   # MatchAllKeyword = 0
+  #   ## Only count these event ids, all events by default.
+  #   # EventIDs = [3008]
+  #   ## Measurement of the event counts.
+  #   # Measurement = "win_etw"
+  #   ## Add a process_id tag and count the events per process.
+  #   # PerProcess = false
 `
 
 type WinPerfCounters struct {
@@ -145,12 +166,15 @@ type WinPerfCounters struct {
 	Object                  []perfobject
 	CountersRefreshInterval internal.Duration
 	UseWildcardsExpansion   bool
+	ETW                     []etwProvider
+	ETWSessionName          string
 
 	Log cua.Logger
 
 	lastRefreshed time.Time
 	counters      []*counter
 	query         PerformanceQuery
+	etwSession    *etwSession
 }
 
 type perfobject struct {
@@ -339,7 +363,58 @@ func (m *WinPerfCounters) ParseConfig() error {
 	return err
 }
 
+// Start starts the ETW session when ETW providers are configured.
+func (m *WinPerfCounters) Start(acc cua.Accumulator) error {
+	if len(m.ETW) == 0 {
+		return nil
+	}
+
+	providers := make([]*etwProvider, 0, len(m.ETW))
+	for i := range m.ETW {
+		if err := m.ETW[i].init(); err != nil {
+			return err
+		}
+		providers = append(providers, &m.ETW[i])
+	}
+
+	session, err := newETWSession(m.ETWSessionName, providers)
+	if err != nil {
+		return err
+	}
+	if err := session.start(); err != nil {
+		return fmt.Errorf("win_perf_counters etw: %w", err)
+	}
+	m.etwSession = session
+	return nil
+}
+
+// Stop stops the ETW session.
+func (m *WinPerfCounters) Stop() {
+	if m.etwSession == nil {
+		return
+	}
+	if err := m.etwSession.stop(); err != nil {
+		m.Log.Errorf("stopping etw session: %s", err)
+	}
+	m.etwSession = nil
+}
+
+func (m *WinPerfCounters) gatherETW(acc cua.Accumulator) {
+	eventsLost, err := m.etwSession.eventsLost()
+	if err != nil {
+		acc.AddError(fmt.Errorf("win_perf_counters etw: %w", err))
+	}
+	m.etwSession.aggregator.flush(acc, time.Now(), eventsLost)
+}
+
 func (m *WinPerfCounters) Gather(acc cua.Accumulator) error {
+	if m.etwSession != nil {
+		m.gatherETW(acc)
+		if len(m.Object) == 0 {
+			return nil
+		}
+	}
+
 	// Parse the config once
 	var err error
 
@@ -476,6 +551,10 @@ func isKnownCounterDataError(err error) bool {
 
 func init() {
 	inputs.Add("win_perf_counters", func() cua.Input {
-		return &WinPerfCounters{query: &PerformanceQueryImpl{}, CountersRefreshInterval: internal.Duration{Duration: time.Second * 60}}
+		return &WinPerfCounters{
+			query:                   &PerformanceQueryImpl{},
+			CountersRefreshInterval: internal.Duration{Duration: time.Second * 60},
+			ETWSessionName:          "cua-win-perf-counters",
+		}
 	})
 }
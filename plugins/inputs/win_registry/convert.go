@@ -0,0 +1,135 @@
+package winregistry
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// rootKeys maps the names of the root keys to their short form.
+var rootKeys = map[string]string{
+	"HKLM":                "HKLM",
+	"HKEY_LOCAL_MACHINE":  "HKLM",
+	"HKCU":                "HKCU",
+	"HKEY_CURRENT_USER":   "HKCU",
+	"HKU":                 "HKU",
+	"HKEY_USERS":          "HKU",
+	"HKCR":                "HKCR",
+	"HKEY_CLASSES_ROOT":   "HKCR",
+	"HKCC":                "HKCC",
+	"HKEY_CURRENT_CONFIG": "HKCC",
+}
+
+// splitKey returns the short name of the root key and the path of a key
+// such as HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft.
+func splitKey(key string) (string, string, error) {
+	key = strings.Trim(key, `\`)
+	parts := strings.SplitN(key, `\`, 2)
+	root, ok := rootKeys[strings.ToUpper(parts[0])]
+	if !ok {
+		return "", "", fmt.Errorf("key %q must start with HKLM, HKCU, HKU, HKCR or HKCC", key)
+	}
+	if len(parts) == 1 {
+		return root, "", nil
+	}
+	return root, parts[1], nil
+}
+
+// coerce converts a registry value to the type of the field: the value is
+// a string, a list of strings, an integer or binary data. With an empty
+// type, the lists are joined with commas and the binary data is
+// hex-encoded.
+func coerce(value interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case "":
+		switch v := value.(type) {
+		case []string:
+			return strings.Join(v, ","), nil
+		case []byte:
+			return hex.EncodeToString(v), nil
+		case uint64:
+			if v > math.MaxInt64 {
+				return v, nil
+			}
+			return int64(v), nil
+		default:
+			return v, nil
+		}
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []string:
+			return strings.Join(v, ","), nil
+		case []byte:
+			return hex.EncodeToString(v), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case "int", "integer":
+		switch v := value.(type) {
+		case uint64:
+			return int64(v), nil
+		case string:
+			i, err := strconv.ParseInt(strings.TrimSpace(v), 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("converting %q to integer: %w", v, err)
+			}
+			return i, nil
+		}
+	case "uint", "unsigned":
+		switch v := value.(type) {
+		case uint64:
+			return v, nil
+		case string:
+			u, err := strconv.ParseUint(strings.TrimSpace(v), 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("converting %q to unsigned: %w", v, err)
+			}
+			return u, nil
+		}
+	case "float":
+		switch v := value.(type) {
+		case uint64:
+			return float64(v), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("converting %q to float: %w", v, err)
+			}
+			return f, nil
+		}
+	case "bool", "boolean":
+		switch v := value.(type) {
+		case uint64:
+			return v != 0, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("converting %q to boolean: %w", v, err)
+			}
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", value, typ)
+}
+
+// checkType returns an error when the type of a field is not supported.
+func checkType(typ string) error {
+	switch typ {
+	case "", "string", "int", "integer", "uint", "unsigned", "float", "bool", "boolean":
+		return nil
+	default:
+		return fmt.Errorf("unknown type %q", typ)
+	}
+}
+
+// formatVersion returns the dotted form of the version of a file, from the
+// most and least significant 32 bits of its version.
+func formatVersion(ms, ls uint32) string {
+	return fmt.Sprintf("%d.%d.%d.%d", ms>>16, ms&0xffff, ls>>16, ls&0xffff)
+}
@@ -0,0 +1,63 @@
+package winregistry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitKey(t *testing.T) {
+	root, path, err := splitKey(`HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows NT\CurrentVersion`)
+	require.NoError(t, err)
+	require.Equal(t, "HKLM", root)
+	require.Equal(t, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, path)
+
+	root, path, err = splitKey(`hkcu\`)
+	require.NoError(t, err)
+	require.Equal(t, "HKCU", root)
+	require.Empty(t, path)
+
+	_, _, err = splitKey(`SOFTWARE\Microsoft`)
+	require.Error(t, err)
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		typ      string
+		expected interface{}
+	}{
+		{value: "19042", typ: "", expected: "19042"},
+		{value: uint64(1), typ: "", expected: int64(1)},
+		{value: uint64(1 << 63), typ: "", expected: uint64(1 << 63)},
+		{value: []string{"a", "b"}, typ: "", expected: "a,b"},
+		{value: []byte{0xde, 0xad}, typ: "", expected: "dead"},
+		{value: uint64(4), typ: "string", expected: "4"},
+		{value: []byte{0x01}, typ: "string", expected: "01"},
+		{value: " 19042 ", typ: "int", expected: int64(19042)},
+		{value: "0x10", typ: "uint", expected: uint64(16)},
+		{value: "6.3", typ: "float", expected: 6.3},
+		{value: uint64(2), typ: "float", expected: 2.0},
+		{value: uint64(0), typ: "bool", expected: false},
+		{value: "true", typ: "bool", expected: true},
+	}
+	for _, tt := range tests {
+		actual, err := coerce(tt.value, tt.typ)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, actual)
+	}
+
+	_, err := coerce("6.3", "int")
+	require.Error(t, err)
+	_, err = coerce([]string{"a"}, "bool")
+	require.Error(t, err)
+	_, err = coerce("a", "time")
+	require.Error(t, err)
+
+	require.NoError(t, checkType("boolean"))
+	require.Error(t, checkType("time"))
+}
+
+func TestFormatVersion(t *testing.T) {
+	require.Equal(t, "10.0.19041.1151", formatVersion(10<<16, 19041<<16|1151))
+}
@@ -0,0 +1,76 @@
+// +build windows
+
+package winregistry
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modversion = windows.NewLazySystemDLL("version.dll")
+
+	procGetFileVersionInfoSizeW = modversion.NewProc("GetFileVersionInfoSizeW")
+	procGetFileVersionInfoW     = modversion.NewProc("GetFileVersionInfoW")
+	procVerQueryValueW          = modversion.NewProc("VerQueryValueW")
+)
+
+// fixedFileInfo is the VS_FIXEDFILEINFO structure.
+// https://docs.microsoft.com/en-us/windows/win32/api/verrsrc/ns-verrsrc-vs_fixedfileinfo
+type fixedFileInfo struct {
+	Signature        uint32
+	StrucVersion     uint32
+	FileVersionMS    uint32
+	FileVersionLS    uint32
+	ProductVersionMS uint32
+	ProductVersionLS uint32
+	FileFlagsMask    uint32
+	FileFlags        uint32
+	FileOS           uint32
+	FileType         uint32
+	FileSubtype      uint32
+	FileDateMS       uint32
+	FileDateLS       uint32
+}
+
+// fileVersion returns the file and product versions of the version
+// resource of a file.
+func fileVersion(path string) (string, string, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", "", fmt.Errorf("file name: %w", err)
+	}
+	size, _, err := procGetFileVersionInfoSizeW.Call(uintptr(unsafe.Pointer(name)), 0)
+	if size == 0 {
+		return "", "", fmt.Errorf("version information size: %w", err)
+	}
+
+	buf := make([]byte, size)
+	r, _, err := procGetFileVersionInfoW.Call(uintptr(unsafe.Pointer(name)), 0, size, uintptr(unsafe.Pointer(&buf[0])))
+	if r == 0 {
+		return "", "", fmt.Errorf("version information: %w", err)
+	}
+
+	root, _ := windows.UTF16PtrFromString(`\`)
+	var (
+		info   *fixedFileInfo
+		length uint32
+	)
+	r, _, err = procVerQueryValueW.Call(
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(root)),
+		uintptr(unsafe.Pointer(&info)),
+		uintptr(unsafe.Pointer(&length)),
+	)
+	if r == 0 || info == nil || length < uint32(unsafe.Sizeof(fixedFileInfo{})) {
+		return "", "", fmt.Errorf("fixed file information: %w", err)
+	}
+	fixed := *info
+	runtime.KeepAlive(buf)
+
+	return formatVersion(fixed.FileVersionMS, fixed.FileVersionLS),
+		formatVersion(fixed.ProductVersionMS, fixed.ProductVersionLS), nil
+}
@@ -0,0 +1,213 @@
+// +build windows
+
+package winregistry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	"golang.org/x/sys/windows/registry"
+)
+
+const sampleConfig = `
+  ## Files to report the existence, size and version of.
+  # files = ['C:\Windows\System32\ntoskrnl.exe']
+
+  ## Registry values to report, the values of a key are the fields of a
+  ## win_registry metric tagged with the key.
+  # [[inputs.win_registry.value]]
+  #   ## Key, starting with HKLM, HKCU, HKU, HKCR or HKCC
+  #   key = 'HKLM\SOFTWARE\Microsoft\Windows NT\CurrentVersion'
+  #   ## Name of the value, empty for the default value of the key
+  #   name = "CurrentBuild"
+  #   ## Name of the field, defaults to the name of the value
+  #   # field = "build"
+  #   ## Type of the field: string, int, uint, float or bool. By default
+  #   ## strings stay strings, numbers are integers, lists of strings are
+  #   ## joined with commas and binary data is hex-encoded.
+  #   # type = "int"
+`
+
+// Value is a registry value to report.
+type Value struct {
+	Key   string `toml:"key"`
+	Name  string `toml:"name"`
+	Field string `toml:"field"`
+	Type  string `toml:"type"`
+
+	root registry.Key
+	path string
+}
+
+// WinRegistry reports registry values and file versions for configuration
+// drift monitoring.
+type WinRegistry struct {
+	Values []*Value   `toml:"value"`
+	Files  []string   `toml:"files"`
+	Log    cua.Logger `toml:"-"`
+}
+
+var roots = map[string]registry.Key{
+	"HKLM": registry.LOCAL_MACHINE,
+	"HKCU": registry.CURRENT_USER,
+	"HKU":  registry.USERS,
+	"HKCR": registry.CLASSES_ROOT,
+	"HKCC": registry.CURRENT_CONFIG,
+}
+
+func (w *WinRegistry) Description() string {
+	return "Reports Windows registry values and file versions"
+}
+
+func (w *WinRegistry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *WinRegistry) Init() error {
+	for _, v := range w.Values {
+		root, path, err := splitKey(v.Key)
+		if err != nil {
+			return err
+		}
+		v.root = roots[root]
+		v.path = path
+		v.Key = strings.TrimSuffix(root+`\`+path, `\`)
+		if err := checkType(v.Type); err != nil {
+			return fmt.Errorf("value %q of %q: %w", v.Name, v.Key, err)
+		}
+		if v.Field == "" {
+			v.Field = v.Name
+		}
+		if v.Field == "" {
+			v.Field = "default"
+		}
+	}
+	return nil
+}
+
+func (w *WinRegistry) Gather(acc cua.Accumulator) error {
+	// the values are grouped by key, in the order of the configuration
+	var keys []string
+	values := make(map[string][]*Value)
+	for _, v := range w.Values {
+		if _, ok := values[v.Key]; !ok {
+			keys = append(keys, v.Key)
+		}
+		values[v.Key] = append(values[v.Key], v)
+	}
+	for _, key := range keys {
+		acc.AddError(w.gatherKey(key, values[key], acc))
+	}
+
+	for _, path := range w.Files {
+		acc.AddError(w.gatherFile(path, acc))
+	}
+	return nil
+}
+
+func (w *WinRegistry) gatherKey(key string, values []*Value, acc cua.Accumulator) error {
+	tags := map[string]string{"key": key}
+	k, err := registry.OpenKey(values[0].root, values[0].path, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		acc.AddFields("win_registry", map[string]interface{}{"exists": false}, tags)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", key, err)
+	}
+	defer k.Close()
+
+	fields := map[string]interface{}{"exists": true}
+	for _, v := range values {
+		raw, err := readValue(k, v.Name)
+		if errors.Is(err, registry.ErrNotExist) {
+			w.Log.Debugf("Value %q of %q does not exist", v.Name, key)
+			continue
+		}
+		if err != nil {
+			acc.AddError(fmt.Errorf("reading %q of %q: %w", v.Name, key, err))
+			continue
+		}
+		value, err := coerce(raw, v.Type)
+		if err != nil {
+			acc.AddError(fmt.Errorf("value %q of %q: %w", v.Name, key, err))
+			continue
+		}
+		fields[v.Field] = value
+	}
+	acc.AddFields("win_registry", fields, tags)
+	return nil
+}
+
+// readValue returns a registry value as a string, a list of strings, an
+// integer or binary data.
+func readValue(k registry.Key, name string) (interface{}, error) {
+	_, typ, err := k.GetValue(name, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case registry.SZ, registry.EXPAND_SZ:
+		s, _, err := k.GetStringValue(name)
+		if err != nil {
+			return nil, err
+		}
+		if typ == registry.EXPAND_SZ {
+			return registry.ExpandString(s)
+		}
+		return s, nil
+	case registry.MULTI_SZ:
+		s, _, err := k.GetStringsValue(name)
+		return s, err
+	case registry.DWORD, registry.QWORD:
+		i, _, err := k.GetIntegerValue(name)
+		return i, err
+	default:
+		n, _, err := k.GetValue(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		n, _, err = k.GetValue(name, buf)
+		return buf[:n], err
+	}
+}
+
+func (w *WinRegistry) gatherFile(path string, acc cua.Accumulator) error {
+	tags := map[string]string{"path": path}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		acc.AddFields("win_file", map[string]interface{}{"exists": false}, tags)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat %q: %w", path, err)
+	}
+
+	fields := map[string]interface{}{
+		"exists":        true,
+		"size_bytes":    info.Size(),
+		"modified_time": info.ModTime().UnixNano(),
+	}
+	if !info.IsDir() {
+		fileVer, productVer, err := fileVersion(path)
+		if err != nil {
+			w.Log.Debugf("Reading the version of %q: %v", path, err)
+		} else {
+			fields["file_version"] = fileVer
+			fields["product_version"] = productVer
+		}
+	}
+	acc.AddFields("win_file", fields, tags)
+	return nil
+}
+
+func init() {
+	inputs.Add("win_registry", func() cua.Input {
+		return &WinRegistry{}
+	})
+}
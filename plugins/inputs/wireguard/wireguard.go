@@ -2,7 +2,6 @@ package wireguard
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
@@ -28,6 +27,8 @@ var (
 type Wireguard struct {
 	Devices []string `toml:"devices"`
 
+	Log cua.Logger `toml:"-"`
+
 	client *wgctrl.Client
 }
 
@@ -83,7 +84,7 @@ func (wg *Wireguard) enumerateDevices() ([]*wgtypes.Device, error) {
 	for _, name := range wg.Devices {
 		dev, err := wg.client.Device(name)
 		if err != nil {
-			log.Printf("W! [inputs.wireguard] No Wireguard device found with name %s", name)
+			wg.Log.Warnf("No Wireguard device found with name %s", name)
 			continue
 		}
 
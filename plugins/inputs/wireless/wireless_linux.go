@@ -5,7 +5,6 @@ package wireless
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"strconv"
@@ -51,7 +50,7 @@ func (w *Wireless) Gather(acc cua.Accumulator) error {
 		return fmt.Errorf("wireless readfile (%s): %w", wirelessPath, err)
 	}
 
-	interfaces, err := loadWirelessTable(table)
+	interfaces, err := w.loadWirelessTable(table)
 	if err != nil {
 		return err
 	}
@@ -80,8 +79,8 @@ func (w *Wireless) Gather(acc cua.Accumulator) error {
 	return nil
 }
 
-func loadWirelessTable(table []byte) ([]*wirelessInterface, error) {
-	var w []*wirelessInterface
+func (w *Wireless) loadWirelessTable(table []byte) ([]*wirelessInterface, error) {
+	var interfaces []*wirelessInterface
 	lines := bytes.Split(table, newLineByte)
 
 	// iterate over interfaces
@@ -99,10 +98,10 @@ func loadWirelessTable(table []byte) ([]*wirelessInterface, error) {
 			values = append(values, v)
 		}
 		if len(values) != interfaceFieldLength {
-			log.Printf("E! [input.wireless] invalid length of interface values")
+			w.Log.Errorf("invalid length of interface values")
 			continue
 		}
-		w = append(w, &wirelessInterface{
+		interfaces = append(interfaces, &wirelessInterface{
 			Interface: strings.Trim(fields[0], ":"),
 			Status:    values[0],
 			Link:      values[1],
@@ -116,7 +115,7 @@ func loadWirelessTable(table []byte) ([]*wirelessInterface, error) {
 			Beacon:    values[9],
 		})
 	}
-	return w, nil
+	return interfaces, nil
 }
 
 // loadPath can be used to read path firstly from config
@@ -5,6 +5,7 @@ package wireless
 import (
 	"testing"
 
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,7 +43,7 @@ func TestLoadWirelessTable(t *testing.T) {
 			Beacon:    int64(0),
 		},
 	}
-	metrics, err := loadWirelessTable(testInput)
+	metrics, err := (&Wireless{Log: testutil.Logger{}}).loadWirelessTable(testInput)
 	if err != nil {
 		t.Fatal(err)
 	}
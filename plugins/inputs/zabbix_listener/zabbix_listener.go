@@ -0,0 +1,293 @@
+package zabbixlistener
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	tlsint "github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/zabbix"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/inputs"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+const (
+	defaultServiceAddress = ":10051"
+	defaultReadTimeout    = 10 * time.Second
+	defaultMaxPacketSize  = 64 * 1024 * 1024
+)
+
+const sampleConfig = `
+  ## Address and port to listen on for Zabbix sender and active agent
+  ## connections.
+  service_address = ":10051"
+
+  ## Maximum number of concurrent connections.
+  ## 0 (default) is unlimited.
+  # max_connections = 0
+
+  ## Read timeout for a request.
+  # read_timeout = "10s"
+
+  ## Maximum size of a (decompressed) request.
+  # max_packet_size = "64MiB"
+
+  ## Optional TLS configuration.
+  # tls_cert = "/opt/circonus/unified-agent/etc/cert.pem"
+  # tls_key  = "/opt/circonus/unified-agent/etc/key.pem"
+  ## Enables client authentication if set.
+  # tls_allowed_cacerts = ["/opt/circonus/unified-agent/etc/clientca.pem"]
+`
+
+// ZabbixListener accepts item values sent with the Zabbix sender protocol,
+// as used by zabbix_sender and active Zabbix agents.
+type ZabbixListener struct {
+	ServiceAddress string            `toml:"service_address"`
+	MaxConnections int               `toml:"max_connections"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	MaxPacketSize  internal.Size     `toml:"max_packet_size"`
+	tlsint.ServerConfig
+
+	Log cua.Logger `toml:"-"`
+
+	acc      cua.Accumulator
+	listener net.Listener
+
+	connections map[net.Conn]struct{}
+	connMu      sync.Mutex
+	wg          sync.WaitGroup
+
+	ItemsRecv   selfstat.Stat
+	ItemsFailed selfstat.Stat
+}
+
+func (*ZabbixListener) Description() string {
+	return "Accept item values using the Zabbix sender protocol"
+}
+
+func (*ZabbixListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*ZabbixListener) Gather(_ cua.Accumulator) error {
+	return nil
+}
+
+func (z *ZabbixListener) Start(acc cua.Accumulator) error {
+	z.acc = acc
+
+	if z.ReadTimeout.Duration == 0 {
+		z.ReadTimeout.Duration = defaultReadTimeout
+	}
+	if z.MaxPacketSize.Size == 0 {
+		z.MaxPacketSize.Size = defaultMaxPacketSize
+	}
+
+	tags := map[string]string{"address": z.ServiceAddress}
+	z.ItemsRecv = selfstat.Register("zabbix_listener", "items_received", tags)
+	z.ItemsFailed = selfstat.Register("zabbix_listener", "items_failed", tags)
+
+	tlsConf, err := z.ServerConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+
+	var l net.Listener
+	if tlsConf != nil {
+		l, err = tls.Listen("tcp", z.ServiceAddress, tlsConf)
+	} else {
+		l, err = net.Listen("tcp", z.ServiceAddress)
+	}
+	if err != nil {
+		return fmt.Errorf("listen (%s): %w", z.ServiceAddress, err)
+	}
+	z.listener = l
+	z.connections = make(map[net.Conn]struct{})
+
+	z.wg.Add(1)
+	go func() {
+		defer z.wg.Done()
+		z.listen()
+	}()
+
+	z.Log.Infof("Listening on %s", l.Addr().String())
+
+	return nil
+}
+
+func (z *ZabbixListener) Stop() {
+	if z.listener != nil {
+		z.listener.Close()
+	}
+
+	z.connMu.Lock()
+	for c := range z.connections {
+		c.Close()
+	}
+	z.connMu.Unlock()
+
+	z.wg.Wait()
+}
+
+func (z *ZabbixListener) listen() {
+	for {
+		c, err := z.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				z.Log.Error(err.Error())
+			}
+			return
+		}
+
+		z.connMu.Lock()
+		if z.MaxConnections > 0 && len(z.connections) >= z.MaxConnections {
+			z.connMu.Unlock()
+			c.Close()
+			continue
+		}
+		z.connections[c] = struct{}{}
+		z.connMu.Unlock()
+
+		z.wg.Add(1)
+		go func() {
+			defer z.wg.Done()
+			z.handle(c)
+		}()
+	}
+}
+
+// handle serves a single request; Zabbix senders open a new connection for
+// every request.
+func (z *ZabbixListener) handle(c net.Conn) {
+	defer func() {
+		z.connMu.Lock()
+		delete(z.connections, c)
+		z.connMu.Unlock()
+		c.Close()
+	}()
+
+	start := time.Now()
+	_ = c.SetDeadline(start.Add(z.ReadTimeout.Duration))
+
+	data, err := zabbix.ReadPacket(c, uint64(z.MaxPacketSize.Size))
+	if err != nil {
+		if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+			z.Log.Debugf("Reading from %s: %s", c.RemoteAddr(), err.Error())
+		}
+		return
+	}
+
+	var req zabbix.Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		z.respond(c, zabbix.Response{Response: "failed", Info: fmt.Sprintf("cannot parse request: %s", err)})
+		return
+	}
+
+	switch req.Request {
+	case "sender data", "agent data":
+	default:
+		z.respond(c, zabbix.Response{Response: "failed", Info: fmt.Sprintf("unsupported request %q", req.Request)})
+		return
+	}
+
+	var failed int
+	for _, item := range req.Data {
+		z.ItemsRecv.Incr(1)
+		if err := z.addItem(item, start); err != nil {
+			z.Log.Debugf("Item from %s: %s", c.RemoteAddr(), err.Error())
+			z.ItemsFailed.Incr(1)
+			failed++
+		}
+	}
+
+	z.respond(c, zabbix.Response{
+		Response: "success",
+		Info: fmt.Sprintf("processed: %d; failed: %d; total: %d; seconds spent: %f",
+			len(req.Data)-failed, failed, len(req.Data), time.Since(start).Seconds()),
+	})
+}
+
+func (z *ZabbixListener) respond(c net.Conn, resp zabbix.Response) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		z.Log.Errorf("Encoding response: %s", err.Error())
+		return
+	}
+	if err := zabbix.WritePacket(c, b); err != nil {
+		z.Log.Debugf("Writing to %s: %s", c.RemoteAddr(), err.Error())
+	}
+}
+
+// addItem converts an item to a metric named after the item key, the key
+// parameters are kept in the "key_params" tag.
+func (z *ZabbixListener) addItem(item zabbix.Item, now time.Time) error {
+	if item.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+
+	name, params, err := splitKey(item.Key)
+	if err != nil {
+		return err
+	}
+
+	tags := map[string]string{"host": item.Host}
+	if params != "" {
+		tags["key_params"] = params
+	}
+
+	fields := map[string]interface{}{"value": parseValue(string(item.Value))}
+
+	ts := now
+	if item.Clock != nil {
+		var ns int64
+		if item.NS != nil {
+			ns = *item.NS
+		}
+		ts = time.Unix(*item.Clock, ns)
+	}
+
+	z.acc.AddFields(name, fields, tags, ts)
+	return nil
+}
+
+// splitKey splits an item key such as "system.cpu.load[all,avg1]" into
+// its name and parameters.
+func splitKey(key string) (string, string, error) {
+	i := strings.IndexByte(key, '[')
+	if i < 0 {
+		if key == "" {
+			return "", "", fmt.Errorf("missing key")
+		}
+		return key, "", nil
+	}
+	if i == 0 || !strings.HasSuffix(key, "]") {
+		return "", "", fmt.Errorf("invalid key %q", key)
+	}
+	return key[:i], key[i+1 : len(key)-1], nil
+}
+
+func parseValue(v string) interface{} {
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+func init() {
+	inputs.Add("zabbix_listener", func() cua.Input {
+		return &ZabbixListener{
+			ServiceAddress: defaultServiceAddress,
+			ReadTimeout:    internal.Duration{Duration: defaultReadTimeout},
+			MaxPacketSize:  internal.Size{Size: defaultMaxPacketSize},
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package zabbixlistener
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/zabbix"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestListener(t *testing.T) (*ZabbixListener, *testutil.Accumulator) {
+	z := &ZabbixListener{
+		ServiceAddress: "127.0.0.1:0",
+		Log:            testutil.Logger{},
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, z.Start(acc))
+	return z, acc
+}
+
+func request(t *testing.T, z *ZabbixListener, body string) zabbix.Response {
+	c, err := net.Dial("tcp", z.listener.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, zabbix.WritePacket(c, []byte(body)))
+	data, err := zabbix.ReadPacket(c, zabbix.DefaultMaxPacketSize)
+	require.NoError(t, err)
+
+	var resp zabbix.Response
+	require.NoError(t, json.Unmarshal(data, &resp))
+	return resp
+}
+
+func TestSenderData(t *testing.T) {
+	z, acc := newTestListener(t)
+	defer z.Stop()
+
+	resp := request(t, z, `{
+		"request": "sender data",
+		"data": [
+			{"host": "web01", "key": "system.cpu.load[all,avg1]", "value": "0.25", "clock": 1600000000, "ns": 500},
+			{"host": "web01", "key": "app.status", "value": "running", "clock": 1600000000},
+			{"host": "", "key": "app.status", "value": "1"},
+			{"host": "web01", "key": "[broken", "value": "1"}
+		]
+	}`)
+	require.Equal(t, "success", resp.Response)
+	processed, failed, err := resp.Counts()
+	require.NoError(t, err)
+	require.Equal(t, 2, processed)
+	require.Equal(t, 2, failed)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("system.cpu.load",
+			map[string]string{"host": "web01", "key_params": "all,avg1"},
+			map[string]interface{}{"value": 0.25},
+			time.Unix(1600000000, 500)),
+		testutil.MustMetric("app.status",
+			map[string]string{"host": "web01"},
+			map[string]interface{}{"value": "running"},
+			time.Unix(1600000000, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetCUAMetrics())
+	require.Equal(t, int64(4), z.ItemsRecv.Get())
+	require.Equal(t, int64(2), z.ItemsFailed.Get())
+}
+
+func TestUnsupportedRequest(t *testing.T) {
+	z, acc := newTestListener(t)
+	defer z.Stop()
+
+	resp := request(t, z, `{"request": "active checks", "host": "web01"}`)
+	require.Equal(t, "failed", resp.Response)
+
+	resp = request(t, z, `not json`)
+	require.Equal(t, "failed", resp.Response)
+	require.Empty(t, acc.GetCUAMetrics())
+}
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		name   string
+		params string
+		err    bool
+	}{
+		{key: "agent.ping", name: "agent.ping"},
+		{key: "vfs.fs.size[/,free]", name: "vfs.fs.size", params: "/,free"},
+		{key: "net.if.in[]", name: "net.if.in"},
+		{key: "", err: true},
+		{key: "[x]", err: true},
+		{key: "a[b", err: true},
+	}
+
+	for _, tt := range tests {
+		name, params, err := splitKey(tt.key)
+		if tt.err {
+			require.Error(t, err, tt.key)
+			continue
+		}
+		require.NoError(t, err, tt.key)
+		require.Equal(t, tt.name, name)
+		require.Equal(t, tt.params, params)
+	}
+}
@@ -1,9 +1,20 @@
+// +build !custom
+
 package all
 
 //nolint:golint
 import (
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/azure_monitor"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/circonus"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/cloud_pubsub"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/cloudwatch"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/datadog"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/discard"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/file"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/health"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/heartbeat"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/mqtt"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/stackdriver"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/wavefront"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/outputs/zabbix"
 )
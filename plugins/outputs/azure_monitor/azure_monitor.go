@@ -0,0 +1,690 @@
+package azuremonitor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+const (
+	defaultRequestTimeout  = time.Second * 5
+	defaultNamespacePrefix = "CUA/"
+	defaultAuthResource    = "https://monitoring.azure.com/"
+
+	vmInstanceMetadataURL  = "http://169.254.169.254/metadata/instance?api-version=2017-12-01"
+	resourceIDTemplate     = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s"
+	vmssResourceIDTemplate = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachineScaleSets/%s"
+	urlTemplate            = "https://%s.monitoring.azure.com%s/metrics"
+	urlOverrideTemplate    = "%s%s/metrics"
+
+	// maxRequestBodySize is the limit of the uncompressed request body
+	// accepted by the custom metrics API.
+	maxRequestBodySize = 4000000
+	// maxDimensions is the limit of dimensions per custom metric.
+	maxDimensions = 10
+)
+
+// AzureMonitor allows publishing of metrics to the Azure Monitor custom
+// metrics service.
+type AzureMonitor struct {
+	Timeout             internal.Duration `toml:"timeout"`
+	NamespacePrefix     string            `toml:"namespace_prefix"`
+	StringsAsDimensions bool              `toml:"strings_as_dimensions"`
+	Region              string            `toml:"region"`
+	ResourceID          string            `toml:"resource_id"`
+	EndpointURL         string            `toml:"endpoint_url"`
+
+	// Service principal credentials, when not set credentials are read
+	// from the environment and fall back to managed identity.
+	TenantID     string `toml:"tenant_id"`
+	ClientID     string `toml:"client_id"`
+	ClientSecret string `toml:"client_secret"`
+	// MSIClientID selects a user assigned managed identity.
+	MSIClientID string `toml:"msi_client_id"`
+
+	Log cua.Logger `toml:"-"`
+
+	url    string
+	auth   autorest.Authorizer
+	client *http.Client
+
+	cache    map[time.Time]map[uint64]*aggregate
+	timeFunc func() time.Time
+
+	MetricOutsideWindow selfstat.Stat
+}
+
+type dimension struct {
+	name  string
+	value string
+}
+
+type aggregate struct {
+	name       string
+	min        float64
+	max        float64
+	sum        float64
+	count      int64
+	dimensions []dimension
+	updated    bool
+}
+
+var sampleConfig = `
+  ## Timeout for HTTP writes.
+  # timeout = "5s"
+
+  ## Set the namespace prefix, defaults to "CUA/<input-name>".
+  # namespace_prefix = "CUA/"
+
+  ## Azure Monitor doesn't have a string value type, so convert string
+  ## fields to dimensions (a.k.a. tags) if enabled. Azure Monitor allows
+  ## a maximum of 10 dimensions so if enabled, ensure you only send a
+  ## limited number of string fields.
+  # strings_as_dimensions = false
+
+  ## Both region and resource_id must be set or be available via the
+  ## Instance Metadata service on Azure Virtual Machines.
+  #
+  ## Azure Region to publish metrics against.
+  ##   ex: region = "southcentralus"
+  # region = ""
+  #
+  ## The Azure Resource ID against which metric will be logged, e.g.
+  ##   ex: resource_id = "/subscriptions/<subscription_id>/resourceGroups/<resource_group>/providers/Microsoft.Compute/virtualMachines/<vm_name>"
+  # resource_id = ""
+
+  ## Optionally, if in Azure US Government, China or other sovereign
+  ## cloud environment, set appropriate REST endpoint for receiving
+  ## metrics. (Note: region may be unused in this context)
+  # endpoint_url = "https://monitoring.core.usgovcloudapi.net"
+
+  ## Service principal credentials. When not set, credentials are read from
+  ## the AZURE_* environment variables, falling back to the managed identity
+  ## of the host.
+  # tenant_id = ""
+  # client_id = ""
+  # client_secret = ""
+
+  ## Client ID of a user assigned managed identity, the system assigned
+  ## identity is used if not set.
+  # msi_client_id = ""
+`
+
+// Connect initializes the plugin and validates connectivity
+func (a *AzureMonitor) Connect() error {
+	a.cache = make(map[time.Time]map[uint64]*aggregate, 36)
+
+	if a.Timeout.Duration == 0 {
+		a.Timeout.Duration = defaultRequestTimeout
+	}
+
+	a.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		},
+		Timeout: a.Timeout.Duration,
+	}
+
+	var err error
+	var region string
+	var resourceID string
+	var endpointURL string
+
+	if a.Region == "" || a.ResourceID == "" {
+		// Pull region and resource identifier
+		region, resourceID, err = vmInstanceMetadata(a.client)
+		if err != nil {
+			return err
+		}
+	}
+	if a.Region != "" {
+		region = a.Region
+	}
+	if a.ResourceID != "" {
+		resourceID = a.ResourceID
+	}
+	if a.EndpointURL != "" {
+		endpointURL = a.EndpointURL
+	}
+
+	if resourceID == "" {
+		return fmt.Errorf("no resource ID configured or available via VM instance metadata")
+	} else if region == "" {
+		return fmt.Errorf("no region configured or available via VM instance metadata")
+	}
+
+	if endpointURL == "" {
+		a.url = fmt.Sprintf(urlTemplate, region, resourceID)
+	} else {
+		a.url = fmt.Sprintf(urlOverrideTemplate, strings.TrimSuffix(endpointURL, "/"), resourceID)
+	}
+
+	a.Log.Debugf("Writing to Azure Monitor URL: %s", a.url)
+
+	a.auth, err = a.authorizer()
+	if err != nil {
+		return err
+	}
+
+	a.MetricOutsideWindow = selfstat.Register(
+		"azure_monitor",
+		"metric_outside_window",
+		map[string]string{
+			"region":      region,
+			"resource_id": resourceID,
+		},
+	)
+
+	a.Reset()
+
+	return nil
+}
+
+// authorizer selects the credentials in order of service principal from
+// the configuration, user assigned managed identity and finally the
+// environment, which includes the system assigned managed identity.
+func (a *AzureMonitor) authorizer() (autorest.Authorizer, error) {
+	var authorizer autorest.Authorizer
+	var err error
+
+	switch {
+	case a.ClientID != "" || a.ClientSecret != "" || a.TenantID != "":
+		if a.ClientID == "" || a.ClientSecret == "" || a.TenantID == "" {
+			return nil, fmt.Errorf("tenant_id, client_id and client_secret must all be set")
+		}
+		cfg := auth.NewClientCredentialsConfig(a.ClientID, a.ClientSecret, a.TenantID)
+		cfg.Resource = defaultAuthResource
+		authorizer, err = cfg.Authorizer()
+	case a.MSIClientID != "":
+		cfg := auth.NewMSIConfig()
+		cfg.Resource = defaultAuthResource
+		cfg.ClientID = a.MSIClientID
+		authorizer, err = cfg.Authorizer()
+	default:
+		authorizer, err = auth.NewAuthorizerFromEnvironmentWithResource(defaultAuthResource)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("authorizer: %w", err)
+	}
+	return authorizer, nil
+}
+
+// vmInstanceMetadata retrieves metadata about the current Azure VM
+func vmInstanceMetadata(c *http.Client) (string, string, error) {
+	req, err := http.NewRequest("GET", vmInstanceMetadataURL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("instance metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("instance metadata: %w", err)
+	}
+	if resp.StatusCode >= 300 || resp.StatusCode < 200 {
+		return "", "", fmt.Errorf("unable to fetch instance metadata: [%s] %d",
+			vmInstanceMetadataURL, resp.StatusCode)
+	}
+
+	var metadata virtualMachineMetadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return "", "", fmt.Errorf("instance metadata: %w", err)
+	}
+
+	region := metadata.Compute.Location
+	resourceID := metadata.ResourceID()
+
+	return region, resourceID, nil
+}
+
+// Description provides a description of the plugin
+func (a *AzureMonitor) Description() string {
+	return "Send aggregate metrics to Azure Monitor"
+}
+
+// SampleConfig provides a sample configuration for the plugin
+func (a *AzureMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+// Close shuts down an any active connections
+func (a *AzureMonitor) Close() error {
+	a.client = nil
+	return nil
+}
+
+type azureMonitorMetric struct {
+	Time time.Time         `json:"time"`
+	Data *azureMonitorData `json:"data"`
+}
+
+type azureMonitorData struct {
+	BaseData *azureMonitorBaseData `json:"baseData"`
+}
+
+type azureMonitorBaseData struct {
+	Metric         string                `json:"metric"`
+	Namespace      string                `json:"namespace"`
+	DimensionNames []string              `json:"dimNames"`
+	Series         []*azureMonitorSeries `json:"series"`
+}
+
+type azureMonitorSeries struct {
+	DimensionValues []string `json:"dimValues"`
+	Min             float64  `json:"min"`
+	Max             float64  `json:"max"`
+	Sum             float64  `json:"sum"`
+	Count           int64    `json:"count"`
+}
+
+// Write writes metrics to the remote endpoint
+func (a *AzureMonitor) Write(metrics []cua.Metric) (int, error) {
+	azmetrics := make(map[uint64]*azureMonitorMetric, len(metrics))
+	for _, m := range metrics {
+		id := hashIDWithTagKeysOnly(m)
+		if azm, ok := azmetrics[id]; !ok {
+			amm, err := translate(m, a.NamespacePrefix)
+			if err != nil {
+				a.Log.Errorf("Could not create azure metric for %q; discarding point", m.Name())
+				continue
+			}
+			azmetrics[id] = amm
+		} else {
+			amm, err := translate(m, a.NamespacePrefix)
+			if err != nil {
+				a.Log.Errorf("Could not create azure metric for %q; discarding point", m.Name())
+				continue
+			}
+
+			azmetrics[id].Data.BaseData.Series = append(
+				azm.Data.BaseData.Series,
+				amm.Data.BaseData.Series...,
+			)
+		}
+	}
+
+	if len(azmetrics) == 0 {
+		return len(metrics), nil
+	}
+
+	var body []byte
+	for _, m := range azmetrics {
+		// Azure Monitor accepts new batches of points in new-line delimited
+		// JSON, following RFC 4288 (see https://github.com/ndjson/ndjson-spec).
+		buf, err := json.Marshal(m)
+		if err != nil {
+			a.Log.Errorf("Could not marshall metric to JSON: %v", err)
+			continue
+		}
+		// Azure Monitor has a maximum request body size of 4MB. Send batches that
+		// exceed this size via separate write requests.
+		if (len(body) + len(buf) + 1) > maxRequestBodySize {
+			if err := a.send(body); err != nil {
+				return 0, err
+			}
+			body = nil
+		}
+		body = append(body, buf...)
+		body = append(body, '\n')
+	}
+
+	if err := a.send(body); err != nil {
+		return 0, err
+	}
+	return len(metrics), nil
+}
+
+func (a *AzureMonitor) send(body []byte) error {
+	var buf bytes.Buffer
+	g := gzip.NewWriter(&buf)
+	if _, err := g.Write(body); err != nil {
+		return fmt.Errorf("gzip: %w", err)
+	}
+	if err := g.Close(); err != nil {
+		return fmt.Errorf("gzip: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", a.url, &buf)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	// Add the authorization header. WithAuthorization will automatically
+	// refresh the token if needed.
+	req, err = autorest.CreatePreparer(a.auth.WithAuthorization()).Prepare(req)
+	if err != nil {
+		return fmt.Errorf("unable to fetch authentication credentials: %w", err)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	respbody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	// Requests the service rejected as invalid will never succeed, drop them
+	// rather than retrying forever.
+	if resp.StatusCode == http.StatusBadRequest {
+		a.Log.Errorf("Dropping metrics rejected by Azure Monitor: [%d] %s", resp.StatusCode, respbody)
+		return nil
+	}
+
+	return fmt.Errorf("failed to write batch: [%d] %s: %s", resp.StatusCode, resp.Status, respbody)
+}
+
+func hashIDWithTagKeysOnly(m cua.Metric) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(m.Name()))
+	h.Write([]byte("\n"))
+	for _, tag := range m.TagList() {
+		if tag.Key == "" || tag.Value == "" {
+			continue
+		}
+
+		h.Write([]byte(tag.Key))
+		h.Write([]byte("\n"))
+	}
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, uint64(m.Time().UnixNano()))
+	h.Write(b[:n])
+	h.Write([]byte("\n"))
+	return h.Sum64()
+}
+
+func translate(m cua.Metric, prefix string) (*azureMonitorMetric, error) {
+	dimensionNames := make([]string, 0, len(m.TagList()))
+	dimensionValues := make([]string, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		// Azure custom metrics service supports up to 10 dimensions
+		if len(dimensionNames) >= maxDimensions {
+			continue
+		}
+
+		if tag.Key == "" || tag.Value == "" {
+			continue
+		}
+
+		dimensionNames = append(dimensionNames, tag.Key)
+		dimensionValues = append(dimensionValues, tag.Value)
+	}
+
+	vmin, err := getFloatField(m, "min")
+	if err != nil {
+		return nil, err
+	}
+	vmax, err := getFloatField(m, "max")
+	if err != nil {
+		return nil, err
+	}
+	vsum, err := getFloatField(m, "sum")
+	if err != nil {
+		return nil, err
+	}
+	count, err := getIntField(m, "count")
+	if err != nil {
+		return nil, err
+	}
+
+	mn, ns := "Missing", "Missing"
+	names := strings.SplitN(m.Name(), "-", 2)
+	if len(names) > 1 {
+		mn = names[1]
+	}
+	if len(names) > 0 {
+		ns = names[0]
+	}
+	ns = prefix + ns
+
+	return &azureMonitorMetric{
+		Time: m.Time(),
+		Data: &azureMonitorData{
+			BaseData: &azureMonitorBaseData{
+				Metric:         mn,
+				Namespace:      ns,
+				DimensionNames: dimensionNames,
+				Series: []*azureMonitorSeries{
+					{
+						DimensionValues: dimensionValues,
+						Min:             vmin,
+						Max:             vmax,
+						Sum:             vsum,
+						Count:           count,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func getFloatField(m cua.Metric, key string) (float64, error) {
+	fv, ok := m.GetField(key)
+	if !ok {
+		return 0, fmt.Errorf("missing field: %s", key)
+	}
+
+	if value, ok := fv.(float64); ok {
+		return value, nil
+	}
+	return 0, fmt.Errorf("unexpected type: %s: %T", key, fv)
+}
+
+func getIntField(m cua.Metric, key string) (int64, error) {
+	fv, ok := m.GetField(key)
+	if !ok {
+		return 0, fmt.Errorf("missing field: %s", key)
+	}
+
+	if value, ok := fv.(int64); ok {
+		return value, nil
+	}
+	return 0, fmt.Errorf("unexpected type: %s: %T", key, fv)
+}
+
+// Add will append a metric to the output aggregate
+func (a *AzureMonitor) Add(m cua.Metric) {
+	// Azure Monitor only supports aggregates 30 minutes into the past and 4
+	// minutes into the future. Future metrics are dropped when pushed.
+	t := m.Time()
+	tbucket := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	if tbucket.Before(a.timeFunc().Add(-time.Minute * 30)) {
+		a.MetricOutsideWindow.Incr(1)
+		return
+	}
+
+	// Azure Monitor doesn't have a string value type, so convert string fields
+	// to dimensions (a.k.a. tags) if enabled.
+	if a.StringsAsDimensions {
+		for _, f := range m.FieldList() {
+			if v, ok := f.Value.(string); ok {
+				m.AddTag(f.Key, v)
+			}
+		}
+	}
+
+	for _, f := range m.FieldList() {
+		fv, ok := convert(f.Value)
+		if !ok {
+			continue
+		}
+
+		// Azure Monitor does not support fields so the field name is appended
+		// to the metric name, the measurement becomes the namespace.
+		name := strings.ReplaceAll(m.Name(), "-", "_") + "-" + f.Key
+		id := hashIDWithField(m.HashID(), f.Key)
+
+		_, ok = a.cache[tbucket]
+		if !ok {
+			// Time bucket does not exist and needs to be created.
+			a.cache[tbucket] = make(map[uint64]*aggregate)
+		}
+
+		// Fetch existing aggregate
+		var agg *aggregate
+		agg, ok = a.cache[tbucket][id]
+		if !ok {
+			dimensions := make([]dimension, 0, len(m.TagList()))
+			for _, tag := range m.TagList() {
+				dimensions = append(dimensions, dimension{
+					name:  tag.Key,
+					value: tag.Value,
+				})
+			}
+			sort.Slice(dimensions, func(i, j int) bool {
+				return dimensions[i].name < dimensions[j].name
+			})
+			if len(dimensions) > maxDimensions {
+				a.Log.Debugf("Metric %q has more than %d dimensions, extra dimensions are dropped", name, maxDimensions)
+			}
+			agg = &aggregate{
+				name:       name,
+				min:        fv,
+				max:        fv,
+				sum:        fv,
+				count:      1,
+				dimensions: dimensions,
+			}
+			a.cache[tbucket][id] = agg
+		} else {
+			if fv < agg.min {
+				agg.min = fv
+			}
+			if fv > agg.max {
+				agg.max = fv
+			}
+			agg.sum += fv
+			agg.count++
+		}
+		agg.updated = true
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+func hashIDWithField(id uint64, fk string) uint64 {
+	h := fnv.New64a()
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, id)
+	h.Write(b[:n])
+	h.Write([]byte("\n"))
+	h.Write([]byte(fk))
+	h.Write([]byte("\n"))
+	return h.Sum64()
+}
+
+// Push sends metrics to the output metric buffer
+func (a *AzureMonitor) Push() []cua.Metric {
+	var metrics []cua.Metric
+	for tbucket, aggs := range a.cache {
+		// Do not send metrics early
+		if tbucket.After(a.timeFunc().Add(-time.Minute)) {
+			continue
+		}
+		for _, agg := range aggs {
+			// Only send aggregates that have had an update since the last push.
+			if !agg.updated {
+				continue
+			}
+
+			tags := make(map[string]string, len(agg.dimensions))
+			for _, tag := range agg.dimensions {
+				tags[tag.name] = tag.value
+			}
+
+			m, err := metric.New(agg.name,
+				tags,
+				map[string]interface{}{
+					"min":   agg.min,
+					"max":   agg.max,
+					"sum":   agg.sum,
+					"count": agg.count,
+				},
+				tbucket,
+			)
+
+			if err != nil {
+				a.Log.Errorf("Could not create metric for aggregation %q; discarding point", agg.name)
+				continue
+			}
+
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+// Reset clears the cache of aggregate metrics
+func (a *AzureMonitor) Reset() {
+	for tbucket := range a.cache {
+		// Remove aggregates older than 30 minutes
+		if tbucket.Before(a.timeFunc().Add(-time.Minute * 30)) {
+			delete(a.cache, tbucket)
+			continue
+		}
+		// Metrics updated within the latest 1m have not been pushed and should
+		// not be cleared.
+		if tbucket.After(a.timeFunc().Add(-time.Minute * 1)) {
+			continue
+		}
+		for id := range a.cache[tbucket] {
+			a.cache[tbucket][id].updated = false
+		}
+	}
+}
+
+func init() {
+	outputs.Add("azure_monitor", func() cua.Output {
+		return &AzureMonitor{
+			NamespacePrefix: defaultNamespacePrefix,
+			Timeout:         internal.Duration{Duration: defaultRequestTimeout},
+			timeFunc:        time.Now,
+		}
+	})
+}
@@ -0,0 +1,448 @@
+package azuremonitor
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *AzureMonitor
+		metrics  []cua.Metric
+		addTime  time.Time
+		pushTime time.Time
+		check    func(t *testing.T, plugin *AzureMonitor, metrics []cua.Metric)
+	}{
+		{
+			name: "add metric outside window is dropped",
+			plugin: &AzureMonitor{
+				Region:     "test",
+				ResourceID: "/test",
+				Log:        testutil.Logger{},
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 42,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			addTime:  time.Unix(3600, 0),
+			pushTime: time.Unix(3600, 0),
+			check: func(t *testing.T, plugin *AzureMonitor, metrics []cua.Metric) {
+				require.Equal(t, int64(1), plugin.MetricOutsideWindow.Get())
+				require.Len(t, metrics, 0)
+			},
+		},
+		{
+			name: "metric not sent until period expires",
+			plugin: &AzureMonitor{
+				Region:     "test",
+				ResourceID: "/test",
+				Log:        testutil.Logger{},
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 42,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			addTime:  time.Unix(0, 0),
+			pushTime: time.Unix(0, 0),
+			check: func(t *testing.T, plugin *AzureMonitor, metrics []cua.Metric) {
+				require.Len(t, metrics, 0)
+			},
+		},
+		{
+			name: "add strings as dimensions",
+			plugin: &AzureMonitor{
+				Region:              "test",
+				ResourceID:          "/test",
+				StringsAsDimensions: true,
+				Log:                 testutil.Logger{},
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host": "localhost",
+					},
+					map[string]interface{}{
+						"value":   42,
+						"message": "howdy",
+					},
+					time.Unix(0, 0),
+				),
+			},
+			addTime:  time.Unix(0, 0),
+			pushTime: time.Unix(3600, 0),
+			check: func(t *testing.T, plugin *AzureMonitor, metrics []cua.Metric) {
+				expected := []cua.Metric{
+					testutil.MustMetric(
+						"cpu-value",
+						map[string]string{
+							"host":    "localhost",
+							"message": "howdy",
+						},
+						map[string]interface{}{
+							"min":   42.0,
+							"max":   42.0,
+							"sum":   42.0,
+							"count": 1,
+						},
+						time.Unix(0, 0),
+					),
+				}
+				testutil.RequireMetricsEqual(t, expected, metrics)
+			},
+		},
+		{
+			name: "add metric to cache and push",
+			plugin: &AzureMonitor{
+				Region:     "test",
+				ResourceID: "/test",
+				Log:        testutil.Logger{},
+				cache:      make(map[time.Time]map[uint64]*aggregate, 36),
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 42,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			addTime:  time.Unix(0, 0),
+			pushTime: time.Unix(3600, 0),
+			check: func(t *testing.T, plugin *AzureMonitor, metrics []cua.Metric) {
+				expected := []cua.Metric{
+					testutil.MustMetric(
+						"cpu-value",
+						map[string]string{},
+						map[string]interface{}{
+							"min":   42.0,
+							"max":   42.0,
+							"sum":   42.0,
+							"count": 1,
+						},
+						time.Unix(0, 0),
+					),
+				}
+
+				testutil.RequireMetricsEqual(t, expected, metrics)
+			},
+		},
+		{
+			name: "added metric are aggregated",
+			plugin: &AzureMonitor{
+				Region:     "test",
+				ResourceID: "/test",
+				Log:        testutil.Logger{},
+				cache:      make(map[time.Time]map[uint64]*aggregate, 36),
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 42,
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 84,
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 2,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			addTime:  time.Unix(0, 0),
+			pushTime: time.Unix(3600, 0),
+			check: func(t *testing.T, plugin *AzureMonitor, metrics []cua.Metric) {
+				expected := []cua.Metric{
+					testutil.MustMetric(
+						"cpu-value",
+						map[string]string{},
+						map[string]interface{}{
+							"min":   2.0,
+							"max":   84.0,
+							"sum":   128.0,
+							"count": 3,
+						},
+						time.Unix(0, 0),
+					),
+				}
+
+				testutil.RequireMetricsEqual(t, expected, metrics)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selfstat.Metrics() // reset stats between tests
+			err := tt.plugin.Connect()
+			require.NoError(t, err)
+
+			// Reset globals
+			tt.plugin.MetricOutsideWindow.Set(0)
+
+			tt.plugin.timeFunc = func() time.Time { return tt.addTime }
+			for _, m := range tt.metrics {
+				tt.plugin.Add(m)
+			}
+
+			tt.plugin.timeFunc = func() time.Time { return tt.pushTime }
+			metrics := tt.plugin.Push()
+			tt.plugin.Reset()
+
+			tt.check(t, tt.plugin, metrics)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	readBody := func(r *http.Request) ([]*azureMonitorMetric, error) {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(gz)
+
+		azmetrics := make([]*azureMonitorMetric, 0)
+		for scanner.Scan() {
+			line := scanner.Text()
+			var amm azureMonitorMetric
+			err = json.Unmarshal([]byte(line), &amm)
+			if err != nil {
+				return nil, err
+			}
+			azmetrics = append(azmetrics, &amm)
+		}
+
+		return azmetrics, nil
+	}
+
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		plugin  *AzureMonitor
+		metrics []cua.Metric
+		handler func(t *testing.T, w http.ResponseWriter, r *http.Request)
+		errFunc func(t *testing.T, err error)
+	}{
+		{
+			name: "if not an azure metric nothing is sent",
+			plugin: &AzureMonitor{
+				Region:     "test",
+				ResourceID: "/test",
+				Log:        testutil.Logger{},
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 42,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				t.Fatal("should not call")
+			},
+		},
+		{
+			name: "single azure metric",
+			plugin: &AzureMonitor{
+				Region:          "test",
+				ResourceID:      "/test",
+				NamespacePrefix: defaultNamespacePrefix,
+				Log:             testutil.Logger{},
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu-value",
+					map[string]string{
+						"host": "localhost",
+					},
+					map[string]interface{}{
+						"min":   float64(42),
+						"max":   float64(42),
+						"sum":   float64(42),
+						"count": int64(1),
+					},
+					time.Unix(0, 0),
+				),
+			},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/test/metrics", r.URL.Path)
+				require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+				azmetrics, err := readBody(r)
+				require.NoError(t, err)
+				require.Len(t, azmetrics, 1)
+				base := azmetrics[0].Data.BaseData
+				require.Equal(t, "CUA/cpu", base.Namespace)
+				require.Equal(t, "value", base.Metric)
+				require.Equal(t, []string{"host"}, base.DimensionNames)
+				require.Equal(t, []string{"localhost"}, base.Series[0].DimensionValues)
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			name: "multiple azure metric",
+			plugin: &AzureMonitor{
+				Region:     "test",
+				ResourceID: "/test",
+				Log:        testutil.Logger{},
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu-value",
+					map[string]string{},
+					map[string]interface{}{
+						"min":   float64(42),
+						"max":   float64(42),
+						"sum":   float64(42),
+						"count": int64(1),
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"cpu-value",
+					map[string]string{},
+					map[string]interface{}{
+						"min":   float64(42),
+						"max":   float64(42),
+						"sum":   float64(42),
+						"count": int64(1),
+					},
+					time.Unix(60, 0),
+				),
+			},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				azmetrics, err := readBody(r)
+				require.NoError(t, err)
+				require.Len(t, azmetrics, 2)
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			name: "rejected batch is dropped",
+			plugin: &AzureMonitor{
+				Region:     "test",
+				ResourceID: "/test",
+				Log:        testutil.Logger{},
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu-value",
+					map[string]string{},
+					map[string]interface{}{
+						"min":   float64(42),
+						"max":   float64(42),
+						"sum":   float64(42),
+						"count": int64(1),
+					},
+					time.Unix(0, 0),
+				),
+			},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+		},
+		{
+			name: "server error is retried",
+			plugin: &AzureMonitor{
+				Region:     "test",
+				ResourceID: "/test",
+				Log:        testutil.Logger{},
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"cpu-value",
+					map[string]string{},
+					map[string]interface{}{
+						"min":   float64(42),
+						"max":   float64(42),
+						"sum":   float64(42),
+						"count": int64(1),
+					},
+					time.Unix(0, 0),
+				),
+			},
+			handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			errFunc: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(t, w, r)
+			})
+
+			tt.plugin.EndpointURL = ts.URL
+			err := tt.plugin.Connect()
+			require.NoError(t, err)
+
+			// override real authorizer and write url
+			tt.plugin.auth = autorest.NullAuthorizer{}
+
+			_, err = tt.plugin.Write(tt.metrics)
+			if tt.errFunc == nil {
+				require.NoError(t, err)
+			} else {
+				tt.errFunc(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthorizerServicePrincipal(t *testing.T) {
+	a := &AzureMonitor{ClientID: "id"}
+	_, err := a.authorizer()
+	require.Error(t, err)
+
+	a = &AzureMonitor{ClientID: "id", ClientSecret: "secret", TenantID: "tenant"}
+	authorizer, err := a.authorizer()
+	require.NoError(t, err)
+	require.NotNil(t, authorizer)
+}
@@ -0,0 +1,317 @@
+package circonus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	apiclient "github.com/circonus-labs/go-apiclient"
+)
+
+// brokerAPI is the part of the Circonus API used for the broker TLS.
+type brokerAPI interface {
+	Get(reqPath string) ([]byte, error)
+	FetchBrokers() (*[]apiclient.Broker, error)
+}
+
+// brokerTLS is the TLS configuration of the submissions to the brokers.  The
+// CA, the client certificate and the names of the brokers are read by the
+// callbacks of the configuration, so that they are rotated by reload without
+// a restart.
+type brokerTLS struct {
+	api       brokerAPI
+	caFile    string
+	cacheFile string
+	certFile  string
+	keyFile   string
+	pins      map[string]bool
+	log       cua.Logger
+
+	mu    sync.RWMutex
+	roots *x509.CertPool
+	cert  *tls.Certificate
+	// CNs of the brokers by IP address and external host, the submission
+	// URLs of the enterprise brokers use an address absent from their
+	// certificates, the CN of the broker is checked instead
+	names map[string]string
+}
+
+func newBrokerTLS(api brokerAPI, caFile, cacheFile, certFile, keyFile string, pins []string, log cua.Logger) (*brokerTLS, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("broker_tls_cert and broker_tls_key must be set together")
+	}
+	b := &brokerTLS{
+		api:       api,
+		caFile:    caFile,
+		cacheFile: cacheFile,
+		certFile:  certFile,
+		keyFile:   keyFile,
+		pins:      make(map[string]bool, len(pins)),
+		log:       log,
+	}
+	for _, pin := range pins {
+		if d, err := base64.StdEncoding.DecodeString(pin); err != nil || len(d) != sha256.Size {
+			return nil, fmt.Errorf("invalid broker pin %q, must be a base64 encoded sha256 digest", pin)
+		}
+		b.pins[pin] = true
+	}
+	return b, nil
+}
+
+// config returns the TLS configuration of the submissions.  The verification
+// of the broker certificates is done by verify, as the name to check depends
+// on the broker.
+func (b *brokerTLS) config() *tls.Config {
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		InsecureSkipVerify:   true, //nolint:gosec // verified by VerifyConnection
+		VerifyConnection:     b.verify,
+		GetClientCertificate: b.clientCertificate,
+	}
+}
+
+// load reads the CA, the client certificate and the names of the brokers.
+// The material that cannot be read is kept from the previous load, an error
+// is returned when there is none.
+func (b *brokerTLS) load() error {
+	var errs []string
+
+	if roots, err := b.loadCA(); err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		b.mu.Lock()
+		b.roots = roots
+		b.mu.Unlock()
+	}
+
+	if b.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(b.certFile, b.keyFile)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("loading broker client certificate (%s): %s", b.certFile, err))
+		} else {
+			b.mu.Lock()
+			b.cert = &cert
+			b.mu.Unlock()
+		}
+	}
+
+	if names, err := b.fetchNames(); err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		b.mu.Lock()
+		b.names = names
+		b.mu.Unlock()
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("loading broker tls: %s", errs)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.roots == nil || (b.certFile != "" && b.cert == nil) {
+		return err
+	}
+	b.log.Warnf("%s, keeping the previous material", err)
+	return nil
+}
+
+// loadCA returns the broker CA read from broker_ca_file, or fetched from the
+// API and cached in broker_ca_cache_file, the cached CA is used when the API
+// is unavailable.
+func (b *brokerTLS) loadCA() (*x509.CertPool, error) {
+	if b.caFile != "" {
+		pem, err := os.ReadFile(b.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load broker ca file (%s): %w", b.caFile, err)
+		}
+		return parseCA(pem, b.caFile)
+	}
+
+	pem, err := b.fetchCA()
+	if err == nil {
+		pool, err := parseCA(pem, "api")
+		if err != nil {
+			return nil, err
+		}
+		if b.cacheFile != "" {
+			b.cacheCA(pem)
+		}
+		return pool, nil
+	}
+	if b.cacheFile == "" {
+		return nil, err
+	}
+
+	b.log.Warnf("%s, using cached broker ca (%s)", err, b.cacheFile)
+	pem, cerr := os.ReadFile(b.cacheFile)
+	if cerr != nil {
+		return nil, fmt.Errorf("unable to load cached broker ca (%s): %w", b.cacheFile, cerr)
+	}
+	return parseCA(pem, b.cacheFile)
+}
+
+func (b *brokerTLS) fetchCA() ([]byte, error) {
+	data, err := b.api.Get("/pki/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("fetching broker ca: %w", err)
+	}
+	var ca struct {
+		Contents string `json:"contents"`
+	}
+	if err := json.Unmarshal(data, &ca); err != nil {
+		return nil, fmt.Errorf("parsing broker ca response: %w", err)
+	}
+	if ca.Contents == "" {
+		return nil, fmt.Errorf("no broker ca in api response")
+	}
+	return []byte(ca.Contents), nil
+}
+
+// cacheCA writes the fetched CA to the cache file when it changed, through a
+// temporary file so that a partial CA is never read.
+func (b *brokerTLS) cacheCA(pem []byte) {
+	if cached, err := os.ReadFile(b.cacheFile); err == nil && bytes.Equal(cached, pem) {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.cacheFile), filepath.Base(b.cacheFile)+".*")
+	if err != nil {
+		b.log.Warnf("unable to cache broker ca (%s): %s", b.cacheFile, err)
+		return
+	}
+	_, err = tmp.Write(pem)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), b.cacheFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		b.log.Warnf("unable to cache broker ca (%s): %s", b.cacheFile, err)
+	}
+}
+
+func parseCA(pem []byte, source string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("unable to parse broker ca (%s)", source)
+	}
+	return pool, nil
+}
+
+// fetchNames returns the CNs of the brokers by IP address and external host.
+func (b *brokerTLS) fetchNames() (map[string]string, error) {
+	brokers, err := b.api.FetchBrokers()
+	if err != nil {
+		return nil, fmt.Errorf("fetching brokers: %w", err)
+	}
+	names := make(map[string]string)
+	for _, broker := range *brokers {
+		for _, d := range broker.Details {
+			if d.IP != nil && *d.IP != "" {
+				names[*d.IP] = d.CN
+			}
+			if d.ExternalHost != nil && *d.ExternalHost != "" {
+				names[*d.ExternalHost] = d.CN
+			}
+		}
+	}
+	return names, nil
+}
+
+func (b *brokerTLS) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.cert == nil {
+		// no client certificate is sent
+		return &tls.Certificate{}, nil
+	}
+	return b.cert, nil
+}
+
+// verify verifies the certificate of a broker: it must be issued by the
+// broker CA to a broker, or be a public certificate of the host, and match
+// one of the pins when any.
+func (b *brokerTLS) verify(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("no broker certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	b.mu.RLock()
+	roots := b.roots
+	names := b.names
+	b.mu.RUnlock()
+
+	chains, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+	if err == nil {
+		err = verifyName(leaf, cs.ServerName, names)
+	}
+	if err != nil {
+		if cs.ServerName == "" {
+			return fmt.Errorf("verifying broker certificate: %w", err)
+		}
+		// the public brokers use certificates of the system roots
+		var serr error
+		chains, serr = leaf.Verify(x509.VerifyOptions{DNSName: cs.ServerName, Intermediates: intermediates})
+		if serr != nil {
+			return fmt.Errorf("verifying broker certificate: %w", err)
+		}
+	}
+
+	if len(b.pins) == 0 {
+		return nil
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if b.pins[pin(cert)] {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("broker certificate of %q does not match any pin", leaf.Subject.CommonName)
+}
+
+// verifyName checks that a certificate is issued to the broker of the server
+// name, or to any broker without server name, as the IP addresses of the
+// submission URLs are not sent as server name.
+func verifyName(leaf *x509.Certificate, serverName string, names map[string]string) error {
+	if serverName == "" {
+		for _, cn := range names {
+			if leaf.Subject.CommonName == cn {
+				return nil
+			}
+		}
+		return fmt.Errorf("broker certificate issued to %q, not to a known broker", leaf.Subject.CommonName)
+	}
+
+	name, ok := names[serverName]
+	if !ok {
+		name = serverName
+	}
+	if leaf.Subject.CommonName == name || leaf.VerifyHostname(name) == nil {
+		return nil
+	}
+	return fmt.Errorf("invalid broker certificate name %q, expected %q", leaf.Subject.CommonName, name)
+}
+
+// pin returns the base64 encoded sha256 digest of the public key of a
+// certificate, the pin-sha256 of HTTP public key pinning.
+func pin(cert *x509.Certificate) string {
+	d := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(d[:])
+}
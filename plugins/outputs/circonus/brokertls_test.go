@@ -0,0 +1,265 @@
+package circonus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+var serial int64
+
+// newTestCert returns a certificate issued to cn by the parent, self-signed
+// without parent.
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	issuer, signer := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, &key.PublicKey, signer)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+// fakeAPI answers the broker CA and the brokers.
+type fakeAPI struct {
+	ca      []byte
+	brokers []apiclient.Broker
+	err     error
+}
+
+func (a *fakeAPI) Get(reqPath string) ([]byte, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	if reqPath != "/pki/ca.crt" {
+		return nil, fmt.Errorf("unexpected path %s", reqPath)
+	}
+	return json.Marshal(map[string]string{"contents": string(a.ca)})
+}
+
+func (a *fakeAPI) FetchBrokers() (*[]apiclient.Broker, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	return &a.brokers, nil
+}
+
+func newFakeAPI(ca *testCert, cn string) *fakeAPI {
+	ip := "127.0.0.1"
+	return &fakeAPI{
+		ca:      ca.certPEM,
+		brokers: []apiclient.Broker{{CID: "/broker/1", Details: []apiclient.BrokerDetail{{CN: cn, IP: &ip}}}},
+	}
+}
+
+// newBroker returns a TLS server with a certificate issued to cn by the CA,
+// requiring a client certificate of the CA with clientAuth.
+func newBroker(t *testing.T, ca *testCert, cn string, clientAuth bool, clientCNs chan<- string) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if clientCNs != nil {
+			clientCNs <- r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+	}))
+	cfg := &tls.Config{Certificates: []tls.Certificate{newTestCert(t, cn, ca).tlsCertificate(t)}}
+	if clientAuth {
+		pool := x509.NewCertPool()
+		pool.AddCert(ca.cert)
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	ts.TLS = cfg
+	ts.StartTLS()
+	return ts
+}
+
+func submit(b *brokerTLS, u string) error {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: b.config()}}
+	resp, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestBrokerTLSFetchAndCache(t *testing.T) {
+	ca := newTestCert(t, "Broker CA", nil)
+	ts := newBroker(t, ca, "broker1.example.com", false, nil)
+	defer ts.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "broker_ca.pem")
+	api := newFakeAPI(ca, "broker1.example.com")
+	b, err := newBrokerTLS(api, "", cacheFile, "", "", nil, testutil.Logger{})
+	require.NoError(t, err)
+	require.NoError(t, b.load())
+	require.NoError(t, submit(b, ts.URL))
+
+	cached, err := os.ReadFile(cacheFile)
+	require.NoError(t, err)
+	require.Equal(t, ca.certPEM, cached)
+
+	// the cached CA is used when the API is unavailable
+	api.err = fmt.Errorf("api unavailable")
+	b, err = newBrokerTLS(api, "", cacheFile, "", "", nil, testutil.Logger{})
+	require.NoError(t, err)
+	require.NoError(t, b.load())
+	b.names = map[string]string{"127.0.0.1": "broker1.example.com"}
+	require.NoError(t, submit(b, ts.URL))
+
+	// without cache nor API there is no CA
+	b, err = newBrokerTLS(api, "", "", "", "", nil, testutil.Logger{})
+	require.NoError(t, err)
+	require.Error(t, b.load())
+}
+
+func TestBrokerTLSVerify(t *testing.T) {
+	ca := newTestCert(t, "Broker CA", nil)
+	ts := newBroker(t, ca, "broker1.example.com", false, nil)
+	defer ts.Close()
+
+	// the CN of the certificate must be the one of the broker of the address
+	b, err := newBrokerTLS(newFakeAPI(ca, "broker2.example.com"), "", "", "", "", nil, testutil.Logger{})
+	require.NoError(t, err)
+	require.NoError(t, b.load())
+	require.Error(t, submit(b, ts.URL))
+
+	// the certificate must be issued by the broker CA
+	other := newTestCert(t, "Other CA", nil)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, other.certPEM, 0600))
+	b, err = newBrokerTLS(newFakeAPI(ca, "broker1.example.com"), caFile, "", "", "", nil, testutil.Logger{})
+	require.NoError(t, err)
+	require.NoError(t, b.load())
+	require.Error(t, submit(b, ts.URL))
+}
+
+func TestBrokerTLSPins(t *testing.T) {
+	ca := newTestCert(t, "Broker CA", nil)
+	ts := newBroker(t, ca, "broker1.example.com", false, nil)
+	defer ts.Close()
+	api := newFakeAPI(ca, "broker1.example.com")
+
+	_, err := newBrokerTLS(api, "", "", "", "", []string{"not a pin"}, testutil.Logger{})
+	require.Error(t, err)
+
+	b, err := newBrokerTLS(api, "", "", "", "", []string{pin(newTestCert(t, "Other CA", nil).cert)}, testutil.Logger{})
+	require.NoError(t, err)
+	require.NoError(t, b.load())
+	require.Error(t, submit(b, ts.URL))
+
+	// the pin of the CA matches the chain of the broker certificate
+	b, err = newBrokerTLS(api, "", "", "", "", []string{pin(ca.cert)}, testutil.Logger{})
+	require.NoError(t, err)
+	require.NoError(t, b.load())
+	require.NoError(t, submit(b, ts.URL))
+}
+
+func TestBrokerTLSClientCertificateRotation(t *testing.T) {
+	ca := newTestCert(t, "Broker CA", nil)
+	clientCNs := make(chan string, 1)
+	ts := newBroker(t, ca, "broker1.example.com", true, clientCNs)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	writeClient := func(cn string) {
+		c := newTestCert(t, cn, ca)
+		require.NoError(t, os.WriteFile(certFile, c.certPEM, 0600))
+		require.NoError(t, os.WriteFile(keyFile, c.keyPEM, 0600))
+	}
+
+	_, err := newBrokerTLS(newFakeAPI(ca, "broker1.example.com"), "", "", certFile, "", nil, testutil.Logger{})
+	require.Error(t, err)
+
+	b, err := newBrokerTLS(newFakeAPI(ca, "broker1.example.com"), "", "", certFile, keyFile, nil, testutil.Logger{})
+	require.NoError(t, err)
+	// the client certificate is required
+	require.Error(t, b.load())
+
+	writeClient("agent-1")
+	require.NoError(t, b.load())
+	require.NoError(t, submit(b, ts.URL))
+	require.Equal(t, "agent-1", <-clientCNs)
+
+	// the rotated certificate is used after the reload, the previous one is
+	// kept when the new one cannot be read
+	writeClient("agent-2")
+	require.NoError(t, b.load())
+	require.NoError(t, os.Remove(keyFile))
+	require.NoError(t, b.load())
+	require.NoError(t, submit(b, ts.URL))
+	require.Equal(t, "agent-2", <-clientCNs)
+}
+
+func TestVerifyName(t *testing.T) {
+	ca := newTestCert(t, "Broker CA", nil)
+	leaf := newTestCert(t, "broker1.example.com", ca).cert
+	names := map[string]string{
+		"10.0.0.1":            "broker1.example.com",
+		"trap.example.com":    "broker1.example.com",
+		"10.0.0.2":            "broker2.example.com",
+		"broker3.example.com": "broker3.example.com",
+	}
+
+	require.NoError(t, verifyName(leaf, "", names))
+	require.NoError(t, verifyName(leaf, "trap.example.com", names))
+	require.NoError(t, verifyName(leaf, "broker1.example.com", names))
+	require.Error(t, verifyName(leaf, "broker3.example.com", names))
+	require.Error(t, verifyName(leaf, "", map[string]string{"10.0.0.2": "broker2.example.com"}))
+}
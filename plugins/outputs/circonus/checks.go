@@ -0,0 +1,132 @@
+package circonus
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+)
+
+// defaultDisplayName is the default check_display_name template.
+const defaultDisplayName = "{{.Prefix}} {{.Name}} ({{.OS}})"
+
+// checkAPI is the part of the Circonus API used to look up the checks.
+type checkAPI interface {
+	SearchCheckBundles(searchCriteria *apiclient.SearchQueryType, filterCriteria *apiclient.SearchFilterType) (*[]apiclient.CheckBundle, error)
+}
+
+// checkInfo describes a check, it is the data of the check_display_name and
+// check_tags templates.
+type checkInfo struct {
+	// Prefix is the check_name_prefix
+	Prefix string
+	// Name is "default", "host", "agent", or the plugin and its instance ID,
+	// followed by the tag of check_tag_key
+	Name       string
+	Plugin     string
+	InstanceID string
+	TagKey     string
+	TagValue   string
+	OS         string
+
+	// typeID identifies the check type, shared by the checks of a plugin
+	// split by tag
+	typeID string
+}
+
+// compileCheckTemplates parses check_display_name and check_tags.
+func (c *Circonus) compileCheckTemplates() error {
+	if c.CheckDisplayName == "" {
+		c.CheckDisplayName = defaultDisplayName
+	}
+	var err error
+	c.displayNameTmpl, err = template.New("check_display_name").Parse(c.CheckDisplayName)
+	if err != nil {
+		return fmt.Errorf("check_display_name: %w", err)
+	}
+	c.checkTagsTmpl = make([]*template.Template, 0, len(c.CheckTags))
+	for _, tag := range c.CheckTags {
+		tmpl, err := template.New("check_tags").Parse(tag)
+		if err != nil {
+			return fmt.Errorf("check_tags %q: %w", tag, err)
+		}
+		c.checkTagsTmpl = append(c.checkTagsTmpl, tmpl)
+	}
+	return nil
+}
+
+func render(tmpl *template.Template, info checkInfo) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, info); err != nil {
+		return "", fmt.Errorf("rendering %s: %w", tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// checkTags returns the tags added to a check when it is created, the empty
+// tags are skipped.
+func (c *Circonus) checkTags(info checkInfo) (string, error) {
+	tags := make([]string, 0, len(c.checkTagsTmpl))
+	for _, tmpl := range c.checkTagsTmpl {
+		tag, err := render(tmpl, info)
+		if err != nil {
+			return "", err
+		}
+		if tag = strings.TrimSpace(tag); tag != "" && !strings.HasSuffix(tag, ":") {
+			tags = append(tags, tag)
+		}
+	}
+	return strings.Join(tags, ","), nil
+}
+
+// pluginCheck returns the id of the check of a plugin instance, split by
+// the value of the check_tag_key tag when not empty, and its description.
+func (c *Circonus) pluginCheck(plugin, instanceID, tagValue string) (string, checkInfo) {
+	id := plugin
+	if instanceID != "" {
+		id += ":" + instanceID
+	}
+	info := checkInfo{
+		Name:       plugin + " " + instanceID,
+		Plugin:     plugin,
+		InstanceID: instanceID,
+		typeID:     id,
+	}
+	if c.OneCheck || plugin == "" {
+		id = "*"
+		info = checkInfo{Name: "default", typeID: "default"}
+	}
+	if tagValue != "" {
+		id += "|" + c.CheckTagKey + "=" + tagValue
+		info.Name += " " + c.CheckTagKey + ":" + tagValue
+		info.TagKey = c.CheckTagKey
+		info.TagValue = tagValue
+	}
+	return id, info
+}
+
+// findCheck returns the ID of the active httptrap check with the display
+// name, or "" when there is none.
+func (c *Circonus) findCheck(displayName string) (string, error) {
+	search := apiclient.SearchQueryType("(active:1)")
+	filter := apiclient.SearchFilterType{"f_display_name": {displayName}}
+	bundles, err := c.checkAPI.SearchCheckBundles(&search, &filter)
+	if err != nil {
+		return "", fmt.Errorf("searching check %q: %w", displayName, err)
+	}
+
+	var found []apiclient.CheckBundle
+	for _, b := range *bundles {
+		if b.DisplayName == displayName && strings.HasPrefix(b.Type, "httptrap") && len(b.Checks) > 0 {
+			found = append(found, b)
+		}
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	if len(found) > 1 {
+		c.Log.Warnf("%d checks named %q, using %s", len(found), displayName, found[0].CID)
+	}
+	return strings.TrimPrefix(found[0].Checks[0], "/check/"), nil
+}
@@ -0,0 +1,155 @@
+package circonus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		oneCheck   bool
+		plugin     string
+		instanceID string
+		tagValue   string
+		id         string
+		info       checkInfo
+	}{
+		{
+			name:       "plugin instance",
+			plugin:     "redis",
+			instanceID: "cache",
+			id:         "redis:cache",
+			info:       checkInfo{Name: "redis cache", Plugin: "redis", InstanceID: "cache", typeID: "redis:cache"},
+		},
+		{
+			name:     "plugin instance by tag",
+			plugin:   "statsd",
+			tagValue: "billing",
+			id:       "statsd|service=billing",
+			info: checkInfo{Name: "statsd  service:billing", Plugin: "statsd", TagKey: "service", TagValue: "billing",
+				typeID: "statsd"},
+		},
+		{
+			name:     "one check by tag",
+			oneCheck: true,
+			plugin:   "statsd",
+			tagValue: "billing",
+			id:       "*|service=billing",
+			info:     checkInfo{Name: "default service:billing", TagKey: "service", TagValue: "billing", typeID: "default"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Circonus{OneCheck: tt.oneCheck, CheckTagKey: "service"}
+			id, info := c.pluginCheck(tt.plugin, tt.instanceID, tt.tagValue)
+			require.Equal(t, tt.id, id)
+			require.Equal(t, tt.info, info)
+		})
+	}
+}
+
+func TestCheckTemplates(t *testing.T) {
+	c := &Circonus{}
+	require.NoError(t, c.compileCheckTemplates())
+	info := checkInfo{Prefix: "web01", Name: "redis cache", Plugin: "redis", InstanceID: "cache", OS: "linux"}
+	name, err := render(c.displayNameTmpl, info)
+	require.NoError(t, err)
+	require.Equal(t, "web01 redis cache (linux)", name)
+	tags, err := c.checkTags(info)
+	require.NoError(t, err)
+	require.Equal(t, "", tags)
+
+	c = &Circonus{
+		CheckDisplayName: "{{.Prefix}} {{.Plugin}}{{with .TagValue}} {{.}}{{end}}",
+		CheckTags:        []string{"plugin:{{.Plugin}}", "{{.TagKey}}:{{.TagValue}}", "team:ops"},
+	}
+	require.NoError(t, c.compileCheckTemplates())
+	name, err = render(c.displayNameTmpl, info)
+	require.NoError(t, err)
+	require.Equal(t, "web01 redis", name)
+	// the tags without value are skipped
+	tags, err = c.checkTags(info)
+	require.NoError(t, err)
+	require.Equal(t, "plugin:redis,team:ops", tags)
+
+	info.TagKey, info.TagValue = "service", "billing"
+	name, err = render(c.displayNameTmpl, info)
+	require.NoError(t, err)
+	require.Equal(t, "web01 redis billing", name)
+	tags, err = c.checkTags(info)
+	require.NoError(t, err)
+	require.Equal(t, "plugin:redis,service:billing,team:ops", tags)
+
+	require.Error(t, (&Circonus{CheckDisplayName: "{{.Prefix"}).compileCheckTemplates())
+	require.Error(t, (&Circonus{CheckTags: []string{"{{"}}).compileCheckTemplates())
+	c = &Circonus{CheckDisplayName: "{{.Unknown}}"}
+	require.NoError(t, c.compileCheckTemplates())
+	_, err = render(c.displayNameTmpl, info)
+	require.Error(t, err)
+}
+
+type fakeCheckAPI struct {
+	bundles []apiclient.CheckBundle
+	filter  apiclient.SearchFilterType
+	err     error
+}
+
+func (a *fakeCheckAPI) SearchCheckBundles(_ *apiclient.SearchQueryType, filter *apiclient.SearchFilterType) (*[]apiclient.CheckBundle, error) {
+	a.filter = *filter
+	return &a.bundles, a.err
+}
+
+func TestFindCheck(t *testing.T) {
+	api := &fakeCheckAPI{
+		bundles: []apiclient.CheckBundle{
+			{CID: "/check_bundle/1", DisplayName: "web01 redis", Type: "json", Checks: []string{"/check/11"}},
+			{CID: "/check_bundle/2", DisplayName: "web01 redis", Type: "httptrap:cua:redis:linux", Checks: []string{"/check/12"}},
+			{CID: "/check_bundle/3", DisplayName: "web01 redis", Type: "httptrap", Checks: []string{"/check/13"}},
+		},
+	}
+	c := &Circonus{checkAPI: api, Log: testutil.Logger{}}
+
+	id, err := c.findCheck("web01 redis")
+	require.NoError(t, err)
+	require.Equal(t, "12", id)
+	require.Equal(t, apiclient.SearchFilterType{"f_display_name": {"web01 redis"}}, api.filter)
+
+	id, err = c.findCheck("web01 statsd")
+	require.NoError(t, err)
+	require.Equal(t, "", id)
+
+	api.err = fmt.Errorf("api unavailable")
+	_, err = c.findCheck("web01 redis")
+	require.Error(t, err)
+}
+
+func TestGetMetricDestByTag(t *testing.T) {
+	dests := map[string]*cgm.CirconusMetrics{
+		"*":                      {},
+		"agent":                  {},
+		"statsd":                 {},
+		"statsd|service=billing": {},
+		"*|service=billing":      {},
+	}
+	metric := func(tags map[string]string) cua.Metric {
+		m := testutil.MustMetric("statsd_timing", tags, map[string]interface{}{"value": 1.0}, time.Now())
+		m.SetOrigin("statsd")
+		return m
+	}
+
+	c := &Circonus{CheckTagKey: "service", checks: dests}
+	require.Same(t, dests["statsd|service=billing"], c.getMetricDest(metric(map[string]string{"service": "billing"})))
+	require.Same(t, dests["statsd"], c.getMetricDest(metric(map[string]string{"host": "web01"})))
+
+	c = &Circonus{CheckTagKey: "service", OneCheck: true, checks: dests}
+	require.Same(t, dests["*|service=billing"], c.getMetricDest(metric(map[string]string{"service": "billing"})))
+	require.Same(t, dests["*"], c.getMetricDest(metric(map[string]string{"host": "web01"})))
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	cgm "github.com/circonus-labs/circonus-gometrics/v3"
@@ -23,7 +24,9 @@ import (
 )
 
 const (
-	metricVolume = "cua_metrics_sent"
+	metricVolume   = "cua_metrics_sent"
+	metricFixed    = "cua_metrics_fixed"
+	metricRejected = "cua_metrics_rejected"
 )
 
 // Circonus values are used to output data to the Circonus platform.
@@ -37,9 +40,33 @@ type Circonus struct {
 	CheckNamePrefix string `toml:"check_name_prefix"`
 	DebugCGM        bool   `toml:"debug_cgm"`
 	DebugMetrics    bool   `toml:"debug_metrics"`
+	Strict          bool   `toml:"strict"`
+	StrictAction    string `toml:"strict_action"`
+
+	BrokerCAFile            string         `toml:"broker_ca_file"`
+	BrokerCACacheFile       string         `toml:"broker_ca_cache_file"`
+	BrokerTLSCert           string         `toml:"broker_tls_cert"`
+	BrokerTLSKey            string         `toml:"broker_tls_key"`
+	BrokerPinSHA256         []string       `toml:"broker_pin_sha256"`
+	BrokerTLSReloadInterval inter.Duration `toml:"broker_tls_reload_interval"`
+
+	CheckTagKey            string   `toml:"check_tag_key"`
+	CheckDisplayName       string   `toml:"check_display_name"`
+	CheckTags              []string `toml:"check_tags"`
+	CheckSearchDisplayName bool     `toml:"check_search_display_name"`
+
 	apicfg          apiclient.Config
 	checks          map[string]*cgm.CirconusMetrics
+	brokerTLS       *brokerTLS
+	checkAPI        checkAPI
+	displayNameTmpl *template.Template
+	checkTagsTmpl   []*template.Template
+	done            chan struct{}
 	Log             cua.Logger
+
+	// metrics fixed and rejected by the strict mode in the current batch
+	fixed    int64
+	rejected int64
 }
 
 // Init performs initialization of a Circonus client.
@@ -74,10 +101,41 @@ func (c *Circonus) Init() error {
 		c.apicfg.CACert = cp
 	}
 
+	switch c.StrictAction {
+	case "":
+		c.StrictAction = strictFix
+	case strictFix, strictReject:
+	default:
+		return fmt.Errorf("invalid strict_action %q, must be %q or %q", c.StrictAction, strictFix, strictReject)
+	}
+
 	if c.Broker != "" {
 		c.Broker = strings.Replace(c.Broker, "/broker/", "", 1)
 	}
 
+	if err := c.compileCheckTemplates(); err != nil {
+		return err
+	}
+
+	manageBrokerTLS := c.BrokerCAFile != "" || c.BrokerCACacheFile != "" || c.BrokerTLSCert != "" || c.BrokerTLSKey != "" || len(c.BrokerPinSHA256) > 0
+	if manageBrokerTLS || c.CheckSearchDisplayName {
+		api, err := apiclient.New(&c.apicfg)
+		if err != nil {
+			return fmt.Errorf("unable to initialize api client: %w", err)
+		}
+		c.checkAPI = api
+
+		if manageBrokerTLS {
+			if c.BrokerTLSReloadInterval.Duration <= 0 {
+				c.BrokerTLSReloadInterval.Duration = time.Hour
+			}
+			c.brokerTLS, err = newBrokerTLS(api, c.BrokerCAFile, c.BrokerCACacheFile, c.BrokerTLSCert, c.BrokerTLSKey, c.BrokerPinSHA256, c.Log)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	if c.CheckNamePrefix == "" {
 		hn, err := os.Hostname()
 		if err != nil || hn == "" {
@@ -117,11 +175,63 @@ var sampleConfig = `
   ## One check - all metrics go to a single check vs one check per input plugin
   ## NOTE: this effectively disables automatic dashboards for supported plugins
   # one_check = false
+
+  ## Check per tag - the metrics with this tag are sent to a check per plugin
+  ## instance and tag value (or per tag value with one_check), rather than
+  ## to the check of their plugin.
+  ## example:
+  # check_tag_key = "service"
+
+  ## Check display name and tags - Go templates of the display name and of
+  ## the tags added to the checks when they are created. The fields are
+  ## .Prefix (check_name_prefix), .Name ("default", "host", "agent", or the
+  ## plugin and its instance id, followed by the tag of check_tag_key),
+  ## .Plugin, .InstanceID, .TagKey, .TagValue and .OS. The tags rendered
+  ## empty or without value are skipped.
+  # check_display_name = "{{.Prefix}} {{.Name}} ({{.OS}})"
+  ## example:
+  # check_tags = ["plugin:{{.Plugin}}", "{{.TagKey}}:{{.TagValue}}"]
+
+  ## Use the existing active check with the display name, rather than the
+  ## check created by the agent for the instance.
+  # check_search_display_name = false
   
   ## Broker
   ## Optional: explicit broker id or blank (default blank, auto select)
   ## example:
   # broker = "/broker/35"
+
+  ## Strict mode - check the metric names and tags against the Circonus
+  ## naming constraints (length, valid UTF-8, no control characters or "|"
+  ## in the metric names) before submission, rather than having the broker
+  ## silently drop them. The invalid metrics are fixed (truncated, invalid
+  ## characters removed) with strict_action = "fix", or dropped with
+  ## strict_action = "reject". The counts are reported on the agent check
+  ## as cua_metrics_fixed and cua_metrics_rejected.
+  # strict = false
+  # strict_action = "fix"
+
+  ## Broker TLS - by default the broker CA is fetched from the API when the
+  ## checks are created. Setting any of the following options makes the
+  ## plugin manage the TLS of the submissions to the brokers instead: the CA
+  ## is read from broker_ca_file, or fetched from the API and cached in
+  ## broker_ca_cache_file to be used when the API is unavailable. The client
+  ## certificate and key are sent to the brokers requiring mutual TLS. The
+  ## CA, the client certificate and the broker names are reloaded every
+  ## broker_tls_reload_interval, so that rotated files are used without a
+  ## restart.
+  ## example:
+  # broker_ca_file = "/opt/circonus/unified-agent/etc/broker_ca.pem"
+  # broker_ca_cache_file = "/opt/circonus/unified-agent/etc/broker_ca.cache.pem"
+  # broker_tls_cert = "/opt/circonus/unified-agent/etc/broker_client.pem"
+  # broker_tls_key = "/opt/circonus/unified-agent/etc/broker_client.key"
+  # broker_tls_reload_interval = "1h"
+
+  ## Broker certificate pinning - base64 encoded sha256 digests of the
+  ## public keys of the broker certificates or of their CAs, the broker
+  ## certificate chain must contain one of them.
+  ## example:
+  # broker_pin_sha256 = ["47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="]
 `
 
 var description = "Configuration for Circonus output plugin."
@@ -137,17 +247,26 @@ func (c *Circonus) Connect() error {
 		c.checks = make(map[string]*cgm.CirconusMetrics)
 	}
 
-	if err := c.initCheck("*", ""); err != nil {
+	if c.brokerTLS != nil && c.done == nil {
+		if err := c.brokerTLS.load(); err != nil {
+			c.Log.Errorf("unable to load broker tls (%s)", err)
+			return err
+		}
+		c.done = make(chan struct{})
+		go c.reloadBrokerTLS(c.done)
+	}
+
+	if err := c.initCheck("*", checkInfo{Name: "default", typeID: "default"}); err != nil {
 		c.Log.Errorf("unable to initialize circonus check (%s)", err)
 		return err
 	}
 	if config.DefaultPluginsEnabled() {
-		if err := c.initCheck("host", "host"); err != nil {
+		if err := c.initCheck("host", checkInfo{Name: "host", typeID: "host"}); err != nil {
 			c.Log.Errorf("unable to initialize circonus check (%s)", err)
 			return err
 		}
 	}
-	if err := c.initCheck("agent", "agent"); err != nil {
+	if err := c.initCheck("agent", checkInfo{Name: "agent", typeID: "agent"}); err != nil {
 		c.Log.Errorf("unable to initialize circonus check (%s)", err)
 		return err
 	}
@@ -177,7 +296,13 @@ func (c *Circonus) Write(metrics []cua.Metric) (int, error) {
 	}
 
 	numMetrics := int64(0)
+	c.fixed, c.rejected = 0, 0
 	for _, m := range metrics {
+		if c.Strict {
+			if m = c.conform(m); m == nil {
+				continue
+			}
+		}
 		switch m.Type() {
 		case cua.Counter, cua.Gauge, cua.Summary:
 			numMetrics += c.buildNumerics(m)
@@ -203,6 +328,14 @@ func (c *Circonus) Write(metrics []cua.Metric) (int, error) {
 		d.AddGauge(metricVolume+"_batch", numMetrics)
 		d.RecordValue(metricVolume, float64(numMetrics))
 		numMetrics += 2
+		if c.Strict {
+			d.AddGauge(metricFixed, c.fixed)
+			d.AddGauge(metricRejected, c.rejected)
+			numMetrics += 2
+		}
+	}
+	if c.fixed > 0 || c.rejected > 0 {
+		c.Log.Warnf("strict mode: %d metrics fixed, %d metrics rejected", c.fixed, c.rejected)
 	}
 	c.Log.Debugf("queued %d metrics for submission", numMetrics)
 
@@ -231,9 +364,29 @@ func (c *Circonus) Description() string {
 
 // Close will close the Circonus client connection.
 func (c *Circonus) Close() error {
+	if c.done != nil {
+		close(c.done)
+		c.done = nil
+	}
 	return nil
 }
 
+// reloadBrokerTLS reloads the broker TLS material until done is closed.
+func (c *Circonus) reloadBrokerTLS(done chan struct{}) {
+	ticker := time.NewTicker(c.BrokerTLSReloadInterval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := c.brokerTLS.load(); err != nil {
+				c.Log.Errorf("unable to reload broker tls (%s)", err)
+			}
+		}
+	}
+}
+
 func init() {
 	outputs.Add("circonus", func() cua.Output {
 		return &Circonus{}
@@ -257,7 +410,13 @@ func (c *Circonus) getMetricDest(m cua.Metric) *cgm.CirconusMetrics {
 		defaultDest = d
 	}
 
-	if c.OneCheck || plugin == "" {
+	// metrics split by tag - a check per plugin instance and tag value
+	var tagValue string
+	if c.CheckTagKey != "" {
+		tagValue, _ = m.GetTag(c.CheckTagKey)
+	}
+
+	if (c.OneCheck || plugin == "") && tagValue == "" {
 		return defaultDest
 	}
 
@@ -277,7 +436,7 @@ func (c *Circonus) getMetricDest(m cua.Metric) *cgm.CirconusMetrics {
 		agentDest = d
 	}
 
-	if config.IsDefaultInstanceID(instanceID) {
+	if config.IsDefaultInstanceID(instanceID) && tagValue == "" {
 		if config.IsDefaultPlugin(plugin) {
 			return hostDest
 		}
@@ -286,17 +445,14 @@ func (c *Circonus) getMetricDest(m cua.Metric) *cgm.CirconusMetrics {
 		}
 	}
 
-	id := plugin
-	if instanceID != "" {
-		id += ":" + instanceID
-	}
+	id, info := c.pluginCheck(plugin, instanceID, tagValue)
 
 	// otherwise - find (or create) a check for the specific plugin
 	if d, ok := c.checks[id]; ok {
 		return d
 	}
 
-	if err := c.initCheck(id, plugin+" "+instanceID); err == nil {
+	if err := c.initCheck(id, info); err == nil {
 		if d, ok := c.checks[id]; ok {
 			return d
 		}
@@ -321,12 +477,10 @@ func (l logshim) Printf(fmt string, args ...interface{}) {
 }
 
 // initCheck initializes cgm instance for the plugin identified by id
-func (c *Circonus) initCheck(id, name string) error {
-	plugID := id
-	if id == "*" {
-		plugID = "default"
-		name = "default"
-	}
+func (c *Circonus) initCheck(id string, info checkInfo) error {
+	plugID := info.typeID
+	info.Prefix = c.CheckNamePrefix
+	info.OS = runtime.GOOS
 
 	checkType := "httptrap:cua:" + plugID + ":" + runtime.GOOS
 
@@ -343,9 +497,31 @@ func (c *Circonus) initCheck(id, name string) error {
 	if c.Broker != "" {
 		cfg.CheckManager.Broker.ID = c.Broker
 	}
+	if c.brokerTLS != nil {
+		cfg.CheckManager.Broker.TLSConfig = c.brokerTLS.config()
+	}
 	cfg.CheckManager.Check.InstanceID = strings.Replace(checkType, "httptrap", c.CheckNamePrefix, 1)
+	if info.TagKey != "" {
+		cfg.CheckManager.Check.InstanceID += ":" + info.TagKey + "=" + info.TagValue
+	}
 	cfg.CheckManager.Check.TargetHost = c.CheckNamePrefix
-	cfg.CheckManager.Check.DisplayName = c.CheckNamePrefix + " " + name + " (" + runtime.GOOS + ")"
+	displayName, err := render(c.displayNameTmpl, info)
+	if err != nil {
+		return fmt.Errorf("initializing cgm instance for %s (%w)", id, err)
+	}
+	cfg.CheckManager.Check.DisplayName = displayName
+	cfg.CheckManager.Check.Tags, err = c.checkTags(info)
+	if err != nil {
+		return fmt.Errorf("initializing cgm instance for %s (%w)", id, err)
+	}
+	if c.CheckSearchDisplayName {
+		checkID, err := c.findCheck(displayName)
+		if err != nil {
+			return fmt.Errorf("initializing cgm instance for %s (%w)", id, err)
+		}
+		// an existing check is used rather than searched by instance id
+		cfg.CheckManager.Check.ID = checkID
+	}
 	cfg.CheckManager.Check.Type = checkType
 	_, an := filepath.Split(os.Args[0])
 	cfg.CheckManager.Check.SearchTag = "service:" + an
@@ -6,10 +6,14 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCirconus(t *testing.T) {
@@ -71,3 +75,84 @@ func TestCirconus(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestStrict(t *testing.T) {
+	now := time.Now()
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		name     string
+		action   string
+		metric   cua.Metric
+		expected cua.Metric
+		fixed    int64
+		rejected int64
+	}{
+		{
+			name:     "valid",
+			action:   strictReject,
+			metric:   testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"}, map[string]interface{}{"usage_idle": 99.5}, now),
+			expected: testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"}, map[string]interface{}{"usage_idle": 99.5}, now),
+		},
+		{
+			name:     "fixed",
+			action:   strictFix,
+			metric:   testutil.MustMetric("cpu", map[string]string{"CPU Name": "cpu0"}, map[string]interface{}{"usage|idle\n": 99.5, long: 1.0}, now),
+			expected: testutil.MustMetric("cpu", map[string]string{"cpuname": "cpu0"}, map[string]interface{}{"usage_idle": 99.5, long[:maxMetricNameLen]: 1.0}, now),
+			fixed:    1,
+		},
+		{
+			name:     "rejected",
+			action:   strictReject,
+			metric:   testutil.MustMetric("cpu", map[string]string{"cpu": long}, map[string]interface{}{"usage_idle": 99.5}, now),
+			rejected: 1,
+		},
+		{
+			name:     "empty name",
+			action:   strictFix,
+			metric:   testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"\t": 99.5}, now),
+			rejected: 1,
+		},
+		{
+			name:     "tagged name too long",
+			action:   strictFix,
+			metric:   testutil.MustMetric("cpu", tagsOf(long, 16), map[string]interface{}{"usage_idle": 99.5}, now),
+			rejected: 1,
+		},
+		{
+			name:     "histogram",
+			action:   strictFix,
+			metric:   testutil.MustMetric("latency|ST[a:b]", map[string]string{}, map[string]interface{}{"0.1": int64(1)}, now, cua.Histogram),
+			expected: testutil.MustMetric("latency_ST[a:b]", map[string]string{}, map[string]interface{}{"0.1": int64(1)}, now, cua.Histogram),
+			fixed:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Circonus{APIToken: "token", Strict: true, StrictAction: tt.action, Log: testutil.Logger{}}
+			require.NoError(t, c.Init())
+
+			m := c.conform(tt.metric)
+			if tt.expected == nil {
+				require.Nil(t, m)
+			} else {
+				testutil.RequireMetricEqual(t, tt.expected, m)
+			}
+			require.Equal(t, tt.fixed, c.fixed)
+			require.Equal(t, tt.rejected, c.rejected)
+		})
+	}
+}
+
+// tagsOf returns n tags of the value.
+func tagsOf(value string, n int) map[string]string {
+	tags := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		tags[string(rune('a'+i))] = value
+	}
+	return tags
+}
+
+func TestStrictInvalidAction(t *testing.T) {
+	c := &Circonus{APIToken: "token", StrictAction: "drop"}
+	require.Error(t, c.Init())
+}
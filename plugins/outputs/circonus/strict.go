@@ -0,0 +1,178 @@
+package circonus
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+)
+
+// Circonus naming constraints, the metrics exceeding them are dropped by the
+// broker without notice.
+const (
+	maxMetricNameLen  = 255
+	maxTagCategoryLen = 254
+	maxTagLen         = 256 // category:value
+	maxTaggedNameLen  = 4096
+
+	strictFix    = "fix"
+	strictReject = "reject"
+)
+
+// conform checks the metric names and the tags of the metric against the
+// Circonus naming constraints.  The metric is returned as is when valid; when
+// not, a fixed copy is returned with the "fix" action and nil with the
+// "reject" action.  The metrics whose tagged names are too long are rejected
+// with both actions.
+func (c *Circonus) conform(m cua.Metric) cua.Metric {
+	var fixed cua.Metric
+	mutable := func() cua.Metric {
+		if fixed == nil {
+			fixed = m.Copy()
+		}
+		return fixed
+	}
+
+	names := make([]string, 0, len(m.FieldList()))
+	if isHistogram(m) {
+		name, ok := conformName(strings.TrimSuffix(m.Name(), "__value"))
+		if !ok {
+			if name == "" || c.StrictAction == strictReject {
+				return c.reject(m, "invalid metric name %q", m.Name())
+			}
+			mutable().SetName(name)
+		}
+		names = append(names, name)
+	} else {
+		for _, field := range m.FieldList() {
+			name, ok := conformName(strings.TrimSuffix(field.Key, "__value"))
+			if !ok {
+				if name == "" || c.StrictAction == strictReject {
+					return c.reject(m, "invalid metric name %q", field.Key)
+				}
+				f := mutable()
+				f.RemoveField(field.Key)
+				f.AddField(name, field.Value)
+			}
+			names = append(names, name)
+		}
+	}
+
+	for _, tag := range m.TagList() {
+		key, value, ok := conformTag(tag.Key, tag.Value)
+		if ok {
+			continue
+		}
+		if c.StrictAction == strictReject {
+			return c.reject(m, "invalid tag %q", tag.Key+":"+tag.Value)
+		}
+		f := mutable()
+		f.RemoveTag(tag.Key)
+		if key != "" {
+			f.AddTag(key, value)
+		}
+	}
+
+	out := m
+	if fixed != nil {
+		out = fixed
+	}
+	tagsLen := taggedLen(c.convertTags(out))
+	for _, name := range names {
+		if len(name)+tagsLen > maxTaggedNameLen {
+			return c.reject(m, "tagged metric name %q longer than %d bytes", name, maxTaggedNameLen)
+		}
+	}
+
+	if fixed != nil {
+		c.fixed++
+	}
+	return out
+}
+
+func (c *Circonus) reject(m cua.Metric, format string, args ...interface{}) cua.Metric {
+	c.rejected++
+	if c.DebugMetrics {
+		c.Log.Infof("rejected %s: "+format, append([]interface{}{m.Name()}, args...)...)
+	}
+	return nil
+}
+
+// isHistogram reports whether the metric is sent as a histogram, named after
+// the measurement rather than the fields.
+func isHistogram(m cua.Metric) bool {
+	switch m.Type() {
+	case cua.Histogram, cua.CumulativeHistogram:
+		return true
+	case cua.Untyped:
+		fields := m.FieldList()
+		if len(fields) == 0 {
+			return false
+		}
+		s, ok := fields[0].Value.(string)
+		return ok && strings.Contains(s, "H[") && strings.Contains(s, "]=")
+	}
+	return false
+}
+
+// conformName returns the metric name fixed and whether it was valid: valid
+// UTF-8 without control characters nor "|", the stream tags delimiter, of
+// at most maxMetricNameLen bytes.
+func conformName(name string) (string, bool) {
+	fixed := strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError, unicode.IsControl(r):
+			return -1
+		case r == '|':
+			return '_'
+		}
+		return r
+	}, name)
+	fixed = truncate(strings.TrimSpace(fixed), maxMetricNameLen)
+	return fixed, fixed == name && fixed != ""
+}
+
+// conformTag returns the tag fixed and whether it was valid.  The categories
+// are lowercased without spaces by the broker, the tags with an empty
+// category are dropped.
+func conformTag(key, value string) (string, string, bool) {
+	fixedKey := strings.ToValidUTF8(strings.ToLower(strings.ReplaceAll(key, " ", "")), "")
+	fixedKey = truncate(fixedKey, maxTagCategoryLen)
+	fixedValue := strings.ToValidUTF8(value, "")
+	fixedValue = truncate(fixedValue, maxTagLen-len(fixedKey)-1)
+	return fixedKey, fixedValue, fixedKey == key && fixedValue == value && key != ""
+}
+
+// truncate returns s of at most n bytes, on a rune boundary.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// taggedLen returns the maximum length of the stream tags appended to the
+// metric names, "|ST[...]" with the tags base64 encoded.
+func taggedLen(tags cgm.Tags) int {
+	if len(tags) == 0 {
+		return 0
+	}
+	n := len("|ST[]")
+	for i, tag := range tags {
+		if i > 0 {
+			n++ // ,
+		}
+		n += encodedLen(tag.Category) + 1 + encodedLen(tag.Value)
+	}
+	return n
+}
+
+// encodedLen is the length of b"<base64>".
+func encodedLen(s string) int {
+	return 3 + (len(s)+2)/3*4
+}
@@ -0,0 +1,313 @@
+package cloudpubsub
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+const sampleConfig = `
+  ## Required. Name of Google Cloud Platform (GCP) Project that owns
+  ## the given PubSub topic.
+  project = "my-project"
+
+  ## Required. Name of PubSub topic to publish metrics to.
+  topic = "my-topic"
+
+  ## Required. Data format to output.
+  ## Each data format has its own unique set of configuration options.
+  ## Read more about them here:
+  ## https://github.com/circonus-labs/circonus-unified-agent/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+
+  ## Optional. Filepath for GCP credentials JSON file to authorize calls to
+  ## PubSub APIs. If not set explicitly, the agent will attempt to use
+  ## Application Default Credentials, which is preferred and also covers
+  ## GKE workload identity and the GCE metadata server.
+  # credentials_file = "path/to/my/creds.json"
+
+  ## Optional. If true, will send all metrics per write in one PubSub message.
+  # send_batched = true
+
+  ## Optional. Publish messages with an ordering key. Messages sharing the
+  ## same key are delivered in order to subscriptions with message ordering
+  ## enabled. The key is either a fixed value or, with ordering_key_tag, the
+  ## value of a metric tag; ordering_key_tag requires send_batched = false.
+  # ordering_key = ""
+  # ordering_key_tag = ""
+
+  ## The following publish_* parameters specifically configures batching
+  ## requests made to the GCP Cloud PubSub API via the PubSub Golang library. Read
+  ## more here: https://godoc.org/cloud.google.com/go/pubsub#PublishSettings
+
+  ## Optional. Send a request to PubSub (i.e. actually publish a batch)
+  ## when it has this many PubSub messages. If send_batched is true,
+  ## this is ignored and treated as if it were 1.
+  # publish_count_threshold = 1000
+
+  ## Optional. Send a request to PubSub (i.e. actually publish a batch)
+  ## when it has this many PubSub messages. If send_batched is true,
+  ## this is ignored and treated as if it were 1
+  # publish_byte_threshold = 1000000
+
+  ## Optional. Specifically configures requests made to the PubSub API.
+  # publish_num_go_routines = 2
+
+  ## Optional. Specifies a timeout for requests to the PubSub API.
+  # publish_timeout = "30s"
+
+  ## Optional. If true, published PubSub message data will be base64-encoded.
+  # base64_data = false
+
+  ## Optional. PubSub attributes to add to metrics.
+  # [outputs.cloud_pubsub.attributes]
+  #   my_attr = "tag_value"
+`
+
+type PubSub struct {
+	CredentialsFile string            `toml:"credentials_file"`
+	Project         string            `toml:"project"`
+	Topic           string            `toml:"topic"`
+	Attributes      map[string]string `toml:"attributes"`
+	OrderingKey     string            `toml:"ordering_key"`
+	OrderingKeyTag  string            `toml:"ordering_key_tag"`
+
+	SendBatched           bool              `toml:"send_batched"`
+	PublishCountThreshold int               `toml:"publish_count_threshold"`
+	PublishByteThreshold  int               `toml:"publish_byte_threshold"`
+	PublishNumGoroutines  int               `toml:"publish_num_go_routines"`
+	PublishTimeout        internal.Duration `toml:"publish_timeout"`
+	Base64Data            bool              `toml:"base64_data"`
+
+	Log cua.Logger `toml:"-"`
+
+	t topic
+	c *pubsub.Client
+
+	stubTopic func(id string) topic
+
+	serializer     serializers.Serializer
+	publishResults []publishResult
+	orderingKeys   []string
+}
+
+func (ps *PubSub) Description() string {
+	return "Publish metrics to a Google Cloud PubSub topic"
+}
+
+func (ps *PubSub) SampleConfig() string {
+	return sampleConfig
+}
+
+func (ps *PubSub) SetSerializer(serializer serializers.Serializer) {
+	ps.serializer = serializer
+}
+
+func (ps *PubSub) Connect() error {
+	if ps.Topic == "" {
+		return fmt.Errorf(`"topic" is required`)
+	}
+
+	if ps.Project == "" {
+		return fmt.Errorf(`"project" is required`)
+	}
+
+	if ps.OrderingKeyTag != "" && ps.SendBatched {
+		return fmt.Errorf(`"ordering_key_tag" can not be used with "send_batched"`)
+	}
+
+	if ps.stubTopic == nil {
+		return ps.initPubSubClient()
+	}
+
+	ps.t = ps.stubTopic(ps.Topic)
+	ps.initTopic()
+	return nil
+}
+
+func (ps *PubSub) Close() error {
+	if ps.t != nil {
+		ps.t.Stop()
+	}
+	return nil
+}
+
+func (ps *PubSub) Write(metrics []cua.Metric) (int, error) {
+	ps.refreshTopic()
+
+	cctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs, err := ps.toMessages(metrics)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range msgs {
+		ps.publish(cctx, m)
+	}
+
+	return len(metrics), ps.waitForResults(cctx, cancel)
+}
+
+func (ps *PubSub) initPubSubClient() error {
+	var credsOpt option.ClientOption
+	if ps.CredentialsFile != "" {
+		credsOpt = option.WithCredentialsFile(ps.CredentialsFile)
+	} else {
+		creds, err := google.FindDefaultCredentials(context.Background(), pubsub.ScopeCloudPlatform)
+		if err != nil {
+			return fmt.Errorf(
+				"unable to find GCP Application Default Credentials: %w."+
+					"Either set ADC or provide CredentialsFile config", err)
+		}
+		credsOpt = option.WithCredentials(creds)
+	}
+	client, err := pubsub.NewClient(
+		context.Background(),
+		ps.Project,
+		credsOpt,
+		option.WithScopes(pubsub.ScopeCloudPlatform),
+		option.WithUserAgent(internal.ProductToken()),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to generate PubSub client: %w", err)
+	}
+	ps.c = client
+	return nil
+}
+
+func (ps *PubSub) refreshTopic() {
+	if ps.stubTopic != nil {
+		return
+	}
+	if ps.t != nil {
+		ps.t.Stop()
+	}
+
+	t := &topicWrapper{ps.c.Topic(ps.Topic)}
+	ps.t = t
+	ps.initTopic()
+}
+
+func (ps *PubSub) initTopic() {
+	settings := ps.t.PublishSettings()
+	if ps.PublishCountThreshold > 0 {
+		settings.CountThreshold = ps.PublishCountThreshold
+	}
+	if ps.PublishByteThreshold > 0 {
+		settings.ByteThreshold = ps.PublishByteThreshold
+	}
+	if ps.PublishNumGoroutines > 0 {
+		settings.NumGoroutines = ps.PublishNumGoroutines
+	}
+	if ps.PublishTimeout.Duration > 0 {
+		settings.Timeout = ps.PublishTimeout.Duration
+	}
+	if ps.SendBatched {
+		settings.CountThreshold = 1
+	}
+	ps.t.SetPublishSettings(settings)
+	ps.t.SetMessageOrdering(ps.OrderingKey != "" || ps.OrderingKeyTag != "")
+}
+
+func (ps *PubSub) publish(ctx context.Context, msg *pubsub.Message) {
+	ps.publishResults = append(ps.publishResults, ps.t.Publish(ctx, msg))
+	ps.orderingKeys = append(ps.orderingKeys, msg.OrderingKey)
+}
+
+func (ps *PubSub) toMessages(metrics []cua.Metric) ([]*pubsub.Message, error) {
+	if ps.SendBatched {
+		b, err := ps.serializer.SerializeBatch(metrics)
+		if err != nil {
+			return nil, fmt.Errorf("serialize batch: %w", err)
+		}
+		return []*pubsub.Message{ps.newMessage(b, ps.OrderingKey)}, nil
+	}
+
+	msgs := make([]*pubsub.Message, 0, len(metrics))
+	for _, m := range metrics {
+		b, err := ps.serializer.Serialize(m)
+		if err != nil {
+			ps.Log.Debugf("Could not serialize metric: %v", err)
+			continue
+		}
+
+		key := ps.OrderingKey
+		if ps.OrderingKeyTag != "" {
+			if v, ok := m.GetTag(ps.OrderingKeyTag); ok {
+				key = v
+			}
+		}
+		msgs = append(msgs, ps.newMessage(b, key))
+	}
+
+	return msgs, nil
+}
+
+func (ps *PubSub) newMessage(b []byte, orderingKey string) *pubsub.Message {
+	if ps.Base64Data {
+		encoded := base64.StdEncoding.EncodeToString(b)
+		b = []byte(encoded)
+	}
+
+	msg := &pubsub.Message{
+		Data:        b,
+		OrderingKey: orderingKey,
+	}
+	if len(ps.Attributes) > 0 {
+		msg.Attributes = ps.Attributes
+	}
+	return msg
+}
+
+// waitForResults waits for all publish calls to complete. A failed publish
+// pauses its ordering key, the key is resumed so the messages can be retried
+// with the next write.
+func (ps *PubSub) waitForResults(ctx context.Context, cancel context.CancelFunc) error {
+	var pErr error
+	var setErr sync.Once
+	var wg sync.WaitGroup
+
+	for i, pr := range ps.publishResults {
+		wg.Add(1)
+
+		go func(r publishResult, orderingKey string) {
+			defer wg.Done()
+			// Wait on each future
+			_, err := r.Get(ctx)
+			if err != nil {
+				if orderingKey != "" {
+					ps.t.ResumePublish(orderingKey)
+				}
+				setErr.Do(func() {
+					pErr = err
+					cancel()
+				})
+			}
+		}(pr, ps.orderingKeys[i])
+	}
+
+	wg.Wait()
+	ps.publishResults = nil
+	ps.orderingKeys = nil
+	if pErr != nil {
+		return fmt.Errorf("publish: %w", pErr)
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("cloud_pubsub", func() cua.Output {
+		return &PubSub{}
+	})
+}
@@ -0,0 +1,192 @@
+package cloudpubsub
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type stubTopic struct {
+	sync.Mutex
+
+	id       string
+	settings pubsub.PublishSettings
+	ordering bool
+	err      error
+
+	published []*pubsub.Message
+	resumed   []string
+}
+
+func (t *stubTopic) ID() string { return t.id }
+
+func (t *stubTopic) Stop() {}
+
+func (t *stubTopic) Publish(ctx context.Context, msg *pubsub.Message) publishResult {
+	t.Lock()
+	defer t.Unlock()
+	t.published = append(t.published, msg)
+	return &stubResult{err: t.err}
+}
+
+func (t *stubTopic) PublishSettings() pubsub.PublishSettings { return t.settings }
+
+func (t *stubTopic) SetPublishSettings(settings pubsub.PublishSettings) { t.settings = settings }
+
+func (t *stubTopic) SetMessageOrdering(enabled bool) { t.ordering = enabled }
+
+func (t *stubTopic) ResumePublish(orderingKey string) {
+	t.Lock()
+	defer t.Unlock()
+	t.resumed = append(t.resumed, orderingKey)
+}
+
+type stubResult struct {
+	err error
+}
+
+func (r *stubResult) Get(ctx context.Context) (string, error) {
+	return "id", r.err
+}
+
+func newTestPubSub(t *testing.T, st *stubTopic) *PubSub {
+	s, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	ps := &PubSub{
+		Project:   "projectIDontMatterForTests",
+		Topic:     st.id,
+		Log:       testutil.Logger{},
+		stubTopic: func(string) topic { return st },
+	}
+	ps.SetSerializer(s)
+	return ps
+}
+
+func testMetrics() []cua.Metric {
+	return []cua.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(0, 0)),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(0, 0)),
+	}
+}
+
+func parseMessages(t *testing.T, msgs []*pubsub.Message, b64 bool) []cua.Metric {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+
+	var metrics []cua.Metric
+	for _, msg := range msgs {
+		data := msg.Data
+		if b64 {
+			data, err = base64.StdEncoding.DecodeString(string(data))
+			require.NoError(t, err)
+		}
+		m, err := parser.Parse(data)
+		require.NoError(t, err)
+		metrics = append(metrics, m...)
+	}
+	return metrics
+}
+
+func TestPubSub_WriteSingle(t *testing.T) {
+	st := &stubTopic{id: "test-topic"}
+	ps := newTestPubSub(t, st)
+	ps.Attributes = map[string]string{"foo": "bar"}
+	require.NoError(t, ps.Connect())
+
+	metrics := testMetrics()
+	n, err := ps.Write(metrics)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	require.Len(t, st.published, 2)
+	require.Equal(t, map[string]string{"foo": "bar"}, st.published[0].Attributes)
+	testutil.RequireMetricsEqual(t, metrics, parseMessages(t, st.published, false))
+	require.False(t, st.ordering)
+}
+
+func TestPubSub_WriteBatchedBase64(t *testing.T) {
+	st := &stubTopic{id: "test-topic"}
+	ps := newTestPubSub(t, st)
+	ps.SendBatched = true
+	ps.Base64Data = true
+	require.NoError(t, ps.Connect())
+	require.Equal(t, 1, st.settings.CountThreshold)
+
+	metrics := testMetrics()
+	_, err := ps.Write(metrics)
+	require.NoError(t, err)
+
+	require.Len(t, st.published, 1)
+	testutil.RequireMetricsEqual(t, metrics, parseMessages(t, st.published, true))
+}
+
+func TestPubSub_OrderingKey(t *testing.T) {
+	st := &stubTopic{id: "test-topic"}
+	ps := newTestPubSub(t, st)
+	ps.OrderingKey = "default"
+	ps.OrderingKeyTag = "host"
+	require.NoError(t, ps.Connect())
+	require.True(t, st.ordering)
+
+	metrics := testMetrics()
+	metrics = append(metrics, testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{"value": 3.0},
+		time.Unix(0, 0)))
+	_, err := ps.Write(metrics)
+	require.NoError(t, err)
+
+	require.Len(t, st.published, 3)
+	require.Equal(t, "a", st.published[0].OrderingKey)
+	require.Equal(t, "b", st.published[1].OrderingKey)
+	require.Equal(t, "default", st.published[2].OrderingKey)
+}
+
+func TestPubSub_OrderingKeyTagBatched(t *testing.T) {
+	st := &stubTopic{id: "test-topic"}
+	ps := newTestPubSub(t, st)
+	ps.OrderingKeyTag = "host"
+	ps.SendBatched = true
+	require.Error(t, ps.Connect())
+}
+
+func TestPubSub_PublishErrorResumesKey(t *testing.T) {
+	st := &stubTopic{id: "test-topic", err: errors.New("publish failed")}
+	ps := newTestPubSub(t, st)
+	ps.OrderingKey = "key"
+	require.NoError(t, ps.Connect())
+
+	_, err := ps.Write(testMetrics())
+	require.Error(t, err)
+	require.Contains(t, st.resumed, "key")
+}
+
+func TestPubSub_Settings(t *testing.T) {
+	st := &stubTopic{id: "test-topic"}
+	ps := newTestPubSub(t, st)
+	ps.PublishCountThreshold = 10
+	ps.PublishByteThreshold = 100
+	ps.PublishNumGoroutines = 3
+	require.NoError(t, ps.Connect())
+
+	require.Equal(t, 10, st.settings.CountThreshold)
+	require.Equal(t, 100, st.settings.ByteThreshold)
+	require.Equal(t, 3, st.settings.NumGoroutines)
+}
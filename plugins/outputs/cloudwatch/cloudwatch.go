@@ -0,0 +1,455 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	internalaws "github.com/circonus-labs/circonus-unified-agent/config/aws"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+)
+
+const (
+	// maxDatumsPerCall is the maximum number of MetricDatum a single
+	// PutMetricData request may contain.
+	maxDatumsPerCall = 20
+	// maxDimensions is the maximum number of dimensions CloudWatch accepts
+	// on a single MetricDatum.
+	maxDimensions = 10
+	// highResolution is the storage resolution, in seconds, of high
+	// resolution metrics; standard resolution metrics use 60.
+	highResolution     = 1
+	standardResolution = 60
+)
+
+// CloudWatch contains the configuration for the cloudwatch output plugin.
+type CloudWatch struct {
+	Region         string          `toml:"region"`
+	AccessKey      string          `toml:"access_key"`
+	SecretKey      string          `toml:"secret_key"`
+	RoleARN        string          `toml:"role_arn"`
+	Profile        string          `toml:"profile"`
+	CredentialPath string          `toml:"shared_credential_file"`
+	Token          string          `toml:"token"`
+	EndpointURL    string          `toml:"endpoint_url"`
+	Timeout        config.Duration `toml:"timeout"`
+
+	Namespace             string   `toml:"namespace"`
+	HighResolutionMetrics bool     `toml:"high_resolution_metrics"`
+	WriteStatistics       bool     `toml:"write_statistics"`
+	DimensionInclude      []string `toml:"dimension_include"`
+	DimensionExclude      []string `toml:"dimension_exclude"`
+
+	Log cua.Logger `toml:"-"`
+
+	client cloudwatchClient
+}
+
+type cloudwatchClient interface {
+	PutMetricData(*cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error)
+}
+
+var sampleConfig = `
+  ## Amazon Region
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+  # role_arn = ""
+  # profile = ""
+  # shared_credential_file = ""
+
+  ## Endpoint to make request against, the correct endpoint is automatically
+  ## determined and this option should only be set if you wish to override the
+  ## default.
+  ##   ex: endpoint_url = "http://localhost:8000"
+  # endpoint_url = ""
+
+  ## Timeout for http requests made by the cloudwatch client.
+  # timeout = "5s"
+
+  ## Namespace for the CloudWatch MetricDatums
+  namespace = "Circonus/UnifiedAgent"
+
+  ## If you have a large amount of metrics, you should consider to send
+  ## statistic values instead of raw metrics which could not only improve
+  ## performance but also save AWS API cost. If enabled, fields ending in
+  ## _max, _min, _sum and _count (e.g. as produced by the basicstats
+  ## aggregator) are sent as a single StatisticSet.
+  # write_statistics = false
+
+  ## Enable high resolution metrics of 1 second (if not enabled, standard
+  ## resolution are of 60 seconds precision)
+  # high_resolution_metrics = false
+
+  ## Tags to send as dimensions. CloudWatch accepts at most 10 dimensions per
+  ## metric, tags are sorted by name and truncated to that limit after the
+  ## filters are applied.
+  # dimension_include = []
+  # dimension_exclude = []
+`
+
+// SampleConfig returns the default configuration of the cloudwatch output plugin.
+func (c *CloudWatch) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a one-sentence description of the cloudwatch output plugin.
+func (c *CloudWatch) Description() string {
+	return "Configuration for AWS CloudWatch output."
+}
+
+// Init validates the plugin configuration.
+func (c *CloudWatch) Init() error {
+	if c.Namespace == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	return nil
+}
+
+// Connect creates the CloudWatch client using the standard AWS credential chain.
+func (c *CloudWatch) Connect() error {
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      c.Region,
+		AccessKey:   c.AccessKey,
+		SecretKey:   c.SecretKey,
+		RoleARN:     c.RoleARN,
+		Profile:     c.Profile,
+		Filename:    c.CredentialPath,
+		Token:       c.Token,
+		EndpointURL: c.EndpointURL,
+	}
+	configProvider, err := credentialConfig.Credentials()
+	if err != nil {
+		return fmt.Errorf("credentials: %w", err)
+	}
+
+	cfg := &aws.Config{
+		HTTPClient: &http.Client{
+			// use values from DefaultTransport
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: 30 * time.Second,
+					DualStack: true,
+				}).DialContext,
+				MaxIdleConns:          100,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: 1 * time.Second,
+			},
+			Timeout: time.Duration(c.Timeout),
+		},
+	}
+
+	c.client = cloudwatch.New(configProvider, cfg.WithLogLevel(aws.LogOff))
+
+	return nil
+}
+
+// Close is a no-op, the client holds no persistent connection.
+func (c *CloudWatch) Close() error {
+	return nil
+}
+
+// Write converts the metrics to MetricDatum and sends them in batches that
+// fit within the PutMetricData API limits.
+func (c *CloudWatch) Write(metrics []cua.Metric) (int, error) {
+	var datums []*cloudwatch.MetricDatum
+	for _, m := range metrics {
+		datums = append(datums, c.buildMetricDatum(m)...)
+	}
+
+	sent := 0
+	for _, batch := range partitionDatums(maxDatumsPerCall, datums) {
+		params := &cloudwatch.PutMetricDataInput{
+			MetricData: batch,
+			Namespace:  aws.String(c.Namespace),
+		}
+		if _, err := c.client.PutMetricData(params); err != nil {
+			return sent, fmt.Errorf("put metric data: %w", err)
+		}
+		sent += len(batch)
+	}
+
+	return sent, nil
+}
+
+// partitionDatums splits datums into slices of at most size elements.
+func partitionDatums(size int, datums []*cloudwatch.MetricDatum) [][]*cloudwatch.MetricDatum {
+	numPartitions := len(datums) / size
+	if len(datums)%size != 0 {
+		numPartitions++
+	}
+
+	partitions := make([][]*cloudwatch.MetricDatum, numPartitions)
+	for i := 0; i < numPartitions; i++ {
+		start := size * i
+		end := size * (i + 1)
+		if end > len(datums) {
+			end = len(datums)
+		}
+		partitions[i] = datums[start:end]
+	}
+
+	return partitions
+}
+
+// statisticType identifies which member of a StatisticSet a field maps to.
+type statisticType int
+
+const (
+	statisticTypeNone statisticType = iota
+	statisticTypeMax
+	statisticTypeMin
+	statisticTypeSum
+	statisticTypeCount
+)
+
+// statisticSet accumulates the fields of a metric that together form a
+// CloudWatch StatisticSet.
+type statisticSet struct {
+	max, min, sum, count float64
+	seen                 map[statisticType]bool
+}
+
+func (s *statisticSet) complete() bool {
+	return s.seen[statisticTypeMax] && s.seen[statisticTypeMin] &&
+		s.seen[statisticTypeSum] && s.seen[statisticTypeCount]
+}
+
+func (s *statisticSet) set(st statisticType, v float64) {
+	switch st {
+	case statisticTypeMax:
+		s.max = v
+	case statisticTypeMin:
+		s.min = v
+	case statisticTypeSum:
+		s.sum = v
+	case statisticTypeCount:
+		s.count = v
+	case statisticTypeNone:
+	}
+	s.seen[st] = true
+}
+
+// buildMetricDatum converts a single metric into one MetricDatum per field,
+// or per statistic set when write_statistics is enabled.
+func (c *CloudWatch) buildMetricDatum(m cua.Metric) []*cloudwatch.MetricDatum {
+	dimensions := c.buildDimensions(m.TagList())
+	resolution := int64(standardResolution)
+	if c.HighResolutionMetrics {
+		resolution = highResolution
+	}
+	ts := m.Time()
+
+	stats := map[string]*statisticSet{}
+	values := map[string]float64{}
+
+	for _, field := range m.FieldList() {
+		v, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+
+		if c.WriteStatistics {
+			if base, st := statisticField(field.Key); st != statisticTypeNone {
+				s, ok := stats[base]
+				if !ok {
+					s = &statisticSet{seen: map[statisticType]bool{}}
+					stats[base] = s
+				}
+				s.set(st, v)
+				continue
+			}
+		}
+		values[field.Key] = v
+	}
+
+	datums := make([]*cloudwatch.MetricDatum, 0, len(values)+len(stats))
+
+	// Incomplete statistic sets are sent as individual values instead.
+	for base, s := range stats {
+		if s.complete() {
+			datums = append(datums, &cloudwatch.MetricDatum{
+				MetricName: aws.String(metricName(m.Name(), base)),
+				Dimensions: dimensions,
+				Timestamp:  aws.Time(ts),
+				StatisticValues: &cloudwatch.StatisticSet{
+					Maximum:     aws.Float64(s.max),
+					Minimum:     aws.Float64(s.min),
+					Sum:         aws.Float64(s.sum),
+					SampleCount: aws.Float64(s.count),
+				},
+				StorageResolution: aws.Int64(resolution),
+			})
+			continue
+		}
+		for st, suffix := range statisticSuffixes {
+			if s.seen[st] {
+				values[base+suffix] = s.value(st)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		datums = append(datums, &cloudwatch.MetricDatum{
+			MetricName:        aws.String(metricName(m.Name(), k)),
+			Dimensions:        dimensions,
+			Timestamp:         aws.Time(ts),
+			Value:             aws.Float64(values[k]),
+			StorageResolution: aws.Int64(resolution),
+		})
+	}
+
+	return datums
+}
+
+var statisticSuffixes = map[statisticType]string{
+	statisticTypeMax:   "_max",
+	statisticTypeMin:   "_min",
+	statisticTypeSum:   "_sum",
+	statisticTypeCount: "_count",
+}
+
+func (s *statisticSet) value(st statisticType) float64 {
+	switch st {
+	case statisticTypeMax:
+		return s.max
+	case statisticTypeMin:
+		return s.min
+	case statisticTypeSum:
+		return s.sum
+	case statisticTypeCount:
+		return s.count
+	case statisticTypeNone:
+	}
+	return 0
+}
+
+// statisticField returns the base field name and statistic type of a field
+// following the basicstats naming convention.
+func statisticField(key string) (string, statisticType) {
+	for st, suffix := range statisticSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return strings.TrimSuffix(key, suffix), st
+		}
+	}
+	return key, statisticTypeNone
+}
+
+func metricName(measurement, field string) string {
+	return measurement + "_" + field
+}
+
+// buildDimensions converts tags into at most maxDimensions dimensions. Tags
+// are already sorted by key; the host tag, when present, is always kept.
+func (c *CloudWatch) buildDimensions(tags []*cua.Tag) []*cloudwatch.Dimension {
+	dimensions := make([]*cloudwatch.Dimension, 0, maxDimensions)
+
+	for _, tag := range tags {
+		if tag.Key == "host" && tag.Value != "" && c.includeDimension(tag.Key) {
+			dimensions = append(dimensions, &cloudwatch.Dimension{
+				Name:  aws.String(tag.Key),
+				Value: aws.String(tag.Value),
+			})
+		}
+	}
+
+	for _, tag := range tags {
+		if len(dimensions) >= maxDimensions {
+			break
+		}
+		// CloudWatch rejects dimensions with empty values.
+		if tag.Key == "host" || tag.Value == "" || !c.includeDimension(tag.Key) {
+			continue
+		}
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String(tag.Key),
+			Value: aws.String(tag.Value),
+		})
+	}
+
+	return dimensions
+}
+
+func (c *CloudWatch) includeDimension(key string) bool {
+	for _, k := range c.DimensionExclude {
+		if k == key {
+			return false
+		}
+	}
+	if len(c.DimensionInclude) == 0 {
+		return true
+	}
+	for _, k := range c.DimensionInclude {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// convert returns the float64 representation of a field value. Values that
+// CloudWatch cannot represent are skipped.
+func convert(v interface{}) (float64, bool) {
+	var f float64
+	switch t := v.(type) {
+	case int64:
+		f = float64(t)
+	case uint64:
+		f = float64(t)
+	case float64:
+		f = t
+	case bool:
+		if t {
+			f = 1
+		}
+	default:
+		return 0, false
+	}
+
+	// CloudWatch rejects values that are NaN, infinite, or outside the
+	// range [-2^360, 2^360].
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	if f > math.Pow(2, 360) || f < -math.Pow(2, 360) {
+		return 0, false
+	}
+	// Values too close to zero are rounded to zero.
+	if f != 0 && math.Abs(f) < math.Pow(2, -260) {
+		f = 0
+	}
+
+	return f, true
+}
+
+func init() {
+	outputs.Add("cloudwatch", func() cua.Output {
+		return &CloudWatch{}
+	})
+}
@@ -0,0 +1,157 @@
+package cloudwatch
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCloudWatchClient struct {
+	calls []*cloudwatch.PutMetricDataInput
+}
+
+func (m *mockCloudWatchClient) PutMetricData(params *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	m.calls = append(m.calls, params)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func TestBuildDimensions(t *testing.T) {
+	c := &CloudWatch{}
+
+	tags := map[string]string{"host": "localhost"}
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"} {
+		tags[k] = "value"
+	}
+	tags["empty"] = ""
+	m := testutil.MustMetric("cpu", tags, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+
+	dimensions := c.buildDimensions(m.TagList())
+	require.Len(t, dimensions, maxDimensions)
+	require.Equal(t, "host", *dimensions[0].Name)
+	for _, d := range dimensions {
+		require.NotEqual(t, "empty", *d.Name)
+		require.NotEqual(t, "k", *d.Name)
+	}
+}
+
+func TestBuildDimensionsFilter(t *testing.T) {
+	c := &CloudWatch{
+		DimensionInclude: []string{"host", "region", "cpu"},
+		DimensionExclude: []string{"host"},
+	}
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost", "region": "us-east-1", "zone": "a"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0))
+
+	dimensions := c.buildDimensions(m.TagList())
+	require.Len(t, dimensions, 1)
+	require.Equal(t, "region", *dimensions[0].Name)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out float64
+		ok  bool
+	}{
+		{int64(42), 42, true},
+		{uint64(42), 42, true},
+		{1.5, 1.5, true},
+		{true, 1, true},
+		{false, 0, true},
+		{"string", 0, false},
+		{math.NaN(), 0, false},
+		{math.Inf(1), 0, false},
+		{math.Pow(2, 361), 0, false},
+		{math.Pow(2, -261), 0, true},
+	}
+	for _, tt := range tests {
+		out, ok := convert(tt.in)
+		require.Equal(t, tt.ok, ok, "%v", tt.in)
+		require.Equal(t, tt.out, out, "%v", tt.in)
+	}
+}
+
+func TestBuildMetricDatumResolution(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"usage": 1.0, "name": "skipped"},
+		time.Unix(0, 0))
+
+	c := &CloudWatch{}
+	datums := c.buildMetricDatum(m)
+	require.Len(t, datums, 1)
+	require.Equal(t, "cpu_usage", *datums[0].MetricName)
+	require.Equal(t, int64(standardResolution), *datums[0].StorageResolution)
+
+	c.HighResolutionMetrics = true
+	datums = c.buildMetricDatum(m)
+	require.Equal(t, int64(highResolution), *datums[0].StorageResolution)
+}
+
+func TestBuildMetricDatumStatistics(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"usage_max":   10.0,
+			"usage_min":   1.0,
+			"usage_sum":   20.0,
+			"usage_count": int64(4),
+			"idle_max":    5.0,
+		},
+		time.Unix(0, 0))
+
+	c := &CloudWatch{WriteStatistics: true}
+	datums := c.buildMetricDatum(m)
+	require.Len(t, datums, 2)
+
+	sort.Slice(datums, func(i, j int) bool { return *datums[i].MetricName < *datums[j].MetricName })
+
+	require.Equal(t, "cpu_idle_max", *datums[0].MetricName)
+	require.Equal(t, 5.0, *datums[0].Value)
+
+	require.Equal(t, "cpu_usage", *datums[1].MetricName)
+	require.Equal(t, &cloudwatch.StatisticSet{
+		Maximum:     aws.Float64(10),
+		Minimum:     aws.Float64(1),
+		Sum:         aws.Float64(20),
+		SampleCount: aws.Float64(4),
+	}, datums[1].StatisticValues)
+}
+
+func TestWriteBatches(t *testing.T) {
+	client := &mockCloudWatchClient{}
+	c := &CloudWatch{Namespace: "test", client: client}
+
+	metrics := make([]cua.Metric, 0, 45)
+	for i := 0; i < 45; i++ {
+		metrics = append(metrics, testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": float64(i)},
+			time.Unix(0, 0)))
+	}
+
+	n, err := c.Write(metrics)
+	require.NoError(t, err)
+	require.Equal(t, 45, n)
+	require.Len(t, client.calls, 3)
+	require.Len(t, client.calls[0].MetricData, maxDatumsPerCall)
+	require.Len(t, client.calls[2].MetricData, 5)
+	require.Equal(t, "test", *client.calls[0].Namespace)
+}
+
+func TestPartitionDatums(t *testing.T) {
+	datum := &cloudwatch.MetricDatum{}
+	require.Len(t, partitionDatums(2, []*cloudwatch.MetricDatum{}), 0)
+	require.Len(t, partitionDatums(2, []*cloudwatch.MetricDatum{datum}), 1)
+	require.Len(t, partitionDatums(2, []*cloudwatch.MetricDatum{datum, datum}), 1)
+	require.Len(t, partitionDatums(2, []*cloudwatch.MetricDatum{datum, datum, datum}), 2)
+}
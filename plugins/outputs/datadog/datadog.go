@@ -0,0 +1,268 @@
+package datadog
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/proxy"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+)
+
+const (
+	datadogAPI     = "https://app.datadoghq.com/api/v1/series"
+	defaultTimeout = 5 * time.Second
+	// maxPayloadSize is the limit of the compressed series payload.
+	maxPayloadSize = 3200000
+)
+
+// Datadog sends metrics to the Datadog series API.
+type Datadog struct {
+	Apikey      string            `toml:"apikey"`
+	Timeout     internal.Duration `toml:"timeout"`
+	URL         string            `toml:"url"`
+	Compression string            `toml:"compression"`
+	ConvertBool bool              `toml:"convert_bool"`
+	proxy.HTTPProxy
+
+	Log cua.Logger `toml:"-"`
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  ## Datadog API key
+  apikey = "my-secret-key"
+
+  ## Connection timeout.
+  # timeout = "5s"
+
+  ## Write URL override; useful for debugging or when the account is hosted
+  ## on another Datadog site, e.g. https://api.datadoghq.eu/api/v1/series
+  # url = "https://app.datadoghq.com/api/v1/series"
+
+  ## Set http_proxy
+  # http_proxy_url = "http://localhost:8888"
+
+  ## Compression of the request body, "zlib" or "none".
+  # compression = "zlib"
+
+  ## Send boolean fields as 0/1, otherwise they are dropped.
+  # convert_bool = true
+`
+
+type TimeSeries struct {
+	Series []*Metric `json:"series"`
+}
+
+type Metric struct {
+	Metric string   `json:"metric"`
+	Points [1]Point `json:"points"`
+	Host   string   `json:"host,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+type Point [2]float64
+
+func (d *Datadog) Connect() error {
+	if d.Apikey == "" {
+		return fmt.Errorf("apikey is a required field for datadog output")
+	}
+
+	switch d.Compression {
+	case "", "zlib", "none":
+	default:
+		return fmt.Errorf("invalid compression %q", d.Compression)
+	}
+
+	proxyFunc, err := d.Proxy()
+	if err != nil {
+		return fmt.Errorf("proxy: %w", err)
+	}
+
+	d.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy: proxyFunc,
+		},
+		Timeout: d.Timeout.Duration,
+	}
+	return nil
+}
+
+func (d *Datadog) Write(metrics []cua.Metric) (int, error) {
+	ts := TimeSeries{}
+	for _, m := range metrics {
+		host, tags := buildTags(m.TagList())
+		for _, field := range m.FieldList() {
+			value, ok := d.convertField(field.Value)
+			if !ok {
+				continue
+			}
+			ts.Series = append(ts.Series, &Metric{
+				Metric: m.Name() + "." + field.Key,
+				Points: [1]Point{{float64(m.Time().Unix()), value}},
+				Host:   host,
+				Tags:   tags,
+			})
+		}
+	}
+
+	if len(ts.Series) == 0 {
+		return len(metrics), nil
+	}
+
+	// Split the series so each request stays below the payload limit.
+	for _, batch := range splitSeries(ts.Series) {
+		if err := d.sendSeries(batch); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(metrics), nil
+}
+
+// splitSeries limits the number of series per request, larger payloads are
+// halved again when encoded.
+func splitSeries(series []*Metric) [][]*Metric {
+	const maxSeries = 10000
+	var batches [][]*Metric
+	for len(series) > maxSeries {
+		batches = append(batches, series[:maxSeries])
+		series = series[maxSeries:]
+	}
+	return append(batches, series)
+}
+
+// sendSeries sends the series, halving the batch while the encoded payload
+// is over the size limit.
+func (d *Datadog) sendSeries(series []*Metric) error {
+	body, err := d.encode(series)
+	if err != nil {
+		return err
+	}
+	if len(body) > maxPayloadSize && len(series) > 1 {
+		half := len(series) / 2
+		if err := d.sendSeries(series[:half]); err != nil {
+			return err
+		}
+		return d.sendSeries(series[half:])
+	}
+	return d.send(body)
+}
+
+func (d *Datadog) encode(series []*Metric) ([]byte, error) {
+	body, err := json.Marshal(TimeSeries{Series: series})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal TimeSeries: %w", err)
+	}
+
+	if d.Compression == "none" {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *Datadog) send(body []byte) error {
+	req, err := http.NewRequest("POST", d.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("unable to create http.Request: %w", err)
+	}
+	req.Header.Set("DD-API-KEY", d.Apikey)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	if d.Compression != "none" {
+		req.Header.Set("Content-Encoding", "deflate")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error POSTing metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("received bad status code, %d: %s", resp.StatusCode, respBody)
+	}
+
+	return nil
+}
+
+func (d *Datadog) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Datadog) Description() string {
+	return "Send metrics to the Datadog series API"
+}
+
+// buildTags returns the host tag, sent as the series host, and the other
+// tags in the "key:value" form.
+func buildTags(tagList []*cua.Tag) (string, []string) {
+	var host string
+	tags := make([]string, 0, len(tagList))
+	for _, tag := range tagList {
+		if tag.Key == "host" {
+			host = tag.Value
+			continue
+		}
+		tags = append(tags, tag.Key+":"+tag.Value)
+	}
+	return host, tags
+}
+
+func (d *Datadog) convertField(v interface{}) (float64, bool) {
+	var f float64
+	switch v := v.(type) {
+	case float64:
+		f = v
+	case uint64:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case bool:
+		if !d.ConvertBool {
+			return 0, false
+		}
+		if v {
+			f = 1
+		}
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func (d *Datadog) Close() error {
+	return nil
+}
+
+func init() {
+	outputs.Add("datadog", func() cua.Output {
+		return &Datadog{
+			URL:         datadogAPI,
+			Compression: "zlib",
+			ConvertBool: true,
+			Timeout:     internal.Duration{Duration: defaultTimeout},
+		}
+	})
+}
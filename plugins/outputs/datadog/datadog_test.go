@@ -0,0 +1,110 @@
+package datadog
+
+import (
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []cua.Metric {
+	return []cua.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "server1", "cpu": "cpu0"},
+			map[string]interface{}{
+				"usage_idle": 91.5,
+				"active":     true,
+				"state":      "ok",
+			},
+			time.Unix(1600000000, 0)),
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression string
+	}{
+		{name: "zlib", compression: "zlib"},
+		{name: "uncompressed", compression: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received TimeSeries
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "secret", r.Header.Get("DD-API-KEY"))
+				var body io.Reader = r.Body
+				if tt.compression == "zlib" {
+					require.Equal(t, "deflate", r.Header.Get("Content-Encoding"))
+					zr, err := zlib.NewReader(r.Body)
+					require.NoError(t, err)
+					body = zr
+				}
+				require.NoError(t, json.NewDecoder(body).Decode(&received))
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer ts.Close()
+
+			d := &Datadog{
+				Apikey:      "secret",
+				URL:         ts.URL,
+				Compression: tt.compression,
+				ConvertBool: true,
+			}
+			require.NoError(t, d.Connect())
+
+			n, err := d.Write(testMetrics())
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+
+			require.Len(t, received.Series, 2)
+			byName := map[string]*Metric{}
+			for _, s := range received.Series {
+				byName[s.Metric] = s
+			}
+			require.Equal(t, Point{1600000000, 91.5}, byName["cpu.usage_idle"].Points[0])
+			require.Equal(t, Point{1600000000, 1}, byName["cpu.active"].Points[0])
+			require.Equal(t, "server1", byName["cpu.usage_idle"].Host)
+			require.Equal(t, []string{"cpu:cpu0"}, byName["cpu.usage_idle"].Tags)
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	d := &Datadog{Apikey: "secret", URL: ts.URL}
+	require.NoError(t, d.Connect())
+
+	_, err := d.Write(testMetrics())
+	require.Error(t, err)
+}
+
+func TestConnectRequiresAPIKey(t *testing.T) {
+	d := &Datadog{URL: datadogAPI}
+	require.Error(t, d.Connect())
+}
+
+func TestConvertField(t *testing.T) {
+	d := &Datadog{}
+	_, ok := d.convertField(true)
+	require.False(t, ok)
+
+	v, ok := d.convertField(uint64(3))
+	require.True(t, ok)
+	require.Equal(t, 3.0, v)
+
+	_, ok = d.convertField("string")
+	require.False(t, ok)
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
@@ -95,6 +94,8 @@ type Health struct {
 
 	mu      sync.Mutex
 	healthy bool
+
+	Log cua.Logger `toml:"-"`
 }
 
 func (h *Health) SampleConfig() string {
@@ -160,14 +161,14 @@ func (h *Health) Connect() error {
 
 	h.origin = h.getOrigin(listener)
 
-	log.Printf("I! [outputs.health] Listening on %s", h.origin)
+	h.Log.Infof("Listening on %s", h.origin)
 
 	h.wg.Add(1)
 	go func() {
 		defer h.wg.Done()
 		err := h.server.Serve(listener)
 		if !errors.Is(err, http.ErrServerClosed) {
-			log.Printf("E! [outputs.health] Serve error on %s: %v", h.origin, err)
+			h.Log.Errorf("Serve error on %s: %v", h.origin, err)
 		}
 		h.origin = ""
 	}()
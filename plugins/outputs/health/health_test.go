@@ -104,6 +104,7 @@ func TestHealth(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			output := health.NewHealth()
+			output.Log = testutil.Logger{}
 			output.ServiceAddress = "tcp://127.0.0.1:0"
 			output.Compares = tt.options.Compares
 			output.Contains = tt.options.Contains
@@ -193,6 +194,7 @@ func TestInitServiceAddress(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			output := health.NewHealth()
+			output.Log = testutil.Logger{}
 			output.ServiceAddress = tt.plugin.ServiceAddress
 
 			err := output.Init()
@@ -0,0 +1,286 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+const sampleConfig = `
+  ## URL pinged while the agent is healthy, such as a healthchecks.io check.
+  url = "https://hc-ping.com/your-uuid"
+
+  ## URL pinged when the agent is unhealthy, to signal the failure without
+  ## waiting for the grace time of the check, with the reason in the body of
+  ## a POST.
+  # fail_url = "https://hc-ping.com/your-uuid/fail"
+
+  ## HTTP method of the pings, GET, HEAD or POST.
+  # method = "GET"
+
+  ## Interval of the pings.
+  # interval = "1m"
+
+  ## The agent is healthy when metrics were gathered, and written by the other
+  ## outputs, within max_age.
+  # max_age = "5m"
+
+  ## Outputs whose writes are checked, supports glob patterns, all the other
+  ## outputs when empty.
+  # outputs = ["circonus"]
+
+  ## Timeout of the pings.
+  # timeout = "5s"
+
+  ## Additional HTTP headers.
+  # [outputs.heartbeat.headers]
+  #   User-Agent = "circonus-unified-agent"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/circonus-unified-agent/ca.pem"
+  # tls_cert = "/etc/circonus-unified-agent/cert.pem"
+  # tls_key = "/etc/circonus-unified-agent/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## The metrics are discarded, drop them before they are buffered.
+  namedrop = ["*"]
+`
+
+// Heartbeat pings a URL while the pipeline of the agent is healthy, giving a
+// dead man's switch to the monitoring service.
+type Heartbeat struct {
+	URL      string            `toml:"url"`
+	FailURL  string            `toml:"fail_url"`
+	Method   string            `toml:"method"`
+	Interval internal.Duration `toml:"interval"`
+	MaxAge   internal.Duration `toml:"max_age"`
+	Outputs  []string          `toml:"outputs"`
+	Timeout  internal.Duration `toml:"timeout"`
+	Headers  map[string]string `toml:"headers"`
+	tls.ClientConfig
+	Log cua.Logger `toml:"-"`
+
+	client  *http.Client
+	outputs filter.Filter
+	// stats returns the metrics of the agent, replaced in tests
+	stats  func() []cua.Metric
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// counters of the previous check and when they last increased
+	gathered   int64
+	written    int64
+	lastGather time.Time
+	lastWrite  time.Time
+}
+
+func (h *Heartbeat) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *Heartbeat) Description() string {
+	return "Ping a URL while the agent gathers and writes metrics, as a dead man's switch"
+}
+
+func (h *Heartbeat) Init() error {
+	if h.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	for _, u := range []string{h.URL, h.FailURL} {
+		if u == "" {
+			continue
+		}
+		if _, err := url.Parse(u); err != nil {
+			return fmt.Errorf("url parse (%s): %w", u, err)
+		}
+	}
+	h.Method = strings.ToUpper(h.Method)
+	switch h.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+	default:
+		return fmt.Errorf("invalid method %q", h.Method)
+	}
+	if h.Interval.Duration <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	var err error
+	if h.outputs, err = filter.Compile(h.Outputs); err != nil {
+		return fmt.Errorf("outputs: %w", err)
+	}
+	return nil
+}
+
+func (h *Heartbeat) Connect() error {
+	tlsCfg, err := h.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+	h.client = &http.Client{
+		Transport: &http.Transport{
+			TLSHandshakeTimeout: 5 * time.Second,
+			TLSClientConfig:     tlsCfg,
+		},
+		Timeout: h.Timeout.Duration,
+	}
+
+	// the pipeline is given max_age to start
+	now := time.Now()
+	h.gathered, h.written = h.counters()
+	h.lastGather, h.lastWrite = now, now
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(h.Interval.Duration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				h.beat(ctx, now)
+			}
+		}
+	}()
+	return nil
+}
+
+func (h *Heartbeat) Close() error {
+	if h.cancel != nil {
+		h.cancel()
+		h.wg.Wait()
+	}
+	return nil
+}
+
+// Write discards the metrics, the health of the agent is read from its
+// internal statistics.
+func (h *Heartbeat) Write(metrics []cua.Metric) (int, error) {
+	return 0, nil
+}
+
+// beat pings the URL when the agent is healthy, the fail URL otherwise.
+func (h *Heartbeat) beat(ctx context.Context, now time.Time) {
+	reason := h.check(now)
+	if reason == "" {
+		if err := h.ping(ctx, h.URL, "OK"); err != nil && ctx.Err() == nil {
+			h.Log.Errorf("ping: %v", err)
+		}
+		return
+	}
+
+	h.Log.Warnf("Unhealthy, not pinging: %s", reason)
+	if h.FailURL != "" {
+		if err := h.ping(ctx, h.FailURL, reason); err != nil && ctx.Err() == nil {
+			h.Log.Errorf("ping fail url: %v", err)
+		}
+	}
+}
+
+// check returns why the agent is unhealthy, or an empty string when it is
+// healthy.
+func (h *Heartbeat) check(now time.Time) string {
+	gathered, written := h.counters()
+	if gathered != h.gathered {
+		h.lastGather = now
+	}
+	if written != h.written {
+		h.lastWrite = now
+	}
+	h.gathered, h.written = gathered, written
+
+	maxAge := h.MaxAge.Duration
+	switch {
+	case now.Sub(h.lastGather) > maxAge:
+		return fmt.Sprintf("no metrics gathered for %s", now.Sub(h.lastGather).Round(time.Second))
+	case now.Sub(h.lastWrite) > maxAge:
+		return fmt.Sprintf("no metrics written for %s", now.Sub(h.lastWrite).Round(time.Second))
+	}
+	return ""
+}
+
+// counters returns the number of metrics gathered by the agent and written
+// by the checked outputs.
+func (h *Heartbeat) counters() (gathered, written int64) {
+	for _, m := range h.stats() {
+		switch m.Name() {
+		case "internal_agent":
+			if v, ok := m.GetField("metrics_gathered"); ok {
+				gathered, _ = v.(int64)
+			}
+		case "internal_write":
+			output, _ := m.GetTag("output")
+			if output == "heartbeat" || (h.outputs != nil && !h.outputs.Match(output)) {
+				continue
+			}
+			if v, ok := m.GetField("metrics_written"); ok {
+				n, _ := v.(int64)
+				written += n
+			}
+		}
+	}
+	return gathered, written
+}
+
+func (h *Heartbeat) ping(ctx context.Context, u, body string) error {
+	var reader io.Reader
+	if h.Method == http.MethodPost {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, h.Method, u, reader)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	for k, v := range h.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+	if h.Method == http.MethodPost {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request (%s): %w", u, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("request (%s): %s", u, resp.Status)
+	}
+	return nil
+}
+
+func newHeartbeat() *Heartbeat {
+	return &Heartbeat{
+		Method:   http.MethodGet,
+		Interval: internal.Duration{Duration: time.Minute},
+		MaxAge:   internal.Duration{Duration: 5 * time.Minute},
+		Timeout:  internal.Duration{Duration: 5 * time.Second},
+		stats:    selfstat.Metrics,
+	}
+}
+
+func init() {
+	outputs.Add("heartbeat", func() cua.Output {
+		return newHeartbeat()
+	})
+}
@@ -0,0 +1,154 @@
+package heartbeat
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStats are the counters of the agent statistics.
+type fakeStats struct {
+	mu       sync.Mutex
+	gathered int64
+	written  map[string]int64
+}
+
+func (f *fakeStats) metrics() []cua.Metric {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	metrics := []cua.Metric{
+		testutil.MustMetric("internal_agent", map[string]string{},
+			map[string]interface{}{"metrics_gathered": f.gathered}, time.Now()),
+	}
+	for output, written := range f.written {
+		metrics = append(metrics, testutil.MustMetric("internal_write", map[string]string{"output": output},
+			map[string]interface{}{"metrics_written": written}, time.Now()))
+	}
+	return metrics
+}
+
+func (f *fakeStats) add(gathered int64, output string, written int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gathered += gathered
+	f.written[output] += written
+}
+
+func TestCheck(t *testing.T) {
+	stats := &fakeStats{written: map[string]int64{}}
+	h := newHeartbeat()
+	h.URL = "http://localhost/ping"
+	h.Outputs = []string{"circonus"}
+	h.stats = stats.metrics
+	require.NoError(t, h.Init())
+
+	start := time.Now()
+	h.gathered, h.written = h.counters()
+	h.lastGather, h.lastWrite = start, start
+
+	// healthy during the first max_age
+	require.Equal(t, "", h.check(start.Add(time.Minute)))
+
+	stats.add(10, "circonus", 10)
+	require.Equal(t, "", h.check(start.Add(5*time.Minute)))
+
+	// the writes of the other outputs are not checked
+	stats.add(10, "heartbeat", 10)
+	stats.add(0, "file", 10)
+	require.Equal(t, "", h.check(start.Add(7*time.Minute)))
+	require.Equal(t, "no metrics written for 6m0s", h.check(start.Add(11*time.Minute)))
+
+	stats.add(0, "circonus", 5)
+	require.Equal(t, "", h.check(start.Add(12*time.Minute)))
+	require.Equal(t, "no metrics gathered for 6m0s", h.check(start.Add(13*time.Minute)))
+}
+
+func TestInit(t *testing.T) {
+	h := newHeartbeat()
+	require.Error(t, h.Init())
+
+	h.URL = "http://localhost/ping"
+	h.Method = "put"
+	require.Error(t, h.Init())
+
+	h.Method = "post"
+	require.NoError(t, h.Init())
+	require.Equal(t, http.MethodPost, h.Method)
+}
+
+type pingServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	pings []string
+}
+
+func newPingServer() *pingServer {
+	s := &pingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.pings = append(s.pings, r.Method+" "+r.URL.Path+" "+string(body)+" "+r.Header.Get("X-Agent"))
+		s.mu.Unlock()
+	}))
+	return s
+}
+
+func (s *pingServer) firstPing(t *testing.T) string {
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.pings) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pings[0]
+}
+
+func newTestHeartbeat(t *testing.T, ts *pingServer, maxAge time.Duration) *Heartbeat {
+	h := newHeartbeat()
+	h.URL = ts.URL + "/ping"
+	h.FailURL = ts.URL + "/ping/fail"
+	h.Method = "POST"
+	h.Headers = map[string]string{"X-Agent": "cua"}
+	h.Interval = internal.Duration{Duration: 20 * time.Millisecond}
+	h.MaxAge = internal.Duration{Duration: maxAge}
+	h.Log = testutil.Logger{}
+	h.stats = (&fakeStats{written: map[string]int64{}}).metrics
+	require.NoError(t, h.Init())
+	return h
+}
+
+func TestHeartbeat(t *testing.T) {
+	ts := newPingServer()
+	defer ts.Close()
+
+	h := newTestHeartbeat(t, ts, time.Hour)
+	require.NoError(t, h.Connect())
+	defer h.Close()
+
+	n, err := h.Write([]cua.Metric{testutil.TestMetric(1)})
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	require.Equal(t, "POST /ping OK cua", ts.firstPing(t))
+}
+
+func TestHeartbeatUnhealthy(t *testing.T) {
+	ts := newPingServer()
+	defer ts.Close()
+
+	// unhealthy once max_age elapses without gathers
+	h := newTestHeartbeat(t, ts, time.Nanosecond)
+	require.NoError(t, h.Connect())
+	defer h.Close()
+
+	require.Regexp(t, `^POST /ping/fail no metrics gathered for \S+ cua$`, ts.firstPing(t))
+}
@@ -0,0 +1,355 @@
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+var sampleConfig = `
+  ## Broker URLs for the MQTT server or cluster.
+  ##   example: servers = ["tcp://localhost:1883"]
+  ##            servers = ["ssl://localhost:8883"]
+  servers = ["tcp://127.0.0.1:1883"]
+
+  ## Prefix of the topics, the metrics are published to
+  ## <topic_prefix>/<host tag>/<measurement>.
+  # topic_prefix = "circonus"
+
+  ## QoS policy for messages
+  ##   0 = at most once
+  ##   1 = at least once
+  ##   2 = exactly once
+  # qos = 0
+
+  ## Retain the messages published, ignored with sparkplug_b.
+  # retain = false
+
+  ## Timeout of the connection and of the publications.
+  # timeout = "5s"
+
+  ## If unset, a random client ID will be generated.
+  # client_id = ""
+
+  ## Username and password to connect MQTT server.
+  # username = "username"
+  # password = "metricsmetricsmetricsmetrics"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/circonus-unified-agent/ca.pem"
+  # tls_cert = "/etc/circonus-unified-agent/cert.pem"
+  # tls_key = "/etc/circonus-unified-agent/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Publish the metrics as a Sparkplug B edge node, for SCADA and IIoT
+  ## consumers, instead of with data_format.  The topics are
+  ## spBv1.0/<group_id>/<message type>/<edge_node_id>/<device>, the device
+  ## is the value of the device tag, or the measurement name.
+  # sparkplug_b = false
+  # sparkplug_group_id = "circonus"
+  ## Defaults to the hostname.
+  # sparkplug_edge_node_id = ""
+  # sparkplug_device_tag = ""
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/circonus-labs/circonus-unified-agent/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+type Client interface {
+	Connect() mqtt.Token
+	Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token
+	Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token
+	IsConnected() bool
+	Disconnect(quiesce uint)
+}
+
+type ClientFactory func(o *mqtt.ClientOptions) Client
+
+type MQTT struct {
+	Servers     []string          `toml:"servers"`
+	TopicPrefix string            `toml:"topic_prefix"`
+	QoS         int               `toml:"qos"`
+	Retain      bool              `toml:"retain"`
+	Timeout     internal.Duration `toml:"timeout"`
+	ClientID    string            `toml:"client_id"`
+	Username    string            `toml:"username"`
+	Password    string            `toml:"password"`
+	tls.ClientConfig
+
+	SparkplugB          bool   `toml:"sparkplug_b"`
+	SparkplugGroupID    string `toml:"sparkplug_group_id"`
+	SparkplugEdgeNodeID string `toml:"sparkplug_edge_node_id"`
+	SparkplugDeviceTag  string `toml:"sparkplug_device_tag"`
+
+	Log cua.Logger `toml:"-"`
+
+	clientFactory ClientFactory
+	serializer    serializers.Serializer
+
+	sync.Mutex
+	client Client
+	node   *sparkplugNode
+}
+
+func (m *MQTT) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *MQTT) Description() string {
+	return "Send metrics to MQTT, with the data format or as a Sparkplug B edge node"
+}
+
+func (m *MQTT) SetSerializer(serializer serializers.Serializer) {
+	m.serializer = serializer
+}
+
+func (m *MQTT) Init() error {
+	if len(m.Servers) == 0 {
+		return errors.New("no servers configured")
+	}
+	if m.QoS < 0 || m.QoS > 2 {
+		return fmt.Errorf("invalid qos %d, expected 0, 1 or 2", m.QoS)
+	}
+	if m.SparkplugB {
+		if m.SparkplugGroupID == "" {
+			return errors.New("sparkplug_group_id is required")
+		}
+		if m.SparkplugEdgeNodeID == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return fmt.Errorf("hostname: %w", err)
+			}
+			m.SparkplugEdgeNodeID = hostname
+		}
+		for _, id := range []string{m.SparkplugGroupID, m.SparkplugEdgeNodeID} {
+			if strings.ContainsAny(id, "/+#") {
+				return fmt.Errorf("invalid sparkplug identifier %q, the characters / + # are not allowed", id)
+			}
+		}
+		m.node = newSparkplugNode(m.SparkplugGroupID, m.SparkplugEdgeNodeID, m.SparkplugDeviceTag)
+	}
+	return nil
+}
+
+func (m *MQTT) Connect() error {
+	m.Lock()
+	defer m.Unlock()
+	return m.connect()
+}
+
+// connect connects to the broker, as a new session of the edge node with
+// sparkplug_b: the death certificate of the session is the will of the
+// connection, and the node subscribes to its commands.
+func (m *MQTT) connect() error {
+	opts, err := m.createOpts()
+	if err != nil {
+		return err
+	}
+	if m.node != nil {
+		m.node.newSession()
+		death := m.node.death()
+		opts.SetBinaryWill(death.topic, death.payload, 1, false)
+	}
+
+	client := m.clientFactory(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(m.Timeout.Duration) {
+		return errors.New("connect timed out")
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	m.client = client
+
+	if m.node != nil {
+		token := client.Subscribe(m.node.topic(spNodeCommand, ""), 1, m.onCommand)
+		if !token.WaitTimeout(m.Timeout.Duration) {
+			return errors.New("subscribe timed out")
+		}
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("subscribe: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *MQTT) createOpts() (*mqtt.ClientOptions, error) {
+	opts := mqtt.NewClientOptions()
+	opts.ConnectTimeout = m.Timeout.Duration
+
+	if m.ClientID == "" {
+		opts.SetClientID("Circonus-Output-" + internal.RandomString(5))
+	} else {
+		opts.SetClientID(m.ClientID)
+	}
+
+	tlsCfg, err := m.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("TLSConfig: %w", err)
+	}
+	if tlsCfg != nil {
+		opts.SetTLSConfig(tlsCfg)
+	}
+
+	if m.Username != "" {
+		opts.SetUsername(m.Username)
+	}
+	if m.Password != "" {
+		opts.SetPassword(m.Password)
+	}
+
+	for _, server := range m.Servers {
+		opts.AddBroker(server)
+	}
+	opts.SetAutoReconnect(false)
+	opts.SetKeepAlive(time.Second * 60)
+	opts.SetCleanSession(true)
+	return opts, nil
+}
+
+// onCommand publishes the birth certificates again when a rebirth of the
+// node is requested by a host application.
+func (m *MQTT) onCommand(_ mqtt.Client, msg mqtt.Message) {
+	rebirth, err := rebirthRequested(msg.Payload())
+	if err != nil {
+		m.Log.Errorf("Invalid command on %s: %v", msg.Topic(), err)
+		return
+	}
+	if !rebirth {
+		return
+	}
+	m.Log.Debugf("Rebirth requested on %s", msg.Topic())
+
+	// the births are not published from the handler of the client, which
+	// would wait on itself for the acknowledgements
+	go func() {
+		m.Lock()
+		defer m.Unlock()
+		if m.client == nil {
+			return
+		}
+		m.node.rebirth()
+		if err := m.publishSparkplug(m.node.encode(nil)); err != nil {
+			m.Log.Errorf("Rebirth: %v", err)
+		}
+	}()
+}
+
+func (m *MQTT) Close() error {
+	m.Lock()
+	defer m.Unlock()
+	if m.client == nil {
+		return nil
+	}
+	if m.node != nil && m.client.IsConnected() {
+		// the death certificate is published by the broker only when the
+		// connection is lost
+		death := m.node.death()
+		if err := m.publish(death.topic, 1, false, death.payload); err != nil {
+			m.Log.Errorf("Publish death certificate: %v", err)
+		}
+	}
+	m.client.Disconnect(200)
+	m.client = nil
+	return nil
+}
+
+func (m *MQTT) Write(metrics []cua.Metric) (int, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if len(metrics) == 0 {
+		return 0, nil
+	}
+	if m.client == nil || !m.client.IsConnected() {
+		if err := m.connect(); err != nil {
+			return 0, err
+		}
+	}
+
+	if m.node != nil {
+		if err := m.publishSparkplug(m.node.encode(metrics)); err != nil {
+			return 0, err
+		}
+		return len(metrics), nil
+	}
+
+	for i, metric := range metrics {
+		buf, err := m.serializer.Serialize(metric)
+		if err != nil {
+			m.Log.Debugf("Could not serialize metric: %v", err)
+			continue
+		}
+		if err := m.publish(m.topic(metric), byte(m.QoS), m.Retain, buf); err != nil {
+			return i, err
+		}
+	}
+	return len(metrics), nil
+}
+
+func (m *MQTT) publishSparkplug(msgs []spMessage) error {
+	for _, msg := range msgs {
+		if err := m.publish(msg.topic, byte(m.QoS), false, msg.payload); err != nil {
+			// the sequence numbers of the messages following the failed one
+			// are lost, the consumers request a rebirth to recover
+			return err
+		}
+	}
+	return nil
+}
+
+// topic returns the topic of the metric, <topic_prefix>/<host>/<measurement>.
+func (m *MQTT) topic(metric cua.Metric) string {
+	parts := []string{}
+	if m.TopicPrefix != "" {
+		parts = append(parts, m.TopicPrefix)
+	}
+	if host, ok := metric.GetTag("host"); ok {
+		parts = append(parts, host)
+	}
+	parts = append(parts, metric.Name())
+	return strings.Join(parts, "/")
+}
+
+func (m *MQTT) publish(topic string, qos byte, retain bool, payload []byte) error {
+	token := m.client.Publish(topic, qos, retain, payload)
+	if !token.WaitTimeout(m.Timeout.Duration) {
+		return fmt.Errorf("publish to %s timed out", topic)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+func New(factory ClientFactory) *MQTT {
+	return &MQTT{
+		Servers:          []string{"tcp://127.0.0.1:1883"},
+		TopicPrefix:      "circonus",
+		Timeout:          internal.Duration{Duration: 5 * time.Second},
+		SparkplugGroupID: "circonus",
+		clientFactory:    factory,
+	}
+}
+
+func init() {
+	outputs.Add("mqtt", func() cua.Output {
+		return New(func(o *mqtt.ClientOptions) Client {
+			return mqtt.NewClient(o)
+		})
+	})
+}
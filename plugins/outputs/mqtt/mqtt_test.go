@@ -0,0 +1,252 @@
+package mqtt
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/influx"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeToken struct {
+	err error
+}
+
+func (t *fakeToken) Wait() bool                     { return true }
+func (t *fakeToken) WaitTimeout(time.Duration) bool { return true }
+func (t *fakeToken) Error() error                   { return t.err }
+
+type message struct {
+	topic   string
+	payload []byte
+}
+
+type fakeClient struct {
+	sync.Mutex
+	opts      *mqtt.ClientOptions
+	connected bool
+	published []message
+	handlers  map[string]mqtt.MessageHandler
+}
+
+func (c *fakeClient) Connect() mqtt.Token {
+	c.connected = true
+	return &fakeToken{}
+}
+
+func (c *fakeClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	c.Lock()
+	defer c.Unlock()
+	c.published = append(c.published, message{topic: topic, payload: payload.([]byte)})
+	return &fakeToken{}
+}
+
+func (c *fakeClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.handlers[topic] = callback
+	return &fakeToken{}
+}
+
+func (c *fakeClient) IsConnected() bool {
+	return c.connected
+}
+
+func (c *fakeClient) Disconnect(quiesce uint) {
+	c.connected = false
+}
+
+func (c *fakeClient) take() []message {
+	c.Lock()
+	defer c.Unlock()
+	published := c.published
+	c.published = nil
+	return published
+}
+
+type fakeMessage struct {
+	mqtt.Message
+	topic   string
+	payload []byte
+}
+
+func (m *fakeMessage) Topic() string   { return m.topic }
+func (m *fakeMessage) Payload() []byte { return m.payload }
+
+func newTestMQTT() (*MQTT, *fakeClient) {
+	client := &fakeClient{handlers: map[string]mqtt.MessageHandler{}}
+	m := New(func(o *mqtt.ClientOptions) Client {
+		client.opts = o
+		return client
+	})
+	m.Log = testutil.Logger{}
+	return m, client
+}
+
+func decode(t *testing.T, msg message) *spPayload {
+	p, err := unmarshalPayload(msg.payload)
+	require.NoError(t, err)
+	return p
+}
+
+func TestWrite(t *testing.T) {
+	m, client := newTestMQTT()
+	m.SetSerializer(influx.NewSerializer())
+	require.NoError(t, m.Init())
+	require.NoError(t, m.Connect())
+
+	metrics := []cua.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "server1"},
+			map[string]interface{}{"usage_idle": 91.5},
+			time.Unix(0, 0)),
+	}
+	n, err := m.Write(metrics)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	published := client.take()
+	require.Len(t, published, 1)
+	require.Equal(t, "circonus/server1/cpu", published[0].topic)
+	require.Equal(t, "cpu,host=server1 usage_idle=91.5 0\n", string(published[0].payload))
+}
+
+func TestSparkplugB(t *testing.T) {
+	m, client := newTestMQTT()
+	m.SparkplugB = true
+	m.SparkplugGroupID = "plant1"
+	m.SparkplugEdgeNodeID = "agent1"
+	m.SparkplugDeviceTag = "device"
+	require.NoError(t, m.Init())
+	require.NoError(t, m.Connect())
+
+	// the death certificate of the session is the will of the connection
+	require.Equal(t, "spBv1.0/plant1/NDEATH/agent1", client.opts.WillTopic)
+	death, err := unmarshalPayload(client.opts.WillPayload)
+	require.NoError(t, err)
+	require.Equal(t, []spMetric{{name: "bdSeq", datatype: spUInt64, value: uint64(0)}}, death.metrics)
+	require.Contains(t, client.handlers, "spBv1.0/plant1/NCMD/agent1")
+
+	_, err = m.Write([]cua.Metric{
+		testutil.MustMetric("modbus",
+			map[string]string{"device": "plc1"},
+			map[string]interface{}{"running": true},
+			time.Unix(10, 0)),
+		testutil.MustMetric("modbus",
+			map[string]string{"device": "plc1"},
+			map[string]interface{}{"temperature": 21.5},
+			time.Unix(10, 0)),
+	})
+	require.NoError(t, err)
+
+	published := client.take()
+	require.Len(t, published, 2)
+	require.Equal(t, "spBv1.0/plant1/NBIRTH/agent1", published[0].topic)
+	nbirth := decode(t, published[0])
+	require.Equal(t, uint64(0), nbirth.seq)
+	require.Equal(t, []spMetric{
+		{name: "bdSeq", datatype: spUInt64, value: uint64(0)},
+		{name: "Node Control/Rebirth", datatype: spBoolean, value: false},
+	}, nbirth.metrics)
+
+	require.Equal(t, "spBv1.0/plant1/DBIRTH/agent1/plc1", published[1].topic)
+	dbirth := decode(t, published[1])
+	require.Equal(t, uint64(1), dbirth.seq)
+	require.Equal(t, []spMetric{
+		{name: "modbus/running", alias: 1, timestamp: 10000, datatype: spBoolean, value: true},
+		{name: "modbus/temperature", alias: 2, timestamp: 10000, datatype: spDouble, value: 21.5},
+	}, dbirth.metrics)
+
+	// once born, the metrics are published by alias, an integer is
+	// converted to the data type of the metric
+	_, err = m.Write([]cua.Metric{
+		testutil.MustMetric("modbus",
+			map[string]string{"device": "plc1"},
+			map[string]interface{}{"temperature": int64(22)},
+			time.Unix(20, 0)),
+	})
+	require.NoError(t, err)
+	published = client.take()
+	require.Len(t, published, 1)
+	require.Equal(t, "spBv1.0/plant1/DDATA/agent1/plc1", published[0].topic)
+	ddata := decode(t, published[0])
+	require.Equal(t, uint64(2), ddata.seq)
+	require.Equal(t, []spMetric{
+		{alias: 2, timestamp: 20000, datatype: spDouble, value: float64(22)},
+	}, ddata.metrics)
+
+	// a new metric of the device is published with a new birth certificate
+	_, err = m.Write([]cua.Metric{
+		testutil.MustMetric("modbus",
+			map[string]string{"device": "plc1"},
+			map[string]interface{}{"pressure": int64(3)},
+			time.Unix(30, 0)),
+	})
+	require.NoError(t, err)
+	published = client.take()
+	require.Len(t, published, 1)
+	require.Equal(t, "spBv1.0/plant1/DBIRTH/agent1/plc1", published[0].topic)
+	dbirth = decode(t, published[0])
+	require.Equal(t, uint64(3), dbirth.seq)
+	require.Len(t, dbirth.metrics, 3)
+	require.Equal(t, spMetric{name: "modbus/pressure", alias: 3, timestamp: 30000, datatype: spInt64, value: int64(3)}, dbirth.metrics[0])
+}
+
+func TestSparkplugBRebirth(t *testing.T) {
+	m, client := newTestMQTT()
+	m.SparkplugB = true
+	m.SparkplugEdgeNodeID = "agent1"
+	require.NoError(t, m.Init())
+	require.NoError(t, m.Connect())
+
+	_, err := m.Write([]cua.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5},
+			time.Unix(10, 0)),
+	})
+	require.NoError(t, err)
+	published := client.take()
+	require.Len(t, published, 2)
+	require.Equal(t, "spBv1.0/circonus/DBIRTH/agent1/cpu", published[1].topic)
+	require.Equal(t, "cpu0/usage_idle", decode(t, published[1]).metrics[0].name)
+
+	cmd := spPayload{metrics: []spMetric{{name: "Node Control/Rebirth", datatype: spBoolean, value: true}}}
+	handler := client.handlers["spBv1.0/circonus/NCMD/agent1"]
+	handler(nil, &fakeMessage{topic: "spBv1.0/circonus/NCMD/agent1", payload: cmd.marshal()})
+
+	require.Eventually(t, func() bool {
+		client.Lock()
+		defer client.Unlock()
+		return len(client.published) == 2
+	}, time.Second, 10*time.Millisecond)
+	published = client.take()
+	require.Equal(t, "spBv1.0/circonus/NBIRTH/agent1", published[0].topic)
+	require.Equal(t, uint64(0), decode(t, published[0]).seq)
+	require.Equal(t, "spBv1.0/circonus/DBIRTH/agent1/cpu", published[1].topic)
+	require.Equal(t, uint64(1), decode(t, published[1]).seq)
+
+	// a new session has the next birth/death sequence number
+	require.NoError(t, m.Close())
+	published = client.take()
+	require.Len(t, published, 1)
+	require.Equal(t, "spBv1.0/circonus/NDEATH/agent1", published[0].topic)
+
+	require.NoError(t, m.Connect())
+	death, err := unmarshalPayload(client.opts.WillPayload)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), death.metrics[0].value)
+}
+
+func TestInitInvalid(t *testing.T) {
+	m, _ := newTestMQTT()
+	m.QoS = 3
+	require.Error(t, m.Init())
+
+	m, _ = newTestMQTT()
+	m.SparkplugB = true
+	m.SparkplugEdgeNodeID = "agent/1"
+	require.Error(t, m.Init())
+}
@@ -0,0 +1,511 @@
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Sparkplug B data types of the metrics
+const (
+	spInt64   uint32 = 4
+	spUInt64  uint32 = 8
+	spDouble  uint32 = 10
+	spBoolean uint32 = 11
+	spString  uint32 = 12
+)
+
+// Sparkplug B message types
+const (
+	spNodeBirth   = "NBIRTH"
+	spNodeDeath   = "NDEATH"
+	spNodeCommand = "NCMD"
+	spDeviceBirth = "DBIRTH"
+	spDeviceData  = "DDATA"
+)
+
+const (
+	spNamespace = "spBv1.0"
+	spBdSeq     = "bdSeq"
+	spRebirth   = "Node Control/Rebirth"
+)
+
+// spMetric is a metric of a Sparkplug B payload.  Once the birth certificate
+// is published the metric is only identified by its alias.
+type spMetric struct {
+	name      string
+	alias     uint64
+	timestamp uint64
+	datatype  uint32
+	value     interface{} // int64, uint64, float64, bool or string
+}
+
+// spPayload is a Sparkplug B payload, encoded as the Payload protobuf message
+// of sparkplug_b.proto.
+type spPayload struct {
+	timestamp uint64
+	seq       uint64
+	metrics   []spMetric
+}
+
+func (p *spPayload) marshal() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, p.timestamp)
+	for i := range p.metrics {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, p.metrics[i].marshal())
+	}
+	b = protowire.AppendTag(b, 3, protowire.VarintType)
+	b = protowire.AppendVarint(b, p.seq)
+	return b
+}
+
+func (m *spMetric) marshal() []byte {
+	var b []byte
+	if m.name != "" {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, m.name)
+	}
+	if m.alias != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, m.alias)
+	}
+	if m.timestamp != 0 {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, m.timestamp)
+	}
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(m.datatype))
+
+	switch v := m.value.(type) {
+	case int64:
+		b = protowire.AppendTag(b, 11, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	case uint64:
+		b = protowire.AppendTag(b, 11, protowire.VarintType)
+		b = protowire.AppendVarint(b, v)
+	case float64:
+		b = protowire.AppendTag(b, 13, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	case bool:
+		b = protowire.AppendTag(b, 14, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case string:
+		b = protowire.AppendTag(b, 15, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	}
+	return b
+}
+
+// unmarshalPayload decodes the fields of a payload used by the agent, the
+// other fields are skipped.
+func unmarshalPayload(b []byte) (*spPayload, error) {
+	p := &spPayload{}
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			p.timestamp = v
+		case num == 3 && typ == protowire.VarintType:
+			p.seq = v
+		case num == 2 && typ == protowire.BytesType:
+			m, err := unmarshalMetric(data)
+			if err != nil {
+				return err
+			}
+			p.metrics = append(p.metrics, *m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func unmarshalMetric(b []byte) (*spMetric, error) {
+	m := &spMetric{}
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			m.name = string(data)
+		case 2:
+			m.alias = v
+		case 3:
+			m.timestamp = v
+		case 4:
+			m.datatype = uint32(v)
+		case 10, 11:
+			if m.datatype == spUInt64 {
+				m.value = v
+			} else {
+				m.value = int64(v)
+			}
+		case 12:
+			m.value = float64(math.Float32frombits(uint32(v)))
+		case 13:
+			m.value = math.Float64frombits(v)
+		case 14:
+			m.value = protowire.DecodeBool(v)
+		case 15:
+			m.value = string(data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// consumeFields calls fn with the fields of a protobuf message, v holds the
+// value of the varint and fixed fields and data the bytes of the length
+// delimited fields.
+func consumeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("consume tag: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var v uint64
+		var data []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("consume field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spMessage is a message to publish.
+type spMessage struct {
+	topic   string
+	payload []byte
+}
+
+type spDevice struct {
+	id      string
+	metrics map[string]*spMetric
+	born    bool
+}
+
+// sparkplugNode is the state of the agent as a Sparkplug B edge node: the
+// sequence numbers, the aliases of the metrics and the devices born.
+type sparkplugNode struct {
+	groupID   string
+	nodeID    string
+	deviceTag string
+
+	bdSeq     uint64
+	seq       uint64
+	started   bool
+	born      bool
+	nextAlias uint64
+	devices   map[string]*spDevice
+}
+
+func newSparkplugNode(groupID, nodeID, deviceTag string) *sparkplugNode {
+	return &sparkplugNode{
+		groupID:   groupID,
+		nodeID:    nodeID,
+		deviceTag: deviceTag,
+		nextAlias: 1,
+		devices:   make(map[string]*spDevice),
+	}
+}
+
+func (n *sparkplugNode) topic(messageType, deviceID string) string {
+	topic := spNamespace + "/" + n.groupID + "/" + messageType + "/" + n.nodeID
+	if deviceID != "" {
+		topic += "/" + deviceID
+	}
+	return topic
+}
+
+// newSession starts a new session with the next birth/death sequence number,
+// the node and all the devices are born again.
+func (n *sparkplugNode) newSession() {
+	if n.started {
+		n.bdSeq = (n.bdSeq + 1) % 256
+	}
+	n.started = true
+	n.rebirth()
+}
+
+// rebirth publishes the birth certificates of the node and the devices with
+// the next messages.
+func (n *sparkplugNode) rebirth() {
+	n.born = false
+	for _, d := range n.devices {
+		d.born = false
+	}
+}
+
+// death returns the death certificate of the node, registered as the will of
+// the connection.
+func (n *sparkplugNode) death() spMessage {
+	p := spPayload{
+		timestamp: uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+		metrics:   []spMetric{{name: spBdSeq, datatype: spUInt64, value: n.bdSeq}},
+	}
+	// the death certificate has no sequence number, seq is left to 0
+	return spMessage{topic: n.topic(spNodeDeath, ""), payload: p.marshal()}
+}
+
+func (n *sparkplugNode) nextSeq() uint64 {
+	seq := n.seq
+	n.seq = (n.seq + 1) % 256
+	return seq
+}
+
+// births returns the birth certificates of the node and of the devices which
+// are not born, when the node is not born.
+func (n *sparkplugNode) births(now uint64) []spMessage {
+	if n.born {
+		return nil
+	}
+	n.born = true
+	n.seq = 0
+	p := spPayload{
+		timestamp: now,
+		seq:       n.nextSeq(),
+		metrics: []spMetric{
+			{name: spBdSeq, datatype: spUInt64, value: n.bdSeq},
+			{name: spRebirth, datatype: spBoolean, value: false},
+		},
+	}
+	msgs := []spMessage{{topic: n.topic(spNodeBirth, ""), payload: p.marshal()}}
+
+	ids := make([]string, 0, len(n.devices))
+	for id := range n.devices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if d := n.devices[id]; len(d.metrics) > 0 {
+			msgs = append(msgs, n.deviceBirth(d, now))
+		}
+	}
+	return msgs
+}
+
+// deviceBirth returns the birth certificate of the device, with the names,
+// aliases, data types and last values of all its metrics.
+func (n *sparkplugNode) deviceBirth(d *spDevice, now uint64) spMessage {
+	names := make([]string, 0, len(d.metrics))
+	for name := range d.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	p := spPayload{timestamp: now, seq: n.nextSeq()}
+	for _, name := range names {
+		p.metrics = append(p.metrics, *d.metrics[name])
+	}
+	d.born = true
+	return spMessage{topic: n.topic(spDeviceBirth, d.id), payload: p.marshal()}
+}
+
+// encode returns the messages publishing the metrics: the birth certificates
+// when needed, and a data message per device.  A device is born again when a
+// new metric is published for it, the birth certificate holding all the
+// metrics of the device.
+func (n *sparkplugNode) encode(metrics []cua.Metric) []spMessage {
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	msgs := n.births(now)
+
+	var order []string
+	data := make(map[string][]spMetric)
+	for _, metric := range metrics {
+		id, prefix := n.deviceAndPrefix(metric)
+		d, ok := n.devices[id]
+		if !ok {
+			d = &spDevice{id: id, metrics: make(map[string]*spMetric)}
+			n.devices[id] = d
+		}
+		if _, ok := data[id]; !ok {
+			order = append(order, id)
+			data[id] = nil
+		}
+
+		ts := uint64(metric.Time().UnixNano() / int64(time.Millisecond))
+		for _, field := range metric.FieldList() {
+			name := prefix + field.Key
+			m, ok := d.metrics[name]
+			if !ok {
+				datatype, ok := dataType(field.Value)
+				if !ok {
+					continue
+				}
+				m = &spMetric{name: name, alias: n.nextAlias, datatype: datatype}
+				n.nextAlias++
+				d.metrics[name] = m
+				d.born = false
+			}
+			value, ok := convertValue(m.datatype, field.Value)
+			if !ok {
+				continue
+			}
+			m.value = value
+			m.timestamp = ts
+			data[id] = append(data[id], spMetric{alias: m.alias, timestamp: ts, datatype: m.datatype, value: value})
+		}
+	}
+
+	for _, id := range order {
+		d := n.devices[id]
+		if !d.born {
+			if len(d.metrics) > 0 {
+				msgs = append(msgs, n.deviceBirth(d, now))
+			}
+			continue
+		}
+		if len(data[id]) == 0 {
+			continue
+		}
+		p := spPayload{timestamp: now, seq: n.nextSeq(), metrics: data[id]}
+		msgs = append(msgs, spMessage{topic: n.topic(spDeviceData, id), payload: p.marshal()})
+	}
+	return msgs
+}
+
+// deviceAndPrefix returns the device of the metric, the value of the device
+// tag or the measurement name, and the prefix of the names of its metrics:
+// the measurement name, unless it is the device, and the values of the other
+// tags in the order of their keys.
+func (n *sparkplugNode) deviceAndPrefix(metric cua.Metric) (string, string) {
+	var parts []string
+	device, ok := metric.GetTag(n.deviceTag)
+	if n.deviceTag == "" || !ok {
+		device = metric.Name()
+	} else {
+		parts = append(parts, metric.Name())
+	}
+	for _, tag := range metric.TagList() {
+		if tag.Key != n.deviceTag {
+			parts = append(parts, tag.Value)
+		}
+	}
+	if len(parts) == 0 {
+		return sanitizeID(device), ""
+	}
+	return sanitizeID(device), strings.Join(parts, "/") + "/"
+}
+
+// sanitizeID replaces the characters not allowed in the identifiers of the
+// topics.
+func sanitizeID(id string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(id)
+}
+
+// dataType returns the Sparkplug B data type of the value.
+func dataType(v interface{}) (uint32, bool) {
+	switch v.(type) {
+	case int64:
+		return spInt64, true
+	case uint64:
+		return spUInt64, true
+	case float64:
+		return spDouble, true
+	case bool:
+		return spBoolean, true
+	case string:
+		return spString, true
+	}
+	return 0, false
+}
+
+// convertValue converts the value to the data type of the metric, the data
+// type of a metric can't change once it is born.  Numbers are converted to
+// the numeric data types.
+func convertValue(datatype uint32, v interface{}) (interface{}, bool) {
+	switch datatype {
+	case spInt64:
+		switch v := v.(type) {
+		case int64:
+			return v, true
+		case uint64:
+			if v <= math.MaxInt64 {
+				return int64(v), true
+			}
+		case float64:
+			if v >= math.MinInt64 && v <= math.MaxInt64 {
+				return int64(v), true
+			}
+		}
+	case spUInt64:
+		switch v := v.(type) {
+		case uint64:
+			return v, true
+		case int64:
+			if v >= 0 {
+				return uint64(v), true
+			}
+		case float64:
+			if v >= 0 && v <= math.MaxUint64 {
+				return uint64(v), true
+			}
+		}
+	case spDouble:
+		switch v := v.(type) {
+		case float64:
+			return v, true
+		case int64:
+			return float64(v), true
+		case uint64:
+			return float64(v), true
+		}
+	case spBoolean:
+		v, ok := v.(bool)
+		return v, ok
+	case spString:
+		v, ok := v.(string)
+		return v, ok
+	}
+	return nil, false
+}
+
+// rebirthRequested returns true when the command payload requests the node
+// to publish its birth certificates again.
+func rebirthRequested(payload []byte) (bool, error) {
+	p, err := unmarshalPayload(payload)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range p.metrics {
+		if m.name == spRebirth {
+			v, ok := m.value.(bool)
+			if !ok {
+				return false, errors.New("rebirth command value is not a boolean")
+			}
+			return v, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,588 @@
+package stackdriver
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	monitoring "cloud.google.com/go/monitoring/apiv3"
+	"github.com/circonus-labs/circonus-unified-agent/config"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+	googlepbts "github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/api/option"
+	labelpb "google.golang.org/genproto/googleapis/api/label"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxTimeSeriesPerRequest is the maximum number of time series a single
+	// CreateTimeSeries request may contain.
+	maxTimeSeriesPerRequest = 200
+	// maxLabels is the maximum number of labels of a custom metric.
+	maxLabels = 30
+	// maxLabelKeyLength and maxLabelValueLength are the maximum lengths of
+	// the labels of a custom metric.
+	maxLabelKeyLength   = 100
+	maxLabelValueLength = 1024
+
+	defaultNamespace         = "circonus"
+	defaultResourceType      = "global"
+	defaultRequestsPerMinute = 6000
+)
+
+// requiredResourceLabels are the labels of the monitored resources types
+// that must be set, besides project_id.
+var requiredResourceLabels = map[string][]string{
+	"global":        nil,
+	"gce_instance":  {"instance_id", "zone"},
+	"k8s_container": {"location", "cluster_name", "namespace_name", "pod_name", "container_name"},
+}
+
+// Stackdriver contains the configuration for the stackdriver output plugin.
+type Stackdriver struct {
+	Project           string            `toml:"project"`
+	ProjectTag        string            `toml:"project_tag"`
+	Namespace         string            `toml:"namespace"`
+	CredentialsFile   string            `toml:"credentials_file"`
+	ResourceType      string            `toml:"resource_type"`
+	ResourceLabels    map[string]string `toml:"resource_labels"`
+	ResourceTags      map[string]string `toml:"resource_tags"`
+	CreateDescriptors bool              `toml:"create_metric_descriptors"`
+	RequestsPerMinute int               `toml:"requests_per_minute"`
+	Timeout           config.Duration   `toml:"timeout"`
+
+	Log cua.Logger `toml:"-"`
+
+	client metricClient
+	// start is the start time of the cumulative series
+	start time.Time
+	// descriptors are the label keys of the metric descriptors created, by
+	// project and metric type
+	descriptors map[string]map[string]bool
+	quotas      map[string]*quota
+}
+
+// metricClient is convenient for testing
+type metricClient interface {
+	CreateTimeSeries(ctx context.Context, req *monitoringpb.CreateTimeSeriesRequest) error
+	CreateMetricDescriptor(ctx context.Context, req *monitoringpb.CreateMetricDescriptorRequest) error
+	Close() error
+}
+
+// stackdriverMetricClient is a metric client for Cloud Monitoring
+type stackdriverMetricClient struct {
+	conn *monitoring.MetricClient
+}
+
+// CreateTimeSeries implements metricClient interface
+func (c *stackdriverMetricClient) CreateTimeSeries(ctx context.Context, req *monitoringpb.CreateTimeSeriesRequest) error {
+	return c.conn.CreateTimeSeries(ctx, req) //nolint:wrapcheck // the status of the error is checked
+}
+
+// CreateMetricDescriptor implements metricClient interface
+func (c *stackdriverMetricClient) CreateMetricDescriptor(ctx context.Context, req *monitoringpb.CreateMetricDescriptorRequest) error {
+	_, err := c.conn.CreateMetricDescriptor(ctx, req)
+	return err //nolint:wrapcheck // the status of the error is checked
+}
+
+// Close implements metricClient interface
+func (c *stackdriverMetricClient) Close() error {
+	return c.conn.Close() //nolint:wrapcheck
+}
+
+var sampleConfig = `
+  ## GCP Project, the metrics are written to this project unless the
+  ## project_tag is set on the metric.
+  project = "erudite-bloom-151019"
+
+  ## Tag holding the project of the metric, to write to several projects.
+  ## The tag is not written as a label.
+  # project_tag = ""
+
+  ## The namespace of the metrics, their type is
+  ## custom.googleapis.com/<namespace>/<measurement>_<field>.
+  # namespace = "circonus"
+
+  ## Path to the service account key file, the Application Default
+  ## Credentials are used when empty.
+  # credentials_file = ""
+
+  ## Monitored resource type of the metrics, such as global, gce_instance or
+  ## k8s_container, and its labels.  The project_id label is always set.
+  # resource_type = "global"
+  # [outputs.stackdriver.resource_labels]
+  #   zone = "us-central1-a"
+
+  ## Resource labels read from the tags of the metrics, overriding the
+  ## resource_labels.  The tags are not written as metric labels.
+  # [outputs.stackdriver.resource_tags]
+  #   instance_id = "instance_id"
+
+  ## Create the metric descriptors of the metrics, with their labels, kind
+  ## and value type, before writing them.  Otherwise they are created
+  ## implicitly by the API.
+  # create_metric_descriptors = false
+
+  ## Maximum number of write requests per minute and per project, the
+  ## metrics are kept in the buffer when the quota is exhausted.
+  # requests_per_minute = 6000
+
+  ## Timeout of the requests.
+  # timeout = "10s"
+`
+
+// SampleConfig returns the default configuration of the stackdriver output plugin.
+func (s *Stackdriver) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a one-sentence description of the stackdriver output plugin.
+func (s *Stackdriver) Description() string {
+	return "Configuration for Google Cloud Monitoring (Stackdriver) to send metrics to"
+}
+
+// Init validates the plugin configuration.
+func (s *Stackdriver) Init() error {
+	if s.Project == "" {
+		return fmt.Errorf("project is required")
+	}
+	if s.Namespace == "" {
+		s.Namespace = defaultNamespace
+	}
+	if s.ResourceType == "" {
+		s.ResourceType = defaultResourceType
+	}
+	if s.RequestsPerMinute <= 0 {
+		s.RequestsPerMinute = defaultRequestsPerMinute
+	}
+	if s.Timeout == 0 {
+		s.Timeout = config.Duration(10 * time.Second)
+	}
+	for _, label := range requiredResourceLabels[s.ResourceType] {
+		_, static := s.ResourceLabels[label]
+		_, tagged := s.ResourceTags[label]
+		if !static && !tagged {
+			return fmt.Errorf("resource type %s requires the %s label in resource_labels or resource_tags", s.ResourceType, label)
+		}
+	}
+	s.descriptors = make(map[string]map[string]bool)
+	s.quotas = make(map[string]*quota)
+	return nil
+}
+
+// Connect creates the Cloud Monitoring client.
+func (s *Stackdriver) Connect() error {
+	s.start = time.Now()
+	if s.client != nil {
+		return nil
+	}
+
+	opts := []option.ClientOption{option.WithUserAgent(internal.ProductToken())}
+	if s.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(s.CredentialsFile))
+	}
+	client, err := monitoring.NewMetricClient(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create stackdriver monitoring client: %w", err)
+	}
+	s.client = &stackdriverMetricClient{conn: client}
+	return nil
+}
+
+// Close closes the client.
+func (s *Stackdriver) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client = nil
+	return err
+}
+
+// Write converts the numeric fields of the metrics to time series and writes
+// them in batches, per project, within the limits of the API.
+func (s *Stackdriver) Write(metrics []cua.Metric) (int, error) {
+	series := make(map[string][]*monitoringpb.TimeSeries)
+	skipped := 0
+	for _, m := range metrics {
+		project, ts, ok := s.timeSeries(m)
+		if !ok {
+			skipped++
+			continue
+		}
+		series[project] = append(series[project], ts...)
+	}
+	if skipped > 0 {
+		s.Log.Warnf("Skipped %d metrics missing the labels of the %s resource", skipped, s.ResourceType)
+	}
+
+	projects := make([]string, 0, len(series))
+	requests := make(map[string][][]*monitoringpb.TimeSeries, len(series))
+	for project, ts := range series {
+		projects = append(projects, project)
+		requests[project] = batchTimeSeries(ts, maxTimeSeriesPerRequest)
+	}
+	sort.Strings(projects)
+
+	// the metrics are kept in the buffer until every project has the quota
+	// to write them
+	now := time.Now()
+	for _, project := range projects {
+		if !s.quota(project).allow(len(requests[project]), now) {
+			return 0, fmt.Errorf("request quota of project %s exhausted, %d requests delayed", project, len(requests[project]))
+		}
+	}
+	for _, project := range projects {
+		s.quota(project).take(len(requests[project]))
+	}
+
+	for _, project := range projects {
+		if s.CreateDescriptors {
+			s.createDescriptors(project, series[project])
+		}
+		for _, batch := range requests[project] {
+			if err := s.createTimeSeries(project, batch); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return len(metrics) - skipped, nil
+}
+
+func (s *Stackdriver) createTimeSeries(project string, batch []*monitoringpb.TimeSeries) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Timeout))
+	defer cancel()
+
+	err := s.client.CreateTimeSeries(ctx, &monitoringpb.CreateTimeSeriesRequest{
+		Name:       "projects/" + project,
+		TimeSeries: batch,
+	})
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.InvalidArgument:
+		// retrying the points rejected fails again, such as the points
+		// written out of order
+		s.Log.Errorf("Time series of project %s rejected: %v", project, err)
+		return nil
+	case codes.ResourceExhausted:
+		s.quota(project).exhaust()
+		return fmt.Errorf("request quota of project %s exhausted: %w", project, err)
+	default:
+		return fmt.Errorf("create time series (project %s): %w", project, err)
+	}
+}
+
+// createDescriptors creates the descriptors of the metric types not created
+// yet, or having new labels.
+func (s *Stackdriver) createDescriptors(project string, series []*monitoringpb.TimeSeries) {
+	for _, ts := range series {
+		key := project + " " + ts.Metric.Type
+		labels := s.descriptors[key]
+		created := labels != nil
+		for k := range ts.Metric.Labels {
+			if !labels[k] {
+				created = false
+			}
+		}
+		if created {
+			continue
+		}
+
+		if labels == nil {
+			labels = make(map[string]bool)
+		}
+		for k := range ts.Metric.Labels {
+			labels[k] = true
+		}
+		if err := s.createDescriptor(project, ts, labels); err != nil {
+			// the descriptor is created implicitly by the first write
+			s.Log.Warnf("Creating the metric descriptor %s of project %s: %v", ts.Metric.Type, project, err)
+			continue
+		}
+		s.descriptors[key] = labels
+	}
+}
+
+func (s *Stackdriver) createDescriptor(project string, ts *monitoringpb.TimeSeries, labels map[string]bool) error {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	descriptors := make([]*labelpb.LabelDescriptor, 0, len(keys))
+	for _, k := range keys {
+		descriptors = append(descriptors, &labelpb.LabelDescriptor{
+			Key:       k,
+			ValueType: labelpb.LabelDescriptor_STRING,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Timeout))
+	defer cancel()
+
+	err := s.client.CreateMetricDescriptor(ctx, &monitoringpb.CreateMetricDescriptorRequest{
+		Name: "projects/" + project,
+		MetricDescriptor: &metricpb.MetricDescriptor{
+			Type:        ts.Metric.Type,
+			DisplayName: strings.TrimPrefix(ts.Metric.Type, "custom.googleapis.com/"),
+			Description: "Written by " + internal.ProductToken(),
+			Labels:      descriptors,
+			MetricKind:  ts.MetricKind,
+			ValueType:   ts.ValueType,
+		},
+	})
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		return fmt.Errorf("create metric descriptor: %w", err)
+	}
+	return nil
+}
+
+func (s *Stackdriver) quota(project string) *quota {
+	q, ok := s.quotas[project]
+	if !ok {
+		q = newQuota(s.RequestsPerMinute, time.Now())
+		s.quotas[project] = q
+	}
+	return q
+}
+
+// timeSeries returns the project and the time series of the numeric fields
+// of the metric, false when the labels of the resource are missing.
+func (s *Stackdriver) timeSeries(m cua.Metric) (string, []*monitoringpb.TimeSeries, bool) {
+	project := s.Project
+	resourceLabels := map[string]string{"project_id": project}
+	for k, v := range s.ResourceLabels {
+		resourceLabels[k] = v
+	}
+	resourceTags := make(map[string]bool, len(s.ResourceTags))
+	for label, tag := range s.ResourceTags {
+		if v, ok := m.GetTag(tag); ok {
+			resourceLabels[label] = v
+		}
+		resourceTags[tag] = true
+	}
+	if s.ProjectTag != "" {
+		if v, ok := m.GetTag(s.ProjectTag); ok && v != "" {
+			project = v
+			resourceLabels["project_id"] = v
+		}
+	}
+	for _, label := range requiredResourceLabels[s.ResourceType] {
+		if resourceLabels[label] == "" {
+			return project, nil, false
+		}
+	}
+
+	labels := make(map[string]string)
+	for _, tag := range m.TagList() {
+		if resourceTags[tag.Key] || tag.Key == s.ProjectTag {
+			continue
+		}
+		if len(labels) == maxLabels {
+			s.Log.Debugf("Metric %s has more than %d labels, %s dropped", m.Name(), maxLabels, tag.Key)
+			break
+		}
+		labels[labelKey(tag.Key)] = truncate(tag.Value, maxLabelValueLength)
+	}
+
+	resource := &monitoredrespb.MonitoredResource{
+		Type:   s.ResourceType,
+		Labels: resourceLabels,
+	}
+	end := &googlepbts.Timestamp{Seconds: m.Time().Unix(), Nanos: int32(m.Time().Nanosecond())}
+
+	var series []*monitoringpb.TimeSeries
+	for _, field := range m.FieldList() {
+		value, valueType, ok := typedValue(field.Value)
+		if !ok {
+			continue
+		}
+		kind := metricpb.MetricDescriptor_GAUGE
+		interval := &monitoringpb.TimeInterval{EndTime: end}
+		if m.Type() == cua.Counter && valueType != metricpb.MetricDescriptor_BOOL {
+			kind = metricpb.MetricDescriptor_CUMULATIVE
+			start := s.start
+			if !start.Before(m.Time()) {
+				start = m.Time().Add(-time.Millisecond)
+			}
+			interval.StartTime = &googlepbts.Timestamp{Seconds: start.Unix(), Nanos: int32(start.Nanosecond())}
+		}
+		series = append(series, &monitoringpb.TimeSeries{
+			Metric: &metricpb.Metric{
+				Type:   metricType(s.Namespace, m.Name(), field.Key),
+				Labels: labels,
+			},
+			Resource:   resource,
+			MetricKind: kind,
+			ValueType:  valueType,
+			Points: []*monitoringpb.Point{{
+				Interval: interval,
+				Value:    value,
+			}},
+		})
+	}
+	return project, series, true
+}
+
+// batchTimeSeries splits the time series in batches of at most size time
+// series.  A batch holds a single point of a series, the points of a series
+// are in the order of the batches.
+func batchTimeSeries(series []*monitoringpb.TimeSeries, size int) [][]*monitoringpb.TimeSeries {
+	var batches [][]*monitoringpb.TimeSeries
+	// the batch of the last point of each series
+	last := make(map[uint64]int)
+	first := 0
+	for _, ts := range series {
+		key := seriesKey(ts)
+		i := first
+		if j, ok := last[key]; ok && j >= i {
+			i = j + 1
+		}
+		for i < len(batches) && len(batches[i]) == size {
+			i++
+		}
+		if i == len(batches) {
+			batches = append(batches, make([]*monitoringpb.TimeSeries, 0, size))
+		}
+		batches[i] = append(batches[i], ts)
+		last[key] = i
+		for first < len(batches) && len(batches[first]) == size {
+			first++
+		}
+	}
+	return batches
+}
+
+// seriesKey identifies the series of a time series, by metric type, labels
+// and resource.
+func seriesKey(ts *monitoringpb.TimeSeries) uint64 {
+	h := fnv.New64a()
+	writeLabels := func(labels map[string]string) {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			h.Write([]byte(k + "\x00" + labels[k] + "\x00"))
+		}
+	}
+	h.Write([]byte(ts.Metric.Type + "\x00"))
+	writeLabels(ts.Metric.Labels)
+	h.Write([]byte(ts.Resource.Type + "\x00"))
+	writeLabels(ts.Resource.Labels)
+	return h.Sum64()
+}
+
+// typedValue converts a field value, the strings and the values out of range
+// are not written.
+func typedValue(v interface{}) (*monitoringpb.TypedValue, metricpb.MetricDescriptor_ValueType, bool) {
+	switch t := v.(type) {
+	case int64:
+		return &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_Int64Value{Int64Value: t}},
+			metricpb.MetricDescriptor_INT64, true
+	case uint64:
+		if t > math.MaxInt64 {
+			t = math.MaxInt64
+		}
+		return &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_Int64Value{Int64Value: int64(t)}},
+			metricpb.MetricDescriptor_INT64, true
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return nil, 0, false
+		}
+		return &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: t}},
+			metricpb.MetricDescriptor_DOUBLE, true
+	case bool:
+		return &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_BoolValue{BoolValue: t}},
+			metricpb.MetricDescriptor_BOOL, true
+	default:
+		return nil, 0, false
+	}
+}
+
+// metricType returns the type of the custom metric of a field.
+func metricType(namespace, measurement, field string) string {
+	return "custom.googleapis.com/" + sanitize(namespace+"/"+measurement+"_"+field, "/.")
+}
+
+// labelKey returns the tag key as a label key: lower case letters, digits
+// and underscores, starting with a letter.
+func labelKey(key string) string {
+	key = sanitize(strings.ToLower(key), "")
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		key = "l_" + key
+	}
+	return truncate(key, maxLabelKeyLength)
+}
+
+// sanitize replaces the characters other than letters, digits, underscores
+// and the allowed characters by underscores.
+func sanitize(s, allowed string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case strings.ContainsRune(allowed, r):
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// quota is a token bucket of the write requests of a project, refilled
+// continuously up to the requests allowed per minute.
+type quota struct {
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newQuota(perMinute int, now time.Time) *quota {
+	return &quota{capacity: float64(perMinute), tokens: float64(perMinute), last: now}
+}
+
+// allow reports whether n requests can be made.  The requests exceeding the
+// capacity are allowed with a full bucket, the tokens then go negative.
+func (q *quota) allow(n int, now time.Time) bool {
+	q.tokens = math.Min(q.capacity, q.tokens+now.Sub(q.last).Minutes()*q.capacity)
+	q.last = now
+	return q.tokens >= math.Min(float64(n), q.capacity)
+}
+
+func (q *quota) take(n int) {
+	q.tokens -= float64(n)
+}
+
+// exhaust empties the bucket when the API reports the quota exhausted.
+func (q *quota) exhaust() {
+	if q.tokens > 0 {
+		q.tokens = 0
+	}
+}
+
+func init() {
+	outputs.Add("stackdriver", func() cua.Output {
+		return &Stackdriver{}
+	})
+}
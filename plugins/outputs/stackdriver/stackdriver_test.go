@@ -0,0 +1,218 @@
+package stackdriver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeClient struct {
+	series      []*monitoringpb.CreateTimeSeriesRequest
+	descriptors []*monitoringpb.CreateMetricDescriptorRequest
+	err         error
+}
+
+func (c *fakeClient) CreateTimeSeries(_ context.Context, req *monitoringpb.CreateTimeSeriesRequest) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.series = append(c.series, req)
+	return nil
+}
+
+func (c *fakeClient) CreateMetricDescriptor(_ context.Context, req *monitoringpb.CreateMetricDescriptorRequest) error {
+	c.descriptors = append(c.descriptors, req)
+	return nil
+}
+
+func (c *fakeClient) Close() error {
+	return nil
+}
+
+func newStackdriver(t *testing.T, s *Stackdriver) (*Stackdriver, *fakeClient) {
+	client := &fakeClient{}
+	s.Log = testutil.Logger{}
+	s.client = client
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	return s, client
+}
+
+func TestWrite(t *testing.T) {
+	s, client := newStackdriver(t, &Stackdriver{
+		Project:    "default",
+		ProjectTag: "project",
+	})
+	now := time.Now()
+
+	n, err := s.Write([]cua.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "CPU-Name": "cpu0"},
+			map[string]interface{}{"usage": 42.5, "name": "x", "nan": math.NaN()},
+			now),
+		testutil.MustMetric("net",
+			map[string]string{"project": "other"},
+			map[string]interface{}{"bytes": uint64(10), "up": true},
+			now, cua.Counter),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Len(t, client.series, 2)
+
+	req := client.series[0]
+	require.Equal(t, "projects/default", req.Name)
+	require.Len(t, req.TimeSeries, 1)
+	ts := req.TimeSeries[0]
+	require.Equal(t, "custom.googleapis.com/circonus/cpu_usage", ts.Metric.Type)
+	require.Equal(t, map[string]string{"host": "a", "cpu_name": "cpu0"}, ts.Metric.Labels)
+	require.Equal(t, "global", ts.Resource.Type)
+	require.Equal(t, map[string]string{"project_id": "default"}, ts.Resource.Labels)
+	require.Equal(t, metricpb.MetricDescriptor_GAUGE, ts.MetricKind)
+	require.Equal(t, metricpb.MetricDescriptor_DOUBLE, ts.ValueType)
+	require.Equal(t, 42.5, ts.Points[0].Value.GetDoubleValue())
+
+	req = client.series[1]
+	require.Equal(t, "projects/other", req.Name)
+	require.Len(t, req.TimeSeries, 2)
+	ts = req.TimeSeries[0]
+	require.Equal(t, "custom.googleapis.com/circonus/net_bytes", ts.Metric.Type)
+	require.Empty(t, ts.Metric.Labels)
+	require.Equal(t, map[string]string{"project_id": "other"}, ts.Resource.Labels)
+	require.Equal(t, metricpb.MetricDescriptor_CUMULATIVE, ts.MetricKind)
+	require.Equal(t, int64(10), ts.Points[0].Value.GetInt64Value())
+	require.NotNil(t, ts.Points[0].Interval.StartTime)
+	// the booleans are gauges
+	require.Equal(t, metricpb.MetricDescriptor_GAUGE, req.TimeSeries[1].MetricKind)
+	require.Equal(t, metricpb.MetricDescriptor_BOOL, req.TimeSeries[1].ValueType)
+}
+
+func TestResourceLabels(t *testing.T) {
+	require.Error(t, (&Stackdriver{Project: "p", ResourceType: "gce_instance"}).Init())
+
+	s, client := newStackdriver(t, &Stackdriver{
+		Project:        "p",
+		ResourceType:   "gce_instance",
+		ResourceLabels: map[string]string{"zone": "us-central1-a"},
+		ResourceTags:   map[string]string{"instance_id": "id"},
+	})
+	n, err := s.Write([]cua.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"id": "1234", "host": "a"},
+			map[string]interface{}{"usage": 1.0},
+			time.Now()),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"usage": 1.0},
+			time.Now()),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, client.series, 1)
+	ts := client.series[0].TimeSeries[0]
+	require.Equal(t, "gce_instance", ts.Resource.Type)
+	require.Equal(t, map[string]string{"project_id": "p", "zone": "us-central1-a", "instance_id": "1234"}, ts.Resource.Labels)
+	require.Equal(t, map[string]string{"host": "a"}, ts.Metric.Labels)
+}
+
+func TestBatchTimeSeries(t *testing.T) {
+	var series []*monitoringpb.TimeSeries
+	for i := 0; i < 5; i++ {
+		for _, host := range []string{"a", "b", "c"} {
+			series = append(series, &monitoringpb.TimeSeries{
+				Metric:   &metricpb.Metric{Type: "t", Labels: map[string]string{"host": host, "point": fmt.Sprint(i % 2)}},
+				Resource: &monitoredrespb.MonitoredResource{Type: "global"},
+			})
+		}
+	}
+	batches := batchTimeSeries(series, 4)
+
+	total := 0
+	last := make(map[uint64]int)
+	for i, batch := range batches {
+		require.LessOrEqual(t, len(batch), 4)
+		keys := make(map[uint64]bool)
+		for _, ts := range batch {
+			key := seriesKey(ts)
+			require.False(t, keys[key], "series twice in batch %d", i)
+			keys[key] = true
+			if j, ok := last[key]; ok {
+				require.Greater(t, i, j)
+			}
+			last[key] = i
+		}
+		total += len(batch)
+	}
+	require.Equal(t, 15, total)
+}
+
+func TestQuota(t *testing.T) {
+	s, client := newStackdriver(t, &Stackdriver{Project: "p", RequestsPerMinute: 2})
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"usage": 1.0}, time.Now())
+
+	for i := 0; i < 2; i++ {
+		_, err := s.Write([]cua.Metric{m})
+		require.NoError(t, err)
+	}
+	_, err := s.Write([]cua.Metric{m})
+	require.Error(t, err)
+	require.Len(t, client.series, 2)
+
+	// the bucket refills with time
+	s.quota("p").last = s.quota("p").last.Add(-time.Minute)
+	_, err = s.Write([]cua.Metric{m})
+	require.NoError(t, err)
+
+	client.err = status.Error(codes.ResourceExhausted, "quota")
+	s.quota("p").last = s.quota("p").last.Add(-time.Minute)
+	_, err = s.Write([]cua.Metric{m})
+	require.Error(t, err)
+	require.Zero(t, s.quota("p").tokens)
+
+	// rejected points are not retried
+	client.err = status.Error(codes.InvalidArgument, "out of order")
+	s.quota("p").last = s.quota("p").last.Add(-time.Minute)
+	_, err = s.Write([]cua.Metric{m})
+	require.NoError(t, err)
+}
+
+func TestCreateDescriptors(t *testing.T) {
+	s, client := newStackdriver(t, &Stackdriver{Project: "p", CreateDescriptors: true})
+	now := time.Now()
+
+	write := func(tags map[string]string) {
+		_, err := s.Write([]cua.Metric{
+			testutil.MustMetric("cpu", tags, map[string]interface{}{"usage": 1.0}, now),
+		})
+		require.NoError(t, err)
+	}
+	write(map[string]string{"host": "a"})
+	write(map[string]string{"host": "b"})
+	require.Len(t, client.descriptors, 1)
+	d := client.descriptors[0].MetricDescriptor
+	require.Equal(t, "custom.googleapis.com/circonus/cpu_usage", d.Type)
+	require.Equal(t, metricpb.MetricDescriptor_GAUGE, d.MetricKind)
+	require.Equal(t, metricpb.MetricDescriptor_DOUBLE, d.ValueType)
+	require.Len(t, d.Labels, 1)
+
+	// a new label updates the descriptor
+	write(map[string]string{"host": "a", "dc": "east"})
+	require.Len(t, client.descriptors, 2)
+	require.Len(t, client.descriptors[1].MetricDescriptor.Labels, 2)
+}
+
+func TestLabelKey(t *testing.T) {
+	require.Equal(t, "host_name", labelKey("Host.Name"))
+	require.Equal(t, "l_1st", labelKey("1st"))
+	require.Equal(t, "custom.googleapis.com/ns/disk_io_time", metricType("ns", "disk io", "time"))
+}
@@ -0,0 +1,126 @@
+package wavefront
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+)
+
+// sender delivers a batch of points in the Wavefront data format.
+type sender interface {
+	Send(lines []byte) error
+	Close() error
+}
+
+// httpSender posts points to the Wavefront direct ingestion API, or to the
+// HTTP port of a Wavefront proxy when no token is used.
+type httpSender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newHTTPSender(url, token string, timeout time.Duration) *httpSender {
+	return &httpSender{
+		url:    strings.TrimSuffix(url, "/") + "/report?f=wavefront",
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *httpSender) Send(lines []byte) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(lines); err != nil {
+		return fmt.Errorf("gzip: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("gzip: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", s.url, &buf)
+	if err != nil {
+		return fmt.Errorf("unable to create http.Request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	if s.token != "" && s.token != "DUMMY_TOKEN" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error POSTing metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("received bad status code, %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+func (s *httpSender) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// tcpSender writes points to the plain text port of a Wavefront proxy. The
+// connection is opened lazily and dropped on error so the next write
+// reconnects.
+type tcpSender struct {
+	address string
+	timeout time.Duration
+	conn    net.Conn
+}
+
+func newTCPSender(host string, port int, timeout time.Duration) *tcpSender {
+	return &tcpSender{
+		address: net.JoinHostPort(host, strconv.Itoa(port)),
+		timeout: timeout,
+	}
+}
+
+func (s *tcpSender) Send(lines []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.address, s.timeout)
+		if err != nil {
+			return fmt.Errorf("dial %s: %w", s.address, err)
+		}
+		s.conn = conn
+	}
+
+	if s.timeout > 0 {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+			s.Close()
+			return fmt.Errorf("set deadline: %w", err)
+		}
+	}
+	if _, err := s.conn.Write(lines); err != nil {
+		s.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+func (s *tcpSender) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	if err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package wavefront
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+)
+
+const (
+	maxTagLength           = 254
+	defaultTimeout         = 10 * time.Second
+	defaultMaximumBatch    = 10000
+	defaultMetricSeparator = "."
+)
+
+type Wavefront struct {
+	URL                  string            `toml:"url"`
+	Token                string            `toml:"token"`
+	Host                 string            `toml:"host"`
+	Port                 int               `toml:"port"`
+	Prefix               string            `toml:"prefix"`
+	SimpleFields         bool              `toml:"simple_fields"`
+	MetricSeparator      string            `toml:"metric_separator"`
+	ConvertPaths         bool              `toml:"convert_paths"`
+	ConvertBool          bool              `toml:"convert_bool"`
+	UseRegex             bool              `toml:"use_regex"`
+	UseStrict            bool              `toml:"use_strict"`
+	TruncateTags         bool              `toml:"truncate_tags"`
+	SourceOverride       []string          `toml:"source_override"`
+	HTTPMaximumBatchSize int               `toml:"http_maximum_batch_size"`
+	Timeout              internal.Duration `toml:"timeout"`
+
+	Log cua.Logger `toml:"-"`
+
+	sender       sender
+	pathReplacer *strings.Replacer
+}
+
+// catch many of the invalid chars that could appear in a metric or tag name
+var sanitizedChars = strings.NewReplacer(
+	"!", "-", "@", "-", "#", "-", "$", "-", "%", "-", "^", "-", "&", "-",
+	"*", "-", "(", "-", ")", "-", "+", "-", "`", "-", "'", "-", "\"", "-",
+	"[", "-", "]", "-", "{", "-", "}", "-", ":", "-", ";", "-", "<", "-",
+	">", "-", ",", "-", "?", "-", "/", "-", "\\", "-", "|", "-", " ", "-",
+	"=", "-",
+)
+
+// catch many of the invalid chars that could appear in a metric or tag name
+var strictSanitizedChars = strings.NewReplacer(
+	"!", "-", "@", "-", "#", "-", "$", "-", "%", "-", "^", "-", "&", "-",
+	"*", "-", "(", "-", ")", "-", "+", "-", "`", "-", "'", "-", "\"", "-",
+	"[", "-", "]", "-", "{", "-", "}", "-", ":", "-", ";", "-", "<", "-",
+	">", "-", "?", "-", "\\", "-", "|", "-", " ", "-", "=", "-",
+)
+
+// instead of Replacer which may miss some special characters we can use a regex pattern, but this is significantly slower than Replacer
+var sanitizedRegex = regexp.MustCompile(`[^a-zA-Z\d_.-]`)
+
+var tagValueReplacer = strings.NewReplacer("\"", "\\\"", "*", "-")
+
+var sampleConfig = `
+  ## Url for Wavefront Direct Ingestion or using HTTP with Wavefront Proxy
+  ## If using Wavefront Proxy, also specify port. example: http://proxyserver:2878
+  url = "https://metrics.wavefront.com"
+
+  ## Authentication Token for Wavefront. Only required if using Direct Ingestion
+  #token = "DUMMY_TOKEN"
+
+  ## DNS name of the wavefront proxy server. Do not use if url is specified
+  #host = "wavefront.example.com"
+
+  ## Port that the Wavefront proxy server listens on. Do not use if url is specified
+  #port = 2878
+
+  ## prefix for metrics keys
+  #prefix = "my.specific.prefix."
+
+  ## whether to use "value" for name of simple fields. default is false
+  #simple_fields = false
+
+  ## character to use between metric and field name.  default is . (dot)
+  #metric_separator = "."
+
+  ## Convert metric name paths to use metricSeparator character
+  ## When true will convert all _ (underscore) characters in final metric name. default is true
+  #convert_paths = true
+
+  ## Use Strict rules to sanitize metric and tag names from invalid characters
+  ## When enabled forward slash (/) and comma (,) will be accepted
+  #use_strict = false
+
+  ## Use Regex to sanitize metric and tag names from invalid characters
+  ## Regex is more thorough, but significantly slower. default is false
+  #use_regex = false
+
+  ## point tags to use as the source name for Wavefront (if none found, host will be used)
+  #source_override = ["hostname", "address", "agent_host", "node_host"]
+
+  ## whether to convert boolean values to numeric values, with false -> 0.0 and true -> 1.0. default is true
+  #convert_bool = true
+
+  ## Truncate metric tags to a total of 254 characters for the tag name value. Wavefront will reject any
+  ## data point exceeding this limit if not truncated. Defaults to 'false' to provide backwards compatibility.
+  #truncate_tags = false
+
+  ## Maximum number of points sent in a single HTTP request.
+  #http_maximum_batch_size = 10000
+
+  ## Timeout for connections and requests.
+  #timeout = "10s"
+`
+
+// MetricPoint is a single Wavefront data point.
+type MetricPoint struct {
+	Metric    string
+	Value     float64
+	Timestamp int64
+	Source    string
+	Tags      map[string]string
+}
+
+func (w *Wavefront) Connect() error {
+	if w.MetricSeparator == "" {
+		w.MetricSeparator = defaultMetricSeparator
+	}
+	if w.ConvertPaths && w.MetricSeparator == "_" {
+		w.ConvertPaths = false
+	}
+	if w.ConvertPaths {
+		w.pathReplacer = strings.NewReplacer("_", w.MetricSeparator)
+	}
+	if w.HTTPMaximumBatchSize <= 0 {
+		w.HTTPMaximumBatchSize = defaultMaximumBatch
+	}
+
+	switch {
+	case w.URL != "":
+		w.Log.Debug("connecting over http/https using Url: ", w.URL)
+		w.sender = newHTTPSender(w.URL, w.Token, w.Timeout.Duration)
+	case w.Host != "":
+		w.Log.Debug("connecting over tcp using Host: ", w.Host, " and Port: ", w.Port)
+		w.sender = newTCPSender(w.Host, w.Port, w.Timeout.Duration)
+	default:
+		return fmt.Errorf("either url or host must be set")
+	}
+
+	return nil
+}
+
+func (w *Wavefront) Write(metrics []cua.Metric) (int, error) {
+	var batch []byte
+	var count int
+	for _, m := range metrics {
+		for _, point := range w.buildMetrics(m) {
+			batch = formatMetricPoint(batch, point)
+			count++
+			if count >= w.HTTPMaximumBatchSize {
+				if err := w.sender.Send(batch); err != nil {
+					return 0, fmt.Errorf("wavefront sending error: %w", err)
+				}
+				batch = batch[:0]
+				count = 0
+			}
+		}
+	}
+
+	if count > 0 {
+		if err := w.sender.Send(batch); err != nil {
+			return 0, fmt.Errorf("wavefront sending error: %w", err)
+		}
+	}
+	return len(metrics), nil
+}
+
+func (w *Wavefront) buildMetrics(m cua.Metric) []*MetricPoint {
+	ret := []*MetricPoint{}
+
+	for fieldName, value := range m.Fields() {
+		var name string
+		if !w.SimpleFields && fieldName == "value" {
+			name = w.Prefix + m.Name()
+		} else {
+			name = w.Prefix + m.Name() + w.MetricSeparator + fieldName
+		}
+
+		switch {
+		case w.UseRegex:
+			name = sanitizedRegex.ReplaceAllLiteralString(name, "-")
+		case w.UseStrict:
+			name = strictSanitizedChars.Replace(name)
+		default:
+			name = sanitizedChars.Replace(name)
+		}
+
+		if w.ConvertPaths {
+			name = w.pathReplacer.Replace(name)
+		}
+
+		metric := &MetricPoint{
+			Metric:    name,
+			Timestamp: m.Time().Unix(),
+		}
+
+		metricValue, buildError := buildValue(value, metric.Metric, w)
+		if buildError != nil {
+			w.Log.Debugf("skipping field: %s", buildError)
+			continue
+		}
+		metric.Value = metricValue
+
+		source, tags := w.buildTags(m.Tags())
+		metric.Source = source
+		metric.Tags = tags
+
+		ret = append(ret, metric)
+	}
+	return ret
+}
+
+func (w *Wavefront) buildTags(mTags map[string]string) (string, map[string]string) {
+	// Remove all empty tags.
+	for k, v := range mTags {
+		if v == "" {
+			delete(mTags, k)
+		}
+	}
+
+	// find source, use source_override property if needed
+	var source string
+	if s, ok := mTags["source"]; ok {
+		source = s
+		delete(mTags, "source")
+	} else {
+		sourceTagFound := false
+		for _, s := range w.SourceOverride {
+			for k, v := range mTags {
+				if k == s {
+					source = v
+					mTags["cua_host"] = mTags["host"]
+					sourceTagFound = true
+					delete(mTags, k)
+					break
+				}
+			}
+			if sourceTagFound {
+				break
+			}
+		}
+
+		if !sourceTagFound {
+			source = mTags["host"]
+		}
+	}
+	source = tagValueReplacer.Replace(source)
+
+	// remove default host tag
+	delete(mTags, "host")
+
+	// sanitize tag keys and values
+	tags := make(map[string]string)
+	for k, v := range mTags {
+		var key string
+		switch {
+		case w.UseRegex:
+			key = sanitizedRegex.ReplaceAllLiteralString(k, "-")
+		case w.UseStrict:
+			key = strictSanitizedChars.Replace(k)
+		default:
+			key = sanitizedChars.Replace(k)
+		}
+		val := tagValueReplacer.Replace(v)
+		if w.TruncateTags {
+			if len(key) > maxTagLength {
+				w.Log.Warnf("Tag key length > 254. Skipping tag: %s", key)
+				continue
+			}
+			if len(key)+len(val) > maxTagLength {
+				w.Log.Debugf("Key+value length > 254: %s", key)
+				val = val[:maxTagLength-len(key)]
+			}
+		}
+		tags[key] = val
+	}
+
+	return source, tags
+}
+
+func buildValue(v interface{}, name string, w *Wavefront) (float64, error) {
+	switch p := v.(type) {
+	case bool:
+		if w.ConvertBool {
+			if p {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case int64:
+		return float64(p), nil
+	case uint64:
+		return float64(p), nil
+	case float64:
+		return p, nil
+	}
+	return 0, fmt.Errorf("unexpected type: %T, with value: %v, for: %s", v, v, name)
+}
+
+// formatMetricPoint appends the point in the Wavefront data format, tags are
+// sorted so the output is stable.
+func formatMetricPoint(b []byte, p *MetricPoint) []byte {
+	b = append(b, '"')
+	b = append(b, p.Metric...)
+	b = append(b, `" `...)
+	b = strconv.AppendFloat(b, p.Value, 'f', -1, 64)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, p.Timestamp, 10)
+	b = append(b, ` source="`...)
+	b = append(b, p.Source...)
+	b = append(b, '"')
+
+	keys := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b = append(b, ` "`...)
+		b = append(b, k...)
+		b = append(b, `"="`...)
+		b = append(b, p.Tags[k]...)
+		b = append(b, '"')
+	}
+	return append(b, '\n')
+}
+
+func (w *Wavefront) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Wavefront) Description() string {
+	return "Configuration for Wavefront server to send metrics to"
+}
+
+func (w *Wavefront) Close() error {
+	if w.sender != nil {
+		return w.sender.Close()
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("wavefront", func() cua.Output {
+		return &Wavefront{
+			Token:                "DUMMY_TOKEN",
+			MetricSeparator:      defaultMetricSeparator,
+			ConvertPaths:         true,
+			ConvertBool:          true,
+			TruncateTags:         false,
+			HTTPMaximumBatchSize: defaultMaximumBatch,
+			Timeout:              internal.Duration{Duration: defaultTimeout},
+		}
+	})
+}
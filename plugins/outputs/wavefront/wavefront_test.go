@@ -0,0 +1,197 @@
+package wavefront
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func defaultWavefront() *Wavefront {
+	return &Wavefront{
+		Token:                "DUMMY_TOKEN",
+		MetricSeparator:      defaultMetricSeparator,
+		ConvertPaths:         true,
+		ConvertBool:          true,
+		HTTPMaximumBatchSize: defaultMaximumBatch,
+		Timeout:              internal.Duration{Duration: time.Second},
+		Log:                  testutil.Logger{},
+	}
+}
+
+func TestBuildMetrics(t *testing.T) {
+	w := defaultWavefront()
+	w.Prefix = "testWF."
+	w.SourceOverride = []string{"snmp_host"}
+	w.URL = "http://localhost:2878"
+	require.NoError(t, w.Connect())
+
+	tm := time.Unix(1600000000, 0)
+	tests := []struct {
+		metric   cua.Metric
+		expected []MetricPoint
+	}{
+		{
+			metric: testutil.MustMetric("cpu",
+				map[string]string{"host": "realHost", "tag_1": "t1"},
+				map[string]interface{}{"usage_idle": 1.5, "value": 2.0},
+				tm),
+			expected: []MetricPoint{
+				{Metric: "testWF.cpu.usage.idle", Value: 1.5, Timestamp: tm.Unix(), Source: "realHost", Tags: map[string]string{"tag_1": "t1"}},
+				{Metric: "testWF.cpu", Value: 2, Timestamp: tm.Unix(), Source: "realHost", Tags: map[string]string{"tag_1": "t1"}},
+			},
+		},
+		{
+			metric: testutil.MustMetric("cpu",
+				map[string]string{"host": "realHost", "snmp_host": "switch01"},
+				map[string]interface{}{"up": true, "name": "skipped"},
+				tm),
+			expected: []MetricPoint{
+				{Metric: "testWF.cpu.up", Value: 1, Timestamp: tm.Unix(), Source: "switch01", Tags: map[string]string{"cua_host": "realHost"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		points := w.buildMetrics(tt.metric)
+		require.Len(t, points, len(tt.expected))
+		for _, e := range tt.expected {
+			var found bool
+			for _, p := range points {
+				if p.Metric == e.Metric {
+					require.Equal(t, e, *p)
+					found = true
+				}
+			}
+			require.True(t, found, "missing %s", e.Metric)
+		}
+	}
+}
+
+func TestBuildTagsTruncate(t *testing.T) {
+	w := defaultWavefront()
+	w.TruncateTags = true
+
+	longValue := make([]byte, 300)
+	for i := range longValue {
+		longValue[i] = 'v'
+	}
+	longKey := string(longValue[:260])
+
+	source, tags := w.buildTags(map[string]string{
+		"host":  "h1",
+		"key":   string(longValue),
+		longKey: "dropped",
+		"empty": "",
+	})
+	require.Equal(t, "h1", source)
+	require.Len(t, tags, 1)
+	require.Len(t, tags["key"], maxTagLength-len("key"))
+}
+
+func TestFormatMetricPoint(t *testing.T) {
+	line := formatMetricPoint(nil, &MetricPoint{
+		Metric:    "cpu.idle",
+		Value:     1.25,
+		Timestamp: 1600000000,
+		Source:    "host1",
+		Tags:      map[string]string{"b": "2", "a": `x\"y`},
+	})
+	require.Equal(t, `"cpu.idle" 1.25 1600000000 source="host1" "a"="x\"y" "b"="2"`+"\n", string(line))
+}
+
+func TestWriteHTTP(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/report", r.URL.Path)
+		require.Equal(t, "wavefront", r.URL.Query().Get("f"))
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		requests = append(requests, string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	w := defaultWavefront()
+	w.URL = ts.URL
+	w.Token = "secret"
+	w.HTTPMaximumBatchSize = 1
+	require.NoError(t, w.Connect())
+	defer w.Close()
+
+	metrics := []cua.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "h1"}, map[string]interface{}{"value": 1.0}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "h2"}, map[string]interface{}{"value": 2.0}, time.Unix(1600000000, 0)),
+	}
+	n, err := w.Write(metrics)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{
+		`"cpu" 1 1600000000 source="h1"` + "\n",
+		`"cpu" 2 1600000000 source="h2"` + "\n",
+	}, requests)
+}
+
+func TestWriteHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	w := defaultWavefront()
+	w.URL = ts.URL
+	require.NoError(t, w.Connect())
+
+	_, err := w.Write([]cua.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	})
+	require.Error(t, err)
+}
+
+func TestWriteTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	addr := l.Addr().(*net.TCPAddr)
+	w := defaultWavefront()
+	w.Host = addr.IP.String()
+	w.Port = addr.Port
+	require.NoError(t, w.Connect())
+	defer w.Close()
+
+	_, err = w.Write([]cua.Metric{
+		testutil.MustMetric("mem", map[string]string{"host": "h1", "dc": "east"}, map[string]interface{}{"used": int64(3)}, time.Unix(1600000000, 0)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, `"mem.used" 3 1600000000 source="h1" "dc"="east"`+"\n", <-lines)
+}
+
+func TestConnectRequiresDestination(t *testing.T) {
+	w := defaultWavefront()
+	require.Error(t, w.Connect())
+}
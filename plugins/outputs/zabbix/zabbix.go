@@ -0,0 +1,243 @@
+package zabbix
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	tlsint "github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/zabbix"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/outputs"
+)
+
+const (
+	defaultAddress = "localhost:10051"
+	defaultTimeout = 5 * time.Second
+	// maxResponseSize bounds the size of the server reply.
+	maxResponseSize = 1024 * 1024
+)
+
+var sampleConfig = `
+  ## Address of the Zabbix server or proxy trapper port.
+  address = "localhost:10051"
+
+  ## Tag used as the Zabbix host of the items, when the tag is missing the
+  ## host name of the agent is used.
+  # host_tag = "host"
+
+  ## Prefix added to all item keys.
+  # key_prefix = "cua."
+
+  ## Connection and request timeout.
+  # timeout = "5s"
+
+  ## Optional TLS configuration.
+  # tls_ca = "/opt/circonus/unified-agent/etc/ca.pem"
+  # tls_cert = "/opt/circonus/unified-agent/etc/cert.pem"
+  # tls_key = "/opt/circonus/unified-agent/etc/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// Zabbix sends metrics to a Zabbix server or proxy as trapper items.
+type Zabbix struct {
+	Address   string            `toml:"address"`
+	HostTag   string            `toml:"host_tag"`
+	KeyPrefix string            `toml:"key_prefix"`
+	Timeout   internal.Duration `toml:"timeout"`
+	tlsint.ClientConfig
+
+	Log cua.Logger `toml:"-"`
+
+	tlsConfig *tls.Config
+	hostname  string
+}
+
+func (z *Zabbix) Connect() error {
+	if z.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	tlsConfig, err := z.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+	z.tlsConfig = tlsConfig
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("hostname: %w", err)
+	}
+	z.hostname = hostname
+	return nil
+}
+
+func (z *Zabbix) Write(metrics []cua.Metric) (int, error) {
+	req := zabbix.Request{Request: "sender data"}
+	for _, m := range metrics {
+		req.Data = append(req.Data, z.buildItems(m)...)
+	}
+	if len(req.Data) == 0 {
+		return len(metrics), nil
+	}
+
+	resp, err := z.send(&req)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Response != "success" {
+		return 0, fmt.Errorf("zabbix response %q: %s", resp.Response, resp.Info)
+	}
+
+	// Items the server does not know about are reported as failed, sending
+	// them again would not help.
+	if _, failed, err := resp.Counts(); err != nil {
+		z.Log.Debug(err.Error())
+	} else if failed > 0 {
+		z.Log.Warnf("Zabbix failed to process %d of %d items", failed, len(req.Data))
+	}
+
+	return len(metrics), nil
+}
+
+func (z *Zabbix) send(req *zabbix.Request) (*zabbix.Response, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	dialer := &net.Dialer{Timeout: z.Timeout.Duration}
+	var conn net.Conn
+	if z.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", z.Address, z.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", z.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", z.Address, err)
+	}
+	defer conn.Close()
+
+	if z.Timeout.Duration > 0 {
+		if err := conn.SetDeadline(time.Now().Add(z.Timeout.Duration)); err != nil {
+			return nil, fmt.Errorf("set deadline: %w", err)
+		}
+	}
+
+	if err := zabbix.WritePacket(conn, data); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+
+	b, err := zabbix.ReadPacket(conn, maxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var resp zabbix.Response
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &resp, nil
+}
+
+// buildItems returns an item per field. The item key is the metric name and
+// field key, the tag values other than the host are used as key parameters
+// ordered by tag key, e.g. "cua.cpu.usage_idle[cpu0]".
+func (z *Zabbix) buildItems(m cua.Metric) []zabbix.Item {
+	host := z.hostname
+	var params []string
+	for _, tag := range m.TagList() {
+		if tag.Key == z.HostTag {
+			host = tag.Value
+			continue
+		}
+		params = append(params, quoteParam(tag.Value))
+	}
+
+	var suffix string
+	if len(params) > 0 {
+		suffix = "[" + strings.Join(params, ",") + "]"
+	}
+
+	clock := m.Time().Unix()
+	ns := int64(m.Time().Nanosecond())
+
+	items := make([]zabbix.Item, 0, len(m.FieldList()))
+	for _, field := range m.FieldList() {
+		value, ok := formatValue(field.Value)
+		if !ok {
+			continue
+		}
+		items = append(items, zabbix.Item{
+			Host:  host,
+			Key:   z.KeyPrefix + m.Name() + "." + field.Key + suffix,
+			Value: zabbix.ItemValue(value),
+			Clock: &clock,
+			NS:    &ns,
+		})
+	}
+	return items
+}
+
+// quoteParam quotes an item key parameter when it contains characters
+// with a special meaning in Zabbix keys.
+func quoteParam(s string) string {
+	if !strings.ContainsAny(s, `,]"`) && !strings.HasPrefix(s, " ") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func formatValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	case string:
+		return v, true
+	default:
+		return "", false
+	}
+}
+
+func (z *Zabbix) Close() error {
+	return nil
+}
+
+func (z *Zabbix) SampleConfig() string {
+	return sampleConfig
+}
+
+func (z *Zabbix) Description() string {
+	return "Send metrics to a Zabbix server or proxy as trapper items"
+}
+
+func init() {
+	outputs.Add("zabbix", func() cua.Output {
+		return &Zabbix{
+			Address:   defaultAddress,
+			HostTag:   "host",
+			KeyPrefix: "cua.",
+			Timeout:   internal.Duration{Duration: defaultTimeout},
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package zabbix
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/zabbix"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer answers a single request with resp and returns the request.
+func fakeServer(t *testing.T, resp string) (string, <-chan zabbix.Request) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	requests := make(chan zabbix.Request, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		data, err := zabbix.ReadPacket(c, zabbix.DefaultMaxPacketSize)
+		if err != nil {
+			return
+		}
+		var req zabbix.Request
+		if err := json.Unmarshal(data, &req); err != nil {
+			return
+		}
+		requests <- req
+		_ = zabbix.WritePacket(c, []byte(resp))
+	}()
+	return l.Addr().String(), requests
+}
+
+func newTestZabbix(address string) *Zabbix {
+	return &Zabbix{
+		Address:   address,
+		HostTag:   "host",
+		KeyPrefix: "cua.",
+		Timeout:   internal.Duration{Duration: time.Second},
+		Log:       testutil.Logger{},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	address, requests := fakeServer(t, `{"response":"success","info":"processed: 3; failed: 0; total: 3; seconds spent: 0.000100"}`)
+	z := newTestZabbix(address)
+	require.NoError(t, z.Connect())
+
+	metrics := []cua.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "web01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 99.5, "count": int64(3)},
+			time.Unix(1600000000, 0)),
+		testutil.MustMetric("disk",
+			map[string]string{"path": "/var, data"},
+			map[string]interface{}{"ok": true},
+			time.Unix(1600000000, 0)),
+	}
+	n, err := z.Write(metrics)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	req := <-requests
+	require.Equal(t, "sender data", req.Request)
+
+	var got []string
+	for _, item := range req.Data {
+		got = append(got, item.Host+" "+item.Key+" "+string(item.Value))
+		require.Equal(t, int64(1600000000), *item.Clock)
+	}
+	require.ElementsMatch(t, []string{
+		"web01 cua.cpu.usage_idle[cpu0] 99.5",
+		"web01 cua.cpu.count[cpu0] 3",
+		z.hostname + ` cua.disk.ok["/var, data"] 1`,
+	}, got)
+}
+
+func TestWriteFailedResponse(t *testing.T) {
+	address, _ := fakeServer(t, `{"response":"failed","info":"host is not allowed"}`)
+	z := newTestZabbix(address)
+	require.NoError(t, z.Connect())
+
+	_, err := z.Write([]cua.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	})
+	require.Error(t, err)
+}
+
+func TestQuoteParam(t *testing.T) {
+	require.Equal(t, "eth0", quoteParam("eth0"))
+	require.Equal(t, `"a,b"`, quoteParam("a,b"))
+	require.Equal(t, `"say \"hi\""`, quoteParam(`say "hi"`))
+	require.Equal(t, `" x"`, quoteParam(" x"))
+}
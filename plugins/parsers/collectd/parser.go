@@ -3,7 +3,6 @@ package collectd
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	"collectd.org/api"
@@ -23,6 +22,7 @@ type Parser struct {
 	// whether or not to split multi value metric into multiple metrics
 	// default value is split
 	ParseMultiValue string
+	Log             cua.Logger
 	popts           network.ParseOpts
 }
 
@@ -80,7 +80,7 @@ func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
 
 	metrics := []cua.Metric{}
 	for _, valueList := range valueLists {
-		metrics = append(metrics, UnmarshalValueList(valueList, p.ParseMultiValue)...)
+		metrics = append(metrics, UnmarshalValueList(valueList, p.ParseMultiValue, p.Log)...)
 	}
 
 	if len(p.DefaultTags) > 0 {
@@ -115,7 +115,7 @@ func (p *Parser) SetDefaultTags(tags map[string]string) {
 }
 
 // UnmarshalValueList translates a ValueList into a metric.
-func UnmarshalValueList(vl *api.ValueList, multiValue string) []cua.Metric {
+func UnmarshalValueList(vl *api.ValueList, multiValue string, log cua.Logger) []cua.Metric {
 	timestamp := vl.Time.UTC()
 
 	var metrics []cua.Metric
@@ -157,7 +157,7 @@ func UnmarshalValueList(vl *api.ValueList, multiValue string) []cua.Metric {
 			// Drop invalid points
 			m, err := metric.New(name, tags, fields, timestamp)
 			if err != nil {
-				log.Printf("E! Dropping metric %v: %v", name, err)
+				log.Errorf("Dropping metric %v: %v", name, err)
 				continue
 			}
 
@@ -193,12 +193,12 @@ func UnmarshalValueList(vl *api.ValueList, multiValue string) []cua.Metric {
 
 		m, err := metric.New(name, tags, fields, timestamp)
 		if err != nil {
-			log.Printf("E! Dropping metric %v: %v", name, err)
+			log.Errorf("Dropping metric %v: %v", name, err)
 		}
 
 		metrics = append(metrics, m)
 	default:
-		log.Printf("parse-multi-value config can only be 'split' or 'join'")
+		log.Errorf("parse-multi-value config can only be 'split' or 'join'")
 	}
 	return metrics
 }
@@ -3,7 +3,6 @@ package dropwizard
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
@@ -46,6 +45,8 @@ type Parser struct {
 	// an optional map of default tags to use for metrics
 	DefaultTags map[string]string
 
+	Log cua.Logger
+
 	separator      string
 	templateEngine *templating.Engine
 
@@ -158,7 +159,7 @@ func (p *Parser) readTags(buf []byte) map[string]string {
 		var tags map[string]string
 		err := json.Unmarshal(tagsBytes, &tags)
 		if err != nil {
-			log.Printf("W! failed to parse tags from JSON path '%s': %s\n", p.TagsPath, err)
+			p.Log.Warnf("Failed to parse tags from JSON path '%s': %s", p.TagsPath, err)
 		} else if len(tags) > 0 {
 			return tags
 		}
@@ -237,7 +238,7 @@ func (p *Parser) readDWMetrics(metricType string, dwms interface{}, metrics []cu
 			if err != nil || len(parsed) != 1 {
 				m, err = metric.New(measurementName, map[string]string{}, map[string]interface{}{}, tm)
 				if err != nil {
-					log.Printf("W! failed to create metric of type '%s': %s\n", metricType, err)
+					p.Log.Warnf("Failed to create metric of type '%s': %s", metricType, err)
 					continue
 				}
 			} else {
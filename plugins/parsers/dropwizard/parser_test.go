@@ -7,6 +7,7 @@ import (
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -376,6 +377,7 @@ func TestTagParsingProblems(t *testing.T) {
 	parser1 := NewParser()
 	parser1.MetricRegistryPath = metricRegistryPath
 	parser1.TagsPath = "tags1"
+	parser1.Log = testutil.Logger{}
 	metrics1, err1 := parser1.Parse([]byte(validEmbeddedCounterJSON))
 	assert.NoError(t, err1)
 	assert.Len(t, metrics1, 1)
@@ -385,6 +387,7 @@ func TestTagParsingProblems(t *testing.T) {
 	parser2 := NewParser()
 	parser2.MetricRegistryPath = metricRegistryPath
 	parser2.TagsPath = "tags1"
+	parser2.Log = testutil.Logger{}
 	parser2.TagPathsMap = map[string]string{"tag1": "tags.tag1"}
 	metrics2, err2 := parser2.Parse([]byte(validEmbeddedCounterJSON))
 	assert.NoError(t, err2)
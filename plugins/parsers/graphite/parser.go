@@ -24,8 +24,10 @@ var (
 type Parser struct {
 	Separator      string
 	Templates      []string
+	RegexTemplates []string
 	DefaultTags    map[string]string
 	templateEngine *templating.Engine
+	regexTemplates []*regexTemplate
 }
 
 func (p *Parser) SetDefaultTags(tags map[string]string) {
@@ -59,6 +61,33 @@ func NewGraphiteParser(
 	return p, nil
 }
 
+// SetRegexTemplates sets the regex templates, they are evaluated in order
+// before the templates and the first matching one is used.  Metric paths
+// not matching any regex template fall back to the templates.
+func (p *Parser) SetRegexTemplates(templates []string) error {
+	rts := make([]*regexTemplate, 0, len(templates))
+	for _, t := range templates {
+		rt, err := parseRegexTemplate(t)
+		if err != nil {
+			return err
+		}
+		rts = append(rts, rt)
+	}
+	p.RegexTemplates = templates
+	p.regexTemplates = rts
+	return nil
+}
+
+// apply decodes the measurement, tags and field from the metric path.
+func (p *Parser) apply(path string) (string, map[string]string, string, error) {
+	for _, rt := range p.regexTemplates {
+		if measurement, tags, field, ok := rt.apply(path, p.Separator); ok {
+			return measurement, tags, field, nil
+		}
+	}
+	return p.templateEngine.Apply(path) //nolint:wrapcheck
+}
+
 func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
 	// parse even if the buffer begins with a newline
 	if len(buf) != 0 && buf[0] == '\n' {
@@ -104,7 +133,7 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 	}
 
 	// decode the name and tags
-	measurement, tags, field, err := p.templateEngine.Apply(fields[0])
+	measurement, tags, field, err := p.apply(fields[0])
 	if err != nil {
 		return nil, fmt.Errorf("apply template: %w", err)
 	}
@@ -167,7 +196,7 @@ func (p *Parser) ApplyTemplate(line string) (string, map[string]string, string,
 	}
 
 	// decode the name and tags
-	name, tags, field, err := p.templateEngine.Apply(fields[0])
+	name, tags, field, err := p.apply(fields[0])
 
 	// Set the default tags on the point if they are not already set
 	for k, v := range p.DefaultTags {
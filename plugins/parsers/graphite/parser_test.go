@@ -841,3 +841,78 @@ func errstr(err error) string {
 	}
 	return ""
 }
+
+func TestParseRegexTemplates(t *testing.T) {
+	p, err := NewGraphiteParser("_", []string{
+		"servers.* .host.measurement*",
+		"measurement*",
+	}, map[string]string{"dc": "east"})
+	require.NoError(t, err)
+	require.NoError(t, p.SetRegexTemplates([]string{
+		`^collectd\.(?P<host>[^.]+)\.(?P<measurement>cpu)-(?P<cpu>\d+)\.(?P<field>.+)$`,
+		`^stats\.counters\.(?P<measurement>[^.]+)\.(?P<measurement>[^.]+)\.count$ field=count,source=statsd`,
+		`^app\.(?P<env>prod|dev)\. measurement=app`,
+	}))
+
+	tests := []struct {
+		input       string
+		measurement string
+		tags        map[string]string
+		field       string
+	}{
+		{
+			input:       "collectd.web01.cpu-0.idle 1 1435077219",
+			measurement: "cpu",
+			tags:        map[string]string{"host": "web01", "cpu": "0", "dc": "east"},
+			field:       "idle",
+		},
+		{
+			input:       "stats.counters.api.requests.count 1 1435077219",
+			measurement: "api_requests",
+			tags:        map[string]string{"source": "statsd", "dc": "east"},
+			field:       "count",
+		},
+		{
+			input:       "app.prod.latency 1 1435077219",
+			measurement: "app",
+			tags:        map[string]string{"env": "prod", "dc": "east"},
+			field:       "value",
+		},
+		{
+			// falls back to the templates
+			input:       "servers.localhost.cpu.load 1 1435077219",
+			measurement: "cpu_load",
+			tags:        map[string]string{"host": "localhost", "dc": "east"},
+			field:       "value",
+		},
+		{
+			// default catch-all template
+			input:       "other.thing 1 1435077219",
+			measurement: "other_thing",
+			tags:        map[string]string{"dc": "east"},
+			field:       "value",
+		},
+	}
+
+	for _, tt := range tests {
+		m, err := p.ParseLine(tt.input)
+		require.NoError(t, err, tt.input)
+		require.Equal(t, tt.measurement, m.Name(), tt.input)
+		require.Equal(t, tt.tags, m.Tags(), tt.input)
+		require.Equal(t, map[string]interface{}{tt.field: 1.0}, m.Fields(), tt.input)
+	}
+}
+
+func TestRegexTemplatesInvalid(t *testing.T) {
+	p, err := NewGraphiteParser("", nil, nil)
+	require.NoError(t, err)
+
+	for _, rt := range []string{
+		`^servers\.(`,
+		`^servers\.(?P<host>[^.]+)`,
+		`^(?P<measurement>cpu) host`,
+		`^(?P<measurement>cpu) a=b c=d`,
+	} {
+		require.Error(t, p.SetRegexTemplates([]string{rt}), rt)
+	}
+}
@@ -0,0 +1,101 @@
+package graphite
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexTemplate maps metric paths matching a regular expression to a
+// measurement, field and tags.  The named capture groups "measurement" and
+// "field" set the measurement and field, every other named group becomes a
+// tag.  Groups sharing a name are joined with the separator.
+type regexTemplate struct {
+	re          *regexp.Regexp
+	measurement string
+	field       string
+	tags        map[string]string
+}
+
+// parseRegexTemplate parses "<regex> [tag=value,...]", the "measurement"
+// and "field" keys in the tag list set a static measurement or field.
+func parseRegexTemplate(s string) (*regexTemplate, error) {
+	parts := strings.Fields(s)
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, fmt.Errorf("invalid regex template format: '%s'", s)
+	}
+
+	re, err := regexp.Compile(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex template '%s': %w", s, err)
+	}
+
+	rt := &regexTemplate{re: re, tags: make(map[string]string)}
+	if len(parts) == 2 {
+		for _, kv := range strings.Split(parts[1], ",") {
+			tag := strings.SplitN(kv, "=", 2)
+			if len(tag) != 2 || tag[0] == "" || tag[1] == "" {
+				return nil, fmt.Errorf("invalid tag format '%s' in regex template '%s'", kv, s)
+			}
+			switch tag[0] {
+			case "measurement":
+				rt.measurement = tag[1]
+			case "field":
+				rt.field = tag[1]
+			default:
+				rt.tags[tag[0]] = tag[1]
+			}
+		}
+	}
+
+	var hasMeasurement bool
+	for _, name := range re.SubexpNames() {
+		if name == "measurement" {
+			hasMeasurement = true
+		}
+	}
+	if !hasMeasurement && rt.measurement == "" {
+		return nil, fmt.Errorf("no measurement in regex template '%s'", s)
+	}
+
+	return rt, nil
+}
+
+// apply returns the measurement, tags and field for the metric path, ok is
+// false when the path does not match.
+func (rt *regexTemplate) apply(path, separator string) (string, map[string]string, string, bool) {
+	match := rt.re.FindStringSubmatch(path)
+	if match == nil {
+		return "", nil, "", false
+	}
+
+	parts := make(map[string][]string)
+	for i, name := range rt.re.SubexpNames() {
+		if name == "" || match[i] == "" {
+			continue
+		}
+		parts[name] = append(parts[name], match[i])
+	}
+
+	measurement := rt.measurement
+	if m, ok := parts["measurement"]; ok {
+		measurement = strings.Join(m, separator)
+	}
+	field := rt.field
+	if f, ok := parts["field"]; ok {
+		field = strings.Join(f, separator)
+	}
+
+	tags := make(map[string]string, len(rt.tags)+len(parts))
+	for k, v := range rt.tags {
+		tags[k] = v
+	}
+	for k, v := range parts {
+		if k == "measurement" || k == "field" {
+			continue
+		}
+		tags[k] = strings.Join(v, separator)
+	}
+
+	return measurement, tags, field, true
+}
@@ -36,4 +36,31 @@ COMBINED_LOG_FORMAT %{COMMON_LOG_FORMAT} "%{DATA:referrer}" "%{DATA:agent}"
 HTTPD20_ERRORLOG \[%{HTTPDERROR_DATE:timestamp}\] \[%{LOGLEVEL:loglevel:tag}\] (?:\[client %{IPORHOST:clientip}\] ){0,1}%{GREEDYDATA:errormsg}
 HTTPD24_ERRORLOG \[%{HTTPDERROR_DATE:timestamp}\] \[%{WORD:module}:%{LOGLEVEL:loglevel:tag}\] \[pid %{POSINT:pid:int}:tid %{NUMBER:tid:int}\]( \(%{POSINT:proxy_errorcode:int}\)%{DATA:proxy_errormessage}:)?( \[client %{IPORHOST:client}:%{POSINT:clientport}\])? %{DATA:errorcode}: %{GREEDYDATA:message}
 HTTPD_ERRORLOG %{HTTPD20_ERRORLOG}|%{HTTPD24_ERRORLOG}
+
+##
+## SYSLOG PATTERNS
+##
+
+# BSD syslog (RFC 3164) lines as written by syslog daemons, the timestamp is
+# parsed as the metric time, the year is the current year.
+#   Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick
+SYSLOGSOURCE (?:%{SYSLOGFACILITY} )?%{SYSLOGHOST:logsource:tag}(?: %{PROG:program:tag}(?:\[%{POSINT:pid:int}\])?:|)
+SYSLOGBASE2 %{SYSLOGTIMESTAMP:timestamp:ts-syslog} %{SYSLOGSOURCE}
+SYSLOGLINE %{SYSLOGBASE2} %{GREEDYDATA:message}
+
+# syslog lines with high precision timestamps, ie the rsyslog default
+#   2021-02-03T10:00:00.123456+00:00 mymachine kernel: oops
+SYSLOGBASE2_ISO8601 %{TIMESTAMP_ISO8601:timestamp:ts-rfc3339} %{SYSLOGSOURCE}
+SYSLOGLINE_ISO8601 %{SYSLOGBASE2_ISO8601} %{GREEDYDATA:message}
+SYSLOGPAMSESSION %{SYSLOGBASE2} %{WORD:pam_module:tag}\(%{DATA:pam_caller}\): session %{WORD:pam_session_state:tag} for user %{USERNAME:username}(?: by %{GREEDYDATA:pam_by})?
+CRON_ACTION [A-Z ]+
+CRONLOG %{SYSLOGBASE2} \(%{USER:user}\) %{CRON_ACTION:action:tag} \(%{DATA:message}\)
+
+# IETF syslog (RFC 5424) lines
+#   <34>1 2003-10-11T22:14:15.003Z mymachine su - ID47 - 'su root' failed
+SYSLOG5424PRINTASCII [!-~]+
+SYSLOG5424PRI <%{NONNEGINT:syslog5424_pri:int}>
+SYSLOG5424SD \[%{DATA}\]+
+SYSLOG5424BASE %{SYSLOG5424PRI}%{NONNEGINT:syslog5424_ver:int} +(?:%{TIMESTAMP_ISO8601:syslog5424_ts:ts-rfc3339}|-) +(?:%{HOSTNAME:syslog5424_host:tag}|-) +(-|%{SYSLOG5424PRINTASCII:syslog5424_app:tag}) +(-|%{SYSLOG5424PRINTASCII:syslog5424_proc}) +(-|%{SYSLOG5424PRINTASCII:syslog5424_msgid}) +(?:%{SYSLOG5424SD:syslog5424_sd}|-|)
+SYSLOG5424LINE %{SYSLOG5424BASE} +%{GREEDYDATA:syslog5424_msg}
 `
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
-	"log"
 	"os"
 	"regexp"
 	"strconv"
@@ -76,6 +75,7 @@ type Parser struct {
 	CustomPatternFiles []string
 	Measurement        string
 	DefaultTags        map[string]string
+	Log                cua.Logger
 
 	// Timezone is an optional component to help render log dates to
 	// your chosen zone.
@@ -180,7 +180,7 @@ func (p *Parser) Compile() error {
 
 	p.loc, err = time.LoadLocation(p.Timezone)
 	if err != nil {
-		log.Printf("W! improper timezone supplied (%s), setting loc to UTC", p.Timezone)
+		p.Log.Warnf("Improper timezone supplied (%s), setting loc to UTC", p.Timezone)
 		p.loc, _ = time.LoadLocation("UTC")
 	}
 
@@ -209,7 +209,7 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 	}
 
 	if len(values) == 0 {
-		log.Printf("D! Grok no match found for: %q", line)
+		p.Log.Debugf("Grok no match found for: %q", line)
 		return nil, nil
 	}
 
@@ -252,21 +252,21 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 		case INT:
 			iv, err := strconv.ParseInt(v, 0, 64)
 			if err != nil {
-				log.Printf("E! Error parsing %s to int: %s", v, err)
+				p.Log.Errorf("Error parsing %s to int: %s", v, err)
 			} else {
 				fields[k] = iv
 			}
 		case FLOAT:
 			fv, err := strconv.ParseFloat(v, 64)
 			if err != nil {
-				log.Printf("E! Error parsing %s to float: %s", v, err)
+				p.Log.Errorf("Error parsing %s to float: %s", v, err)
 			} else {
 				fields[k] = fv
 			}
 		case DURATION:
 			d, err := time.ParseDuration(v)
 			if err != nil {
-				log.Printf("E! Error parsing %s to duration: %s", v, err)
+				p.Log.Errorf("Error parsing %s to duration: %s", v, err)
 			} else {
 				fields[k] = int64(d)
 			}
@@ -277,13 +277,13 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 		case EPOCH:
 			parts := strings.SplitN(v, ".", 2)
 			if len(parts) == 0 {
-				log.Printf("E! Error parsing %s to timestamp: %s", v, err)
+				p.Log.Errorf("Error parsing %s to timestamp: %s", v, err)
 				break
 			}
 
 			sec, err := strconv.ParseInt(parts[0], 10, 64)
 			if err != nil {
-				log.Printf("E! Error parsing %s to timestamp: %s", v, err)
+				p.Log.Errorf("Error parsing %s to timestamp: %s", v, err)
 				break
 			}
 			ts := time.Unix(sec, 0)
@@ -293,7 +293,7 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 				nsString := strings.ReplaceAll(padded[:9], " ", "0")
 				nanosec, err := strconv.ParseInt(nsString, 10, 64)
 				if err != nil {
-					log.Printf("E! Error parsing %s to timestamp: %s", v, err)
+					p.Log.Errorf("Error parsing %s to timestamp: %s", v, err)
 					break
 				}
 				ts = ts.Add(time.Duration(nanosec) * time.Nanosecond)
@@ -302,14 +302,14 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 		case EPOCHMILLI:
 			ms, err := strconv.ParseInt(v, 10, 64)
 			if err != nil {
-				log.Printf("E! Error parsing %s to int: %s", v, err)
+				p.Log.Errorf("Error parsing %s to int: %s", v, err)
 			} else {
 				timestamp = time.Unix(0, ms*int64(time.Millisecond))
 			}
 		case EPOCHNANO:
 			iv, err := strconv.ParseInt(v, 10, 64)
 			if err != nil {
-				log.Printf("E! Error parsing %s to int: %s", v, err)
+				p.Log.Errorf("Error parsing %s to int: %s", v, err)
 			} else {
 				timestamp = time.Unix(0, iv)
 			}
@@ -321,7 +321,7 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 				}
 				timestamp = ts
 			} else {
-				log.Printf("E! Error parsing %s to time layout [%s]: %s", v, t, err)
+				p.Log.Errorf("Error parsing %s to time layout [%s]: %s", v, t, err)
 			}
 		case GENERICTIMESTAMP:
 			var foundTs bool
@@ -350,7 +350,7 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 			// if we still haven't found a timestamp layout, log it and we will
 			// just use time.Now()
 			if !foundTs {
-				log.Printf("E! Error parsing timestamp [%s], could not find any "+
+				p.Log.Errorf("Error parsing timestamp [%s], could not find any "+
 					"suitable time layouts.", v)
 			}
 		case DROP:
@@ -364,7 +364,7 @@ func (p *Parser) ParseLine(line string) (cua.Metric, error) {
 				}
 				timestamp = ts
 			} else {
-				log.Printf("E! Error parsing %s to time layout [%s]: %s", v, t, err)
+				p.Log.Errorf("Error parsing %s to time layout [%s]: %s", v, t, err)
 			}
 		}
 	}
@@ -420,7 +420,7 @@ func TestParseEpochErrors(t *testing.T) {
 		Patterns: []string{"%{MYAPP}"},
 		CustomPatterns: `
 			MYAPP %{WORD:ts:ts-epoch} response_time=%{POSINT:response_time:int} mymetric=%{NUMBER:metric:float}
-		`,
+		`, Log: testutil.Logger{},
 	}
 	assert.NoError(t, p.Compile())
 
@@ -431,7 +431,7 @@ func TestParseEpochErrors(t *testing.T) {
 		Patterns: []string{"%{MYAPP}"},
 		CustomPatterns: `
 			MYAPP %{WORD:ts:ts-epochnano} response_time=%{POSINT:response_time:int} mymetric=%{NUMBER:metric:float}
-		`,
+		`, Log: testutil.Logger{},
 	}
 	assert.NoError(t, p.Compile())
 
@@ -472,6 +472,7 @@ func TestParseGenericTimestamp(t *testing.T) {
 
 func TestParseGenericTimestampNotFound(t *testing.T) {
 	p := &Parser{
+		Log:      testutil.Logger{},
 		Patterns: []string{`\[%{NOTSPACE:ts:ts}\] response_time=%{POSINT:response_time:int} mymetric=%{NUMBER:metric:float}`},
 	}
 	assert.NoError(t, p.Compile())
@@ -552,6 +553,7 @@ func TestCompileNoModifiersAndParse(t *testing.T) {
 
 func TestCompileNoNamesAndParse(t *testing.T) {
 	p := &Parser{
+		Log:      testutil.Logger{},
 		Patterns: []string{"%{TEST_LOG_C}"},
 		CustomPatterns: `
 			DURATION %{NUMBER}[nuµm]?s
@@ -567,6 +569,7 @@ func TestCompileNoNamesAndParse(t *testing.T) {
 
 func TestParseNoMatch(t *testing.T) {
 	p := &Parser{
+		Log:                testutil.Logger{},
 		Patterns:           []string{"%{TEST_LOG_A}", "%{TEST_LOG_B}"},
 		CustomPatternFiles: []string{"./testdata/test-patterns"},
 	}
@@ -610,6 +613,7 @@ func TestParseErrors_MissingPattern(t *testing.T) {
 
 func TestParseErrors_WrongIntegerType(t *testing.T) {
 	p := &Parser{
+		Log:         testutil.Logger{},
 		Measurement: "grok",
 		Patterns:    []string{"%{TEST_LOG_A}"},
 		CustomPatterns: `
@@ -626,6 +630,7 @@ func TestParseErrors_WrongIntegerType(t *testing.T) {
 
 func TestParseErrors_WrongFloatType(t *testing.T) {
 	p := &Parser{
+		Log:         testutil.Logger{},
 		Measurement: "grok",
 		Patterns:    []string{"%{TEST_LOG_A}"},
 		CustomPatterns: `
@@ -642,6 +647,7 @@ func TestParseErrors_WrongFloatType(t *testing.T) {
 
 func TestParseErrors_WrongDurationType(t *testing.T) {
 	p := &Parser{
+		Log:         testutil.Logger{},
 		Measurement: "grok",
 		Patterns:    []string{"%{TEST_LOG_A}"},
 		CustomPatterns: `
@@ -658,6 +664,7 @@ func TestParseErrors_WrongDurationType(t *testing.T) {
 
 func TestParseErrors_WrongTimeLayout(t *testing.T) {
 	p := &Parser{
+		Log:         testutil.Logger{},
 		Measurement: "grok",
 		Patterns:    []string{"%{TEST_LOG_A}"},
 		CustomPatterns: `
@@ -821,6 +828,7 @@ func TestTimezoneEmptyCompileFileAndParse(t *testing.T) {
 
 func TestTimezoneMalformedCompileFileAndParse(t *testing.T) {
 	p := &Parser{
+		Log:                testutil.Logger{},
 		Patterns:           []string{"%{TEST_LOG_A}", "%{TEST_LOG_B}"},
 		CustomPatternFiles: []string{"./testdata/test-patterns"},
 		Timezone:           "Something/Weird",
@@ -1117,3 +1125,79 @@ func TestTrimRegression(t *testing.T) {
 	)
 	require.Equal(t, expected, actual)
 }
+
+func TestSyslogPatterns(t *testing.T) {
+	currentYear := time.Now().Year()
+	tests := []struct {
+		name    string
+		pattern string
+		line    string
+		tags    map[string]string
+		fields  map[string]interface{}
+		time    time.Time
+	}{
+		{
+			name:    "syslog line",
+			pattern: "%{SYSLOGLINE}",
+			line:    "Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick",
+			tags:    map[string]string{"logsource": "mymachine", "program": "su"},
+			fields:  map[string]interface{}{"pid": int64(123), "message": "'su root' failed for lonvick"},
+			time:    time.Date(currentYear, time.October, 11, 22, 14, 15, 0, time.UTC),
+		},
+		{
+			name:    "syslog line high precision timestamp",
+			pattern: "%{SYSLOGLINE_ISO8601}",
+			line:    "2021-02-03T10:00:00.123456+00:00 mymachine kernel: oops",
+			tags:    map[string]string{"logsource": "mymachine", "program": "kernel"},
+			fields:  map[string]interface{}{"message": "oops"},
+			time:    time.Date(2021, time.February, 3, 10, 0, 0, 123456000, time.UTC),
+		},
+		{
+			name:    "rfc5424 line",
+			pattern: "%{SYSLOG5424LINE}",
+			line:    "<34>1 2003-10-11T22:14:15.003Z mymachine su - ID47 - 'su root' failed",
+			tags:    map[string]string{"syslog5424_host": "mymachine", "syslog5424_app": "su"},
+			fields: map[string]interface{}{
+				"syslog5424_pri":   int64(34),
+				"syslog5424_ver":   int64(1),
+				"syslog5424_msgid": "ID47",
+				"syslog5424_msg":   "'su root' failed",
+			},
+			time: time.Date(2003, time.October, 11, 22, 14, 15, 3000000, time.UTC),
+		},
+		{
+			name:    "cron line",
+			pattern: "%{CRONLOG}",
+			line:    "Oct 11 22:14:15 host CRON[42]: (root) CMD (run-parts /etc/cron.hourly)",
+			tags:    map[string]string{"logsource": "host", "program": "CRON", "action": "CMD"},
+			fields:  map[string]interface{}{"pid": int64(42), "user": "root", "message": "run-parts /etc/cron.hourly"},
+			time:    time.Date(currentYear, time.October, 11, 22, 14, 15, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{Patterns: []string{tt.pattern}}
+			require.NoError(t, p.Compile())
+			m, err := p.ParseLine(tt.line)
+			require.NoError(t, err)
+			require.NotNil(t, m)
+			require.Equal(t, tt.tags, m.Tags())
+			require.Equal(t, tt.fields, m.Fields())
+			require.Equal(t, tt.time, m.Time().UTC())
+		})
+	}
+}
+
+func TestRegexNamedCaptures(t *testing.T) {
+	p := &Parser{
+		Patterns:       []string{`%{STATUS:status:int} (?P<path>/\S*) (?P<method>[A-Z]+)`},
+		CustomPatterns: `STATUS \d{3}`,
+	}
+	require.NoError(t, p.Compile())
+
+	m, err := p.ParseLine("404 /missing GET")
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	require.Equal(t, map[string]interface{}{"status": int64(404), "path": "/missing", "method": "GET"}, m.Fields())
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"time"
 
@@ -45,6 +44,8 @@ type Parser struct {
 	timezone     string
 	defaultTags  map[string]string
 	strict       bool
+
+	Log cua.Logger
 }
 
 func New(config *Config) (*Parser, error) {
@@ -166,7 +167,7 @@ func (p *Parser) switchFieldToTag(tags map[string]string, fields map[string]inte
 			tags[name] = strconv.FormatFloat(value, 'f', -1, 64)
 			delete(fields, name)
 		default:
-			log.Printf("E! [parsers.json] Unrecognized type %T", value)
+			p.Log.Errorf("Unrecognized type %T", value)
 		}
 	}
 
@@ -0,0 +1,547 @@
+package jsonv2
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/tidwall/gjson"
+)
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// Config declares how the metrics of a document are extracted, paths use
+// the GJSON syntax (https://github.com/tidwall/gjson/blob/master/SYNTAX.md).
+type Config struct {
+	// MeasurementName is the measurement, defaults to the plugin name.
+	MeasurementName string `toml:"measurement_name"`
+	// MeasurementNamePath is the path of the measurement.
+	MeasurementNamePath string `toml:"measurement_name_path"`
+
+	// TimestampPath is the path of the metric time, the current time is
+	// used when not set.
+	TimestampPath     string `toml:"timestamp_path"`
+	TimestampFormat   string `toml:"timestamp_format"`
+	TimestampTimezone string `toml:"timestamp_timezone"`
+
+	Tags    []DataSet `toml:"tag"`
+	Fields  []DataSet `toml:"field"`
+	Objects []Object  `toml:"object"`
+}
+
+// DataSet is a tag or field at a path.  When the path returns an array a
+// metric is created for each element.
+type DataSet struct {
+	Path string `toml:"path"`
+	// Rename is the tag or field key, defaults to the last key of the path.
+	Rename string `toml:"rename"`
+	// Type of the field: "int", "uint", "float", "string" or "bool",
+	// defaults to the JSON type.
+	Type string `toml:"type"`
+	// Optional ignores the data set when the path does not exist instead of
+	// failing the parse.
+	Optional bool `toml:"optional"`
+}
+
+// Object is the path of an object or an array of objects, each object is
+// flattened to a metric.
+type Object struct {
+	Path     string `toml:"path"`
+	Optional bool   `toml:"optional"`
+
+	TimestampKey      string `toml:"timestamp_key"`
+	TimestampFormat   string `toml:"timestamp_format"`
+	TimestampTimezone string `toml:"timestamp_timezone"`
+
+	// DisablePrependKeys uses the key of nested values as is instead of
+	// prefixing it with the keys of the parents.
+	DisablePrependKeys bool              `toml:"disable_prepend_keys"`
+	IncludedKeys       []string          `toml:"included_keys"`
+	ExcludedKeys       []string          `toml:"excluded_keys"`
+	Tags               []string          `toml:"tags"`
+	Renames            map[string]string `toml:"renames"`
+	// Fields sets the type of fields, see DataSet.Type.
+	Fields map[string]string `toml:"fields"`
+}
+
+// Parser extracts metrics from JSON documents using explicit paths.
+type Parser struct {
+	Configs     []Config
+	MetricName  string
+	DefaultTags map[string]string
+	TimeFunc    func() time.Time
+}
+
+// row is a partial metric built from the tag and field data sets.
+type row struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// New validates the configuration and returns a parser.
+func New(configs []Config, metricName string, defaultTags map[string]string) (*Parser, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("json_v2: no configuration")
+	}
+	for _, c := range configs {
+		if c.TimestampPath != "" && c.TimestampFormat == "" {
+			return nil, fmt.Errorf("json_v2: timestamp_format is required with timestamp_path")
+		}
+		dataSets := make([]DataSet, 0, len(c.Tags)+len(c.Fields))
+		dataSets = append(dataSets, c.Tags...)
+		dataSets = append(dataSets, c.Fields...)
+		for _, ds := range dataSets {
+			if ds.Path == "" {
+				return nil, fmt.Errorf("json_v2: tag and field path is required")
+			}
+			if err := checkType(ds.Type); err != nil {
+				return nil, err
+			}
+		}
+		for _, o := range c.Objects {
+			if o.Path == "" {
+				return nil, fmt.Errorf("json_v2: object path is required")
+			}
+			if o.TimestampKey != "" && o.TimestampFormat == "" {
+				return nil, fmt.Errorf("json_v2: timestamp_format is required with timestamp_key")
+			}
+			for _, t := range o.Fields {
+				if err := checkType(t); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return &Parser{
+		Configs:     configs,
+		MetricName:  metricName,
+		DefaultTags: defaultTags,
+		TimeFunc:    time.Now,
+	}, nil
+}
+
+func checkType(t string) error {
+	switch t {
+	case "", "int", "uint", "float", "string", "bool":
+		return nil
+	default:
+		return fmt.Errorf("json_v2: unknown type %q", t)
+	}
+}
+
+// Parse converts a JSON document to metrics.
+func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
+	buf = bytes.TrimPrefix(buf, utf8BOM)
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return make([]cua.Metric, 0), nil
+	}
+	if !gjson.ValidBytes(buf) {
+		return nil, fmt.Errorf("json_v2: invalid JSON")
+	}
+	doc := gjson.ParseBytes(buf)
+	now := p.TimeFunc()
+
+	metrics := make([]cua.Metric, 0)
+	for _, c := range p.Configs {
+		m, err := p.parseConfig(doc, c, now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseConfig(doc gjson.Result, c Config, now time.Time) ([]cua.Metric, error) {
+	name := p.MetricName
+	if c.MeasurementName != "" {
+		name = c.MeasurementName
+	}
+	if c.MeasurementNamePath != "" {
+		r := doc.Get(c.MeasurementNamePath)
+		if !r.Exists() || r.String() == "" {
+			return nil, fmt.Errorf("json_v2: measurement name path %q does not exist", c.MeasurementNamePath)
+		}
+		name = r.String()
+	}
+
+	timestamp := now
+	if c.TimestampPath != "" {
+		r := doc.Get(c.TimestampPath)
+		if !r.Exists() {
+			return nil, fmt.Errorf("json_v2: timestamp path %q does not exist", c.TimestampPath)
+		}
+		t, err := internal.ParseTimestamp(c.TimestampFormat, r.String(), c.TimestampTimezone)
+		if err != nil {
+			return nil, fmt.Errorf("json_v2: timestamp: %w", err)
+		}
+		timestamp = t
+	}
+
+	rows := []row{{tags: map[string]string{}, fields: map[string]interface{}{}}}
+	for _, ds := range c.Tags {
+		values, err := dataSetValues(doc, ds, true)
+		if err != nil {
+			return nil, err
+		}
+		rows = expand(rows, dataSetKey(ds), values, true)
+	}
+	for _, ds := range c.Fields {
+		values, err := dataSetValues(doc, ds, false)
+		if err != nil {
+			return nil, err
+		}
+		rows = expand(rows, dataSetKey(ds), values, false)
+	}
+
+	var metrics []cua.Metric
+	if len(c.Objects) == 0 {
+		for _, r := range rows {
+			metrics = p.appendMetric(metrics, name, r, timestamp)
+		}
+		return metrics, nil
+	}
+
+	for _, o := range c.Objects {
+		objRows, err := objectRows(doc, o, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		for _, base := range rows {
+			for _, or := range objRows {
+				r := row{
+					tags:   make(map[string]string, len(base.tags)+len(or.tags)),
+					fields: make(map[string]interface{}, len(base.fields)+len(or.fields)),
+				}
+				for k, v := range base.tags {
+					r.tags[k] = v
+				}
+				for k, v := range or.tags {
+					r.tags[k] = v
+				}
+				for k, v := range base.fields {
+					r.fields[k] = v
+				}
+				for k, v := range or.fields {
+					r.fields[k] = v
+				}
+				metrics = p.appendMetric(metrics, name, r, or.timestamp)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) appendMetric(metrics []cua.Metric, name string, r row, t time.Time) []cua.Metric {
+	// metrics without fields are not valid
+	if len(r.fields) == 0 {
+		return metrics
+	}
+	tags := make(map[string]string, len(p.DefaultTags)+len(r.tags))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for k, v := range r.tags {
+		tags[k] = v
+	}
+	m, err := metric.New(name, tags, r.fields, t)
+	if err != nil {
+		return metrics
+	}
+	return append(metrics, m)
+}
+
+// dataSetValues returns the values at the path, an array is expanded to
+// its elements.
+func dataSetValues(doc gjson.Result, ds DataSet, tag bool) ([]interface{}, error) {
+	r := doc.Get(ds.Path)
+	if !r.Exists() {
+		if ds.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("json_v2: path %q does not exist", ds.Path)
+	}
+
+	results := []gjson.Result{r}
+	if r.IsArray() {
+		results = r.Array()
+	}
+
+	values := make([]interface{}, 0, len(results))
+	for _, r := range results {
+		if tag {
+			if r.Type != gjson.Null {
+				values = append(values, r.String())
+			}
+			continue
+		}
+		v, err := convert(r, ds.Type)
+		if err != nil {
+			return nil, fmt.Errorf("json_v2: path %q: %w", ds.Path, err)
+		}
+		if v != nil {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// expand returns a row for each combination of the rows and values.
+func expand(rows []row, key string, values []interface{}, tag bool) []row {
+	if len(values) == 0 {
+		return rows
+	}
+	expanded := make([]row, 0, len(rows)*len(values))
+	for _, r := range rows {
+		for _, v := range values {
+			n := r
+			if len(values) > 1 {
+				n = row{
+					tags:   make(map[string]string, len(r.tags)+1),
+					fields: make(map[string]interface{}, len(r.fields)+1),
+				}
+				for k, v := range r.tags {
+					n.tags[k] = v
+				}
+				for k, v := range r.fields {
+					n.fields[k] = v
+				}
+			}
+			if tag {
+				n.tags[key] = v.(string)
+			} else {
+				n.fields[key] = v
+			}
+			expanded = append(expanded, n)
+		}
+	}
+	return expanded
+}
+
+// dataSetKey returns the rename or the last key of the path.
+func dataSetKey(ds DataSet) string {
+	if ds.Rename != "" {
+		return ds.Rename
+	}
+	path := ds.Path
+	if i := strings.IndexAny(path, "|@"); i > 0 {
+		path = path[:i]
+	}
+	keys := strings.Split(path, ".")
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := keys[i]
+		if key != "" && key != "#" && !strings.HasPrefix(key, "#(") {
+			return key
+		}
+	}
+	return ds.Path
+}
+
+type objectRow struct {
+	row
+	timestamp time.Time
+}
+
+// objectRows flattens the object or each object of the array at the path.
+func objectRows(doc gjson.Result, o Object, timestamp time.Time) ([]objectRow, error) {
+	r := doc.Get(o.Path)
+	if !r.Exists() {
+		if o.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("json_v2: object path %q does not exist", o.Path)
+	}
+
+	var objects []gjson.Result
+	switch {
+	case r.IsArray():
+		objects = r.Array()
+	case r.IsObject():
+		objects = []gjson.Result{r}
+	default:
+		return nil, fmt.Errorf("json_v2: object path %q is not an object or array", o.Path)
+	}
+
+	included := make(map[string]bool, len(o.IncludedKeys))
+	for _, k := range o.IncludedKeys {
+		included[k] = true
+	}
+	excluded := make(map[string]bool, len(o.ExcludedKeys))
+	for _, k := range o.ExcludedKeys {
+		excluded[k] = true
+	}
+	tagKeys := make(map[string]bool, len(o.Tags))
+	for _, k := range o.Tags {
+		tagKeys[k] = true
+	}
+
+	rows := make([]objectRow, 0, len(objects))
+	for _, obj := range objects {
+		if !obj.IsObject() {
+			return nil, fmt.Errorf("json_v2: object path %q contains a non object", o.Path)
+		}
+
+		values := make(map[string]gjson.Result)
+		flatten(values, "", obj, o.DisablePrependKeys)
+
+		or := objectRow{
+			row: row{
+				tags:   make(map[string]string),
+				fields: make(map[string]interface{}),
+			},
+			timestamp: timestamp,
+		}
+		for key, v := range values {
+			if key == o.TimestampKey {
+				t, err := internal.ParseTimestamp(o.TimestampFormat, v.String(), o.TimestampTimezone)
+				if err != nil {
+					return nil, fmt.Errorf("json_v2: timestamp: %w", err)
+				}
+				or.timestamp = t
+				continue
+			}
+
+			if len(included) > 0 && !included[key] && !tagKeys[key] {
+				continue
+			}
+			if excluded[key] {
+				continue
+			}
+
+			name := key
+			if rename, ok := o.Renames[key]; ok {
+				name = rename
+			}
+			if tagKeys[key] {
+				if v.Type != gjson.Null {
+					or.tags[name] = v.String()
+				}
+				continue
+			}
+
+			value, err := convert(v, o.Fields[key])
+			if err != nil {
+				return nil, fmt.Errorf("json_v2: key %q: %w", key, err)
+			}
+			if value != nil {
+				or.fields[name] = value
+			}
+		}
+		rows = append(rows, or)
+	}
+	return rows, nil
+}
+
+// flatten adds the values of nested objects and arrays joining the keys
+// with "_", array elements are keyed by their index.
+func flatten(values map[string]gjson.Result, prefix string, r gjson.Result, disablePrepend bool) {
+	if !r.IsObject() && !r.IsArray() {
+		values[prefix] = r
+		return
+	}
+
+	i := 0
+	r.ForEach(func(k, v gjson.Result) bool {
+		key := k.String()
+		if r.IsArray() {
+			key = strconv.Itoa(i)
+			i++
+		}
+		switch {
+		case prefix == "":
+		case disablePrepend && r.IsObject():
+		default:
+			key = prefix + "_" + key
+		}
+		flatten(values, key, v, disablePrepend)
+		return true
+	})
+}
+
+// convert returns the value of the result as the type, nil is returned for
+// JSON null values.
+func convert(r gjson.Result, typ string) (interface{}, error) {
+	if r.Type == gjson.Null {
+		return nil, nil
+	}
+	if r.IsObject() || r.IsArray() {
+		return nil, fmt.Errorf("cannot convert %s to a field", r.Raw)
+	}
+
+	switch typ {
+	case "":
+		switch r.Type {
+		case gjson.String:
+			return r.Str, nil
+		case gjson.True, gjson.False:
+			return r.Bool(), nil
+		default:
+			return r.Float(), nil
+		}
+	case "int":
+		if r.Type == gjson.String {
+			v, err := strconv.ParseInt(r.Str, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse int: %w", err)
+			}
+			return v, nil
+		}
+		return r.Int(), nil
+	case "uint":
+		if r.Type == gjson.String {
+			v, err := strconv.ParseUint(r.Str, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse uint: %w", err)
+			}
+			return v, nil
+		}
+		return r.Uint(), nil
+	case "float":
+		if r.Type == gjson.String {
+			v, err := strconv.ParseFloat(r.Str, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse float: %w", err)
+			}
+			return v, nil
+		}
+		return r.Float(), nil
+	case "string":
+		return r.String(), nil
+	case "bool":
+		if r.Type == gjson.String {
+			v, err := strconv.ParseBool(r.Str)
+			if err != nil {
+				return nil, fmt.Errorf("parse bool: %w", err)
+			}
+			return v, nil
+		}
+		return r.Bool(), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// ParseLine parses a single JSON document which must produce one metric.
+func (p *Parser) ParseLine(line string) (cua.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metrics in line")
+	}
+	if len(metrics) > 1 {
+		return nil, fmt.Errorf("more than one metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,176 @@
+package jsonv2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const apiResponse = `
+{
+  "service": "api",
+  "region": "us-east",
+  "updated": 1612345678,
+  "stats": {"requests": 120, "errors": 3, "healthy": true},
+  "disks": [
+    {"name": "sda", "usage": {"used": 10, "free": 90}, "model": "x1", "ts": "2021-02-03T10:00:00Z"},
+    {"name": "sdb", "usage": {"used": 55, "free": 45}, "model": null, "ts": "2021-02-03T10:00:05Z"}
+  ],
+  "latencies": [1.5, 2.5]
+}
+`
+
+func newParser(t *testing.T, configs ...Config) *Parser {
+	p, err := New(configs, "json_v2", nil)
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+	return p
+}
+
+func TestFieldsAndTags(t *testing.T) {
+	p := newParser(t, Config{
+		MeasurementNamePath: "service",
+		TimestampPath:       "updated",
+		TimestampFormat:     "unix",
+		Tags:                []DataSet{{Path: "region"}},
+		Fields: []DataSet{
+			{Path: "stats.requests", Type: "int"},
+			{Path: "stats.errors", Rename: "error_count", Type: "uint"},
+			{Path: "stats.healthy"},
+			{Path: "stats.missing", Optional: true},
+		},
+	})
+
+	metrics, err := p.Parse([]byte(apiResponse))
+	require.NoError(t, err)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("api",
+			map[string]string{"region": "us-east"},
+			map[string]interface{}{
+				"requests":    int64(120),
+				"error_count": uint64(3),
+				"healthy":     true,
+			},
+			time.Unix(1612345678, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestMissingPath(t *testing.T) {
+	p := newParser(t, Config{
+		Fields: []DataSet{{Path: "stats.missing"}},
+	})
+	_, err := p.Parse([]byte(apiResponse))
+	require.Error(t, err)
+}
+
+func TestArrayExpansion(t *testing.T) {
+	p := newParser(t, Config{
+		MeasurementName: "latency",
+		Tags:            []DataSet{{Path: "disks.#.name", Rename: "disk"}},
+		Fields:          []DataSet{{Path: "latencies", Rename: "value"}},
+	})
+
+	metrics, err := p.Parse([]byte(apiResponse))
+	require.NoError(t, err)
+
+	now := time.Unix(42, 0)
+	expected := []cua.Metric{
+		testutil.MustMetric("latency", map[string]string{"disk": "sda"}, map[string]interface{}{"value": 1.5}, now),
+		testutil.MustMetric("latency", map[string]string{"disk": "sda"}, map[string]interface{}{"value": 2.5}, now),
+		testutil.MustMetric("latency", map[string]string{"disk": "sdb"}, map[string]interface{}{"value": 1.5}, now),
+		testutil.MustMetric("latency", map[string]string{"disk": "sdb"}, map[string]interface{}{"value": 2.5}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestObjects(t *testing.T) {
+	p := newParser(t, Config{
+		MeasurementName: "disk",
+		Tags:            []DataSet{{Path: "region"}},
+		Objects: []Object{
+			{
+				Path:            "disks",
+				TimestampKey:    "ts",
+				TimestampFormat: "2006-01-02T15:04:05Z07:00",
+				Tags:            []string{"name", "model"},
+				ExcludedKeys:    []string{"usage_free"},
+				Renames:         map[string]string{"name": "device"},
+				Fields:          map[string]string{"usage_used": "int"},
+			},
+		},
+	})
+
+	metrics, err := p.Parse([]byte(apiResponse))
+	require.NoError(t, err)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{"region": "us-east", "device": "sda", "model": "x1"},
+			map[string]interface{}{"usage_used": int64(10)},
+			time.Date(2021, 2, 3, 10, 0, 0, 0, time.UTC)),
+		testutil.MustMetric("disk",
+			map[string]string{"region": "us-east", "device": "sdb"},
+			map[string]interface{}{"usage_used": int64(55)},
+			time.Date(2021, 2, 3, 10, 0, 5, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestObjectDisablePrependKeys(t *testing.T) {
+	p := newParser(t, Config{
+		MeasurementName: "disk",
+		Objects: []Object{
+			{
+				Path:               "disks.0",
+				DisablePrependKeys: true,
+				IncludedKeys:       []string{"used", "free"},
+			},
+		},
+	})
+
+	metrics, err := p.Parse([]byte(apiResponse))
+	require.NoError(t, err)
+
+	expected := []cua.Metric{
+		testutil.MustMetric("disk",
+			map[string]string{},
+			map[string]interface{}{"used": 10.0, "free": 90.0},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestInvalid(t *testing.T) {
+	p := newParser(t, Config{Fields: []DataSet{{Path: "a"}}})
+	_, err := p.Parse([]byte(`{"a": `))
+	require.Error(t, err)
+
+	metrics, err := p.Parse([]byte(""))
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+
+	_, err = New([]Config{{Fields: []DataSet{{Path: "a", Type: "number"}}}}, "json_v2", nil)
+	require.Error(t, err)
+
+	_, err = New([]Config{{TimestampPath: "a"}}, "json_v2", nil)
+	require.Error(t, err)
+
+	_, err = New(nil, "json_v2", nil)
+	require.Error(t, err)
+}
+
+func TestParseLine(t *testing.T) {
+	p := newParser(t, Config{Fields: []DataSet{{Path: "value"}}})
+	p.SetDefaultTags(map[string]string{"host": "localhost"})
+
+	m, err := p.ParseLine(`{"value": 42}`)
+	require.NoError(t, err)
+	testutil.RequireMetricEqual(t,
+		testutil.MustMetric("json_v2", map[string]string{"host": "localhost"}, map[string]interface{}{"value": 42.0}, time.Unix(42, 0)),
+		m)
+}
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"log"
 	"os/exec"
 	"regexp"
 	"strconv"
@@ -17,6 +16,29 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/metric"
 )
 
+// The states of the checks, the exit codes of the plugins.
+const (
+	StateOK       = 0
+	StateWarning  = 1
+	StateCritical = 2
+	StateUnknown  = 3
+)
+
+// StateName returns the status of the state: OK, WARNING, CRITICAL or
+// UNKNOWN for the other states.
+func StateName(state int) string {
+	switch state {
+	case StateOK:
+		return "OK"
+	case StateWarning:
+		return "WARNING"
+	case StateCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 // getExitCode get the exit code from an error value which is the result
 // of running a command through exec package api.
 func getExitCode(err error) (int, error) {
@@ -49,10 +71,16 @@ func TryAddState(runErr error, metrics []cua.Metric) ([]cua.Metric, error) {
 	if err != nil {
 		return metrics, fmt.Errorf("exec: get exit code: %w", err)
 	}
+	return AddState(metrics, state)
+}
 
+// AddState adds the state and its status to the nagios_state metric, which
+// is created when missing.
+func AddState(metrics []cua.Metric, state int) ([]cua.Metric, error) {
 	for _, m := range metrics {
 		if m.Name() == "nagios_state" {
 			m.AddField("state", state)
+			m.AddField("status", StateName(state))
 			return metrics, nil
 		}
 	}
@@ -64,7 +92,8 @@ func TryAddState(runErr error, metrics []cua.Metric) ([]cua.Metric, error) {
 		ts = time.Now().UTC()
 	}
 	f := map[string]interface{}{
-		"state": state,
+		"state":  state,
+		"status": StateName(state),
 	}
 	m, err := metric.New("nagios_state", nil, f, ts)
 	if err != nil {
@@ -77,6 +106,7 @@ func TryAddState(runErr error, metrics []cua.Metric) ([]cua.Metric, error) {
 type Parser struct {
 	MetricName  string
 	DefaultTags map[string]string
+	Log         cua.Logger
 }
 
 // Got from Alignak
@@ -112,9 +142,9 @@ func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
 	parts := bytes.Split(s.Bytes(), []byte{'|'})
 	switch len(parts) {
 	case 2:
-		ms, err := parsePerfData(string(parts[1]), ts)
+		ms, err := ParsePerfData(string(parts[1]), ts)
 		if err != nil {
-			log.Printf("E! [parser.nagios] failed to parse performance data: %s\n", err.Error())
+			p.Log.Errorf("Failed to parse performance data: %s", err.Error())
 		}
 		metrics = append(metrics, ms...)
 		fallthrough
@@ -133,9 +163,9 @@ func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
 			}
 			longmsg.Write(bytes.TrimSpace(parts[0]))
 
-			ms, err := parsePerfData(string(parts[1]), ts)
+			ms, err := ParsePerfData(string(parts[1]), ts)
 			if err != nil {
-				log.Printf("E! [parser.nagios] failed to parse performance data: %s\n", err.Error())
+				p.Log.Errorf("Failed to parse performance data: %s", err.Error())
 			}
 			metrics = append(metrics, ms...)
 			break
@@ -148,15 +178,15 @@ func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
 
 	// Parse extra performance data.
 	for s.Scan() {
-		ms, err := parsePerfData(s.Text(), ts)
+		ms, err := ParsePerfData(s.Text(), ts)
 		if err != nil {
-			log.Printf("E! [parser.nagios] failed to parse performance data: %s\n", err.Error())
+			p.Log.Errorf("Failed to parse performance data: %s", err.Error())
 		}
 		metrics = append(metrics, ms...)
 	}
 
 	if s.Err() != nil {
-		log.Printf("D! [parser.nagios] unexpected io error: %s\n", s.Err())
+		p.Log.Debugf("Unexpected io error: %s", s.Err())
 	}
 
 	// Create nagios state.
@@ -171,13 +201,15 @@ func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
 	if err == nil {
 		metrics = append(metrics, m)
 	} else {
-		log.Printf("E! [parser.nagios] failed to add nagios_state: %s\n", err)
+		p.Log.Errorf("Failed to add nagios_state: %s", err)
 	}
 
 	return metrics, nil
 }
 
-func parsePerfData(perfdatas string, timestamp time.Time) ([]cua.Metric, error) {
+// ParsePerfData returns a nagios metric for each performance data of the
+// perfdatas, tagged with its label and its unit.
+func ParsePerfData(perfdatas string, timestamp time.Time) ([]cua.Metric, error) {
 	metrics := make([]cua.Metric, 0)
 
 	for _, unParsedPerf := range perfSplitRegExp.FindAllString(perfdatas, -1) {
@@ -135,7 +135,8 @@ func TestTryAddState(t *testing.T) {
 					mb().
 						n("nagios_state").
 						f("service_output", "OK: system working").
-						f("state", 0).b(),
+						f("state", 0).
+						f("status", "OK").b(),
 				}
 				assertEqual(t, exp, metrics)
 				require.NoError(t, err)
@@ -158,7 +159,8 @@ func TestTryAddState(t *testing.T) {
 						f("perfdata", 0).b(),
 					mb().
 						n("nagios_state").
-						f("state", 0).b(),
+						f("state", 0).
+						f("status", "OK").b(),
 				}
 				assertEqual(t, exp, metrics)
 				require.NoError(t, err)
@@ -0,0 +1,244 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Parser converts the Prometheus text exposition format into metrics.
+//
+// With metric version 1 (default) every sample of a metric family becomes a
+// metric named after the family, histograms and summaries are reported as a
+// single metric with a field per bucket or quantile.  With metric version 2
+// all metrics are named "prometheus" and the family name is used as the
+// field key, buckets and quantiles are reported as separate metrics with an
+// "le" or "quantile" tag.
+type Parser struct {
+	MetricVersion   int
+	IgnoreTimestamp bool
+	DefaultTags     map[string]string
+	Now             func() time.Time
+}
+
+// NewParser creates a parser.
+func NewParser(metricVersion int, ignoreTimestamp bool, defaultTags map[string]string) (*Parser, error) {
+	switch metricVersion {
+	case 0:
+		metricVersion = 1
+	case 1, 2:
+	default:
+		return nil, fmt.Errorf("invalid prometheus metric version %d", metricVersion)
+	}
+	return &Parser{
+		MetricVersion:   metricVersion,
+		IgnoreTimestamp: ignoreTimestamp,
+		DefaultTags:     defaultTags,
+		Now:             time.Now,
+	}, nil
+}
+
+// Parse converts the text exposition format to metrics.
+func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
+	var parser expfmt.TextParser
+
+	// the text parser expects complete lines
+	if len(buf) > 0 && buf[len(buf)-1] != '\n' {
+		buf = append(buf[:len(buf):len(buf)], '\n')
+	}
+
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("reading text format failed: %w", err)
+	}
+
+	// sort the families so the output order is stable
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// a consistent timestamp so metrics don't straddle two different seconds
+	now := p.Now()
+
+	metrics := make([]cua.Metric, 0)
+	for _, name := range names {
+		mf := families[name]
+		for _, m := range mf.Metric {
+			tags := p.makeTags(m)
+			t := p.timestamp(m, now)
+			if p.MetricVersion == 2 {
+				metrics = append(metrics, p.metricsV2(name, mf.GetType(), m, tags, t)...)
+			} else {
+				metrics = append(metrics, p.metricsV1(name, mf.GetType(), m, tags, t)...)
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+// ParseLine parses a single sample line.
+func (p *Parser) ParseLine(line string) (cua.Metric, error) {
+	metrics, err := p.Parse([]byte(line + "\n"))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metrics in line")
+	}
+	if len(metrics) > 1 {
+		return nil, fmt.Errorf("more than one metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) metricsV1(name string, mt dto.MetricType, m *dto.Metric, tags map[string]string, t time.Time) []cua.Metric {
+	fields := make(map[string]interface{})
+	switch mt {
+	case dto.MetricType_SUMMARY:
+		for _, q := range m.GetSummary().Quantile {
+			if !math.IsNaN(q.GetValue()) {
+				fields[formatFloat(q.GetQuantile())] = q.GetValue()
+			}
+		}
+		fields["count"] = float64(m.GetSummary().GetSampleCount())
+		fields["sum"] = m.GetSummary().GetSampleSum()
+	case dto.MetricType_HISTOGRAM:
+		for _, b := range m.GetHistogram().Bucket {
+			fields[formatFloat(b.GetUpperBound())] = float64(b.GetCumulativeCount())
+		}
+		fields["count"] = float64(m.GetHistogram().GetSampleCount())
+		fields["sum"] = m.GetHistogram().GetSampleSum()
+	default:
+		key, value := sampleValue(m)
+		if key == "" || math.IsNaN(value) {
+			return nil
+		}
+		fields[key] = value
+	}
+
+	return appendMetric(nil, name, tags, fields, t, valueType(mt))
+}
+
+func (p *Parser) metricsV2(name string, mt dto.MetricType, m *dto.Metric, tags map[string]string, t time.Time) []cua.Metric {
+	var metrics []cua.Metric
+	switch mt {
+	case dto.MetricType_SUMMARY:
+		metrics = appendMetric(metrics, "prometheus", tags, map[string]interface{}{
+			name + "_count": float64(m.GetSummary().GetSampleCount()),
+			name + "_sum":   m.GetSummary().GetSampleSum(),
+		}, t, cua.Summary)
+		for _, q := range m.GetSummary().Quantile {
+			if math.IsNaN(q.GetValue()) {
+				continue
+			}
+			qtags := copyTags(tags)
+			qtags["quantile"] = formatFloat(q.GetQuantile())
+			metrics = appendMetric(metrics, "prometheus", qtags, map[string]interface{}{
+				name: q.GetValue(),
+			}, t, cua.Summary)
+		}
+	case dto.MetricType_HISTOGRAM:
+		metrics = appendMetric(metrics, "prometheus", tags, map[string]interface{}{
+			name + "_count": float64(m.GetHistogram().GetSampleCount()),
+			name + "_sum":   m.GetHistogram().GetSampleSum(),
+		}, t, cua.Histogram)
+		for _, b := range m.GetHistogram().Bucket {
+			btags := copyTags(tags)
+			btags["le"] = formatFloat(b.GetUpperBound())
+			metrics = appendMetric(metrics, "prometheus", btags, map[string]interface{}{
+				name + "_bucket": float64(b.GetCumulativeCount()),
+			}, t, cua.Histogram)
+		}
+	default:
+		key, value := sampleValue(m)
+		if key == "" || math.IsNaN(value) {
+			return nil
+		}
+		metrics = appendMetric(metrics, "prometheus", tags, map[string]interface{}{
+			name: value,
+		}, t, valueType(mt))
+	}
+	return metrics
+}
+
+func (p *Parser) makeTags(m *dto.Metric) map[string]string {
+	tags := make(map[string]string, len(p.DefaultTags)+len(m.Label))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, lp := range m.Label {
+		tags[lp.GetName()] = lp.GetValue()
+	}
+	return tags
+}
+
+func (p *Parser) timestamp(m *dto.Metric, now time.Time) time.Time {
+	if !p.IgnoreTimestamp && m.TimestampMs != nil && *m.TimestampMs > 0 {
+		return time.Unix(0, *m.TimestampMs*int64(time.Millisecond))
+	}
+	return now
+}
+
+// sampleValue returns the field key and value of a counter, gauge or
+// untyped sample.
+func sampleValue(m *dto.Metric) (string, float64) {
+	switch {
+	case m.Gauge != nil:
+		return "gauge", m.GetGauge().GetValue()
+	case m.Counter != nil:
+		return "counter", m.GetCounter().GetValue()
+	case m.Untyped != nil:
+		return "value", m.GetUntyped().GetValue()
+	}
+	return "", 0
+}
+
+func valueType(mt dto.MetricType) cua.ValueType {
+	switch mt {
+	case dto.MetricType_COUNTER:
+		return cua.Counter
+	case dto.MetricType_GAUGE:
+		return cua.Gauge
+	case dto.MetricType_SUMMARY:
+		return cua.Summary
+	case dto.MetricType_HISTOGRAM:
+		return cua.Histogram
+	default:
+		return cua.Untyped
+	}
+}
+
+func appendMetric(metrics []cua.Metric, name string, tags map[string]string, fields map[string]interface{}, t time.Time, tp cua.ValueType) []cua.Metric {
+	m, err := metric.New(name, tags, fields, t, tp)
+	if err != nil {
+		return metrics
+	}
+	return append(metrics, m)
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	c := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,124 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const exposition = `# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 15
+# HELP http_requests_total Total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{code="200",method="get"} 1027 1600000000000
+http_requests_total{code="400",method="post"} 3 1600000000000
+# HELP rpc_duration_seconds RPC latency.
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 0.05
+rpc_duration_seconds{quantile="0.99"} NaN
+rpc_duration_seconds_sum 17.5
+rpc_duration_seconds_count 250
+# HELP request_duration_seconds Request latency.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 10
+request_duration_seconds_bucket{le="1"} 15
+request_duration_seconds_bucket{le="+Inf"} 16
+request_duration_seconds_sum 4.5
+request_duration_seconds_count 16
+temperature{room="lab"} 21.5`
+
+func newTestParser(t *testing.T, version int) *Parser {
+	p, err := NewParser(version, false, map[string]string{"source": "test"})
+	require.NoError(t, err)
+	p.Now = func() time.Time { return time.Unix(1700000000, 0) }
+	return p
+}
+
+func TestParseV1(t *testing.T) {
+	p := newTestParser(t, 1)
+	metrics, err := p.Parse([]byte(exposition))
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	ts := time.Unix(1600000000, 0)
+	expected := []cua.Metric{
+		testutil.MustMetric("go_goroutines",
+			map[string]string{"source": "test"},
+			map[string]interface{}{"gauge": 15.0}, now, cua.Gauge),
+		testutil.MustMetric("http_requests_total",
+			map[string]string{"source": "test", "code": "200", "method": "get"},
+			map[string]interface{}{"counter": 1027.0}, ts, cua.Counter),
+		testutil.MustMetric("http_requests_total",
+			map[string]string{"source": "test", "code": "400", "method": "post"},
+			map[string]interface{}{"counter": 3.0}, ts, cua.Counter),
+		testutil.MustMetric("request_duration_seconds",
+			map[string]string{"source": "test"},
+			map[string]interface{}{"0.1": 10.0, "1": 15.0, "+Inf": 16.0, "sum": 4.5, "count": 16.0}, now, cua.Histogram),
+		testutil.MustMetric("rpc_duration_seconds",
+			map[string]string{"source": "test"},
+			map[string]interface{}{"0.5": 0.05, "sum": 17.5, "count": 250.0}, now, cua.Summary),
+		testutil.MustMetric("temperature",
+			map[string]string{"source": "test", "room": "lab"},
+			map[string]interface{}{"value": 21.5}, now, cua.Untyped),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseV2(t *testing.T) {
+	p := newTestParser(t, 2)
+	p.IgnoreTimestamp = true
+	metrics, err := p.Parse([]byte(exposition))
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	tags := func(kv ...string) map[string]string {
+		m := map[string]string{"source": "test"}
+		for i := 0; i < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return m
+	}
+	expected := []cua.Metric{
+		testutil.MustMetric("prometheus", tags(), map[string]interface{}{"go_goroutines": 15.0}, now, cua.Gauge),
+		testutil.MustMetric("prometheus", tags("code", "200", "method", "get"), map[string]interface{}{"http_requests_total": 1027.0}, now, cua.Counter),
+		testutil.MustMetric("prometheus", tags("code", "400", "method", "post"), map[string]interface{}{"http_requests_total": 3.0}, now, cua.Counter),
+		testutil.MustMetric("prometheus", tags(), map[string]interface{}{"request_duration_seconds_count": 16.0, "request_duration_seconds_sum": 4.5}, now, cua.Histogram),
+		testutil.MustMetric("prometheus", tags("le", "0.1"), map[string]interface{}{"request_duration_seconds_bucket": 10.0}, now, cua.Histogram),
+		testutil.MustMetric("prometheus", tags("le", "1"), map[string]interface{}{"request_duration_seconds_bucket": 15.0}, now, cua.Histogram),
+		testutil.MustMetric("prometheus", tags("le", "+Inf"), map[string]interface{}{"request_duration_seconds_bucket": 16.0}, now, cua.Histogram),
+		testutil.MustMetric("prometheus", tags(), map[string]interface{}{"rpc_duration_seconds_count": 250.0, "rpc_duration_seconds_sum": 17.5}, now, cua.Summary),
+		testutil.MustMetric("prometheus", tags("quantile", "0.5"), map[string]interface{}{"rpc_duration_seconds": 0.05}, now, cua.Summary),
+		testutil.MustMetric("prometheus", tags("room", "lab"), map[string]interface{}{"temperature": 21.5}, now, cua.Untyped),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseLine(t *testing.T) {
+	p := newTestParser(t, 1)
+	m, err := p.ParseLine(`cpu_seconds{mode="idle"} 42`)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []cua.Metric{
+		testutil.MustMetric("cpu_seconds",
+			map[string]string{"source": "test", "mode": "idle"},
+			map[string]interface{}{"value": 42.0}, time.Unix(1700000000, 0)),
+	}, []cua.Metric{m})
+
+	_, err = p.ParseLine("# just a comment")
+	require.Error(t, err)
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := newTestParser(t, 1)
+	_, err := p.Parse([]byte("# TYPE foo counter\n# TYPE foo gauge\nfoo 1\n"))
+	require.Error(t, err)
+
+	_, err = p.Parse([]byte("foo{bar=} 1\n"))
+	require.Error(t, err)
+
+	_, err = NewParser(3, false, nil)
+	require.Error(t, err)
+}
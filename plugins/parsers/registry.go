@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/models"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/collectd"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/csv"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/dropwizard"
@@ -12,10 +13,14 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/grok"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/influx"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/json"
+	jsonv2 "github.com/circonus-labs/circonus-unified-agent/plugins/parsers/json_v2"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/logfmt"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/nagios"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/prometheus"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/sensu"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/value"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/wavefront"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/xpath"
 )
 
 type ParserFunc func() (Parser, error)
@@ -59,13 +64,15 @@ type Parser interface {
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
-	// Dataformat can be one of: json, influx, graphite, value, nagios
+	// Dataformat can be one of: json, influx, graphite, value, nagios, sensu
 	DataFormat string `toml:"data_format"`
 
 	// Separator only applied to Graphite data.
 	Separator string `toml:"separator"`
 	// Templates only apply to Graphite data.
 	Templates []string `toml:"templates"`
+	// GraphiteRegexTemplates are evaluated in order before the Templates.
+	GraphiteRegexTemplates []string `toml:"graphite_regex_templates"`
 
 	// TagKeys only apply to JSON data
 	TagKeys []string `toml:"tag_keys"`
@@ -148,12 +155,23 @@ type Config struct {
 
 	// FormData configuration
 	FormUrlencodedTagKeys []string `toml:"form_urlencoded_tag_keys"`
+
+	// prometheus configuration
+	PrometheusMetricVersion   int  `toml:"prometheus_metric_version"`
+	PrometheusIgnoreTimestamp bool `toml:"prometheus_ignore_timestamp"`
+
+	// json_v2 configuration
+	JSONV2Config []jsonv2.Config `toml:"json_v2"`
+
+	// xml and html configuration
+	XPathConfig []xpath.Config `toml:"xpath"`
 }
 
 // NewParser returns a Parser interface based on the given config.
 func NewParser(config *Config) (Parser, error) {
 	var err error
 	var parser Parser
+	log := models.NewLogger("parsers", config.DataFormat, config.MetricName)
 	switch config.DataFormat {
 	case "json":
 		parser, err = json.New(
@@ -170,6 +188,10 @@ func NewParser(config *Config) (Parser, error) {
 				Strict:       config.JSONStrict,
 			},
 		)
+	case "json_v2":
+		parser, err = jsonv2.New(config.JSONV2Config, config.MetricName, config.DefaultTags)
+	case "xml", "html":
+		parser, err = xpath.New(config.DataFormat, config.XPathConfig, config.MetricName, config.DefaultTags)
 	case "value":
 		parser, err = NewValueParser(config.MetricName,
 			config.DataType, config.DefaultTags)
@@ -177,9 +199,11 @@ func NewParser(config *Config) (Parser, error) {
 		parser, err = NewInfluxParser()
 	case "nagios":
 		parser, err = NewNagiosParser()
+	case "sensu":
+		parser, err = NewSensuParser(config.DefaultTags)
 	case "graphite":
-		parser, err = NewGraphiteParser(config.Separator,
-			config.Templates, config.DefaultTags)
+		parser, err = newGraphiteParser(config.Separator,
+			config.Templates, config.GraphiteRegexTemplates, config.DefaultTags)
 	case "collectd":
 		parser, err = NewCollectdParser(config.CollectdAuthFile,
 			config.CollectdSecurityLevel, config.CollectdTypesDB, config.CollectdSplit)
@@ -203,7 +227,8 @@ func NewParser(config *Config) (Parser, error) {
 			config.GrokCustomPatterns,
 			config.GrokCustomPatternFiles,
 			config.GrokTimezone,
-			config.GrokUniqueTimestamp)
+			config.GrokUniqueTimestamp,
+			log)
 	case "csv":
 		config := &csv.Config{
 			MetricName:        config.MetricName,
@@ -232,16 +257,25 @@ func NewParser(config *Config) (Parser, error) {
 			config.DefaultTags,
 			config.FormUrlencodedTagKeys,
 		)
+	case "prometheus":
+		parser, err = NewPrometheusParser(
+			config.PrometheusMetricVersion,
+			config.PrometheusIgnoreTimestamp,
+			config.DefaultTags,
+		)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
+	if err == nil {
+		models.SetLoggerOnPlugin(parser, log)
+	}
 	return parser, err
 }
 
 func newGrokParser(metricName string,
 	patterns []string, nPatterns []string,
 	cPatterns string, cPatternFiles []string,
-	tZone string, uniqueTimestamp string) (Parser, error) {
+	tZone string, uniqueTimestamp string, log cua.Logger) (Parser, error) {
 	parser := grok.Parser{
 		Measurement:        metricName,
 		Patterns:           patterns,
@@ -250,16 +284,23 @@ func newGrokParser(metricName string,
 		CustomPatternFiles: cPatternFiles,
 		Timezone:           tZone,
 		UniqueTimestamp:    uniqueTimestamp,
+		Log:                log,
 	}
 
-	err := parser.Compile()
-	return &parser, fmt.Errorf("parser compile: %w", err)
+	if err := parser.Compile(); err != nil {
+		return nil, fmt.Errorf("parser compile: %w", err)
+	}
+	return &parser, nil
 }
 
 func NewNagiosParser() (Parser, error) {
 	return &nagios.Parser{}, nil
 }
 
+func NewSensuParser(defaultTags map[string]string) (Parser, error) {
+	return &sensu.Parser{DefaultTags: defaultTags}, nil
+}
+
 func NewInfluxParser() (Parser, error) {
 	handler := influx.NewMetricHandler()
 	return influx.NewParser(handler), nil
@@ -273,6 +314,22 @@ func NewGraphiteParser(
 	return graphite.NewGraphiteParser(separator, templates, defaultTags)
 }
 
+func newGraphiteParser(
+	separator string,
+	templates []string,
+	regexTemplates []string,
+	defaultTags map[string]string,
+) (Parser, error) {
+	parser, err := graphite.NewGraphiteParser(separator, templates, defaultTags)
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.SetRegexTemplates(regexTemplates); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
 func NewValueParser(
 	metricName string,
 	dataType string,
@@ -339,3 +396,11 @@ func NewFormUrlencodedParser(
 		TagKeys:     tagKeys,
 	}, nil
 }
+
+func NewPrometheusParser(
+	metricVersion int,
+	ignoreTimestamp bool,
+	defaultTags map[string]string,
+) (Parser, error) {
+	return prometheus.NewParser(metricVersion, ignoreTimestamp, defaultTags)
+}
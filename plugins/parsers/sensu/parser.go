@@ -0,0 +1,198 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers/nagios"
+)
+
+// checkResult is a Sensu Core check result, or the check of a Sensu Go
+// event.
+type checkResult struct {
+	Name     string `json:"name"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status             *int   `json:"status"`
+	Output             string `json:"output"`
+	OutputMetricFormat string `json:"output_metric_format"`
+	Executed           int64  `json:"executed"`
+}
+
+// event is a Sensu Go event, or a Sensu Core check result when Check is
+// nil.
+type event struct {
+	checkResult
+	Check   *checkResult `json:"check"`
+	Metrics *struct {
+		Points []point `json:"points"`
+	} `json:"metrics"`
+}
+
+type point struct {
+	Name      string  `json:"name"`
+	Value     float64 `json:"value"`
+	Timestamp int64   `json:"timestamp"`
+	Tags      []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"tags"`
+}
+
+// Parser parses the check results of Sensu checks: a Sensu Core check
+// result, a Sensu Go event, or an array of them.
+type Parser struct {
+	DefaultTags map[string]string
+}
+
+func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return nil, nil
+	}
+
+	var events []event
+	if buf[0] == '[' {
+		if err := json.Unmarshal(buf, &events); err != nil {
+			return nil, fmt.Errorf("json unmarshal: %w", err)
+		}
+	} else {
+		var e event
+		if err := json.Unmarshal(buf, &e); err != nil {
+			return nil, fmt.Errorf("json unmarshal: %w", err)
+		}
+		events = []event{e}
+	}
+
+	now := time.Now().UTC()
+	var metrics []cua.Metric
+	for _, e := range events {
+		ms, err := p.parseEvent(e, now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, ms...)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseEvent(e event, now time.Time) ([]cua.Metric, error) {
+	check := e.checkResult
+	if e.Check != nil {
+		check = *e.Check
+	}
+	name := check.Name
+	if name == "" {
+		name = check.Metadata.Name
+	}
+	if check.Status == nil {
+		return nil, errors.New("check result without status")
+	}
+
+	ts := now
+	if check.Executed > 0 {
+		ts = time.Unix(check.Executed, 0)
+	}
+
+	// the first line of the output is the message, the performance data
+	// follows it after a "|" as with the nagios plugins
+	output, perfdata := check.Output, ""
+	if i := strings.IndexByte(output, '|'); i >= 0 {
+		output, perfdata = output[:i], output[i+1:]
+	}
+
+	var metrics []cua.Metric
+	state, err := metric.New("sensu_check", p.tags(map[string]string{"check": name}),
+		map[string]interface{}{
+			"state":          *check.Status,
+			"status":         nagios.StateName(*check.Status),
+			"service_output": strings.TrimSpace(output),
+		}, ts)
+	if err != nil {
+		return nil, fmt.Errorf("new metric: %w", err)
+	}
+	metrics = append(metrics, state)
+
+	if perfdata != "" && (check.OutputMetricFormat == "" || check.OutputMetricFormat == "nagios_perfdata") {
+		ms, err := nagios.ParsePerfData(perfdata, ts)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", name, err)
+		}
+		for _, m := range ms {
+			m.SetName("sensu_perfdata")
+			m.AddTag("check", name)
+			for k, v := range p.DefaultTags {
+				if !m.HasTag(k) {
+					m.AddTag(k, v)
+				}
+			}
+			metrics = append(metrics, m)
+		}
+	}
+
+	if e.Metrics != nil {
+		for _, pt := range e.Metrics.Points {
+			tags := map[string]string{"check": name}
+			for _, tag := range pt.Tags {
+				tags[tag.Name] = tag.Value
+			}
+			t := ts
+			if pt.Timestamp > 0 {
+				t = unixTime(pt.Timestamp)
+			}
+			m, err := metric.New(pt.Name, p.tags(tags), map[string]interface{}{"value": pt.Value}, t)
+			if err != nil {
+				return nil, fmt.Errorf("new metric: %w", err)
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+// tags adds the default tags to the tags.
+func (p *Parser) tags(tags map[string]string) map[string]string {
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+// unixTime returns the time of a timestamp in seconds, milliseconds,
+// microseconds or nanoseconds, as accepted by Sensu.
+func unixTime(ts int64) time.Time {
+	switch {
+	case ts > 1e17:
+		return time.Unix(0, ts)
+	case ts > 1e14:
+		return time.Unix(0, ts*int64(time.Microsecond))
+	case ts > 1e11:
+		return time.Unix(0, ts*int64(time.Millisecond))
+	default:
+		return time.Unix(ts, 0)
+	}
+}
+
+func (p *Parser) ParseLine(line string) (cua.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
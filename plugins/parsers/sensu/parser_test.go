@@ -0,0 +1,99 @@
+package sensu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCoreCheckResult(t *testing.T) {
+	p := &Parser{DefaultTags: map[string]string{"dc": "east"}}
+	metrics, err := p.Parse([]byte(`{
+  "name": "check_disk",
+  "status": 1,
+  "executed": 1600000000,
+  "output": "DISK WARNING - free space: / 2643 MB (4%) | /=2643MB;5948;5958;0;5968"
+}`))
+	require.NoError(t, err)
+
+	ts := time.Unix(1600000000, 0)
+	expected := []cua.Metric{
+		testutil.MustMetric("sensu_check",
+			map[string]string{"check": "check_disk", "dc": "east"},
+			map[string]interface{}{
+				"state":          1,
+				"status":         "WARNING",
+				"service_output": "DISK WARNING - free space: / 2643 MB (4%)",
+			}, ts),
+		testutil.MustMetric("sensu_perfdata",
+			map[string]string{"check": "check_disk", "dc": "east", "perfdata": "/", "unit": "MB"},
+			map[string]interface{}{
+				"value":       2643.0,
+				"warning_lt":  0.0,
+				"warning_gt":  5948.0,
+				"critical_lt": 0.0,
+				"critical_gt": 5958.0,
+				"min":         0.0,
+				"max":         5968.0,
+			}, ts),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseGoEvents(t *testing.T) {
+	p := &Parser{}
+	metrics, err := p.Parse([]byte(`[
+  {
+    "metadata": {"namespace": "default"},
+    "check": {
+      "metadata": {"name": "check_cpu"},
+      "status": 0,
+      "executed": 1600000000,
+      "output": "cpu.user 12.5 1600000000",
+      "output_metric_format": "graphite_plaintext"
+    },
+    "metrics": {
+      "points": [
+        {"name": "cpu.user", "value": 12.5, "timestamp": 1600000001000, "tags": [{"name": "cpu", "value": "cpu0"}]}
+      ]
+    }
+  },
+  {"check": {"metadata": {"name": "check_http"}, "status": 2, "output": "HTTP CRITICAL"}}
+]`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	require.Equal(t, "sensu_check", metrics[0].Name())
+	require.Equal(t, map[string]string{"check": "check_cpu"}, metrics[0].Tags())
+	require.Equal(t, "OK", metrics[0].Fields()["status"])
+
+	testutil.RequireMetricEqual(t,
+		testutil.MustMetric("cpu.user",
+			map[string]string{"check": "check_cpu", "cpu": "cpu0"},
+			map[string]interface{}{"value": 12.5},
+			time.Unix(1600000001, 0)),
+		metrics[1])
+
+	require.Equal(t, map[string]string{"check": "check_http"}, metrics[2].Tags())
+	require.Equal(t, int64(2), metrics[2].Fields()["state"])
+	require.Equal(t, "CRITICAL", metrics[2].Fields()["status"])
+}
+
+func TestParseWithoutStatus(t *testing.T) {
+	p := &Parser{}
+	_, err := p.Parse([]byte(`{"name": "check_disk", "output": "DISK OK"}`))
+	require.Error(t, err)
+
+	_, err = p.Parse([]byte(`DISK OK`))
+	require.Error(t, err)
+}
+
+func TestUnixTime(t *testing.T) {
+	expected := time.Unix(1600000000, 0)
+	for _, ts := range []int64{1600000000, 1600000000000, 1600000000000000, 1600000000000000000} {
+		require.True(t, expected.Equal(unixTime(ts)), "timestamp %d", ts)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"strconv"
 	"sync"
 	"time"
@@ -26,6 +25,8 @@ type Point struct {
 }
 
 type Parser struct {
+	Log cua.Logger
+
 	parsers     *sync.Pool
 	defaultTags map[string]string
 }
@@ -207,7 +208,7 @@ func (p *PointParser) unscan() {
 func (p *PointParser) unscanTokens(n int) {
 	if n > MaxBufferSize {
 		// just log for now
-		log.Printf("cannot unscan more than %d tokens", MaxBufferSize)
+		p.parent.Log.Errorf("Cannot unscan more than %d tokens", MaxBufferSize)
 	}
 	p.buf.n += n
 }
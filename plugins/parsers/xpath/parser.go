@@ -0,0 +1,353 @@
+package xpath
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+)
+
+// Config declares the XPath expressions extracting a set of metrics.  The
+// expressions other than the selection are evaluated relative to the
+// selected nodes.
+type Config struct {
+	// MetricQuery is the expression of the measurement name, defaults to the
+	// plugin name.
+	MetricQuery string `toml:"metric_name"`
+	// Selection selects the nodes producing a metric, defaults to the root.
+	Selection string `toml:"metric_selection"`
+
+	// Timestamp is the expression of the metric time and TimestampFormat its
+	// format: "unix", "unix_ms", "unix_us", "unix_ns" or a Go time layout.
+	Timestamp       string `toml:"timestamp"`
+	TimestampFormat string `toml:"timestamp_format"`
+
+	Tags map[string]string `toml:"tags"`
+	// Fields keep the type of the expression result, use number() or
+	// boolean() for numeric or boolean fields.
+	Fields map[string]string `toml:"fields"`
+	// FieldsInt are converted to integers.
+	FieldsInt map[string]string `toml:"fields_int"`
+
+	// FieldSelection selects nodes producing a field each, with the name
+	// and value of the FieldNameQuery and FieldValueQuery expressions.
+	FieldSelection  string `toml:"field_selection"`
+	FieldNameQuery  string `toml:"field_name"`
+	FieldValueQuery string `toml:"field_value"`
+}
+
+type compiledConfig struct {
+	name            *xpath.Expr
+	selection       *xpath.Expr
+	timestamp       *xpath.Expr
+	timestampFormat string
+	tags            map[string]*xpath.Expr
+	fields          map[string]*xpath.Expr
+	fieldsInt       map[string]*xpath.Expr
+	fieldSelection  *xpath.Expr
+	fieldName       *xpath.Expr
+	fieldValue      *xpath.Expr
+}
+
+// Parser converts XML or HTML documents to metrics using XPath expressions.
+type Parser struct {
+	// Format is "xml" or "html".
+	Format      string
+	MetricName  string
+	DefaultTags map[string]string
+	TimeFunc    func() time.Time
+
+	configs []compiledConfig
+}
+
+// New compiles the expressions of the configurations.
+func New(format string, configs []Config, metricName string, defaultTags map[string]string) (*Parser, error) {
+	switch format {
+	case "xml", "html":
+	default:
+		return nil, fmt.Errorf("xpath: unknown format %q", format)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("xpath: no configuration")
+	}
+
+	p := &Parser{
+		Format:      format,
+		MetricName:  metricName,
+		DefaultTags: defaultTags,
+		TimeFunc:    time.Now,
+	}
+	for _, c := range configs {
+		cc, err := compile(c)
+		if err != nil {
+			return nil, err
+		}
+		p.configs = append(p.configs, cc)
+	}
+	return p, nil
+}
+
+func compile(c Config) (compiledConfig, error) {
+	var cc compiledConfig
+	var err error
+
+	compileOptional := func(expr string) (*xpath.Expr, error) {
+		if expr == "" {
+			return nil, nil
+		}
+		e, err := xpath.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("xpath: compiling %q: %w", expr, err)
+		}
+		return e, nil
+	}
+	compileMap := func(exprs map[string]string) (map[string]*xpath.Expr, error) {
+		compiled := make(map[string]*xpath.Expr, len(exprs))
+		for k, expr := range exprs {
+			e, err := xpath.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("xpath: compiling %q of %q: %w", expr, k, err)
+			}
+			compiled[k] = e
+		}
+		return compiled, nil
+	}
+
+	if cc.name, err = compileOptional(c.MetricQuery); err != nil {
+		return cc, err
+	}
+	if cc.selection, err = compileOptional(c.Selection); err != nil {
+		return cc, err
+	}
+	if cc.timestamp, err = compileOptional(c.Timestamp); err != nil {
+		return cc, err
+	}
+	cc.timestampFormat = c.TimestampFormat
+	if cc.timestampFormat == "" {
+		cc.timestampFormat = "unix"
+	}
+	if cc.tags, err = compileMap(c.Tags); err != nil {
+		return cc, err
+	}
+	if cc.fields, err = compileMap(c.Fields); err != nil {
+		return cc, err
+	}
+	if cc.fieldsInt, err = compileMap(c.FieldsInt); err != nil {
+		return cc, err
+	}
+	if cc.fieldSelection, err = compileOptional(c.FieldSelection); err != nil {
+		return cc, err
+	}
+	if c.FieldSelection != "" {
+		nameQuery, valueQuery := c.FieldNameQuery, c.FieldValueQuery
+		if nameQuery == "" {
+			nameQuery = "name()"
+		}
+		if valueQuery == "" {
+			valueQuery = "."
+		}
+		if cc.fieldName, err = compileOptional(nameQuery); err != nil {
+			return cc, err
+		}
+		if cc.fieldValue, err = compileOptional(valueQuery); err != nil {
+			return cc, err
+		}
+	}
+	return cc, nil
+}
+
+// Parse converts a document to metrics.
+func (p *Parser) Parse(buf []byte) ([]cua.Metric, error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return make([]cua.Metric, 0), nil
+	}
+
+	var root xpath.NodeNavigator
+	if p.Format == "html" {
+		doc, err := htmlquery.Parse(bytes.NewReader(buf))
+		if err != nil {
+			return nil, fmt.Errorf("xpath: parsing html: %w", err)
+		}
+		root = htmlquery.CreateXPathNavigator(doc)
+	} else {
+		doc, err := xmlquery.Parse(bytes.NewReader(buf))
+		if err != nil {
+			return nil, fmt.Errorf("xpath: parsing xml: %w", err)
+		}
+		root = xmlquery.CreateXPathNavigator(doc)
+	}
+
+	now := p.TimeFunc()
+	metrics := make([]cua.Metric, 0)
+	for _, c := range p.configs {
+		nodes := []xpath.NodeNavigator{root}
+		if c.selection != nil {
+			nodes = selectNodes(c.selection, root)
+		}
+		for _, node := range nodes {
+			m, err := p.parseNode(c, node, now)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseNode(c compiledConfig, node xpath.NodeNavigator, now time.Time) (cua.Metric, error) {
+	name := p.MetricName
+	if c.name != nil {
+		name = toString(c.name.Evaluate(node.Copy()))
+		if name == "" {
+			return nil, fmt.Errorf("xpath: empty metric name")
+		}
+	}
+
+	timestamp := now
+	if c.timestamp != nil {
+		v := toString(c.timestamp.Evaluate(node.Copy()))
+		t, err := internal.ParseTimestamp(c.timestampFormat, v, "")
+		if err != nil {
+			return nil, fmt.Errorf("xpath: timestamp: %w", err)
+		}
+		timestamp = t
+	}
+
+	tags := make(map[string]string, len(p.DefaultTags)+len(c.tags))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for k, e := range c.tags {
+		if v := toString(e.Evaluate(node.Copy())); v != "" {
+			tags[k] = v
+		}
+	}
+
+	fields := make(map[string]interface{}, len(c.fields)+len(c.fieldsInt))
+	for k, e := range c.fields {
+		v := e.Evaluate(node.Copy())
+		if iter, ok := v.(*xpath.NodeIterator); ok {
+			if !iter.MoveNext() {
+				continue
+			}
+			v = iter.Current().Value()
+		}
+		// NaN is not a valid field value
+		if f, ok := v.(float64); ok && math.IsNaN(f) {
+			continue
+		}
+		fields[k] = v
+	}
+	for k, e := range c.fieldsInt {
+		v, ok, err := toInt(e.Evaluate(node.Copy()))
+		if err != nil {
+			return nil, fmt.Errorf("xpath: field %q: %w", k, err)
+		}
+		if ok {
+			fields[k] = v
+		}
+	}
+	if c.fieldSelection != nil {
+		for _, f := range selectNodes(c.fieldSelection, node) {
+			key := toString(c.fieldName.Evaluate(f.Copy()))
+			if key == "" {
+				continue
+			}
+			fields[key] = toString(c.fieldValue.Evaluate(f.Copy()))
+		}
+	}
+
+	// metrics without fields are not valid
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	m, err := metric.New(name, tags, fields, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("xpath: %w", err)
+	}
+	return m, nil
+}
+
+func selectNodes(e *xpath.Expr, node xpath.NodeNavigator) []xpath.NodeNavigator {
+	var nodes []xpath.NodeNavigator
+	iter := e.Select(node.Copy())
+	for iter.MoveNext() {
+		nodes = append(nodes, iter.Current().Copy())
+	}
+	return nodes
+}
+
+// toString converts an expression result, node sets are converted to the
+// value of their first node.
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case *xpath.NodeIterator:
+		if v.MoveNext() {
+			return v.Current().Value()
+		}
+	}
+	return ""
+}
+
+func toInt(v interface{}) (int64, bool, error) {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) {
+			return 0, false, nil
+		}
+		return int64(v), true, nil
+	case bool:
+		if v {
+			return 1, true, nil
+		}
+		return 0, true, nil
+	default:
+		s := strings.TrimSpace(toString(v))
+		if s == "" {
+			return 0, false, nil
+		}
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("parse int: %w", err)
+		}
+		return i, true, nil
+	}
+}
+
+// ParseLine parses a single document which must produce one metric.
+func (p *Parser) ParseLine(line string) (cua.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metrics in line")
+	}
+	if len(metrics) > 1 {
+		return nil, fmt.Errorf("more than one metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,153 @@
+package xpath
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const sensorsXML = `<?xml version="1.0"?>
+<Gateway>
+  <Name>gw-01</Name>
+  <Timestamp>1612345678</Timestamp>
+  <Bus>
+    <Sensor name="Sensor Facility A">
+      <Variable temperature="20.0"/>
+      <Variable consumers="3"/>
+      <Variable ok="true"/>
+      <Mode>busy</Mode>
+    </Sensor>
+    <Sensor name="Sensor Facility B">
+      <Variable temperature="23.1"/>
+      <Variable consumers="1"/>
+      <Variable ok="false"/>
+      <Mode>idle</Mode>
+    </Sensor>
+  </Bus>
+</Gateway>
+`
+
+func newParser(t *testing.T, format string, configs ...Config) *Parser {
+	p, err := New(format, configs, "xml", nil)
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+	return p
+}
+
+func TestSelection(t *testing.T) {
+	p := newParser(t, "xml", Config{
+		MetricQuery: "'sensor'",
+		Selection:   "/Gateway/Bus/Sensor",
+		Timestamp:   "/Gateway/Timestamp",
+		Tags: map[string]string{
+			"name":    "substring-after(@name, ' ')",
+			"gateway": "/Gateway/Name",
+		},
+		Fields: map[string]string{
+			"temperature": "number(Variable/@temperature)",
+			"ok":          "Variable/@ok = 'true'",
+			"mode":        "Mode",
+		},
+		FieldsInt: map[string]string{
+			"consumers": "Variable/@consumers",
+		},
+	})
+
+	metrics, err := p.Parse([]byte(sensorsXML))
+	require.NoError(t, err)
+
+	ts := time.Unix(1612345678, 0)
+	expected := []cua.Metric{
+		testutil.MustMetric("sensor",
+			map[string]string{"name": "Facility A", "gateway": "gw-01"},
+			map[string]interface{}{"temperature": 20.0, "ok": true, "mode": "busy", "consumers": int64(3)},
+			ts),
+		testutil.MustMetric("sensor",
+			map[string]string{"name": "Facility B", "gateway": "gw-01"},
+			map[string]interface{}{"temperature": 23.1, "ok": false, "mode": "idle", "consumers": int64(1)},
+			ts),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestFieldSelection(t *testing.T) {
+	p := newParser(t, "xml", Config{
+		Selection:       "/Gateway/Bus/Sensor",
+		Tags:            map[string]string{"name": "@name"},
+		FieldSelection:  "Variable/@*",
+		FieldValueQuery: ".",
+	})
+
+	metrics, err := p.Parse([]byte(sensorsXML))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]interface{}{
+		"temperature": "20.0",
+		"consumers":   "3",
+		"ok":          "true",
+	}, metrics[0].Fields())
+	require.Equal(t, time.Unix(42, 0), metrics[0].Time())
+	require.Equal(t, "xml", metrics[0].Name())
+}
+
+func TestHTML(t *testing.T) {
+	p := newParser(t, "html", Config{
+		MetricQuery: "'status'",
+		Selection:   "//table[@id='ports']//tr[td]",
+		Tags:        map[string]string{"port": "td[1]"},
+		Fields:      map[string]string{"up": "td[2] = 'up'"},
+		FieldsInt:   map[string]string{"errors": "td[3]"},
+	})
+
+	metrics, err := p.Parse([]byte(`<html><body>
+<table id="ports">
+<tr><th>Port</th><th>State</th><th>Errors</th></tr>
+<tr><td>eth0</td><td>up</td><td>0</td></tr>
+<tr><td>eth1</td><td>down</td><td>12</td></tr>
+</table>
+</body></html>`))
+	require.NoError(t, err)
+
+	now := time.Unix(42, 0)
+	expected := []cua.Metric{
+		testutil.MustMetric("status", map[string]string{"port": "eth0"},
+			map[string]interface{}{"up": true, "errors": int64(0)}, now),
+		testutil.MustMetric("status", map[string]string{"port": "eth1"},
+			map[string]interface{}{"up": false, "errors": int64(12)}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestMissingValues(t *testing.T) {
+	p := newParser(t, "xml", Config{
+		Selection: "/Gateway",
+		Fields:    map[string]string{"name": "Name", "missing": "Missing"},
+		FieldsInt: map[string]string{"missing_int": "Missing"},
+	})
+
+	metrics, err := p.Parse([]byte(sensorsXML))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"name": "gw-01"}, metrics[0].Fields())
+}
+
+func TestInvalid(t *testing.T) {
+	_, err := New("xml", []Config{{Fields: map[string]string{"a": "///"}}}, "xml", nil)
+	require.Error(t, err)
+
+	_, err = New("json", []Config{{}}, "xml", nil)
+	require.Error(t, err)
+
+	_, err = New("xml", nil, "xml", nil)
+	require.Error(t, err)
+
+	p := newParser(t, "xml", Config{FieldsInt: map[string]string{"name": "/Gateway/Name"}})
+	_, err = p.Parse([]byte(sensorsXML))
+	require.Error(t, err)
+
+	_, err = p.Parse([]byte("<Gateway><Name>"))
+	require.Error(t, err)
+}
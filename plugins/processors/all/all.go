@@ -1,16 +1,22 @@
+// +build !custom
+
 package all
 
 //nolint:golint
 import (
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/circonus_histogram"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/clone"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/converter"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/date"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/dedup"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/defaults"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/derivative"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/downcase_keys"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/enum"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/execd"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/filepath"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/ifname"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/lookup"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/override"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/parser"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/pivot"
@@ -20,10 +26,12 @@ import (
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/rename"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/reverse_dns"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/s2geo"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/schema"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/starlark"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/strings"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/tag_limit"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/template"
+	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/timestamp"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/topk"
 	_ "github.com/circonus-labs/circonus-unified-agent/plugins/processors/unpivot"
 )
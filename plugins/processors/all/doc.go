@@ -0,0 +1,3 @@
+// Package all registers the processors, all of them by default or the ones selected
+// by tools/custom_builder in a build with the custom tag.
+package all
@@ -0,0 +1,238 @@
+package circonushistogram
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Fields to convert into histograms, supports glob patterns.  Only the
+  ## numeric fields are used.  Use namepass to select the measurements.
+  fields = ["*"]
+
+  ## Period of the histograms, the samples received during a period are sent
+  ## as a single histogram per series and field.  Set it to the flush_interval
+  ## of the agent to send a histogram per flush.
+  # period = "10s"
+
+  ## Remove the converted fields from the metrics, the metrics left without
+  ## fields are dropped.  Disable it to send both the samples and the
+  ## histograms.
+  # drop_original = true
+`
+
+// seriesKey identifies the histogram of a field of a series.
+type seriesKey struct {
+	id    uint64
+	field string
+}
+
+// series is the histogram of the samples of a field of a series.
+type series struct {
+	name           string
+	tags           map[string]string
+	origin         string
+	originInstance string
+	bins           map[float64]int64
+}
+
+type CirconusHistogram struct {
+	Fields       []string          `toml:"fields"`
+	Period       internal.Duration `toml:"period"`
+	DropOriginal bool              `toml:"drop_original"`
+	Log          cua.Logger        `toml:"-"`
+
+	fieldFilter filter.Filter
+	acc         cua.Accumulator
+	mu          sync.Mutex
+	cache       map[seriesKey]*series
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func (h *CirconusHistogram) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *CirconusHistogram) Description() string {
+	return "Convert numeric samples into Circonus log-linear histograms"
+}
+
+func (h *CirconusHistogram) Init() error {
+	if h.Period.Duration <= 0 {
+		return fmt.Errorf("period must be positive, got %s", h.Period.Duration)
+	}
+
+	var err error
+	h.fieldFilter, err = filter.Compile(h.Fields)
+	if err != nil {
+		return fmt.Errorf("fields: %w", err)
+	}
+	h.cache = make(map[seriesKey]*series)
+	return nil
+}
+
+func (h *CirconusHistogram) Start(acc cua.Accumulator) error {
+	h.acc = acc
+	h.done = make(chan struct{})
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(h.Period.Duration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+				h.flush()
+			}
+		}
+	}()
+	return nil
+}
+
+func (h *CirconusHistogram) Add(m cua.Metric, acc cua.Accumulator) error {
+	h.mu.Lock()
+	converted := h.add(m)
+	h.mu.Unlock()
+
+	if converted && h.DropOriginal && len(m.FieldList()) == 0 {
+		m.Drop()
+		return nil
+	}
+	acc.AddMetric(m)
+	return nil
+}
+
+// Stop sends the histograms of the current period.
+func (h *CirconusHistogram) Stop() error {
+	close(h.done)
+	h.wg.Wait()
+	h.flush()
+	return nil
+}
+
+// add observes the fields of the metric to convert, it returns whether any
+// was converted.
+func (h *CirconusHistogram) add(m cua.Metric) bool {
+	id := m.HashID()
+	converted := false
+	// the fields are removed while iterating with drop_original
+	for _, field := range append([]*cua.Field(nil), m.FieldList()...) {
+		if h.fieldFilter != nil && !h.fieldFilter.Match(field.Key) {
+			continue
+		}
+		value, ok := toFloat(field.Value)
+		if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+
+		key := seriesKey{id: id, field: field.Key}
+		s, ok := h.cache[key]
+		if !ok {
+			s = &series{
+				name:           field.Key,
+				tags:           m.Tags(),
+				origin:         m.Origin(),
+				originInstance: m.OriginInstance(),
+				bins:           make(map[float64]int64),
+			}
+			h.cache[key] = s
+		}
+		s.bins[bin(value)]++
+		converted = true
+
+		if h.DropOriginal {
+			m.RemoveField(field.Key)
+		}
+	}
+	return converted
+}
+
+// flush sends a histogram per series and field with the samples received
+// since the previous flush, and forgets the series.
+func (h *CirconusHistogram) flush() {
+	h.mu.Lock()
+	cache := h.cache
+	h.cache = make(map[seriesKey]*series, len(cache))
+	h.mu.Unlock()
+
+	now := time.Now()
+	for _, s := range cache {
+		fields := make(map[string]interface{}, len(s.bins))
+		for b, n := range s.bins {
+			fields[strconv.FormatFloat(b, 'g', -1, 64)] = n
+		}
+		m, err := metric.New(s.name, s.tags, fields, now, cua.Histogram)
+		if err != nil {
+			h.Log.Errorf("Error creating histogram %q: %s", s.name, err)
+			continue
+		}
+		// the histograms are sent to the check of the plugin of the samples
+		m.SetOrigin(s.origin)
+		m.SetOriginInstance(s.originInstance)
+		// as the metrics of the aggregators, they arrive when flushed
+		m.SetArrival(now)
+		h.acc.AddMetric(m)
+	}
+}
+
+// bin returns the bound closest to zero of the log-linear bin of a value,
+// with two significant digits: 0.1234 is in the bin 0.12, 1234 in the bin
+// 1200 and -1234 in the bin -1200.
+func bin(v float64) float64 {
+	if v == 0 {
+		return 0
+	}
+	if v < 0 {
+		return -bin(-v)
+	}
+	e := int(math.Floor(math.Log10(v))) - 1
+	// the mantissa is divided rather than multiplied by a negative power of
+	// ten to get the exact decimal bounds, like 0.12 rather than
+	// 0.12000000000000001
+	if e < 0 {
+		p := math.Pow10(-e)
+		m := math.Floor(v * p)
+		if m >= 100 {
+			return bin(m / p)
+		}
+		return m / p
+	}
+	p := math.Pow10(e)
+	return math.Floor(v/p) * p
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.AddStreaming("circonus_histogram", func() cua.StreamingProcessor {
+		return &CirconusHistogram{
+			Fields:       []string{"*"},
+			Period:       internal.Duration{Duration: 10 * time.Second},
+			DropOriginal: true,
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package circonushistogram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newHistogram(t *testing.T, fields []string, dropOriginal bool) *CirconusHistogram {
+	h := &CirconusHistogram{
+		Fields:       fields,
+		Period:       internal.Duration{Duration: time.Hour},
+		DropOriginal: dropOriginal,
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, h.Init())
+	return h
+}
+
+// metricsAccumulator keeps the added metrics, with their origin.
+type metricsAccumulator struct {
+	testutil.Accumulator
+	added []cua.Metric
+}
+
+func (a *metricsAccumulator) AddMetric(m cua.Metric) {
+	a.added = append(a.added, m)
+	a.Accumulator.AddMetric(m)
+}
+
+func timing(fields map[string]interface{}) cua.Metric {
+	m := testutil.MustMetric("statsd_timing", map[string]string{"metric_type": "timing"}, fields, time.Now())
+	m.SetOrigin("statsd")
+	m.SetOriginInstance("web")
+	return m
+}
+
+func TestHistograms(t *testing.T) {
+	h := newHistogram(t, []string{"latency"}, true)
+	acc := &metricsAccumulator{}
+	require.NoError(t, h.Start(acc))
+
+	for _, v := range []interface{}{0.1234, 0.125, int64(1234), uint64(1299), 0.0, -5.5} {
+		require.NoError(t, h.Add(timing(map[string]interface{}{"latency": v, "count": int64(1)}), acc))
+	}
+	// the metrics left without fields are dropped
+	require.NoError(t, h.Add(timing(map[string]interface{}{"latency": 0.5}), acc))
+	// the fields not converted are passed through
+	require.NoError(t, h.Add(timing(map[string]interface{}{"count": int64(2)}), acc))
+	require.Len(t, acc.GetCUAMetrics(), 7)
+	for _, m := range acc.GetCUAMetrics() {
+		require.False(t, m.HasField("latency"))
+	}
+
+	acc.added = nil
+	require.NoError(t, h.Stop())
+
+	metrics := acc.added
+	require.Len(t, metrics, 1)
+	m := metrics[0]
+	require.Equal(t, cua.Histogram, m.Type())
+	require.Equal(t, "latency", m.Name())
+	require.Equal(t, map[string]string{"metric_type": "timing"}, m.Tags())
+	require.Equal(t, map[string]interface{}{
+		"0.12": int64(2),
+		"1200": int64(2),
+		"0":    int64(1),
+		"-5.5": int64(1),
+		"0.5":  int64(1),
+	}, m.Fields())
+	require.Equal(t, "statsd", m.Origin())
+	require.Equal(t, "web", m.OriginInstance())
+}
+
+func TestHistogramsPerSeries(t *testing.T) {
+	h := newHistogram(t, []string{"*"}, false)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, h.Start(acc))
+
+	now := time.Now()
+	for _, host := range []string{"a", "b", "a"} {
+		m := testutil.MustMetric("opcua", map[string]string{"host": host},
+			map[string]interface{}{"temperature": 21.5, "quality": "OK"}, now)
+		require.NoError(t, h.Add(m, acc))
+	}
+	// the samples are kept with drop_original disabled
+	require.Len(t, acc.GetCUAMetrics(), 3)
+	for _, m := range acc.GetCUAMetrics() {
+		require.True(t, m.HasField("temperature"))
+	}
+
+	acc.ClearMetrics()
+	h.flush()
+	counts := map[string]interface{}{}
+	for _, m := range acc.GetCUAMetrics() {
+		require.Equal(t, "temperature", m.Name())
+		counts[m.Tags()["host"]] = m.Fields()["21"]
+	}
+	require.Equal(t, map[string]interface{}{"a": int64(2), "b": int64(1)}, counts)
+
+	// the series are forgotten after each period
+	acc.ClearMetrics()
+	h.flush()
+	require.Empty(t, acc.GetCUAMetrics())
+	require.NoError(t, h.Stop())
+}
+
+func TestBin(t *testing.T) {
+	tests := []struct {
+		value float64
+		bin   float64
+	}{
+		{0, 0},
+		{1, 1},
+		{9.99, 9.9},
+		{10, 10},
+		{0.1234, 0.12},
+		{0.0999, 0.099},
+		{123456, 120000},
+		{-0.1234, -0.12},
+		{-1234, -1200},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.bin, bin(tt.value), "value %v", tt.value)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	h := &CirconusHistogram{Fields: []string{"*"}}
+	require.Error(t, h.Init())
+
+	h = &CirconusHistogram{Fields: []string{"["}, Period: internal.Duration{Duration: time.Second}}
+	require.Error(t, h.Init())
+}
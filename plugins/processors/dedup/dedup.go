@@ -27,12 +27,6 @@ func (d *Dedup) Description() string {
 	return "Filter metrics with repeating field values"
 }
 
-// Remove single item from slice
-func remove(slice []cua.Metric, i int) []cua.Metric {
-	slice[len(slice)-1], slice[i] = slice[i], slice[len(slice)-1]
-	return slice[:len(slice)-1]
-}
-
 // Remove expired items from cache
 func (d *Dedup) cleanup() {
 	// No need to cleanup cache too often. Lets save some CPU
@@ -57,19 +51,23 @@ func (d *Dedup) save(metric cua.Metric, id uint64) {
 
 // main processing method
 func (d *Dedup) Apply(metrics ...cua.Metric) []cua.Metric {
-	for idx, metric := range metrics {
+	// the metrics kept are moved to the front, in their original order
+	out := metrics[:0]
+	for _, metric := range metrics {
 		id := metric.HashID()
 		m, ok := d.Cache[id]
 
 		// If not in cache then just save it
 		if !ok {
 			d.save(metric, id)
+			out = append(out, metric)
 			continue
 		}
 
 		// If cache item has expired then refresh it
 		if time.Since(m.Time()) >= d.DedupInterval.Duration {
 			d.save(metric, id)
+			out = append(out, metric)
 			continue
 		}
 
@@ -103,18 +101,20 @@ func (d *Dedup) Apply(metrics ...cua.Metric) []cua.Metric {
 		// If any field value has changed then refresh the cache
 		if changed {
 			d.save(metric, id)
+			out = append(out, metric)
 			continue
 		}
 
 		if sametime && added {
+			out = append(out, metric)
 			continue
 		}
 
 		// In any other case remove metric from the output
-		metrics = remove(metrics, idx)
+		metric.Drop()
 	}
 	d.cleanup()
-	return metrics
+	return out
 }
 
 func init() {
@@ -191,3 +191,22 @@ func TestSameTimestamp(t *testing.T) {
 	out = dedup.Apply(in)
 	require.Equal(t, []cua.Metric{}, out) // drop
 }
+
+func TestSuppressRepeatedValuesInBatch(t *testing.T) {
+	deduplicate := createDedup(time.Now())
+	past := time.Now().Add(-1 * time.Second)
+	_ = deduplicate.Apply(createMetric("m1", 1, past), createMetric("m2", 1, past))
+
+	now := time.Now()
+	target := deduplicate.Apply(
+		createMetric("m1", 1, now),
+		createMetric("m2", 1, now),
+		createMetric("m3", 1, now),
+		createMetric("m4", 1, now),
+	)
+
+	require.Len(t, target, 2)
+	require.Equal(t, "m3", target[0].Name())
+	require.Equal(t, "m4", target[1].Name())
+	require.Len(t, deduplicate.Cache, 4)
+}
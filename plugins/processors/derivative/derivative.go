@@ -0,0 +1,198 @@
+package derivative
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Counter fields to compute the rate of, supports glob patterns.  Only the
+  ## numeric fields are used.  Use namepass to select the measurements.
+  fields = ["*"]
+
+  ## Suffix of the rate fields, added next to the counter fields.  Ignored
+  ## when replace is enabled.
+  # suffix = "_rate"
+
+  ## Replace the counter fields with their rates, using the counter field
+  ## names.  The metrics left without fields, such as the first ones of each
+  ## series, are dropped.
+  # replace = false
+
+  ## Unit of the rates, "1s" for per second rates.
+  # per = "1s"
+
+  ## Counters not seen for this long are forgotten, their next value starts a
+  ## new series without a rate.
+  # max_age = "10m"
+`
+
+// sample is the last value of a counter.
+type sample struct {
+	value float64
+	time  time.Time
+}
+
+type Derivative struct {
+	Fields  []string          `toml:"fields"`
+	Suffix  string            `toml:"suffix"`
+	Replace bool              `toml:"replace"`
+	Per     internal.Duration `toml:"per"`
+	MaxAge  internal.Duration `toml:"max_age"`
+	Log     cua.Logger        `toml:"-"`
+
+	fieldFilter filter.Filter
+	// last values of the counters by series and field
+	cache     map[uint64]map[string]sample
+	cleanedAt time.Time
+}
+
+func (d *Derivative) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Derivative) Description() string {
+	return "Compute the rate of change of monotonically increasing counters"
+}
+
+func (d *Derivative) Init() error {
+	if d.Per.Duration <= 0 {
+		return fmt.Errorf("per must be positive, got %s", d.Per.Duration)
+	}
+	if !d.Replace && d.Suffix == "" {
+		return fmt.Errorf("suffix must be set unless replace is enabled")
+	}
+
+	var err error
+	d.fieldFilter, err = filter.Compile(d.Fields)
+	if err != nil {
+		return fmt.Errorf("fields: %w", err)
+	}
+	d.cache = make(map[uint64]map[string]sample)
+	d.cleanedAt = time.Now()
+	return nil
+}
+
+func (d *Derivative) Apply(in ...cua.Metric) []cua.Metric {
+	out := in[:0]
+	for _, m := range in {
+		if d.apply(m) {
+			out = append(out, m)
+		} else {
+			m.Drop()
+		}
+	}
+	d.cleanup()
+	return out
+}
+
+// apply adds the rates of the counters of the metric, it returns false when
+// the metric must be dropped.
+func (d *Derivative) apply(m cua.Metric) bool {
+	id := m.HashID()
+	series, ok := d.cache[id]
+	if !ok {
+		series = make(map[string]sample)
+		d.cache[id] = series
+	}
+
+	now := m.Time()
+	// the fields are modified while computing the rates
+	fields := m.FieldList()
+	keys := make([]string, 0, len(fields))
+	values := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		keys = append(keys, field.Key)
+		values = append(values, field.Value)
+	}
+
+	for i, key := range keys {
+		if d.fieldFilter != nil && !d.fieldFilter.Match(key) {
+			continue
+		}
+		value, ok := toFloat(values[i])
+		if !ok {
+			continue
+		}
+
+		prev, seen := series[key]
+		if seen && !now.After(prev.time) {
+			// out of order or duplicate sample, keep the newest value
+			if d.Replace {
+				m.RemoveField(key)
+			}
+			continue
+		}
+		series[key] = sample{value: value, time: now}
+
+		if d.Replace {
+			m.RemoveField(key)
+		}
+
+		switch {
+		case !seen:
+			continue
+		case now.Sub(prev.time) > d.MaxAge.Duration && d.MaxAge.Duration > 0:
+			continue
+		case value < prev.value:
+			d.Log.Debugf("Counter %q of %q reset from %v to %v", key, m.Name(), prev.value, value)
+			continue
+		}
+
+		rate := (value - prev.value) * float64(d.Per.Duration) / float64(now.Sub(prev.time))
+		if d.Replace {
+			m.AddField(key, rate)
+		} else {
+			m.AddField(key+d.Suffix, rate)
+		}
+	}
+
+	return len(m.FieldList()) != 0
+}
+
+// cleanup forgets the counters not seen for longer than max_age.
+func (d *Derivative) cleanup() {
+	if d.MaxAge.Duration <= 0 || time.Since(d.cleanedAt) < d.MaxAge.Duration {
+		return
+	}
+	d.cleanedAt = time.Now()
+	for id, series := range d.cache {
+		for key, s := range series {
+			if time.Since(s.time) > d.MaxAge.Duration {
+				delete(series, key)
+			}
+		}
+		if len(series) == 0 {
+			delete(d.cache, id)
+		}
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.Add("derivative", func() cua.Processor {
+		return &Derivative{
+			Fields: []string{"*"},
+			Suffix: "_rate",
+			Per:    internal.Duration{Duration: time.Second},
+			MaxAge: internal.Duration{Duration: 10 * time.Minute},
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package derivative
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newDerivative(t *testing.T, fields []string, replace bool) *Derivative {
+	d := &Derivative{
+		Fields:  fields,
+		Suffix:  "_rate",
+		Replace: replace,
+		Per:     internal.Duration{Duration: time.Second},
+		MaxAge:  internal.Duration{Duration: 10 * time.Minute},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, d.Init())
+	return d
+}
+
+func nstat(fields map[string]interface{}, sec int64) cua.Metric {
+	return testutil.MustMetric("nstat", map[string]string{"name": "netstat"}, fields, time.Unix(sec, 0))
+}
+
+func TestRates(t *testing.T) {
+	d := newDerivative(t, []string{"Tcp*"}, false)
+
+	now := time.Now().Unix()
+	out := d.Apply(nstat(map[string]interface{}{"TcpInSegs": int64(100), "UdpInDatagrams": uint64(5), "state": "up"}, now))
+	testutil.RequireMetricsEqual(t,
+		[]cua.Metric{nstat(map[string]interface{}{"TcpInSegs": int64(100), "UdpInDatagrams": uint64(5), "state": "up"}, now)},
+		out)
+
+	out = d.Apply(nstat(map[string]interface{}{"TcpInSegs": int64(150), "UdpInDatagrams": uint64(9), "state": "up"}, now+10))
+	testutil.RequireMetricsEqual(t,
+		[]cua.Metric{nstat(map[string]interface{}{"TcpInSegs": int64(150), "TcpInSegs_rate": 5.0, "UdpInDatagrams": uint64(9), "state": "up"}, now+10)},
+		out)
+}
+
+func TestCounterReset(t *testing.T) {
+	d := newDerivative(t, []string{"*"}, false)
+
+	now := time.Now().Unix()
+	d.Apply(nstat(map[string]interface{}{"TcpInSegs": uint64(1000)}, now))
+	out := d.Apply(nstat(map[string]interface{}{"TcpInSegs": uint64(10)}, now+10))
+	testutil.RequireMetricsEqual(t,
+		[]cua.Metric{nstat(map[string]interface{}{"TcpInSegs": uint64(10)}, now+10)},
+		out)
+
+	// the rate starts again from the value after the reset
+	out = d.Apply(nstat(map[string]interface{}{"TcpInSegs": uint64(30)}, now+20))
+	testutil.RequireMetricsEqual(t,
+		[]cua.Metric{nstat(map[string]interface{}{"TcpInSegs": uint64(30), "TcpInSegs_rate": 2.0}, now+20)},
+		out)
+}
+
+func TestReplace(t *testing.T) {
+	d := newDerivative(t, []string{"*"}, true)
+
+	now := time.Now().Unix()
+	out := d.Apply(nstat(map[string]interface{}{"TcpInSegs": 1.5}, now))
+	require.Empty(t, out)
+
+	out = d.Apply(
+		nstat(map[string]interface{}{"TcpInSegs": 4.5}, now+2),
+		// out of order
+		nstat(map[string]interface{}{"TcpInSegs": 3.0}, now+1),
+	)
+	testutil.RequireMetricsEqual(t,
+		[]cua.Metric{nstat(map[string]interface{}{"TcpInSegs": 1.5}, now+2)},
+		out)
+}
+
+func TestSeparateSeries(t *testing.T) {
+	d := newDerivative(t, []string{"*"}, false)
+	d.Per = internal.Duration{Duration: time.Minute}
+
+	now := time.Now()
+	d.Apply(
+		testutil.MustMetric("pdns", map[string]string{"server": "a"}, map[string]interface{}{"queries": int64(0)}, now),
+		testutil.MustMetric("pdns", map[string]string{"server": "b"}, map[string]interface{}{"queries": int64(600)}, now),
+	)
+	out := d.Apply(
+		testutil.MustMetric("pdns", map[string]string{"server": "a"}, map[string]interface{}{"queries": int64(60)}, now.Add(time.Minute)),
+		testutil.MustMetric("pdns", map[string]string{"server": "b"}, map[string]interface{}{"queries": int64(720)}, now.Add(time.Minute)),
+	)
+	testutil.RequireMetricsEqual(t,
+		[]cua.Metric{
+			testutil.MustMetric("pdns", map[string]string{"server": "a"}, map[string]interface{}{"queries": int64(60), "queries_rate": 60.0}, now.Add(time.Minute)),
+			testutil.MustMetric("pdns", map[string]string{"server": "b"}, map[string]interface{}{"queries": int64(720), "queries_rate": 120.0}, now.Add(time.Minute)),
+		},
+		out)
+}
+
+func TestMaxAge(t *testing.T) {
+	d := newDerivative(t, []string{"*"}, false)
+	d.MaxAge = internal.Duration{Duration: time.Minute}
+
+	now := time.Now().Add(-time.Hour)
+	d.Apply(nstat(map[string]interface{}{"TcpInSegs": int64(1)}, now.Unix()))
+	out := d.Apply(nstat(map[string]interface{}{"TcpInSegs": int64(2)}, now.Add(2*time.Minute).Unix()))
+	testutil.RequireMetricsEqual(t,
+		[]cua.Metric{nstat(map[string]interface{}{"TcpInSegs": int64(2)}, now.Add(2*time.Minute).Unix())},
+		out)
+
+	d.cleanedAt = time.Now().Add(-time.Hour)
+	d.cleanup()
+	require.Empty(t, d.cache)
+}
+
+func TestInitErrors(t *testing.T) {
+	d := &Derivative{Fields: []string{"*"}, Per: internal.Duration{Duration: time.Second}}
+	require.Error(t, d.Init())
+
+	d = &Derivative{Fields: []string{"*"}, Suffix: "_rate"}
+	require.Error(t, d.Init())
+}
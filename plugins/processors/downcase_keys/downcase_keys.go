@@ -0,0 +1,88 @@
+package downcasekeys
+
+import (
+	"strings"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Lowercase the measurement names, the tag keys and the field keys.
+  # measurement = true
+  # tag_keys = true
+  # field_keys = true
+
+  ## Lowercase the tag values too.
+  # tag_values = false
+`
+
+type DowncaseKeys struct {
+	Measurement bool `toml:"measurement"`
+	TagKeys     bool `toml:"tag_keys"`
+	FieldKeys   bool `toml:"field_keys"`
+	TagValues   bool `toml:"tag_values"`
+}
+
+func (d *DowncaseKeys) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DowncaseKeys) Description() string {
+	return "Lowercase the measurement names, tag keys and field keys of the metrics."
+}
+
+func (d *DowncaseKeys) Apply(in ...cua.Metric) []cua.Metric {
+	for _, metric := range in {
+		if d.Measurement {
+			metric.SetName(strings.ToLower(metric.Name()))
+		}
+
+		if d.TagKeys || d.TagValues {
+			tags := make([]cua.Tag, 0, len(metric.TagList()))
+			for _, tag := range metric.TagList() {
+				tags = append(tags, *tag)
+			}
+			for _, tag := range tags {
+				key, value := tag.Key, tag.Value
+				if d.TagKeys {
+					key = strings.ToLower(key)
+				}
+				if d.TagValues {
+					value = strings.ToLower(value)
+				}
+				if key == tag.Key && value == tag.Value {
+					continue
+				}
+				metric.RemoveTag(tag.Key)
+				metric.AddTag(key, value)
+			}
+		}
+
+		if d.FieldKeys {
+			fields := make([]cua.Field, 0, len(metric.FieldList()))
+			for _, field := range metric.FieldList() {
+				fields = append(fields, *field)
+			}
+			for _, field := range fields {
+				key := strings.ToLower(field.Key)
+				if key == field.Key {
+					continue
+				}
+				metric.RemoveField(field.Key)
+				metric.AddField(key, field.Value)
+			}
+		}
+	}
+	return in
+}
+
+func init() {
+	processors.Add("downcase_keys", func() cua.Processor {
+		return &DowncaseKeys{
+			Measurement: true,
+			TagKeys:     true,
+			FieldKeys:   true,
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package downcasekeys
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestMetric() cua.Metric {
+	metric, _ := metric.New("Win_CPU",
+		map[string]string{"Instance": "_Total", "host": "Web01"},
+		map[string]interface{}{"Percent_Idle_Time": 92.5, "value": int64(1)},
+		time.Now(),
+	)
+	return metric
+}
+
+func TestDefaults(t *testing.T) {
+	processor := DowncaseKeys{Measurement: true, TagKeys: true, FieldKeys: true}
+
+	processed := processor.Apply(createTestMetric())
+
+	assert.Equal(t, "win_cpu", processed[0].Name())
+	assert.Equal(t, map[string]string{"instance": "_Total", "host": "Web01"}, processed[0].Tags())
+	assert.Equal(t, map[string]interface{}{"percent_idle_time": 92.5, "value": int64(1)}, processed[0].Fields())
+}
+
+func TestTagValues(t *testing.T) {
+	processor := DowncaseKeys{TagValues: true}
+
+	processed := processor.Apply(createTestMetric())
+
+	assert.Equal(t, "Win_CPU", processed[0].Name())
+	assert.Equal(t, map[string]string{"Instance": "_total", "host": "web01"}, processed[0].Tags())
+	assert.Equal(t, map[string]interface{}{"Percent_Idle_Time": 92.5, "value": int64(1)}, processed[0].Fields())
+}
+
+func TestConflictingKeys(t *testing.T) {
+	m, _ := metric.New("m1",
+		map[string]string{"Host": "a", "host": "b"},
+		map[string]interface{}{"Value": int64(1), "value": int64(2)},
+		time.Now(),
+	)
+	processor := DowncaseKeys{TagKeys: true, FieldKeys: true}
+
+	processed := processor.Apply(m)
+
+	assert.Len(t, processed[0].TagList(), 1)
+	assert.Len(t, processed[0].FieldList(), 1)
+}
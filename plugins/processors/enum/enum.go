@@ -2,6 +2,7 @@ package enum
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
@@ -30,6 +31,16 @@ var sampleConfig = `
       green = 1
       amber = 2
       red = 3
+
+  ## Numeric values are looked up by their decimal representation, to map
+  ## status codes back to their text.
+  # [[processors.enum.mapping]]
+  #   field = "status_code"
+  #   dest = "status"
+  #   default = "Unknown"
+  #   [processors.enum.mapping.value_mappings]
+  #     "0" = "Good"
+  #     "2150891520" = "BadNodeIdUnknown"
 `
 
 type Mapper struct {
@@ -94,6 +105,13 @@ func adjustValue(in interface{}) interface{} {
 		return strconv.FormatInt(val, 10)
 	case uint64:
 		return strconv.FormatUint(val, 10)
+	case float64:
+		// numbers parsed from formats without integers, such as JSON, are
+		// looked up like integers when they have no fractional part
+		if val == math.Trunc(val) && math.Abs(val) < 1<<63 {
+			return strconv.FormatInt(int64(val), 10)
+		}
+		return in
 	default:
 		return in
 	}
@@ -159,3 +159,21 @@ func TestDoNotWriteToDestinationWithoutDefaultOrDefinedMapping(t *testing.T) {
 	_, present := fields[field]
 	assert.False(t, present, "value of field '"+field+"' was present")
 }
+
+func TestMapsIntegralFloatValue(t *testing.T) {
+	mapper := Mapper{Mappings: []Mapping{{Field: "status_code", Dest: "status", ValueMappings: map[string]interface{}{"2": "CRIT"}}}}
+	source, _ := metric.New("m1", nil, map[string]interface{}{"status_code": float64(2)}, time.Now())
+
+	fields := calculateProcessedValues(mapper, source)
+
+	assertFieldValue(t, "CRIT", "status", fields)
+	assertFieldValue(t, float64(2), "status_code", fields)
+}
+
+func TestMapsNumericValueToTextWithDefault(t *testing.T) {
+	mapper := Mapper{Mappings: []Mapping{{Field: "uint_value", Dest: "status", Default: "Unknown", ValueMappings: map[string]interface{}{"0": "Good"}}}}
+
+	fields := calculateProcessedValues(mapper, createTestMetric())
+
+	assertFieldValue(t, "Unknown", "status", fields)
+}
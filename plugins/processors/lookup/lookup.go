@@ -0,0 +1,329 @@
+package lookup
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/common/tls"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Tag of the metrics holding the lookup key.
+  key = "device_id"
+
+  ## Files and URLs of the lookup tables, their entries are merged, the last
+  ## source wins for a key present in several.
+  files = ["/etc/circonus-unified-agent/devices.csv"]
+  # urls = ["https://inventory.example.com/devices.json"]
+
+  ## Format of the tables, "csv" or "json".
+  ##   csv: a header row, then one row per key, the header names the tags.
+  ##   json: an object mapping the keys to objects of tags, or an array of
+  ##         objects of tags.
+  # format = "csv"
+
+  ## Column or attribute holding the key, defaults to the first column of
+  ## csv tables; required for json arrays.
+  # key_column = ""
+
+  ## Overwrite the tags already set on the metrics.
+  # overwrite = false
+
+  ## Interval between reloads of the tables, 0 to load them only once.  The
+  ## previous entries are kept when a reload fails.
+  # reload_interval = "5m"
+
+  ## Timeout of the HTTP requests.
+  # timeout = "5s"
+
+  ## Optional TLS Config for the URLs
+  # tls_ca = "/etc/circonus-unified-agent/ca.pem"
+  # tls_cert = "/etc/circonus-unified-agent/cert.pem"
+  # tls_key = "/etc/circonus-unified-agent/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// table maps the lookup keys to the tags to add.
+type table map[string]map[string]string
+
+type Lookup struct {
+	Key            string            `toml:"key"`
+	Files          []string          `toml:"files"`
+	URLs           []string          `toml:"urls"`
+	Format         string            `toml:"format"`
+	KeyColumn      string            `toml:"key_column"`
+	Overwrite      bool              `toml:"overwrite"`
+	ReloadInterval internal.Duration `toml:"reload_interval"`
+	Timeout        internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+	Log cua.Logger `toml:"-"`
+
+	client *http.Client
+
+	mu    sync.RWMutex
+	table table
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (l *Lookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Lookup) Description() string {
+	return "Add tags from a lookup table keyed by a tag of the metrics"
+}
+
+func (l *Lookup) Init() error {
+	if l.Key == "" {
+		return fmt.Errorf("key must be set")
+	}
+	if len(l.Files) == 0 && len(l.URLs) == 0 {
+		return fmt.Errorf("no files or urls configured")
+	}
+	switch l.Format {
+	case "csv", "json":
+	default:
+		return fmt.Errorf("invalid format %q, expected \"csv\" or \"json\"", l.Format)
+	}
+
+	tlsCfg, err := l.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("TLSConfig: %w", err)
+	}
+	l.client = &http.Client{
+		Transport: &http.Transport{
+			TLSHandshakeTimeout: 5 * time.Second,
+			TLSClientConfig:     tlsCfg,
+		},
+		Timeout: l.Timeout.Duration,
+	}
+	return nil
+}
+
+func (l *Lookup) Start(acc cua.Accumulator) error {
+	t, err := l.load()
+	if err != nil {
+		return err
+	}
+	l.setTable(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	if l.ReloadInterval.Duration > 0 {
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			l.reloadLoop(ctx)
+		}()
+	}
+	return nil
+}
+
+func (l *Lookup) Add(metric cua.Metric, acc cua.Accumulator) error {
+	if key, ok := metric.GetTag(l.Key); ok {
+		l.mu.RLock()
+		tags := l.table[key]
+		l.mu.RUnlock()
+		for k, v := range tags {
+			if !l.Overwrite && metric.HasTag(k) {
+				continue
+			}
+			metric.AddTag(k, v)
+		}
+	}
+	acc.AddMetric(metric)
+	return nil
+}
+
+func (l *Lookup) Stop() error {
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.wg.Wait()
+	return nil
+}
+
+func (l *Lookup) reloadLoop(ctx context.Context) {
+	ticker := time.NewTicker(l.ReloadInterval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t, err := l.load()
+			if err != nil {
+				l.Log.Errorf("Reloading, keeping the previous entries: %v", err)
+				continue
+			}
+			l.setTable(t)
+		}
+	}
+}
+
+func (l *Lookup) setTable(t table) {
+	l.mu.Lock()
+	l.table = t
+	l.mu.Unlock()
+	l.Log.Debugf("Loaded %d entries", len(t))
+}
+
+// load reads all the sources and merges their entries.
+func (l *Lookup) load() (table, error) {
+	t := make(table)
+	for _, file := range l.Files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read (%s): %w", file, err)
+		}
+		if err := l.parse(t, data); err != nil {
+			return nil, fmt.Errorf("parse (%s): %w", file, err)
+		}
+	}
+	for _, url := range l.URLs {
+		data, err := l.fetch(url)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.parse(t, data); err != nil {
+			return nil, fmt.Errorf("parse (%s): %w", url, err)
+		}
+	}
+	return t, nil
+}
+
+func (l *Lookup) fetch(url string) ([]byte, error) {
+	resp, err := l.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("get (%s): %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get (%s): %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read (%s): %w", url, err)
+	}
+	return data, nil
+}
+
+func (l *Lookup) parse(t table, data []byte) error {
+	if l.Format == "json" {
+		return l.parseJSON(t, data)
+	}
+	return l.parseCSV(t, data)
+}
+
+func (l *Lookup) parseCSV(t table, data []byte) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	header := records[0]
+	keyIndex := 0
+	if l.KeyColumn != "" {
+		keyIndex = -1
+		for i, column := range header {
+			if column == l.KeyColumn {
+				keyIndex = i
+			}
+		}
+		if keyIndex < 0 {
+			return fmt.Errorf("no %q column", l.KeyColumn)
+		}
+	}
+
+	for _, record := range records[1:] {
+		tags := make(map[string]string)
+		for i, value := range record {
+			if i != keyIndex && value != "" {
+				tags[header[i]] = value
+			}
+		}
+		t[record[keyIndex]] = tags
+	}
+	return nil
+}
+
+func (l *Lookup) parseJSON(t table, data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		for key, entry := range doc {
+			object, ok := entry.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("entry %q is not an object", key)
+			}
+			t[key] = jsonTags(object, "")
+		}
+	case []interface{}:
+		if l.KeyColumn == "" {
+			return fmt.Errorf("key_column is required for arrays")
+		}
+		for i, entry := range doc {
+			object, ok := entry.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("entry %d is not an object", i)
+			}
+			key, ok := object[l.KeyColumn]
+			if !ok {
+				return fmt.Errorf("entry %d has no %q attribute", i, l.KeyColumn)
+			}
+			t[fmt.Sprint(key)] = jsonTags(object, l.KeyColumn)
+		}
+	default:
+		return fmt.Errorf("expected an object or an array")
+	}
+	return nil
+}
+
+// jsonTags returns the scalar attributes of an object as tags.
+func jsonTags(object map[string]interface{}, skip string) map[string]string {
+	tags := make(map[string]string, len(object))
+	for k, v := range object {
+		switch v.(type) {
+		case nil, map[string]interface{}, []interface{}:
+			continue
+		}
+		if k != skip {
+			tags[k] = fmt.Sprint(v)
+		}
+	}
+	return tags
+}
+
+func init() {
+	processors.AddStreaming("lookup", func() cua.StreamingProcessor {
+		return &Lookup{
+			Format:         "csv",
+			ReloadInterval: internal.Duration{Duration: 5 * time.Minute},
+			Timeout:        internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package lookup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newLookup(format string) *Lookup {
+	return &Lookup{
+		Key:     "device_id",
+		Format:  format,
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		Log:     testutil.Logger{},
+	}
+}
+
+func device(tags map[string]string) cua.Metric {
+	return testutil.MustMetric("opcua", tags, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+}
+
+func TestLookupCSV(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "devices.csv")
+	require.NoError(t, os.WriteFile(file, []byte(`# inventory
+device_id, site, rack, owner
+plc-1, paris, r1, ops
+plc-2, lyon, , maintenance
+`), 0600))
+
+	l := newLookup("csv")
+	l.Files = []string{file}
+	require.NoError(t, l.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, l.Start(&acc))
+	require.NoError(t, l.Add(device(map[string]string{"device_id": "plc-1", "owner": "it"}), &acc))
+	require.NoError(t, l.Add(device(map[string]string{"device_id": "plc-2"}), &acc))
+	require.NoError(t, l.Add(device(map[string]string{"device_id": "plc-3"}), &acc))
+	require.NoError(t, l.Add(device(map[string]string{"host": "a"}), &acc))
+	require.NoError(t, l.Stop())
+
+	testutil.RequireMetricsEqual(t, []cua.Metric{
+		device(map[string]string{"device_id": "plc-1", "site": "paris", "rack": "r1", "owner": "it"}),
+		device(map[string]string{"device_id": "plc-2", "site": "lyon", "owner": "maintenance"}),
+		device(map[string]string{"device_id": "plc-3"}),
+		device(map[string]string{"host": "a"}),
+	}, acc.GetCUAMetrics())
+}
+
+func TestLookupCSVKeyColumnOverwrite(t *testing.T) {
+	l := newLookup("csv")
+	l.KeyColumn = "id"
+	l.Overwrite = true
+
+	tbl := make(table)
+	require.NoError(t, l.parse(tbl, []byte("site,id\nparis,plc-1\n")))
+	l.table = tbl
+
+	var acc testutil.Accumulator
+	require.NoError(t, l.Add(device(map[string]string{"device_id": "plc-1", "site": "unknown"}), &acc))
+	testutil.RequireMetricsEqual(t, []cua.Metric{
+		device(map[string]string{"device_id": "plc-1", "site": "paris"}),
+	}, acc.GetCUAMetrics())
+
+	l.KeyColumn = "serial"
+	require.Error(t, l.parse(tbl, []byte("site,id\nparis,plc-1\n")))
+}
+
+func TestLookupJSON(t *testing.T) {
+	l := newLookup("json")
+
+	tbl := make(table)
+	require.NoError(t, l.parse(tbl, []byte(`{"plc-1": {"site": "paris", "floor": 2, "links": []}}`)))
+	require.Equal(t, table{"plc-1": {"site": "paris", "floor": "2"}}, tbl)
+
+	require.Error(t, l.parse(tbl, []byte(`[{"id": "plc-2", "site": "lyon"}]`)))
+
+	l.KeyColumn = "id"
+	tbl = make(table)
+	require.NoError(t, l.parse(tbl, []byte(`[{"id": "plc-2", "site": "lyon"}]`)))
+	require.Equal(t, table{"plc-2": {"site": "lyon"}}, tbl)
+
+	require.Error(t, l.parse(tbl, []byte(`[{"site": "lyon"}]`)))
+	require.Error(t, l.parse(tbl, []byte(`"plc"`)))
+}
+
+func TestLookupURLReload(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			_, _ = w.Write([]byte(`{"plc-1": {"site": "paris"}}`))
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`{"plc-1": {"site": "lyon"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	l := newLookup("json")
+	l.URLs = []string{ts.URL}
+	l.ReloadInterval = internal.Duration{Duration: 10 * time.Millisecond}
+	require.NoError(t, l.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, l.Start(&acc))
+	defer l.Stop()
+
+	site := func() string {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		return l.table["plc-1"]["site"]
+	}
+	require.Equal(t, "paris", site())
+	// the failed reload keeps the previous entries
+	require.Eventually(t, func() bool {
+		return site() == "lyon"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.GreaterOrEqual(t, atomic.LoadInt32(&requests), int32(3))
+}
+
+func TestLookupStartError(t *testing.T) {
+	l := newLookup("csv")
+	l.Files = []string{filepath.Join(t.TempDir(), "missing.csv")}
+	require.NoError(t, l.Init())
+	require.Error(t, l.Start(&testutil.Accumulator{}))
+}
+
+func TestLookupInitErrors(t *testing.T) {
+	l := newLookup("csv")
+	require.Error(t, l.Init())
+
+	l = newLookup("yaml")
+	l.Files = []string{"devices.yaml"}
+	require.Error(t, l.Init())
+
+	l = newLookup("csv")
+	l.Key = ""
+	l.Files = []string{"devices.csv"}
+	require.Error(t, l.Init())
+}
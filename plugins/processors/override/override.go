@@ -1,7 +1,10 @@
 package override
 
 import (
+	"fmt"
+
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
 )
 
@@ -14,6 +17,14 @@ var sampleConfig = `
   ## Tags to be added (all values must be strings)
   # [processors.override.tags]
   #   additional_tag = "tag_value"
+
+  ## Rules applied in order after the modifications above, to the metrics
+  ## whose name matches one of the glob patterns of the rule.
+  # [[processors.override.rule]]
+  #   metrics = ["win_cpu"]
+  #   name_override = "cpu"
+  #   [processors.override.rule.tags]
+  #     source = "windows"
 `
 
 type Override struct {
@@ -21,6 +32,16 @@ type Override struct {
 	NamePrefix   string
 	NameSuffix   string
 	Tags         map[string]string
+	Rules        []*Rule `toml:"rule"`
+}
+
+// Rule overrides the name and the tags of the metrics it matches.
+type Rule struct {
+	Metrics      []string          `toml:"metrics"`
+	NameOverride string            `toml:"name_override"`
+	Tags         map[string]string `toml:"tags"`
+
+	metricFilter filter.Filter
 }
 
 func (p *Override) SampleConfig() string {
@@ -31,6 +52,20 @@ func (p *Override) Description() string {
 	return "Apply metric modifications using override semantics."
 }
 
+func (p *Override) Init() error {
+	for i, rule := range p.Rules {
+		if len(rule.Metrics) == 0 {
+			return fmt.Errorf("rule %d: metrics is required", i+1)
+		}
+		f, err := filter.Compile(rule.Metrics)
+		if err != nil {
+			return fmt.Errorf("rule %d: metrics: %w", i+1, err)
+		}
+		rule.metricFilter = f
+	}
+	return nil
+}
+
 func (p *Override) Apply(in ...cua.Metric) []cua.Metric {
 	for _, metric := range in {
 		if len(p.NameOverride) > 0 {
@@ -45,6 +80,17 @@ func (p *Override) Apply(in ...cua.Metric) []cua.Metric {
 		for key, value := range p.Tags {
 			metric.AddTag(key, value)
 		}
+		for _, rule := range p.Rules {
+			if rule.metricFilter == nil || !rule.metricFilter.Match(metric.Name()) {
+				continue
+			}
+			if len(rule.NameOverride) > 0 {
+				metric.SetName(rule.NameOverride)
+			}
+			for key, value := range rule.Tags {
+				metric.AddTag(key, value)
+			}
+		}
 	}
 	return in
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/metric"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func createTestMetric() cua.Metric {
@@ -78,3 +79,30 @@ func TestNameSuffix(t *testing.T) {
 
 	assert.Equal(t, "m1-suff", processed[0].Name(), "Suffix was not applied")
 }
+
+func TestRules(t *testing.T) {
+	processor := Override{
+		NamePrefix: "win_",
+		Tags:       map[string]string{"source": "agent"},
+		Rules: []*Rule{
+			{Metrics: []string{"win_m*"}, NameOverride: "cpu", Tags: map[string]string{"source": "windows"}},
+			// applied to the result of the previous rule
+			{Metrics: []string{"cpu"}, Tags: map[string]string{"legacy": "true"}},
+			{Metrics: []string{"m1"}, Tags: map[string]string{"unmatched": "true"}},
+		},
+	}
+	require.NoError(t, processor.Init())
+
+	processed := processor.Apply(createTestMetric())[0]
+	assert.Equal(t, "cpu", processed.Name())
+	assert.Equal(t, map[string]string{
+		"metric_tag": "from_metric",
+		"source":     "windows",
+		"legacy":     "true",
+	}, processed.Tags())
+}
+
+func TestRuleRequiresMetrics(t *testing.T) {
+	processor := Override{Rules: []*Rule{{Tags: map[string]string{"a": "b"}}}}
+	require.Error(t, processor.Init())
+}
@@ -1,8 +1,6 @@
 package parser
 
 import (
-	"log"
-
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
@@ -14,6 +12,8 @@ type Parser struct {
 	Merge        string   `toml:"merge"`
 	ParseFields  []string `toml:"parse_fields"`
 	Parser       parsers.Parser
+
+	Log cua.Logger `toml:"-"`
 }
 
 var SampleConfig = `
@@ -47,7 +47,7 @@ func (p *Parser) Apply(metrics ...cua.Metric) []cua.Metric {
 		var err error
 		p.Parser, err = parsers.NewParser(&p.Config)
 		if err != nil {
-			log.Printf("E! [processors.parser] could not create parser: %v", err)
+			p.Log.Errorf("could not create parser: %v", err)
 			return metrics
 		}
 	}
@@ -67,7 +67,7 @@ func (p *Parser) Apply(metrics ...cua.Metric) []cua.Metric {
 					case string:
 						fromFieldMetric, err := p.parseField(value)
 						if err != nil {
-							log.Printf("E! [processors.parser] could not parse field %s: %v", key, err)
+							p.Log.Errorf("could not parse field %s: %v", key, err)
 						}
 
 						for _, m := range fromFieldMetric {
@@ -81,7 +81,7 @@ func (p *Parser) Apply(metrics ...cua.Metric) []cua.Metric {
 						// prior to returning.
 						newMetrics = append(newMetrics, fromFieldMetric...)
 					default:
-						log.Printf("E! [processors.parser] field '%s' not a string, skipping", key)
+						p.Log.Errorf("field '%s' not a string, skipping", key)
 					}
 				}
 			}
@@ -7,6 +7,7 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/metric"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/parsers"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -524,6 +525,7 @@ func TestApply(t *testing.T) {
 				ParseFields:  tt.parseFields,
 				DropOriginal: tt.dropOriginal,
 				Merge:        tt.merge,
+				Log:          testutil.Logger{},
 			}
 
 			output := parser.Apply(tt.input)
@@ -597,6 +599,7 @@ func TestBadApply(t *testing.T) {
 			parser := Parser{
 				Config:      tt.config,
 				ParseFields: tt.parseFields,
+				Log:         testutil.Logger{},
 			}
 
 			output := parser.Apply(tt.input)
@@ -12,12 +12,17 @@ const (
   tag_key = "name"
   ## Field to use as the value of the new field.
   value_key = "value"
+  ## Merge the pivoted metrics of a batch with the same measurement, tags and
+  ## timestamp into a single metric, turning one metric per value into a wide
+  ## metric.
+  # merge = false
 `
 )
 
 type Pivot struct {
 	TagKey   string `toml:"tag_key"`
 	ValueKey string `toml:"value_key"`
+	Merge    bool   `toml:"merge"`
 }
 
 func (p *Pivot) SampleConfig() string {
@@ -29,22 +34,50 @@ func (p *Pivot) Description() string {
 }
 
 func (p *Pivot) Apply(metrics ...cua.Metric) []cua.Metric {
+	// pivoted metrics by series and timestamp, when merging
+	type seriesTime struct {
+		id   uint64
+		time int64
+	}
+	var merged map[seriesTime]cua.Metric
+	if p.Merge {
+		merged = make(map[seriesTime]cua.Metric)
+	}
+
+	out := metrics[:0]
 	for _, m := range metrics {
 		key, ok := m.GetTag(p.TagKey)
 		if !ok {
+			out = append(out, m)
 			continue
 		}
 
 		value, ok := m.GetField(p.ValueKey)
 		if !ok {
+			out = append(out, m)
 			continue
 		}
 
 		m.RemoveTag(p.TagKey)
 		m.RemoveField(p.ValueKey)
 		m.AddField(key, value)
+
+		if !p.Merge {
+			out = append(out, m)
+			continue
+		}
+		st := seriesTime{id: m.HashID(), time: m.Time().UnixNano()}
+		if first, ok := merged[st]; ok {
+			for _, field := range m.FieldList() {
+				first.AddField(field.Key, field.Value)
+			}
+			m.Accept()
+			continue
+		}
+		merged[st] = m
+		out = append(out, m)
 	}
-	return metrics
+	return out
 }
 
 func init() {
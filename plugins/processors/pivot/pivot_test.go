@@ -101,6 +101,53 @@ func TestPivot(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "merge",
+			pivot: &Pivot{
+				TagKey:   "name",
+				ValueKey: "value",
+				Merge:    true,
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu0", "name": "time_idle"},
+					map[string]interface{}{"value": int64(42)},
+					now,
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu1", "name": "time_idle"},
+					map[string]interface{}{"value": int64(44)},
+					now,
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu0", "name": "time_user"},
+					map[string]interface{}{"value": int64(43)},
+					now,
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu0", "name": "time_user"},
+					map[string]interface{}{"value": int64(45)},
+					now.Add(time.Second),
+				),
+			},
+			expected: []cua.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu0"},
+					map[string]interface{}{"time_idle": int64(42), "time_user": int64(43)},
+					now,
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu1"},
+					map[string]interface{}{"time_idle": int64(44)},
+					now,
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu0"},
+					map[string]interface{}{"time_user": int64(45)},
+					now.Add(time.Second),
+				),
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -10,6 +10,8 @@ import (
 )
 
 type Printer struct {
+	Log cua.Logger `toml:"-"`
+
 	serializer serializers.Serializer
 }
 
@@ -24,6 +26,13 @@ func (p *Printer) Description() string {
 	return "Print all metrics that pass through this filter."
 }
 
+func (p *Printer) Init() error {
+	s := influx.NewSerializer()
+	s.Log = p.Log
+	p.serializer = s
+	return nil
+}
+
 func (p *Printer) Apply(in ...cua.Metric) []cua.Metric {
 	for _, metric := range in {
 		octets, err := p.serializer.Serialize(metric)
@@ -37,8 +46,6 @@ func (p *Printer) Apply(in ...cua.Metric) []cua.Metric {
 
 func init() {
 	processors.Add("printer", func() cua.Processor {
-		return &Printer{
-			serializer: influx.NewSerializer(),
-		}
+		return &Printer{}
 	})
 }
@@ -1,6 +1,7 @@
 package regex
 
 import (
+	"fmt"
 	"regexp"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
@@ -8,9 +9,12 @@ import (
 )
 
 type Regex struct {
-	Tags       []converter
-	Fields     []converter
-	regexCache map[string]*regexp.Regexp
+	Tags         []converter `toml:"tags"`
+	Fields       []converter `toml:"fields"`
+	TagRename    []converter `toml:"tag_rename"`
+	FieldRename  []converter `toml:"field_rename"`
+	MetricRename []converter `toml:"metric_rename"`
+	regexCache   map[string]*regexp.Regexp
 }
 
 type converter struct {
@@ -50,6 +54,27 @@ const sampleConfig = `
   #   pattern = ".*category=(\\w+).*"
   #   replacement = "${1}"
   #   result_key = "search_category"
+
+  ## Rename the tags whose key matches the pattern
+  # [[processors.regex.tag_rename]]
+  #   pattern = "^search_(\\w+)d$"
+  #   replacement = "${1}"
+  #   ## If the new key already exists, "overwrite" its value or "keep" it
+  #   ## and leave the tag unchanged
+  #   # result_key = "keep"
+
+  ## Rename the fields whose key matches the pattern
+  # [[processors.regex.field_rename]]
+  #   pattern = "^search_(\\w+)d$"
+  #   replacement = "${1}"
+  #   ## If the new key already exists, "overwrite" its value or "keep" it
+  #   ## and leave the field unchanged
+  #   # result_key = "overwrite"
+
+  ## Rename the measurements matching the pattern
+  # [[processors.regex.metric_rename]]
+  #   pattern = "^(\\w+)_total$"
+  #   replacement = "${1}"
 `
 
 func NewRegex() *Regex {
@@ -66,6 +91,57 @@ func (r *Regex) Description() string {
 	return "Transforms tag and field values with regex pattern"
 }
 
+func (r *Regex) Init() error {
+	for _, c := range append(r.Tags, r.Fields...) {
+		if c.Key == "" {
+			return fmt.Errorf("key missing for pattern %q", c.Pattern)
+		}
+		if err := r.compile(c.Pattern); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range append(r.TagRename, r.FieldRename...) {
+		switch c.ResultKey {
+		case "", "overwrite", "keep":
+		default:
+			return fmt.Errorf("invalid result_key %q for pattern %q, must be \"overwrite\" or \"keep\"", c.ResultKey, c.Pattern)
+		}
+		if err := r.compile(c.Pattern); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range r.MetricRename {
+		if err := r.compile(c.Pattern); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Regex) compile(pattern string) error {
+	if _, ok := r.regexCache[pattern]; ok {
+		return nil
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("compile pattern %q: %w", pattern, err)
+	}
+	r.regexCache[pattern] = regex
+	return nil
+}
+
+func (r *Regex) regex(pattern string) *regexp.Regexp {
+	regex, compiled := r.regexCache[pattern]
+	if !compiled {
+		regex = regexp.MustCompile(pattern)
+		r.regexCache[pattern] = regex
+	}
+	return regex
+}
+
 func (r *Regex) Apply(in ...cua.Metric) []cua.Metric {
 	for _, metric := range in {
 		for _, converter := range r.Tags {
@@ -91,17 +167,73 @@ func (r *Regex) Apply(in ...cua.Metric) []cua.Metric {
 				}
 			}
 		}
+
+		for _, converter := range r.TagRename {
+			regex := r.regex(converter.Pattern)
+			// the tags are modified while renaming
+			tags := make([]cua.Tag, 0, len(metric.TagList()))
+			for _, tag := range metric.TagList() {
+				tags = append(tags, *tag)
+			}
+			for _, tag := range tags {
+				newKey, ok := rename(regex, converter, tag.Key, metric.HasTag)
+				if !ok {
+					continue
+				}
+				metric.RemoveTag(tag.Key)
+				metric.AddTag(newKey, tag.Value)
+			}
+		}
+
+		for _, converter := range r.FieldRename {
+			regex := r.regex(converter.Pattern)
+			// the fields are modified while renaming
+			fields := make([]cua.Field, 0, len(metric.FieldList()))
+			for _, field := range metric.FieldList() {
+				fields = append(fields, *field)
+			}
+			for _, field := range fields {
+				newKey, ok := rename(regex, converter, field.Key, metric.HasField)
+				if !ok {
+					continue
+				}
+				metric.RemoveField(field.Key)
+				metric.AddField(newKey, field.Value)
+			}
+		}
+
+		for _, converter := range r.MetricRename {
+			regex := r.regex(converter.Pattern)
+			if !regex.MatchString(metric.Name()) {
+				continue
+			}
+			if name := regex.ReplaceAllString(metric.Name(), converter.Replacement); name != "" {
+				metric.SetName(name)
+			}
+		}
 	}
 
 	return in
 }
 
-func (r *Regex) convert(c converter, src string) (string, string) {
-	regex, compiled := r.regexCache[c.Pattern]
-	if !compiled {
-		regex = regexp.MustCompile(c.Pattern)
-		r.regexCache[c.Pattern] = regex
+// rename returns the new key of a tag or field, and whether it must be
+// renamed.
+func rename(regex *regexp.Regexp, c converter, key string, exists func(string) bool) (string, bool) {
+	if !regex.MatchString(key) {
+		return "", false
+	}
+	newKey := regex.ReplaceAllString(key, c.Replacement)
+	if newKey == "" || newKey == key {
+		return "", false
+	}
+	if c.ResultKey == "keep" && exists(newKey) {
+		return "", false
 	}
+	return newKey, true
+}
+
+func (r *Regex) convert(c converter, src string) (string, string) {
+	regex := r.regex(c.Pattern)
 
 	value := ""
 	if c.ResultKey == "" || regex.MatchString(src) {
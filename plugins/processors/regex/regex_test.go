@@ -6,7 +6,9 @@ import (
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func newM1() cua.Metric {
@@ -261,6 +263,137 @@ func TestNoMatches(t *testing.T) {
 	}
 }
 
+func TestTagRename(t *testing.T) {
+	tests := []struct {
+		message      string
+		converter    converter
+		expectedTags map[string]string
+	}{
+		{
+			message: "Should rename the matching tags",
+			converter: converter{
+				Pattern:     "^(\\w+)_code$",
+				Replacement: "${1}",
+			},
+			expectedTags: map[string]string{
+				"verb": "GET",
+				"resp": "200",
+			},
+		},
+		{
+			message: "Should overwrite an existing tag",
+			converter: converter{
+				Pattern:     "^resp_code$",
+				Replacement: "verb",
+				ResultKey:   "overwrite",
+			},
+			expectedTags: map[string]string{
+				"verb": "200",
+			},
+		},
+		{
+			message: "Should keep an existing tag",
+			converter: converter{
+				Pattern:     "^resp_code$",
+				Replacement: "verb",
+				ResultKey:   "keep",
+			},
+			expectedTags: map[string]string{
+				"verb":      "GET",
+				"resp_code": "200",
+			},
+		},
+		{
+			message: "Should not rename to an empty key",
+			converter: converter{
+				Pattern:     "^verb$",
+				Replacement: "",
+			},
+			expectedTags: map[string]string{
+				"verb":      "GET",
+				"resp_code": "200",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		regex := NewRegex()
+		regex.TagRename = []converter{test.converter}
+		require.NoError(t, regex.Init())
+
+		processed := regex.Apply(newM1())
+
+		assert.Equal(t, test.expectedTags, processed[0].Tags(), test.message)
+	}
+}
+
+func TestFieldRename(t *testing.T) {
+	regex := NewRegex()
+	regex.FieldRename = []converter{
+		{
+			Pattern:     "^ignore_(\\w+)$",
+			Replacement: "${1}_value",
+		},
+		{
+			Pattern:     "^request$",
+			Replacement: "number_value",
+			ResultKey:   "keep",
+		},
+	}
+	require.NoError(t, regex.Init())
+
+	processed := regex.Apply(newM2())
+
+	assert.Equal(t, map[string]interface{}{
+		"request":      "/api/search/?category=plugins&q=regex&sort=asc",
+		"number_value": int64(200),
+		"bool_value":   true,
+	}, processed[0].Fields())
+}
+
+func TestMetricRename(t *testing.T) {
+	regex := NewRegex()
+	regex.MetricRename = []converter{
+		{
+			Pattern:     "^access_(\\w+)$",
+			Replacement: "http_${1}",
+		},
+		{
+			Pattern:     "^unmatched$",
+			Replacement: "renamed",
+		},
+	}
+	require.NoError(t, regex.Init())
+
+	processed := regex.Apply(newM1())
+
+	expected := testutil.MustMetric("http_log",
+		map[string]string{
+			"verb":      "GET",
+			"resp_code": "200",
+		},
+		map[string]interface{}{
+			"request": "/users/42/",
+		},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, processed[0], testutil.IgnoreTime())
+}
+
+func TestInitErrors(t *testing.T) {
+	regex := NewRegex()
+	regex.Tags = []converter{{Pattern: "^a$"}}
+	require.Error(t, regex.Init())
+
+	regex = NewRegex()
+	regex.MetricRename = []converter{{Pattern: "(unclosed"}}
+	require.Error(t, regex.Init())
+
+	regex = NewRegex()
+	regex.FieldRename = []converter{{Pattern: "^a$", ResultKey: "replace"}}
+	require.Error(t, regex.Init())
+}
+
 func BenchmarkConversions(b *testing.B) {
 	regex := NewRegex()
 	regex.Tags = []converter{
@@ -1,18 +1,45 @@
 package rename
 
 import (
+	"fmt"
+
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
 )
 
 const sampleConfig = `
+  ## Specify one sub-table per rename operation, the operations are applied
+  ## in order, each to the result of the previous ones.
+  [[processors.rename.replace]]
+    measurement = "network_interface_throughput"
+    dest = "throughput"
+
+  [[processors.rename.replace]]
+    tag = "hostname"
+    dest = "host"
+
+  ## Limit the operation to the measurements matching the glob patterns,
+  ## after the renames of the previous operations.
+  [[processors.rename.replace]]
+    field = "lower"
+    dest = "min"
+    metrics = ["throughput"]
+
+  [[processors.rename.replace]]
+    field = "upper"
+    dest = "max"
+    metrics = ["throughput"]
 `
 
 type Replace struct {
-	Measurement string `toml:"measurement"`
-	Tag         string `toml:"tag"`
-	Field       string `toml:"field"`
-	Dest        string `toml:"dest"`
+	Measurement string   `toml:"measurement"`
+	Tag         string   `toml:"tag"`
+	Field       string   `toml:"field"`
+	Dest        string   `toml:"dest"`
+	Metrics     []string `toml:"metrics"`
+
+	metricFilter filter.Filter
 }
 
 type Rename struct {
@@ -27,12 +54,33 @@ func (r *Rename) Description() string {
 	return "Rename measurements, tags, and fields that pass through this filter."
 }
 
+func (r *Rename) Init() error {
+	for i := range r.Replaces {
+		replace := &r.Replaces[i]
+		if replace.Measurement == "" && replace.Tag == "" && replace.Field == "" {
+			return fmt.Errorf("replace %d: one of measurement, tag or field is required", i+1)
+		}
+		if replace.Dest == "" {
+			return fmt.Errorf("replace %d: dest is required", i+1)
+		}
+		f, err := filter.Compile(replace.Metrics)
+		if err != nil {
+			return fmt.Errorf("replace %d: metrics: %w", i+1, err)
+		}
+		replace.metricFilter = f
+	}
+	return nil
+}
+
 func (r *Rename) Apply(in ...cua.Metric) []cua.Metric {
 	for _, point := range in {
 		for _, replace := range r.Replaces {
 			if replace.Dest == "" {
 				continue
 			}
+			if replace.metricFilter != nil && !replace.metricFilter.Match(point.Name()) {
+				continue
+			}
 
 			if replace.Measurement != "" {
 				if value := point.Name(); value == replace.Measurement {
@@ -7,6 +7,7 @@ import (
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/metric"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func newMetric(name string, tags map[string]string, fields map[string]interface{}) cua.Metric {
@@ -59,3 +60,33 @@ func TestFieldRename(t *testing.T) {
 
 	assert.Equal(t, map[string]interface{}{"time": int64(1250), "snakes": true}, results[0].Fields(), "should change field 'time_msec' to 'time'")
 }
+
+func TestRenameMetricsFilter(t *testing.T) {
+	r := Rename{
+		Replaces: []Replace{
+			{Measurement: "win_cpu", Dest: "cpu"},
+			// matched against the name renamed by the previous operation
+			{Field: "Percent_Idle_Time", Dest: "usage_idle", Metrics: []string{"cpu"}},
+			{Tag: "instance", Dest: "cpu", Metrics: []string{"c*"}},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	m1 := newMetric("win_cpu", map[string]string{"instance": "0"}, map[string]interface{}{"Percent_Idle_Time": 95.0})
+	m2 := newMetric("win_disk", map[string]string{"instance": "C:"}, map[string]interface{}{"Percent_Idle_Time": 80.0})
+	results := r.Apply(m1, m2)
+
+	assert.Equal(t, "cpu", results[0].Name())
+	assert.Equal(t, map[string]string{"cpu": "0"}, results[0].Tags())
+	assert.Equal(t, map[string]interface{}{"usage_idle": 95.0}, results[0].Fields())
+
+	assert.Equal(t, "win_disk", results[1].Name())
+	assert.Equal(t, map[string]string{"instance": "C:"}, results[1].Tags())
+	assert.Equal(t, map[string]interface{}{"Percent_Idle_Time": 80.0}, results[1].Fields())
+}
+
+func TestRenameInit(t *testing.T) {
+	require.Error(t, (&Rename{Replaces: []Replace{{Tag: "hostname"}}}).Init())
+	require.Error(t, (&Rename{Replaces: []Replace{{Dest: "host"}}}).Init())
+	require.NoError(t, (&Rename{Replaces: []Replace{{Tag: "hostname", Dest: "host"}}}).Init())
+}
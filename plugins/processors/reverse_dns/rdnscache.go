@@ -27,7 +27,13 @@ type AnyResolver interface {
 // requests will trigger the lookup and the rest will wait for its response.
 type RDNSCache struct {
 	Resolver AnyResolver
-	stats    RDNSCacheStats
+	// MaxEntries bounds the number of cached answers, the answers closest to
+	// expiring are evicted first.  Zero means unbounded.
+	MaxEntries int
+	// NegativeTTL is how long the failed lookups are cached, they are
+	// retried on each request when zero.  Timeouts are never cached.
+	NegativeTTL time.Duration
+	stats       RDNSCacheStats
 
 	// settings
 	ttl           time.Duration
@@ -50,12 +56,15 @@ type RDNSCache struct {
 	// must lock to get access to this.
 	expireList     []*dnslookup
 	expireListLock sync.Mutex
+	// failed lookups, kept apart as their ttl differs
+	negativeExpireList []*dnslookup
 }
 
 type RDNSCacheStats struct {
 	CacheHit          uint64
 	CacheMiss         uint64
 	CacheExpire       uint64
+	CacheEvict        uint64
 	RequestsAbandoned uint64
 	RequestsFilled    uint64
 }
@@ -166,6 +175,7 @@ func (d *RDNSCache) subscribeTo(ip string) callbackChannelType {
 		callbacks: []callbackChannelType{callback},
 	}
 
+	d.lockedEvict()
 	d.lockedSaveToCache(l)
 	go d.doLookup(l.ip)
 	return callback
@@ -191,6 +201,30 @@ func (d *RDNSCache) lockedSaveToCache(lookup *dnslookup) {
 	d.cache[lookup.ip] = lookup
 }
 
+// lockedEvict evicts the answers closest to expiring to make room for a new
+// lookup when the cache is full, the failed lookups first.
+// you MUST first do a write lock before calling it.
+func (d *RDNSCache) lockedEvict() {
+	if d.MaxEntries <= 0 || len(d.cache) < d.MaxEntries {
+		return
+	}
+
+	d.expireListLock.Lock()
+	defer d.expireListLock.Unlock()
+	for _, list := range []*[]*dnslookup{&d.negativeExpireList, &d.expireList} {
+		for len(*list) > 0 && len(d.cache) >= d.MaxEntries {
+			lookup := (*list)[0]
+			*list = (*list)[1:]
+			// the ip may have been looked up again since
+			if d.cache[lookup.ip] == lookup {
+				delete(d.cache, lookup.ip)
+				atomic.AddUint64(&d.stats.CacheEvict, 1)
+			}
+		}
+	}
+	// the remaining entries are pending lookups, bounded by the workers
+}
+
 func (d *RDNSCache) startCleanupWorker(ctx context.Context) {
 	go func() {
 		cleanupTick := time.NewTicker(10 * time.Second)
@@ -258,8 +292,21 @@ func (d *RDNSCache) abandonLookup(ip string, err error) {
 	}
 
 	callbacks := lookup.callbacks
-	delete(d.cache, lookup.ip)
-	d.rwLock.Unlock()
+	lookup.callbacks = nil
+	if d.NegativeTTL > 0 && !isTimeout(err) {
+		// remember the failure to not query the resolver for each metric
+		lookup.completed = true
+		lookup.expiresAt = time.Now().Add(d.NegativeTTL)
+		d.lockedSaveToCache(lookup)
+		d.rwLock.Unlock()
+
+		d.expireListLock.Lock()
+		d.negativeExpireList = append(d.negativeExpireList, lookup)
+		d.expireListLock.Unlock()
+	} else {
+		delete(d.cache, lookup.ip)
+		d.rwLock.Unlock()
+	}
 	// resolve the remaining callbacks to free the resources.
 	atomic.AddUint64(&d.stats.RequestsAbandoned, uint64(len(callbacks)))
 	for _, cb := range callbacks {
@@ -271,31 +318,45 @@ func (d *RDNSCache) abandonLookup(ip string, err error) {
 func (d *RDNSCache) cleanup() {
 	now := time.Now()
 	d.expireListLock.Lock()
-	if len(d.expireList) == 0 {
-		d.expireListLock.Unlock()
-		return
-	}
-	ipsToDelete := []string{}
-	for i := 0; i < len(d.expireList); i++ {
-		if !d.expireList[i].expiresAt.Before(now) {
-			break // done. Nothing after this point is expired.
-		}
-		ipsToDelete = append(ipsToDelete, d.expireList[i].ip)
-	}
-	if len(ipsToDelete) == 0 {
-		d.expireListLock.Unlock()
+	expired := expiredLookups(&d.expireList, now)
+	expired = append(expired, expiredLookups(&d.negativeExpireList, now)...)
+	d.expireListLock.Unlock()
+	if len(expired) == 0 {
 		return
 	}
-	d.expireList = d.expireList[len(ipsToDelete):]
-	d.expireListLock.Unlock()
 
-	atomic.AddUint64(&d.stats.CacheExpire, uint64(len(ipsToDelete)))
+	atomic.AddUint64(&d.stats.CacheExpire, uint64(len(expired)))
 
 	d.rwLock.Lock()
 	defer d.rwLock.Unlock()
-	for _, ip := range ipsToDelete {
-		delete(d.cache, ip)
+	for _, lookup := range expired {
+		// the ip may have been looked up again since
+		if d.cache[lookup.ip] == lookup {
+			delete(d.cache, lookup.ip)
+		}
+	}
+}
+
+// expiredLookups pops the expired lookups of a list ordered by expiration.
+// you MUST first lock the expire lists before calling it.
+func expiredLookups(list *[]*dnslookup, now time.Time) []*dnslookup {
+	i := 0
+	for ; i < len(*list); i++ {
+		if !(*list)[i].expiresAt.Before(now) {
+			break // done. Nothing after this point is expired.
+		}
+	}
+	expired := (*list)[:i]
+	*list = (*list)[i:]
+	return expired
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
 	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsTimeout
 }
 
 // blockAllWorkers is a test function that eats up all the worker pool space to
@@ -309,6 +370,7 @@ func (d *RDNSCache) Stats() RDNSCacheStats {
 	stats.CacheHit = atomic.LoadUint64(&d.stats.CacheHit)
 	stats.CacheMiss = atomic.LoadUint64(&d.stats.CacheMiss)
 	stats.CacheExpire = atomic.LoadUint64(&d.stats.CacheExpire)
+	stats.CacheEvict = atomic.LoadUint64(&d.stats.CacheEvict)
 	stats.RequestsAbandoned = atomic.LoadUint64(&d.stats.RequestsAbandoned)
 	stats.RequestsFilled = atomic.LoadUint64(&d.stats.RequestsFilled)
 	return stats
@@ -3,7 +3,9 @@ package reversedns
 import (
 	"context"
 	"errors"
+	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -123,6 +125,95 @@ func TestLookupTimeout(t *testing.T) {
 	require.EqualValues(t, 1, d.Stats().RequestsAbandoned)
 }
 
+func TestCacheEviction(t *testing.T) {
+	d := NewReverseDNSCache(time.Minute, time.Second, -1)
+	defer d.Stop()
+	d.Resolver = &localResolver{}
+	d.MaxEntries = 2
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		_, err := d.Lookup(ip)
+		require.NoError(t, err)
+	}
+	require.Len(t, d.cache, 2)
+	require.NotContains(t, d.cache, "10.0.0.1")
+	require.EqualValues(t, 1, d.Stats().CacheEvict)
+
+	// the oldest answer is evicted, the evicted ip is looked up again
+	_, err := d.Lookup("10.0.0.1")
+	require.NoError(t, err)
+	require.Len(t, d.cache, 2)
+	require.NotContains(t, d.cache, "10.0.0.2")
+	require.EqualValues(t, 4, d.Stats().CacheMiss)
+}
+
+func TestNegativeCache(t *testing.T) {
+	d := NewReverseDNSCache(time.Minute, time.Second, -1)
+	defer d.Stop()
+	resolver := &notFoundResolver{}
+	d.Resolver = resolver
+	d.NegativeTTL = 100 * time.Millisecond
+
+	// the failure is returned once, then the lookup is answered from the cache
+	_, err := d.Lookup("10.0.0.1")
+	require.Error(t, err)
+	names, err := d.Lookup("10.0.0.1")
+	require.NoError(t, err)
+	require.Empty(t, names)
+	require.EqualValues(t, 1, resolver.calls)
+	require.Len(t, d.negativeExpireList, 1)
+
+	time.Sleep(100 * time.Millisecond)
+	d.cleanup()
+	require.Len(t, d.cache, 0)
+	require.Len(t, d.negativeExpireList, 0)
+	_, err = d.Lookup("10.0.0.1")
+	require.Error(t, err)
+	require.EqualValues(t, 2, resolver.calls)
+}
+
+func TestNegativeCacheSkipsTimeouts(t *testing.T) {
+	d := NewReverseDNSCache(time.Minute, time.Second, -1)
+	defer d.Stop()
+	d.Resolver = &dnsTimeoutResolver{}
+	d.NegativeTTL = time.Minute
+
+	_, err := d.Lookup("10.0.0.1")
+	require.Error(t, err)
+	require.Len(t, d.cache, 0)
+}
+
+func TestCleanupKeepsNewerLookup(t *testing.T) {
+	ttl := 50 * time.Millisecond
+	d := NewReverseDNSCache(ttl, time.Second, -1)
+	defer d.Stop()
+	d.Resolver = &localResolver{}
+
+	_, err := d.Lookup("127.0.0.1")
+	require.NoError(t, err)
+	time.Sleep(ttl)
+	// the expired entry is replaced before the cleanup runs
+	_, err = d.Lookup("127.0.0.1")
+	require.NoError(t, err)
+	d.cleanup()
+	require.Contains(t, d.cache, "127.0.0.1")
+}
+
+type notFoundResolver struct {
+	calls int32
+}
+
+func (r *notFoundResolver) LookupAddr(ctx context.Context, addr string) (names []string, err error) {
+	atomic.AddInt32(&r.calls, 1)
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+type dnsTimeoutResolver struct{}
+
+func (r *dnsTimeoutResolver) LookupAddr(ctx context.Context, addr string) (names []string, err error) {
+	return nil, &net.DNSError{Err: "i/o timeout", Name: addr, IsTimeout: true}
+}
+
 type timeoutResolver struct{}
 
 func (r *timeoutResolver) LookupAddr(ctx context.Context, addr string) (names []string, err error) {
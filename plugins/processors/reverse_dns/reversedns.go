@@ -19,6 +19,15 @@ const sampleConfig = `
   ## you'll want to consider memory use.
   cache_ttl = "24h"
 
+  ## cache_size is the maximum number of dns entries cached, the entries closest
+  ## to expiring are evicted first when the cache is full. 0 is unlimited.
+  cache_size = 100000
+
+  ## negative_cache_ttl is how long failed lookups (e.g. IPs without PTR record)
+  ## stay cached, to not query the dns server again for every metric. Timed out
+  ## lookups are never cached. 0 disables caching failed lookups.
+  negative_cache_ttl = "5m"
+
   ## lookup_timeout is how long should you wait for a single dns request to respond.
   ## this is also the maximum acceptable latency for a metric travelling through
   ## the reverse_dns processor. After lookup_timeout is exceeded, a metric will
@@ -69,6 +78,8 @@ type ReverseDNS struct {
 
 	Lookups            []lookupEntry   `toml:"lookup"`
 	CacheTTL           config.Duration `toml:"cache_ttl"`
+	CacheSize          int             `toml:"cache_size"`
+	NegativeCacheTTL   config.Duration `toml:"negative_cache_ttl"`
 	LookupTimeout      config.Duration `toml:"lookup_timeout"`
 	MaxParallelLookups int             `toml:"max_parallel_lookups"`
 	Ordered            bool            `toml:"ordered"`
@@ -90,6 +101,8 @@ func (r *ReverseDNS) Start(acc cua.Accumulator) error {
 		time.Duration(r.LookupTimeout),
 		r.MaxParallelLookups, // max parallel reverse-dns lookups
 	)
+	r.reverseDNSCache.MaxEntries = r.CacheSize
+	r.reverseDNSCache.NegativeTTL = time.Duration(r.NegativeCacheTTL)
 	if r.Ordered {
 		r.parallel = parallel.NewOrdered(acc, r.asyncAdd, 10000, r.MaxParallelLookups)
 	} else {
@@ -150,7 +163,9 @@ func init() {
 func newReverseDNS() *ReverseDNS {
 	return &ReverseDNS{
 		CacheTTL:           config.Duration(24 * time.Hour),
-		LookupTimeout:      config.Duration(1 * time.Minute),
+		CacheSize:          100000,
+		NegativeCacheTTL:   config.Duration(5 * time.Minute),
+		LookupTimeout:      config.Duration(3 * time.Second),
 		MaxParallelLookups: 10,
 	}
 }
@@ -0,0 +1,399 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/filter"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+)
+
+const sampleConfig = `
+  ## Action on the values which cannot be coerced to the declared type,
+  ## "drop_field" or "drop_metric".
+  # on_mismatch = "drop_field"
+
+  ## JSON files declaring the types, with the layout of the measurements
+  ## table: {"<measurement>": {"<field>": "<type>"}}.  The types declared in
+  ## the configuration take precedence.
+  # files = ["/etc/circonus-unified-agent/schema.json"]
+
+  ## Declared type of the fields per measurement, "integer", "unsigned",
+  ## "float", "boolean" or "string".  The measurements and fields may be
+  ## globs, the fields not declared are passed through.
+  # [processors.schema.measurements.http_api]
+  #   latency = "float"
+  #   status = "integer"
+  #   "*_name" = "string"
+`
+
+const (
+	actionDropField  = "drop_field"
+	actionDropMetric = "drop_metric"
+)
+
+var fieldTypes = map[string]bool{
+	"integer":  true,
+	"unsigned": true,
+	"float":    true,
+	"boolean":  true,
+	"string":   true,
+}
+
+type Schema struct {
+	OnMismatch   string                       `toml:"on_mismatch"`
+	Files        []string                     `toml:"files"`
+	Measurements map[string]map[string]string `toml:"measurements"`
+	Log          cua.Logger                   `toml:"-"`
+
+	rules []measurementRule
+	stats map[string]*measurementStats
+}
+
+// measurementRule holds the declared types of the fields of the measurements
+// matching its filter.
+type measurementRule struct {
+	name  filter.Filter
+	exact map[string]string
+	globs []fieldRule
+}
+
+type fieldRule struct {
+	name filter.Filter
+	typ  string
+}
+
+type measurementStats struct {
+	violations     selfstat.Stat
+	coerced        selfstat.Stat
+	droppedFields  selfstat.Stat
+	droppedMetrics selfstat.Stat
+}
+
+func (s *Schema) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Schema) Description() string {
+	return "Coerce fields to the types declared per measurement and drop the mismatched values"
+}
+
+func (s *Schema) Init() error {
+	switch s.OnMismatch {
+	case "":
+		s.OnMismatch = actionDropField
+	case actionDropField, actionDropMetric:
+	default:
+		return fmt.Errorf("unknown on_mismatch %q, expected %s or %s", s.OnMismatch, actionDropField, actionDropMetric)
+	}
+
+	declared := map[string]map[string]string{}
+	for _, path := range s.Files {
+		types, err := loadFile(path)
+		if err != nil {
+			return err
+		}
+		merge(declared, types)
+	}
+	merge(declared, s.Measurements)
+
+	// exact measurement names first, then the globs in a stable order
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		gi, gj := hasMeta(names[i]), hasMeta(names[j])
+		if gi != gj {
+			return gj
+		}
+		return names[i] < names[j]
+	})
+	s.rules = make([]measurementRule, 0, len(names))
+	for _, name := range names {
+		rule, err := compileRule(name, declared[name])
+		if err != nil {
+			return err
+		}
+		s.rules = append(s.rules, rule)
+	}
+	return nil
+}
+
+func loadFile(path string) (map[string]map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %w", err)
+	}
+	var types map[string]map[string]string
+	if err := json.Unmarshal(b, &types); err != nil {
+		return nil, fmt.Errorf("parsing schema %s: %w", path, err)
+	}
+	return types, nil
+}
+
+func merge(dst, src map[string]map[string]string) {
+	for measurement, fields := range src {
+		if dst[measurement] == nil {
+			dst[measurement] = map[string]string{}
+		}
+		for field, typ := range fields {
+			dst[measurement][field] = typ
+		}
+	}
+}
+
+func compileRule(measurement string, fields map[string]string) (measurementRule, error) {
+	rule := measurementRule{exact: map[string]string{}}
+	var err error
+	if rule.name, err = filter.Compile([]string{measurement}); err != nil {
+		return rule, fmt.Errorf("measurement %q: %w", measurement, err)
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		typ := fields[key]
+		if !fieldTypes[typ] {
+			return rule, fmt.Errorf("measurement %q: field %q: unknown type %q", measurement, key, typ)
+		}
+		if !hasMeta(key) {
+			rule.exact[key] = typ
+			continue
+		}
+		f, err := filter.Compile([]string{key})
+		if err != nil {
+			return rule, fmt.Errorf("measurement %q: field %q: %w", measurement, key, err)
+		}
+		rule.globs = append(rule.globs, fieldRule{name: f, typ: typ})
+	}
+	return rule, nil
+}
+
+// fieldType returns the declared type of a field, the exact names take
+// precedence over the globs.
+func (r *measurementRule) fieldType(field string) (string, bool) {
+	if typ, ok := r.exact[field]; ok {
+		return typ, true
+	}
+	for _, g := range r.globs {
+		if g.name.Match(field) {
+			return g.typ, true
+		}
+	}
+	return "", false
+}
+
+func (s *Schema) Apply(in ...cua.Metric) []cua.Metric {
+	out := in[:0]
+	for _, m := range in {
+		if s.apply(m) {
+			out = append(out, m)
+			continue
+		}
+		m.Drop()
+	}
+	return out
+}
+
+// apply coerces the fields of a metric, it returns false when the metric
+// is to be dropped.
+func (s *Schema) apply(m cua.Metric) bool {
+	var rule *measurementRule
+	for i := range s.rules {
+		if s.rules[i].name.Match(m.Name()) {
+			rule = &s.rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		return true
+	}
+
+	coerced := map[string]interface{}{}
+	var mismatched []string
+	for _, field := range m.FieldList() {
+		typ, ok := rule.fieldType(field.Key)
+		if !ok || isType(field.Value, typ) {
+			continue
+		}
+		stats := s.statsFor(m.Name())
+		stats.violations.Incr(1)
+		if v, ok := coerce(field.Value, typ); ok {
+			coerced[field.Key] = v
+			stats.coerced.Incr(1)
+			continue
+		}
+		mismatched = append(mismatched, field.Key)
+	}
+	for key, v := range coerced {
+		m.RemoveField(key)
+		m.AddField(key, v)
+	}
+	if len(mismatched) == 0 {
+		return true
+	}
+
+	stats := s.statsFor(m.Name())
+	if s.OnMismatch == actionDropMetric {
+		s.Log.Debugf("Dropping metric %q, fields %v do not match the schema", m.Name(), mismatched)
+		stats.droppedMetrics.Incr(1)
+		return false
+	}
+	for _, key := range mismatched {
+		m.RemoveField(key)
+	}
+	stats.droppedFields.Incr(int64(len(mismatched)))
+	// a metric without fields is not valid
+	if len(m.FieldList()) == 0 {
+		stats.droppedMetrics.Incr(1)
+		return false
+	}
+	return true
+}
+
+func (s *Schema) statsFor(measurement string) *measurementStats {
+	if stats, ok := s.stats[measurement]; ok {
+		return stats
+	}
+	tags := map[string]string{"measurement": measurement}
+	stats := &measurementStats{
+		violations:     selfstat.Register("schema", "violations", tags),
+		coerced:        selfstat.Register("schema", "coerced", tags),
+		droppedFields:  selfstat.Register("schema", "dropped_fields", tags),
+		droppedMetrics: selfstat.Register("schema", "dropped_metrics", tags),
+	}
+	s.stats[measurement] = stats
+	return stats
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+func isType(v interface{}, typ string) bool {
+	switch v.(type) {
+	case int64:
+		return typ == "integer"
+	case uint64:
+		return typ == "unsigned"
+	case float64:
+		return typ == "float"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	}
+	return false
+}
+
+// coerce converts a value to a type, the conversions losing the value, like
+// a fractional float to an integer or an out of range number, fail.
+func coerce(v interface{}, typ string) (interface{}, bool) {
+	switch typ {
+	case "integer":
+		switch value := v.(type) {
+		case uint64:
+			if value <= math.MaxInt64 {
+				return int64(value), true
+			}
+		case float64:
+			if value == math.Trunc(value) && value >= math.MinInt64 && value < math.MaxInt64 {
+				return int64(value), true
+			}
+		case bool:
+			if value {
+				return int64(1), true
+			}
+			return int64(0), true
+		case string:
+			if result, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return result, true
+			}
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return coerce(f, typ)
+			}
+		}
+	case "unsigned":
+		switch value := v.(type) {
+		case int64:
+			if value >= 0 {
+				return uint64(value), true
+			}
+		case float64:
+			if value == math.Trunc(value) && value >= 0 && value < math.MaxUint64 {
+				return uint64(value), true
+			}
+		case bool:
+			if value {
+				return uint64(1), true
+			}
+			return uint64(0), true
+		case string:
+			if result, err := strconv.ParseUint(value, 10, 64); err == nil {
+				return result, true
+			}
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return coerce(f, typ)
+			}
+		}
+	case "float":
+		switch value := v.(type) {
+		case int64:
+			return float64(value), true
+		case uint64:
+			return float64(value), true
+		case bool:
+			if value {
+				return 1.0, true
+			}
+			return 0.0, true
+		case string:
+			if result, err := strconv.ParseFloat(value, 64); err == nil {
+				return result, true
+			}
+		}
+	case "boolean":
+		switch value := v.(type) {
+		case int64:
+			return value != 0, true
+		case uint64:
+			return value != 0, true
+		case float64:
+			return value != 0, true
+		case string:
+			if result, err := strconv.ParseBool(value); err == nil {
+				return result, true
+			}
+		}
+	case "string":
+		switch value := v.(type) {
+		case int64:
+			return strconv.FormatInt(value, 10), true
+		case uint64:
+			return strconv.FormatUint(value, 10), true
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(value), true
+		}
+	}
+	return nil, false
+}
+
+func init() {
+	processors.Add("schema", func() cua.Processor {
+		return &Schema{
+			stats: map[string]*measurementStats{},
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/selfstat"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSchema(measurements map[string]map[string]string) *Schema {
+	return &Schema{
+		Measurements: measurements,
+		Log:          testutil.Logger{},
+		stats:        map[string]*measurementStats{},
+	}
+}
+
+func statValue(t *testing.T, measurement, field string) interface{} {
+	for _, m := range selfstat.Metrics() {
+		if m.Name() == "internal_schema" && m.Tags()["measurement"] == measurement {
+			v, _ := m.GetField(field)
+			return v
+		}
+	}
+	t.Fatalf("no schema stats for %s", measurement)
+	return nil
+}
+
+func TestCoerce(t *testing.T) {
+	s := newSchema(map[string]map[string]string{
+		"api": {
+			"latency": "float",
+			"status":  "integer",
+			"bytes":   "unsigned",
+			"up":      "boolean",
+			"*_name":  "string",
+			"code":    "string",
+		},
+	})
+	require.NoError(t, s.Init())
+
+	now := time.Now()
+	out := s.Apply(
+		testutil.MustMetric("api", map[string]string{}, map[string]interface{}{
+			"latency":   int64(12),
+			"status":    "200",
+			"bytes":     float64(1024),
+			"up":        "true",
+			"host_name": int64(3),
+			"code":      "E42",
+			"other":     "passed through",
+		}, now),
+		testutil.MustMetric("other", map[string]string{}, map[string]interface{}{
+			"latency": int64(12),
+		}, now),
+	)
+	testutil.RequireMetricsEqual(t, []cua.Metric{
+		testutil.MustMetric("api", map[string]string{}, map[string]interface{}{
+			"latency":   float64(12),
+			"status":    int64(200),
+			"bytes":     uint64(1024),
+			"up":        true,
+			"host_name": "3",
+			"code":      "E42",
+			"other":     "passed through",
+		}, now),
+		testutil.MustMetric("other", map[string]string{}, map[string]interface{}{
+			"latency": int64(12),
+		}, now),
+	}, out, testutil.SortMetrics())
+	require.Equal(t, int64(5), statValue(t, "api", "coerced"))
+}
+
+func TestMismatch(t *testing.T) {
+	now := time.Now()
+	input := func() []cua.Metric {
+		return []cua.Metric{
+			testutil.MustMetric("sensor", map[string]string{}, map[string]interface{}{
+				"temp":  "n/a",
+				"count": float64(1.5),
+				"label": "kitchen",
+			}, now),
+			testutil.MustMetric("sensor", map[string]string{}, map[string]interface{}{
+				"temp": "n/a",
+			}, now),
+		}
+	}
+	types := map[string]map[string]string{"sens*": {"temp": "float", "count": "integer"}}
+
+	s := newSchema(types)
+	require.NoError(t, s.Init())
+	testutil.RequireMetricsEqual(t, []cua.Metric{
+		testutil.MustMetric("sensor", map[string]string{}, map[string]interface{}{
+			"label": "kitchen",
+		}, now),
+	}, s.Apply(input()...))
+	require.Equal(t, int64(3), statValue(t, "sensor", "dropped_fields"))
+	require.Equal(t, int64(1), statValue(t, "sensor", "dropped_metrics"))
+
+	s = newSchema(types)
+	s.OnMismatch = "drop_metric"
+	require.NoError(t, s.Init())
+	require.Empty(t, s.Apply(input()...))
+	require.Equal(t, int64(3), statValue(t, "sensor", "dropped_metrics"))
+}
+
+func TestSchemaFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"api": {"latency": "integer", "status": "integer"}}`), 0600))
+
+	s := newSchema(map[string]map[string]string{"api": {"latency": "float"}})
+	s.Files = []string{path}
+	require.NoError(t, s.Init())
+	typ, _ := s.rules[0].fieldType("latency")
+	require.Equal(t, "float", typ)
+	typ, _ = s.rules[0].fieldType("status")
+	require.Equal(t, "integer", typ)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	s := newSchema(map[string]map[string]string{"api": {"latency": "double"}})
+	require.Error(t, s.Init())
+
+	s = newSchema(nil)
+	s.OnMismatch = "ignore"
+	require.Error(t, s.Init())
+
+	s = newSchema(nil)
+	s.Files = []string{filepath.Join(t.TempDir(), "missing.json")}
+	require.Error(t, s.Init())
+}
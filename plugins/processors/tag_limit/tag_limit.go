@@ -2,7 +2,6 @@ package taglimit
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
@@ -17,8 +16,9 @@ const sampleConfig = `
 `
 
 type TagLimit struct {
-	Limit    int      `toml:"limit"`
-	Keep     []string `toml:"keep"`
+	Limit    int        `toml:"limit"`
+	Keep     []string   `toml:"keep"`
+	Log      cua.Logger `toml:"-"`
 	init     bool
 	keepTags map[string]string
 }
@@ -50,7 +50,7 @@ func (d *TagLimit) initOnce() error {
 func (d *TagLimit) Apply(in ...cua.Metric) []cua.Metric {
 	err := d.initOnce()
 	if err != nil {
-		log.Printf("E! [processors.tag_limit] could not create tag_limit processor: %v", err)
+		d.Log.Errorf("could not create tag_limit processor: %v", err)
 		return in
 	}
 	for _, point := range in {
@@ -0,0 +1,93 @@
+package timestamp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Replace the timestamps more than max_skew away from the clock of the
+  ## agent by the time the metric is processed, before the other changes.
+  # max_skew = "0s"
+
+  ## Duration added to the timestamps, use a negative duration to shift
+  ## them back.
+  # offset = "0s"
+
+  ## Truncate the timestamps to a multiple of the resolution, since the
+  ## zero time. Only one of truncate and round can be set.
+  # truncate = "1s"
+
+  ## Round the timestamps to the nearest multiple of the resolution, halfway
+  ## values are rounded up.
+  # round = "0s"
+`
+
+// Timestamp aligns the timestamps of the metrics of sources with skewed
+// clocks.
+type Timestamp struct {
+	MaxSkew  internal.Duration `toml:"max_skew"`
+	Offset   internal.Duration `toml:"offset"`
+	Truncate internal.Duration `toml:"truncate"`
+	Round    internal.Duration `toml:"round"`
+
+	Log cua.Logger `toml:"-"`
+
+	now func() time.Time
+}
+
+func (t *Timestamp) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Timestamp) Description() string {
+	return "Shift, truncate or round the timestamps of metrics"
+}
+
+func (t *Timestamp) Init() error {
+	switch {
+	case t.Truncate.Duration < 0 || t.Round.Duration < 0 || t.MaxSkew.Duration < 0:
+		return errors.New("max_skew, truncate and round cannot be negative")
+	case t.Truncate.Duration > 0 && t.Round.Duration > 0:
+		return errors.New("only one of truncate or round can be set")
+	}
+	if t.now == nil {
+		t.now = time.Now
+	}
+	return nil
+}
+
+func (t *Timestamp) Apply(in ...cua.Metric) []cua.Metric {
+	var now time.Time
+	if t.MaxSkew.Duration > 0 {
+		now = t.now()
+	}
+	for _, m := range in {
+		tm := m.Time()
+		if t.MaxSkew.Duration > 0 {
+			if skew := tm.Sub(now); skew > t.MaxSkew.Duration || skew < -t.MaxSkew.Duration {
+				t.Log.Debugf("Replacing the timestamp of %q skewed by %s", m.Name(), skew)
+				tm = now
+			}
+		}
+		tm = tm.Add(t.Offset.Duration)
+		switch {
+		case t.Truncate.Duration > 0:
+			tm = tm.Truncate(t.Truncate.Duration)
+		case t.Round.Duration > 0:
+			tm = tm.Round(t.Round.Duration)
+		}
+		m.SetTime(tm)
+	}
+	return in
+}
+
+func init() {
+	processors.Add("timestamp", func() cua.Processor {
+		return &Timestamp{}
+	})
+}
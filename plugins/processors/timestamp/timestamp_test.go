@@ -0,0 +1,97 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/internal"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTimestamp(t *testing.T, ts *Timestamp) *Timestamp {
+	ts.Log = testutil.Logger{}
+	require.NoError(t, ts.Init())
+	return ts
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&Timestamp{
+		Truncate: internal.Duration{Duration: time.Second},
+		Round:    internal.Duration{Duration: time.Second},
+	}).Init())
+	require.Error(t, (&Timestamp{Truncate: internal.Duration{Duration: -time.Second}}).Init())
+	require.NoError(t, (&Timestamp{Offset: internal.Duration{Duration: -time.Second}}).Init())
+}
+
+func TestApply(t *testing.T) {
+	base := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		timestamp Timestamp
+		time      time.Time
+		expected  time.Time
+	}{
+		{
+			name:     "unchanged",
+			time:     base.Add(1500 * time.Millisecond),
+			expected: base.Add(1500 * time.Millisecond),
+		},
+		{
+			name:      "offset",
+			timestamp: Timestamp{Offset: internal.Duration{Duration: -2 * time.Second}},
+			time:      base,
+			expected:  base.Add(-2 * time.Second),
+		},
+		{
+			name:      "truncate",
+			timestamp: Timestamp{Truncate: internal.Duration{Duration: 10 * time.Second}},
+			time:      base.Add(19 * time.Second),
+			expected:  base.Add(10 * time.Second),
+		},
+		{
+			name:      "round",
+			timestamp: Timestamp{Round: internal.Duration{Duration: 10 * time.Second}},
+			time:      base.Add(15 * time.Second),
+			expected:  base.Add(20 * time.Second),
+		},
+		{
+			name: "offset then round",
+			timestamp: Timestamp{
+				Offset: internal.Duration{Duration: 3 * time.Second},
+				Round:  internal.Duration{Duration: 10 * time.Second},
+			},
+			time:     base.Add(3 * time.Second),
+			expected: base.Add(10 * time.Second),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTimestamp(t, &tt.timestamp)
+			m := testutil.MustMetric("plc", nil, map[string]interface{}{"value": 1}, tt.time)
+			out := ts.Apply(m)
+			require.Len(t, out, 1)
+			require.Equal(t, tt.expected, out[0].Time())
+		})
+	}
+}
+
+func TestMaxSkew(t *testing.T) {
+	now := time.Date(2021, 3, 1, 10, 0, 7, 0, time.UTC)
+	ts := &Timestamp{
+		MaxSkew:  internal.Duration{Duration: time.Minute},
+		Truncate: internal.Duration{Duration: 5 * time.Second},
+		now:      func() time.Time { return now },
+	}
+	ts = newTimestamp(t, ts)
+
+	out := ts.Apply(
+		testutil.MustMetric("snmp", nil, map[string]interface{}{"value": 1}, now.Add(-30*time.Second)),
+		testutil.MustMetric("snmp", nil, map[string]interface{}{"value": 1}, now.Add(time.Hour)),
+		testutil.MustMetric("snmp", nil, map[string]interface{}{"value": 1}, now.Add(-48*time.Hour)),
+	)
+	require.Equal(t, time.Date(2021, 3, 1, 9, 59, 35, 0, time.UTC), out[0].Time())
+	require.Equal(t, time.Date(2021, 3, 1, 10, 0, 5, 0, time.UTC), out[1].Time())
+	require.Equal(t, time.Date(2021, 3, 1, 10, 0, 5, 0, time.UTC), out[2].Time())
+}
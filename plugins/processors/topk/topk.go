@@ -2,7 +2,6 @@ package topk
 
 import (
 	"fmt"
-	"log"
 	"math"
 	"sort"
 	"time"
@@ -32,6 +31,8 @@ type TopK struct {
 	AddRankFields      []string `toml:"add_rank_fields"`
 	AddAggregateFields []string `toml:"add_aggregate_fields"`
 
+	Log cua.Logger `toml:"-"`
+
 	cache           map[string][]cua.Metric
 	tagsGlobs       filter.Filter
 	rankFieldSet    map[string]bool
@@ -137,7 +138,29 @@ func (t *TopK) Reset() {
 }
 
 func (t *TopK) Description() string {
-	return "Print all metrics that pass through this filter."
+	return "Keep only the top k series over a period of time, dropping the others"
+}
+
+func (t *TopK) Init() error {
+	if t.K <= 0 {
+		return fmt.Errorf("k must be positive")
+	}
+	if t.Period.Duration <= 0 {
+		return fmt.Errorf("period must be positive")
+	}
+	if len(t.Fields) == 0 {
+		return fmt.Errorf("fields is required")
+	}
+	if _, err := t.getAggregationFunction(t.Aggregation); err != nil {
+		return err
+	}
+	if len(t.GroupBy) > 0 {
+		var err error
+		if t.tagsGlobs, err = filter.Compile(t.GroupBy); err != nil {
+			return fmt.Errorf("could not compile pattern: %v %w", t.GroupBy, err)
+		}
+	}
+	return nil
 }
 
 func (t *TopK) generateGroupByKey(m cua.Metric) (string, error) {
@@ -177,7 +200,7 @@ func (t *TopK) groupBy(m cua.Metric) {
 	if err != nil {
 		// If we could not generate the groupkey, fail hard
 		// by dropping this and all subsequent metrics
-		log.Printf("E! [processors.topk]: could not generate group key: %v", err)
+		t.Log.Errorf("could not generate group key: %v", err)
 		return
 	}
 
@@ -272,7 +295,7 @@ func (t *TopK) push() []cua.Metric {
 	if err != nil {
 		// If we could not generate the aggregation
 		// function, fail hard by dropping all metrics
-		log.Printf("E! [processors.topk]: %v", err)
+		t.Log.Errorf("%v", err)
 		return []cua.Metric{}
 	}
 	for k, ms := range t.cache {
@@ -345,7 +368,7 @@ func (t *TopK) getAggregationFunction(aggOperation string) (func([]cua.Metric, [
 				}
 				val, ok := convert(fieldVal)
 				if !ok {
-					log.Printf("Cannot convert value '%s' from metric '%s' with tags '%s'",
+					t.Log.Errorf("Cannot convert value '%s' from metric '%s' with tags '%s'",
 						m.Fields()[field], m.Name(), m.Tags())
 					continue
 				}
@@ -411,7 +434,7 @@ func (t *TopK) getAggregationFunction(aggOperation string) (func([]cua.Metric, [
 					}
 					val, ok := convert(fieldVal)
 					if !ok {
-						log.Printf("Cannot convert value '%s' from metric '%s' with tags '%s'",
+						t.Log.Errorf("Cannot convert value '%s' from metric '%s' with tags '%s'",
 							m.Fields()[field], m.Name(), m.Tags())
 						continue
 					}
@@ -516,3 +516,25 @@ func TestTopkGroupByKeyTag(t *testing.T) {
 	// Run the test
 	runAndCompare(&topk, input, answer, "GroupByKeyTag test", t)
 }
+
+func TestTopkInit(t *testing.T) {
+	topk := New()
+	if err := topk.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invalid := map[string]func(*TopK){
+		"aggregation": func(topk *TopK) { topk.Aggregation = "median" },
+		"k":           func(topk *TopK) { topk.K = 0 },
+		"period":      func(topk *TopK) { topk.Period = internal.Duration{} },
+		"fields":      func(topk *TopK) { topk.Fields = nil },
+		"group_by":    func(topk *TopK) { topk.GroupBy = []string{"[tag"} },
+	}
+	for name, set := range invalid {
+		topk := New()
+		set(topk)
+		if err := topk.Init(); err == nil {
+			t.Errorf("expected an error for an invalid %s", name)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package unpivot
 
 import (
+	"fmt"
+
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/processors"
 )
@@ -12,12 +14,17 @@ const (
   tag_key = "name"
   ## Field to use for the name of the value.
   value_key = "value"
+  ## Where to put the name of the original field: "tag" to use the tag_key
+  ## tag, or "measurement" to name the metrics <measurement>_<field> like
+  ## Prometheus metrics.
+  # field_name_as = "tag"
 `
 )
 
 type Unpivot struct {
-	TagKey   string `toml:"tag_key"`
-	ValueKey string `toml:"value_key"`
+	TagKey      string `toml:"tag_key"`
+	ValueKey    string `toml:"value_key"`
+	FieldNameAs string `toml:"field_name_as"`
 }
 
 func (p *Unpivot) SampleConfig() string {
@@ -28,6 +35,17 @@ func (p *Unpivot) Description() string {
 	return description
 }
 
+func (p *Unpivot) Init() error {
+	switch p.FieldNameAs {
+	case "":
+		p.FieldNameAs = "tag"
+	case "tag", "measurement":
+	default:
+		return fmt.Errorf("invalid field_name_as %q, expected \"tag\" or \"measurement\"", p.FieldNameAs)
+	}
+	return nil
+}
+
 func copyWithoutFields(metric cua.Metric) cua.Metric {
 	m := metric.Copy()
 
@@ -56,7 +74,11 @@ func (p *Unpivot) Apply(metrics ...cua.Metric) []cua.Metric {
 		for _, field := range m.FieldList() {
 			newMetric := base.Copy()
 			newMetric.AddField(p.ValueKey, field.Value)
-			newMetric.AddTag(p.TagKey, field.Key)
+			if p.FieldNameAs == "measurement" {
+				newMetric.SetName(m.Name() + "_" + field.Key)
+			} else {
+				newMetric.AddTag(p.TagKey, field.Key)
+			}
 			results = append(results, newMetric)
 		}
 		m.Accept()
@@ -66,6 +88,8 @@ func (p *Unpivot) Apply(metrics ...cua.Metric) []cua.Metric {
 
 func init() {
 	processors.Add("unpivot", func() cua.Processor {
-		return &Unpivot{}
+		return &Unpivot{
+			FieldNameAs: "tag",
+		}
 	})
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnpivot(t *testing.T) {
@@ -80,6 +81,36 @@ func TestUnpivot(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "field name as measurement",
+			unpivot: &Unpivot{
+				TagKey:      "name",
+				ValueKey:    "value",
+				FieldNameAs: "measurement",
+			},
+			metrics: []cua.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu0"},
+					map[string]interface{}{
+						"time_idle": int64(42),
+						"time_user": int64(43),
+					},
+					now,
+				),
+			},
+			expected: []cua.Metric{
+				testutil.MustMetric("cpu_time_idle",
+					map[string]string{"cpu": "cpu0"},
+					map[string]interface{}{"value": int64(42)},
+					now,
+				),
+				testutil.MustMetric("cpu_time_user",
+					map[string]string{"cpu": "cpu0"},
+					map[string]interface{}{"value": int64(43)},
+					now,
+				),
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -89,3 +120,12 @@ func TestUnpivot(t *testing.T) {
 		})
 	}
 }
+
+func TestUnpivotInit(t *testing.T) {
+	u := &Unpivot{TagKey: "name", ValueKey: "value"}
+	require.NoError(t, u.Init())
+	require.Equal(t, "tag", u.FieldNameAs)
+
+	u = &Unpivot{TagKey: "name", ValueKey: "value", FieldNameAs: "field"}
+	require.Error(t, u.Init())
+}
@@ -3,6 +3,7 @@ package carbon2
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -23,11 +24,16 @@ var formats = map[format]struct{}{
 	Carbon2FormatMetricIncludesField: {},
 }
 
+// DefaultSanitizeReplaceChar replaces the equal signs of the names, keys and
+// values, they would be read as the separator of a key and its value.
+const DefaultSanitizeReplaceChar = ":"
+
 type Serializer struct {
 	metricsFormat format
+	sanitizer     *strings.Replacer
 }
 
-func NewSerializer(metricsFormat string) (*Serializer, error) {
+func NewSerializer(metricsFormat, sanitizeReplaceChar string) (*Serializer, error) {
 	var f = format(metricsFormat)
 
 	if _, ok := formats[f]; !ok {
@@ -39,8 +45,16 @@ func NewSerializer(metricsFormat string) (*Serializer, error) {
 		f = Carbon2FormatFieldSeparate
 	}
 
+	if sanitizeReplaceChar == "" {
+		sanitizeReplaceChar = DefaultSanitizeReplaceChar
+	}
+	if len(sanitizeReplaceChar) != 1 || sanitizeReplaceChar == "=" || sanitizeReplaceChar == " " {
+		return nil, fmt.Errorf("invalid carbon2 sanitize replace char: %q", sanitizeReplaceChar)
+	}
+
 	return &Serializer{
 		metricsFormat: f,
+		sanitizer:     strings.NewReplacer(" ", "_", "=", sanitizeReplaceChar),
 	}, nil
 }
 
@@ -60,35 +74,39 @@ func (s *Serializer) createObject(metric cua.Metric) []byte {
 	var m bytes.Buffer
 	metricsFormat := s.getMetricsFormat()
 
-	for fieldName, fieldValue := range metric.Fields() {
-		if isString(fieldValue) {
+	// the fields are sorted for the output to be stable
+	fields := append([]*cua.Field(nil), metric.FieldList()...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+
+	for _, field := range fields {
+		if isString(field.Value) {
 			continue
 		}
 
 		switch metricsFormat {
 		case Carbon2FormatFieldSeparate:
-			m.WriteString(serializeMetricFieldSeparate(
-				metric.Name(), fieldName,
+			m.WriteString(s.serializeMetricFieldSeparate(
+				metric.Name(), field.Key,
 			))
 
 		case Carbon2FormatMetricIncludesField:
-			m.WriteString(serializeMetricIncludeField(
-				metric.Name(), fieldName,
+			m.WriteString(s.serializeMetricIncludeField(
+				metric.Name(), field.Key,
 			))
 		}
 
 		for _, tag := range metric.TagList() {
-			m.WriteString(strings.ReplaceAll(tag.Key, " ", "_"))
+			m.WriteString(s.sanitizer.Replace(tag.Key))
 			m.WriteString("=")
 			value := tag.Value
 			if len(value) == 0 {
 				value = "null"
 			}
-			m.WriteString(strings.ReplaceAll(value, " ", "_"))
+			m.WriteString(s.sanitizer.Replace(value))
 			m.WriteString(" ")
 		}
 		m.WriteString(" ")
-		m.WriteString(formatValue(fieldValue))
+		m.WriteString(formatValue(field.Value))
 		m.WriteString(" ")
 		m.WriteString(strconv.FormatInt(metric.Time().Unix(), 10))
 		m.WriteString("\n")
@@ -108,17 +126,17 @@ func (s *Serializer) IsMetricsFormatUnset() bool {
 	return s.metricsFormat == carbon2FormatFieldEmpty
 }
 
-func serializeMetricFieldSeparate(name, fieldName string) string {
+func (s *Serializer) serializeMetricFieldSeparate(name, fieldName string) string {
 	return fmt.Sprintf("metric=%s field=%s ",
-		strings.ReplaceAll(name, " ", "_"),
-		strings.ReplaceAll(fieldName, " ", "_"),
+		s.sanitizer.Replace(name),
+		s.sanitizer.Replace(fieldName),
 	)
 }
 
-func serializeMetricIncludeField(name, fieldName string) string {
+func (s *Serializer) serializeMetricIncludeField(name, fieldName string) string {
 	return fmt.Sprintf("metric=%s_%s ",
-		strings.ReplaceAll(name, " ", "_"),
-		strings.ReplaceAll(fieldName, " ", "_"),
+		s.sanitizer.Replace(name),
+		s.sanitizer.Replace(fieldName),
 	)
 }
 
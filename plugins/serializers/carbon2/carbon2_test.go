@@ -46,7 +46,7 @@ func TestSerializeMetricFloat(t *testing.T) {
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(string(tc.format), func(t *testing.T) {
-			s, err := NewSerializer(string(tc.format))
+			s, err := NewSerializer(string(tc.format), "")
 			require.NoError(t, err)
 
 			buf, err := s.Serialize(m)
@@ -85,7 +85,7 @@ func TestSerializeMetricWithEmptyStringTag(t *testing.T) {
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(string(tc.format), func(t *testing.T) {
-			s, err := NewSerializer(string(tc.format))
+			s, err := NewSerializer(string(tc.format), "")
 			require.NoError(t, err)
 
 			buf, err := s.Serialize(m)
@@ -124,7 +124,7 @@ func TestSerializeWithSpaces(t *testing.T) {
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(string(tc.format), func(t *testing.T) {
-			s, err := NewSerializer(string(tc.format))
+			s, err := NewSerializer(string(tc.format), "")
 			require.NoError(t, err)
 
 			buf, err := s.Serialize(m)
@@ -163,7 +163,7 @@ func TestSerializeMetricInt(t *testing.T) {
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(string(tc.format), func(t *testing.T) {
-			s, err := NewSerializer(string(tc.format))
+			s, err := NewSerializer(string(tc.format), "")
 			require.NoError(t, err)
 
 			buf, err := s.Serialize(m)
@@ -202,7 +202,7 @@ func TestSerializeMetricString(t *testing.T) {
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(string(tc.format), func(t *testing.T) {
-			s, err := NewSerializer(string(tc.format))
+			s, err := NewSerializer(string(tc.format), "")
 			require.NoError(t, err)
 
 			buf, err := s.Serialize(m)
@@ -260,7 +260,7 @@ func TestSerializeMetricBool(t *testing.T) {
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(tc.format, func(t *testing.T) {
-			s, err := NewSerializer(tc.format)
+			s, err := NewSerializer(tc.format, "")
 			require.NoError(t, err)
 
 			buf, err := s.Serialize(tc.metric)
@@ -306,7 +306,7 @@ metric=cpu_value  42 0
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(string(tc.format), func(t *testing.T) {
-			s, err := NewSerializer(string(tc.format))
+			s, err := NewSerializer(string(tc.format), "")
 			require.NoError(t, err)
 
 			buf, err := s.SerializeBatch(metrics)
@@ -316,3 +316,81 @@ metric=cpu_value  42 0
 		})
 	}
 }
+
+func TestSerializeSanitize(t *testing.T) {
+	m := MustMetric(
+		metric.New(
+			"cpu=metric",
+			map[string]string{
+				"cpu=0": "a=b",
+			},
+			map[string]interface{}{
+				"usage=idle": 91.5,
+			},
+			time.Unix(0, 0),
+		),
+	)
+
+	testcases := []struct {
+		name        string
+		replaceChar string
+		expected    string
+	}{
+		{
+			name:     "default",
+			expected: "metric=cpu:metric field=usage:idle cpu:0=a:b  91.5 0\n",
+		},
+		{
+			name:        "underscore",
+			replaceChar: "_",
+			expected:    "metric=cpu_metric field=usage_idle cpu_0=a_b  91.5 0\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewSerializer(string(Carbon2FormatFieldSeparate), tc.replaceChar)
+			require.NoError(t, err)
+
+			buf, err := s.Serialize(m)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, string(buf))
+		})
+	}
+}
+
+func TestSerializeInvalidReplaceChar(t *testing.T) {
+	_, err := NewSerializer(string(Carbon2FormatFieldSeparate), "=")
+	require.Error(t, err)
+	_, err = NewSerializer(string(Carbon2FormatFieldSeparate), "ab")
+	require.Error(t, err)
+}
+
+func TestSerializeFieldOrder(t *testing.T) {
+	m := MustMetric(
+		metric.New(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"usage_user":   1,
+				"usage_idle":   2,
+				"usage_system": 3,
+			},
+			time.Unix(0, 0),
+		),
+	)
+
+	s, err := NewSerializer(string(Carbon2FormatFieldSeparate), "")
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	expected := `metric=cpu field=usage_idle  2 0
+metric=cpu field=usage_system  3 0
+metric=cpu field=usage_user  1 0
+`
+	assert.Equal(t, expected, string(buf))
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math"
 	"sort"
 	"strconv"
@@ -59,6 +58,10 @@ func (e FieldError) Error() string {
 
 // Serializer is a serializer for line protocol.
 type Serializer struct {
+	// Log receives the messages about the discarded fields and metrics, they
+	// are not logged when it is nil.
+	Log cua.Logger
+
 	maxLineBytes     int
 	bytesWritten     int
 	fieldSortOrder   FieldSortOrder
@@ -134,13 +137,19 @@ func (s *Serializer) Write(w io.Writer, m cua.Metric) (int, error) {
 func (s *Serializer) writeString(w io.Writer, str string) error {
 	n, err := io.WriteString(w, str)
 	s.bytesWritten += n
-	return fmt.Errorf("io write string: %w", err)
+	if err != nil {
+		return fmt.Errorf("io write string: %w", err)
+	}
+	return nil
 }
 
 func (s *Serializer) write(w io.Writer, b []byte) error {
 	n, err := w.Write(b)
 	s.bytesWritten += n
-	return fmt.Errorf("write: %w", err)
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
 }
 
 func (s *Serializer) buildHeader(m cua.Metric) error {
@@ -229,9 +238,9 @@ func (s *Serializer) writeMetric(w io.Writer, m cua.Metric) error {
 	for _, field := range m.FieldList() {
 		err = s.buildFieldPair(field.Key, field.Value)
 		if err != nil {
-			log.Printf(
-				"D! [serializers.influx] could not serialize field %q: %v; discarding field",
-				field.Key, err)
+			if s.Log != nil {
+				s.Log.Debugf("Could not serialize field %q: %v; discarding field", field.Key, err)
+			}
 			continue
 		}
 
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"io"
-	"log"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 )
@@ -60,7 +59,9 @@ func (r *reader) Read(p []byte) (int, error) {
 			}
 			// Since we are serializing multiple metrics, don't fail the
 			// the entire batch just because of one unserializable metric.
-			log.Printf("E! [serializers.influx] could not serialize metric: %v; discarding metric", err)
+			if r.serializer.Log != nil {
+				r.serializer.Log.Errorf("Could not serialize metric: %v; discarding metric", err)
+			}
 			continue
 		}
 		break
@@ -2,6 +2,7 @@ package prometheus
 
 import (
 	"hash/fnv"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -65,6 +66,21 @@ func (h *Histogram) merge(b Bucket) {
 	h.Buckets = append(h.Buckets, b)
 }
 
+// SortedBuckets returns the buckets ordered by bound, ending with the +Inf
+// bucket required by Prometheus which is added with the histogram count if
+// missing.
+func (h *Histogram) SortedBuckets() []Bucket {
+	buckets := make([]Bucket, len(h.Buckets), len(h.Buckets)+1)
+	copy(buckets, h.Buckets)
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Bound < buckets[j].Bound
+	})
+	if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1].Bound, 1) {
+		buckets = append(buckets, Bucket{Bound: math.Inf(1), Count: h.Count})
+	}
+	return buckets
+}
+
 type Summary struct {
 	Quantiles []Quantile
 	Count     uint64
@@ -81,6 +97,16 @@ func (s *Summary) merge(q Quantile) {
 	s.Quantiles = append(s.Quantiles, q)
 }
 
+// SortedQuantiles returns the quantiles in increasing order.
+func (s *Summary) SortedQuantiles() []Quantile {
+	quantiles := make([]Quantile, len(s.Quantiles))
+	copy(quantiles, s.Quantiles)
+	sort.Slice(quantiles, func(i, j int) bool {
+		return quantiles[i].Quantile < quantiles[j].Quantile
+	})
+	return quantiles
+}
+
 type MetricKey uint64
 
 func MakeMetricKey(labels []LabelPair) MetricKey {
@@ -437,7 +463,7 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 				m.Untyped = &dto.Untyped{Value: proto.Float64(metric.Scaler.Value)}
 			case cua.Histogram:
 				buckets := make([]*dto.Bucket, 0, len(metric.Histogram.Buckets))
-				for _, bucket := range metric.Histogram.Buckets {
+				for _, bucket := range metric.Histogram.SortedBuckets() {
 					buckets = append(buckets, &dto.Bucket{
 						UpperBound:      proto.Float64(bucket.Bound),
 						CumulativeCount: proto.Uint64(bucket.Count),
@@ -451,7 +477,7 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 				}
 			case cua.Summary:
 				quantiles := make([]*dto.Quantile, 0, len(metric.Summary.Quantiles))
-				for _, quantile := range metric.Summary.Quantiles {
+				for _, quantile := range metric.Summary.SortedQuantiles() {
 					quantiles = append(quantiles, &dto.Quantile{
 						Quantile: proto.Float64(quantile.Quantile),
 						Value:    proto.Float64(quantile.Value),
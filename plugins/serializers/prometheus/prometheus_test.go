@@ -318,6 +318,48 @@ http_request_duration_seconds_bucket{le="1"} 133988
 http_request_duration_seconds_bucket{le="+Inf"} 144320
 http_request_duration_seconds_sum 53423
 http_request_duration_seconds_count 144320
+`),
+		},
+		{
+			name: "histogram buckets out of order without +Inf",
+			metrics: []cua.Metric{
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"le": "0.5"},
+					map[string]interface{}{
+						"http_request_duration_seconds_bucket": 129389.0,
+					},
+					time.Unix(0, 0),
+					cua.Histogram,
+				),
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"le": "0.05"},
+					map[string]interface{}{
+						"http_request_duration_seconds_bucket": 24054.0,
+					},
+					time.Unix(0, 0),
+					cua.Histogram,
+				),
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{},
+					map[string]interface{}{
+						"http_request_duration_seconds_sum":   53423,
+						"http_request_duration_seconds_count": 144320,
+					},
+					time.Unix(0, 0),
+					cua.Histogram,
+				),
+			},
+			expected: []byte(`
+# HELP http_request_duration_seconds Circonus Unified Agent collected metric
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{le="0.05"} 24054
+http_request_duration_seconds_bucket{le="0.5"} 129389
+http_request_duration_seconds_bucket{le="+Inf"} 144320
+http_request_duration_seconds_sum 53423
+http_request_duration_seconds_count 144320
 `),
 		},
 		{
@@ -0,0 +1,135 @@
+package prometheusremotewrite
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/prometheus"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+)
+
+const helpString = "Circonus Unified Agent collected metric"
+
+// Serializer converts metrics to a snappy compressed Prometheus remote write
+// request.  The request holds all the metrics of a batch, use it with the
+// batch format of the outputs.
+type Serializer struct {
+	config prometheus.FormatConfig
+}
+
+func NewSerializer(config prometheus.FormatConfig) (*Serializer, error) {
+	s := &Serializer{config: config}
+	return s, nil
+}
+
+func (s *Serializer) Serialize(metric cua.Metric) ([]byte, error) {
+	return s.SerializeBatch([]cua.Metric{metric})
+}
+
+func (s *Serializer) SerializeBatch(metrics []cua.Metric) ([]byte, error) {
+	coll := prometheus.NewCollection(s.config)
+	for _, metric := range metrics {
+		coll.Add(metric, time.Now())
+	}
+
+	req := &WriteRequest{}
+	for _, entry := range coll.GetEntries(s.config.MetricSortOrder) {
+		name := entry.Family.Name
+		n := len(req.Timeseries)
+		for _, metric := range coll.GetMetrics(entry, s.config.MetricSortOrder) {
+			ts := timestamp(metric.Time)
+			switch entry.Family.Type {
+			case cua.Histogram:
+				for _, b := range metric.Histogram.SortedBuckets() {
+					req.Timeseries = append(req.Timeseries, series(name+"_bucket", metric.Labels,
+						&Label{Name: "le", Value: formatFloat(b.Bound)}, float64(b.Count), ts))
+				}
+				req.Timeseries = append(req.Timeseries,
+					series(name+"_sum", metric.Labels, nil, metric.Histogram.Sum, ts),
+					series(name+"_count", metric.Labels, nil, float64(metric.Histogram.Count), ts))
+			case cua.Summary:
+				for _, q := range metric.Summary.SortedQuantiles() {
+					req.Timeseries = append(req.Timeseries, series(name, metric.Labels,
+						&Label{Name: "quantile", Value: formatFloat(q.Quantile)}, q.Value, ts))
+				}
+				req.Timeseries = append(req.Timeseries,
+					series(name+"_sum", metric.Labels, nil, metric.Summary.Sum, ts),
+					series(name+"_count", metric.Labels, nil, float64(metric.Summary.Count), ts))
+			default:
+				req.Timeseries = append(req.Timeseries, series(name, metric.Labels, nil, metric.Scaler.Value, ts))
+			}
+		}
+		if len(req.Timeseries) > n {
+			req.Metadata = append(req.Metadata, &MetricMetadata{
+				Type:             metricType(entry.Family.Type),
+				MetricFamilyName: name,
+				Help:             helpString,
+			})
+		}
+	}
+
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal write request: %w", err)
+	}
+	return snappy.Encode(nil, data), nil
+}
+
+// series creates a time series, the labels of a series must be sorted by
+// name and include the metric name as __name__.
+func series(name string, labels []prometheus.LabelPair, extra *Label, value float64, ts int64) *TimeSeries {
+	l := make([]*Label, 0, len(labels)+2)
+	l = append(l, &Label{Name: "__name__", Value: name})
+	for _, label := range labels {
+		l = append(l, &Label{Name: label.Name, Value: label.Value})
+	}
+	if extra != nil {
+		l = append(l, extra)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].Name < l[j].Name
+	})
+
+	return &TimeSeries{
+		Labels:  l,
+		Samples: []*Sample{{Value: value, Timestamp: ts}},
+	}
+}
+
+func timestamp(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func metricType(valueType cua.ValueType) MetricType {
+	switch valueType {
+	case cua.Counter:
+		return MetricTypeCounter
+	case cua.Gauge:
+		return MetricTypeGauge
+	case cua.Histogram:
+		return MetricTypeHistogram
+	case cua.Summary:
+		return MetricTypeSummary
+	default:
+		return MetricTypeUnknown
+	}
+}
+
+// formatFloat formats bounds and quantiles like the Prometheus client
+// libraries.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
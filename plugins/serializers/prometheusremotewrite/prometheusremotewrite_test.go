@@ -0,0 +1,171 @@
+package prometheusremotewrite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/prometheus"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, data []byte) *WriteRequest {
+	t.Helper()
+	buf, err := snappy.Decode(nil, data)
+	require.NoError(t, err)
+	req := &WriteRequest{}
+	require.NoError(t, proto.Unmarshal(buf, req))
+	return req
+}
+
+func labels(pairs ...string) []*Label {
+	l := make([]*Label, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		l = append(l, &Label{Name: pairs[i], Value: pairs[i+1]})
+	}
+	return l
+}
+
+func TestSerializeBatch(t *testing.T) {
+	metrics := []cua.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"host": "example.org",
+			},
+			map[string]interface{}{
+				"time_idle": 42.0,
+				"name":      "cpu0",
+			},
+			time.Unix(1, 0),
+			cua.Gauge,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{
+				"le": "0.5",
+			},
+			map[string]interface{}{
+				"http_request_duration_seconds_bucket": 129389.0,
+			},
+			time.Unix(1, 0),
+			cua.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{
+				"le": "0.05",
+			},
+			map[string]interface{}{
+				"http_request_duration_seconds_bucket": 24054.0,
+			},
+			time.Unix(1, 0),
+			cua.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{},
+			map[string]interface{}{
+				"http_request_duration_seconds_sum":   53423.0,
+				"http_request_duration_seconds_count": 144320.0,
+			},
+			time.Unix(1, 0),
+			cua.Histogram,
+		),
+	}
+
+	s, err := NewSerializer(prometheus.FormatConfig{
+		MetricSortOrder: prometheus.SortMetrics,
+	})
+	require.NoError(t, err)
+	data, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	expected := &WriteRequest{
+		Timeseries: []*TimeSeries{
+			{
+				Labels:  labels("__name__", "cpu_time_idle", "host", "example.org"),
+				Samples: []*Sample{{Value: 42, Timestamp: 1000}},
+			},
+			{
+				Labels:  labels("__name__", "http_request_duration_seconds_bucket", "le", "0.05"),
+				Samples: []*Sample{{Value: 24054, Timestamp: 1000}},
+			},
+			{
+				Labels:  labels("__name__", "http_request_duration_seconds_bucket", "le", "0.5"),
+				Samples: []*Sample{{Value: 129389, Timestamp: 1000}},
+			},
+			{
+				Labels:  labels("__name__", "http_request_duration_seconds_bucket", "le", "+Inf"),
+				Samples: []*Sample{{Value: 144320, Timestamp: 1000}},
+			},
+			{
+				Labels:  labels("__name__", "http_request_duration_seconds_sum"),
+				Samples: []*Sample{{Value: 53423, Timestamp: 1000}},
+			},
+			{
+				Labels:  labels("__name__", "http_request_duration_seconds_count"),
+				Samples: []*Sample{{Value: 144320, Timestamp: 1000}},
+			},
+		},
+		Metadata: []*MetricMetadata{
+			{Type: MetricTypeGauge, MetricFamilyName: "cpu_time_idle", Help: helpString},
+			{Type: MetricTypeHistogram, MetricFamilyName: "http_request_duration_seconds", Help: helpString},
+		},
+	}
+	require.Equal(t, expected, decode(t, data))
+}
+
+func TestSerializeSummaryStringAsLabel(t *testing.T) {
+	metrics := []cua.Metric{
+		testutil.MustMetric(
+			"rpc",
+			map[string]string{
+				"quantile": "0.99",
+			},
+			map[string]interface{}{
+				"duration_seconds": 0.5,
+				"service":          "api",
+			},
+			time.Unix(0, 0),
+			cua.Summary,
+		),
+		testutil.MustMetric(
+			"rpc",
+			map[string]string{},
+			map[string]interface{}{
+				"duration_seconds_sum":   10.0,
+				"duration_seconds_count": 20.0,
+				"service":                "api",
+			},
+			time.Unix(0, 0),
+			cua.Summary,
+		),
+	}
+
+	s, err := NewSerializer(prometheus.FormatConfig{
+		StringHandling: prometheus.StringAsLabel,
+	})
+	require.NoError(t, err)
+	data, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	expected := []*TimeSeries{
+		{
+			Labels:  labels("__name__", "rpc_duration_seconds", "quantile", "0.99", "service", "api"),
+			Samples: []*Sample{{Value: 0.5}},
+		},
+		{
+			Labels:  labels("__name__", "rpc_duration_seconds_sum", "service", "api"),
+			Samples: []*Sample{{Value: 10}},
+		},
+		{
+			Labels:  labels("__name__", "rpc_duration_seconds_count", "service", "api"),
+			Samples: []*Sample{{Value: 20}},
+		},
+	}
+	require.Equal(t, expected, decode(t, data).Timeseries)
+}
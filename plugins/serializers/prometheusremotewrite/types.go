@@ -0,0 +1,64 @@
+package prometheusremotewrite
+
+import "github.com/gogo/protobuf/proto"
+
+// The messages of the Prometheus remote write protocol, from prompb/types.proto
+// and prompb/remote.proto.
+
+// MetricType is the type of a metric family in the remote write metadata.
+type MetricType int32
+
+const (
+	MetricTypeUnknown   MetricType = 0
+	MetricTypeCounter   MetricType = 1
+	MetricTypeGauge     MetricType = 2
+	MetricTypeHistogram MetricType = 3
+	MetricTypeSummary   MetricType = 5
+)
+
+type WriteRequest struct {
+	Timeseries []*TimeSeries     `protobuf:"bytes,1,rep,name=timeseries,proto3"`
+	Metadata   []*MetricMetadata `protobuf:"bytes,3,rep,name=metadata,proto3"`
+}
+
+func (m *WriteRequest) Reset()         { *m = WriteRequest{} }
+func (m *WriteRequest) String() string { return proto.CompactTextString(m) }
+func (*WriteRequest) ProtoMessage()    {}
+
+type TimeSeries struct {
+	Labels  []*Label  `protobuf:"bytes,1,rep,name=labels,proto3"`
+	Samples []*Sample `protobuf:"bytes,2,rep,name=samples,proto3"`
+}
+
+func (m *TimeSeries) Reset()         { *m = TimeSeries{} }
+func (m *TimeSeries) String() string { return proto.CompactTextString(m) }
+func (*TimeSeries) ProtoMessage()    {}
+
+type Label struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *Label) Reset()         { *m = Label{} }
+func (m *Label) String() string { return proto.CompactTextString(m) }
+func (*Label) ProtoMessage()    {}
+
+type Sample struct {
+	Value float64 `protobuf:"fixed64,1,opt,name=value,proto3"`
+	// Timestamp is in milliseconds since the epoch.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp,proto3"`
+}
+
+func (m *Sample) Reset()         { *m = Sample{} }
+func (m *Sample) String() string { return proto.CompactTextString(m) }
+func (*Sample) ProtoMessage()    {}
+
+type MetricMetadata struct {
+	Type             MetricType `protobuf:"varint,1,opt,name=type,proto3,enum=prometheus.MetricMetadata_MetricType"`
+	MetricFamilyName string     `protobuf:"bytes,2,opt,name=metric_family_name,proto3"`
+	Help             string     `protobuf:"bytes,4,opt,name=help,proto3"`
+}
+
+func (m *MetricMetadata) Reset()         { *m = MetricMetadata{} }
+func (m *MetricMetadata) String() string { return proto.CompactTextString(m) }
+func (*MetricMetadata) ProtoMessage()    {}
@@ -5,13 +5,16 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/models"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/carbon2"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/graphite"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/influx"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/json"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/nowmetric"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/prometheus"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/prometheusremotewrite"
 	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/splunkmetric"
+	"github.com/circonus-labs/circonus-unified-agent/plugins/serializers/wavefront"
 )
 
 // SerializerOutput is an interface for output plugins that are able to
@@ -50,6 +53,9 @@ type Config struct {
 	// Carbon2 metric format.
 	Carbon2Format string `toml:"carbon2_format"`
 
+	// Character replacing the equal signs in Carbon2 names, keys and values.
+	Carbon2SanitizeReplaceChar string `toml:"carbon2_sanitize_replace_char"`
+
 	// Support tags in graphite protocol
 	GraphiteTagSupport bool `toml:"graphite_tag_support"`
 
@@ -120,14 +126,19 @@ func NewSerializer(config *Config) (Serializer, error) {
 	case "nowmetric":
 		serializer, err = NewNowSerializer()
 	case "carbon2":
-		serializer, err = NewCarbon2Serializer(config.Carbon2Format)
-	// case "wavefront":
-	// 	serializer, err = NewWavefrontSerializer(config.Prefix, config.WavefrontUseStrict, config.WavefrontSourceOverride)
+		serializer, err = NewCarbon2Serializer(config.Carbon2Format, config.Carbon2SanitizeReplaceChar)
+	case "wavefront":
+		serializer, err = NewWavefrontSerializer(config.Prefix, config.WavefrontUseStrict, config.WavefrontSourceOverride)
 	case "prometheus":
 		serializer, err = NewPrometheusSerializer(config)
+	case "prometheusremotewrite":
+		serializer, err = NewPrometheusRemoteWriteSerializer(config)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
+	if err == nil {
+		models.SetLoggerOnPlugin(serializer, models.NewLogger("serializers", config.DataFormat, ""))
+	}
 	return serializer, err
 }
 
@@ -138,7 +149,7 @@ func NewPrometheusSerializer(config *Config) (Serializer, error) {
 	}
 
 	sortMetrics := prometheus.NoSortMetrics
-	if config.PrometheusExportTimestamp {
+	if config.PrometheusSortMetrics {
 		sortMetrics = prometheus.SortMetrics
 	}
 
@@ -154,16 +165,33 @@ func NewPrometheusSerializer(config *Config) (Serializer, error) {
 	})
 }
 
-// func NewWavefrontSerializer(prefix string, useStrict bool, sourceOverride []string) (Serializer, error) {
-// 	return wavefront.NewSerializer(prefix, useStrict, sourceOverride)
-// }
+func NewPrometheusRemoteWriteSerializer(config *Config) (Serializer, error) {
+	sortMetrics := prometheus.NoSortMetrics
+	if config.PrometheusSortMetrics {
+		sortMetrics = prometheus.SortMetrics
+	}
+
+	stringAsLabels := prometheus.DiscardStrings
+	if config.PrometheusStringAsLabel {
+		stringAsLabels = prometheus.StringAsLabel
+	}
+
+	return prometheusremotewrite.NewSerializer(prometheus.FormatConfig{
+		MetricSortOrder: sortMetrics,
+		StringHandling:  stringAsLabels,
+	})
+}
+
+func NewWavefrontSerializer(prefix string, useStrict bool, sourceOverride []string) (Serializer, error) {
+	return wavefront.NewSerializer(prefix, useStrict, sourceOverride)
+}
 
 func NewJSONSerializer(timestampUnits time.Duration) (Serializer, error) {
 	return json.NewSerializer(timestampUnits)
 }
 
-func NewCarbon2Serializer(carbon2format string) (Serializer, error) {
-	return carbon2.NewSerializer(carbon2format)
+func NewCarbon2Serializer(carbon2format, sanitizeReplaceChar string) (Serializer, error) {
+	return carbon2.NewSerializer(carbon2format, sanitizeReplaceChar)
 }
 
 func NewSplunkmetricSerializer(splunkmetricHecRouting bool, splunkmetricMultimetric bool) (Serializer, error) {
@@ -3,7 +3,6 @@ package splunkmetric
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 )
@@ -11,6 +10,7 @@ import (
 type Serializer struct {
 	HecRouting              bool
 	SplunkmetricMultiMetric bool
+	Log                     cua.Logger
 }
 
 type CommonTags struct {
@@ -89,7 +89,7 @@ func (s *Serializer) createMulti(metric cua.Metric, dataGroup HECTimeSeries, com
 		value, valid := verifyValue(field.Value)
 
 		if !valid {
-			log.Printf("D! Can not parse value: %v for key: %v", field.Value, field.Key)
+			s.Log.Debugf("Can not parse value: %v for key: %v", field.Value, field.Key)
 			continue
 		}
 
@@ -130,7 +130,7 @@ func (s *Serializer) createSingle(metric cua.Metric, dataGroup HECTimeSeries, co
 		value, valid := verifyValue(field.Value)
 
 		if !valid {
-			log.Printf("D! Can not parse value: %v for key: %v", field.Value, field.Key)
+			s.Log.Debugf("Can not parse value: %v for key: %v", field.Value, field.Key)
 			continue
 		}
 
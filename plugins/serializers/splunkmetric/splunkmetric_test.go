@@ -6,6 +6,7 @@ import (
 
 	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/metric"
+	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -149,6 +150,7 @@ func TestSerializeMetricString(t *testing.T) {
 	assert.NoError(t, err)
 
 	s, _ := NewSerializer(false, false)
+	s.Log = testutil.Logger{}
 	var buf []byte
 	buf, err = s.Serialize(m)
 	assert.NoError(t, err)
@@ -0,0 +1,145 @@
+package selfstat
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-unified-agent/cua"
+	"github.com/circonus-labs/circonus-unified-agent/metric"
+)
+
+// Histogram is a distribution of values, such as latencies, in log-linear
+// bins of two significant digits.
+type Histogram struct {
+	measurement string
+	tags        map[string]string
+	bins        map[float64]int64
+	mu          sync.Mutex
+}
+
+// RegisterHistogram registers the given measurement and tags in the selfstat
+// registry. If given an identical measurement, it will return the histogram
+// that's already been registered.
+//
+// The values observed by the returned histogram since the previous call to
+// HistogramMetrics are returned as an agent metric of the histogram type.
+func RegisterHistogram(measurement string, tags map[string]string) *Histogram {
+	return registry.registerHistogram("internal_"+measurement, tags)
+}
+
+// Observe adds a value to the histogram.
+func (h *Histogram) Observe(v float64) {
+	b := bin(v)
+	h.mu.Lock()
+	h.bins[b]++
+	h.mu.Unlock()
+}
+
+// Name is the name of the measurement.
+func (h *Histogram) Name() string {
+	return h.measurement
+}
+
+// Tags returns a copy of the histogram's tags.
+func (h *Histogram) Tags() map[string]string {
+	m := make(map[string]string, len(h.tags))
+	for k, v := range h.tags {
+		m[k] = v
+	}
+	return m
+}
+
+// reset returns the counts of the bins and clears them.
+func (h *Histogram) reset() map[float64]int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	bins := h.bins
+	h.bins = make(map[float64]int64, len(bins))
+	return bins
+}
+
+// bin returns the lower bound of the bin of a value, with two significant
+// digits: 0.1234 is in the bin 0.12 and 1234 in the bin 1200.
+func bin(v float64) float64 {
+	if v <= 0 || math.IsNaN(v) {
+		return 0
+	}
+	if math.IsInf(v, 1) {
+		return math.MaxFloat64
+	}
+	e := int(math.Floor(math.Log10(v))) - 1
+	// the mantissa is divided rather than multiplied by a negative power
+	// of ten to get the exact decimal bounds, like 0.12 rather than
+	// 0.12000000000000001
+	if e < 0 {
+		p := math.Pow10(-e)
+		m := math.Floor(v * p)
+		if m >= 100 {
+			return bin(m / p)
+		}
+		return m / p
+	}
+	p := math.Pow10(e)
+	return math.Floor(v/p) * p
+}
+
+// HistogramMetrics returns the registered histograms with the values
+// observed since the previous call as agent metrics of the histogram type,
+// with a field per bin counting its values.
+func HistogramMetrics() []cua.Metric {
+	registry.mu.Lock()
+	histograms := make([]*Histogram, 0, len(registry.histograms))
+	for _, h := range registry.histograms {
+		histograms = append(histograms, h)
+	}
+	registry.mu.Unlock()
+
+	now := time.Now()
+	metrics := make([]cua.Metric, 0, len(histograms))
+	for _, h := range histograms {
+		bins := h.reset()
+		if len(bins) == 0 {
+			continue
+		}
+		fields := make(map[string]interface{}, len(bins))
+		for b, n := range bins {
+			fields[strconv.FormatFloat(b, 'g', -1, 64)] = n
+		}
+		m, err := metric.New(h.measurement, h.Tags(), fields, now, cua.Histogram)
+		if err != nil {
+			log.Printf("E! Error creating selfstat histogram: %s", err)
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func (r *Registry) registerHistogram(measurement string, tags map[string]string) *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := key(measurement, tags)
+	if h, ok := r.histograms[key]; ok {
+		return h
+	}
+
+	t := make(map[string]string, len(tags))
+	for k, v := range tags {
+		t[k] = v
+	}
+
+	h := &Histogram{
+		measurement: measurement,
+		tags:        t,
+		bins:        make(map[float64]int64),
+	}
+	if r.histograms == nil {
+		r.histograms = make(map[uint64]*Histogram)
+	}
+	r.histograms[key] = h
+	return h
+}
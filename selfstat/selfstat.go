@@ -9,6 +9,7 @@ import (
 	"hash/fnv"
 	"log"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -109,9 +110,51 @@ func Metrics() []cua.Metric {
 	return metrics
 }
 
+// Snapshot returns the values of the registered stats by series, such as
+// "internal_write,output=circonus", for the diagnostics.  Unlike Metrics, the
+// averages of the timings are not reset.
+func Snapshot() map[string]map[string]int64 {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	snapshot := make(map[string]map[string]int64, len(registry.stats))
+	for _, stats := range registry.stats {
+		var series string
+		fields := make(map[string]int64, len(stats))
+		for fieldname, stat := range stats {
+			if series == "" {
+				series = seriesName(stat.Name(), stat.Tags())
+			}
+			if t, ok := stat.(*timingStat); ok {
+				fields[fieldname] = t.peek()
+			} else {
+				fields[fieldname] = stat.Get()
+			}
+		}
+		if len(fields) > 0 {
+			snapshot[series] = fields
+		}
+	}
+	return snapshot
+}
+
+func seriesName(measurement string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(measurement)
+	for _, k := range keys {
+		b.WriteString("," + k + "=" + tags[k])
+	}
+	return b.String()
+}
+
 type Registry struct {
-	stats map[uint64]map[string]Stat
-	mu    sync.Mutex
+	stats      map[uint64]map[string]Stat
+	histograms map[uint64]*Histogram
+	mu         sync.Mutex
 }
 
 func (r *Registry) register(measurement, field string, tags map[string]string) Stat {
@@ -201,6 +244,7 @@ func key(measurement string, tags map[string]string) uint64 {
 
 func init() {
 	registry = &Registry{
-		stats: make(map[uint64]map[string]Stat),
+		stats:      make(map[uint64]map[string]Stat),
+		histograms: make(map[uint64]*Histogram),
 	}
 }
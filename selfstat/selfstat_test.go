@@ -4,6 +4,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/circonus-labs/circonus-unified-agent/cua"
 	"github.com/circonus-labs/circonus-unified-agent/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,7 +20,8 @@ var (
 // testCleanup resets the global registry for test cleanup & unlocks the test lock
 func testCleanup() {
 	registry = &Registry{
-		stats: make(map[uint64]map[string]Stat),
+		stats:      make(map[uint64]map[string]Stat),
+		histograms: make(map[uint64]*Histogram),
 	}
 	testLock.Unlock()
 }
@@ -211,3 +213,48 @@ func TestRegisterCopy(t *testing.T) {
 	tags["new"] = "value"
 	require.NotEqual(t, tags, stat.Tags())
 }
+
+func TestSnapshot(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	s := Register("test", "count", map[string]string{"b": "2", "a": "1"})
+	s.Incr(3)
+	ts := RegisterTiming("test", "time_ns", map[string]string{"b": "2", "a": "1"})
+	ts.Incr(10)
+	ts.Incr(20)
+
+	expected := map[string]int64{"count": 3, "time_ns": 15}
+	require.Equal(t, expected, Snapshot()["internal_test,a=1,b=2"])
+	// the timings are not reset
+	require.Equal(t, expected, Snapshot()["internal_test,a=1,b=2"])
+	require.Equal(t, int64(15), ts.Get())
+}
+
+func TestHistogram(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	h := RegisterHistogram("test", map[string]string{"a": "1"})
+	require.Same(t, h, RegisterHistogram("test", map[string]string{"a": "1"}))
+	h.Observe(0.1234)
+	h.Observe(0.125)
+	h.Observe(5)
+	h.Observe(1234)
+	h.Observe(-1)
+
+	metrics := HistogramMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, "internal_test", metrics[0].Name())
+	require.Equal(t, cua.Histogram, metrics[0].Type())
+	require.Equal(t, map[string]string{"a": "1"}, metrics[0].Tags())
+	require.Equal(t, map[string]interface{}{
+		"0.12": int64(2),
+		"5":    int64(1),
+		"1200": int64(1),
+		"0":    int64(1),
+	}, metrics[0].Fields())
+
+	// the histograms are reset by each call
+	require.Empty(t, HistogramMetrics())
+}
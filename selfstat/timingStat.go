@@ -40,6 +40,16 @@ func (s *timingStat) Get() int64 {
 	return avg
 }
 
+// peek returns the average like Get, without starting a new period.
+func (s *timingStat) peek() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count > 0 {
+		return s.v / s.count
+	}
+	return s.prev
+}
+
 func (s *timingStat) Name() string {
 	return s.measurement
 }
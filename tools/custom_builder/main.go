@@ -0,0 +1,303 @@
+// custom_builder selects the plugins compiled in an agent built with the
+// custom tag, from a manifest listing the plugins or from the agent
+// configuration files using them.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/toml"
+	tomlast "github.com/influxdata/toml/ast"
+)
+
+// pluginTypes are the plugin types, each registered in its plugins/<type>/all
+// package.
+var pluginTypes = []string{"aggregators", "inputs", "outputs", "processors"}
+
+const generatedFile = "custom_generated.go"
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	var manifests, configs stringList
+	root := flag.String("root", ".", "root directory of the repository")
+	dryRun := flag.Bool("dry-run", false, "list the selected plugins without generating the files")
+	flag.Var(&manifests, "manifest", "manifest listing the plugins as <type>.<name>, one per line (repeatable)")
+	flag.Var(&configs, "config", "agent configuration file whose plugins are selected (repeatable)")
+	flag.Parse()
+	log.SetFlags(0)
+
+	if len(manifests) == 0 && len(configs) == 0 {
+		log.Fatal("E! at least one -manifest or -config is required")
+	}
+
+	registry, err := scanRegistry(*root)
+	if err != nil {
+		log.Fatalf("E! %v", err)
+	}
+
+	var names []string
+	for _, path := range manifests {
+		n, err := readManifest(path)
+		if err != nil {
+			log.Fatalf("E! %v", err)
+		}
+		names = append(names, n...)
+	}
+	for _, path := range configs {
+		n, err := readConfig(path)
+		if err != nil {
+			log.Fatalf("E! %v", err)
+		}
+		names = append(names, n...)
+	}
+
+	packages, err := selectPackages(registry, names)
+	if err != nil {
+		log.Fatalf("E! %v", err)
+	}
+
+	for _, ptype := range pluginTypes {
+		for _, pkg := range packages[ptype] {
+			fmt.Printf("%s.%s\n", ptype, pkg)
+		}
+	}
+	if *dryRun {
+		return
+	}
+
+	module, err := modulePath(*root)
+	if err != nil {
+		log.Fatalf("E! %v", err)
+	}
+	for _, ptype := range pluginTypes {
+		path := filepath.Join(*root, "plugins", ptype, "all", generatedFile)
+		if err := ioutil.WriteFile(path, generate(module, ptype, packages[ptype]), 0644); err != nil { //nolint:gosec // G306
+			log.Fatalf("E! %v", err)
+		}
+	}
+	log.Printf("I! build the agent with: go build -tags custom ./cmd/circonus-unified-agent")
+}
+
+// scanRegistry maps the names of the plugins, such as "inputs.cpu", to the
+// package registering them.
+func scanRegistry(root string) (map[string]string, error) {
+	registry := make(map[string]string)
+	for _, ptype := range pluginTypes {
+		dirs, err := ioutil.ReadDir(filepath.Join(root, "plugins", ptype))
+		if err != nil {
+			return nil, fmt.Errorf("read plugins: %w", err)
+		}
+		for _, dir := range dirs {
+			if !dir.IsDir() || dir.Name() == "all" {
+				continue
+			}
+			names, err := registeredNames(filepath.Join(root, "plugins", ptype, dir.Name()), ptype)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				registry[ptype+"."+name] = dir.Name()
+			}
+		}
+	}
+	return registry, nil
+}
+
+// registeredNames returns the names passed to <ptype>.Add or
+// <ptype>.AddStreaming by the package in dir, as a literal or a constant.
+func registeredNames(dir, ptype string) ([]string, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, pkg := range pkgs {
+		constants := make(map[string]string)
+		var args []ast.Expr
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.ValueSpec:
+					for i, ident := range n.Names {
+						if i < len(n.Values) {
+							if s, ok := stringLit(n.Values[i]); ok {
+								constants[ident.Name] = s
+							}
+						}
+					}
+				case *ast.CallExpr:
+					sel, ok := n.Fun.(*ast.SelectorExpr)
+					if !ok || (sel.Sel.Name != "Add" && sel.Sel.Name != "AddStreaming") || len(n.Args) == 0 {
+						return true
+					}
+					if x, ok := sel.X.(*ast.Ident); ok && x.Name == ptype {
+						args = append(args, n.Args[0])
+					}
+				}
+				return true
+			})
+		}
+		for _, arg := range args {
+			if s, ok := stringLit(arg); ok {
+				names = append(names, s)
+			} else if ident, ok := arg.(*ast.Ident); ok && constants[ident.Name] != "" {
+				names = append(names, constants[ident.Name])
+			}
+		}
+	}
+	return names, nil
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// readManifest reads the plugin names of a manifest, blank lines and the
+// text following a "#" are ignored.
+func readManifest(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, name := range strings.Fields(line) {
+			names = append(names, name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read manifest (%s): %w", path, err)
+	}
+	return names, nil
+}
+
+// readConfig reads the names of the plugins configured in an agent
+// configuration file.
+func readConfig(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	tbl, err := toml.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse config (%s): %w", path, err)
+	}
+
+	var names []string
+	for _, ptype := range pluginTypes {
+		sub, ok := tbl.Fields[ptype].(*tomlast.Table)
+		if !ok {
+			continue
+		}
+		for name := range sub.Fields {
+			// legacy name of the diskio input
+			if ptype == "inputs" && name == "io" {
+				name = "diskio"
+			}
+			names = append(names, ptype+"."+name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// selectPackages returns the sorted packages of each plugin type
+// registering the named plugins.
+func selectPackages(registry map[string]string, names []string) (map[string][]string, error) {
+	selected := make(map[string]map[string]bool)
+	var unknown []string
+	for _, name := range names {
+		pkg, ok := registry[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		ptype := name[:strings.Index(name, ".")]
+		if selected[ptype] == nil {
+			selected[ptype] = make(map[string]bool)
+		}
+		selected[ptype][pkg] = true
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown plugins: %s", strings.Join(unknown, ", "))
+	}
+
+	packages := make(map[string][]string)
+	for ptype, pkgs := range selected {
+		for pkg := range pkgs {
+			packages[ptype] = append(packages[ptype], pkg)
+		}
+		sort.Strings(packages[ptype])
+	}
+	return packages, nil
+}
+
+func modulePath(root string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("read go.mod: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "module" {
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("no module in go.mod")
+}
+
+// generate returns the source of the generated file of a plugin type.
+func generate(module, ptype string, pkgs []string) []byte {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by tools/custom_builder; DO NOT EDIT.\n\n")
+	b.WriteString("// +build custom\n\n")
+	b.WriteString("package all\n")
+	if len(pkgs) > 0 {
+		b.WriteString("\n//nolint:golint\nimport (\n")
+		for _, pkg := range pkgs {
+			fmt.Fprintf(&b, "\t_ %q\n", module+"/plugins/"+ptype+"/"+pkg)
+		}
+		b.WriteString(")\n")
+	}
+	return b.Bytes()
+}
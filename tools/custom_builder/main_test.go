@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func TestScanRegistry(t *testing.T) {
+	root := t.TempDir()
+	for _, ptype := range pluginTypes {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "plugins", ptype, "all"), 0755))
+	}
+	writeFile(t, filepath.Join(root, "plugins/inputs/cpu/cpu.go"), `package cpu
+func init() {
+	inputs.Add("cpu", func() cua.Input { return &CPU{} })
+}
+`)
+	writeFile(t, filepath.Join(root, "plugins/inputs/net/net.go"), `package net
+const pluginName = "net"
+func init() {
+	inputs.Add(pluginName, func() cua.Input { return &Net{} })
+}
+`)
+	writeFile(t, filepath.Join(root, "plugins/inputs/net/netstat.go"), `package net
+func init() {
+	inputs.Add("netstat", func() cua.Input { return &NetStat{} })
+}
+`)
+	writeFile(t, filepath.Join(root, "plugins/inputs/net/net_test.go"), `package net
+func init() {
+	inputs.Add("ignored", nil)
+}
+`)
+	writeFile(t, filepath.Join(root, "plugins/processors/lookup/lookup.go"), `package lookup
+func init() {
+	processors.AddStreaming("lookup", func() cua.StreamingProcessor { return &Lookup{} })
+}
+`)
+
+	registry, err := scanRegistry(root)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"inputs.cpu":        "cpu",
+		"inputs.net":        "net",
+		"inputs.netstat":    "net",
+		"processors.lookup": "lookup",
+	}, registry)
+}
+
+func TestSelectPlugins(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest")
+	writeFile(t, manifest, `# host
+inputs.cpu
+inputs.net inputs.netstat  # same package
+
+processors.lookup
+`)
+	config := filepath.Join(dir, "agent.conf")
+	writeFile(t, config, `[agent]
+  interval = "10s"
+[[inputs.io]]
+  instance_id = "host"
+[[outputs.circonus]]
+  api_token = "${API_TOKEN}"
+`)
+
+	names, err := readManifest(manifest)
+	require.NoError(t, err)
+	require.Equal(t, []string{"inputs.cpu", "inputs.net", "inputs.netstat", "processors.lookup"}, names)
+
+	fromConfig, err := readConfig(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{"inputs.diskio", "outputs.circonus"}, fromConfig)
+
+	registry := map[string]string{
+		"inputs.cpu":        "cpu",
+		"inputs.diskio":     "diskio",
+		"inputs.net":        "net",
+		"inputs.netstat":    "net",
+		"outputs.circonus":  "circonus",
+		"processors.lookup": "lookup",
+	}
+	packages, err := selectPackages(registry, append(names, fromConfig...))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"inputs":     {"cpu", "diskio", "net"},
+		"outputs":    {"circonus"},
+		"processors": {"lookup"},
+	}, packages)
+
+	_, err = selectPackages(registry, []string{"inputs.cpu", "inputs.opcua", "outputs.kafka"})
+	require.EqualError(t, err, "unknown plugins: inputs.opcua, outputs.kafka")
+}
+
+func TestGenerate(t *testing.T) {
+	require.Equal(t, `// Code generated by tools/custom_builder; DO NOT EDIT.
+
+// +build custom
+
+package all
+
+//nolint:golint
+import (
+	_ "example.com/agent/plugins/inputs/cpu"
+	_ "example.com/agent/plugins/inputs/net"
+)
+`, string(generate("example.com/agent", "inputs", []string{"cpu", "net"})))
+
+	require.Equal(t, `// Code generated by tools/custom_builder; DO NOT EDIT.
+
+// +build custom
+
+package all
+`, string(generate("example.com/agent", "aggregators", nil)))
+}